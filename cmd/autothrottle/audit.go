@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// auditMessage is the JSON value of an audit topic message.
+type auditMessage struct {
+	Cluster string `json:"cluster,omitempty"`
+	replication.AuditRecord
+}
+
+// kafkaAuditWriter is a replication.AuditWriter that produces AuditRecords
+// to a Kafka topic as JSON, keyed by broker ID.
+type kafkaAuditWriter struct {
+	p       *kafka.Producer
+	topic   string
+	cluster string
+}
+
+// newKafkaAuditWriter returns a kafkaAuditWriter for the topic, producing
+// to the bootstrap servers. Records are tagged with the cluster name, which
+// may be empty.
+func newKafkaAuditWriter(bootstrapServers, topic, cluster string) (*kafkaAuditWriter, error) {
+	p, err := kafkaadmin.NewProducer(kafkaadmin.Config{BootstrapServers: bootstrapServers})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &kafkaAuditWriter{p: p, topic: topic, cluster: cluster}
+	go w.logDeliveryErrors()
+
+	return w, nil
+}
+
+// logDeliveryErrors logs any records that failed to be delivered.
+func (w *kafkaAuditWriter) logDeliveryErrors() {
+	for e := range w.p.Events() {
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			logging.Error("Error writing audit record", logging.Fields{"topic": w.topic, "error": m.TopicPartition.Error.Error()})
+		}
+	}
+}
+
+// WriteAudit produces the AuditRecord. Records are delivered asynchronously.
+func (w *kafkaAuditWriter) WriteAudit(r replication.AuditRecord) error {
+	b, err := json.Marshal(auditMessage{Cluster: w.cluster, AuditRecord: r})
+	if err != nil {
+		return err
+	}
+
+	return w.p.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &w.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(strconv.Itoa(r.BrokerID)),
+		Value:          b,
+	}, nil)
+}
+
+// Close delivers any outstanding records, waiting up to 5 seconds, and
+// closes the producer.
+func (w *kafkaAuditWriter) Close() {
+	w.p.Flush(5000)
+	w.p.Close()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clustersFile is the clusters-config file format.
+type clustersFile struct {
+	Clusters []clusterSpec `json:"clusters" yaml:"clusters"`
+}
+
+// clusterSpec is a cluster managed in multi-cluster mode.
+type clusterSpec struct {
+	// Name is the cluster name, setting the cluster flag.
+	Name string `json:"name" yaml:"name"`
+	// Flags are flag names to values overriding the top level flags for the
+	// cluster, e.g. "zk-addr", "bootstrap-servers", "cap-map" or "interval".
+	Flags map[string]interface{} `json:"flags" yaml:"flags"`
+}
+
+// Flags that can't be overridden per cluster. The zk-config-prefix is shared
+// by all clusters; the override and leader znodes under it are namespaced
+// by cluster.
+var sharedFlags = map[string]struct{}{
+	"cluster":          {},
+	"zk-config-prefix": {},
+}
+
+// loadClusterConfigs reads the clusters-config file at the path and returns
+// a configParams for each cluster, made from the base configParams with the
+// cluster flag overrides applied.
+func loadClusterConfigs(path string, base configParams) ([]*configParams, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f clustersFile
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(b, &f)
+	default:
+		err = yaml.Unmarshal(b, &f)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing clusters config %s: %s", path, err)
+	}
+
+	if len(f.Clusters) == 0 {
+		return nil, fmt.Errorf("no clusters configured in %s", path)
+	}
+
+	var configs []*configParams
+	names := map[string]struct{}{}
+	listeners := map[string]string{}
+
+	for _, c := range f.Clusters {
+		if c.Name == "" {
+			return nil, fmt.Errorf("clusters config %s: cluster name required", path)
+		}
+
+		if _, exists := names[c.Name]; exists {
+			return nil, fmt.Errorf("clusters config %s: duplicate cluster %s", path, c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		cfg, err := clusterConfig(c, base)
+		if err != nil {
+			return nil, fmt.Errorf("clusters config %s: cluster %s: %s", path, c.Name, err)
+		}
+
+		// Each cluster serves its own admin API.
+		if other, exists := listeners[cfg.APIListen]; exists {
+			return nil, fmt.Errorf("clusters config %s: clusters %s and %s share the api-listen address %s", path, other, c.Name, cfg.APIListen)
+		}
+		listeners[cfg.APIListen] = c.Name
+
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// clusterConfig returns a copy of the base configParams with the
+// clusterSpec flag overrides applied.
+func clusterConfig(c clusterSpec, base configParams) (*configParams, error) {
+	cfg := &configParams{}
+
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, cfg)
+
+	// Registering the flags sets the defaults; start from the base values.
+	*cfg = base
+	cfg.Cluster = c.Name
+	cfg.ClustersConfig = ""
+
+	for name, v := range c.Flags {
+		if _, shared := sharedFlags[name]; shared {
+			return nil, fmt.Errorf("flag %s can't be set per cluster", name)
+		}
+
+		if err := fs.Set(name, fmt.Sprint(v)); err != nil {
+			return nil, fmt.Errorf("invalid flag %s: %s", name, err)
+		}
+	}
+
+	return cfg, nil
+}
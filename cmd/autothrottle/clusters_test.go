@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadClusterConfigs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	data := `
+clusters:
+  - name: kafka-a
+    flags:
+      zk-addr: zk-a:2181
+      api-listen: localhost:8081
+      interval: 60
+      cap-map: '{"d2.2xlarge":120}'
+  - name: kafka-b
+    flags:
+      zk-addr: zk-b:2181
+      api-listen: localhost:8082
+      dry-run: true
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := configParams{Interval: 180, MinRate: 20, ConfigZKPrefix: "autothrottle"}
+
+	configs, err := loadClusterConfigs(path, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 configs, got %d\n", len(configs))
+	}
+
+	a, b := configs[0], configs[1]
+
+	// Cluster overrides.
+	if a.Cluster != "kafka-a" || a.ZKAddr != "zk-a:2181" || a.Interval != 60 {
+		t.Errorf("Unexpected config %+v\n", a)
+	}
+
+	if b.Cluster != "kafka-b" || b.ZKAddr != "zk-b:2181" || !b.DryRun {
+		t.Errorf("Unexpected config %+v\n", b)
+	}
+
+	if err := a.parseCapacityFlags(); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.CapMap["d2.2xlarge"] != 120 {
+		t.Errorf("Expected d2.2xlarge capacity 120, got %f\n", a.CapMap["d2.2xlarge"])
+	}
+
+	// Base values are retained where not overridden.
+	for _, c := range configs {
+		if c.MinRate != 20 || c.ConfigZKPrefix != "autothrottle" {
+			t.Errorf("[cluster %s] Expected base values, got %+v\n", c.Cluster, c)
+		}
+	}
+
+	if b.Interval != 180 {
+		t.Errorf("Expected interval 180, got %d\n", b.Interval)
+	}
+}
+
+func TestLoadClusterConfigsErrors(t *testing.T) {
+	tests := map[string]string{
+		"no clusters":       `{"clusters": []}`,
+		"missing name":      `{"clusters": [{"flags": {}}]}`,
+		"duplicate name":    `{"clusters": [{"name": "a", "flags": {"api-listen": ":1"}}, {"name": "a", "flags": {"api-listen": ":2"}}]}`,
+		"shared api-listen": `{"clusters": [{"name": "a"}, {"name": "b"}]}`,
+		"unknown flag":      `{"clusters": [{"name": "a", "flags": {"unknown": 1}}]}`,
+		"invalid value":     `{"clusters": [{"name": "a", "flags": {"interval": "x"}}]}`,
+		"shared flag":       `{"clusters": [{"name": "a", "flags": {"zk-config-prefix": "x"}}]}`,
+		"clusters-config":   `{"clusters": [{"name": "a", "flags": {"clusters-config": "x"}}]}`,
+	}
+
+	for name, data := range tests {
+		path := filepath.Join(t.TempDir(), "clusters.json")
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadClusterConfigs(path, configParams{APIListen: "localhost:8080"}); err == nil {
+			t.Errorf("[%s] Expected error\n", name)
+		}
+	}
+}
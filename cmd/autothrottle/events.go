@@ -1,41 +1,100 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 )
 
-// Events configs.
-var eventTitlePrefix = "kafka-autothrottle"
+// eventTitlePrefix returns the event title prefix for the cluster name,
+// which may be empty.
+func eventTitlePrefix(cluster string) string {
+	if cluster == "" {
+		return "kafka-autothrottle"
+	}
+
+	return "kafka-autothrottle " + cluster
+}
 
 // DDEventWriter wraps a channel where *kafkametrics.Event are written
 // to along with any defaults configs, such as tags to apply to each event.
 type DDEventWriter struct {
-	c           chan *kafkametrics.Event
-	tags        []string
-	titlePrefix string
+	c              chan *kafkametrics.Event
+	tags           []string
+	titlePrefix    string
+	aggregationKey string
 }
 
 // Write takes an event title and message string and writes a
 // *kafkametrics.Event to the event channel, formatted with
-// the configured title and tags.
+// the configured title, tags and aggregation key.
 func (e *DDEventWriter) Write(t string, m string) {
+	e.WriteAlert(t, m, "info")
+}
+
+// WriteAlert writes an event as Write does, with the alert type.
+func (e *DDEventWriter) WriteAlert(t, m, alertType string) {
 	e.c <- &kafkametrics.Event{
-		Title: fmt.Sprintf("[%s] %s", e.titlePrefix, t),
-		Text:  m,
-		Tags:  e.tags,
+		Title:          fmt.Sprintf("[%s] %s", e.titlePrefix, t),
+		Text:           m,
+		Tags:           e.tags,
+		AggregationKey: e.aggregationKey,
+		AlertType:      alertType,
+		SourceTypeName: "kafka",
 	}
 }
 
+// eventLimit is the JSON config of an event class limit, in seconds.
+type eventLimit struct {
+	DedupWindow int `json:"dedup_window"`
+	MinInterval int `json:"min_interval"`
+}
+
+// limit returns the eventLimit as a kafkametrics.EventLimit.
+func (l eventLimit) limit() kafkametrics.EventLimit {
+	return kafkametrics.EventLimit{
+		DedupWindow: time.Duration(l.DedupWindow) * time.Second,
+		MinInterval: time.Duration(l.MinInterval) * time.Second,
+	}
+}
+
+// withEventLimits wraps the Handler with a kafkametrics.EventLimitHandler if
+// event deduplication or rate limiting is configured via the
+// event-dedup-window, event-min-interval or event-limits flags.
+func withEventLimits(cfg *configParams, km kafkametrics.Handler) (kafkametrics.Handler, error) {
+	if cfg.EventDedupWindow <= 0 && cfg.EventMinInterval <= 0 && cfg.EventLimits == "" {
+		return km, nil
+	}
+
+	c := kafkametrics.EventLimitConfig{
+		Default: eventLimit{DedupWindow: cfg.EventDedupWindow, MinInterval: cfg.EventMinInterval}.limit(),
+		Classes: map[string]kafkametrics.EventLimit{},
+	}
+
+	if cfg.EventLimits != "" {
+		limits := map[string]eventLimit{}
+		if err := json.Unmarshal([]byte(cfg.EventLimits), &limits); err != nil {
+			return nil, fmt.Errorf("error parsing event-limits flag: %s", err)
+		}
+
+		for class, l := range limits {
+			c.Classes[class] = l.limit()
+		}
+	}
+
+	return kafkametrics.NewEventLimitHandler(km, c), nil
+}
+
 // eventWriter reads from a channel of *kafkametrics.Event and writes
 // them to the Datadog API.
 func eventWriter(k kafkametrics.Handler, c chan *kafkametrics.Event) {
 	for e := range c {
 		err := k.PostEvent(e)
 		if err != nil {
-			log.Printf("Error writing event: %s\n", err)
+			logging.Error("Error writing event", logging.Fields{"title": e.Title, "error": err.Error()})
 		}
 	}
 }
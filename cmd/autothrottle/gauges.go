@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+)
+
+// gaugeRegistry is a replication.MetricsWriter that retains the most recent
+// value of each gauge, served in the Prometheus text exposition format. Metric
+// names have dots replaced with underscores and key:value tags are written as
+// labels. Gauges not written within the ttl are dropped so that, for
+// instance, the rates of brokers no longer throttled aren't served
+// indefinitely.
+type gaugeRegistry struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu sync.Mutex
+	// Gauges by metric name and labels.
+	gauges map[string]map[string]gaugeValue
+}
+
+type gaugeValue struct {
+	value   float64
+	updated time.Time
+}
+
+func newGaugeRegistry(ttl time.Duration) *gaugeRegistry {
+	return &gaugeRegistry{
+		ttl:    ttl,
+		now:    time.Now,
+		gauges: map[string]map[string]gaugeValue{},
+	}
+}
+
+// Gauge records the gauge value.
+func (g *gaugeRegistry) Gauge(name string, value float64, tags []string) error {
+	name = promName(name)
+	labels := promLabels(tags)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.gauges[name] == nil {
+		g.gauges[name] = map[string]gaugeValue{}
+	}
+	g.gauges[name][labels] = gaugeValue{value: value, updated: g.now()}
+
+	return nil
+}
+
+// WriteMetrics writes the gauges in the Prometheus text exposition format to
+// the io.Writer, dropping any expired gauges.
+func (g *gaugeRegistry) WriteMetrics(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+
+	var names []string
+	for name, series := range g.gauges {
+		for labels, v := range series {
+			if g.ttl > 0 && now.Sub(v.updated) > g.ttl {
+				delete(series, labels)
+			}
+		}
+		if len(series) == 0 {
+			delete(g.gauges, name)
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+
+		var labels []string
+		for l := range g.gauges[name] {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		for _, l := range labels {
+			fmt.Fprintf(w, "%s%s %g\n", name, l, g.gauges[name][l].value)
+		}
+	}
+}
+
+// promName returns the metric name with any characters invalid in Prometheus
+// metric names replaced with underscores.
+func promName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// promLabels takes a []string of key:value tags and returns the Prometheus
+// label set, sorted by label name. Tags without a value are ignored.
+func promLabels(tags []string) string {
+	var labels []string
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		// Colons are reserved for recording rules in label names.
+		k := strings.ReplaceAll(promName(kv[0]), ":", "_")
+		labels = append(labels, fmt.Sprintf("%s=%q", k, kv[1]))
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+
+	sort.Strings(labels)
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// metricsWriters is a replication.MetricsWriter that writes gauges to
+// several MetricsWriters.
+type metricsWriters []replication.MetricsWriter
+
+// Gauge writes the gauge to each MetricsWriter, returning the first error
+// encountered.
+func (m metricsWriters) Gauge(name string, value float64, tags []string) error {
+	var err error
+	for _, w := range m {
+		if e := w.Gauge(name, value, tags); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// metricsExposer writes metrics in the Prometheus text exposition format.
+type metricsExposer interface {
+	WriteMetrics(io.Writer)
+}
+
+// metricsEndpoint is an http.Handler that serves the metrics of each
+// metricsExposer in the Prometheus text exposition format.
+type metricsEndpoint []metricsExposer
+
+// ServeHTTP serves the metrics.
+func (m metricsEndpoint) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_ = req
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, e := range m {
+		e.WriteMetrics(w)
+	}
+}
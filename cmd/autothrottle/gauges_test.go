@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestGaugeRegistry(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := newGaugeRegistry(time.Minute)
+	g.now = func() time.Time { return now }
+
+	g.Gauge("autothrottle.broker.throttle_rate", 100, []string{"role:leader", "broker_id:1001", "source:metrics"})
+	g.Gauge("autothrottle.broker.throttle_rate", 50.5, []string{"broker_id:1002", "role:follower", "source:metrics"})
+	g.Gauge("autothrottle.failure_mode", 0, nil)
+
+	expected := `# TYPE autothrottle_broker_throttle_rate gauge
+autothrottle_broker_throttle_rate{broker_id="1001",role="leader",source="metrics"} 100
+autothrottle_broker_throttle_rate{broker_id="1002",role="follower",source="metrics"} 50.5
+# TYPE autothrottle_failure_mode gauge
+autothrottle_failure_mode 0
+`
+
+	var b bytes.Buffer
+	g.WriteMetrics(&b)
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, b.String())
+	}
+
+	// Gauges not written within the ttl are dropped.
+	now = now.Add(2 * time.Minute)
+	g.Gauge("autothrottle.failure_mode", 1, nil)
+
+	expected = `# TYPE autothrottle_failure_mode gauge
+autothrottle_failure_mode 1
+`
+
+	b.Reset()
+	g.WriteMetrics(&b)
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, b.String())
+	}
+}
+
+func TestPromLabels(t *testing.T) {
+	tests := map[string][]string{
+		"":                             nil,
+		`{a="1"}`:                      {"a:1", "novalue"},
+		`{dry_run="true",host="a:b"}`:  {"host:a:b", "dry-run:true"},
+		`{cluster="kafka-a",role="x"}`: {"role:x", "cluster:kafka-a"},
+	}
+
+	for expected, tags := range tests {
+		if got := promLabels(tags); got != expected {
+			t.Errorf("Expected labels %s, got %s\n", expected, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// intervalSchedule determines the time between check intervals. An active
+// or idle interval, if set, replaces the base interval while reassignments
+// are or aren't ongoing, and each interval is randomly varied by up to the
+// jitter percentage to spread the metrics API requests of many instances.
+type intervalSchedule struct {
+	base   time.Duration
+	active time.Duration
+	idle   time.Duration
+	// The jitter as a fraction of the interval.
+	jitter float64
+	rand   func() float64
+}
+
+// newIntervalSchedule returns an intervalSchedule from the interval flags.
+func newIntervalSchedule(cfg *configParams) (intervalSchedule, error) {
+	switch {
+	case cfg.Interval <= 0:
+		return intervalSchedule{}, errors.New("interval must be > 0")
+	case cfg.ActiveInterval < 0:
+		return intervalSchedule{}, errors.New("active-interval must be >= 0")
+	case cfg.IdleInterval < 0:
+		return intervalSchedule{}, errors.New("idle-interval must be >= 0")
+	case cfg.IntervalJitter < 0 || cfg.IntervalJitter > 50:
+		return intervalSchedule{}, errors.New("interval-jitter must be >= 0 and <= 50")
+	}
+
+	return intervalSchedule{
+		base:   time.Duration(cfg.Interval) * time.Second,
+		active: time.Duration(cfg.ActiveInterval) * time.Second,
+		idle:   time.Duration(cfg.IdleInterval) * time.Second,
+		jitter: cfg.IntervalJitter / 100,
+		rand:   rand.Float64,
+	}, nil
+}
+
+// interval returns the interval without jitter depending on whether
+// reassignments are ongoing.
+func (s intervalSchedule) interval(active bool) time.Duration {
+	switch {
+	case active && s.active > 0:
+		return s.active
+	case !active && s.idle > 0:
+		return s.idle
+	}
+
+	return s.base
+}
+
+// next returns the jittered interval depending on whether reassignments are
+// ongoing.
+func (s intervalSchedule) next(active bool) time.Duration {
+	return s.jittered(s.interval(active))
+}
+
+// jittered returns the interval randomly varied by up to the jitter.
+func (s intervalSchedule) jittered(d time.Duration) time.Duration {
+	if s.jitter == 0 {
+		return d
+	}
+
+	return d + time.Duration(float64(d)*s.jitter*(2*s.rand()-1))
+}
+
+// max returns the longest possible interval, including jitter.
+func (s intervalSchedule) max() time.Duration {
+	d := s.base
+	for _, i := range []time.Duration{s.active, s.idle} {
+		if i > d {
+			d = i
+		}
+	}
+
+	return d + time.Duration(float64(d)*s.jitter)
+}
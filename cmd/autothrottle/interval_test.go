@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalSchedule(t *testing.T) {
+	cfg := &configParams{Interval: 180, ActiveInterval: 30, IdleInterval: 600, IntervalJitter: 10}
+
+	s, err := newIntervalSchedule(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := 1.0
+	s.rand = func() float64 { return r }
+
+	expected := []struct {
+		active bool
+		rand   float64
+		d      time.Duration
+	}{
+		{true, 0.5, 30 * time.Second},
+		{false, 0.5, 600 * time.Second},
+		// Jitter of up to +/- 10%.
+		{true, 1, 33 * time.Second},
+		{false, 0, 540 * time.Second},
+	}
+
+	for n, e := range expected {
+		r = e.rand
+		if d := s.next(e.active); d != e.d {
+			t.Errorf("[test index %d] Expected interval %s, got %s\n", n, e.d, d)
+		}
+	}
+
+	if d := s.max(); d != 660*time.Second {
+		t.Errorf("Expected max interval 660s, got %s\n", d)
+	}
+
+	// Unset active and idle intervals use the interval.
+	s, _ = newIntervalSchedule(&configParams{Interval: 180})
+	for _, active := range []bool{true, false} {
+		if d := s.next(active); d != 180*time.Second {
+			t.Errorf("Expected interval 180s, got %s\n", d)
+		}
+	}
+}
+
+func TestIntervalScheduleValidation(t *testing.T) {
+	for _, cfg := range []*configParams{
+		{Interval: 0},
+		{Interval: 180, ActiveInterval: -1},
+		{Interval: 180, IdleInterval: -1},
+		{Interval: 180, IntervalJitter: 60},
+	} {
+		if _, err := newIntervalSchedule(cfg); err == nil {
+			t.Errorf("Expected error for config %+v\n", cfg)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+// leaderElector elects a single leader among the autothrottle instances
+// managing a cluster, using an ephemeral znode holding the instance ID of the
+// leader as a lock. ZooKeeper deletes the znode when the session of the
+// leader ends, allowing a standby to acquire it.
+type leaderElector struct {
+	zk     kafkazk.Handler
+	path   string
+	id     string
+	leader bool
+}
+
+// newLeaderElector returns a *leaderElector for the instance ID using the
+// lock znode under the zk-config-prefix chroot. Clusters in multi-cluster
+// mode each use their own lock.
+func newLeaderElector(zk kafkazk.Handler, prefix, cluster, id string) *leaderElector {
+	path := fmt.Sprintf("/%s/leader", prefix)
+	if cluster != "" {
+		path = fmt.Sprintf("%s-%s", path, cluster)
+	}
+
+	return &leaderElector{
+		zk:   zk,
+		path: path,
+		id:   id,
+	}
+}
+
+// defaultInstanceID returns the instance ID used if none is configured,
+// made from the hostname and process ID.
+func defaultInstanceID() string {
+	h, err := os.Hostname()
+	if err != nil {
+		h = "autothrottle"
+	}
+
+	return fmt.Sprintf("%s-%d", h, os.Getpid())
+}
+
+// elect acquires the lock if unheld and returns whether the instance is the
+// leader, along with whether leadership changed since the previous call.
+// Leadership is relinquished if the lock can't be checked.
+func (l *leaderElector) elect() (leader bool, changed bool, err error) {
+	was := l.leader
+	l.leader, err = l.holdsLock()
+
+	return l.leader, l.leader != was, err
+}
+
+// holdsLock acquires the lock if unheld and returns whether it's held by the
+// instance.
+func (l *leaderElector) holdsLock() (bool, error) {
+	if !l.zk.Ready() {
+		return false, errors.New("ZooKeeper not connected")
+	}
+
+	exists, err := l.zk.Exists(l.path)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		// Another instance may acquire the lock first, in which case the
+		// create fails and the lock holder is read below.
+		if err := l.zk.CreateEphemeral(l.path, l.id); err != nil {
+			if exists, _ := l.zk.Exists(l.path); !exists {
+				return false, err
+			}
+		}
+	}
+
+	v, err := l.zk.Get(l.path)
+	if err != nil {
+		return false, err
+	}
+
+	return string(v) == l.id, nil
+}
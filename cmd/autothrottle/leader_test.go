@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+func TestLeaderElection(t *testing.T) {
+	zk := kafkazk.NewZooKeeperStub()
+
+	a := newLeaderElector(zk, "autothrottle", "", "instance-a")
+	b := newLeaderElector(zk, "autothrottle", "", "instance-b")
+
+	if a.path != "/autothrottle/leader" {
+		t.Errorf("Unexpected lock path %s\n", a.path)
+	}
+
+	// The first instance acquires the lock.
+	if leader, changed, err := a.elect(); !leader || !changed || err != nil {
+		t.Errorf("Expected instance-a to acquire leadership, got leader: %v, changed: %v, err: %v\n", leader, changed, err)
+	}
+
+	if leader, changed, err := b.elect(); leader || changed || err != nil {
+		t.Errorf("Expected instance-b to be on standby, got leader: %v, changed: %v, err: %v\n", leader, changed, err)
+	}
+
+	// Leadership is retained.
+	if leader, changed, _ := a.elect(); !leader || changed {
+		t.Errorf("Expected instance-a to retain leadership, got leader: %v, changed: %v\n", leader, changed)
+	}
+
+	// The standby takes over once the session of the leader ends.
+	zk.Delete(a.path)
+
+	if leader, changed, _ := b.elect(); !leader || !changed {
+		t.Errorf("Expected instance-b to acquire leadership, got leader: %v, changed: %v\n", leader, changed)
+	}
+
+	if leader, changed, _ := a.elect(); leader || !changed {
+		t.Errorf("Expected instance-a to lose leadership, got leader: %v, changed: %v\n", leader, changed)
+	}
+}
+
+func TestLeaderElectionClusterPath(t *testing.T) {
+	zk := kafkazk.NewZooKeeperStub()
+
+	a := newLeaderElector(zk, "autothrottle", "kafka-a", "instance-a")
+	b := newLeaderElector(zk, "autothrottle", "kafka-b", "instance-a")
+
+	if a.path != "/autothrottle/leader-kafka-a" {
+		t.Errorf("Unexpected lock path %s\n", a.path)
+	}
+
+	// Each cluster has its own lock.
+	for _, l := range []*leaderElector{a, b} {
+		if leader, _, err := l.elect(); !leader || err != nil {
+			t.Errorf("Expected leadership of %s, got err: %v\n", l.path, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// initLogging sets the default logger from the log-format and log-level
+// flags. Output of the standard library log package is written through the
+// logger as info records, or error and warn records for messages beginning
+// with "error" or "warn".
+func initLogging(format, level string) error {
+	lvl, err := logging.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	l, err := logging.New(logging.Config{
+		Output: os.Stderr,
+		Format: format,
+		Level:  lvl,
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.SetDefault(l)
+	log.SetFlags(0)
+	log.SetOutput(l.Writer(logging.LevelInfo))
+
+	return nil
+}
+
+// fatal writes an error record with the fields to the logger and exits.
+func fatal(l *logging.Logger, msg string, f logging.Fields) {
+	l.Error(msg, f)
+	os.Exit(1)
+}
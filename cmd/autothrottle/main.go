@@ -2,20 +2,25 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
+	"github.com/DataDog/kafka-kit/v4/internal/health"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
-	"github.com/DataDog/kafka-kit/v4/kafkametrics/datadog"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 
 	"github.com/jamiealquiza/envy"
@@ -25,61 +30,119 @@ var (
 	// This can be set with -ldflags "-X main.version=x.x.x"
 	version = "0.0.0"
 
-	// Config holds configuration parameters.
-	Config struct {
-		KafkaNativeMode         bool
-		KafkaAPIRequestTimeout  int
-		APIKey                  string
-		AppKey                  string
-		NetworkTXQuery          string
-		NetworkRXQuery          string
-		BrokerIDTag             string
-		InstanceTypeTag         string
-		MetricsWindow           int
-		BootstrapServers        string
-		ZKAddr                  string
-		ZKPrefix                string
-		Interval                int
-		APIListen               string
-		ConfigZKPrefix          string
-		DDEventTags             string
-		MinRate                 float64
-		SourceMaxRate           float64
-		DestinationMaxRate      float64
-		ChangeThreshold         float64
-		FailureThreshold        int
-		CapMap                  map[string]float64
-		CleanupAfter            int64
-		SkipAutoDeleteThrottles bool
-	}
+	// Config holds the configuration parameters parsed from flags.
+	Config configParams
 )
 
+// configParams holds configuration parameters. In multi-cluster mode, each
+// cluster has its own configParams.
+type configParams struct {
+	KafkaNativeMode          bool
+	KafkaAPIReassignments    bool
+	KafkaAPIRequestTimeout   int
+	APIKey                   string
+	AppKey                   string
+	MetricsBackend           string
+	MetricsBackendConfig     string
+	MetricsFallbackPolicy    string
+	EventSinks               string
+	EventSinksConfig         string
+	OTLPConfig               string
+	DogStatsDAddress         string
+	NetworkTXQuery           string
+	NetworkRXQuery           string
+	DiskUsedQuery            string
+	DiskFreeQuery            string
+	CPUQuery                 string
+	LeaderBytesOutQuery      string
+	ReplicationBytesOutQuery string
+	DiskIOQuery              string
+	AlterLogDirsLagQuery     string
+	ConsumerLagQuery         string
+	ConsumerGroupTag         string
+	BrokerIDTag              string
+	InstanceTypeTag          string
+	InstanceTypeResolver     string
+	ResolverConfig           string
+	MetricsWindow            int
+	MetricsTimeout           int
+	BootstrapServers         string
+	ZKAddr                   string
+	ZKPrefix                 string
+	Interval                 int
+	ActiveInterval           int
+	IdleInterval             int
+	IntervalJitter           float64
+	APIListen                string
+	ConfigZKPrefix           string
+	DDEventTags              string
+	DDEventAggregationKey    string
+	EventDedupWindow         int
+	EventMinInterval         int
+	EventLimits              string
+	DDRateLimit              float64
+	DDHostTagConcurrency     int
+	DDHostTagCacheTTL        int
+	DDTagsFromScope          bool
+	DDMaxPointAge            int
+	DDAggregation            string
+	DDSite                   string
+	DDProxyURL               string
+	DDCACertFile             string
+	DDLazyValidation         bool
+	MinRate                  float64
+	RateCeiling              float64
+	SourceMaxRate            float64
+	DestinationMaxRate       float64
+	CPUThreshold             float64
+	ChangeThreshold          float64
+	MinChange                float64
+	MaxStep                  float64
+	SmoothingFactor          float64
+	RampUpIntervals          int
+	RampUpStart              float64
+	DestinationAware         bool
+	DryRun                   bool
+	LeaderElection           bool
+	InstanceID               string
+	ThrottleMetrics          bool
+	FailurePolicy            string
+	FailureRate              float64
+	CriticalFailureThreshold int
+	FailureThreshold         int
+	ShutdownPolicy           string
+	ShutdownRate             float64
+	TolerateMissingMetrics   bool
+	LagThreshold             float64
+	LagBackoff               float64
+	LogDirMaxRate            float64
+	ClientQuotaClients       string
+	ClientQuotaMaxRate       float64
+	ClientQuotaMinRate       float64
+	RecordMetrics            string
+	AuditTopic               string
+	CapMap                   map[string]float64
+	CapMapFile               string
+	HeadroomMap              map[string]float64
+	BrokerCapMap             map[int]float64
+	CapacityProfiles         string
+	ThrottleSchedule         string
+	CleanupAfter             int64
+	SkipAutoDeleteThrottles  bool
+	Cluster                  string
+	ClustersConfig           string
+	// Raw JSON capacity map flag values.
+	capMapFlag       string
+	brokerCapMapFlag string
+}
+
 func main() {
 	v := flag.Bool("version", false, "version")
-	flag.BoolVar(&Config.KafkaNativeMode, "kafka-native-mode", false, "Favor native Kafka RPCs over ZooKeeper metadata access")
-	flag.IntVar(&Config.KafkaAPIRequestTimeout, "kafka-api-request-timeout", 15, "Kafka API request timeout (seconds)")
-	flag.StringVar(&Config.APIKey, "api-key", "", "Datadog API key")
-	flag.StringVar(&Config.AppKey, "app-key", "", "Datadog app key")
-	flag.StringVar(&Config.NetworkTXQuery, "net-tx-query", "avg:system.net.bytes_sent{service:kafka} by {host}", "Datadog query for broker outbound bandwidth by host")
-	flag.StringVar(&Config.NetworkRXQuery, "net-rx-query", "avg:system.net.bytes_rcvd{service:kafka} by {host}", "Datadog query for broker inbound bandwidth by host")
-	flag.StringVar(&Config.BrokerIDTag, "broker-id-tag", "broker_id", "Datadog host tag for broker ID")
-	flag.StringVar(&Config.InstanceTypeTag, "instance-type-tag", "instance-type", "Datadog tag for instance type")
-	flag.IntVar(&Config.MetricsWindow, "metrics-window", 120, "Time span of metrics required (seconds)")
-	flag.StringVar(&Config.BootstrapServers, "bootstrap-servers", "localhost:9092", "Kafka bootstrap servers")
-	flag.StringVar(&Config.ZKAddr, "zk-addr", "localhost:2181", "ZooKeeper connect string (for broker metadata or rebuild-topic lookups)")
-	flag.StringVar(&Config.ZKPrefix, "zk-prefix", "", "ZooKeeper namespace prefix")
-	flag.IntVar(&Config.Interval, "interval", 180, "Autothrottle check interval (seconds)")
-	flag.StringVar(&Config.APIListen, "api-listen", "localhost:8080", "Admin API listen address:port")
-	flag.StringVar(&Config.ConfigZKPrefix, "zk-config-prefix", "autothrottle", "ZooKeeper prefix to store autothrottle configuration")
-	flag.StringVar(&Config.DDEventTags, "dd-event-tags", "", "Comma-delimited list of Datadog event tags")
-	flag.Float64Var(&Config.MinRate, "min-rate", 10, "Minimum replication throttle rate (MB/s)")
-	flag.Float64Var(&Config.SourceMaxRate, "max-tx-rate", 90, "Maximum outbound replication throttle rate (as a percentage of available capacity)")
-	flag.Float64Var(&Config.DestinationMaxRate, "max-rx-rate", 90, "Maximum inbound replication throttle rate (as a percentage of available capacity)")
-	flag.Float64Var(&Config.ChangeThreshold, "change-threshold", 10, "Required change in replication throttle to trigger an update (percent)")
-	flag.IntVar(&Config.FailureThreshold, "failure-threshold", 1, "Number of iterations that throttle determinations can fail before reverting to the min-rate")
-	m := flag.String("cap-map", "", "JSON map of instance types to network capacity in MB/s")
-	flag.Int64Var(&Config.CleanupAfter, "cleanup-after", 60, "Number of intervals after which to issue a global throttle unset if no replication is running")
-	flag.BoolVar(&Config.SkipAutoDeleteThrottles, "skip-auto-delete-throttles", false, "Skip automatic throttle removal")
+	registerFlags(flag.CommandLine, &Config)
+	flag.StringVar(&Config.ClustersConfig, "clusters-config", "", "Path to a YAML or JSON file of clusters, each with flag overrides, to manage from a single process")
+	logFormat := flag.String("log-format", "text", "Log format [text, json]")
+	logLevel := flag.String("log-level", "info", "Minimum log level [debug, info, warn, error]")
+	simulatePath := flag.String("simulate", "", "Path to a metrics snapshots file (as written with record-metrics) to replay through the throttle calculation, printing the throttle decisions and exiting")
 
 	envy.Parse("AUTOTHROTTLE")
 	flag.Parse()
@@ -89,71 +152,317 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Deserialize instance-type capacity map.
-	Config.CapMap = map[string]float64{}
-	if len(*m) > 0 {
-		err := json.Unmarshal([]byte(*m), &Config.CapMap)
-		if err != nil {
-			fmt.Printf("Error parsing cap-map flag: %s\n", err)
+	if err := initLogging(*logFormat, *logLevel); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Build the configs of each cluster.
+	configs := []*configParams{&Config}
+	if Config.ClustersConfig != "" {
+		var err error
+		if configs, err = loadClusterConfigs(Config.ClustersConfig, Config); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	for _, cfg := range configs {
+		if err := cfg.parseCapacityFlags(); err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
+
+		if cfg.KafkaAPIReassignments && !cfg.KafkaNativeMode {
+			fatal(logging.Default(), "kafka-api-reassignments requires kafka-native-mode", nil)
+		}
 	}
 
-	log.Println("Autothrottle Running")
+	// Replay recorded metrics offline.
+	if *simulatePath != "" {
+		if err := simulate(configs[0], *simulatePath, os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	logging.Info("Autothrottle running", logging.Fields{"clusters": len(configs)})
 	// Lazily prevent a tight restart loop from thrashing ZK.
 	time.Sleep(1 * time.Second)
 
+	// Stop managing clusters on SIGTERM or SIGINT, applying the
+	// shutdown-policy. A second signal exits immediately.
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		s := <-sigs
+		signal.Stop(sigs)
+		logging.Info("Shutting down", logging.Fields{"signal": s.String()})
+		close(stop)
+	}()
+
+	// Manage each cluster independently.
+	var wg sync.WaitGroup
+	for _, cfg := range configs {
+		wg.Add(1)
+		go func(cfg *configParams) {
+			defer wg.Done()
+			run(cfg, stop)
+		}(cfg)
+	}
+
+	wg.Wait()
+}
+
+// registerFlags registers the flags of all configParams on the FlagSet.
+func registerFlags(fs *flag.FlagSet, cfg *configParams) {
+	fs.BoolVar(&cfg.KafkaNativeMode, "kafka-native-mode", false, "Favor native Kafka RPCs over ZooKeeper metadata access")
+	fs.BoolVar(&cfg.KafkaAPIReassignments, "kafka-api-reassignments", false, "Detect reassignments with the Kafka ListPartitionReassignments API rather than ZooKeeper; requires kafka-native-mode")
+	fs.IntVar(&cfg.KafkaAPIRequestTimeout, "kafka-api-request-timeout", 15, "Kafka API request timeout (seconds)")
+	fs.StringVar(&cfg.APIKey, "api-key", "", "Datadog API key")
+	fs.StringVar(&cfg.AppKey, "app-key", "", "Datadog app key")
+	fs.StringVar(&cfg.MetricsBackend, "metrics-backend", "datadog", "Metrics backend [datadog, prometheus, cloudwatch, cloudmonitoring, azure, influxdb, graphite, newrelic, wavefront, signalfx, opentsdb, jolokia, file, plugin, m3, elasticsearch, admin]")
+	fs.StringVar(&cfg.MetricsBackendConfig, "metrics-backend-config", "", "JSON config for non-Datadog metrics backends")
+	fs.StringVar(&cfg.MetricsFallbackPolicy, "metrics-fallback-policy", "api-error", "Errors that cause a fallback to the next of several metrics backends [api-error, no-results, any-error]")
+	fs.StringVar(&cfg.EventSinks, "event-sinks", "", "Comma-delimited list of additional event sinks [webhook, slack, pagerduty]")
+	fs.StringVar(&cfg.EventSinksConfig, "event-sinks-config", "", "JSON object of event sink names to event sink configs")
+	fs.IntVar(&cfg.EventDedupWindow, "event-dedup-window", 0, "Time (seconds) during which events identical to the previous event of the same type are suppressed; 0 disables")
+	fs.IntVar(&cfg.EventMinInterval, "event-min-interval", 0, "Minimum time (seconds) between events of the same type; 0 disables")
+	fs.StringVar(&cfg.EventLimits, "event-limits", "", "JSON map of event titles to dedup_window and min_interval (seconds) overrides for events of the type")
+	fs.StringVar(&cfg.OTLPConfig, "otlp-config", "", "JSON config for OTLP export of broker metrics and events")
+	fs.StringVar(&cfg.DogStatsDAddress, "dogstatsd-address", "", "DogStatsD address; if set, events and throttle metrics are written to the local agent rather than the metrics backend")
+	fs.StringVar(&cfg.NetworkTXQuery, "net-tx-query", "avg:system.net.bytes_sent{service:kafka} by {host}", "Datadog query for broker outbound bandwidth by host")
+	fs.StringVar(&cfg.NetworkRXQuery, "net-rx-query", "avg:system.net.bytes_rcvd{service:kafka} by {host}", "Datadog query for broker inbound bandwidth by host")
+	fs.StringVar(&cfg.DiskUsedQuery, "disk-used-query", "", "Optional Datadog query for broker disk used (bytes) by host")
+	fs.StringVar(&cfg.DiskFreeQuery, "disk-free-query", "", "Optional Datadog query for broker disk free (bytes) by host")
+	fs.StringVar(&cfg.CPUQuery, "cpu-query", "", "Optional Datadog query for broker CPU utilization (percent) by host")
+	fs.StringVar(&cfg.LeaderBytesOutQuery, "leader-bytes-out-query", "", "Optional Datadog query for broker client fetch bytes out by host")
+	fs.StringVar(&cfg.ReplicationBytesOutQuery, "replication-bytes-out-query", "", "Optional Datadog query for broker replication bytes out by host")
+	fs.StringVar(&cfg.DiskIOQuery, "disk-io-query", "", "Optional Datadog query for broker disk IO (bytes/s) by host; required for the log-dir-max-rate")
+	fs.StringVar(&cfg.AlterLogDirsLagQuery, "alter-log-dirs-lag-query", "", "Optional Datadog query for the max lag of replicas being moved between broker log dirs by host; required for the log-dir-max-rate")
+	fs.StringVar(&cfg.ConsumerLagQuery, "consumer-lag-query", "", "Optional Datadog query for the lag of critical consumer groups by consumer group; required for the lag-threshold")
+	fs.StringVar(&cfg.ConsumerGroupTag, "consumer-group-tag", "consumer_group", "Datadog tag for consumer group names in the consumer-lag-query series")
+	fs.StringVar(&cfg.BrokerIDTag, "broker-id-tag", "broker_id", "Datadog host tag for broker ID")
+	fs.StringVar(&cfg.InstanceTypeTag, "instance-type-tag", "instance-type", "Datadog tag for instance type")
+	fs.StringVar(&cfg.InstanceTypeResolver, "instance-type-resolver", "", "Optional cloud API used to resolve instance types for brokers missing the Datadog instance type tag [ec2, gce]")
+	fs.StringVar(&cfg.ResolverConfig, "instance-type-resolver-config", "", "JSON config for the instance-type-resolver")
+	fs.IntVar(&cfg.MetricsWindow, "metrics-window", 120, "Time span of metrics required (seconds)")
+	fs.IntVar(&cfg.MetricsTimeout, "metrics-timeout", 0, "Timeout for metrics backend requests (seconds); 0 disables")
+	fs.StringVar(&cfg.BootstrapServers, "bootstrap-servers", "localhost:9092", "Kafka bootstrap servers")
+	fs.StringVar(&cfg.ZKAddr, "zk-addr", "localhost:2181", "ZooKeeper connect string (for broker metadata or rebuild-topic lookups)")
+	fs.StringVar(&cfg.ZKPrefix, "zk-prefix", "", "ZooKeeper namespace prefix")
+	fs.IntVar(&cfg.Interval, "interval", 180, "Autothrottle check interval (seconds)")
+	fs.IntVar(&cfg.ActiveInterval, "active-interval", 0, "Check interval (seconds) while reassignments are ongoing; 0 uses the interval")
+	fs.IntVar(&cfg.IdleInterval, "idle-interval", 0, "Check interval (seconds) while no reassignments are ongoing; 0 uses the interval")
+	fs.Float64Var(&cfg.IntervalJitter, "interval-jitter", 0, "Random variation of each check interval (percent, up to 50)")
+	fs.StringVar(&cfg.APIListen, "api-listen", "localhost:8080", "Admin API listen address:port")
+	fs.StringVar(&cfg.ConfigZKPrefix, "zk-config-prefix", "autothrottle", "ZooKeeper prefix to store autothrottle configuration")
+	fs.StringVar(&cfg.DDEventTags, "dd-event-tags", "", "Comma-delimited list of Datadog event tags")
+	fs.StringVar(&cfg.DDEventAggregationKey, "dd-event-aggregation-key", "", "Datadog event aggregation key; events sharing a key are rolled up into a single event thread")
+	fs.Float64Var(&cfg.DDRateLimit, "dd-rate-limit", 0, "Maximum Datadog API requests per second; 0 disables")
+	fs.IntVar(&cfg.DDHostTagConcurrency, "dd-host-tag-concurrency", 10, "Maximum concurrent Datadog host tags requests")
+	fs.IntVar(&cfg.DDHostTagCacheTTL, "dd-host-tag-cache-ttl", 0, "Time that Datadog broker host tags are cached for (seconds); 0 never expires")
+	fs.StringVar(&cfg.DDAggregation, "dd-aggregation", "", "Optional client-side aggregation of Datadog metric points over the metrics window [avg, max, p95, p99]; if unset, the window avg rollup is used")
+	fs.IntVar(&cfg.DDMaxPointAge, "dd-max-point-age", 0, "Maximum age of the most recent Datadog metric point (seconds), after which brokers are skipped as stale; 0 disables")
+	fs.StringVar(&cfg.DDSite, "dd-site", "", "Datadog site (e.g. datadoghq.eu, us3.datadoghq.com, ddog-gov.com); defaults to datadoghq.com")
+	fs.StringVar(&cfg.DDProxyURL, "dd-proxy-url", "", "HTTP(S) proxy URL for Datadog API requests; defaults to HTTPS_PROXY")
+	fs.StringVar(&cfg.DDCACertFile, "dd-ca-cert-file", "", "PEM CA bundle file trusted for Datadog API requests in addition to the system roots")
+	fs.BoolVar(&cfg.DDLazyValidation, "dd-lazy-validation", false, "Defer Datadog API and app key validation from startup to the first metrics request")
+	fs.BoolVar(&cfg.DDTagsFromScope, "dd-tags-from-scope", false, "Read broker ID and instance type tags from the network query series scopes rather than Datadog host tags")
+	fs.Float64Var(&cfg.MinRate, "min-rate", 10, "Minimum replication throttle rate (MB/s)")
+	fs.Float64Var(&cfg.RateCeiling, "rate-ceiling", 0, "Maximum replication throttle rate (MB/s) of calculated and failure mode rates; 0 disables")
+	fs.Float64Var(&cfg.SourceMaxRate, "max-tx-rate", 90, "Maximum outbound replication throttle rate (as a percentage of available capacity)")
+	fs.Float64Var(&cfg.DestinationMaxRate, "max-rx-rate", 90, "Maximum inbound replication throttle rate (as a percentage of available capacity)")
+	fs.Float64Var(&cfg.CPUThreshold, "cpu-threshold", 0, "Broker CPU utilization (percent) above which throttle rates are reduced; 0 disables")
+	fs.Float64Var(&cfg.ChangeThreshold, "change-threshold", 10, "Required change in replication throttle to trigger an update (percent)")
+	fs.Float64Var(&cfg.MinChange, "min-change", 0, "Required change in replication throttle to trigger an update (MB/s), in addition to the change-threshold")
+	fs.Float64Var(&cfg.MaxStep, "max-step", 0, "Maximum change in replication throttle per interval (as a percentage of the previous throttle); 0 disables")
+	fs.Float64Var(&cfg.SmoothingFactor, "smoothing-factor", 0, "Weight (0-1) of the most recent broker network utilization in a moving average used for throttle calculations; 0 disables")
+	fs.IntVar(&cfg.RampUpIntervals, "ramp-up-intervals", 0, "Number of intervals over which throttles of brokers newly throttled for a reassignment are ramped up to the calculated rate; 0 disables")
+	fs.Float64Var(&cfg.RampUpStart, "ramp-up-start", 25, "Initial throttle rate (as a percentage of the calculated rate) of brokers ramped up with ramp-up-intervals")
+	fs.BoolVar(&cfg.DestinationAware, "destination-aware", false, "Limit source broker throttles to the inbound allowance of their destination brokers, and vice versa")
+	fs.IntVar(&cfg.FailureThreshold, "failure-threshold", 1, "Number of iterations that throttle determinations can fail before applying the failure-policy")
+	fs.StringVar(&cfg.FailurePolicy, "failure-policy", "fixed-rate", "Throttle handling once the failure-threshold is exceeded [fixed-rate, hold, remove]")
+	fs.Float64Var(&cfg.FailureRate, "failure-rate", 0, "Throttle rate (MB/s) applied with the fixed-rate failure-policy; 0 uses the min-rate")
+	fs.StringVar(&cfg.ShutdownPolicy, "shutdown-policy", "freeze", "Throttle handling on SIGTERM or SIGINT [freeze, remove, fixed-rate]")
+	fs.Float64Var(&cfg.ShutdownRate, "shutdown-rate", 0, "Throttle rate (MB/s) applied to reassigning brokers with the fixed-rate shutdown-policy; 0 uses the min-rate")
+	fs.IntVar(&cfg.CriticalFailureThreshold, "critical-failure-threshold", 0, "Number of consecutive metrics fetch failures before writing a critical event; 0 disables")
+	fs.BoolVar(&cfg.TolerateMissingMetrics, "tolerate-missing-metrics", false, "Apply the min-rate only to brokers missing metrics rather than to all brokers")
+	fs.Float64Var(&cfg.LagThreshold, "lag-threshold", 0, "Consumer group lag above which throttle rates are reduced by the lag-backoff; 0 disables")
+	fs.Float64Var(&cfg.LagBackoff, "lag-backoff", 50, "Reduction of throttle rates (percent) while any consumer group lag exceeds the lag-threshold")
+	fs.Float64Var(&cfg.LogDirMaxRate, "log-dir-max-rate", 0, "Maximum throttle rate of replica moves between broker log dirs (as a percentage of available disk IO capacity); 0 disables")
+	fs.StringVar(&cfg.ClientQuotaClients, "client-quota-clients", "", "Comma-delimited list of client IDs whose produce and fetch quotas are managed; requires kafka-native-mode")
+	fs.Float64Var(&cfg.ClientQuotaMaxRate, "client-quota-max-rate", 0, "Maximum combined quota rate of the client-quota-clients (as a percentage of available network capacity); 0 disables")
+	fs.Float64Var(&cfg.ClientQuotaMinRate, "client-quota-min-rate", 1, "Minimum produce and fetch quota rate (MB/s) of each of the client-quota-clients")
+	fs.StringVar(&cfg.AuditTopic, "audit-topic", "", "Kafka topic that an audit record of each replication throttle change is written to as JSON")
+	fs.StringVar(&cfg.RecordMetrics, "record-metrics", "", "Path to a file that the broker metrics fetched for reassignment throttles are appended to as snapshots, for replay with simulate")
+	fs.StringVar(&cfg.capMapFlag, "cap-map", "", "JSON map of instance types to network capacity in MB/s")
+	fs.StringVar(&cfg.CapMapFile, "cap-map-file", "", "Path to a JSON or YAML capacity map file of instance types to network and disk capacity and replication headroom; cap-map values take precedence")
+	fs.StringVar(&cfg.CapacityProfiles, "capacity-profiles", "", "Path to a YAML or JSON capacity profiles file of instance types and broker IDs to network capacity and replication headroom; reloaded when modified")
+	fs.StringVar(&cfg.ThrottleSchedule, "throttle-schedule", "", "Path to a YAML or JSON throttle schedule file of time windows to maximum throttle rates; reloaded when modified")
+	fs.StringVar(&cfg.brokerCapMapFlag, "broker-cap-map", "", "JSON map of broker IDs to network capacity in MB/s; takes precedence over instance type capacities")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Compute, log and post events for throttles without writing any broker or topic configs")
+	fs.BoolVar(&cfg.LeaderElection, "leader-election", false, "Elect a leader among autothrottle instances managing the cluster using a ZooKeeper lock; standbys don't apply throttles until acquiring leadership")
+	fs.StringVar(&cfg.InstanceID, "instance-id", "", "Instance ID used for leader-election; defaults to the hostname and process ID")
+	fs.BoolVar(&cfg.ThrottleMetrics, "throttle-metrics", false, "Write throttle rate and failure mode metrics to the metrics backend each interval [datadog]; always enabled with dogstatsd-address")
+	fs.Int64Var(&cfg.CleanupAfter, "cleanup-after", 60, "Number of intervals after which to issue a global throttle unset if no replication is running")
+	fs.BoolVar(&cfg.SkipAutoDeleteThrottles, "skip-auto-delete-throttles", false, "Skip automatic throttle removal")
+	fs.StringVar(&cfg.Cluster, "cluster", "", "Kafka cluster name; tags events and throttle metrics and is available to metrics query templates as {{.Cluster}}")
+}
+
+// parseCapacityFlags deserializes the capacity map flags and merges in any
+// capacities from the capacity map file.
+func (cfg *configParams) parseCapacityFlags() error {
+	// Deserialize instance-type capacity map.
+	cfg.CapMap = map[string]float64{}
+	if len(cfg.capMapFlag) > 0 {
+		err := json.Unmarshal([]byte(cfg.capMapFlag), &cfg.CapMap)
+		if err != nil {
+			return fmt.Errorf("Error parsing cap-map flag: %s", err)
+		}
+	}
+
+	// Deserialize broker capacity map.
+	cfg.BrokerCapMap = map[int]float64{}
+	if len(cfg.brokerCapMapFlag) > 0 {
+		err := json.Unmarshal([]byte(cfg.brokerCapMapFlag), &cfg.BrokerCapMap)
+		if err != nil {
+			return fmt.Errorf("Error parsing broker-cap-map flag: %s", err)
+		}
+	}
+
+	// Merge in capacities from the capacity map file.
+	if cfg.CapMapFile != "" {
+		c, err := kafkametrics.LoadCapacityMap(cfg.CapMapFile)
+		if err != nil {
+			return err
+		}
+
+		for t, capacity := range c.NetworkCapacities() {
+			if _, exists := cfg.CapMap[t]; !exists {
+				cfg.CapMap[t] = capacity
+			}
+		}
+
+		cfg.HeadroomMap = c.Headrooms()
+	}
+
+	return nil
+}
+
+// run manages the throttles of the cluster configured by the configParams
+// until stop is closed.
+func run(cfg *configParams, stop <-chan struct{}) {
+	// Records are tagged with the cluster name, if set.
+	logger := logging.Default()
+	if cfg.Cluster != "" {
+		logger = logger.With(logging.Fields{"cluster": cfg.Cluster})
+		logger.Info("Managing cluster", logging.Fields{"zookeeper": cfg.ZKAddr})
+	}
+
+	if cfg.DryRun {
+		logger.Info("Dry-run mode: throttles will be logged but not applied", nil)
+	}
+
 	// Init ZK.
 	zk, err := kafkazk.NewHandler(&kafkazk.Config{
-		Connect: Config.ZKAddr,
-		Prefix:  Config.ZKPrefix,
+		Connect: cfg.ZKAddr,
+		Prefix:  cfg.ZKPrefix,
 	})
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "Error connecting to ZooKeeper", logging.Fields{"zookeeper": cfg.ZKAddr, "error": err.Error()})
 	}
 
 	defer zk.Close()
 
-	// Init the admin API.
-	apiConfig := &api.APIConfig{
-		Listen:   Config.APIListen,
-		ZKPrefix: Config.ConfigZKPrefix,
+	// Init a Kafka metrics fetcher.
+	km, backendMetricsWriter, lag, err := newMetricsHandler(cfg)
+	if err != nil {
+		fatal(logger, "Error initializing the metrics backend", logging.Fields{"error": err.Error()})
 	}
 
-	trigger := make(chan struct{}, 1)
-	api.Init(apiConfig, zk, trigger)
-	log.Printf("Admin API: %s\n", Config.APIListen)
+	// Consumer lag is only fetched with a lag threshold set.
+	if cfg.LagThreshold <= 0 {
+		lag = nil
+	} else if lag == nil {
+		fatal(logger, "lag-threshold requires a metrics backend that supports consumer lag [datadog]", nil)
+	}
 
-	// Init a Kafka metrics fetcher.
-	km, err := datadog.NewHandler(&datadog.Config{
-		APIKey:          Config.APIKey,
-		AppKey:          Config.AppKey,
-		NetworkTXQuery:  Config.NetworkTXQuery,
-		NetworkRXQuery:  Config.NetworkRXQuery,
-		BrokerIDTag:     Config.BrokerIDTag,
-		InstanceTypeTag: Config.InstanceTypeTag,
-		MetricsWindow:   Config.MetricsWindow,
-	})
-	if err != nil {
-		log.Fatal(err)
+	if cfg.LogDirMaxRate > 0 && (cfg.DiskIOQuery == "" || cfg.AlterLogDirsLagQuery == "") {
+		fatal(logger, "log-dir-max-rate requires the disk-io-query and alter-log-dirs-lag-query", nil)
 	}
 
 	// Get optional Datadog event tags.
-	t := strings.Split(Config.DDEventTags, ",")
+	t := strings.Split(cfg.DDEventTags, ",")
 	tags := []string{"name:kafka-autothrottle"}
 	for _, tag := range t {
 		tags = append(tags, tag)
 	}
 
+	// Tag events and metrics with the cluster name if set.
+	if cfg.Cluster != "" {
+		tags = append(tags, "cluster:"+cfg.Cluster)
+	}
+
+	// Write events and throttle metrics to a local DogStatsD agent if
+	// configured.
+	km, metricsWriter, err := withDogStatsD(cfg, km, tags)
+	if err != nil {
+		fatal(logger, "Error initializing DogStatsD", logging.Fields{"error": err.Error()})
+	}
+
+	// Otherwise, optionally write throttle metrics to the metrics backend.
+	metricsWriter, err = throttleMetricsWriter(cfg, metricsWriter, backendMetricsWriter, tags)
+	if err != nil {
+		fatal(logger, "Error initializing throttle metrics", logging.Fields{"error": err.Error()})
+	}
+
+	intervals, err := newIntervalSchedule(cfg)
+	if err != nil {
+		fatal(logger, "Error parsing the interval schedule", logging.Fields{"error": err.Error()})
+	}
+
+	// Throttle and interval metrics are always served by the admin API.
+	// Gauges expire if not written for several intervals.
+	gauges := newGaugeRegistry(3 * intervals.max())
+	if metricsWriter != nil {
+		metricsWriter = metricsWriters{gauges, metricsWriter}
+	} else {
+		metricsWriter = gauges
+	}
+
+	// Add any additional event sinks.
+	km, err = withEventSinks(cfg, km)
+	if err != nil {
+		fatal(logger, "Error initializing event sinks", logging.Fields{"error": err.Error()})
+	}
+
+	// Record metrics and event request metrics, served by the admin API.
+	instrumented := kafkametrics.NewInstrumentedHandler(km)
+	km = instrumented
+
+	// Export metrics and events with OTLP if configured.
+	km, err = withOTLPExport(cfg, km)
+	if err != nil {
+		fatal(logger, "Error initializing OTLP export", logging.Fields{"error": err.Error()})
+	}
+
 	// Init the Datadog event writer.
 	echan := make(chan *kafkametrics.Event, 100)
-	go eventWriter(km, echan)
+	eventsHandler, err := withEventLimits(cfg, km)
+	if err != nil {
+		fatal(logger, "Error initializing event limits", logging.Fields{"error": err.Error()})
+	}
+	go eventWriter(eventsHandler, echan)
 
 	// Init an DDEventWriter.
 	events := &DDEventWriter{
-		c:           echan,
-		titlePrefix: eventTitlePrefix,
-		tags:        tags,
+		c:              echan,
+		titlePrefix:    eventTitlePrefix(cfg.Cluster),
+		tags:           tags,
+		aggregationKey: cfg.DDEventAggregationKey,
 	}
 
 	// Default to true on startup in case throttles were set in an autothrottle
@@ -172,58 +481,283 @@ func main() {
 	// Params for the updateReplicationThrottle request.
 
 	limitsCfg := replication.NewLimitsConfig{
-		Minimum:            Config.MinRate,
-		SourceMaximum:      Config.SourceMaxRate,
-		DestinationMaximum: Config.DestinationMaxRate,
-		CapacityMap:        Config.CapMap,
+		Minimum:            cfg.MinRate,
+		Maximum:            cfg.RateCeiling,
+		SourceMaximum:      cfg.SourceMaxRate,
+		DestinationMaximum: cfg.DestinationMaxRate,
+		CPUThreshold:       cfg.CPUThreshold,
+		CapacityMap:        cfg.CapMap,
+		BrokerCapacityMap:  cfg.BrokerCapMap,
+		HeadroomMap:        cfg.HeadroomMap,
+	}
+
+	// Load capacity profiles.
+	var profiles *profilesReloader
+	if cfg.CapacityProfiles != "" {
+		profiles = &profilesReloader{path: cfg.CapacityProfiles}
+		if limitsCfg.Profiles, _, err = profiles.load(); err != nil {
+			fatal(logger, "Error loading capacity profiles", logging.Fields{"path": cfg.CapacityProfiles, "error": err.Error()})
+		}
 	}
 
 	lim, err := replication.NewLimits(limitsCfg)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "Error initializing throttle limits", logging.Fields{"error": err.Error()})
+	}
+
+	// Load the throttle schedule.
+	var schedule *replication.ThrottleSchedule
+	var scheduleFile *scheduleReloader
+	if cfg.ThrottleSchedule != "" {
+		scheduleFile = &scheduleReloader{path: cfg.ThrottleSchedule}
+		if schedule, _, err = scheduleFile.load(); err != nil {
+			fatal(logger, "Error loading the throttle schedule", logging.Fields{"path": cfg.ThrottleSchedule, "error": err.Error()})
+		}
+	}
+
+	failurePolicy, err := replication.ParseFailurePolicy(cfg.FailurePolicy)
+	if err != nil {
+		fatal(logger, "Error parsing the failure policy", logging.Fields{"error": err.Error()})
+	}
+
+	shutdownPolicy, err := replication.ParseShutdownPolicy(cfg.ShutdownPolicy)
+	if err != nil {
+		fatal(logger, "Error parsing the shutdown policy", logging.Fields{"error": err.Error()})
+	}
+
+	// Record metrics snapshots if configured.
+	var recorder io.Writer
+	if cfg.RecordMetrics != "" {
+		f, err := openMetricsRecorder(cfg.RecordMetrics)
+		if err != nil {
+			fatal(logger, "Error opening the metrics recorder", logging.Fields{"path": cfg.RecordMetrics, "error": err.Error()})
+		}
+		defer f.Close()
+		recorder = f
+	}
+
+	// Write throttle change audit records to Kafka if configured.
+	var audit replication.AuditWriter
+	if cfg.AuditTopic != "" {
+		w, err := newKafkaAuditWriter(cfg.BootstrapServers, cfg.AuditTopic, cfg.Cluster)
+		if err != nil {
+			fatal(logger, "Error initializing the audit writer", logging.Fields{"topic": cfg.AuditTopic, "error": err.Error()})
+		}
+		defer w.Close()
+		audit = w
+		logger.Info("Writing throttle audit records", logging.Fields{"topic": cfg.AuditTopic})
+	}
+
+	var quotaClients []string
+	for _, c := range strings.Split(cfg.ClientQuotaClients, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			quotaClients = append(quotaClients, c)
+		}
 	}
 
+	overridePath := api.OverrideRateZnodePath(cfg.ConfigZKPrefix, cfg.Cluster)
+
 	tmCfg := replication.ThrottleManagerConfig{
-		Limits:                 lim,
-		FailureThreshold:       Config.FailureThreshold,
-		ChangeThreshold:        Config.ChangeThreshold,
-		KafkaZK:                zk,
-		KafkaMetrics:           km,
-		KafkaNativeMode:        Config.KafkaNativeMode,
-		KafkaAPIRequestTimeout: Config.KafkaAPIRequestTimeout,
-		Events:                 events,
+		Limits:                   lim,
+		FailureThreshold:         cfg.FailureThreshold,
+		FailurePolicy:            failurePolicy,
+		FailureRate:              cfg.FailureRate,
+		CriticalFailureThreshold: cfg.CriticalFailureThreshold,
+		ShutdownPolicy:           shutdownPolicy,
+		ShutdownRate:             cfg.ShutdownRate,
+		TolerateMissingMetrics:   cfg.TolerateMissingMetrics,
+		ConsumerLag:              lag,
+		LagThreshold:             cfg.LagThreshold,
+		LagBackoff:               cfg.LagBackoff,
+		LogDirMaxRate:            cfg.LogDirMaxRate,
+		ClientQuotaClients:       quotaClients,
+		ClientQuotaMaxRate:       cfg.ClientQuotaMaxRate,
+		ClientQuotaMinRate:       cfg.ClientQuotaMinRate,
+		Schedule:                 schedule,
+		ChangeThreshold:          cfg.ChangeThreshold,
+		MinChange:                cfg.MinChange,
+		MaxStep:                  cfg.MaxStep,
+		SmoothingFactor:          cfg.SmoothingFactor,
+		RampUpIntervals:          cfg.RampUpIntervals,
+		RampUpStart:              cfg.RampUpStart,
+		DestinationAware:         cfg.DestinationAware,
+		DryRun:                   cfg.DryRun,
+		KafkaZK:                  zk,
+		KafkaMetrics:             km,
+		KafkaNativeMode:          cfg.KafkaNativeMode,
+		KafkaAPIRequestTimeout:   cfg.KafkaAPIRequestTimeout,
+		Events:                   events,
+		Metrics:                  metricsWriter,
+		MetricsRecorder:          recorder,
+		Audit:                    audit,
+		OverrideRateZnodePath:    overridePath,
 	}
 
 	throttleManager, err := replication.NewThrottleManager(tmCfg)
 	if err != nil {
-		log.Fatal(err)
+		fatal(logger, "Error initializing the throttle manager", logging.Fields{"error": err.Error()})
 	}
 
 	// Init a KafkaAdmin Client if needed.
-	if Config.KafkaNativeMode {
-		if err := throttleManager.InitKafkaAdmin(Config.BootstrapServers); err != nil {
-			log.Fatal(err)
+	if cfg.KafkaNativeMode {
+		if err := throttleManager.InitKafkaAdmin(cfg.BootstrapServers); err != nil {
+			fatal(logger, "Error connecting to Kafka", logging.Fields{"bootstrap_servers": cfg.BootstrapServers, "error": err.Error()})
 		}
-		log.Printf("Connected to Kafka: %s\n", Config.BootstrapServers)
+		logger.Info("Connected to Kafka", logging.Fields{"bootstrap_servers": cfg.BootstrapServers})
+	}
+
+	// Health checks. The run loop is considered stuck if an iteration
+	// hasn't completed within three of the longest intervals.
+	heartbeat := &health.Heartbeat{}
+	checker := &health.Checker{}
+	checker.SetHeartbeat(heartbeat)
+	checker.AddLivenessCheck("loop", heartbeat.Check(3*intervals.max()))
+	checker.AddReadinessCheck("zookeeper", func() error {
+		if !zk.Ready() {
+			return errors.New("not connected")
+		}
+		return nil
+	})
+	checker.AddReadinessCheck("metrics_backend", instrumented.MetricsError)
+
+	// Init the admin API.
+	apiConfig := &api.APIConfig{
+		Listen:         cfg.APIListen,
+		ZKPrefix:       cfg.ConfigZKPrefix,
+		Cluster:        cfg.Cluster,
+		MetricsHandler: metricsEndpoint{instrumented, gauges},
+		ThrottleStatus: throttleManager,
+		Health:         checker,
+	}
+
+	trigger := make(chan struct{}, 1)
+	api.Init(apiConfig, zk, trigger)
+	logger.Info("Admin API listening", logging.Fields{"listen": cfg.APIListen})
+
+	// Init leader election.
+	var elector *leaderElector
+	if cfg.LeaderElection {
+		id := cfg.InstanceID
+		if id == "" {
+			id = defaultInstanceID()
+		}
+		elector = newLeaderElector(zk, cfg.ConfigZKPrefix, cfg.Cluster, id)
+		logger.Info("Leader election enabled", logging.Fields{"instance_id": id})
 	}
 
 	// Run.
 	var interval int64
-	var ticker = time.NewTicker(time.Duration(Config.Interval) * time.Second)
+
+	// Wait for the next interval, the duration d since the start of the
+	// current interval, or a triggered update. Returns false once stopped,
+	// after applying the shutdown-policy if leading.
+	wait := func(start time.Time, d time.Duration) bool {
+		timer := time.NewTimer(d - time.Since(start))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			interval++
+		case <-trigger:
+		case <-stop:
+			if elector == nil || elector.leader {
+				if err := throttleManager.Shutdown(); err != nil {
+					logger.Error("Error applying the shutdown policy", logging.Fields{"error": err.Error()})
+				}
+			}
+			return false
+		}
+
+		return true
+	}
 
 	// TODO(jamie): refactor this loop.
 	for {
+		start := time.Now()
+
+		// Standbys leave the cluster throttles to the leader.
+		if elector != nil {
+			leader, changed, err := elector.elect()
+			if err != nil {
+				logger.Error("Error checking leadership", logging.Fields{"error": err.Error()})
+			}
+
+			switch {
+			case changed && leader:
+				m := fmt.Sprintf("Instance %s acquired leadership and is applying throttles", elector.id)
+				logger.Info("Leadership acquired", logging.Fields{"instance_id": elector.id})
+				events.Write("Leadership acquired", m)
+				// Throttles may have been set by the previous leader, and any
+				// state from a previous leadership term is stale.
+				knownThrottles = true
+				topicsReplicatingPreviously = newSet()
+				throttleManager.ResetPreviousThrottles()
+			case changed:
+				m := fmt.Sprintf("Instance %s lost leadership and is on standby", elector.id)
+				logger.Warn("Leadership lost", logging.Fields{"instance_id": elector.id})
+				events.Write("Leadership lost", m)
+			}
+
+			if !leader {
+				heartbeat.Beat(time.Now())
+				if !wait(start, intervals.jittered(intervals.base)) {
+					return
+				}
+				continue
+			}
+		}
+
+		// Reload the capacity profiles if modified.
+		if profiles != nil {
+			if p, modified, err := profiles.load(); err != nil {
+				logger.Error("Error reloading capacity profiles", logging.Fields{"error": err.Error()})
+			} else if modified {
+				limitsCfg.Profiles = p
+				if lim, err := replication.NewLimits(limitsCfg); err != nil {
+					logger.Error("Error reloading capacity profiles", logging.Fields{"error": err.Error()})
+				} else {
+					throttleManager.SetLimits(lim)
+					m := fmt.Sprintf("Capacity profiles reloaded from %s", cfg.CapacityProfiles)
+					logger.Info("Capacity profiles reloaded", logging.Fields{"path": cfg.CapacityProfiles})
+					events.Write("Capacity profiles reloaded", m)
+				}
+			}
+		}
+
+		// Reload the throttle schedule if modified.
+		if scheduleFile != nil {
+			if s, modified, err := scheduleFile.load(); err != nil {
+				logger.Error("Error reloading throttle schedule", logging.Fields{"error": err.Error()})
+			} else if modified {
+				throttleManager.SetSchedule(s)
+				m := fmt.Sprintf("Throttle schedule reloaded from %s", cfg.ThrottleSchedule)
+				logger.Info("Throttle schedule reloaded", logging.Fields{"path": cfg.ThrottleSchedule})
+				events.Write("Throttle schedule reloaded", m)
+			}
+		}
 
 		// Get topics undergoing reassignment.
-		if !Config.KafkaNativeMode {
+		var err error
+		switch {
+		case !cfg.KafkaNativeMode:
 			reassignments = zk.GetReassignments()
-		} else {
+		case cfg.KafkaAPIReassignments:
+			// KIP-455 reassignments lookup via the Kafka Admin API.
+			reassignments, err = throttleManager.ListReassignments()
+		default:
 			// KIP-455 compatible reassignments lookup.
 			reassignments, err = zk.ListReassignments()
-			if err != nil {
-				fmt.Printf("error fetching reassignments: %s\n", err)
-				continue
+		}
+
+		// Throttles are left as is until reassignments can be fetched; the
+		// loop still waits for the next interval.
+		if err != nil {
+			logger.Error("Error fetching reassignments", logging.Fields{"error": err.Error()})
+			heartbeat.Beat(time.Now())
+			if !wait(start, intervals.next(len(topicsReplicatingNow) > 0)) {
+				return
 			}
+			continue
 		}
 
 		topicsReplicatingNow = newSet()
@@ -238,7 +772,7 @@ func main() {
 		// Log and write event.
 		if len(topicsDoneReplicating) > 0 {
 			m := fmt.Sprintf("Topics done reassigning: %s", topicsDoneReplicating.keys())
-			log.Println(m)
+			logger.Info("Topics done reassigning", logging.Fields{"topics": topicsDoneReplicating.keys()})
 			events.Write("Topics done reassigning", m)
 		}
 
@@ -282,21 +816,40 @@ func main() {
 		topicsReplicatingPreviously = topicsReplicatingNow.copy()
 
 		// Check if a global throttle override was configured.
-		overrideCfg, err := throttlestore.FetchThrottleOverride(zk, api.OverrideRateZnodePath)
+		overrideCfg, err := throttlestore.FetchThrottleOverride(zk, overridePath)
 		if err != nil {
-			log.Println(err)
+			logger.Error("Error fetching the throttle override", logging.Fields{"error": err.Error()})
 		}
 
 		// Fetch all broker-specific overrides.
-		bo, err := throttlestore.FetchBrokerOverrides(zk, api.OverrideRateZnodePath)
+		bo, err := throttlestore.FetchBrokerOverrides(zk, overridePath)
 		if err != nil {
-			log.Println(err)
+			logger.Error("Error fetching broker throttle overrides", logging.Fields{"error": err.Error()})
+		}
+
+		// Remove any overrides past their ttl, reverting to dynamic throttles.
+		// Expired broker overrides are set to 0 and purged below.
+		now := time.Now()
+		if expired, err := throttlestore.ExpireThrottleOverride(zk, overridePath, overrideCfg, now); err != nil {
+			logger.Error("Error expiring the throttle override", logging.Fields{"error": err.Error()})
+		} else if expired {
+			logger.Info("Global throttle override expired", nil)
+		}
+
+		for id, o := range bo {
+			path := fmt.Sprintf("%s/%d", overridePath, id)
+			if expired, err := throttlestore.ExpireThrottleOverride(zk, path, &o.Config, now); err != nil {
+				logger.Error("Error expiring broker throttle override", logging.Fields{"broker_id": id, "error": err.Error()})
+			} else if expired {
+				logger.Info("Broker throttle override expired", logging.Fields{"broker_id": id})
+				bo[id] = o
+			}
 		}
 
 		// Get the maps of brokers handling reassignments.
 		rb, err := replication.GetReassigningBrokers(reassignments, zk)
 		if err != nil {
-			log.Println(err)
+			logger.Error("Error fetching reassigning brokers", logging.Fields{"error": err.Error()})
 		}
 
 		throttleManager.SetBrokerOverrides(bo)
@@ -304,7 +857,15 @@ func main() {
 
 		// If topics are being reassigned, update the replication throttle.
 		if len(topicsReplicatingNow) > 0 {
-			log.Printf("Topics with ongoing reassignments: %s\n", topicsReplicatingNow.keys())
+			logger.Info("Topics with ongoing reassignments", logging.Fields{"topics": topicsReplicatingNow.keys()})
+
+			// Remove throttles left by any reassignments that completed while
+			// others are ongoing.
+			if len(topicsDoneReplicating) > 0 && !cfg.SkipAutoDeleteThrottles {
+				if _, err := throttleManager.CleanupCompletedThrottles(topicsDoneReplicating.keys()); err != nil {
+					logger.Error("Error removing completed reassignment throttles", logging.Fields{"error": err.Error()})
+				}
+			}
 
 			// Update the throttleManager.
 			throttleManager.SetOverrideRate(overrideCfg.Rate)
@@ -312,7 +873,7 @@ func main() {
 
 			err = throttleManager.UpdateReplicationThrottle()
 			if err != nil {
-				log.Println(err)
+				logger.Error("Error updating replication throttles", logging.Fields{"error": err.Error()})
 			} else {
 				// Set knownThrottles.
 				knownThrottles = true
@@ -339,7 +900,7 @@ func main() {
 			var err error
 			otl, err := throttleManager.GetTopicsWithThrottledBrokers()
 			if err != nil {
-				log.Printf("Error fetching topic states: %s\n", err)
+				logger.Error("Error fetching topic states", logging.Fields{"error": err.Error()})
 			}
 
 			throttleManager.SetOverrideThrottleLists(otl)
@@ -362,7 +923,7 @@ func main() {
 
 			// Update throttles.
 			if err := throttleManager.UpdateOverrideThrottles(); err != nil {
-				log.Println(err)
+				logger.Error("Error updating broker override throttles", logging.Fields{"error": err.Error()})
 			}
 
 			// If we're updating throttles and the active count (those not marked for
@@ -372,11 +933,20 @@ func main() {
 			}
 		}
 
+		// Throttle any replica moves between broker log dirs.
+		if err := throttleManager.UpdateLogDirThrottles(); err != nil {
+			logger.Error("Error updating log dir throttles", logging.Fields{"error": err.Error()})
+		}
+
+		// Update the quotas of any managed clients.
+		if err := throttleManager.UpdateClientQuotas(); err != nil {
+			logger.Error("Error updating client quotas", logging.Fields{"error": err.Error()})
+		}
+
 		// Remove and delete any broker-specific overrides set to 0.
 		if errs := throttleManager.PurgeOverrideThrottles(); errs != nil {
-			log.Println("Error removing persisted broker throttle overrides")
 			for i := range errs {
-				log.Println(errs[i])
+				logger.Error("Error removing persisted broker throttle overrides", logging.Fields{"error": errs[i].Error()})
 			}
 		}
 
@@ -398,7 +968,7 @@ func main() {
 		// Capture all the current conditions:
 
 		// Are there throttles eligible to be cleared?
-		var throttlesToClear = knownThrottles || interval == Config.CleanupAfter
+		var throttlesToClear = knownThrottles || interval == cfg.CleanupAfter
 
 		// Are any topics being reassigned?
 		var topicsReassigning bool
@@ -415,11 +985,11 @@ func main() {
 		// Next steps according to the various conditions:
 
 		if !topicsReassigning {
-			log.Println("No topics undergoing reassignment")
+			logger.Info("No topics undergoing reassignment", nil)
 		}
 
 		if !topicsReassigning && throttlesToClear && brokerOverridesSet {
-			log.Println("One or more brokers level override are set; automatic throttle removal will be skipped")
+			logger.Warn("One or more brokers level override are set; automatic throttle removal will be skipped", logging.Fields{"brokers_overridden": len(activeOverrideBrokers)})
 		}
 
 		// If there's previously set throttles but no topics reassigning nor
@@ -428,13 +998,13 @@ func main() {
 			// Reset the interval count.
 			interval = 0
 
-			if Config.SkipAutoDeleteThrottles {
-				log.Println("There may be throttles eligible for removal, but skipping automatic removal since skip-auto-delete-throttles is set")
+			if cfg.SkipAutoDeleteThrottles {
+				logger.Warn("There may be throttles eligible for removal, but skipping automatic removal since skip-auto-delete-throttles is set", nil)
 			} else {
 				// Remove all the broker + topic throttle configs.
-				err := throttleManager.RemoveAllThrottles()
+				_, err := throttleManager.RemoveAllThrottles()
 				if err != nil {
-					log.Printf("Error removing throttles: %s\n", err.Error())
+					logger.Error("Error removing throttles", logging.Fields{"error": err.Error()})
 				} else {
 					// Only set knownThrottles to false if we've removed all
 					// without error.
@@ -447,19 +1017,38 @@ func main() {
 
 				// Remove any configured throttle overrides if AutoRemove is true.
 				if overrideCfg.AutoRemove {
-					err := throttlestore.StoreThrottleOverride(zk, api.OverrideRateZnodePath, throttlestore.ThrottleOverrideConfig{})
+					err := throttlestore.StoreThrottleOverride(zk, overridePath, throttlestore.ThrottleOverrideConfig{})
 					if err != nil {
-						log.Println(err)
+						logger.Error("Error removing the throttle override", logging.Fields{"error": err.Error()})
 					} else {
-						log.Println("Global throttle override removed")
+						logger.Info("Global throttle override removed", nil)
 					}
 				}
 			}
 		}
-		select {
-		case <-ticker.C:
-			interval++
-		case <-trigger:
+
+		writeIntervalMetrics(metricsWriter, intervalStats{
+			duration:          time.Since(start),
+			overrideRate:      overrideCfg.Rate,
+			brokerOverrides:   len(activeOverrideBrokers),
+			brokersThrottled:  throttleManager.ThrottledBrokerCount(),
+			topicsReassigning: len(topicsReplicatingNow),
+		})
+
+		logger.Debug("Interval complete", logging.Fields{
+			"interval":           interval,
+			"duration_ms":        time.Since(start).Milliseconds(),
+			"topics_reassigning": len(topicsReplicatingNow),
+			"brokers_throttled":  throttleManager.ThrottledBrokerCount(),
+		})
+
+		heartbeat.Beat(time.Now())
+
+		next := intervals.next(len(topicsReplicatingNow) > 0)
+		logger.Debug("Next interval", logging.Fields{"next_interval_s": next.Seconds()})
+
+		if !wait(start, next) {
+			return
 		}
 	}
 
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/admin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/azure"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/cloudmonitoring"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/cloudwatch"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/datadog"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/dogstatsd"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/elasticsearch"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/file"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/graphite"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/influxdb"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/jolokia"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/m3"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/newrelic"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/opentsdb"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/otlp"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/pagerduty"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/plugin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/prometheus"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/signalfx"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/slack"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/wavefront"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/webhook"
+)
+
+// newMetricsHandler initializes a kafkametrics.Handler for the configured
+// metrics backends. Backends other than Datadog are configured with the
+// backend specific JSON config supplied via the metrics-backend-config flag.
+// If several comma-delimited backends are configured, a CompositeHandler is
+// returned that fetches metrics using the backends in order of priority and
+// posts events to all backends. The metrics-backend-config flag is then
+// a JSON object of backend names to backend configs. The first backend that
+// supports writing metrics is returned as a replication.MetricsWriter, and
+// the first that supports fetching consumer lag as a kafkametrics.LagHandler,
+// if any.
+func newMetricsHandler(cfg *configParams) (kafkametrics.Handler, replication.MetricsWriter, kafkametrics.LagHandler, error) {
+	backends := strings.Split(cfg.MetricsBackend, ",")
+	if len(backends) == 1 {
+		h, err := newBackendHandler(cfg, backends[0], cfg.MetricsBackendConfig)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		w, _ := h.(replication.MetricsWriter)
+		l, _ := h.(kafkametrics.LagHandler)
+		return withMetricsTimeout(cfg, h), w, l, nil
+	}
+
+	configs := map[string]json.RawMessage{}
+	if cfg.MetricsBackendConfig != "" {
+		if err := json.Unmarshal([]byte(cfg.MetricsBackendConfig), &configs); err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing metrics-backend-config flag: %s", err)
+		}
+	}
+
+	policy, err := kafkametrics.ParseFailurePolicy(cfg.MetricsFallbackPolicy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var handlers []kafkametrics.Handler
+	var writer replication.MetricsWriter
+	var lag kafkametrics.LagHandler
+	for _, name := range backends {
+		name = strings.TrimSpace(name)
+		h, err := newBackendHandler(cfg, name, string(configs[name]))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error initializing %s metrics backend: %s", name, err)
+		}
+		if w, ok := h.(replication.MetricsWriter); ok && writer == nil {
+			writer = w
+		}
+		if l, ok := h.(kafkametrics.LagHandler); ok && lag == nil {
+			lag = l
+		}
+		handlers = append(handlers, withMetricsTimeout(cfg, h))
+	}
+
+	h, err := kafkametrics.NewCompositeHandler(&kafkametrics.CompositeConfig{
+		Metrics:       handlers,
+		Events:        handlers,
+		FailurePolicy: policy,
+	})
+
+	return h, writer, lag, err
+}
+
+// withMetricsTimeout wraps the Handler with a kafkametrics.TimeoutHandler if
+// a timeout is configured via the metrics-timeout flag. Timeouts are returned
+// as API errors, allowing a fallback to the next metrics backend.
+func withMetricsTimeout(cfg *configParams, h kafkametrics.Handler) kafkametrics.Handler {
+	if cfg.MetricsTimeout <= 0 {
+		return h
+	}
+
+	return kafkametrics.NewTimeoutHandler(h, time.Duration(cfg.MetricsTimeout)*time.Second)
+}
+
+// newBackendHandler initializes a kafkametrics.Handler for the named metrics
+// backend with the backend specific JSON config.
+func newBackendHandler(cfg *configParams, name, config string) (kafkametrics.Handler, error) {
+	switch name {
+	case "datadog":
+		resolver, err := newInstanceTypeResolver(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return datadog.NewHandler(&datadog.Config{
+			APIKey:                   cfg.APIKey,
+			AppKey:                   cfg.AppKey,
+			Site:                     cfg.DDSite,
+			ProxyURL:                 cfg.DDProxyURL,
+			CACertFile:               cfg.DDCACertFile,
+			LazyValidation:           cfg.DDLazyValidation,
+			NetworkTXQuery:           cfg.NetworkTXQuery,
+			NetworkRXQuery:           cfg.NetworkRXQuery,
+			DiskUsedQuery:            cfg.DiskUsedQuery,
+			DiskFreeQuery:            cfg.DiskFreeQuery,
+			CPUQuery:                 cfg.CPUQuery,
+			LeaderBytesOutQuery:      cfg.LeaderBytesOutQuery,
+			ReplicationBytesOutQuery: cfg.ReplicationBytesOutQuery,
+			DiskIOQuery:              cfg.DiskIOQuery,
+			AlterLogDirsLagQuery:     cfg.AlterLogDirsLagQuery,
+			ConsumerLagQuery:         cfg.ConsumerLagQuery,
+			ConsumerGroupTag:         cfg.ConsumerGroupTag,
+			BrokerIDTag:              cfg.BrokerIDTag,
+			InstanceTypeTag:          cfg.InstanceTypeTag,
+			MetricsWindow:            cfg.MetricsWindow,
+			Timeout:                  cfg.MetricsTimeout,
+			RateLimit:                cfg.DDRateLimit,
+			HostTagConcurrency:       cfg.DDHostTagConcurrency,
+			HostTagCacheTTL:          cfg.DDHostTagCacheTTL,
+			TagsFromScope:            cfg.DDTagsFromScope,
+			MaxPointAge:              cfg.DDMaxPointAge,
+			Aggregation:              cfg.DDAggregation,
+			Cluster:                  cfg.Cluster,
+			InstanceTypeResolver:     resolver,
+		})
+	case "prometheus":
+		c := &prometheus.Config{
+			BrokerIDLabel:     cfg.BrokerIDTag,
+			InstanceTypeLabel: cfg.InstanceTypeTag,
+			MetricsWindow:     cfg.MetricsWindow,
+			Cluster:           cfg.Cluster,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return prometheus.NewHandler(c)
+	case "cloudwatch":
+		c := &cloudwatch.Config{
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return cloudwatch.NewHandler(c)
+	case "cloudmonitoring":
+		c := &cloudmonitoring.Config{
+			BrokerIDLabel: cfg.BrokerIDTag,
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return cloudmonitoring.NewHandler(c)
+	case "azure":
+		c := &azure.Config{
+			BrokerIDTag:   cfg.BrokerIDTag,
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return azure.NewHandler(c)
+	case "influxdb":
+		c := &influxdb.Config{
+			BrokerIDTag:     cfg.BrokerIDTag,
+			InstanceTypeTag: cfg.InstanceTypeTag,
+			MetricsWindow:   cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return influxdb.NewHandler(c)
+	case "graphite":
+		c := &graphite.Config{
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return graphite.NewHandler(c)
+	case "newrelic":
+		c := &newrelic.Config{
+			BrokerIDAttribute:     cfg.BrokerIDTag,
+			InstanceTypeAttribute: cfg.InstanceTypeTag,
+			MetricsWindow:         cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return newrelic.NewHandler(c)
+	case "wavefront":
+		c := &wavefront.Config{
+			BrokerIDTag:     cfg.BrokerIDTag,
+			InstanceTypeTag: cfg.InstanceTypeTag,
+			MetricsWindow:   cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return wavefront.NewHandler(c)
+	case "signalfx":
+		c := &signalfx.Config{
+			BrokerIDDimension:     cfg.BrokerIDTag,
+			InstanceTypeDimension: cfg.InstanceTypeTag,
+			MetricsWindow:         cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return signalfx.NewHandler(c)
+	case "opentsdb":
+		c := &opentsdb.Config{
+			BrokerIDTag:     cfg.BrokerIDTag,
+			InstanceTypeTag: cfg.InstanceTypeTag,
+			MetricsWindow:   cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return opentsdb.NewHandler(c)
+	case "jolokia":
+		c := &jolokia.Config{
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return jolokia.NewHandler(c)
+	case "file":
+		c := &file.Config{}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return file.NewHandler(c)
+	case "plugin":
+		c := &plugin.Config{}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return plugin.NewHandler(c)
+	case "m3":
+		c := &m3.Config{
+			Config: prometheus.Config{
+				BrokerIDLabel:     cfg.BrokerIDTag,
+				InstanceTypeLabel: cfg.InstanceTypeTag,
+				MetricsWindow:     cfg.MetricsWindow,
+			},
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return m3.NewHandler(c)
+	case "elasticsearch":
+		c := &elasticsearch.Config{
+			MetricsWindow: cfg.MetricsWindow,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		return elasticsearch.NewHandler(c)
+	case "admin":
+		c := &admin.Config{
+			Timeout: cfg.MetricsTimeout,
+		}
+		if err := parseFlagConfig("metrics-backend-config", config, c); err != nil {
+			return nil, err
+		}
+		ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: cfg.BootstrapServers})
+		if err != nil {
+			return nil, err
+		}
+		c.Admin = ka
+		return admin.NewHandler(c)
+	default:
+		return nil, fmt.Errorf("unknown metrics backend: %s", name)
+	}
+}
+
+// withDogStatsD returns a kafkametrics.Handler that fetches metrics using
+// the metrics handler and posts events to the DogStatsD address configured
+// via the dogstatsd-address flag, along with a replication.MetricsWriter for
+// throttle metrics. The metrics handler and a nil MetricsWriter are returned
+// if no address is configured.
+func withDogStatsD(cfg *configParams, km kafkametrics.Handler, tags []string) (kafkametrics.Handler, replication.MetricsWriter, error) {
+	if cfg.DogStatsDAddress == "" {
+		return km, nil, nil
+	}
+
+	dsd, err := dogstatsd.NewHandler(&dogstatsd.Config{
+		Address: cfg.DogStatsDAddress,
+		Tags:    tags,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := kafkametrics.NewCompositeHandler(&kafkametrics.CompositeConfig{
+		Metrics: []kafkametrics.Handler{km},
+		Events:  []kafkametrics.Handler{dsd},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logging.Info("Writing events and throttle metrics to DogStatsD", logging.Fields{"address": cfg.DogStatsDAddress})
+
+	return h, dsd, nil
+}
+
+// taggedMetricsWriter is a replication.MetricsWriter that adds tags to all
+// metrics written.
+type taggedMetricsWriter struct {
+	w    replication.MetricsWriter
+	tags []string
+}
+
+// Gauge writes a gauge metric with the writer tags and any additional tags.
+func (t taggedMetricsWriter) Gauge(name string, value float64, tags []string) error {
+	return t.w.Gauge(name, value, append(append([]string{}, t.tags...), tags...))
+}
+
+// throttleMetricsWriter returns the replication.MetricsWriter for throttle
+// metrics. The DogStatsD writer is used if configured, otherwise the metrics
+// backend writer with the tags if the throttle-metrics flag is set. The
+// backend writer may be nil where no metrics backend supports writing
+// metrics.
+func throttleMetricsWriter(cfg *configParams, dsd, backend replication.MetricsWriter, tags []string) (replication.MetricsWriter, error) {
+	switch {
+	case dsd != nil:
+		return dsd, nil
+	case !cfg.ThrottleMetrics:
+		return nil, nil
+	case backend == nil:
+		return nil, fmt.Errorf("throttle-metrics requires a metrics backend that supports writing metrics [datadog] or a dogstatsd-address")
+	}
+
+	logging.Info("Writing throttle metrics to the metrics backend", nil)
+
+	return taggedMetricsWriter{w: backend, tags: tags}, nil
+}
+
+// Interval metric names.
+const (
+	// The duration of the most recent interval in seconds.
+	loopDurationMetric = "autothrottle.loop.duration_seconds"
+	// The global throttle override rate in MB/s, 0 if unset.
+	overrideRateMetric = "autothrottle.override.rate"
+	// The number of active broker throttle overrides.
+	brokerOverridesMetric = "autothrottle.override.brokers"
+	// The number of brokers throttled for reassignments.
+	brokersThrottledMetric = "autothrottle.brokers_throttled"
+	// The number of topics being reassigned.
+	topicsReassigningMetric = "autothrottle.topics_reassigning"
+)
+
+// intervalStats describes an interval of the autothrottle run loop.
+type intervalStats struct {
+	duration          time.Duration
+	overrideRate      int
+	brokerOverrides   int
+	brokersThrottled  int
+	topicsReassigning int
+}
+
+// writeIntervalMetrics writes the intervalStats as gauges with the
+// replication.MetricsWriter, logging any errors.
+func writeIntervalMetrics(w replication.MetricsWriter, s intervalStats) {
+	for name, v := range map[string]float64{
+		loopDurationMetric:      s.duration.Seconds(),
+		overrideRateMetric:      float64(s.overrideRate),
+		brokerOverridesMetric:   float64(s.brokerOverrides),
+		brokersThrottledMetric:  float64(s.brokersThrottled),
+		topicsReassigningMetric: float64(s.topicsReassigning),
+	} {
+		if err := w.Gauge(name, v, nil); err != nil {
+			logging.Error("Error writing interval metric", logging.Fields{"metric": name, "error": err.Error()})
+		}
+	}
+}
+
+// withEventSinks returns a kafkametrics.Handler that fetches metrics using
+// the metrics handler and posts events to both the metrics handler and any
+// event sinks configured via the event-sinks flag. Event sinks are configured
+// with the event-sinks-config flag, a JSON object of sink names to sink
+// configs. The metrics handler is returned as is if no sinks are configured.
+func withEventSinks(cfg *configParams, km kafkametrics.Handler) (kafkametrics.Handler, error) {
+	if cfg.EventSinks == "" {
+		return km, nil
+	}
+
+	configs := map[string]json.RawMessage{}
+	if cfg.EventSinksConfig != "" {
+		if err := json.Unmarshal([]byte(cfg.EventSinksConfig), &configs); err != nil {
+			return nil, fmt.Errorf("error parsing event-sinks-config flag: %s", err)
+		}
+	}
+
+	events := []kafkametrics.Handler{km}
+	for _, name := range strings.Split(cfg.EventSinks, ",") {
+		name = strings.TrimSpace(name)
+		h, err := newEventSink(name, string(configs[name]))
+		if err != nil {
+			return nil, fmt.Errorf("error initializing %s event sink: %s", name, err)
+		}
+		events = append(events, h)
+	}
+
+	return kafkametrics.NewCompositeHandler(&kafkametrics.CompositeConfig{
+		Metrics: []kafkametrics.Handler{km},
+		Events:  events,
+	})
+}
+
+// newEventSink initializes a kafkametrics.Handler for the named event sink
+// with the sink specific JSON config.
+func newEventSink(name, config string) (kafkametrics.Handler, error) {
+	switch name {
+	case "webhook":
+		c := &webhook.Config{}
+		if err := parseFlagConfig("event-sinks-config", config, c); err != nil {
+			return nil, err
+		}
+		return webhook.NewHandler(c)
+	case "slack":
+		c := &slack.Config{}
+		if err := parseFlagConfig("event-sinks-config", config, c); err != nil {
+			return nil, err
+		}
+		return slack.NewHandler(c)
+	case "pagerduty":
+		c := &pagerduty.Config{}
+		if err := parseFlagConfig("event-sinks-config", config, c); err != nil {
+			return nil, err
+		}
+		return pagerduty.NewHandler(c)
+	default:
+		return nil, fmt.Errorf("unknown event sink: %s", name)
+	}
+}
+
+// parseFlagConfig deserializes the JSON config supplied via the named flag
+// into the provided config, overriding any defaults already populated.
+func parseFlagConfig(flag, config string, c interface{}) error {
+	if config == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(config), c); err != nil {
+		return fmt.Errorf("error parsing %s flag: %s", flag, err)
+	}
+
+	return nil
+}
+
+// newInstanceTypeResolver initializes the kafkametrics.InstanceTypeResolver
+// selected with the instance-type-resolver flag, configured with the JSON
+// config supplied via the instance-type-resolver-config flag. A nil resolver
+// is returned if none is selected.
+func newInstanceTypeResolver(cfg *configParams) (kafkametrics.InstanceTypeResolver, error) {
+	switch cfg.InstanceTypeResolver {
+	case "":
+		return nil, nil
+	case "ec2":
+		c := &cloudwatch.ResolverConfig{}
+		if err := parseFlagConfig("instance-type-resolver-config", cfg.ResolverConfig, c); err != nil {
+			return nil, err
+		}
+		return cloudwatch.NewInstanceTypeResolver(c)
+	case "gce":
+		c := &cloudmonitoring.ResolverConfig{}
+		if err := parseFlagConfig("instance-type-resolver-config", cfg.ResolverConfig, c); err != nil {
+			return nil, err
+		}
+		return cloudmonitoring.NewInstanceTypeResolver(c)
+	}
+
+	return nil, fmt.Errorf("unknown instance type resolver %s", cfg.InstanceTypeResolver)
+}
+
+// withOTLPExport returns a kafkametrics.Handler that exports broker metrics
+// fetched and events posted with the provided handler to an OpenTelemetry
+// collector, configured via the otlp-config flag. The handler is returned as
+// is if OTLP export isn't configured.
+func withOTLPExport(cfg *configParams, km kafkametrics.Handler) (kafkametrics.Handler, error) {
+	if cfg.OTLPConfig == "" {
+		return km, nil
+	}
+
+	c := &otlp.Config{
+		ServiceName: "autothrottle",
+		ErrorHandler: func(err error) {
+			logging.Error("Error exporting metrics", logging.Fields{"error": err.Error()})
+		},
+	}
+
+	if err := json.Unmarshal([]byte(cfg.OTLPConfig), c); err != nil {
+		return nil, fmt.Errorf("error parsing otlp-config flag: %s", err)
+	}
+
+	return otlp.NewHandler(c, km)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMetricsHandlerComposite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	if err := os.WriteFile(path, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backend names are trimmed before their configs are looked up.
+	cfg := &configParams{
+		MetricsBackend:        "file, file",
+		MetricsBackendConfig:  fmt.Sprintf(`{"file": {"Path": %q}}`, path),
+		MetricsFallbackPolicy: "api-error",
+	}
+
+	if _, _, _, err := newMetricsHandler(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.MetricsBackendConfig = `{"file": {"Path": 1}}`
+	if _, _, _, err := newMetricsHandler(cfg); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestParseFlagConfig(t *testing.T) {
+	c := struct{ Path, Format string }{Format: "json"}
+
+	if err := parseFlagConfig("metrics-backend-config", `{"Path": "/tmp/metrics"}`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Defaults not in the config are kept.
+	if c.Path != "/tmp/metrics" || c.Format != "json" {
+		t.Errorf("Unexpected config %+v\n", c)
+	}
+
+	err := parseFlagConfig("event-sinks-config", `{`, &c)
+	if err == nil || err.Error() != "error parsing event-sinks-config flag: unexpected end of JSON input" {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
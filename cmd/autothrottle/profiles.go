@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+)
+
+// profilesReloader loads capacity profiles from a file, reloading them once
+// the file is modified.
+type profilesReloader struct {
+	path    string
+	modTime time.Time
+}
+
+// load returns the capacity profiles and whether the file was modified since
+// it was last loaded. Unmodified files aren't read.
+func (r *profilesReloader) load() (replication.CapacityProfiles, bool, error) {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return replication.CapacityProfiles{}, false, err
+	}
+
+	if fi.ModTime().Equal(r.modTime) {
+		return replication.CapacityProfiles{}, false, nil
+	}
+
+	p, err := replication.LoadCapacityProfiles(r.path)
+	if err != nil {
+		return p, false, err
+	}
+
+	r.modTime = fi.ModTime()
+
+	return p, true, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+)
+
+// scheduleReloader loads a throttle schedule from a file, reloading it once
+// the file is modified.
+type scheduleReloader struct {
+	path    string
+	modTime time.Time
+}
+
+// load returns the throttle schedule and whether the file was modified since
+// it was last loaded. Unmodified files aren't read.
+func (r *scheduleReloader) load() (*replication.ThrottleSchedule, bool, error) {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if fi.ModTime().Equal(r.modTime) {
+		return nil, false, nil
+	}
+
+	s, err := replication.LoadThrottleSchedule(r.path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.modTime = fi.ModTime()
+
+	return s, true, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/replication"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// logEventWriter is a replication.EventWriter that logs events.
+type logEventWriter struct{}
+
+// Write logs the event.
+func (logEventWriter) Write(title, m string) {
+	logging.Info("Event", logging.Fields{"title": title, "text": m})
+}
+
+// simulate replays the metrics snapshots file through the throttle
+// calculation configured by the configParams, writing the throttle
+// decisions to w. No ZooKeeper, Kafka or metrics backend connections are
+// made.
+func simulate(cfg *configParams, path string, w io.Writer) error {
+	snapshots, err := replication.LoadMetricsSnapshots(path)
+	if err != nil {
+		return fmt.Errorf("Error loading metrics snapshots: %s", err)
+	}
+
+	limitsCfg := replication.NewLimitsConfig{
+		Minimum:            cfg.MinRate,
+		Maximum:            cfg.RateCeiling,
+		SourceMaximum:      cfg.SourceMaxRate,
+		DestinationMaximum: cfg.DestinationMaxRate,
+		CPUThreshold:       cfg.CPUThreshold,
+		CapacityMap:        cfg.CapMap,
+		BrokerCapacityMap:  cfg.BrokerCapMap,
+		HeadroomMap:        cfg.HeadroomMap,
+	}
+
+	if cfg.CapacityProfiles != "" {
+		if limitsCfg.Profiles, err = replication.LoadCapacityProfiles(cfg.CapacityProfiles); err != nil {
+			return err
+		}
+	}
+
+	lim, err := replication.NewLimits(limitsCfg)
+	if err != nil {
+		return err
+	}
+
+	var schedule *replication.ThrottleSchedule
+	if cfg.ThrottleSchedule != "" {
+		if schedule, err = replication.LoadThrottleSchedule(cfg.ThrottleSchedule); err != nil {
+			return err
+		}
+	}
+
+	failurePolicy, err := replication.ParseFailurePolicy(cfg.FailurePolicy)
+	if err != nil {
+		return err
+	}
+
+	tm, err := replication.NewThrottleManager(replication.ThrottleManagerConfig{
+		Limits:                 lim,
+		FailureThreshold:       cfg.FailureThreshold,
+		FailurePolicy:          failurePolicy,
+		FailureRate:            cfg.FailureRate,
+		TolerateMissingMetrics: cfg.TolerateMissingMetrics,
+		Schedule:               schedule,
+		ChangeThreshold:        cfg.ChangeThreshold,
+		MinChange:              cfg.MinChange,
+		MaxStep:                cfg.MaxStep,
+		SmoothingFactor:        cfg.SmoothingFactor,
+		RampUpIntervals:        cfg.RampUpIntervals,
+		RampUpStart:            cfg.RampUpStart,
+		DestinationAware:       cfg.DestinationAware,
+		Events:                 logEventWriter{},
+	})
+	if err != nil {
+		return err
+	}
+
+	decisions := tm.Simulate(snapshots)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tBROKER\tROLE\tPREVIOUS\tRATE\tSOURCE\tAPPLIED")
+	for _, d := range decisions {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.2f\t%.2f\t%s\t%t\n",
+			d.Time.Format("2006-01-02T15:04:05Z07:00"), d.BrokerID, d.Role, d.Previous, d.Rate, d.Source, d.Applied)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%d snapshot(s), %d throttle decision(s)\n", len(snapshots), len(decisions))
+
+	return nil
+}
+
+// openMetricsRecorder opens the record-metrics file for appending
+// MetricsSnapshots.
+func openMetricsRecorder(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+
+	"github.com/spf13/cobra"
+)
+
+// applyPollInterval is the interval at which in-flight reassignments are
+// checked for completion between batches.
+var applyPollInterval = 10 * time.Second
+
+// addApplyFlags adds the reassignment submission flags to the command.
+func addApplyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("apply", false, "Submit the output maps as partition reassignments via the Kafka AlterPartitionReassignments API")
+	cmd.Flags().Int("apply-batch-size", 0, "Max number of partition reassignments submitted at a time with --apply; each batch is submitted once the previous completes (0 submits each map at once)")
+	cmd.Flags().Int("wait-timeout", 0, "Fail --apply if the reassignments of a batch are still in progress after this many minutes (0 waits indefinitely)")
+	cmd.Flags().Bool("yes", false, "Submit reassignments with --apply without a confirmation prompt")
+}
+
+type applyParams struct {
+	apply       bool
+	batchSize   int
+	waitTimeout int
+	yes         bool
+}
+
+func applyParamsFromCmd(cmd *cobra.Command) (params applyParams) {
+	apply, _ := cmd.Flags().GetBool("apply")
+	params.apply = apply
+	batchSize, _ := cmd.Flags().GetInt("apply-batch-size")
+	params.batchSize = batchSize
+	waitTimeout, _ := cmd.Flags().GetInt("wait-timeout")
+	params.waitTimeout = waitTimeout
+	yes, _ := cmd.Flags().GetBool("yes")
+	params.yes = yes
+	return params
+}
+
+func (c applyParams) validate() error {
+	switch {
+	case c.batchSize < 0:
+		return fmt.Errorf("\n[ERROR] --apply-batch-size must be >= 0")
+	case c.waitTimeout < 0:
+		return fmt.Errorf("\n[ERROR] --wait-timeout must be >= 0")
+	}
+	return nil
+}
+
+// applyMaps submits the partition reassignments of the PartitionMaps, if
+// enabled by the applyParams. Maps, such as phases, are submitted in order,
+// each once the reassignments of the previous have completed. Unless
+// confirmation is skipped, the submission must be confirmed via the
+// confirm Reader.
+func applyMaps(params applyParams, ka kafkaadmin.KafkaAdmin, pms []*mapper.PartitionMap, confirm io.Reader) error {
+	if !params.apply {
+		return nil
+	}
+
+	var batches []kafkaadmin.ReplicaAssignments
+	var partitions int
+	for _, pm := range pms {
+		batches = append(batches, reassignmentBatches(pm, params.batchSize)...)
+		partitions += len(pm.Partitions)
+	}
+
+	if len(batches) == 0 {
+		fmt.Println("\nNo partition reassignments to apply")
+		return nil
+	}
+
+	if !params.yes {
+		fmt.Printf("\nSubmit %d partition reassignment(s) in %d batch(es)? [y/N]: ", partitions, len(batches))
+		answer, _ := bufio.NewReader(confirm).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println("Reassignment not applied")
+			return nil
+		}
+	}
+
+	fmt.Println("\nApplying reassignments:")
+
+	ctx := context.Background()
+
+	for i, batch := range batches {
+		// Each batch is submitted once the previous has completed.
+		if i > 0 {
+			if err := waitForReassignments(ctx, ka, batches[i-1], time.Duration(params.waitTimeout)*time.Minute); err != nil {
+				return fmt.Errorf("batch %d of %d: %s", i, len(batches), err)
+			}
+		}
+
+		if err := ka.AlterPartitionReassignments(ctx, batch); err != nil {
+			return fmt.Errorf("batch %d of %d: %s", i+1, len(batches), err)
+		}
+
+		fmt.Printf("%sbatch %d of %d: submitted %d partition reassignment(s)\n",
+			indent, i+1, len(batches), batchSize(batch))
+	}
+
+	return nil
+}
+
+// reassignmentBatches splits the partitions of the PartitionMap into
+// ReplicaAssignments of at most size partitions each. A size of 0 returns a
+// single batch.
+func reassignmentBatches(pm *mapper.PartitionMap, size int) []kafkaadmin.ReplicaAssignments {
+	var batches []kafkaadmin.ReplicaAssignments
+	var batch kafkaadmin.ReplicaAssignments
+	var n int
+
+	for _, p := range pm.Partitions {
+		if batch == nil || (size > 0 && n == size) {
+			batch = kafkaadmin.ReplicaAssignments{}
+			batches = append(batches, batch)
+			n = 0
+		}
+
+		if batch[p.Topic] == nil {
+			batch[p.Topic] = map[int32][]int32{}
+		}
+
+		replicas := make([]int32, len(p.Replicas))
+		for i, id := range p.Replicas {
+			replicas[i] = int32(id)
+		}
+
+		batch[p.Topic][int32(p.Partition)] = replicas
+		n++
+	}
+
+	return batches
+}
+
+// batchSize returns the number of partitions in the ReplicaAssignments.
+func batchSize(ra kafkaadmin.ReplicaAssignments) int {
+	var n int
+	for _, partitions := range ra {
+		n += len(partitions)
+	}
+	return n
+}
+
+// waitForReassignments blocks until none of the partitions in the
+// ReplicaAssignments are being reassigned, returning an error if the context
+// is done or reassignments are still in progress after the timeout. A timeout
+// of 0 waits indefinitely.
+func waitForReassignments(ctx context.Context, ka kafkaadmin.KafkaAdmin, ra kafkaadmin.ReplicaAssignments, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		reassigning, err := ka.ListPartitionReassignments(ctx)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %s waiting on partition reassignments", timeout)
+			}
+			return err
+		}
+
+		var pending int
+		for topic, partitions := range ra {
+			for id := range partitions {
+				if _, exists := reassigning[topic][id]; exists {
+					pending++
+				}
+			}
+		}
+
+		if pending == 0 {
+			return nil
+		}
+
+		fmt.Printf("%swaiting on %d partition reassignment(s)\n", indent, pending)
+
+		select {
+		case <-time.After(applyPollInterval):
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("timed out after %s with %d partition reassignment(s) in progress", timeout, pending)
+			}
+			return ctx.Err()
+		}
+	}
+}
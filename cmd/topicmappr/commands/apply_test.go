@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin/stub"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+// applyStub is a kafkaadmin.KafkaAdmin recording submitted reassignments.
+type applyStub struct {
+	stub.Client
+	submitted *[]kafkaadmin.ReplicaAssignments
+}
+
+func (s applyStub) AlterPartitionReassignments(_ context.Context, ra kafkaadmin.ReplicaAssignments) error {
+	*s.submitted = append(*s.submitted, ra)
+	return nil
+}
+
+// reassigningStub is a kafkaadmin.KafkaAdmin reporting every partition of
+// the test map as being reassigned.
+type reassigningStub struct {
+	stub.Client
+}
+
+func (s reassigningStub) ListPartitionReassignments(context.Context) (kafkaadmin.PartitionReassignments, error) {
+	return kafkaadmin.PartitionReassignments{
+		"test":  {0: {}, 1: {}},
+		"test2": {0: {}},
+	}, nil
+}
+
+func testApplyMap() *mapper.PartitionMap {
+	pm := mapper.NewPartitionMap()
+	pm.Partitions = mapper.PartitionList{
+		{Topic: "test", Partition: 0, Replicas: []int{1001, 1002}},
+		{Topic: "test", Partition: 1, Replicas: []int{1002, 1003}},
+		{Topic: "test2", Partition: 0, Replicas: []int{1003, 1001}},
+	}
+
+	return pm
+}
+
+func TestReassignmentBatches(t *testing.T) {
+	pm := testApplyMap()
+
+	if batches := reassignmentBatches(pm, 0); len(batches) != 1 || batchSize(batches[0]) != 3 {
+		t.Errorf("Expected a single batch of 3 partitions, got %v", batches)
+	}
+
+	batches := reassignmentBatches(pm, 2)
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(batches))
+	}
+
+	if batchSize(batches[0]) != 2 || batchSize(batches[1]) != 1 {
+		t.Errorf("Expected batch sizes 2 and 1, got %d and %d", batchSize(batches[0]), batchSize(batches[1]))
+	}
+
+	if r := batches[1]["test2"][0]; len(r) != 2 || r[0] != 1003 || r[1] != 1001 {
+		t.Errorf("Expected replicas [1003 1001], got %v", r)
+	}
+
+	if batches := reassignmentBatches(mapper.NewPartitionMap(), 2); len(batches) != 0 {
+		t.Errorf("Expected no batches, got %v", batches)
+	}
+}
+
+func TestApplyMaps(t *testing.T) {
+	var submitted []kafkaadmin.ReplicaAssignments
+	ka := applyStub{Client: stub.NewClient(), submitted: &submitted}
+	pms := []*mapper.PartitionMap{testApplyMap()}
+
+	// Not enabled.
+	if err := applyMaps(applyParams{}, ka, pms, strings.NewReader("y\n")); err != nil || len(submitted) != 0 {
+		t.Fatalf("Expected no submissions, got %v (%v)", submitted, err)
+	}
+
+	// Declined.
+	if err := applyMaps(applyParams{apply: true}, ka, pms, strings.NewReader("n\n")); err != nil || len(submitted) != 0 {
+		t.Fatalf("Expected no submissions, got %v (%v)", submitted, err)
+	}
+
+	// Confirmed.
+	if err := applyMaps(applyParams{apply: true, batchSize: 2}, ka, pms, strings.NewReader("y\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(submitted) != 2 {
+		t.Fatalf("Expected 2 batches submitted, got %d", len(submitted))
+	}
+
+	// Confirmation skipped.
+	submitted = nil
+	if err := applyMaps(applyParams{apply: true, yes: true}, ka, pms, strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(submitted) != 1 {
+		t.Errorf("Expected 1 batch submitted, got %d", len(submitted))
+	}
+}
+
+func TestWaitForReassignments(t *testing.T) {
+	ra := reassignmentBatches(testApplyMap(), 0)[0]
+
+	// Completed.
+	if err := waitForReassignments(context.Background(), stub.NewClient(), ra, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Timed out.
+	expected := "timed out after 10ms with 3 partition reassignment(s) in progress"
+	if err := waitForReassignments(context.Background(), reassigningStub{}, ra, 10*time.Millisecond); err == nil || err.Error() != expected {
+		t.Errorf("Expected error '%s', got %v", expected, err)
+	}
+
+	// Cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := waitForReassignments(ctx, reassigningStub{}, ra, 0); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
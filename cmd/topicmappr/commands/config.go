@@ -33,6 +33,37 @@ func sanitizeInput(cmd *cobra.Command) {
 	}
 }
 
+// internalTopicRegex matches Kafka internal topics, such as __consumer_offsets.
+var internalTopicRegex = regexp.MustCompile(`^__`)
+
+// addTopicExclusionFlags adds the topic exclusion flags to the command.
+func addTopicExclusionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("exclude-topics", "", "Exclude topics (comma delim. list of names and/or regex patterns)")
+	cmd.Flags().String("topics-exclude", "", "Exclude topics")
+	cmd.Flags().MarkDeprecated("topics-exclude", "use --exclude-topics")
+	cmd.Flags().Bool("exclude-internal", false, "Exclude Kafka internal topics (those prefixed with '__')")
+	cmd.Flags().Bool("exclude-reassigning", false, "Exclude topics with partition reassignments in progress")
+}
+
+// topicExclusionsFromCmd returns the topic exclusion patterns set via the
+// --exclude-topics, --topics-exclude and --exclude-internal flags.
+func topicExclusionsFromCmd(cmd *cobra.Command) []*regexp.Regexp {
+	var s []string
+	for _, f := range []string{"exclude-topics", "topics-exclude"} {
+		if v, _ := cmd.Flags().GetString(f); v != "" {
+			s = append(s, v)
+		}
+	}
+
+	out := topicRegex(strings.Join(s, ","))
+
+	if internal, _ := cmd.Flags().GetBool("exclude-internal"); internal {
+		out = append(out, internalTopicRegex)
+	}
+
+	return out
+}
+
 // topicRegex takes a string of csv values and returns a []*regexp.Regexp.
 // The values are either a string literal and become ^value$ or are regex and
 // compiled then added.
@@ -40,7 +71,7 @@ func topicRegex(s string) []*regexp.Regexp {
 	var out []*regexp.Regexp
 
 	// Update string literals to ^value$ regex.
-	topicNames := strings.Split(s, ",")
+	topicNames := splitTopics(s)
 	for n, t := range topicNames {
 		if !containsRegex(t) {
 			topicNames[n] = fmt.Sprintf(`^%s$`, t)
@@ -51,8 +82,7 @@ func topicRegex(s string) []*regexp.Regexp {
 	for _, t := range topicNames {
 		r, err := regexp.Compile(t)
 		if err != nil {
-			fmt.Printf("Invalid topic regex: %s\n", t)
-			os.Exit(1)
+			exitOnErr(fmt.Errorf("Invalid topic regex: %s", t))
 		}
 
 		out = append(out, r)
@@ -61,6 +91,32 @@ func topicRegex(s string) []*regexp.Regexp {
 	return out
 }
 
+// splitTopics splits a comma delimited list of topic names and/or regex
+// patterns. Commas within regex repetition braces, such as in \d{1,3}, aren't
+// treated as delimiters.
+func splitTopics(s string) []string {
+	var out []string
+	var depth, start int
+
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(out, s[start:])
+}
+
 // initZooKeeper inits a ZooKeeper connection if one is needed.
 // Scenarios that would require a connection:
 //   - the --use-meta flag is true (default), which requests
@@ -119,8 +175,7 @@ func brokerStringToSlice(s string) []int {
 		i, err := strconv.Atoi(strings.TrimSpace(p))
 		// Err and exit on bad input.
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitOnErr(err)
 		}
 
 		if ids[i] {
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSplitTopics(t *testing.T) {
+	tests := map[string][]string{
+		"test1":                     {"test1"},
+		"test1,test2":               {"test1", "test2"},
+		`^metrics\..*,logs-\d{1,3}`: {`^metrics\..*`, `logs-\d{1,3}`},
+	}
+
+	for in, expected := range tests {
+		out := splitTopics(in)
+		if len(out) != len(expected) {
+			t.Fatalf("[%s] Expected %v, got %v", in, expected, out)
+		}
+
+		for i := range out {
+			if out[i] != expected[i] {
+				t.Errorf("[%s] Expected %v, got %v", in, expected, out)
+			}
+		}
+	}
+}
+
+func TestTopicExclusionsFromCmd(t *testing.T) {
+	cmd := &cobra.Command{}
+	addTopicExclusionFlags(cmd)
+	cmd.Flags().Set("exclude-topics", "test1")
+	cmd.Flags().Set("topics-exclude", `^connect-.*`)
+	cmd.Flags().Set("exclude-internal", "true")
+
+	re := topicExclusionsFromCmd(cmd)
+
+	for _, topic := range []string{"test1", "connect-configs", "__consumer_offsets"} {
+		var matched bool
+		for _, r := range re {
+			if r.MatchString(topic) {
+				matched = true
+			}
+		}
+
+		if !matched {
+			t.Errorf("Expected topic %s to be excluded", topic)
+		}
+	}
+
+	for _, r := range re {
+		if r.MatchString("test10") {
+			t.Errorf("Unexpected exclusion of test10 by %s", r)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+
+	"github.com/spf13/cobra"
+)
+
+var evacuateCmd = &cobra.Command{
+	Use:   "evacuate",
+	Short: "Relocate all partition replicas off of one or more brokers",
+	Long: `evacuate drains the brokers provided via the --evacuate flag, replacing
+them in every replica set of the target topics with the least utilized brokers that
+satisfy placement constraints. Replica sets not referencing evacuated brokers are
+left as-is. Destination brokers default to all currently mapped brokers and can be
+scoped via the --brokers flag.`,
+	Run: evacuate,
+}
+
+func init() {
+	rootCmd.AddCommand(evacuateCmd)
+
+	evacuateCmd.Flags().String("evacuate", "", "Broker list to relocate all partition replicas from")
+	evacuateCmd.Flags().String("topics", ".*", "Evacuate topics (comma delim. list) by lookup in ZooKeeper")
+	evacuateCmd.Flags().String("topics-file", "", "Path to a newline delimited file of topics to evacuate in place of the --topics default")
+	evacuateCmd.Flags().String("map-file", "", "Evacuate brokers from a partition map read from a JSON file in place of --topics")
+	addTopicExclusionFlags(evacuateCmd)
+	evacuateCmd.Flags().String("out-path", "", "Path to write output map files to")
+	evacuateCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	evacuateCmd.Flags().String("brokers", "-1", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster); evacuated brokers are always excluded")
+	evacuateCmd.Flags().Bool("force-rebuild", false, "Forces a complete map rebuild, placing all replicas from scratch rather than minimizing changes to the current map")
+	evacuateCmd.Flags().String("placement", "count", "Partition placement strategy: [count, storage]")
+	evacuateCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	evacuateCmd.Flags().Bool("relax-rack-awareness", false, "Place replicas in racks already used by the replica set where rack constraints can't be satisfied, minimizing same-rack colocations")
+	evacuateCmd.Flags().Int("max-partitions-per-broker", 0, "Maximum number of partition replicas of the target topics placed on any broker (0 is unlimited)")
+	evacuateCmd.Flags().Int("max-topic-partitions-per-broker", 0, "Maximum number of partition replicas of any one topic placed on any broker (0 is unlimited)")
+	evacuateCmd.Flags().String("partition-cap-policy", "spill", "Policy where a partition cap would be exceeded: [spill, error]; spill places replicas on other brokers, error fails")
+	evacuateCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
+	evacuateCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage placement")
+	evacuateCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage placement)")
+	evacuateCmd.Flags().String("metrics-file", "", "Path to a JSON file of broker storage and partition size metrics to use in place of ZooKeeper (when using storage placement)")
+	evacuateCmd.Flags().Bool("plan-summary", false, "Print the current and projected size of each broker's partitions (requires partition size metrics; always printed with storage placement or --phase-max-gb)")
+	evacuateCmd.Flags().Bool("skip-no-ops", true, "Skip no-op partition assigments")
+	evacuateCmd.Flags().Bool("optimize-leadership", false, "Rebalance all broker leader/follower ratios")
+	evacuateCmd.Flags().Int("phase-max-partitions", 0, "Split the reassignment into sequential phases of at most this many partitions (0 disables phasing by count)")
+	evacuateCmd.Flags().Float64("phase-max-gb", 0.00, "Split the reassignment into sequential phases of at most this many gigabytes of estimated data movement (0 disables phasing by size)")
+	addApplyFlags(evacuateCmd)
+
+	// Required.
+	evacuateCmd.MarkFlagRequired("evacuate")
+}
+
+func evacuate(cmd *cobra.Command, _ []string) {
+	sanitizeInput(cmd)
+	apply := applyParamsFromCmd(cmd)
+	if err := apply.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
+	params := rebuildParamsFromCmd(cmd)
+	// Broker metadata is required for rack awareness.
+	params.useMetadata = true
+
+	evac, _ := cmd.Flags().GetString("evacuate")
+	params.evacuateBrokers = brokerStringToSlice(evac)
+
+	if err := params.applyTopicsFile(); err != nil {
+		logError(fmt.Errorf("\n[ERROR] %s", err), nil)
+		defaultsAndExit()
+	}
+
+	err := params.validate()
+	if err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
+	// Init kafkaadmin client.
+	bs := cmd.Parent().Flag("kafka-addr").Value.String()
+	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
+	if err != nil {
+		exitOnErr(err)
+	}
+
+	// ZooKeeper init.
+	zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
+	kafkaPrefix := cmd.Parent().Flag("zk-prefix").Value.String()
+	metricsPrefix := cmd.Flag("zk-metrics-prefix").Value.String()
+	zk, err := initZooKeeper(zkAddr, kafkaPrefix, metricsPrefix)
+	if err != nil {
+		exitOnErr(err)
+	}
+
+	defer zk.Close()
+
+	maps, errs := runEvacuate(params, ka, zk)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	outPath := cmd.Flag("out-path").Value.String()
+	outFile := cmd.Flag("out-file").Value.String()
+	writeMaps(outPath, outFile, maps)
+
+	// Submit the reassignments if configured.
+	if err := applyMaps(apply, ka, maps, os.Stdin); err != nil {
+		exitOnErr(err)
+	}
+}
+
+// runEvacuate performs a rebuild where the evacuated brokers are marked for
+// replacement, then verifies that no replicas remain on the evacuated
+// brokers.
+func runEvacuate(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handler) ([]*mapper.PartitionMap, []error) {
+	maps, errs := runRebuild(params, ka, zk)
+	if len(maps) > 0 {
+		errs = append(errs, evacuationErrors(maps[len(maps)-1], params.evacuateBrokers)...)
+	}
+
+	return maps, errs
+}
+
+// excludeBrokers takes a broker list as provided via the --brokers flag, a
+// list of broker IDs to exclude, the current BrokerMap and the BrokerMetaMap.
+// The -1 and -2 placeholders are expanded to the brokers in the BrokerMap and
+// BrokerMetaMap, respectively, and the resulting broker list is returned
+// without the excluded IDs.
+func excludeBrokers(bl []int, exclude []int, b mapper.BrokerMap, bm mapper.BrokerMetaMap) []int {
+	excluded := map[int]struct{}{}
+	for _, id := range exclude {
+		excluded[id] = struct{}{}
+	}
+
+	ids := map[int]struct{}{}
+	for _, id := range bl {
+		switch id {
+		case -1:
+			for id := range b {
+				if id != mapper.StubBrokerID {
+					ids[id] = struct{}{}
+				}
+			}
+		case -2:
+			for id := range bm {
+				ids[id] = struct{}{}
+			}
+		default:
+			ids[id] = struct{}{}
+		}
+	}
+
+	var out []int
+	for id := range ids {
+		if _, ok := excluded[id]; !ok {
+			out = append(out, id)
+		}
+	}
+
+	sort.Ints(out)
+
+	return out
+}
+
+// evacuationErrors returns an error for each evacuated broker still holding
+// replicas in the PartitionMap.
+func evacuationErrors(pm *mapper.PartitionMap, evacuated []int) []error {
+	remaining := map[int]int{}
+	for _, p := range pm.Partitions {
+		for _, id := range p.Replicas {
+			remaining[id]++
+		}
+	}
+
+	var errs []error
+	for _, id := range evacuated {
+		if n := remaining[id]; n > 0 {
+			errs = append(errs, fmt.Errorf("broker %d still holds %d replica(s) after evacuation", id, n))
+		}
+	}
+
+	return errs
+}
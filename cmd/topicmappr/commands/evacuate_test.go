@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestExcludeBrokers(t *testing.T) {
+	b := mapper.BrokerMap{
+		mapper.StubBrokerID: &mapper.Broker{ID: mapper.StubBrokerID},
+		1001:                &mapper.Broker{ID: 1001},
+		1002:                &mapper.Broker{ID: 1002},
+		1003:                &mapper.Broker{ID: 1003},
+	}
+
+	bm := mapper.BrokerMetaMap{
+		1001: &mapper.BrokerMeta{},
+		1002: &mapper.BrokerMeta{},
+		1003: &mapper.BrokerMeta{},
+		1004: &mapper.BrokerMeta{},
+	}
+
+	tests := []struct {
+		bl       []int
+		expected []int
+	}{
+		{bl: []int{-1}, expected: []int{1001, 1003}},
+		{bl: []int{-2}, expected: []int{1001, 1003, 1004}},
+		{bl: []int{1002, 1004}, expected: []int{1004}},
+	}
+
+	for i, test := range tests {
+		out := excludeBrokers(test.bl, []int{1002}, b, bm)
+		if len(out) != len(test.expected) {
+			t.Fatalf("[test %d] Expected %v, got %v", i, test.expected, out)
+		}
+
+		for j := range out {
+			if out[j] != test.expected[j] {
+				t.Errorf("[test %d] Expected %v, got %v", i, test.expected, out)
+			}
+		}
+	}
+}
+
+func TestEvacuationErrors(t *testing.T) {
+	pm, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1003]},
+		{"topic":"test","partition":1,"replicas":[1003,1001]}]}`)
+
+	if errs := evacuationErrors(pm, []int{1002}); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	if errs := evacuationErrors(pm, []int{1001, 1002}); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(errs))
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// logger writes errors, warnings and info messages as records to stderr if
+// --log-format is json, otherwise it's nil and messages are printed with the
+// rest of the command output.
+var logger *logging.Logger
+
+// initLogging initializes the logger from the --log-format flag.
+func initLogging(cmd *cobra.Command, _ []string) error {
+	format, _ := cmd.Flags().GetString("log-format")
+
+	switch format {
+	case logging.FormatText:
+		logger = nil
+		return nil
+	case logging.FormatJSON:
+	default:
+		return fmt.Errorf("[ERROR] --log-format must be either 'text' or 'json'")
+	}
+
+	l, err := logging.New(logging.Config{Output: os.Stderr, Format: format})
+	if err != nil {
+		return err
+	}
+	logger = l
+
+	return nil
+}
+
+// logMessage trims a message of whitespace and any leading level prefix,
+// such as "[ERROR] ", for its record.
+func logMessage(m string) string {
+	m = strings.TrimSpace(m)
+	for _, p := range []string{"[ERROR] ", "[WARN] ", "[INFO] "} {
+		m = strings.TrimPrefix(m, p)
+	}
+
+	return m
+}
+
+// logError logs the error.
+func logError(err error, f logging.Fields) {
+	if logger == nil {
+		fmt.Println(err)
+		return
+	}
+
+	logger.Error(logMessage(err.Error()), f)
+}
+
+// logWarn logs a warning. In text mode, warnings are printed by
+// handleOverridableErrs.
+func logWarn(m string, f logging.Fields) {
+	if logger != nil {
+		logger.Warn(logMessage(m), f)
+	}
+}
+
+// logInfo logs an info message.
+func logInfo(m string, f logging.Fields) {
+	if logger == nil {
+		fmt.Println(m)
+		return
+	}
+
+	logger.Info(logMessage(m), f)
+}
+
+// exitOnErr logs the errors and exits 1.
+func exitOnErr(errs ...error) {
+	for _, err := range errs {
+		logError(err, nil)
+	}
+	os.Exit(1)
+}
@@ -2,9 +2,13 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
@@ -59,6 +63,10 @@ func getPartitionMaps(ka kafkaadmin.KafkaAdmin, topics []string) (*mapper.Partit
 	pm, _ := mapper.PartitionMapFromTopicStates(tState)
 	sort.Sort(pm.Partitions)
 
+	if len(pm.Partitions) == 0 {
+		return nil, fmt.Errorf("No topics found matching: %s", strings.Join(topics, ", "))
+	}
+
 	return pm, nil
 }
 
@@ -76,6 +84,70 @@ func ensureBrokerMetrics(bm mapper.BrokerMap, bmm mapper.BrokerMetaMap) []error
 	return errs
 }
 
+// metricsFile holds broker storage and partition size metrics read from a
+// file in place of ZooKeeper. The structures are the same as those persisted
+// in ZooKeeper by metricsfetcher.
+type metricsFile struct {
+	BrokerMetrics mapper.BrokerMetricsMap `json:"brokermetrics"`
+	PartitionMeta mapper.PartitionMetaMap `json:"partitionmeta"`
+}
+
+// loadMetricsFile reads a metricsFile from path, checking the age of the file
+// against the tolerated metrics age parameter.
+func loadMetricsFile(path string, maxAge int) (*metricsFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading metrics file: %s\n", err)
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading metrics file: %s\n", err)
+	}
+
+	if age := time.Since(info.ModTime()); age > time.Duration(maxAge)*time.Minute {
+		return nil, fmt.Errorf("Metrics file is older than allowed: %s\n", age)
+	}
+
+	return readMetricsFile(f)
+}
+
+func readMetricsFile(r io.Reader) (*metricsFile, error) {
+	m := &metricsFile{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling metrics file: %s\n", err)
+	}
+
+	switch {
+	case len(m.BrokerMetrics) == 0:
+		return nil, fmt.Errorf("No broker metrics in metrics file\n")
+	case len(m.PartitionMeta) == 0:
+		return nil, fmt.Errorf("No partition meta in metrics file\n")
+	}
+
+	return m, nil
+}
+
+// applyBrokerMetrics populates the storage free of each broker in the
+// BrokerMetaMap from the BrokerMetricsMap. Brokers without metrics are marked
+// as having incomplete metrics.
+func applyBrokerMetrics(bm mapper.BrokerMetaMap, metrics mapper.BrokerMetricsMap) []error {
+	var errs []error
+	for id := range bm {
+		m, exists := metrics[id]
+		if !exists {
+			errs = append(errs, fmt.Errorf("Metrics not found for broker %d", id))
+			bm[id].MetricsIncomplete = true
+		} else {
+			bm[id].StorageFree = m.StorageFree
+		}
+	}
+
+	return errs
+}
+
 // getPartitionMeta returns a map of topic, partition metadata persisted in
 // ZooKeeper (via an external mechanism*). This is primarily partition size
 // metrics data used for the storage placement strategy.
@@ -83,6 +155,22 @@ func getPartitionMeta(zk kafkazk.Handler) (mapper.PartitionMetaMap, error) {
 	return zk.GetAllPartitionMeta()
 }
 
+// removeReassigningTopics removes topics with partition reassignments in
+// progress from the PartitionMap, returning a []string of removed topics.
+func removeReassigningTopics(ka kafkaadmin.KafkaAdmin, pm *mapper.PartitionMap) ([]string, error) {
+	reassignments, err := ka.ListPartitionReassignments(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("Error listing partition reassignments: %s", err)
+	}
+
+	var patterns []*regexp.Regexp
+	for t := range reassignments {
+		patterns = append(patterns, regexp.MustCompile(`^`+regexp.QuoteMeta(t)+`$`))
+	}
+
+	return removeTopics(pm, patterns), nil
+}
+
 // removeTopics takes a PartitionMap and []*regexp.Regexp of topic name patters.
 // Any topic names that match any provided pattern will be removed from the
 // PartitionMap and a []string of topics that were found and removed is returned.
@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"context"
 	"regexp"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin/stub"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 	"github.com/DataDog/kafka-kit/v4/mapper"
 )
@@ -61,3 +65,83 @@ func mergePartitionMaps(pms ...*mapper.PartitionMap) *mapper.PartitionMap {
 
 	return pm
 }
+
+func TestReadMetricsFile(t *testing.T) {
+	in := `{"brokermetrics":{"1001":{"StorageFree":100}},"partitionmeta":{"test":{"0":{"Size":10}}}}`
+
+	m, err := readMetricsFile(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.BrokerMetrics[1001].StorageFree != 100 {
+		t.Errorf("Expected storage free of 100, got %f", m.BrokerMetrics[1001].StorageFree)
+	}
+
+	if size, _ := m.PartitionMeta.Size(mapper.Partition{Topic: "test", Partition: 0}); size != 10 {
+		t.Errorf("Expected partition size of 10, got %f", size)
+	}
+
+	// Missing partition meta.
+	if _, err := readMetricsFile(strings.NewReader(`{"brokermetrics":{"1001":{"StorageFree":100}}}`)); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestApplyBrokerMetrics(t *testing.T) {
+	bm := mapper.BrokerMetaMap{
+		1001: &mapper.BrokerMeta{},
+		1002: &mapper.BrokerMeta{},
+	}
+
+	errs := applyBrokerMetrics(bm, mapper.BrokerMetricsMap{1001: &mapper.BrokerMetrics{StorageFree: 100}})
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(errs))
+	}
+
+	if bm[1001].StorageFree != 100 || bm[1001].MetricsIncomplete {
+		t.Errorf("Unexpected broker meta %+v", bm[1001])
+	}
+
+	if !bm[1002].MetricsIncomplete {
+		t.Error("Expected broker 1002 to have incomplete metrics")
+	}
+}
+
+// reassignmentsStub is a kafkaadmin.KafkaAdmin returning fixed partition
+// reassignments.
+type reassignmentsStub struct {
+	stub.Client
+	reassignments kafkaadmin.PartitionReassignments
+}
+
+func (s reassignmentsStub) ListPartitionReassignments(context.Context) (kafkaadmin.PartitionReassignments, error) {
+	return s.reassignments, nil
+}
+
+func TestRemoveReassigningTopics(t *testing.T) {
+	zk := kafkazk.NewZooKeeperStub()
+	pm1, _ := zk.GetPartitionMap("test")
+	pm2, _ := zk.GetPartitionMap("test2")
+	pm := mergePartitionMaps(pm1, pm2)
+
+	ka := reassignmentsStub{
+		Client: stub.NewClient(),
+		reassignments: kafkaadmin.PartitionReassignments{
+			"test2": {0: {Replicas: []int32{1001, 1002}}},
+		},
+	}
+
+	removed, err := removeReassigningTopics(ka, pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(removed) != 1 || removed[0] != "test2" {
+		t.Errorf("Expected removed topic test2, got %v", removed)
+	}
+
+	if topics := pm.Topics(); len(topics) != 1 || topics[0] != "test" {
+		t.Errorf("Unexpected remaining topics %v", topics)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"sort"
+	"text/tabwriter"
 
 	"github.com/DataDog/kafka-kit/v4/mapper"
 
@@ -55,8 +56,7 @@ func printMapChanges(pm1, pm2 *mapper.PartitionMap) {
 		t1, t2 := pm1.Partitions[i].Topic, pm2.Partitions[i].Topic
 		p1, p2 := pm1.Partitions[i].Partition, pm2.Partitions[i].Partition
 		if t1 != t2 || p1 != p2 {
-			fmt.Println("Unexpected partition map order")
-			os.Exit(1)
+			exitOnErr(fmt.Errorf("Unexpected partition map order"))
 		}
 	}
 
@@ -173,6 +173,297 @@ func printBrokerAssignmentStats(pm1, pm2 *mapper.PartitionMap, bm1, bm2 mapper.B
 	return errs
 }
 
+// printScorecard prints the partitions, leaders and, if storageBased, the
+// storage free of each broker before and after the reassignment, along with
+// the range and std. deviation of each. The 'before' metrics are of brokers
+// in the input PartitionMap, the 'after' metrics of brokers not marked for
+// replacement.
+func printScorecard(pm1, pm2 *mapper.PartitionMap, bm1, bm2 mapper.BrokerMap, storageBased bool) {
+	u1, u2 := pm1.UseStats(), pm2.UseStats()
+
+	// Brokers before and after.
+	before := map[int]struct{}{}
+	for id := range u1 {
+		before[id] = struct{}{}
+	}
+
+	after := map[int]struct{}{}
+	for id, b := range bm2 {
+		if id != mapper.StubBrokerID && !b.Replace {
+			after[id] = struct{}{}
+		}
+	}
+	for id := range u2 {
+		after[id] = struct{}{}
+	}
+
+	var ids []int
+	for id := range before {
+		ids = append(ids, id)
+	}
+	for id := range after {
+		if _, exists := before[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	partitions := func(u mapper.BrokerUseStatsMap, id int) float64 {
+		if s, exists := u[id]; exists {
+			return float64(s.Leader + s.Follower)
+		}
+		return 0
+	}
+
+	leaders := func(u mapper.BrokerUseStatsMap, id int) float64 {
+		if s, exists := u[id]; exists {
+			return float64(s.Leader)
+		}
+		return 0
+	}
+
+	storageFree := func(bm mapper.BrokerMap, id int) float64 {
+		if b, exists := bm[id]; exists {
+			return b.StorageFree
+		}
+		return 0
+	}
+
+	fmt.Println("\nScorecard:")
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := indent + "BROKER\tPARTITIONS\tLEADERS"
+	if storageBased {
+		header += "\tSTORAGE FREE"
+	}
+	fmt.Fprintln(tw, header)
+
+	var p1, p2, l1, l2, s1, s2 []float64
+
+	for _, id := range ids {
+		if id == mapper.StubBrokerID {
+			continue
+		}
+
+		_, inBefore := before[id]
+		_, inAfter := after[id]
+
+		if inBefore {
+			p1 = append(p1, partitions(u1, id))
+			l1 = append(l1, leaders(u1, id))
+			s1 = append(s1, storageFree(bm1, id))
+		}
+
+		if inAfter {
+			p2 = append(p2, partitions(u2, id))
+			l2 = append(l2, leaders(u2, id))
+			s2 = append(s2, storageFree(bm2, id))
+		}
+
+		row := fmt.Sprintf("%s%d\t%.0f -> %.0f\t%.0f -> %.0f", indent, id,
+			partitions(u1, id), partitions(u2, id), leaders(u1, id), leaders(u2, id))
+		if storageBased {
+			row += fmt.Sprintf("\t%.2fGB -> %.2fGB", storageFree(bm1, id)/div, storageFree(bm2, id)/div)
+		}
+		fmt.Fprintln(tw, row)
+	}
+
+	tw.Flush()
+
+	fmt.Printf("%s-\n", indent)
+
+	r1, sd1 := scoreStats(p1)
+	r2, sd2 := scoreStats(p2)
+	fmt.Printf("%spartitions range, std. deviation: %.0f, %.2f -> %.0f, %.2f\n", indent, r1, sd1, r2, sd2)
+
+	r1, sd1 = scoreStats(l1)
+	r2, sd2 = scoreStats(l2)
+	fmt.Printf("%sleaders range, std. deviation: %.0f, %.2f -> %.0f, %.2f\n", indent, r1, sd1, r2, sd2)
+
+	if storageBased {
+		r1, sd1 = scoreStats(s1)
+		r2, sd2 = scoreStats(s2)
+		fmt.Printf("%sstorage free range, std. deviation: %.2fGB, %.2fGB -> %.2fGB, %.2fGB\n",
+			indent, r1/div, sd1/div, r2/div, sd2/div)
+	}
+}
+
+// scoreStats returns the range and population std. deviation of the values.
+func scoreStats(vals []float64) (float64, float64) {
+	if len(vals) == 0 {
+		return 0, 0
+	}
+
+	min, max, sum := vals[0], vals[0], 0.00
+	for _, v := range vals {
+		min, max = math.Min(min, v), math.Max(max, v)
+		sum += v
+	}
+
+	mean := sum / float64(len(vals))
+
+	var ss float64
+	for _, v := range vals {
+		ss += math.Pow(v-mean, 2)
+	}
+
+	return max - min, math.Sqrt(ss / float64(len(vals)))
+}
+
+// planSize is the size of the partition replicas of the target topics held by
+// a broker before and after a reassignment, in bytes.
+type planSize struct {
+	current   float64
+	projected float64
+}
+
+// planSizes returns the planSize of each broker mapped in either
+// PartitionMap, using the partition sizes in the PartitionMetaMap. The number
+// of partitions without size metadata, which are counted as empty, is also
+// returned.
+func planSizes(pm1, pm2 *mapper.PartitionMap, pmm mapper.PartitionMetaMap) (map[int]*planSize, int) {
+	sizes := map[int]*planSize{}
+	get := func(id int) *planSize {
+		if sizes[id] == nil {
+			sizes[id] = &planSize{}
+		}
+		return sizes[id]
+	}
+
+	var missing int
+
+	for _, p := range pm1.Partitions {
+		size, err := pmm.Size(p)
+		if err != nil {
+			missing++
+		}
+		for _, id := range p.Replicas {
+			get(id).current += size
+		}
+	}
+
+	for _, p := range pm2.Partitions {
+		size, _ := pmm.Size(p)
+		for _, id := range p.Replicas {
+			get(id).projected += size
+		}
+	}
+
+	delete(sizes, mapper.StubBrokerID)
+
+	return sizes, missing
+}
+
+// printPlanSummary prints the current size, projected size and delta of the
+// partition replicas of the target topics held by each broker. If
+// storageBased, the projected storage free of each broker is included and an
+// error is returned for each broker projected to run out of storage.
+func printPlanSummary(pm1, pm2 *mapper.PartitionMap, pmm mapper.PartitionMetaMap, bm mapper.BrokerMap, storageBased bool) errors {
+	var errs errors
+
+	sizes, missing := planSizes(pm1, pm2, pmm)
+
+	var ids []int
+	for id := range sizes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	fmt.Println("\nPlan summary:")
+	if missing > 0 {
+		fmt.Printf("%s%d partition(s) without size metrics counted as empty\n", indent, missing)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := indent + "BROKER\tCURRENT\tPROJECTED\tDELTA"
+	if storageBased {
+		header += "\tFREE AFTER"
+	}
+	fmt.Fprintln(tw, header)
+
+	var current, projected float64
+
+	for _, id := range ids {
+		s := sizes[id]
+		current += s.current
+		projected += s.projected
+
+		row := fmt.Sprintf("%s%d\t%.2fGB\t%.2fGB\t%+.2fGB", indent, id,
+			s.current/div, s.projected/div, (s.projected-s.current)/div)
+
+		if b, exists := bm[id]; storageBased && exists {
+			row += fmt.Sprintf("\t%.2fGB", b.StorageFree/div)
+			if b.StorageFree < 0 {
+				errs = append(errs, fmt.Errorf("broker %d projected storage free is negative: %.2fGB", id, b.StorageFree/div))
+			}
+		}
+
+		fmt.Fprintln(tw, row)
+	}
+
+	tw.Flush()
+
+	fmt.Printf("%s-\n", indent)
+	fmt.Printf("%stotal: %.2fGB -> %.2fGB (%+.2fGB)\n", indent, current/div, projected/div, (projected-current)/div)
+
+	return errs
+}
+
+// printLeadershipChanges prints the leaders per broker before and after a
+// leadership optimization pass.
+func printLeadershipChanges(pm1, pm2 *mapper.PartitionMap) {
+	fmt.Println("\nLeadership optimization:")
+
+	s1, s2 := pm1.UseStats(), pm2.UseStats()
+
+	min1, max1 := leaderMinMax(s1)
+	min2, max2 := leaderMinMax(s2)
+	fmt.Printf("%sleaders [min/max]: %d/%d -> %d/%d\n", indent, min1, max1, min2, max2)
+
+	fmt.Printf("%s-\n", indent)
+
+	for _, use := range s2.List() {
+		var before int
+		if s, exists := s1[use.ID]; exists {
+			before = s.Leader
+		}
+
+		fmt.Printf("%sBroker %d - leader: %d -> %d\n", indent, use.ID, before, use.Leader)
+	}
+}
+
+// printRackViolations prints the replica sets left spanning fewer unique rack
+// IDs than required with --relax-rack-awareness.
+func printRackViolations(v []rackViolation) {
+	fmt.Println("\nRack constraint violations:")
+
+	if len(v) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	for _, rv := range v {
+		fmt.Printf("%s%s p%d: %v spans %d of %d required rack IDs\n",
+			indent, rv.topic, rv.partition, rv.replicas, rv.racks, rv.required)
+	}
+}
+
+// leaderMinMax returns the minimum and maximum leader counts in the
+// BrokerUseStatsMap.
+func leaderMinMax(s mapper.BrokerUseStatsMap) (int, int) {
+	var min, max int
+	for i, use := range s.List() {
+		if use.Leader < min || i == 0 {
+			min = use.Leader
+		}
+		if use.Leader > max {
+			max = use.Leader
+		}
+	}
+
+	return min, max
+}
+
 // skipReassignmentNoOps removes no-op partition map changes
 // from the input and final output PartitionMap
 func skipReassignmentNoOps(pm1, pm2 *mapper.PartitionMap) (*mapper.PartitionMap, *mapper.PartitionMap) {
@@ -189,46 +480,24 @@ func skipReassignmentNoOps(pm1, pm2 *mapper.PartitionMap) (*mapper.PartitionMap,
 	return prunedInputPartitionMap, prunedOutputPartitionMap
 }
 
-// writeMaps takes a PartitionMap and writes out files.
+// writeMaps takes a PartitionMap and writes out files. A map is written for
+// each topic, allowing topics to be applied individually, in addition to a
+// combined map of all topics if an outFile is set.
 func writeMaps(outPath, outFile string, pms []*mapper.PartitionMap) {
 	if len(pms) == 0 || len(pms[0].Partitions) == 0 {
 		fmt.Println("\nNo partition reassignments, skipping map generation")
 		return
 	}
 
-	phasedMaps := len(pms) > 1
-	suffix := func(i int) string {
-		if phasedMaps {
-			return fmt.Sprintf("-phase%d", i)
-		}
-		return ""
-	}
-
-	// Break map up by topic.
-	tm := map[string]*mapper.PartitionMap{}
-
-	// For each map type, create per-topic maps.
-	for i, m := range pms {
-		// Populate each partition in the parent map keyed
-		// by topic name and possible phase suffix.
-		for _, p := range m.Partitions {
-			mapName := fmt.Sprintf("%s%s", p.Topic, suffix(i))
-			if tm[mapName] == nil {
-				tm[mapName] = mapper.NewPartitionMap()
-			}
-			tm[mapName].Partitions = append(tm[mapName].Partitions, p)
-		}
-	}
-
 	fmt.Println("\nNew partition maps:")
 
 	// Write global map if set.
 	if outFile != "" {
 		for i, m := range pms {
-			fullPath := fmt.Sprintf("%s%s%s", outPath, outFile, suffix(i))
+			fullPath := fmt.Sprintf("%s%s%s", outPath, outFile, phaseSuffix(pms, i))
 			err := mapper.WriteMap(m, fullPath)
 			if err != nil {
-				fmt.Printf("%s%s", indent, err)
+				fmt.Printf("%s%s\n", indent, err)
 			} else {
 				fmt.Printf("%s%s.json [combined map]\n", indent, fullPath)
 			}
@@ -236,16 +505,51 @@ func writeMaps(outPath, outFile string, pms []*mapper.PartitionMap) {
 	}
 
 	// Write per-topic maps.
-	for t := range tm {
-		err := mapper.WriteMap(tm[t], outPath+t)
+	tm := topicMaps(pms)
+
+	var names []string
+	for name := range tm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := mapper.WriteMap(tm[name], outPath+name)
 		if err != nil {
-			fmt.Printf("%s%s", indent, err)
+			fmt.Printf("%s%s\n", indent, err)
 		} else {
-			fmt.Printf("%s%s%s.json\n", indent, outPath, t)
+			fmt.Printf("%s%s%s.json\n", indent, outPath, name)
 		}
 	}
 }
 
+// topicMaps breaks up the PartitionMaps by topic. Maps are keyed by topic name
+// and, where several PartitionMaps are provided, a phase suffix.
+func topicMaps(pms []*mapper.PartitionMap) map[string]*mapper.PartitionMap {
+	tm := map[string]*mapper.PartitionMap{}
+
+	for i, m := range pms {
+		for _, p := range m.Partitions {
+			name := p.Topic + phaseSuffix(pms, i)
+			if tm[name] == nil {
+				tm[name] = mapper.NewPartitionMap()
+			}
+			tm[name].Partitions = append(tm[name].Partitions, p)
+		}
+	}
+
+	return tm
+}
+
+// phaseSuffix returns the file name suffix for the i'th of the PartitionMaps.
+func phaseSuffix(pms []*mapper.PartitionMap, i int) string {
+	if len(pms) > 1 {
+		return fmt.Sprintf("-phase%d", i)
+	}
+
+	return ""
+}
+
 func printReassignmentParams(params reassignParams, results []reassignmentBundle, brokers mapper.BrokerMap, tol float64) {
 	fmt.Printf("\nReassignment parameters:\n")
 
@@ -261,12 +565,20 @@ func printReassignmentParams(params reassignParams, results []reassignmentBundle
 	fmt.Printf("%s%sSources limited to <= %.2fGB\n", indent, indent, mean*(1+tol)/div)
 	fmt.Printf("%s%sDestinations limited to >= %.2fGB\n", indent, indent, mean*(1-tol)/div)
 
+	if params.maxSpreadGB > 0 {
+		fmt.Printf("%sTarget free storage range: <= %.2fGB\n", indent, params.maxSpreadGB)
+	}
+
+	if params.objective == "min-movement" {
+		fmt.Printf("%sObjective: minimize data moved\n", indent)
+	}
+
 	// Print the top 10 rebalance results in verbose.
 	if params.verbose {
 		fmt.Printf("%s-\nTop 10 reassignment map results\n", indent)
 		for i, r := range results {
-			fmt.Printf("%stolerance: %.2f -> range: %.2fGB, std. deviation: %.2fGB\n",
-				indent, r.tolerance, r.storageRange/div, r.stdDev/div)
+			fmt.Printf("%stolerance: %.2f -> range: %.2fGB, std. deviation: %.2fGB, relocations: %d (%.2fGB)\n",
+				indent, r.tolerance, r.storageRange/div, r.stdDev/div, r.relocationCount(), r.relocatedSize/div)
 			if i == 10 {
 				break
 			}
@@ -296,6 +608,45 @@ func printPlannedRelocations(targets []int, relos map[int][]relocation, pmm mapp
 	fmt.Printf("%sTotal relocation volume: %.2fGB\n", indent, total)
 }
 
+// printBrokerTransfers prints the estimated data sent and received by each
+// broker.
+func printBrokerTransfers(transfers map[int]*brokerTransfer) {
+	fmt.Printf("\nEstimated broker data transfer:\n")
+
+	if len(transfers) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	var ids []int
+	for id := range transfers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		t := transfers[id]
+		fmt.Printf("%sBroker %d - sent: %.2fGB, received: %.2fGB\n",
+			indent, id, t.sent/div, t.received/div)
+	}
+}
+
+// printScaleShares prints the storage received by each new broker against its
+// proportional share.
+func printScaleShares(shares []scaleShare) {
+	fmt.Printf("\nNew broker storage shares:\n")
+
+	for _, s := range shares {
+		var pct float64
+		if s.share > 0 {
+			pct = s.received / s.share * 100
+		}
+
+		fmt.Printf("%sBroker %d: receiving %.2fGB of a %.2fGB proportional share (%.2f%%)\n",
+			indent, s.id, s.received/div, s.share/div, pct)
+	}
+}
+
 // handleOverridableErrs handles errors that can be optionally ignored by the
 // user (hence being referred to as 'WARN' in the CLI). If --ignore-warns is
 // false (default), any errors passed here will cause an exit(1).
@@ -305,6 +656,7 @@ func handleOverridableErrs(cmd *cobra.Command, e errors) {
 		sort.Sort(e)
 		for _, err := range e {
 			fmt.Printf("%s%s\n", indent, err)
+			logWarn(err.Error(), nil)
 		}
 	} else {
 		fmt.Printf("%s[none]\n", indent)
@@ -312,8 +664,7 @@ func handleOverridableErrs(cmd *cobra.Command, e errors) {
 
 	iw, _ := cmd.Flags().GetBool("ignore-warns")
 	if !iw && len(e) > 0 {
-		fmt.Printf("\n%sWarnings encountered, partition map not created. Override with --ignore-warns.\n", indent)
-		os.Exit(1)
+		exitOnErr(fmt.Errorf("\n%sWarnings encountered, partition map not created. Override with --ignore-warns.", indent))
 	}
 }
 
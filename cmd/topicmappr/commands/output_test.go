@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
 )
 
 func TestWhatChanged(t *testing.T) {
@@ -32,3 +36,109 @@ func TestWhatChanged(t *testing.T) {
 		}
 	}
 }
+
+func TestLeaderMinMax(t *testing.T) {
+	pm, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002]},
+		{"topic":"test","partition":1,"replicas":[1001,1002]},
+		{"topic":"test","partition":2,"replicas":[1003,1001]}]}`)
+
+	// Broker 1002 holds no leaders.
+	if min, max := leaderMinMax(pm.UseStats()); min != 0 || max != 2 {
+		t.Errorf("Expected min/max of 0/2, got %d/%d", min, max)
+	}
+}
+
+func TestTopicMaps(t *testing.T) {
+	pm := mapper.NewPartitionMap()
+	pm.Partitions = mapper.PartitionList{
+		{Topic: "a", Partition: 0, Replicas: []int{1001}},
+		{Topic: "b", Partition: 0, Replicas: []int{1002}},
+		{Topic: "a", Partition: 1, Replicas: []int{1002}},
+	}
+
+	tm := topicMaps([]*mapper.PartitionMap{pm})
+	if len(tm) != 2 || len(tm["a"].Partitions) != 2 || len(tm["b"].Partitions) != 1 {
+		t.Fatalf("Unexpected topic maps: %v", tm)
+	}
+
+	// Phased maps are suffixed.
+	tm = topicMaps([]*mapper.PartitionMap{pm, pm})
+	for _, name := range []string{"a-phase0", "b-phase0", "a-phase1", "b-phase1"} {
+		if _, exists := tm[name]; !exists {
+			t.Errorf("Expected topic map %s", name)
+		}
+	}
+}
+
+func TestWriteMaps(t *testing.T) {
+	pm := mapper.NewPartitionMap()
+	pm.Partitions = mapper.PartitionList{
+		{Topic: "a", Partition: 0, Replicas: []int{1001}},
+		{Topic: "b", Partition: 0, Replicas: []int{1002}},
+	}
+
+	dir := t.TempDir() + string(filepath.Separator)
+	writeMaps(dir, "combined", []*mapper.PartitionMap{pm})
+
+	for _, name := range []string{"a", "b", "combined"} {
+		if _, err := os.Stat(dir + name + ".json"); err != nil {
+			t.Errorf("Expected map file %s: %s", name, err)
+		}
+	}
+}
+
+func TestScoreStats(t *testing.T) {
+	if r, sd := scoreStats(nil); r != 0 || sd != 0 {
+		t.Errorf("Expected 0, 0, got %.2f, %.2f", r, sd)
+	}
+
+	if r, sd := scoreStats([]float64{2, 4, 4, 4, 5, 5, 7, 9}); r != 7 || sd != 2 {
+		t.Errorf("Expected 7, 2, got %.2f, %.2f", r, sd)
+	}
+}
+
+func TestPlanSizes(t *testing.T) {
+	pm1 := mapper.NewPartitionMap()
+	pm1.Partitions = mapper.PartitionList{
+		{Topic: "test", Partition: 0, Replicas: []int{1001, 1002}},
+		{Topic: "test", Partition: 1, Replicas: []int{1002, 1001}},
+		{Topic: "other", Partition: 0, Replicas: []int{1001, 1002}},
+	}
+
+	pm2 := mapper.NewPartitionMap()
+	pm2.Partitions = mapper.PartitionList{
+		{Topic: "test", Partition: 0, Replicas: []int{1001, 1003}},
+		{Topic: "test", Partition: 1, Replicas: []int{1003, 1001}},
+		{Topic: "other", Partition: 0, Replicas: []int{1001, 1002}},
+	}
+
+	pmm := mapper.PartitionMetaMap{
+		"test": map[int]*mapper.PartitionMeta{
+			0: {Size: 100},
+			1: {Size: 200},
+		},
+	}
+
+	sizes, missing := planSizes(pm1, pm2, pmm)
+
+	if missing != 1 {
+		t.Errorf("Expected 1 partition without size metrics, got %d", missing)
+	}
+
+	expected := map[int]planSize{
+		1001: {current: 300, projected: 300},
+		1002: {current: 300, projected: 0},
+		1003: {current: 0, projected: 300},
+	}
+
+	if len(sizes) != len(expected) {
+		t.Fatalf("Expected %d brokers, got %d", len(expected), len(sizes))
+	}
+
+	for id, s := range expected {
+		if *sizes[id] != s {
+			t.Errorf("[broker %d] Expected %+v, got %+v", id, s, *sizes[id])
+		}
+	}
+}
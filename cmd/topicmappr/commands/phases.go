@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+// reassignmentPhase is one of a series of sequential reassignments.
+type reassignmentPhase struct {
+	partitionMap *mapper.PartitionMap
+	// The estimated data movement in bytes.
+	size float64
+}
+
+// splitReassignmentPhases takes the original and output PartitionMaps, the
+// PartitionMetaMap, and a max partition count and max size in bytes per phase
+// (0 for no limit). The changed partitions of the output map are split, in
+// order, into sequential phases that each satisfy the limits. A partition
+// exceeding the max size on its own is placed in a dedicated phase. The
+// estimated data movement for a partition is its size multiplied by the number
+// of replicas added. Errors are returned for partitions without size metadata
+// where a max size is set.
+func splitReassignmentPhases(pm1, pm2 *mapper.PartitionMap, pmm mapper.PartitionMetaMap, maxPartitions int, maxSize float64) ([]reassignmentPhase, []error) {
+	var phases []reassignmentPhase
+	var errs []error
+
+	current := reassignmentPhase{partitionMap: mapper.NewPartitionMap()}
+
+	for i, p := range pm2.Partitions {
+		if i < len(pm1.Partitions) && pm1.Partitions[i].Equal(p) {
+			continue
+		}
+
+		var size float64
+		if maxSize > 0 {
+			pSize, err := pmm.Size(p)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			var original []int
+			if i < len(pm1.Partitions) {
+				original = pm1.Partitions[i].Replicas
+			}
+
+			for _, id := range p.Replicas {
+				if notInReplicaSet(id, original) {
+					size += pSize
+				}
+			}
+		}
+
+		// Start a new phase if adding the partition would exceed either limit.
+		n := len(current.partitionMap.Partitions)
+		if n > 0 && ((maxPartitions > 0 && n+1 > maxPartitions) || (maxSize > 0 && current.size+size > maxSize)) {
+			phases = append(phases, current)
+			current = reassignmentPhase{partitionMap: mapper.NewPartitionMap()}
+		}
+
+		current.partitionMap.Partitions = append(current.partitionMap.Partitions, p)
+		current.size += size
+	}
+
+	if len(current.partitionMap.Partitions) > 0 {
+		phases = append(phases, current)
+	}
+
+	return phases, errs
+}
+
+// printReassignmentPhases prints the partition count and estimated data
+// movement of each phase.
+func printReassignmentPhases(phases []reassignmentPhase) {
+	fmt.Printf("\nReassignment phases:\n")
+
+	if len(phases) == 0 {
+		fmt.Printf("%s[none]\n", indent)
+		return
+	}
+
+	for i, p := range phases {
+		fmt.Printf("%sPhase %d: %d partitions, %.2fGB\n",
+			indent, i, len(p.partitionMap.Partitions), p.size/div)
+	}
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestSplitReassignmentPhases(t *testing.T) {
+	pm1, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002]},
+		{"topic":"test","partition":1,"replicas":[1001,1002]},
+		{"topic":"test","partition":2,"replicas":[1001,1002]},
+		{"topic":"test","partition":3,"replicas":[1001,1002]}]}`)
+
+	pm2, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1003,1004]},
+		{"topic":"test","partition":1,"replicas":[1001,1002]},
+		{"topic":"test","partition":2,"replicas":[1001,1003]},
+		{"topic":"test","partition":3,"replicas":[1002,1003]}]}`)
+
+	pmm := mapper.PartitionMetaMap{
+		"test": {
+			0: &mapper.PartitionMeta{Size: 100},
+			1: &mapper.PartitionMeta{Size: 100},
+			2: &mapper.PartitionMeta{Size: 100},
+			3: &mapper.PartitionMeta{Size: 100},
+		},
+	}
+
+	// By count; the no-op partition 1 is excluded.
+	phases, errs := splitReassignmentPhases(pm1, pm2, pmm, 2, 0)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if len(phases) != 2 || len(phases[0].partitionMap.Partitions) != 2 || len(phases[1].partitionMap.Partitions) != 1 {
+		t.Fatalf("Unexpected phases %+v", phases)
+	}
+
+	// By size; partition 0 moves two replicas, partitions 2 and 3 move one each.
+	phases, _ = splitReassignmentPhases(pm1, pm2, pmm, 0, 200)
+
+	expected := []float64{200, 200}
+	if len(phases) != len(expected) {
+		t.Fatalf("Expected %d phases, got %d", len(expected), len(phases))
+	}
+
+	for i, p := range phases {
+		if p.size != expected[i] {
+			t.Errorf("[phase %d] Expected size %.0f, got %.0f", i, expected[i], p.size)
+		}
+	}
+
+	if phases[1].partitionMap.Partitions[0].Partition != 2 {
+		t.Errorf("Expected phase 1 to start with partition 2")
+	}
+
+	// Missing partition meta.
+	if _, errs := splitReassignmentPhases(pm1, pm2, mapper.PartitionMetaMap{}, 0, 200); len(errs) != 3 {
+		t.Errorf("Expected 3 errors, got %d", len(errs))
+	}
+}
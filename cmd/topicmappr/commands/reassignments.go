@@ -3,7 +3,6 @@ package commands
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -31,6 +30,8 @@ type reassignmentBundle struct {
 	partitionMap *mapper.PartitionMap
 	// Partition relocations that constitute the reassignment.
 	relocations map[int][]relocation
+	// The total size of the partition relocations in bytes.
+	relocatedSize float64
 	// The brokers that the PartitionMap is assigning brokers to.
 	brokers mapper.BrokerMap
 }
@@ -39,6 +40,8 @@ type reassignParams struct {
 	brokers                []int
 	localityScoped         bool
 	maxMetadataAge         int
+	maxSpreadGB            float64
+	objective              string
 	optimizeLeadership     bool
 	partitionLimit         int
 	partitionSizeThreshold int
@@ -47,12 +50,97 @@ type reassignParams struct {
 	tolerance              float64
 	topics                 []string
 	topicsExclude          []*regexp.Regexp
+	excludeReassigning     bool
 	requireNewBrokers      bool
 	verbose                bool
 }
 
 func (s reassignParams) UseFixedTolerance() bool { return s.tolerance != 0.00 }
 
+func (s reassignParams) validate() error {
+	switch {
+	case s.objective != "balance" && s.objective != "min-movement":
+		return fmt.Errorf("\n[ERROR] --objective must be either 'balance' or 'min-movement'")
+	case s.objective == "min-movement" && s.maxSpreadGB <= 0:
+		return fmt.Errorf("\n[ERROR] --objective=min-movement requires --max-spread-gb")
+	}
+	return nil
+}
+
+// relocationCount returns the number of partition relocations in the
+// reassignmentBundle.
+func (r reassignmentBundle) relocationCount() int {
+	var n int
+	for _, relos := range r.relocations {
+		n += len(relos)
+	}
+
+	return n
+}
+
+// selectReassignmentBundle takes reassignmentBundles sorted by storage range
+// ascending, a max storage range in bytes and the objective. It returns the
+// bundle with the fewest relocations among those within the max range, or the
+// fewest bytes relocated with the "min-movement" objective, preferring the
+// lower range in a tie. If the max range is 0 or no bundles are within it, the
+// bundle with the lowest range is returned.
+func selectReassignmentBundle(bundles []reassignmentBundle, maxRange float64, objective string) reassignmentBundle {
+	selected := bundles[0]
+	if maxRange <= 0 {
+		return selected
+	}
+
+	cost := func(b reassignmentBundle) float64 {
+		if objective == "min-movement" {
+			return b.relocatedSize
+		}
+		return float64(b.relocationCount())
+	}
+
+	found := false
+	for _, b := range bundles {
+		if b.storageRange > maxRange {
+			continue
+		}
+
+		if !found || cost(b) < cost(selected) {
+			selected, found = b, true
+		}
+	}
+
+	return selected
+}
+
+// brokerTransfer is the estimated data a broker sends and receives in a
+// reassignment, in bytes.
+type brokerTransfer struct {
+	sent     float64
+	received float64
+}
+
+// relocationTransfers takes partition relocations keyed by source broker ID
+// and returns the estimated brokerTransfer of each broker involved, keyed by
+// broker ID.
+func relocationTransfers(relos map[int][]relocation, pmm mapper.PartitionMetaMap) map[int]*brokerTransfer {
+	transfers := map[int]*brokerTransfer{}
+	get := func(id int) *brokerTransfer {
+		if transfers[id] == nil {
+			transfers[id] = &brokerTransfer{}
+		}
+		return transfers[id]
+	}
+
+	for src, rs := range relos {
+		for _, r := range rs {
+			size, _ := pmm.Size(r.partition)
+			get(src).sent += size
+			get(r.destination).received += size
+		}
+	}
+
+	return transfers
+}
+
 func reassignParamsFromCmd(cmd *cobra.Command) (params reassignParams) {
 	brokers, _ := cmd.Flags().GetString("brokers")
 	params.brokers = brokerStringToSlice(brokers)
@@ -60,6 +148,10 @@ func reassignParamsFromCmd(cmd *cobra.Command) (params reassignParams) {
 	params.localityScoped = localityScoped
 	maxMetadataAge, _ := cmd.Flags().GetInt("metrics-age")
 	params.maxMetadataAge = maxMetadataAge
+	maxSpreadGB, _ := cmd.Flags().GetFloat64("max-spread-gb")
+	params.maxSpreadGB = maxSpreadGB
+	objective, _ := cmd.Flags().GetString("objective")
+	params.objective = objective
 	optimizeLeadership, _ := cmd.Flags().GetBool("optimize-leadership")
 	params.optimizeLeadership = optimizeLeadership
 	partitionLimit, _ := cmd.Flags().GetInt("partition-limit")
@@ -73,9 +165,10 @@ func reassignParamsFromCmd(cmd *cobra.Command) (params reassignParams) {
 	tolerance, _ := cmd.Flags().GetFloat64("tolerance")
 	params.tolerance = tolerance
 	topics, _ := cmd.Flags().GetString("topics")
-	params.topics = strings.Split(topics, ",")
-	topicsExclude, _ := cmd.Flags().GetString("topics-exclude")
-	params.topicsExclude = topicRegex(topicsExclude)
+	params.topics = splitTopics(topics)
+	params.topicsExclude = topicExclusionsFromCmd(cmd)
+	excludeReassigning, _ := cmd.Flags().GetBool("exclude-reassigning")
+	params.excludeReassigning = excludeReassigning
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	params.verbose = verbose
 	return params
@@ -84,32 +177,35 @@ func reassignParamsFromCmd(cmd *cobra.Command) (params reassignParams) {
 func reassign(params reassignParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handler) ([]*mapper.PartitionMap, []error) {
 	// Get broker and partition metadata.
 	if err := checkMetaAge(zk, params.maxMetadataAge); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 	brokerMeta, errs := getBrokerMeta(ka, zk, true)
 	if errs != nil && brokerMeta == nil {
-		for _, e := range errs {
-			fmt.Println(e)
-		}
-		os.Exit(1)
+		exitOnErr(errs...)
 	}
 	partitionMeta, err := getPartitionMeta(zk)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	// Get the current partition map.
 	partitionMapIn, err := getPartitionMaps(ka, params.topics)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	// Exclude any explicit exclusions.
 	excluded := removeTopics(partitionMapIn, params.topicsExclude)
 
+	// Exclude topics being reassigned.
+	if params.excludeReassigning {
+		reassigning, err := removeReassigningTopics(ka, partitionMapIn)
+		if err != nil {
+			exitOnErr(err)
+		}
+		excluded = append(excluded, reassigning...)
+	}
+
 	// Print topics matched to input params.
 	printTopics(partitionMapIn)
 
@@ -122,10 +218,7 @@ func reassign(params reassignParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handle
 	// Validate all broker params, get a copy of the broker IDs targeted for
 	// partition offloading.
 	if errs := validateBrokers(params.brokers, brokersIn, brokerMeta, params.requireNewBrokers); len(errs) > 0 {
-		for _, e := range errs {
-			fmt.Println(e)
-		}
-		os.Exit(1)
+		exitOnErr(errs...)
 	}
 
 	// Get offload targets.
@@ -161,8 +254,9 @@ func reassign(params reassignParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handle
 		return resultsByRange[i].stdDev < resultsByRange[j].stdDev
 	})
 
-	// Chose the results with the lowest range.
-	m := resultsByRange[0]
+	// Chose the results with the lowest range, or with the fewest relocations
+	// (or bytes relocated) within the max spread.
+	m := selectReassignmentBundle(resultsByRange, params.maxSpreadGB*div, params.objective)
 	partitionMapOut, brokersOut, relos := m.partitionMap, m.brokers, m.relocations
 
 	// Print parameters used for rebalance decisions.
@@ -170,18 +264,34 @@ func reassign(params reassignParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handle
 
 	// Optimize leaders.
 	if params.optimizeLeadership {
+		unoptimized := partitionMapOut.Copy()
 		partitionMapOut.OptimizeLeaderFollower()
+		printLeadershipChanges(unoptimized, partitionMapOut)
 	}
 
 	// Print planned relocations.
 	printPlannedRelocations(offloadTargets, relos, partitionMeta)
 
+	// Print the estimated data sent and received by each broker.
+	printBrokerTransfers(relocationTransfers(relos, partitionMeta))
+
+	// Print the share of storage taken on by newly added brokers.
+	if params.requireNewBrokers {
+		printScaleShares(scaleShares(brokersIn, brokersOut))
+	}
+
 	// Print map change results.
 	printMapChanges(partitionMapIn, partitionMapOut)
 
 	// Print broker assignment statistics.
 	errs = printBrokerAssignmentStats(partitionMapIn, partitionMapOut, brokersIn, brokersOut, true, 1.0)
 
+	// Print the broker balance scorecard.
+	printScorecard(partitionMapIn, partitionMapOut, brokersIn, brokersOut, true)
+
+	// Print the projected per-broker sizes.
+	errs = append(errs, printPlanSummary(partitionMapIn, partitionMapOut, partitionMeta, brokersOut, true)...)
+
 	// Ignore no-ops; rebalances will naturally have a high percentage of these.
 	partitionMapIn, partitionMapOut = skipReassignmentNoOps(partitionMapIn, partitionMapOut)
 
@@ -189,6 +299,39 @@ func reassign(params reassignParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handle
 
 }
 
+// scaleShare describes the storage taken on by a newly added broker in a
+// scale, in bytes.
+type scaleShare struct {
+	id       int
+	received float64
+	// The proportional share; the storage that would bring the broker to the
+	// mean storage free.
+	share float64
+}
+
+// scaleShares takes the input and output BrokerMaps of a reassignment and
+// returns a scaleShare for each new broker, sorted by ID.
+func scaleShares(in, out mapper.BrokerMap) []scaleShare {
+	mean := in.Mean()
+
+	var shares []scaleShare
+	for id, b := range in {
+		if !b.New || out[id] == nil {
+			continue
+		}
+
+		shares = append(shares, scaleShare{
+			id:       id,
+			received: b.StorageFree - out[id].StorageFree,
+			share:    b.StorageFree - mean,
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool { return shares[i].id < shares[j].id })
+
+	return shares
+}
+
 // computeReassignmentBundles takes computeReassignmentBundlesParams and returns
 // a chan reassignmentBundle. The channel will either contain a single reassignmentBundle
 // if a fixed computeReassignmentBundlesParams.tolerance value (non 0.00) is
@@ -244,7 +387,9 @@ func computeReassignmentBundles(
 			}
 
 			// Iterate over offload targets, planning at most one relocation per iteration.
-			// Continue this loop until no more relocations can be planned.
+			// Continue this loop until no more relocations can be planned, or until
+			// the storage range is within the max spread, if set.
+		plan:
 			for exhaustedCount := 0; exhaustedCount < len(offloadTargets); {
 				relocationParams.pass++
 				for _, sourceID := range offloadTargets {
@@ -256,6 +401,11 @@ func computeReassignmentBundles(
 					// If no relocations could be planned, increment the exhaustion counter.
 					if relos == 0 {
 						exhaustedCount++
+						continue
+					}
+
+					if params.maxSpreadGB > 0 && relocationParams.brokers.StorageRange() <= params.maxSpreadGB*div {
+						break plan
 					}
 				}
 			}
@@ -263,14 +413,20 @@ func computeReassignmentBundles(
 			// Update the partition map with the relocation plan.
 			applyRelocationPlan(partitionMap, relocationParams.plan)
 
+			var relocatedSize float64
+			for _, t := range relocationTransfers(relocationParams.relos, partitionMeta) {
+				relocatedSize += t.sent
+			}
+
 			// Insert the reassignmentBundle.
 			results <- reassignmentBundle{
-				storageRange: relocationParams.brokers.StorageRange(),
-				stdDev:       relocationParams.brokers.StorageStdDev(),
-				tolerance:    tol,
-				partitionMap: partitionMap,
-				relocations:  relocationParams.relos,
-				brokers:      relocationParams.brokers,
+				storageRange:  relocationParams.brokers.StorageRange(),
+				stdDev:        relocationParams.brokers.StorageStdDev(),
+				tolerance:     tol,
+				partitionMap:  partitionMap,
+				relocations:   relocationParams.relos,
+				relocatedSize: relocatedSize,
+				brokers:       relocationParams.brokers,
 			}
 
 		}()
@@ -318,8 +474,7 @@ func getPartitionMapChunks(finalMap *mapper.PartitionMap, initialMap *mapper.Par
 				if _, correctReplica := chunkBrokers[r]; correctReplica {
 					// This replica needs to be switched with one from the final map
 					if len(tempMap.Partitions[pIndex].Replicas) != len(finalMap.Partitions[pIndex].Replicas) {
-						fmt.Println("Chunked reassignment cannot be used when reducing or increasing replication factor. Exiting.")
-						os.Exit(1)
+						exitOnErr(fmt.Errorf("Chunked reassignment cannot be used when reducing or increasing replication factor. Exiting."))
 					}
 					tempMap.Partitions[pIndex].Replicas[rIndex] = finalMap.Partitions[pIndex].Replicas[rIndex]
 				}
@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestSelectReassignmentBundle(t *testing.T) {
+	relos := func(n int) map[int][]relocation {
+		return map[int][]relocation{1001: make([]relocation, n)}
+	}
+
+	// Sorted by storage range ascending.
+	bundles := []reassignmentBundle{
+		{storageRange: 10, tolerance: 0.01, relocations: relos(8)},
+		{storageRange: 20, tolerance: 0.02, relocations: relos(3)},
+		{storageRange: 30, tolerance: 0.03, relocations: relos(3)},
+		{storageRange: 40, tolerance: 0.04, relocations: relos(1)},
+	}
+
+	tests := map[float64]float64{
+		// No max range; the lowest range.
+		0: 0.01,
+		// The fewest relocations within the range, lowest range in a tie.
+		35: 0.02,
+		50: 0.04,
+		// None within the range; the lowest range.
+		5: 0.01,
+	}
+
+	for maxRange, expected := range tests {
+		if b := selectReassignmentBundle(bundles, maxRange, "balance"); b.tolerance != expected {
+			t.Errorf("[max range %.0f] Expected tolerance %.2f, got %.2f", maxRange, expected, b.tolerance)
+		}
+	}
+
+	// The fewest bytes relocated within the range.
+	bundles[0].relocatedSize = 400
+	bundles[1].relocatedSize = 300
+	bundles[2].relocatedSize = 100
+	bundles[3].relocatedSize = 200
+
+	if b := selectReassignmentBundle(bundles, 35, "min-movement"); b.tolerance != 0.03 {
+		t.Errorf("Expected tolerance 0.03, got %.2f", b.tolerance)
+	}
+}
+
+func TestRelocationTransfers(t *testing.T) {
+	pmm := mapper.PartitionMetaMap{
+		"test": {
+			0: &mapper.PartitionMeta{Size: 10},
+			1: &mapper.PartitionMeta{Size: 20},
+		},
+	}
+
+	relos := map[int][]relocation{
+		1001: {
+			{partition: mapper.Partition{Topic: "test", Partition: 0}, destination: 1002},
+			{partition: mapper.Partition{Topic: "test", Partition: 1}, destination: 1003},
+		},
+		1002: {
+			{partition: mapper.Partition{Topic: "test", Partition: 1}, destination: 1003},
+		},
+	}
+
+	transfers := relocationTransfers(relos, pmm)
+
+	expected := map[int]brokerTransfer{
+		1001: {sent: 30},
+		1002: {sent: 20, received: 10},
+		1003: {received: 40},
+	}
+
+	if len(transfers) != len(expected) {
+		t.Fatalf("Expected %d transfers, got %d", len(expected), len(transfers))
+	}
+
+	for id, e := range expected {
+		if *transfers[id] != e {
+			t.Errorf("[broker %d] Expected %+v, got %+v", id, e, *transfers[id])
+		}
+	}
+}
+
+func TestReassignParamsValidate(t *testing.T) {
+	tests := []struct {
+		params reassignParams
+		ok     bool
+	}{
+		{params: reassignParams{objective: "balance"}, ok: true},
+		{params: reassignParams{objective: "min-movement", maxSpreadGB: 10}, ok: true},
+		// A target range is required.
+		{params: reassignParams{objective: "min-movement"}, ok: false},
+		{params: reassignParams{objective: "other"}, ok: false},
+	}
+
+	for i, test := range tests {
+		if err := test.params.validate(); (err == nil) != test.ok {
+			t.Errorf("[test %d] Unexpected result: %v", i, err)
+		}
+	}
+}
+
+func TestScaleShares(t *testing.T) {
+	in := mapper.BrokerMap{
+		1001: &mapper.Broker{ID: 1001, StorageFree: 100},
+		1002: &mapper.Broker{ID: 1002, StorageFree: 200},
+		1003: &mapper.Broker{ID: 1003, StorageFree: 600, New: true},
+	}
+
+	out := in.Copy()
+	out[1001].StorageFree = 220
+	out[1002].StorageFree = 280
+	out[1003].StorageFree = 400
+
+	shares := scaleShares(in, out)
+	if len(shares) != 1 {
+		t.Fatalf("Expected 1 share, got %d", len(shares))
+	}
+
+	// The mean storage free is 300.
+	if s := shares[0]; s.id != 1003 || s.received != 200 || s.share != 300 {
+		t.Errorf("Unexpected share %+v", s)
+	}
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
@@ -20,19 +19,22 @@ func init() {
 	rootCmd.AddCommand(rebalanceCmd)
 
 	rebalanceCmd.Flags().String("topics", "", "Rebuild topics (comma delim. list) by lookup in ZooKeeper")
-	rebalanceCmd.Flags().String("topics-exclude", "", "Exclude topics")
+	addTopicExclusionFlags(rebalanceCmd)
 	rebalanceCmd.Flags().String("out-path", "", "Path to write output map files to")
 	rebalanceCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
 	rebalanceCmd.Flags().String("brokers", "", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
 	rebalanceCmd.Flags().Float64("storage-threshold", 0.20, "Percent below the harmonic mean storage free to target for partition offload (0 targets a brokers)")
 	rebalanceCmd.Flags().Float64("storage-threshold-gb", 0.00, "Storage free in gigabytes to target for partition offload (those below the specified value); 0 [default] defers target selection to --storage-threshold")
 	rebalanceCmd.Flags().Float64("tolerance", 0.0, "Percent distance from the mean storage free to limit storage scheduling (0 performs automatic tolerance selection)")
+	rebalanceCmd.Flags().Float64("max-spread-gb", 0.00, "Target range of broker storage free in gigabytes; relocation planning stops once within the range and the plan with the fewest relocations is chosen (0 [default] chooses the plan with the lowest range)")
+	rebalanceCmd.Flags().String("objective", "balance", "Plan selection objective: [balance, min-movement]; min-movement chooses the plan relocating the fewest bytes within --max-spread-gb")
 	rebalanceCmd.Flags().Int("partition-limit", 30, "Limit the number of top partitions by size eligible for relocation per broker")
 	rebalanceCmd.Flags().Int("partition-size-threshold", 512, "Size in megabytes where partitions below this value will not be moved in a rebalance")
 	rebalanceCmd.Flags().Bool("locality-scoped", false, "Ensure that all partition movements are scoped by rack.id")
 	rebalanceCmd.Flags().Bool("verbose", false, "Verbose output")
 	rebalanceCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes)")
 	rebalanceCmd.Flags().Bool("optimize-leadership", false, "Rebalance all broker leader/follower ratios")
+	addApplyFlags(rebalanceCmd)
 
 	// Required.
 	rebalanceCmd.MarkFlagRequired("brokers")
@@ -41,17 +43,27 @@ func init() {
 
 func rebalance(cmd *cobra.Command, _ []string) {
 	sanitizeInput(cmd)
+	apply := applyParamsFromCmd(cmd)
+	if err := apply.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
 	params := reassignParamsFromCmd(cmd)
 	params.requireNewBrokers = false
 
+	if err := params.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
 	// ZooKeeper init.
 	zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
 	kafkaPrefix := cmd.Parent().Flag("zk-prefix").Value.String()
 	metricsPrefix := cmd.Flag("zk-metrics-prefix").Value.String()
 	zk, err := initZooKeeper(zkAddr, kafkaPrefix, metricsPrefix)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	defer zk.Close()
@@ -60,8 +72,7 @@ func rebalance(cmd *cobra.Command, _ []string) {
 	bs := cmd.Parent().Flag("kafka-addr").Value.String()
 	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	partitionMaps, errs := reassign(params, ka, zk)
@@ -74,4 +85,9 @@ func rebalance(cmd *cobra.Command, _ []string) {
 	outPath := cmd.Flag("out-path").Value.String()
 	outFile := cmd.Flag("out-file").Value.String()
 	writeMaps(outPath, outFile, partitionMaps)
+
+	// Submit the reassignments if configured.
+	if err := applyMaps(apply, ka, partitionMaps, os.Stdin); err != nil {
+		exitOnErr(err)
+	}
 }
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
 
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
 
 	"github.com/spf13/cobra"
 )
@@ -19,8 +19,11 @@ var rebuildCmd = &cobra.Command{
 target topics and a list of broker IDs to which those topics should be mapped.
 Target topics are provided as a comma delimited list of topic names and/or regex patterns
 via the --topics parameter, which discovers matching topics in ZooKeeper (additionally,
-the --zk-addr and --zk-prefix global flags should be set). Alternatively, a JSON map can be
-provided via the --map-string flag. Target broker IDs are provided via the --broker flag.`,
+the --zk-addr and --zk-prefix global flags should be set), or as a newline delimited
+list via the --topics-file parameter. Alternatively, a JSON map can be
+provided via the --map-string flag or read from a file via the --map-file flag, such as a
+previously generated map or kafka-reassign-partitions JSON. Target broker IDs are provided
+via the --broker flag.`,
 	Run: rebuild,
 }
 
@@ -28,26 +31,37 @@ func init() {
 	rootCmd.AddCommand(rebuildCmd)
 
 	rebuildCmd.Flags().String("topics", "", "Rebuild topics (comma delim. list) by lookup in ZooKeeper")
-	rebuildCmd.Flags().String("topics-exclude", "", "Exclude topics")
+	rebuildCmd.Flags().String("topics-file", "", "Path to a newline delimited file of topics to rebuild, each optionally followed by a comma delim. target broker list")
+	addTopicExclusionFlags(rebuildCmd)
 	rebuildCmd.Flags().String("map-string", "", "Rebuild a partition map provided as a string literal")
+	rebuildCmd.Flags().String("map-file", "", "Rebuild a partition map read from a JSON file (e.g. a previously generated or kafka-reassign-partitions map)")
 	rebuildCmd.Flags().Bool("use-meta", true, "Use broker metadata in placement constraints")
 	rebuildCmd.Flags().String("out-path", "", "Path to write output map files to")
 	rebuildCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
-	rebuildCmd.Flags().Bool("force-rebuild", false, "Forces a complete map rebuild")
+	rebuildCmd.Flags().Bool("force-rebuild", false, "Forces a complete map rebuild, placing all replicas from scratch rather than minimizing changes to the current map")
 	rebuildCmd.Flags().Int("replication", 0, "Normalize the topic replication factor across all replica sets (0 results in a no-op)")
 	rebuildCmd.Flags().Bool("sub-affinity", false, "Replacement broker substitution affinity")
 	rebuildCmd.Flags().String("placement", "count", "Partition placement strategy: [count, storage]")
 	rebuildCmd.Flags().Int("min-rack-ids", 0, "Minimum number of required of unique rack IDs per replica set (0 requires that all are unique)")
+	rebuildCmd.Flags().Bool("relax-rack-awareness", false, "Place replicas in racks already used by the replica set where rack constraints can't be satisfied, minimizing same-rack colocations")
+	rebuildCmd.Flags().Int("max-partitions-per-broker", 0, "Maximum number of partition replicas of the target topics placed on any broker (0 is unlimited)")
+	rebuildCmd.Flags().Int("max-topic-partitions-per-broker", 0, "Maximum number of partition replicas of any one topic placed on any broker (0 is unlimited)")
+	rebuildCmd.Flags().String("partition-cap-policy", "spill", "Policy where a partition cap would be exceeded: [spill, error]; spill places replicas on other brokers, error fails")
 	rebuildCmd.Flags().String("optimize", "distribution", "Optimization priority for the storage placement strategy: [distribution, storage]")
 	rebuildCmd.Flags().Float64("partition-size-factor", 1.0, "Factor by which to multiply partition sizes when using storage placement")
 	rebuildCmd.Flags().String("brokers", "", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
 	rebuildCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes) (when using storage placement)")
+	rebuildCmd.Flags().String("metrics-file", "", "Path to a JSON file of broker storage and partition size metrics to use in place of ZooKeeper (when using storage placement)")
+	rebuildCmd.Flags().Bool("plan-summary", false, "Print the current and projected size of each broker's partitions (requires partition size metrics; always printed with storage placement or --phase-max-gb)")
 	rebuildCmd.Flags().Bool("skip-no-ops", false, "Skip no-op partition assigments")
 	rebuildCmd.Flags().Bool("optimize-leadership", false, "Rebalance all broker leader/follower ratios")
 	rebuildCmd.Flags().Bool("phased-reassignment", false, "Create two-phase output maps")
 	rebuildCmd.Flags().String("leader-evac-brokers", "", "Broker list to remove leadership for topics in leader-evac-topics.")
 	rebuildCmd.Flags().String("leader-evac-topics", "", "Topics list to remove leadership for the brokers given in leader-evac-brokers")
 	rebuildCmd.Flags().Int("chunk-step-size", 0, "Number of brokers to move data at a time for with a chunked operation.")
+	rebuildCmd.Flags().Int("phase-max-partitions", 0, "Split the reassignment into sequential phases of at most this many partitions (0 disables phasing by count)")
+	rebuildCmd.Flags().Float64("phase-max-gb", 0.00, "Split the reassignment into sequential phases of at most this many gigabytes of estimated data movement (0 disables phasing by size)")
+	addApplyFlags(rebuildCmd)
 
 	// Required.
 	rebuildCmd.MarkFlagRequired("brokers")
@@ -57,22 +71,35 @@ type rebuildParams struct {
 	brokers             []int
 	forceRebuild        bool
 	mapString           string
+	mapFile             string
 	maxMetadataAge      int
+	metricsFile         string
+	maxPartitions       int
+	maxTopicPartitions  int
+	partitionCapPolicy  string
 	minRackIds          int
+	relaxRackAwareness  bool
 	optimize            string
 	optimizeLeadership  bool
 	partitionSizeFactor float64
 	phasedReassignment  bool
+	planSummary         bool
 	placement           string
 	replication         int
 	skipNoOps           bool
 	subAffinity         bool
 	topics              []string
+	topicsFile          string
+	topicBrokers        []topicsFileEntry
 	topicsExclude       []*regexp.Regexp
+	excludeReassigning  bool
 	useMetadata         bool
 	leaderEvacTopics    []string
 	leaderEvacBrokers   []int
 	chunkStepSize       int
+	phaseMaxPartitions  int
+	phaseMaxGB          float64
+	evacuateBrokers     []int
 }
 
 func rebuildParamsFromCmd(cmd *cobra.Command) (params rebuildParams) {
@@ -82,10 +109,22 @@ func rebuildParamsFromCmd(cmd *cobra.Command) (params rebuildParams) {
 	params.forceRebuild = forceRebuild
 	mapString, _ := cmd.Flags().GetString("map-string")
 	params.mapString = mapString
+	mapFile, _ := cmd.Flags().GetString("map-file")
+	params.mapFile = mapFile
 	maxMetadataAge, _ := cmd.Flags().GetInt("metrics-age")
 	params.maxMetadataAge = maxMetadataAge
+	metricsFile, _ := cmd.Flags().GetString("metrics-file")
+	params.metricsFile = metricsFile
+	maxPartitions, _ := cmd.Flags().GetInt("max-partitions-per-broker")
+	params.maxPartitions = maxPartitions
+	maxTopicPartitions, _ := cmd.Flags().GetInt("max-topic-partitions-per-broker")
+	params.maxTopicPartitions = maxTopicPartitions
+	partitionCapPolicy, _ := cmd.Flags().GetString("partition-cap-policy")
+	params.partitionCapPolicy = partitionCapPolicy
 	minRackIds, _ := cmd.Flags().GetInt("min-rack-ids")
 	params.minRackIds = minRackIds
+	relaxRackAwareness, _ := cmd.Flags().GetBool("relax-rack-awareness")
+	params.relaxRackAwareness = relaxRackAwareness
 	optimize, _ := cmd.Flags().GetString("optimize")
 	params.optimize = optimize
 	optimizeLeadership, _ := cmd.Flags().GetBool("optimize-leadership")
@@ -94,6 +133,8 @@ func rebuildParamsFromCmd(cmd *cobra.Command) (params rebuildParams) {
 	params.partitionSizeFactor = partitionSizeFactor
 	phasedReassignment, _ := cmd.Flags().GetBool("phased-reassignment")
 	params.phasedReassignment = phasedReassignment
+	planSummary, _ := cmd.Flags().GetBool("plan-summary")
+	params.planSummary = planSummary
 	placement, _ := cmd.Flags().GetString("placement")
 	params.placement = placement
 	replication, _ := cmd.Flags().GetInt("replication")
@@ -102,17 +143,27 @@ func rebuildParamsFromCmd(cmd *cobra.Command) (params rebuildParams) {
 	params.skipNoOps = skipNoOps
 	subAffinity, _ := cmd.Flags().GetBool("sub-affinity")
 	params.subAffinity = subAffinity
-	topics, _ := cmd.Flags().GetString("topics")
-	params.topics = strings.Split(topics, ",")
-	topicsExclude, _ := cmd.Flags().GetString("topics-exclude")
-	params.topicsExclude = topicRegex(topicsExclude)
+	topicsFile, _ := cmd.Flags().GetString("topics-file")
+	params.topicsFile = topicsFile
+	// A topics file replaces the --topics default unless --topics is set.
+	if topicsFile == "" || cmd.Flags().Changed("topics") {
+		topics, _ := cmd.Flags().GetString("topics")
+		params.topics = splitTopics(topics)
+	}
+	params.topicsExclude = topicExclusionsFromCmd(cmd)
+	excludeReassigning, _ := cmd.Flags().GetBool("exclude-reassigning")
+	params.excludeReassigning = excludeReassigning
 	useMetadata, _ := cmd.Flags().GetBool("use-meta")
 	params.useMetadata = useMetadata
 	chunkStepSize, _ := cmd.Flags().GetInt("chunk-step-size")
 	params.chunkStepSize = chunkStepSize
+	phaseMaxPartitions, _ := cmd.Flags().GetInt("phase-max-partitions")
+	params.phaseMaxPartitions = phaseMaxPartitions
+	phaseMaxGB, _ := cmd.Flags().GetFloat64("phase-max-gb")
+	params.phaseMaxGB = phaseMaxGB
 	let, _ := cmd.Flags().GetString("leader-evac-topics")
 	if let != "" {
-		params.leaderEvacTopics = strings.Split(let, ",")
+		params.leaderEvacTopics = splitTopics(let)
 	}
 	leb, _ := cmd.Flags().GetString("leader-evac-brokers")
 	if leb != "" {
@@ -123,14 +174,32 @@ func rebuildParamsFromCmd(cmd *cobra.Command) (params rebuildParams) {
 
 func (c rebuildParams) validate() error {
 	switch {
-	case c.mapString == "" && len(c.topics) == 0:
-		return fmt.Errorf("\n[ERROR] must specify either --topics or --map-string")
+	case c.mapString == "" && c.mapFile == "" && len(c.topics) == 0:
+		return fmt.Errorf("\n[ERROR] must specify either --topics, --topics-file, --map-string or --map-file")
+	case c.mapString != "" && c.mapFile != "":
+		return fmt.Errorf("\n[ERROR] --map-string and --map-file cannot be used together")
+	case c.topicsFile != "" && (c.mapString != "" || c.mapFile != ""):
+		return fmt.Errorf("\n[ERROR] --topics-file cannot be used with --map-string or --map-file")
+	case len(c.topicBrokers) > 0 && len(c.evacuateBrokers) > 0:
+		return fmt.Errorf("\n[ERROR] per-topic broker lists in --topics-file are not supported by evacuate")
+	case len(c.topicBrokers) > 0 && (c.chunkStepSize > 0 || c.phasedReassignment || c.phaseMaxPartitions > 0 || c.phaseMaxGB > 0):
+		return fmt.Errorf("\n[ERROR] per-topic broker lists in --topics-file cannot be used with --chunk-step-size, --phased-reassignment or phasing")
 	case c.placement != "count" && c.placement != "storage":
 		return fmt.Errorf("\n[ERROR] --placement must be either 'count' or 'storage'")
 	case c.optimize != "distribution" && c.optimize != "storage":
 		return fmt.Errorf("\n[ERROR] --optimize must be either 'distribution' or 'storage'")
 	case !c.useMetadata && c.placement == "storage":
 		return fmt.Errorf("\n[ERROR] --placement=storage requires --use-meta=true")
+	case c.metricsFile != "" && c.placement != "storage" && c.phaseMaxGB == 0 && !c.planSummary:
+		return fmt.Errorf("\n[ERROR] --metrics-file requires --placement=storage, --phase-max-gb or --plan-summary")
+	case c.maxPartitions < 0 || c.maxTopicPartitions < 0:
+		return fmt.Errorf("\n[ERROR] --max-partitions-per-broker and --max-topic-partitions-per-broker must be >= 0")
+	case c.partitionCapPolicy != "spill" && c.partitionCapPolicy != "error":
+		return fmt.Errorf("\n[ERROR] --partition-cap-policy must be either 'spill' or 'error'")
+	case c.phaseMaxPartitions < 0 || c.phaseMaxGB < 0:
+		return fmt.Errorf("\n[ERROR] --phase-max-partitions and --phase-max-gb must be >= 0")
+	case (c.phaseMaxPartitions > 0 || c.phaseMaxGB > 0) && (c.chunkStepSize > 0 || c.phasedReassignment):
+		return fmt.Errorf("\n[ERROR] --phase-max-partitions and --phase-max-gb cannot be used with --chunk-step-size or --phased-reassignment")
 	case c.forceRebuild && c.subAffinity:
 		return fmt.Errorf("\n[INFO] --force-rebuild disables --sub-affinity")
 	case (len(c.leaderEvacBrokers) != 0 || len(c.leaderEvacTopics) != 0) && (len(c.leaderEvacBrokers) == 0 || len(c.leaderEvacTopics) == 0):
@@ -141,40 +210,59 @@ func (c rebuildParams) validate() error {
 
 func rebuild(cmd *cobra.Command, _ []string) {
 	sanitizeInput(cmd)
+	apply := applyParamsFromCmd(cmd)
+	if err := apply.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
 	params := rebuildParamsFromCmd(cmd)
 
+	if err := params.applyTopicsFile(); err != nil {
+		logError(fmt.Errorf("\n[ERROR] %s", err), nil)
+		defaultsAndExit()
+	}
+
 	err := params.validate()
 	if err != nil {
-		fmt.Println(err)
+		logError(err, nil)
 		defaultsAndExit()
 	}
 	if params.forceRebuild && params.subAffinity {
-		fmt.Println("\n[INFO] --force-rebuild disables --sub-affinity")
+		logInfo("\n[INFO] --force-rebuild disables --sub-affinity", nil)
 	}
 
 	// Init kafkaadmin client.
 	bs := cmd.Parent().Flag("kafka-addr").Value.String()
 	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
-	// ZooKeeper init.
+	// ZooKeeper init. Storage metrics and partition sizes are read from
+	// ZooKeeper under --zk-metrics-prefix unless a --metrics-file is provided.
 	var zk kafkazk.Handler
-	if params.useMetadata || len(params.topics) > 0 || params.placement == "storage" {
+	zkMetrics := params.metricsFile == "" && (params.placement == "storage" || params.phaseMaxGB > 0 || params.planSummary)
+	if params.useMetadata || len(params.topics) > 0 || zkMetrics {
 		zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
 		kafkaPrefix := cmd.Parent().Flag("zk-prefix").Value.String()
 		metricsPrefix := cmd.Flag("zk-metrics-prefix").Value.String()
 		zk, err = initZooKeeper(zkAddr, kafkaPrefix, metricsPrefix)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitOnErr(err)
 		}
 		defer zk.Close()
 	}
 
-	maps, errs := runRebuild(params, ka, zk)
+	var maps []*mapper.PartitionMap
+	var errs []error
+
+	// Topics with per-topic broker lists are rebuilt in groups.
+	if len(params.topicBrokers) > 0 {
+		maps, errs = runRebuildGroups(params, ka, zk)
+	} else {
+		maps, errs = runRebuild(params, ka, zk)
+	}
 
 	// Print error/warnings.
 	handleOverridableErrs(cmd, errs)
@@ -182,4 +270,9 @@ func rebuild(cmd *cobra.Command, _ []string) {
 	outPath := cmd.Flag("out-path").Value.String()
 	outFile := cmd.Flag("out-file").Value.String()
 	writeMaps(outPath, outFile, maps)
+
+	// Submit the reassignments if configured.
+	if err := applyMaps(apply, ka, maps, os.Stdin); err != nil {
+		exitOnErr(err)
+	}
 }
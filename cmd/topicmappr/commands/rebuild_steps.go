@@ -3,7 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
-	"os"
+	"sort"
 
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
@@ -31,40 +31,52 @@ func runRebuild(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handl
 	if len(params.leaderEvacTopics) != 0 {
 		tState, err := ka.DescribeTopics(context.Background(), params.leaderEvacTopics)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitOnErr(err)
 		}
 		evacTopics = tState.List()
 
 	}
 
-	// Fetch broker metadata.
-	var withMetrics bool
-	if params.placement == "storage" {
-		if err := checkMetaAge(zk, params.maxMetadataAge); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+	// Fetch broker metadata. Storage metrics are required for the storage
+	// placement strategy and partition sizes for phasing by size and the plan
+	// summary; these are read from either ZooKeeper or the metrics file.
+	withMetrics := params.placement == "storage"
+	withPartitionMeta := withMetrics || params.phaseMaxGB > 0 || params.planSummary
+	var metrics *metricsFile
+	if withPartitionMeta {
+		switch params.metricsFile {
+		case "":
+			if err := checkMetaAge(zk, params.maxMetadataAge); err != nil {
+				exitOnErr(err)
+			}
+		default:
+			if metrics, err = loadMetricsFile(params.metricsFile, params.maxMetadataAge); err != nil {
+				exitOnErr(err)
+			}
 		}
-		withMetrics = true
 	}
 
 	var brokerMeta mapper.BrokerMetaMap
 	var errs []error
 	if params.useMetadata {
-		if brokerMeta, errs = getBrokerMeta(ka, zk, withMetrics); errs != nil && brokerMeta == nil {
-			for _, e := range errs {
-				fmt.Println(e)
+		if brokerMeta, errs = getBrokerMeta(ka, zk, withMetrics && metrics == nil); errs != nil && brokerMeta == nil {
+			exitOnErr(errs...)
+		}
+
+		if withMetrics && metrics != nil {
+			if errs := applyBrokerMetrics(brokerMeta, metrics.BrokerMetrics); errs != nil {
+				exitOnErr(errs...)
 			}
-			os.Exit(1)
 		}
 	}
 
 	// Fetch partition metadata.
 	var partitionMeta mapper.PartitionMetaMap
-	if params.placement == "storage" {
-		if partitionMeta, err = getPartitionMeta(zk); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+	if withPartitionMeta {
+		if metrics != nil {
+			partitionMeta = metrics.PartitionMeta
+		} else if partitionMeta, err = getPartitionMeta(zk); err != nil {
+			exitOnErr(err)
 		}
 	}
 
@@ -91,10 +103,7 @@ func runRebuild(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handl
 	// missing/partial metrics data.
 	if params.useMetadata {
 		if errs := ensureBrokerMetrics(brokers, brokerMeta); len(errs) > 0 {
-			for _, e := range errs {
-				fmt.Println(e)
-			}
-			os.Exit(1)
+			exitOnErr(errs...)
 		}
 	}
 
@@ -108,16 +117,33 @@ func runRebuild(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handl
 	// Print changes, actions.
 	printChangesActions(params, bs)
 
-	// Apply any replication factor settings.
-	updateReplicationFactor(params, partitionMapIn)
+	// Ensure that the replication factor can be satisfied, then apply any
+	// replication factor settings.
+	if err := checkReplication(params, brokers); err != nil {
+		exitOnErr(err)
+	}
+
+	replicationErrs := updateReplicationFactor(params, partitionMapIn, partitionMeta, brokers)
 
 	// Build a new map using the provided list of brokers. This is OK to run even
 	// when a no-op is intended.
 	partitionMapOut, errs := buildMap(params, partitionMapIn, partitionMeta, brokers, affinities)
+	errs = append(errs, replicationErrs...)
+
+	// Check partition caps. Caps that couldn't be satisfied by the spill policy,
+	// such as with existing placements, are reported as warnings.
+	if capErrs := partitionCapErrors(partitionMapOut, params.maxPartitions, params.maxTopicPartitions); len(capErrs) > 0 {
+		if params.partitionCapPolicy == "error" {
+			exitOnErr(capErrs...)
+		}
+		errs = append(errs, capErrs...)
+	}
 
 	// Optimize leaders.
 	if params.optimizeLeadership {
+		unoptimized := partitionMapOut.Copy()
 		partitionMapOut.OptimizeLeaderFollower()
+		printLeadershipChanges(unoptimized, partitionMapOut)
 	}
 
 	// Count missing brokers as a warning.
@@ -143,25 +169,48 @@ func runRebuild(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handl
 	// Print map change results.
 	printMapChanges(originalMap, partitionMapOut)
 
+	// Report any rack constraint violations accepted.
+	if params.relaxRackAwareness {
+		printRackViolations(rackViolations(partitionMapOut, brokers, params.minRackIds))
+	}
+
 	// Print broker assignment statistics.
 	errs = append(
 		errs,
 		printBrokerAssignmentStats(originalMap, partitionMapOut, brokersOrig, brokers, params.placement == "storage", params.partitionSizeFactor)...,
 	)
 
+	// Print the broker balance scorecard.
+	printScorecard(originalMap, partitionMapOut, brokersOrig, brokers, params.placement == "storage")
+
+	// Print the projected per-broker sizes where partition sizes are known.
+	if partitionMeta != nil {
+		errs = append(errs, printPlanSummary(originalMap, partitionMapOut, partitionMeta, brokers, params.placement == "storage")...)
+	}
+
 	// Skip no-ops if configured.
 	if params.skipNoOps {
 		originalMap, partitionMapOut = skipReassignmentNoOps(originalMap, partitionMapOut)
 	}
 
+	switch {
 	// If this is a getPartitionMapChunks operation, break it up into smaller operations and list those as intermediate maps.
-	if params.chunkStepSize > 0 {
+	case params.chunkStepSize > 0:
 		fmt.Printf("\n\nGenerating reassignments in chunks %d brokers at a time: \n\n", params.chunkStepSize)
 		mapChunks := getPartitionMapChunks(partitionMapOut, originalMap, brokersOrig.List(), params.chunkStepSize)
 		for _, chunk := range mapChunks {
 			outputMaps = append(outputMaps, chunk)
 		}
-	} else {
+	// If phases are limited by partition count or size, split the changes into
+	// sequential phases.
+	case params.phaseMaxPartitions > 0 || params.phaseMaxGB > 0:
+		phases, phaseErrs := splitReassignmentPhases(originalMap, partitionMapOut, partitionMeta, params.phaseMaxPartitions, params.phaseMaxGB*div)
+		errs = append(errs, phaseErrs...)
+		printReassignmentPhases(phases)
+		for _, p := range phases {
+			outputMaps = append(outputMaps, p.partitionMap)
+		}
+	default:
 		outputMaps = append(outputMaps, partitionMapOut)
 	}
 
@@ -174,40 +223,50 @@ func runRebuild(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handl
 // getPartitionMap returns a map of of partition, topic config (particuarly what
 // brokers compose every replica set) for all topics specified. A partition map
 // is either built from a string literal input (json from off-the-shelf Kafka
-// tools output) provided via the ---map-string flag, or, by building a map based
+// tools output) provided via the ---map-string flag or read from the file provided
+// via the --map-file flag, or, by building a map based
 // on topic config found in ZooKeeper for all topics matching input provided
 // via the --topics flag. Two []string are returned; topics excluded due to
-// pending deletion and topics explicitly excluded (via the --topics-exclude
-// flag), respectively.
+// pending deletion and topics explicitly excluded (via the --exclude-topics,
+// --exclude-internal and --exclude-reassigning flags), respectively.
 func getPartitionMap(params rebuildParams, ka kafkaadmin.KafkaAdmin) (*mapper.PartitionMap, []string, []string) {
+	var pm *mapper.PartitionMap
+	var err error
 
 	switch {
 	// The map was provided as text.
 	case params.mapString != "":
 		// Get a deserialized map.
-		pm, err := mapper.PartitionMapFromString(params.mapString)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		if pm, err = mapper.PartitionMapFromString(params.mapString); err != nil {
+			exitOnErr(err)
+		}
+	// The map was provided as a file.
+	case params.mapFile != "":
+		if pm, err = mapper.PartitionMapFromFile(params.mapFile); err != nil {
+			exitOnErr(err)
 		}
-		// Exclude topics explicitly listed.
-		et := removeTopics(pm, params.topicsExclude)
-		return pm, []string{}, et
 	// The map needs to be fetched via ZooKeeper metadata for all specified topics.
 	case len(params.topics) > 0:
-		pm, err := getPartitionMaps(ka, params.topics)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		if pm, err = getPartitionMaps(ka, params.topics); err != nil {
+			exitOnErr(err)
 		}
+	default:
+		return nil, nil, nil
+	}
 
-		// Exclude topics explicitly listed.
-		et := removeTopics(pm, params.topicsExclude)
+	// Exclude topics explicitly listed.
+	et := removeTopics(pm, params.topicsExclude)
 
-		return pm, nil, et
+	// Exclude topics being reassigned.
+	if params.excludeReassigning {
+		reassigning, err := removeReassigningTopics(ka, pm)
+		if err != nil {
+			exitOnErr(err)
+		}
+		et = append(et, reassigning...)
 	}
 
-	return nil, nil, nil
+	return pm, nil, et
 }
 
 // getSubAffinities, if enabled via --sub-affinity, takes reference broker maps
@@ -219,8 +278,7 @@ func getSubAffinities(params rebuildParams, bm mapper.BrokerMap, bmo mapper.Brok
 		var err error
 		affinities, err = bm.SubstitutionAffinities(pm)
 		if err != nil {
-			fmt.Printf("Substitution affinity error: %s\n", err.Error())
-			os.Exit(1)
+			exitOnErr(fmt.Errorf("Substitution affinity error: %s", err))
 		}
 	}
 
@@ -254,8 +312,15 @@ func getBrokers(params rebuildParams, pm *mapper.PartitionMap, bm mapper.BrokerM
 	// If meta data isn't being looked up, brokerMeta will be empty.
 	brokers := mapper.BrokerMapFromPartitionMap(pm, bm, params.forceRebuild)
 
+	// Brokers being evacuated are excluded from the provided broker list so
+	// that they're marked for removal.
+	brokerList := params.brokers
+	if len(params.evacuateBrokers) > 0 {
+		brokerList = excludeBrokers(params.brokers, params.evacuateBrokers, brokers, bm)
+	}
+
 	// Update the currentBrokers list with the provided broker list.
-	bs, msgs := brokers.Update(params.brokers, bm)
+	bs, msgs := brokers.Update(brokerList, bm)
 	for m := range msgs {
 		fmt.Printf("%s%s\n", indent, m)
 	}
@@ -315,14 +380,167 @@ func printChangesActions(params rebuildParams, bs *mapper.BrokerStatus) {
 }
 
 // updateReplicationFactor takes a PartitionMap and normalizes the replica set
-// length to an optionally provided value.
-func updateReplicationFactor(params rebuildParams, pm *mapper.PartitionMap) {
+// length to an optionally provided value. Errors are returned for replica sets
+// that no longer span the required number of rack IDs after a decrease.
+func updateReplicationFactor(params rebuildParams, pm *mapper.PartitionMap, pmm mapper.PartitionMetaMap, bm mapper.BrokerMap) []error {
+	if params.replication == 0 {
+		return nil
+	}
+
 	// If the replication factor is changed, the partition map input needs to have
-	// stub brokers appended (r factor increase) or existing brokers removed
-	// (r factor decrease).
-	if params.replication > 0 {
-		pm.SetReplication(params.replication)
+	// existing brokers removed (r factor decrease) or stub brokers appended (r
+	// factor increase). Replicas to remove are chosen to balance storage or
+	// partition counts while preserving rack diversity.
+	errs := pm.ReduceReplication(params.replication, mapper.RebuildParams{
+		PMM:                pmm,
+		BM:                 bm,
+		Strategy:           params.placement,
+		PartnSzFactor:      params.partitionSizeFactor,
+		MinUniqueRackIDs:   params.minRackIds,
+		RelaxRackAwareness: params.relaxRackAwareness,
+	})
+
+	pm.SetReplication(params.replication)
+
+	return errs
+}
+
+// checkReplication returns an error if the replication factor provided via
+// --replication can't be satisfied by the brokers not marked for replacement.
+// Where all brokers have rack IDs, the replication factor is also checked
+// against the number of unique rack IDs required per replica set by
+// --min-rack-ids, unless --relax-rack-awareness is set.
+func checkReplication(params rebuildParams, bm mapper.BrokerMap) error {
+	if params.replication == 0 {
+		return nil
+	}
+
+	var brokers int
+	racks := map[string]struct{}{}
+	rackMissing := false
+
+	for id, b := range bm {
+		if id == mapper.StubBrokerID || b.Replace {
+			continue
+		}
+
+		brokers++
+
+		if b.Locality == "" {
+			rackMissing = true
+		}
+		racks[b.Locality] = struct{}{}
 	}
+
+	if params.replication > brokers {
+		return fmt.Errorf("replication factor %d exceeds the %d available brokers", params.replication, brokers)
+	}
+
+	// A --min-rack-ids of 0 requires that all rack IDs are unique.
+	required := params.replication
+	if params.minRackIds > 0 && params.minRackIds < required {
+		required = params.minRackIds
+	}
+
+	if !rackMissing && !params.relaxRackAwareness && required > len(racks) {
+		return fmt.Errorf("replication factor %d requires %d unique rack IDs, %d available (see --min-rack-ids)",
+			params.replication, required, len(racks))
+	}
+
+	return nil
+}
+
+// rackViolation describes a replica set spanning fewer unique rack IDs than
+// required.
+type rackViolation struct {
+	topic     string
+	partition int
+	replicas  []int
+	racks     int
+	required  int
+}
+
+// rackViolations returns the replica sets in the PartitionMap that span fewer
+// unique rack IDs than required, as accepted with --relax-rack-awareness. A
+// minRackIds of 0 requires that all rack IDs are unique. Replica sets with
+// brokers without a rack ID are skipped.
+func rackViolations(pm *mapper.PartitionMap, bm mapper.BrokerMap, minRackIds int) []rackViolation {
+	var violations []rackViolation
+
+	for _, p := range pm.Partitions {
+		required := len(p.Replicas)
+		if minRackIds > 0 && minRackIds < required {
+			required = minRackIds
+		}
+
+		racks := map[string]struct{}{}
+		for _, id := range p.Replicas {
+			var rack string
+			if b, exists := bm[id]; exists {
+				rack = b.Locality
+			}
+			racks[rack] = struct{}{}
+		}
+
+		if _, unknown := racks[""]; unknown || len(racks) >= required {
+			continue
+		}
+
+		violations = append(violations, rackViolation{
+			topic:     p.Topic,
+			partition: p.Partition,
+			replicas:  p.Replicas,
+			racks:     len(racks),
+			required:  required,
+		})
+	}
+
+	return violations
+}
+
+// partitionCapErrors returns an error for each broker holding more than
+// maxTotal partition replicas in the PartitionMap, and for each topic and
+// broker where the broker holds more than maxTopic replicas of the topic. A
+// limit of 0 is unlimited.
+func partitionCapErrors(pm *mapper.PartitionMap, maxTotal, maxTopic int) []error {
+	var errs []error
+
+	if maxTotal > 0 {
+		stats := pm.UseStats()
+		for _, s := range stats.List() {
+			if n := s.Leader + s.Follower; n > maxTotal {
+				errs = append(errs, fmt.Errorf("broker %d holds %d partition replicas, exceeding the limit of %d", s.ID, n, maxTotal))
+			}
+		}
+	}
+
+	if maxTopic > 0 {
+		for _, t := range pm.Topics() {
+			counts := map[int]int{}
+			for _, p := range pm.Partitions {
+				if p.Topic != t {
+					continue
+				}
+				for _, id := range p.Replicas {
+					counts[id]++
+				}
+			}
+
+			var ids []int
+			for id := range counts {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+
+			for _, id := range ids {
+				if counts[id] > maxTopic {
+					errs = append(errs, fmt.Errorf("broker %d holds %d %s partition replicas, exceeding the limit of %d", id, counts[id], t, maxTopic))
+				}
+			}
+		}
+	}
+
+	return errs
 }
 
 // buildMap takes an input PartitionMap, rebuild parameters, and all partition/broker
@@ -330,16 +548,22 @@ func updateReplicationFactor(params rebuildParams, pm *mapper.PartitionMap) {
 // warnings / advisories is returned if any are encountered.
 func buildMap(params rebuildParams, pm *mapper.PartitionMap, pmm mapper.PartitionMetaMap, bm mapper.BrokerMap, af mapper.SubstitutionAffinities) (*mapper.PartitionMap, errors) {
 	rebuildParams := mapper.RebuildParams{
-		PMM:              pmm,
-		BM:               bm,
-		Strategy:         params.placement,
-		Optimization:     params.optimize,
-		PartnSzFactor:    params.partitionSizeFactor,
-		MinUniqueRackIDs: params.minRackIds,
+		PMM:                pmm,
+		BM:                 bm,
+		Strategy:           params.placement,
+		Optimization:       params.optimize,
+		PartnSzFactor:      params.partitionSizeFactor,
+		MinUniqueRackIDs:   params.minRackIds,
+		RelaxRackAwareness: params.relaxRackAwareness,
 	}
 	if af != nil {
 		rebuildParams.Affinities = af
 	}
+	// Partition caps are enforced in placement with the spill policy.
+	if params.partitionCapPolicy == "spill" {
+		rebuildParams.MaxReplicasPerBroker = params.maxPartitions
+		rebuildParams.MaxTopicReplicasPerBroker = params.maxTopicPartitions
+	}
 
 	// If we're doing a force rebuild, the input map must have all brokers stripped out.
 	// A few notes about doing force rebuilds:
@@ -362,8 +586,7 @@ func buildMap(params rebuildParams, pm *mapper.PartitionMap, pmm mapper.Partitio
 		if params.placement == "storage" {
 			err := rebuildParams.BM.SubStorage(pm, pmm, mapper.AllBrokersFn)
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				exitOnErr(err)
 			}
 		}
 
@@ -375,8 +598,7 @@ func buildMap(params rebuildParams, pm *mapper.PartitionMap, pmm mapper.Partitio
 	if params.placement == "storage" {
 		err := rebuildParams.BM.SubStorage(pm, pmm, mapper.ReplacedBrokersFn)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitOnErr(err)
 		}
 	}
 
@@ -473,8 +695,7 @@ func evacuateLeadership(partitionMapIn mapper.PartitionMap, evacBrokers []int, e
 
 			// If we've tried every replica, but they are all being leader evac'd.
 			if replica == p.Replicas[len(p.Replicas)-1] {
-				fmt.Println("[ERROR] trying to evict all replicas at once")
-				os.Exit(1)
+				exitOnErr(fmt.Errorf("[ERROR] trying to evict all replicas at once"))
 			}
 		}
 	}
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
 )
 
 func TestNotInReplicaSet(t *testing.T) {
@@ -53,3 +56,158 @@ func TestPhasedReassignment(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckReplication(t *testing.T) {
+	bm := mapper.BrokerMap{
+		mapper.StubBrokerID: &mapper.Broker{ID: mapper.StubBrokerID, Replace: true},
+		1001:                &mapper.Broker{ID: 1001, Locality: "a"},
+		1002:                &mapper.Broker{ID: 1002, Locality: "b"},
+		1003:                &mapper.Broker{ID: 1003, Locality: "b"},
+		1004:                &mapper.Broker{ID: 1004, Locality: "c", Replace: true},
+	}
+
+	tests := []struct {
+		params rebuildParams
+		ok     bool
+	}{
+		{params: rebuildParams{replication: 0}, ok: true},
+		{params: rebuildParams{replication: 2}, ok: true},
+		// Only two unique rack IDs among non-replaced brokers.
+		{params: rebuildParams{replication: 3}, ok: false},
+		{params: rebuildParams{replication: 3, minRackIds: 2}, ok: true},
+		// Only three non-replaced brokers.
+		{params: rebuildParams{replication: 4, minRackIds: 1}, ok: false},
+		// Rack ID constraints are relaxed.
+		{params: rebuildParams{replication: 3, relaxRackAwareness: true}, ok: true},
+	}
+
+	for i, test := range tests {
+		if err := checkReplication(test.params, bm); (err == nil) != test.ok {
+			t.Errorf("[test %d] Unexpected result: %v", i, err)
+		}
+	}
+
+	// Rack ID constraints aren't checked where rack IDs are missing.
+	bm[1001].Locality = ""
+	if err := checkReplication(rebuildParams{replication: 3}, bm); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestRackViolations(t *testing.T) {
+	bm := mapper.BrokerMap{
+		1001: &mapper.Broker{ID: 1001, Locality: "a"},
+		1002: &mapper.Broker{ID: 1002, Locality: "a"},
+		1003: &mapper.Broker{ID: 1003, Locality: "b"},
+		1004: &mapper.Broker{ID: 1004},
+	}
+
+	pm := mapper.NewPartitionMap()
+	pm.Partitions = mapper.PartitionList{
+		{Topic: "test", Partition: 0, Replicas: []int{1001, 1003}},
+		{Topic: "test", Partition: 1, Replicas: []int{1001, 1002}},
+		{Topic: "test", Partition: 2, Replicas: []int{1001, 1002, 1003}},
+		// Unknown rack IDs are skipped.
+		{Topic: "test", Partition: 3, Replicas: []int{1001, 1004}},
+	}
+
+	v := rackViolations(pm, bm, 0)
+	if len(v) != 2 || v[0].partition != 1 || v[1].partition != 2 {
+		t.Fatalf("Unexpected violations: %+v", v)
+	}
+
+	if v[1].racks != 2 || v[1].required != 3 {
+		t.Errorf("Unexpected violation: %+v", v[1])
+	}
+
+	// Two unique rack IDs satisfy p2.
+	if v := rackViolations(pm, bm, 2); len(v) != 1 || v[0].partition != 1 {
+		t.Errorf("Unexpected violations: %+v", v)
+	}
+}
+
+func TestBuildMapForceRebuild(t *testing.T) {
+	pm, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002]},
+		{"topic":"test","partition":1,"replicas":[1001,1002]},
+		{"topic":"test","partition":2,"replicas":[1001,1002]},
+		{"topic":"test","partition":3,"replicas":[1001,1002]}]}`)
+
+	brokers := func(force bool) mapper.BrokerMap {
+		bm := mapper.BrokerMapFromPartitionMap(pm, nil, force)
+		bm[1003] = &mapper.Broker{ID: 1003, New: true}
+		bm[1004] = &mapper.Broker{ID: 1004, New: true}
+		return bm
+	}
+
+	params := rebuildParams{placement: "count", optimize: "distribution", partitionSizeFactor: 1}
+
+	// The current map is kept where no brokers are marked for replacement.
+	out, errs := buildMap(params, pm, nil, brokers(false), nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if eq, _ := pm.Equal(out); !eq {
+		t.Errorf("Expected an unchanged map, got %v", out.Partitions)
+	}
+
+	// A force rebuild places all replicas from scratch.
+	params.forceRebuild = true
+	out, errs = buildMap(params, pm, nil, brokers(true), nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	stats := out.UseStats()
+	for _, id := range []int{1001, 1002, 1003, 1004} {
+		if s, exists := stats[id]; !exists || s.Leader+s.Follower != 2 {
+			t.Errorf("Expected 2 replicas on broker %d, got %+v", id, s)
+		}
+	}
+}
+
+func TestPartitionCapErrors(t *testing.T) {
+	pm, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"a","partition":0,"replicas":[1001,1002]},
+		{"topic":"a","partition":1,"replicas":[1001,1003]},
+		{"topic":"b","partition":0,"replicas":[1001,1002]}]}`)
+
+	if errs := partitionCapErrors(pm, 0, 0); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	// 1001 holds 3 replicas.
+	if errs := partitionCapErrors(pm, 2, 0); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+
+	// 1001 holds 2 replicas of topic a.
+	if errs := partitionCapErrors(pm, 0, 1); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+}
+
+func TestGetPartitionMapFromFile(t *testing.T) {
+	path := t.TempDir() + "/map.json"
+	pm, _ := mapper.PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002]},
+		{"topic":"excluded","partition":0,"replicas":[1001,1002]}]}`)
+	if err := mapper.WriteMap(pm, strings.TrimSuffix(path, ".json")); err != nil {
+		t.Fatal(err)
+	}
+
+	params := rebuildParams{
+		mapFile:       path,
+		topicsExclude: []*regexp.Regexp{regexp.MustCompile("^excluded$")},
+	}
+
+	out, _, excluded := getPartitionMap(params, nil)
+	if len(out.Partitions) != 1 || out.Partitions[0].Topic != "test" {
+		t.Errorf("Unexpected partition map: %v", out.Partitions)
+	}
+
+	if len(excluded) != 1 || excluded[0] != "excluded" {
+		t.Errorf("Unexpected excluded topics: %v", excluded)
+	}
+}
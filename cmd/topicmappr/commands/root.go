@@ -1,15 +1,13 @@
 package commands
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/jamiealquiza/envy"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
-	Use: "topicmappr",
+	Use:               "topicmappr",
+	PersistentPreRunE: initLogging,
 }
 
 // Execute rootCmd.
@@ -17,8 +15,7 @@ func Execute() {
 	envy.ParseCobra(rootCmd, envy.CobraConfig{Prefix: "TOPICMAPPR", Persistent: true, Recursive: false})
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 }
 
@@ -26,6 +23,7 @@ func init() {
 	rootCmd.PersistentFlags().String("kafka-addr", "localhost:9092", "Kafka bootstrap address")
 	rootCmd.PersistentFlags().String("zk-addr", "localhost:2181", "ZooKeeper connect string")
 	rootCmd.PersistentFlags().String("zk-prefix", "", "ZooKeeper prefix (if Kafka is configured with a chroot path prefix)")
-	rootCmd.PersistentFlags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics")
+	rootCmd.PersistentFlags().String("zk-metrics-prefix", "topicmappr", "ZooKeeper namespace prefix for Kafka metrics, as written by metricsfetcher")
 	rootCmd.PersistentFlags().Bool("ignore-warns", false, "Produce a map even if warnings are encountered")
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format [text, json]; json writes errors and warnings as records to stderr")
 }
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
@@ -12,25 +11,31 @@ import (
 var scaleCmd = &cobra.Command{
 	Use:   "scale",
 	Short: "Redistribute partitions to additional brokers",
-	Long:  `Redistribute partitions to additional brokers`,
-	Run:   scale,
+	Long: `scale moves a proportional share of partitions from all existing brokers
+onto brokers newly provided via the --brokers flag. Only the largest partitions
+needed to bring the new brokers to the mean storage free are relocated; all other
+replica sets are left as-is.`,
+	Run: scale,
 }
 
 func init() {
 	rootCmd.AddCommand(scaleCmd)
 
 	scaleCmd.Flags().String("topics", "", "Rebuild topics (comma delim. list) by lookup in ZooKeeper")
-	scaleCmd.Flags().String("topics-exclude", "", "Exclude topics")
+	addTopicExclusionFlags(scaleCmd)
 	scaleCmd.Flags().String("out-path", "", "Path to write output map files to")
 	scaleCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
 	scaleCmd.Flags().String("brokers", "", "Broker list to scope all partition placements to ('-1' for all currently mapped brokers, '-2' for all brokers in cluster)")
 	scaleCmd.Flags().Float64("tolerance", 0.0, "Percent distance from the mean storage free to limit storage scheduling (0 performs automatic tolerance selection)")
+	scaleCmd.Flags().Float64("max-spread-gb", 0.00, "Target range of broker storage free in gigabytes; relocation planning stops once within the range and the plan with the fewest relocations is chosen (0 [default] chooses the plan with the lowest range)")
+	scaleCmd.Flags().String("objective", "balance", "Plan selection objective: [balance, min-movement]; min-movement chooses the plan relocating the fewest bytes within --max-spread-gb")
 	scaleCmd.Flags().Int("partition-limit", 30, "Limit the number of top partitions by size eligible for relocation per broker")
 	scaleCmd.Flags().Int("partition-size-threshold", 512, "Size in megabytes where partitions below this value will not be moved in a scale")
 	scaleCmd.Flags().Bool("locality-scoped", false, "Ensure that all partition movements are scoped by rack.id")
 	scaleCmd.Flags().Bool("verbose", false, "Verbose output")
 	scaleCmd.Flags().Int("metrics-age", 60, "Kafka metrics age tolerance (in minutes)")
 	scaleCmd.Flags().Bool("optimize-leadership", false, "Scale all broker leader/follower ratios")
+	addApplyFlags(scaleCmd)
 
 	// Required.
 	scaleCmd.MarkFlagRequired("brokers")
@@ -39,17 +44,27 @@ func init() {
 
 func scale(cmd *cobra.Command, _ []string) {
 	sanitizeInput(cmd)
+	apply := applyParamsFromCmd(cmd)
+	if err := apply.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
 	params := reassignParamsFromCmd(cmd)
 	params.requireNewBrokers = true
 
+	if err := params.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
 	// ZooKeeper init.
 	zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
 	kafkaPrefix := cmd.Parent().Flag("zk-prefix").Value.String()
 	metricsPrefix := cmd.Flag("zk-metrics-prefix").Value.String()
 	zk, err := initZooKeeper(zkAddr, kafkaPrefix, metricsPrefix)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	defer zk.Close()
@@ -58,8 +73,7 @@ func scale(cmd *cobra.Command, _ []string) {
 	bs := cmd.Parent().Flag("kafka-addr").Value.String()
 	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnErr(err)
 	}
 
 	partitionMaps, _ := reassign(params, ka, zk)
@@ -71,4 +85,9 @@ func scale(cmd *cobra.Command, _ []string) {
 	outPath := cmd.Flag("out-path").Value.String()
 	outFile := cmd.Flag("out-file").Value.String()
 	writeMaps(outPath, outFile, partitionMaps)
+
+	// Submit the reassignments if configured.
+	if err := applyMaps(apply, ka, partitionMaps, os.Stdin); err != nil {
+		exitOnErr(err)
+	}
 }
@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+
+	"github.com/spf13/cobra"
+)
+
+var swapCmd = &cobra.Command{
+	Use:   "swap",
+	Short: "Substitute brokers one-for-one in all replica sets",
+	Long: `swap replaces each source broker provided via the --swap flag with its
+destination broker in every replica set of the target topics, preserving replica
+positions and leadership. No other replicas are moved, producing the minimal map
+for broker hardware replacements.`,
+	Run: swap,
+}
+
+func init() {
+	rootCmd.AddCommand(swapCmd)
+
+	swapCmd.Flags().String("swap", "", "Comma delim. list of source:destination broker ID pairs (e.g. 1001:1011,1002:1012)")
+	swapCmd.Flags().String("topics", ".*", "Swap brokers for topics (comma delim. list) by lookup in ZooKeeper")
+	swapCmd.Flags().String("map-string", "", "Swap brokers for a partition map provided as a string literal")
+	swapCmd.Flags().String("map-file", "", "Swap brokers for a partition map read from a JSON file")
+	addTopicExclusionFlags(swapCmd)
+	swapCmd.Flags().String("out-path", "", "Path to write output map files to")
+	swapCmd.Flags().String("out-file", "", "If defined, write a combined map of all topics to a file")
+	swapCmd.Flags().Bool("skip-no-ops", true, "Skip no-op partition assigments")
+	addApplyFlags(swapCmd)
+
+	// Required.
+	swapCmd.MarkFlagRequired("swap")
+}
+
+type swapParams struct {
+	swaps              map[int]int
+	mapString          string
+	mapFile            string
+	topics             []string
+	topicsExclude      []*regexp.Regexp
+	excludeReassigning bool
+	skipNoOps          bool
+}
+
+func swapParamsFromCmd(cmd *cobra.Command) (params swapParams, err error) {
+	s, _ := cmd.Flags().GetString("swap")
+	if params.swaps, err = parseBrokerSwaps(s); err != nil {
+		return params, err
+	}
+	mapString, _ := cmd.Flags().GetString("map-string")
+	params.mapString = mapString
+	mapFile, _ := cmd.Flags().GetString("map-file")
+	params.mapFile = mapFile
+	if mapString != "" && mapFile != "" {
+		return params, fmt.Errorf("--map-string and --map-file cannot be used together")
+	}
+	// A provided map takes precedence over the default topics.
+	if mapString == "" && mapFile == "" {
+		topics, _ := cmd.Flags().GetString("topics")
+		params.topics = splitTopics(topics)
+	}
+	params.topicsExclude = topicExclusionsFromCmd(cmd)
+	excludeReassigning, _ := cmd.Flags().GetBool("exclude-reassigning")
+	params.excludeReassigning = excludeReassigning
+	skipNoOps, _ := cmd.Flags().GetBool("skip-no-ops")
+	params.skipNoOps = skipNoOps
+
+	return params, nil
+}
+
+func swap(cmd *cobra.Command, _ []string) {
+	sanitizeInput(cmd)
+	apply := applyParamsFromCmd(cmd)
+	if err := apply.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
+	params, err := swapParamsFromCmd(cmd)
+	if err != nil {
+		logError(fmt.Errorf("\n[ERROR] %s", err), nil)
+		defaultsAndExit()
+	}
+
+	// Init kafkaadmin client.
+	bs := cmd.Parent().Flag("kafka-addr").Value.String()
+	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
+	if err != nil {
+		exitOnErr(err)
+	}
+
+	maps, errs := runSwap(params, ka)
+
+	// Print error/warnings.
+	handleOverridableErrs(cmd, errs)
+
+	outPath := cmd.Flag("out-path").Value.String()
+	outFile := cmd.Flag("out-file").Value.String()
+	writeMaps(outPath, outFile, maps)
+
+	// Submit the reassignments if configured.
+	if err := applyMaps(apply, ka, maps, os.Stdin); err != nil {
+		exitOnErr(err)
+	}
+}
+
+// runSwap substitutes the swapped brokers in the partition map of the target
+// topics. Errors are returned for replica sets that can't be substituted,
+// destination brokers not found in the cluster and destination brokers with a
+// rack ID differing from the source broker.
+func runSwap(params swapParams, ka kafkaadmin.KafkaAdmin) ([]*mapper.PartitionMap, []error) {
+	partitionMapIn, _, excluded := getPartitionMap(rebuildParams{
+		mapString:          params.mapString,
+		mapFile:            params.mapFile,
+		topics:             params.topics,
+		topicsExclude:      params.topicsExclude,
+		excludeReassigning: params.excludeReassigning,
+	}, ka)
+	originalMap := partitionMapIn.Copy()
+
+	printTopics(partitionMapIn)
+	printExcludedTopics(nil, excluded)
+
+	brokerMeta, errs := getBrokerMeta(ka, nil, false)
+	if errs != nil && brokerMeta == nil {
+		exitOnErr(errs...)
+	}
+
+	errs = append(errs, swapErrors(params.swaps, brokerMeta)...)
+	printBrokerSwaps(params.swaps)
+
+	partitionMapOut := partitionMapIn.Copy()
+	errs = append(errs, partitionMapOut.SubstituteBrokers(params.swaps)...)
+
+	// Print map change results.
+	printMapChanges(originalMap, partitionMapOut)
+
+	// Skip no-ops if configured.
+	if params.skipNoOps {
+		_, partitionMapOut = skipReassignmentNoOps(originalMap, partitionMapOut)
+	}
+
+	return []*mapper.PartitionMap{partitionMapOut}, errs
+}
+
+// parseBrokerSwaps takes a comma delimited list of source:destination broker
+// ID pairs and returns a map of source to destination IDs.
+func parseBrokerSwaps(s string) (map[int]int, error) {
+	swaps := map[int]int{}
+	destinations := map[int]struct{}{}
+
+	for _, pair := range strings.Split(s, ",") {
+		ids := strings.Split(strings.TrimSpace(pair), ":")
+		if len(ids) != 2 {
+			return nil, fmt.Errorf("invalid broker swap '%s'; expected source:destination", pair)
+		}
+
+		src, err := strconv.Atoi(ids[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker swap '%s': %s", pair, err)
+		}
+
+		dst, err := strconv.Atoi(ids[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker swap '%s': %s", pair, err)
+		}
+
+		_, swapped := swaps[src]
+		_, dup := destinations[dst]
+
+		switch {
+		case src == dst:
+			return nil, fmt.Errorf("invalid broker swap '%s'; source and destination are the same", pair)
+		case swapped:
+			return nil, fmt.Errorf("broker %d is swapped more than once", src)
+		case dup:
+			return nil, fmt.Errorf("broker %d is the destination of more than one swap", dst)
+		}
+
+		swaps[src] = dst
+		destinations[dst] = struct{}{}
+	}
+
+	return swaps, nil
+}
+
+// swapErrors returns an error for each destination broker not found in the
+// BrokerMetaMap or with a rack ID differing from the source broker.
+func swapErrors(swaps map[int]int, bm mapper.BrokerMetaMap) []error {
+	var errs []error
+
+	for _, src := range sortedSwapSources(swaps) {
+		dst := swaps[src]
+
+		dstMeta, exists := bm[dst]
+		if !exists {
+			errs = append(errs, fmt.Errorf("destination broker %d not found in cluster", dst))
+			continue
+		}
+
+		if srcMeta, exists := bm[src]; exists && srcMeta.Rack != dstMeta.Rack {
+			errs = append(errs, fmt.Errorf("destination broker %d rack ID '%s' differs from broker %d rack ID '%s'",
+				dst, dstMeta.Rack, src, srcMeta.Rack))
+		}
+	}
+
+	return errs
+}
+
+// printBrokerSwaps prints the broker substitutions.
+func printBrokerSwaps(swaps map[int]int) {
+	fmt.Printf("\nBroker swaps:\n")
+	for _, src := range sortedSwapSources(swaps) {
+		fmt.Printf("%s%d -> %d\n", indent, src, swaps[src])
+	}
+}
+
+// sortedSwapSources returns the source broker IDs of the swaps, sorted.
+func sortedSwapSources(swaps map[int]int) []int {
+	var ids []int
+	for id := range swaps {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids
+}
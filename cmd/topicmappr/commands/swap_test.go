@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestParseBrokerSwaps(t *testing.T) {
+	swaps, err := parseBrokerSwaps("1001:1011, 1002:1012")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(swaps) != 2 || swaps[1001] != 1011 || swaps[1002] != 1012 {
+		t.Errorf("Unexpected swaps: %v", swaps)
+	}
+
+	for _, s := range []string{"", "1001", "1001:a", "1001:1001", "1001:1011,1001:1012", "1001:1011,1002:1011"} {
+		if _, err := parseBrokerSwaps(s); err == nil {
+			t.Errorf("Expected error for '%s'", s)
+		}
+	}
+}
+
+func TestSwapErrors(t *testing.T) {
+	bm := mapper.BrokerMetaMap{
+		1001: &mapper.BrokerMeta{Rack: "a"},
+		1002: &mapper.BrokerMeta{Rack: "b"},
+		1011: &mapper.BrokerMeta{Rack: "a"},
+		1012: &mapper.BrokerMeta{Rack: "a"},
+	}
+
+	// 1012 is in a different rack and 1013 doesn't exist.
+	errs := swapErrors(map[int]int{1001: 1011, 1002: 1012, 1003: 1013}, bm)
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+}
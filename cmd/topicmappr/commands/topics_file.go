@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+// topicsFileEntry is a topic name or regex pattern read from a topics file
+// along with its optional target broker list.
+type topicsFileEntry struct {
+	topic   string
+	brokers []int
+}
+
+// topicGroup is a set of target topics sharing a target broker list.
+type topicGroup struct {
+	topics  []string
+	brokers []int
+}
+
+// loadTopicsFile reads the topics file at path.
+func loadTopicsFile(path string) ([]topicsFileEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading topics file: %s", err)
+	}
+
+	defer f.Close()
+
+	return readTopicsFile(f)
+}
+
+// readTopicsFile reads a newline delimited list of topic names and/or regex
+// patterns. Each topic may be followed by whitespace and a comma delimited
+// target broker list for the topic, e.g.:
+//
+//	# Checkout topics.
+//	orders 1001,1002,1003
+//	^payments-.*
+//
+// Blank lines and lines beginning with '#' are ignored.
+func readTopicsFile(r io.Reader) ([]topicsFileEntry, error) {
+	var entries []topicsFileEntry
+	seen := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("topics file line %d: expected a topic and an optional broker list, got '%s'", n, line)
+		}
+
+		if prev, dup := seen[fields[0]]; dup {
+			return nil, fmt.Errorf("topics file line %d: topic %s already listed on line %d", n, fields[0], prev)
+		}
+		seen[fields[0]] = n
+
+		e := topicsFileEntry{topic: fields[0]}
+
+		if len(fields) == 2 {
+			for _, s := range strings.Split(fields[1], ",") {
+				id, err := strconv.Atoi(s)
+				if err != nil {
+					return nil, fmt.Errorf("topics file line %d: invalid broker ID '%s'", n, s)
+				}
+				e.brokers = append(e.brokers, id)
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading topics file: %s", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("No topics in topics file")
+	}
+
+	return entries, nil
+}
+
+// applyTopicsFile appends the topics read from the topics file, if set, to
+// the target topics. Topics listed with a target broker list are recorded in
+// the topicBrokers.
+func (c *rebuildParams) applyTopicsFile() error {
+	if c.topicsFile == "" {
+		return nil
+	}
+
+	entries, err := loadTopicsFile(c.topicsFile)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		c.topics = append(c.topics, e.topic)
+		if len(e.brokers) > 0 {
+			c.topicBrokers = append(c.topicBrokers, e)
+		}
+	}
+
+	return nil
+}
+
+// topicGroups groups the target topics by target broker list. Topics without
+// a broker list in the topics file are scoped to the --brokers list. Groups
+// are ordered by the first appearance of their broker list.
+func (c rebuildParams) topicGroups() []topicGroup {
+	brokers := map[string][]int{}
+	for _, e := range c.topicBrokers {
+		brokers[e.topic] = e.brokers
+	}
+
+	var groups []topicGroup
+	index := map[string]int{}
+
+	for _, t := range c.topics {
+		bl, exists := brokers[t]
+		if !exists {
+			bl = c.brokers
+		}
+
+		sorted := make([]int, len(bl))
+		copy(sorted, bl)
+		sort.Ints(sorted)
+		key := fmt.Sprint(sorted)
+
+		i, exists := index[key]
+		if !exists {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, topicGroup{brokers: bl})
+		}
+
+		groups[i].topics = append(groups[i].topics, t)
+	}
+
+	return groups
+}
+
+// runRebuildGroups performs a rebuild for each topic group with placements
+// scoped to the group's broker list, returning the merged output map. Errors
+// are returned for partitions matched by more than one group; the placement
+// of the first group is kept.
+func runRebuildGroups(params rebuildParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handler) ([]*mapper.PartitionMap, []error) {
+	groups := params.topicGroups()
+	merged := mapper.NewPartitionMap()
+	seen := map[string]map[int]struct{}{}
+	var errs []error
+
+	for i, g := range groups {
+		fmt.Printf("\nTopic group %d of %d, brokers %v:\n", i+1, len(groups), g.brokers)
+
+		p := params
+		p.topics = g.topics
+		p.brokers = g.brokers
+		p.topicBrokers = nil
+
+		maps, e := runRebuild(p, ka, zk)
+		errs = append(errs, e...)
+
+		merged, e = mergeTopicGroupMap(merged, maps, seen)
+		errs = append(errs, e...)
+	}
+
+	sort.Sort(merged.Partitions)
+
+	return []*mapper.PartitionMap{merged}, errs
+}
+
+// mergeTopicGroupMap appends the partitions of a topic group's output maps to
+// the merged PartitionMap. Partitions already seen are skipped with an error.
+func mergeTopicGroupMap(merged *mapper.PartitionMap, maps []*mapper.PartitionMap, seen map[string]map[int]struct{}) (*mapper.PartitionMap, []error) {
+	var errs []error
+
+	for _, pm := range maps {
+		for _, p := range pm.Partitions {
+			if _, exists := seen[p.Topic]; !exists {
+				seen[p.Topic] = map[int]struct{}{}
+			}
+
+			if _, dup := seen[p.Topic][p.Partition]; dup {
+				errs = append(errs, fmt.Errorf("%s p%d matched by more than one topic group; keeping the first placement", p.Topic, p.Partition))
+				continue
+			}
+
+			seen[p.Topic][p.Partition] = struct{}{}
+			merged.Partitions = append(merged.Partitions, p)
+		}
+	}
+
+	return merged, errs
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestReadTopicsFile(t *testing.T) {
+	in := `# Checkout topics.
+orders 1001,1002,1003
+
+^payments-.*
+  refunds   1004,1005
+`
+
+	entries, err := readTopicsFile(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []topicsFileEntry{
+		{topic: "orders", brokers: []int{1001, 1002, 1003}},
+		{topic: "^payments-.*"},
+		{topic: "refunds", brokers: []int{1004, 1005}},
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+}
+
+func TestReadTopicsFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"too many fields":  "orders 1001 1002\n",
+		"invalid broker":   "orders 1001,a\n",
+		"duplicate topic":  "orders\npayments\norders 1001\n",
+		"no topics":        "# empty\n\n",
+		"trailing comma":   "orders 1001,\n",
+		"duplicate regex":  "^logs-.*\n^logs-.*\n",
+		"negative garbage": "orders -x\n",
+	}
+
+	for name, in := range tests {
+		if _, err := readTopicsFile(strings.NewReader(in)); err == nil {
+			t.Errorf("[%s] Expected error", name)
+		}
+	}
+}
+
+func TestApplyTopicsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topics.txt")
+	if err := os.WriteFile(path, []byte("orders 1001,1002\npayments\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := rebuildParams{topics: []string{"refunds"}, topicsFile: path}
+	if err := params.applyTopicsFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTopics := []string{"refunds", "orders", "payments"}
+	if !reflect.DeepEqual(params.topics, expectedTopics) {
+		t.Errorf("Expected topics %v, got %v", expectedTopics, params.topics)
+	}
+
+	if len(params.topicBrokers) != 1 || params.topicBrokers[0].topic != "orders" {
+		t.Errorf("Expected topic brokers for orders, got %v", params.topicBrokers)
+	}
+
+	params = rebuildParams{topicsFile: filepath.Join(t.TempDir(), "missing.txt")}
+	if err := params.applyTopicsFile(); err == nil {
+		t.Error("Expected error for missing topics file")
+	}
+}
+
+func TestTopicGroups(t *testing.T) {
+	params := rebuildParams{
+		brokers: []int{1001, 1002, 1003},
+		topics:  []string{"orders", "payments", "refunds", "logs"},
+		topicBrokers: []topicsFileEntry{
+			{topic: "payments", brokers: []int{1005, 1004}},
+			{topic: "refunds", brokers: []int{1004, 1005}},
+		},
+	}
+
+	groups := params.topicGroups()
+
+	expected := []topicGroup{
+		{topics: []string{"orders", "logs"}, brokers: []int{1001, 1002, 1003}},
+		{topics: []string{"payments", "refunds"}, brokers: []int{1005, 1004}},
+	}
+
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %v, got %v", expected, groups)
+	}
+}
+
+func TestMergeTopicGroupMap(t *testing.T) {
+	seen := map[string]map[int]struct{}{}
+	merged := mapper.NewPartitionMap()
+
+	group1 := mapper.NewPartitionMap()
+	group1.Partitions = mapper.PartitionList{
+		{Topic: "orders", Partition: 0, Replicas: []int{1001, 1002}},
+		{Topic: "orders", Partition: 1, Replicas: []int{1002, 1001}},
+	}
+
+	group2 := mapper.NewPartitionMap()
+	group2.Partitions = mapper.PartitionList{
+		{Topic: "orders", Partition: 1, Replicas: []int{1004, 1005}},
+		{Topic: "payments", Partition: 0, Replicas: []int{1004, 1005}},
+	}
+
+	merged, errs := mergeTopicGroupMap(merged, []*mapper.PartitionMap{group1}, seen)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	merged, errs = mergeTopicGroupMap(merged, []*mapper.PartitionMap{group2}, seen)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+
+	if len(merged.Partitions) != 3 {
+		t.Fatalf("Expected 3 partitions, got %d", len(merged.Partitions))
+	}
+
+	if r := merged.Partitions[1].Replicas; !reflect.DeepEqual(r, []int{1002, 1001}) {
+		t.Errorf("Expected the first group placement for orders p1, got %v", r)
+	}
+}
@@ -0,0 +1,470 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Monitor the progress of in-flight partition reassignments",
+	Long: `watch polls in-flight partition reassignments via the Kafka Admin API or
+ZooKeeper, reporting the target replicas of each reassigning partition that have
+joined the ISR, the estimated completion time given the current replication
+throttles and replicas that appear stuck. watch exits once no reassignments of
+the target topics remain, or exits 1 once the --timeout elapses.`,
+	Run: watch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().String("source", "kafka", "Reassignment and ISR source: [kafka, zookeeper]; kafka uses the Kafka Admin API")
+	watchCmd.Flags().String("topics", ".*", "Watch reassignments of topics (comma delim. list of names and/or regex patterns)")
+	watchCmd.Flags().Int("interval", 30, "Polling interval (in seconds)")
+	watchCmd.Flags().Int("timeout", 0, "Exit 1 if reassignments are still in progress after this many minutes (0 waits indefinitely)")
+	watchCmd.Flags().Int("stuck-after", 60, "Report replicas that haven't joined the ISR after this many minutes of watching as stuck (0 only reports replicas on offline brokers)")
+}
+
+type watchParams struct {
+	source     string
+	topics     []*regexp.Regexp
+	interval   int
+	timeout    int
+	stuckAfter int
+}
+
+func watchParamsFromCmd(cmd *cobra.Command) (params watchParams) {
+	source, _ := cmd.Flags().GetString("source")
+	params.source = source
+	topics, _ := cmd.Flags().GetString("topics")
+	params.topics = topicRegex(topics)
+	interval, _ := cmd.Flags().GetInt("interval")
+	params.interval = interval
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	params.timeout = timeout
+	stuckAfter, _ := cmd.Flags().GetInt("stuck-after")
+	params.stuckAfter = stuckAfter
+	return params
+}
+
+func (c watchParams) validate() error {
+	switch {
+	case c.source != "kafka" && c.source != "zookeeper":
+		return fmt.Errorf("\n[ERROR] --source must be either 'kafka' or 'zookeeper'")
+	case c.interval <= 0:
+		return fmt.Errorf("\n[ERROR] --interval must be > 0")
+	case c.timeout < 0 || c.stuckAfter < 0:
+		return fmt.Errorf("\n[ERROR] --timeout and --stuck-after must be >= 0")
+	}
+	return nil
+}
+
+// partitionProgress describes the progress of a partition reassignment.
+type partitionProgress struct {
+	topic     string
+	partition int
+	target    []int
+	// Target replicas not yet in the ISR.
+	pending []int
+}
+
+// stuckReplica is a target replica that hasn't joined the ISR.
+type stuckReplica struct {
+	topic     string
+	partition int
+	broker    int
+	reason    string
+}
+
+func watch(cmd *cobra.Command, _ []string) {
+	params := watchParamsFromCmd(cmd)
+	if err := params.validate(); err != nil {
+		logError(err, nil)
+		defaultsAndExit()
+	}
+
+	// Init kafkaadmin client.
+	bs := cmd.Parent().Flag("kafka-addr").Value.String()
+	ka, err := kafkaadmin.NewClient(kafkaadmin.Config{BootstrapServers: bs})
+	if err != nil {
+		exitOnErr(err)
+	}
+
+	// ZooKeeper init. Partition sizes for completion estimates are read from
+	// ZooKeeper with either source.
+	zkAddr := cmd.Parent().Flag("zk-addr").Value.String()
+	kafkaPrefix := cmd.Parent().Flag("zk-prefix").Value.String()
+	metricsPrefix := cmd.Flag("zk-metrics-prefix").Value.String()
+	zk, err := initZooKeeper(zkAddr, kafkaPrefix, metricsPrefix)
+	if err != nil {
+		exitOnErr(err)
+	}
+
+	defer zk.Close()
+
+	start := time.Now()
+	firstPending := map[string]time.Time{}
+
+	for {
+		progress, err := fetchReassignmentProgress(params, ka, zk)
+		if err != nil {
+			logError(fmt.Errorf("Error fetching reassignments: %s", err), nil)
+		} else {
+			if len(progress) == 0 {
+				fmt.Println("\nNo partition reassignments in progress")
+				return
+			}
+
+			now := time.Now()
+			trackPending(firstPending, progress, now)
+
+			printReassignmentProgress(now, progress)
+			printCompletionEstimate(progress, ka, zk)
+			printStuckReplicas(stuckReplicas(progress, firstPending, liveBrokers(ka), now, time.Duration(params.stuckAfter)*time.Minute))
+		}
+
+		if params.timeout > 0 && time.Since(start) >= time.Duration(params.timeout)*time.Minute {
+			exitOnErr(fmt.Errorf("Timed out after %d minute(s) with partition reassignments in progress", params.timeout))
+		}
+
+		time.Sleep(time.Duration(params.interval) * time.Second)
+	}
+}
+
+// fetchReassignmentProgress returns the progress of each in-flight
+// reassignment of the target topics, sorted by topic and partition. The
+// reassignments and ISRs are read from the configured source.
+func fetchReassignmentProgress(params watchParams, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handler) ([]partitionProgress, error) {
+	var reassignments kafkazk.Reassignments
+	isr := map[string]map[int][]int{}
+
+	switch params.source {
+	case "kafka":
+		r, err := ka.ListPartitionReassignments(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		reassignments = kafkazk.Reassignments{}
+		for topic, partitions := range r {
+			if !matchesAny(topic, params.topics) {
+				continue
+			}
+
+			reassignments[topic] = map[int][]int{}
+			for id, p := range partitions {
+				reassignments[topic][int(id)] = int32sToInts(p.TargetReplicas())
+			}
+		}
+
+		if len(reassignments) == 0 {
+			return nil, nil
+		}
+
+		states, err := ka.DescribeTopics(context.Background(), reassignments.List())
+		if err != nil {
+			return nil, err
+		}
+
+		for topic, state := range states {
+			isr[topic] = map[int][]int{}
+			for id, p := range state.PartitionStates {
+				isr[topic][id] = int32sToInts(p.ISR)
+			}
+		}
+	case "zookeeper":
+		r, err := zk.ListReassignments()
+		if err != nil {
+			return nil, err
+		}
+
+		reassignments = kafkazk.Reassignments{}
+		for topic, partitions := range r {
+			if matchesAny(topic, params.topics) {
+				reassignments[topic] = partitions
+			}
+		}
+
+		for topic := range reassignments {
+			states, err := zk.GetTopicStateISR(topic)
+			if err != nil {
+				return nil, err
+			}
+
+			isr[topic] = map[int][]int{}
+			for id, p := range states {
+				if i, err := strconv.Atoi(id); err == nil {
+					isr[topic][i] = p.ISR
+				}
+			}
+		}
+	}
+
+	return reassignmentProgress(reassignments, isr), nil
+}
+
+// reassignmentProgress returns the progress of each reassignment given the
+// ISR of each partition, sorted by topic and partition.
+func reassignmentProgress(reassignments kafkazk.Reassignments, isr map[string]map[int][]int) []partitionProgress {
+	var progress []partitionProgress
+
+	for topic, partitions := range reassignments {
+		for id, target := range partitions {
+			inSync := map[int]struct{}{}
+			for _, b := range isr[topic][id] {
+				inSync[b] = struct{}{}
+			}
+
+			p := partitionProgress{topic: topic, partition: id, target: target}
+			for _, b := range target {
+				if _, exists := inSync[b]; !exists {
+					p.pending = append(p.pending, b)
+				}
+			}
+
+			progress = append(progress, p)
+		}
+	}
+
+	sort.Slice(progress, func(i, j int) bool {
+		if progress[i].topic != progress[j].topic {
+			return progress[i].topic < progress[j].topic
+		}
+		return progress[i].partition < progress[j].partition
+	})
+
+	return progress
+}
+
+// printReassignmentProgress prints the target replicas in sync of each
+// reassigning partition, along with the totals.
+func printReassignmentProgress(now time.Time, progress []partitionProgress) {
+	var target, inSync int
+	for _, p := range progress {
+		target += len(p.target)
+		inSync += len(p.target) - len(p.pending)
+	}
+
+	fmt.Printf("\n[%s] %d partition(s) reassigning, %d/%d target replicas in sync (%.2f%%)\n",
+		now.Format(time.RFC3339), len(progress), inSync, target, float64(inSync)/float64(target)*100)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, indent+"TOPIC\tPARTITION\tTARGET\tPENDING\tIN SYNC")
+	for _, p := range progress {
+		fmt.Fprintf(tw, "%s%s\t%d\t%v\t%v\t%d/%d\n", indent,
+			p.topic, p.partition, p.target, p.pending, len(p.target)-len(p.pending), len(p.target))
+	}
+	tw.Flush()
+}
+
+// printCompletionEstimate prints the estimated time to completion given the
+// partition sizes from ZooKeeper and the follower replication throttle of each
+// destination broker.
+func printCompletionEstimate(progress []partitionProgress, ka kafkaadmin.KafkaAdmin, zk kafkazk.Handler) {
+	pmm, err := getPartitionMeta(zk)
+	if err != nil {
+		fmt.Printf("%sestimated completion: unknown (partition sizes unavailable: %s)\n", indent, err)
+		return
+	}
+
+	remaining := pendingBytes(progress, pmm)
+
+	var names []string
+	for id := range remaining {
+		names = append(names, strconv.Itoa(id))
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	configs, err := ka.GetDynamicConfigs(context.Background(), "broker", names)
+	if err != nil {
+		fmt.Printf("%sestimated completion: unknown (throttles unavailable: %s)\n", indent, err)
+		return
+	}
+
+	eta, broker, ok := estimateCompletion(remaining, followerThrottles(configs))
+	switch {
+	case !ok:
+		fmt.Printf("%sestimated completion: unknown (broker %d is unthrottled)\n", indent, broker)
+	default:
+		fmt.Printf("%sestimated completion: %s (%.2fGB remaining on broker %d)\n",
+			indent, eta.Round(time.Second), remaining[broker]/div, broker)
+	}
+}
+
+// pendingBytes returns the bytes remaining to be replicated to each
+// destination broker, counting the full size of each pending replica.
+// Partitions without size metadata are counted as empty.
+func pendingBytes(progress []partitionProgress, pmm mapper.PartitionMetaMap) map[int]float64 {
+	remaining := map[int]float64{}
+	for _, p := range progress {
+		size, _ := pmm.Size(mapper.Partition{Topic: p.topic, Partition: p.partition})
+		for _, b := range p.pending {
+			remaining[b] += size
+		}
+	}
+
+	return remaining
+}
+
+// followerThrottles returns the follower replication throttle of each broker
+// in bytes/s from the broker configs. Brokers without a throttle are omitted.
+func followerThrottles(configs kafkaadmin.ResourceConfigs) map[int]float64 {
+	rates := map[int]float64{}
+	for name, c := range configs {
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+
+		if rate, err := strconv.ParseFloat(c["follower.replication.throttled.rate"], 64); err == nil && rate > 0 {
+			rates[id] = rate
+		}
+	}
+
+	return rates
+}
+
+// estimateCompletion returns the time to replicate the remaining bytes of
+// each broker at its throttle rate, which is that of the slowest broker, and
+// the slowest broker ID. If any broker with bytes remaining is unthrottled,
+// false is returned along with that broker ID.
+func estimateCompletion(remaining map[int]float64, rates map[int]float64) (time.Duration, int, bool) {
+	var ids []int
+	for id := range remaining {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var eta time.Duration
+	var slowest int
+
+	for _, id := range ids {
+		if remaining[id] == 0 {
+			continue
+		}
+
+		rate, exists := rates[id]
+		if !exists {
+			return 0, id, false
+		}
+
+		if d := time.Duration(remaining[id] / rate * float64(time.Second)); d > eta {
+			eta, slowest = d, id
+		}
+	}
+
+	return eta, slowest, true
+}
+
+// trackPending records when each pending replica was first seen, removing
+// those no longer pending.
+func trackPending(firstPending map[string]time.Time, progress []partitionProgress, now time.Time) {
+	current := map[string]struct{}{}
+	for _, p := range progress {
+		for _, b := range p.pending {
+			key := pendingKey(p.topic, p.partition, b)
+			current[key] = struct{}{}
+			if _, exists := firstPending[key]; !exists {
+				firstPending[key] = now
+			}
+		}
+	}
+
+	for key := range firstPending {
+		if _, exists := current[key]; !exists {
+			delete(firstPending, key)
+		}
+	}
+}
+
+func pendingKey(topic string, partition, broker int) string {
+	return fmt.Sprintf("%s/%d/%d", topic, partition, broker)
+}
+
+// stuckReplicas returns the pending replicas on brokers not in the live
+// brokers and, if the stuck duration is non-zero, those pending for at least
+// the stuck duration. A nil live brokers map skips the liveness check.
+func stuckReplicas(progress []partitionProgress, firstPending map[string]time.Time, live map[int]struct{}, now time.Time, after time.Duration) []stuckReplica {
+	var stuck []stuckReplica
+
+	for _, p := range progress {
+		for _, b := range p.pending {
+			r := stuckReplica{topic: p.topic, partition: p.partition, broker: b}
+
+			if _, alive := live[b]; live != nil && !alive {
+				r.reason = "broker offline"
+				stuck = append(stuck, r)
+				continue
+			}
+
+			if since, exists := firstPending[pendingKey(p.topic, p.partition, b)]; exists && after > 0 && now.Sub(since) >= after {
+				r.reason = fmt.Sprintf("not in sync after %s", now.Sub(since).Round(time.Second))
+				stuck = append(stuck, r)
+			}
+		}
+	}
+
+	return stuck
+}
+
+// printStuckReplicas prints the stuck replicas, if any.
+func printStuckReplicas(stuck []stuckReplica) {
+	if len(stuck) == 0 {
+		return
+	}
+
+	fmt.Println("\nStuck replicas:")
+	for _, r := range stuck {
+		fmt.Printf("%s%s p%d -> %d: %s\n", indent, r.topic, r.partition, r.broker, r.reason)
+	}
+}
+
+// liveBrokers returns the IDs of the brokers in the cluster, or nil if they
+// can't be listed.
+func liveBrokers(ka kafkaadmin.KafkaAdmin) map[int]struct{} {
+	ids, err := ka.ListBrokers(context.Background())
+	if err != nil || len(ids) == 0 {
+		return nil
+	}
+
+	live := map[int]struct{}{}
+	for _, id := range ids {
+		live[id] = struct{}{}
+	}
+
+	return live
+}
+
+// matchesAny returns whether the topic matches any of the patterns.
+func matchesAny(topic string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func int32sToInts(s []int32) []int {
+	out := make([]int, len(s))
+	for i, v := range s {
+		out[i] = int(v)
+	}
+	return out
+}
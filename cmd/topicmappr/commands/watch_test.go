@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin/stub"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+	"github.com/DataDog/kafka-kit/v4/mapper"
+)
+
+func TestFetchReassignmentProgressZooKeeper(t *testing.T) {
+	params := watchParams{source: "zookeeper", topics: topicRegex(".*")}
+
+	progress, err := fetchReassignmentProgress(params, stub.NewClient(), kafkazk.NewZooKeeperStub())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []partitionProgress{
+		{topic: "reassigning_topic", partition: 0, target: []int{1003, 1000, 1002}, pending: []int{1003}},
+		{topic: "reassigning_topic", partition: 1, target: []int{1005, 1010}, pending: []int{1005, 1010}},
+	}
+
+	if !reflect.DeepEqual(progress, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, progress)
+	}
+
+	// Non-matching topics are excluded.
+	params.topics = topicRegex("test1")
+	if progress, _ := fetchReassignmentProgress(params, stub.NewClient(), kafkazk.NewZooKeeperStub()); len(progress) != 0 {
+		t.Errorf("Expected no reassignments, got %+v", progress)
+	}
+}
+
+func TestFetchReassignmentProgressKafka(t *testing.T) {
+	params := watchParams{source: "kafka", topics: topicRegex("test1")}
+	ka := reassignmentsStub{
+		Client: stub.NewClient(),
+		reassignments: kafkaadmin.PartitionReassignments{
+			"other": {0: {Replicas: []int32{1001, 1002}, AddingReplicas: []int32{1002}}},
+		},
+	}
+
+	// Reassignments of non-matching topics are excluded.
+	progress, err := fetchReassignmentProgress(params, ka, kafkazk.NewZooKeeperStub())
+	if err != nil || len(progress) != 0 {
+		t.Errorf("Expected no reassignments, got %+v (%v)", progress, err)
+	}
+}
+
+func TestReassignmentProgress(t *testing.T) {
+	reassignments := kafkazk.Reassignments{
+		"test2": {0: {1001, 1002}},
+		"test1": {
+			1: {1001, 1003},
+			0: {1002, 1003},
+		},
+	}
+
+	isr := map[string]map[int][]int{
+		"test1": {0: {1002, 1001, 1003}, 1: {1001}},
+	}
+
+	progress := reassignmentProgress(reassignments, isr)
+
+	expected := []partitionProgress{
+		{topic: "test1", partition: 0, target: []int{1002, 1003}},
+		{topic: "test1", partition: 1, target: []int{1001, 1003}, pending: []int{1003}},
+		{topic: "test2", partition: 0, target: []int{1001, 1002}, pending: []int{1001, 1002}},
+	}
+
+	if !reflect.DeepEqual(progress, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, progress)
+	}
+}
+
+func TestPendingBytes(t *testing.T) {
+	progress := []partitionProgress{
+		{topic: "test1", partition: 0, pending: []int{1003}},
+		{topic: "test1", partition: 1, pending: []int{1003, 1004}},
+		{topic: "test2", partition: 0, pending: []int{1004}},
+	}
+
+	pmm := mapper.PartitionMetaMap{
+		"test1": map[int]*mapper.PartitionMeta{
+			0: {Size: 100},
+			1: {Size: 200},
+		},
+	}
+
+	expected := map[int]float64{1003: 300, 1004: 200}
+	if remaining := pendingBytes(progress, pmm); !reflect.DeepEqual(remaining, expected) {
+		t.Errorf("Expected %v, got %v", expected, remaining)
+	}
+}
+
+func TestFollowerThrottles(t *testing.T) {
+	configs := kafkaadmin.ResourceConfigs{
+		"1001":    {"follower.replication.throttled.rate": "1000", "leader.replication.throttled.rate": "2000"},
+		"1002":    {"leader.replication.throttled.rate": "2000"},
+		"invalid": {"follower.replication.throttled.rate": "1000"},
+	}
+
+	expected := map[int]float64{1001: 1000}
+	if rates := followerThrottles(configs); !reflect.DeepEqual(rates, expected) {
+		t.Errorf("Expected %v, got %v", expected, rates)
+	}
+}
+
+func TestEstimateCompletion(t *testing.T) {
+	remaining := map[int]float64{1001: 1000, 1002: 6000, 1003: 0}
+	rates := map[int]float64{1001: 100, 1002: 200}
+
+	eta, broker, ok := estimateCompletion(remaining, rates)
+	if !ok || eta != 30*time.Second || broker != 1002 {
+		t.Errorf("Expected 30s on broker 1002, got %s on broker %d (%t)", eta, broker, ok)
+	}
+
+	// Unthrottled brokers with bytes remaining can't be estimated.
+	remaining[1004] = 100
+	if _, broker, ok := estimateCompletion(remaining, rates); ok || broker != 1004 {
+		t.Errorf("Expected unknown completion for broker 1004, got broker %d (%t)", broker, ok)
+	}
+}
+
+func TestStuckReplicas(t *testing.T) {
+	start := time.Now()
+	firstPending := map[string]time.Time{}
+
+	progress := []partitionProgress{
+		{topic: "test1", partition: 0, target: []int{1001, 1002}, pending: []int{1002}},
+		{topic: "test1", partition: 1, target: []int{1002, 1003}, pending: []int{1002, 1003}},
+	}
+
+	trackPending(firstPending, progress, start)
+
+	// Nothing is stuck initially with all brokers live.
+	live := map[int]struct{}{1001: {}, 1002: {}, 1003: {}}
+	if stuck := stuckReplicas(progress, firstPending, live, start, time.Hour); len(stuck) != 0 {
+		t.Errorf("Expected no stuck replicas, got %+v", stuck)
+	}
+
+	// Replicas on offline brokers are stuck.
+	delete(live, 1003)
+	stuck := stuckReplicas(progress, firstPending, live, start, time.Hour)
+	expected := []stuckReplica{{topic: "test1", partition: 1, broker: 1003, reason: "broker offline"}}
+	if !reflect.DeepEqual(stuck, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, stuck)
+	}
+
+	// test1 p1 completes; replicas still pending after the stuck duration
+	// are stuck.
+	progress = progress[:1]
+	now := start.Add(2 * time.Hour)
+	trackPending(firstPending, progress, now)
+
+	if len(firstPending) != 1 {
+		t.Errorf("Expected 1 pending replica tracked, got %d", len(firstPending))
+	}
+
+	stuck = stuckReplicas(progress, firstPending, nil, now, time.Hour)
+	expected = []stuckReplica{{topic: "test1", partition: 0, broker: 1002, reason: "not in sync after 2h0m0s"}}
+	if !reflect.DeepEqual(stuck, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, stuck)
+	}
+}
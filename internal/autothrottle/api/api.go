@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
+	"github.com/DataDog/kafka-kit/v4/internal/health"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 )
 
@@ -17,34 +20,58 @@ import (
 type APIConfig struct {
 	Listen   string
 	ZKPrefix string
+	// Cluster optionally namespaces the throttle override znode, for
+	// clusters sharing a ZooKeeper prefix.
+	Cluster string
+	// MetricsHandler optionally serves autothrottle's own metrics at
+	// /metrics.
+	MetricsHandler http.Handler
+	// ThrottleStatus optionally serves the currently applied throttles at
+	// /throttles.
+	ThrottleStatus ThrottleStatusReader
+	// Health optionally serves liveness and readiness checks at /healthz
+	// and /readyz.
+	Health *health.Checker
 }
 
-var (
-	overrideRateZnode     = "override_rate"
-	OverrideRateZnodePath string
-	incorrectMethodError  = errors.New("disallowed method")
-)
+const overrideRateZnode = "override_rate"
+
+var incorrectMethodError = errors.New("disallowed method")
+
+// OverrideRateZnodePath returns the path of the throttle override znode
+// under the ZooKeeper prefix. Clusters in multi-cluster mode each use their
+// own znode.
+func OverrideRateZnodePath(zkPrefix, cluster string) string {
+	path := fmt.Sprintf("/%s/%s", zkPrefix, overrideRateZnode)
+	if cluster != "" {
+		path = fmt.Sprintf("%s-%s", path, cluster)
+	}
+
+	return path
+}
 
 func Init(c *APIConfig, zk kafkazk.Handler, trigger chan<- struct{}) {
 	chroot := fmt.Sprintf("/%s", c.ZKPrefix)
-	OverrideRateZnodePath = fmt.Sprintf("%s/%s", chroot, overrideRateZnode)
+	overridePath := OverrideRateZnodePath(c.ZKPrefix, c.Cluster)
 
 	m := http.NewServeMux()
 
 	// Check ZK for override rate config znode.
 	var exists bool
-	for _, path := range []string{chroot, OverrideRateZnodePath} {
+	for _, path := range []string{chroot, overridePath} {
 		var err error
 		exists, err = zk.Exists(path)
 		if err != nil {
-			log.Fatal(err)
+			logging.Error("Error checking the throttle override znode", logging.Fields{"path": path, "error": err})
+			os.Exit(1)
 		}
 
 		if !exists {
 			// Create chroot.
 			err = zk.Create(path, "")
 			if err != nil {
-				log.Fatal(err)
+				logging.Error("Error creating the throttle override znode", logging.Fields{"path": path, "error": err})
+				os.Exit(1)
 			}
 		}
 	}
@@ -53,47 +80,64 @@ func Init(c *APIConfig, zk kafkazk.Handler, trigger chan<- struct{}) {
 	// If it is, update it to the json format.
 	// TODO(jamie): we can probably remove this by now.
 	if exists {
-		r, _ := zk.Get(OverrideRateZnodePath)
+		r, _ := zk.Get(overridePath)
 		if rate, err := strconv.Atoi(string(r)); err == nil {
 			// Populate the updated config.
 			tor := throttlestore.ThrottleOverrideConfig{Rate: rate}
-			err := throttlestore.StoreThrottleOverride(zk, OverrideRateZnodePath, tor)
+			err := throttlestore.StoreThrottleOverride(zk, overridePath, tor)
 			if err != nil {
-				log.Fatal(err)
+				logging.Error("Error updating the throttle override config format", logging.Fields{"path": overridePath, "error": err})
+				os.Exit(1)
 			}
 
-			log.Println("Throttle override config format updated")
+			logging.Info("Throttle override config format updated", logging.Fields{"path": overridePath})
 		}
 	}
 
 	// Routes. A global rate vs broker-specific rate is distinguished in whether
 	// or not there's a trailing slash (and in a properly formed request, the
 	// addition of a broker ID in the request path).
-	m.HandleFunc("/throttle", func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
-	m.HandleFunc("/throttle/", func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
-	m.HandleFunc("/throttle/remove", func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, trigger) })
-	m.HandleFunc("/throttle/remove/", func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, trigger) })
+	m.HandleFunc("/throttle", func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, overridePath, trigger) })
+	m.HandleFunc("/throttle/", func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, overridePath, trigger) })
+	m.HandleFunc("/throttle/remove", func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, overridePath, trigger) })
+	m.HandleFunc("/throttle/remove/", func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, overridePath, trigger) })
+
+	if c.MetricsHandler != nil {
+		m.Handle("/metrics", c.MetricsHandler)
+	}
+
+	if c.ThrottleStatus != nil {
+		m.HandleFunc("/throttles", func(w http.ResponseWriter, req *http.Request) { throttleStatus(w, req, c.ThrottleStatus) })
+	}
+
+	if c.Health != nil {
+		c.Health.Register(m)
+	}
 
 	// Start listener.
 	go func() {
 		err := http.ListenAndServe(c.Listen, m)
 		if err != nil {
-			log.Fatal(err)
+			logging.Error("Error serving the admin API", logging.Fields{"listen": c.Listen, "error": err})
+			os.Exit(1)
 		}
 	}()
 }
 
 // throttleGetSet conditionally handles the request depending on the HTTP method.
-func throttleGetSet(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, trigger chan<- struct{}) {
+func throttleGetSet(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, overridePath string, trigger chan<- struct{}) {
 	logReq(req)
 
 	switch req.Method {
 	case http.MethodGet:
 		// Get a throttle rate.
-		getThrottle(w, req, zk)
+		getThrottle(w, req, zk, overridePath)
 	case http.MethodPost:
 		// Set a throttle rate.
-		setThrottle(w, req, zk)
+		if ttl := setThrottle(w, req, zk, overridePath); ttl > 0 {
+			// Trigger a throttle update once the override expires.
+			time.AfterFunc(ttl, func() { trigger <- struct{}{} })
+		}
 		trigger <- struct{}{}
 	default:
 		// Invalid method.
@@ -104,13 +148,13 @@ func throttleGetSet(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler
 }
 
 // throttleRemove removes either the global, broker-specific throttle, or all broker-specific throttles.
-func throttleRemove(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, trigger chan<- struct{}) {
+func throttleRemove(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, overridePath string, trigger chan<- struct{}) {
 	logReq(req)
 
 	switch req.Method {
 	case http.MethodPost:
 		// Remove the throttle.
-		removeThrottle(w, req, zk)
+		removeThrottle(w, req, zk, overridePath)
 		trigger <- struct{}{}
 	default:
 		// Invalid method.
@@ -121,7 +165,7 @@ func throttleRemove(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler
 }
 
 // getThrottle returns the throttle rate applied to all brokers.
-func getThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
+func getThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, overridePath string) {
 	// Determine whether this is a global or broker-specific throttle lookup.
 	var id string
 	paths := parsePaths(req)
@@ -134,7 +178,7 @@ func getThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
 		}
 	}
 
-	configPath := OverrideRateZnodePath
+	configPath := overridePath
 
 	// A non-0 ID means that this is broker specific.
 	if id != "" {
@@ -144,7 +188,17 @@ func getThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
 
 	r, err := throttlestore.FetchThrottleOverride(zk, configPath)
 
-	respMessage := fmt.Sprintf("a throttle override is configured at %dMB/s, autoremove==%v\n", r.Rate, r.AutoRemove)
+	var expiry string
+	if r.ExpiresAt > 0 {
+		expiry = fmt.Sprintf(", expires at %s", time.Unix(r.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+
+	kind := "throttle override"
+	if r.Ceiling {
+		kind = "throttle ceiling override"
+	}
+
+	respMessage := fmt.Sprintf("a %s is configured at %dMB/s, autoremove==%v%s\n", kind, r.Rate, r.AutoRemove, expiry)
 	noOverrideMessage := "no throttle override is set\n"
 
 	// Update the response message.
@@ -165,34 +219,55 @@ func getThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
 		}
 	}
 
-	switch r.Rate {
-	case 0:
+	// Expired overrides are pending removal.
+	switch {
+	case r.Rate == 0, r.Expired(time.Now()):
 		io.WriteString(w, noOverrideMessage)
 	default:
 		io.WriteString(w, respMessage)
 	}
 }
 
-// setThrottle sets a throtle rate that applies to all brokers.
-func setThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
+// setThrottle sets a throtle rate that applies to all brokers. It returns
+// the override ttl, if set.
+func setThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, overridePath string) time.Duration {
 	// Check rate param.
 	rate, err := parseRateParam(req)
 	if err != nil {
 		writeNLError(w, err)
-		return
+		return 0
 	}
 
 	// Check autoremove param.
 	autoRemove, err := parseAutoRemoveParam(req)
 	if err != nil {
 		writeNLError(w, err)
-		return
+		return 0
+	}
+
+	// Check ttl param.
+	ttl, err := parseTTLParam(req)
+	if err != nil {
+		writeNLError(w, err)
+		return 0
+	}
+
+	// Check ceiling param.
+	ceiling, err := parseCeilingParam(req)
+	if err != nil {
+		writeNLError(w, err)
+		return 0
 	}
 
 	// Populate configs.
 	rateCfg := throttlestore.ThrottleOverrideConfig{
 		Rate:       rate,
 		AutoRemove: autoRemove,
+		Ceiling:    ceiling,
+	}
+
+	if ttl > 0 {
+		rateCfg.ExpiresAt = time.Now().Add(ttl).Unix()
 	}
 
 	// Determine whether this is a global or broker-specific override.
@@ -202,18 +277,35 @@ func setThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
 		id, err = brokerIDFromPath(req)
 		if err != nil {
 			writeNLError(w, err)
-			return
+			return 0
 		}
 	}
 
-	updateMessage := fmt.Sprintf("throttle successfully set to %dMB/s, autoremove==%v\n", rate, autoRemove)
-	configPath := OverrideRateZnodePath
+	// Ceilings only apply to broker overrides.
+	if ceiling && id == "" {
+		writeNLError(w, errCeilingNotBroker)
+		return 0
+	}
+
+	kind := "throttle"
+	if ceiling {
+		kind = "throttle ceiling"
+	}
+
+	updateMessage := fmt.Sprintf("%s successfully set to %dMB/s, autoremove==%v\n", kind, rate, autoRemove)
+	if ttl > 0 {
+		updateMessage = fmt.Sprintf("%s successfully set to %dMB/s, autoremove==%v, ttl==%s\n", kind, rate, autoRemove, ttl)
+	}
+
+	configPath := overridePath
 
 	writeOverride(w, id, configPath, updateMessage, err, zk, rateCfg)
+
+	return ttl
 }
 
 // removeThrottle removes the throttle rate for a specific broker, the global rate, or for all brokers.
-func removeThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler) {
+func removeThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler, overridePath string) {
 	// Removing a rate means setting it to 0.
 	c := throttlestore.ThrottleOverrideConfig{
 		Rate:       0,
@@ -232,7 +324,7 @@ func removeThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler
 		}
 	}
 
-	configPath := OverrideRateZnodePath
+	configPath := overridePath
 	updateMessage := "throttle removed\n"
 
 	var err error
@@ -240,7 +332,7 @@ func removeThrottle(w http.ResponseWriter, req *http.Request, zk kafkazk.Handler
 	if id == "all" {
 		// Instead of specifying a broker, the string 'all' means clear all overrides we have by setting to 0.
 		var children []string
-		var parentPath = OverrideRateZnodePath
+		var parentPath = overridePath
 		children, err = zk.Children(parentPath)
 
 		sort.Strings(children)
@@ -3,10 +3,12 @@ package api
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 )
 
 var (
@@ -15,6 +17,9 @@ var (
 	errRateParamIsZero      = errors.New("rate param must be >0")
 	errRateParamNotInt      = errors.New("rate param must be supplied as an integer")
 	errAutoRemoveNotBool    = errors.New("autoremove param must be a bool")
+	errTTLParamInvalid      = errors.New("ttl param must be a positive duration, e.g. 30m")
+	errCeilingNotBool       = errors.New("ceiling param must be a bool")
+	errCeilingNotBroker     = errors.New("ceiling param is only supported for broker overrides")
 )
 
 // parseRateParam takes a *http.Request and returns the specified
@@ -52,6 +57,38 @@ func parseAutoRemoveParam(req *http.Request) (bool, error) {
 	return autoRemove, nil
 }
 
+// parseCeilingParam takes a *http.Request and returns the specified ceiling
+// parameter as a bool.
+func parseCeilingParam(req *http.Request) (bool, error) {
+	c := req.URL.Query().Get("ceiling")
+	if c == "" {
+		return false, nil
+	}
+
+	ceiling, err := strconv.ParseBool(c)
+	if err != nil {
+		return false, errCeilingNotBool
+	}
+
+	return ceiling, nil
+}
+
+// parseTTLParam takes a *http.Request and returns the specified ttl
+// parameter as a time.Duration. An unspecified ttl returns 0.
+func parseTTLParam(req *http.Request) (time.Duration, error) {
+	t := req.URL.Query().Get("ttl")
+	if t == "" {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(t)
+	if err != nil || ttl <= 0 {
+		return 0, errTTLParamInvalid
+	}
+
+	return ttl, nil
+}
+
 // parsePaths takes a *http.Request and returns a []string elements of the full
 // request path, stripped of all '/' chars.
 func parsePaths(req *http.Request) []string {
@@ -102,5 +139,5 @@ func writeNLError(w http.ResponseWriter, err error) {
 
 // logReq logs *http.Request parameters.
 func logReq(req *http.Request) {
-	log.Printf("[API] %s %s %s\n", req.Method, req.RequestURI, req.RemoteAddr)
+	logging.Info("API request", logging.Fields{"method": req.Method, "uri": req.RequestURI, "remote_addr": req.RemoteAddr})
 }
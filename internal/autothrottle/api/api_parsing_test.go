@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestParseRateParam(t *testing.T) {
@@ -144,3 +145,52 @@ func TestBrokerIDFromPath(t *testing.T) {
 		t.Errorf("Expected broker ID '%s', got '%s'", expected, out)
 	}
 }
+
+func TestParseTTLParam(t *testing.T) {
+	expected := map[string]struct {
+		ttl time.Duration
+		err error
+	}{
+		"":     {0, nil},
+		"30m":  {30 * time.Minute, nil},
+		"text": {0, errTTLParamInvalid},
+		"-1h":  {0, errTTLParamInvalid},
+	}
+
+	for param, e := range expected {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("http://localhost?ttl=%s", param), nil)
+		ttl, err := parseTTLParam(req)
+
+		if ttl != e.ttl {
+			t.Errorf("Expected ttl '%s', got '%s'", e.ttl, ttl)
+		}
+
+		if err != e.err {
+			t.Errorf("Expected error '%s', got '%s'", e.err, err)
+		}
+	}
+}
+
+func TestParseCeilingParam(t *testing.T) {
+	expected := map[string]struct {
+		ceiling bool
+		err     error
+	}{
+		"":     {false, nil},
+		"true": {true, nil},
+		"text": {false, errCeilingNotBool},
+	}
+
+	for param, e := range expected {
+		req, _ := http.NewRequest("POST", "http://localhost?ceiling="+param, nil)
+		ceiling, err := parseCeilingParam(req)
+
+		if ceiling != e.ceiling {
+			t.Errorf("Expected bool '%v', got '%v'", e.ceiling, ceiling)
+		}
+
+		if err != e.err {
+			t.Errorf("Expected error '%s', got '%s'", e.err, err)
+		}
+	}
+}
@@ -1,16 +1,17 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 )
 
 var (
-	trigger = make(chan struct{}, 10)
+	trigger          = make(chan struct{}, 10)
+	testOverridePath = OverrideRateZnodePath("zkChroot", "")
 )
 
 func TestSetThrottle(t *testing.T) {
@@ -23,7 +24,7 @@ func TestSetThrottle(t *testing.T) {
 	}
 
 	responseRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(responseRecorder, req)
@@ -45,7 +46,7 @@ func TestSetBrokerThrottle(t *testing.T) {
 	}
 
 	responseRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(responseRecorder, req)
@@ -60,7 +61,6 @@ func TestSetBrokerThrottle(t *testing.T) {
 func TestGetThrottle(t *testing.T) {
 	t.Cleanup(clearTrigger)
 	// GIVEN
-	overrideRateZnode = "override_rate"
 	zk := kafkazk.NewZooKeeperStub()
 
 	setReq, err := http.NewRequest("POST", "/throttle?rate=5&autoremove=false", nil)
@@ -71,7 +71,7 @@ func TestGetThrottle(t *testing.T) {
 
 	setRecorder := httptest.NewRecorder()
 	getRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(setRecorder, setReq)
@@ -84,7 +84,6 @@ func TestGetThrottle(t *testing.T) {
 func TestGetBrokerThrottle(t *testing.T) {
 	t.Cleanup(clearTrigger)
 	// GIVEN
-	overrideRateZnode = "override_rate"
 	zk := kafkazk.NewZooKeeperStub()
 
 	setReq, err := http.NewRequest("POST", "/throttle/123?rate=5&autoremove=false", nil)
@@ -95,7 +94,7 @@ func TestGetBrokerThrottle(t *testing.T) {
 
 	setRecorder := httptest.NewRecorder()
 	getRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(setRecorder, setReq)
@@ -105,10 +104,66 @@ func TestGetBrokerThrottle(t *testing.T) {
 	checkResults(http.StatusOK, "broker 123: a throttle override is configured at 5MB/s, autoremove==false\n", getRecorder, t)
 }
 
+func TestSetBrokerThrottleCeiling(t *testing.T) {
+	t.Cleanup(clearTrigger)
+	// GIVEN
+	zk := kafkazk.NewZooKeeperStub()
+
+	setReq, err := http.NewRequest("POST", "/throttle/123?rate=20&ceiling=true", nil)
+	getReq, err := http.NewRequest("GET", "/throttle/123", nil)
+	globalReq, err := http.NewRequest("POST", "/throttle?rate=20&ceiling=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setRecorder := httptest.NewRecorder()
+	getRecorder := httptest.NewRecorder()
+	globalRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
+
+	// WHEN
+	handler.ServeHTTP(setRecorder, setReq)
+	handler.ServeHTTP(getRecorder, getReq)
+	handler.ServeHTTP(globalRecorder, globalReq)
+
+	// THEN
+	checkResults(http.StatusOK, "broker 123: throttle ceiling successfully set to 20MB/s, autoremove==false\n", setRecorder, t)
+	checkResults(http.StatusOK, "broker 123: a throttle ceiling override is configured at 20MB/s, autoremove==false\n", getRecorder, t)
+	// Ceilings aren't supported for global overrides.
+	checkResults(http.StatusOK, errCeilingNotBroker.Error()+"\n", globalRecorder, t)
+}
+
+func TestSetThrottleTTL(t *testing.T) {
+	t.Cleanup(clearTrigger)
+	// GIVEN
+	zk := kafkazk.NewZooKeeperStub()
+
+	setReq, err := http.NewRequest("POST", "/throttle/123?rate=5&ttl=1h", nil)
+	getReq, err := http.NewRequest("GET", "/throttle/123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setRecorder := httptest.NewRecorder()
+	getRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
+
+	// WHEN
+	handler.ServeHTTP(setRecorder, setReq)
+	handler.ServeHTTP(getRecorder, getReq)
+
+	// THEN
+	checkResults(http.StatusOK, "broker 123: throttle successfully set to 5MB/s, autoremove==false, ttl==1h0m0s\n", setRecorder, t)
+
+	expected := "broker 123: a throttle override is configured at 5MB/s, autoremove==false, expires at "
+	if body := getRecorder.Body.String(); !strings.HasPrefix(body, expected) {
+		t.Errorf("handler returned unexpected body: got %v want prefix %v", body, expected)
+	}
+}
+
 func TestRemoveThrottle(t *testing.T) {
 	t.Cleanup(clearTrigger)
 	// GIVEN
-	overrideRateZnode = "override_rate"
 	zk := kafkazk.NewZooKeeperStub()
 
 	setReq, err := http.NewRequest("POST", "/throttle?rate=5&autoremove=false", nil)
@@ -121,8 +176,8 @@ func TestRemoveThrottle(t *testing.T) {
 	setRecorder := httptest.NewRecorder()
 	getRecorder := httptest.NewRecorder()
 	removeRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
-	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
+	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(setRecorder, setReq)
@@ -141,7 +196,6 @@ func TestRemoveThrottle(t *testing.T) {
 func TestRemoveBrokerThrottle(t *testing.T) {
 	t.Cleanup(clearTrigger)
 	// GIVEN
-	overrideRateZnode = "override_rate"
 	zk := kafkazk.NewZooKeeperStub()
 
 	setReq, err := http.NewRequest("POST", "/throttle/123?rate=5&autoremove=false", nil)
@@ -154,8 +208,8 @@ func TestRemoveBrokerThrottle(t *testing.T) {
 	setRecorder := httptest.NewRecorder()
 	getRecorder := httptest.NewRecorder()
 	removeRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
-	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
+	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(setRecorder, setReq)
@@ -174,8 +228,6 @@ func TestRemoveBrokerThrottle(t *testing.T) {
 func TestRemoveAllBrokerThrottle(t *testing.T) {
 	t.Cleanup(clearTrigger)
 	// GIVEN
-	overrideRateZnode = "override_rate"
-	OverrideRateZnodePath = fmt.Sprintf("%s/%s", "zkChroot", overrideRateZnode)
 	zk := kafkazk.NewZooKeeperStub()
 
 	setReq, err := http.NewRequest("POST", "/throttle/123?rate=5&autoremove=false", nil)
@@ -191,8 +243,8 @@ func TestRemoveAllBrokerThrottle(t *testing.T) {
 	getRecorder := httptest.NewRecorder()
 	getRecorder2 := httptest.NewRecorder()
 	removeRecorder := httptest.NewRecorder()
-	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, trigger) })
-	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, trigger) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleGetSet(w, req, zk, testOverridePath, trigger) })
+	removeHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleRemove(w, req, zk, testOverridePath, trigger) })
 
 	// WHEN
 	handler.ServeHTTP(setRecorder, setReq)
@@ -207,6 +259,33 @@ func TestRemoveAllBrokerThrottle(t *testing.T) {
 	checkResults(http.StatusOK, "broker 456: no throttle override is set\n", getRecorder2, t)
 }
 
+type stubStatusReader struct{}
+
+func (stubStatusReader) ThrottleStatus() (ThrottleStatus, error) {
+	rate := 50.0
+	return ThrottleStatus{
+		Brokers: map[int]BrokerThrottleStatus{1001: {LeaderRate: &rate}},
+	}, nil
+}
+
+func TestThrottleStatus(t *testing.T) {
+	// GIVEN
+	req, err := http.NewRequest("GET", "/throttles", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { throttleStatus(w, req, stubStatusReader{}) })
+
+	// WHEN
+	handler.ServeHTTP(responseRecorder, req)
+
+	// THEN
+	expected := `{"last_adjustment":null,"brokers":{"1001":{"leader_rate":50,"follower_rate":null}}}` + "\n"
+	checkResults(http.StatusOK, expected, responseRecorder, t)
+}
+
 func checkResults(statusCode int, expectedMessage string, rr *httptest.ResponseRecorder, t *testing.T) {
 	if status := rr.Code; status != statusCode {
 		t.Errorf("handler returned wrong status code: got %v want %v",
@@ -235,3 +314,14 @@ func countTrigger() int {
 		}
 	}
 }
+
+func TestOverrideRateZnodePath(t *testing.T) {
+	if p := OverrideRateZnodePath("autothrottle", ""); p != "/autothrottle/override_rate" {
+		t.Errorf("Unexpected override path %s\n", p)
+	}
+
+	// Each cluster has its own overrides.
+	if p := OverrideRateZnodePath("autothrottle", "kafka-a"); p != "/autothrottle/override_rate-kafka-a" {
+		t.Errorf("Unexpected override path %s\n", p)
+	}
+}
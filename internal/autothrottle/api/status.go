@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ThrottleStatusReader returns the currently applied throttles.
+type ThrottleStatusReader interface {
+	ThrottleStatus() (ThrottleStatus, error)
+}
+
+// ThrottleStatus describes the replication throttles currently applied to
+// each broker.
+type ThrottleStatus struct {
+	// LastAdjustment is when throttles were last changed by autothrottle;
+	// nil if they haven't been changed since startup.
+	LastAdjustment *time.Time `json:"last_adjustment"`
+	// Brokers maps broker IDs to their throttle status.
+	Brokers map[int]BrokerThrottleStatus `json:"brokers"`
+}
+
+// BrokerThrottleStatus describes the throttle rates of a broker.
+type BrokerThrottleStatus struct {
+	// LeaderRate and FollowerRate are the live throttle rates in MB/s as
+	// read back from the broker configs; nil if unset.
+	LeaderRate   *float64 `json:"leader_rate"`
+	FollowerRate *float64 `json:"follower_rate"`
+	// Inputs are the inputs used to compute the most recent throttles, if
+	// computed by autothrottle since startup.
+	Inputs *ThrottleInputs `json:"inputs,omitempty"`
+}
+
+// ThrottleInputs are the inputs used to compute a broker's throttles.
+type ThrottleInputs struct {
+	// Source is what determined the rates: "metrics", "global_override",
+	// "broker_override", "min_rate" where metrics couldn't be fetched or
+	// "consumer_lag" where metrics based rates were reduced due to consumer
+	// lag.
+	Source string `json:"source"`
+	// InstanceType, NetTX, NetRX and CPU are from the broker metrics.
+	InstanceType string  `json:"instance_type,omitempty"`
+	NetTX        float64 `json:"net_tx"`
+	NetRX        float64 `json:"net_rx"`
+	CPU          float64 `json:"cpu"`
+	// Capacity is the broker's network capacity in MB/s.
+	Capacity float64 `json:"capacity"`
+	// PreviousLeaderRate and PreviousFollowerRate are the previously set
+	// throttle rates in MB/s, accounted for as replication utilization.
+	PreviousLeaderRate   *float64 `json:"previous_leader_rate"`
+	PreviousFollowerRate *float64 `json:"previous_follower_rate"`
+	// LeaderRate and FollowerRate are the computed throttle rates in MB/s.
+	LeaderRate   *float64 `json:"leader_rate"`
+	FollowerRate *float64 `json:"follower_rate"`
+	// Time is when the rates were computed.
+	Time time.Time `json:"time"`
+}
+
+// throttleStatus returns the currently applied throttles as JSON.
+func throttleStatus(w http.ResponseWriter, req *http.Request, r ThrottleStatusReader) {
+	logReq(req)
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeNLError(w, incorrectMethodError)
+		return
+	}
+
+	s, err := r.ThrottleStatus()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeNLError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,139 @@
+package replication
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// Audit reasons other than the throttle sources.
+const (
+	auditReasonShutdown = "shutdown"
+	auditReasonRemoved  = "removed"
+)
+
+// AuditRecord describes a change of a broker's replication throttle.
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	BrokerID int       `json:"broker_id"`
+	// Role is "leader" or "follower".
+	Role string `json:"role"`
+	// PreviousRate is the previously set rate in MB/s; nil if unknown.
+	PreviousRate *float64 `json:"previous_rate"`
+	// Rate is the new rate in MB/s; nil where the throttle was removed.
+	Rate *float64 `json:"rate"`
+	// Reason is what determined the change: a throttle source such as
+	// "metrics", "global_override", "broker_override", "min_rate" or
+	// "consumer_lag", or "shutdown" or "removed".
+	Reason string `json:"reason"`
+	// DryRun is set where the change wasn't applied in dry-run mode.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Inputs are the inputs used to compute the rate, if computed.
+	Inputs *api.ThrottleInputs `json:"inputs,omitempty"`
+}
+
+// AuditWriter writes AuditRecords. Optional.
+type AuditWriter interface {
+	WriteAudit(AuditRecord) error
+}
+
+// copy returns a copy of the ReplicationCapacityByBroker.
+func (r ReplicationCapacityByBroker) copy() ReplicationCapacityByBroker {
+	c := make(ReplicationCapacityByBroker, len(r))
+	for id, rates := range r {
+		c[id] = rates
+	}
+
+	return c
+}
+
+// auditChanges writes an AuditRecord for each change event to the audit
+// writer, if configured, returning the change events in a new channel. The
+// previous rates are those set before the changes were applied. The reason
+// of each record is the source of the broker's inputs, if any, otherwise the
+// reason given.
+func (tm *ThrottleManager) auditChanges(events chan brokerChangeEvent, prev ReplicationCapacityByBroker, reason string, inputs map[int]api.ThrottleInputs) chan brokerChangeEvent {
+	if tm.audit == nil {
+		return events
+	}
+
+	out := make(chan brokerChangeEvent, len(events))
+	now := time.Now()
+
+	for e := range events {
+		out <- e
+
+		rate := e.rate
+		r := AuditRecord{
+			Time:     now,
+			BrokerID: e.id,
+			Role:     e.role,
+			Rate:     &rate,
+			Reason:   reason,
+			DryRun:   tm.dryRun,
+		}
+
+		if e.role == "leader" {
+			r.PreviousRate = prev[e.id][0]
+		} else {
+			r.PreviousRate = prev[e.id][1]
+		}
+
+		if in, exists := inputs[e.id]; exists {
+			r.Reason = in.Source
+			r.Inputs = &in
+		}
+
+		tm.writeAudit(r)
+	}
+
+	close(out)
+
+	return out
+}
+
+// auditRemovals writes an AuditRecord for each throttle of the removed
+// brokers with a previous rate, or for both roles of brokers throttled for
+// reassignments where the rates are unknown.
+func (tm *ThrottleManager) auditRemovals(ids map[int]struct{}, prev ReplicationCapacityByBroker) {
+	if tm.audit == nil {
+		return
+	}
+
+	var sorted []int
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+
+	now := time.Now()
+
+	for _, id := range sorted {
+		_, throttled := tm.throttledBrokers[id]
+
+		for i, rate := range prev[id] {
+			if rate == nil && !throttled {
+				continue
+			}
+
+			tm.writeAudit(AuditRecord{
+				Time:         now,
+				BrokerID:     id,
+				Role:         roleFromIndex(i),
+				PreviousRate: rate,
+				Reason:       auditReasonRemoved,
+				DryRun:       tm.dryRun,
+			})
+		}
+	}
+}
+
+// writeAudit writes the AuditRecord, logging any errors. Audit failures don't
+// affect throttle updates.
+func (tm *ThrottleManager) writeAudit(r AuditRecord) {
+	if err := tm.audit.WriteAudit(r); err != nil {
+		logging.Error("Error writing audit record", logging.Fields{"broker_id": r.BrokerID, "role": r.Role, "error": err})
+	}
+}
@@ -0,0 +1,91 @@
+package replication
+
+import (
+	"testing"
+	"time"
+)
+
+// auditStub is an AuditWriter storing the records written.
+type auditStub struct {
+	records []AuditRecord
+}
+
+func (a *auditStub) WriteAudit(r AuditRecord) error {
+	a.records = append(a.records, r)
+	return nil
+}
+
+func TestAuditChanges(t *testing.T) {
+	audit := &auditStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Events: eventsStub{},
+		DryRun: true,
+		Audit:  audit,
+	})
+
+	capacities := ReplicationCapacityByBroker{}
+	capacities.storeLeaderCapacity(1001, 100)
+	capacities.storeFollowerCapacity(1002, 50)
+
+	prev := ReplicationCapacityByBroker{}
+	prev.storeLeaderCapacity(1001, 80)
+
+	inputs := tm.throttleInputs(throttleSourceMetrics, capacities, nil, map[int]struct{}{1002: {}}, time.Now())
+
+	changes, _ := tm.applyBrokerThrottles(map[int]struct{}{1001: {}, 1002: {}}, capacities)
+	changes = tm.auditChanges(changes, prev, throttleSourceMetrics, inputs)
+
+	// The change events are passed through.
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 change events, got %d\n", len(changes))
+	}
+
+	if len(audit.records) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d\n", len(audit.records))
+	}
+
+	for _, r := range audit.records {
+		switch r.BrokerID {
+		case 1001:
+			if r.Role != "leader" || *r.PreviousRate != 80 || *r.Rate != 100 || r.Reason != throttleSourceMinRate || !r.DryRun || r.Inputs == nil {
+				t.Errorf("Unexpected audit record %+v\n", r)
+			}
+		case 1002:
+			if r.Role != "follower" || r.PreviousRate != nil || *r.Rate != 50 || r.Reason != throttleSourceBrokerOverride {
+				t.Errorf("Unexpected audit record %+v\n", r)
+			}
+		}
+	}
+}
+
+func TestAuditRemovals(t *testing.T) {
+	audit := &auditStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Events: eventsStub{},
+		DryRun: true,
+		Audit:  audit,
+	})
+
+	tm.previouslySetThrottles.storeLeaderCapacity(1001, 100)
+	tm.throttledBrokers = map[int]struct{}{1002: {}}
+
+	// Broker 1003 has no known throttles.
+	if err := tm.removeBrokerThrottlesByID(map[int]struct{}{1001: {}, 1002: {}, 1003: {}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(audit.records) != 3 {
+		t.Fatalf("Expected 3 audit records, got %d: %+v\n", len(audit.records), audit.records)
+	}
+
+	first := audit.records[0]
+	if first.BrokerID != 1001 || first.Role != "leader" || *first.PreviousRate != 100 || first.Rate != nil || first.Reason != auditReasonRemoved {
+		t.Errorf("Unexpected audit record %+v\n", first)
+	}
+
+	for _, r := range audit.records[1:] {
+		if r.BrokerID != 1002 || r.PreviousRate != nil {
+			t.Errorf("Unexpected audit record %+v\n", r)
+		}
+	}
+}
@@ -16,6 +16,9 @@ type reassigningBrokers struct {
 	dst               map[int]struct{}
 	all               map[int]struct{}
 	throttledReplicas TopicThrottledReplicas
+	// Map of source broker IDs to the destination brokers they're
+	// replicating to.
+	pairs map[int]map[int]struct{}
 }
 
 // lists returns a sorted []int of broker IDs for the src, dst
@@ -59,6 +62,7 @@ func GetReassigningBrokers(r kafkazk.Reassignments, zk kafkazk.Handler) (reassig
 		// A map for each topic with a list throttled leaders and followers.
 		// This is used to write the topic config throttled brokers lists.
 		throttledReplicas: TopicThrottledReplicas{},
+		pairs:             map[int]map[int]struct{}{},
 	}
 
 	// Get topic data for each topic undergoing a reassignment.
@@ -101,6 +105,10 @@ func GetReassigningBrokers(r kafkazk.Reassignments, zk kafkazk.Handler) (reassig
 						lb.dst[b] = struct{}{}
 						followers := lb.throttledReplicas[topic]["followers"]
 						lb.throttledReplicas[topic]["followers"] = append(followers, fmt.Sprintf("%d:%d", partn, b))
+
+						if leader != -1 {
+							lb.addPair(leader, b)
+						}
 					}
 				}
 			}
@@ -112,6 +120,15 @@ func GetReassigningBrokers(r kafkazk.Reassignments, zk kafkazk.Handler) (reassig
 	return lb, nil
 }
 
+// addPair records a source broker replicating to a destination broker.
+func (bm reassigningBrokers) addPair(src, dst int) {
+	if _, exists := bm.pairs[src]; !exists {
+		bm.pairs[src] = map[int]struct{}{}
+	}
+
+	bm.pairs[src][dst] = struct{}{}
+}
+
 // mergeMaps takes two maps and merges them.
 func mergeMaps(a map[int]struct{}, b map[int]struct{}) map[int]struct{} {
 	m := map[int]struct{}{}
@@ -78,6 +78,26 @@ func TestGetReassigningBrokers(t *testing.T) {
 			t.Errorf("Expected follower string '%s', got '%s'", expectedThrottledFollowers[n], s)
 		}
 	}
+
+	// Check source to destination pairs.
+
+	expectedPairs := map[int][]int{1000: {1003}, 1002: {1005, 1010}}
+
+	if len(bmaps.pairs) != len(expectedPairs) {
+		t.Errorf("Expected %d source brokers in pairs, got %d", len(expectedPairs), len(bmaps.pairs))
+	}
+
+	for src, dsts := range expectedPairs {
+		if len(bmaps.pairs[src]) != len(dsts) {
+			t.Errorf("Expected %d destinations for ID %d, got %d", len(dsts), src, len(bmaps.pairs[src]))
+		}
+
+		for _, dst := range dsts {
+			if _, exists := bmaps.pairs[src][dst]; !exists {
+				t.Errorf("Expected destination ID %d for source ID %d", dst, src)
+			}
+		}
+	}
 }
 
 func TestIncompleteBrokerMetrics(t *testing.T) {
@@ -48,6 +48,54 @@ func (r ReplicationCapacityByBroker) setAllRatesWithDefault(ids []int, rate floa
 	}
 }
 
+// setRoleRatesWithDefault sets the rate as the leader throttle of reassigning
+// source brokers and the follower throttle of reassigning destination
+// brokers. Brokers that are both receive both throttles.
+func (r ReplicationCapacityByBroker) setRoleRatesWithDefault(rb reassigningBrokers, rate float64) {
+	for id := range rb.src {
+		r.storeLeaderCapacity(id, rate)
+	}
+
+	for id := range rb.dst {
+		r.storeFollowerCapacity(id, rate)
+	}
+}
+
+// limitToPeerAllowances limits the leader throttle of each source broker to
+// the sum of the follower throttles of the destination brokers it's
+// replicating to, and the follower throttle of each destination broker to
+// the sum of the leader throttles of its source brokers. This prevents
+// sources from sending more than their destinations can take in, such as
+// newly added brokers during a rebuild, and vice versa.
+func (r ReplicationCapacityByBroker) limitToPeerAllowances(rb reassigningBrokers) {
+	leaderAllowances := map[int]float64{}
+	followerAllowances := map[int]float64{}
+
+	for src, dsts := range rb.pairs {
+		for dst := range dsts {
+			leader, follower := r[src][0], r[dst][1]
+			if leader == nil || follower == nil {
+				continue
+			}
+
+			leaderAllowances[src] += *follower
+			followerAllowances[dst] += *leader
+		}
+	}
+
+	for id, allowance := range leaderAllowances {
+		if allowance < *r[id][0] {
+			r.storeLeaderCapacity(id, allowance)
+		}
+	}
+
+	for id, allowance := range followerAllowances {
+		if allowance < *r[id][1] {
+			r.storeFollowerCapacity(id, allowance)
+		}
+	}
+}
+
 func (r ReplicationCapacityByBroker) reset() {
 	for id := range r {
 		delete(r, id)
@@ -57,7 +105,9 @@ func (r ReplicationCapacityByBroker) reset() {
 // brokerReplicationCapacities traverses the list of all brokers participating
 // in the reassignment. For each broker, it determines whether the broker is
 // a leader (source) or a follower (destination), and calculates a throttle
-// accordingly, returning a ReplicationCapacityByBroker and error.
+// accordingly, returning a ReplicationCapacityByBroker and error. If missing
+// metrics are tolerated, brokers not found in the broker metrics receive the
+// minimum rate.
 func brokerReplicationCapacities(rtc *ThrottleManager, reassigning reassigningBrokers, bm kafkametrics.BrokerMetrics) (ReplicationCapacityByBroker, error) {
 	capacities := ReplicationCapacityByBroker{}
 
@@ -68,7 +118,7 @@ func brokerReplicationCapacities(rtc *ThrottleManager, reassigning reassigningBr
 		// Get the kafkametrics.Broker from the ID, check that
 		// it exists in the kafkametrics.BrokerMetrics.
 		broker, exists := bm[ID]
-		if !exists {
+		if !exists && !rtc.tolerateMissingMetrics {
 			return capacities, fmt.Errorf("Broker %d not found in broker metrics", ID)
 		}
 
@@ -100,9 +150,14 @@ func brokerReplicationCapacities(rtc *ThrottleManager, reassigning reassigningBr
 			}
 
 			// Calc. and store the rate.
-			rate, err := rtc.limits.replicationHeadroom(broker, role, currThrottle)
-			if err != nil {
-				return capacities, err
+			rate := rtc.limits["minimum"]
+			if broker != nil {
+				var err error
+				rate, err = rtc.limits.replicationHeadroom(broker, role, currThrottle)
+				if err != nil {
+					return capacities, err
+				}
+				rate = rtc.limitStep(rate, currThrottle)
 			}
 
 			switch role {
@@ -54,6 +54,45 @@ func TestBrokerReplicationCapacities(t *testing.T) {
 	}
 }
 
+func TestBrokerReplicationCapacitiesMissingMetrics(t *testing.T) {
+	zk := &kafkazk.Stub{}
+	reassignments := zk.GetReassignments()
+	reassigningBrokers, _ := GetReassigningBrokers(reassignments, zk)
+
+	lim, _ := NewLimits(NewLimitsConfig{
+		Minimum:            20,
+		SourceMaximum:      90,
+		DestinationMaximum: 80,
+		CapacityMap:        map[string]float64{"stub": 200.00},
+	})
+
+	rtc := &ThrottleManager{
+		reassignments:          reassignments,
+		previouslySetThrottles: ReplicationCapacityByBroker{},
+		limits:                 lim,
+	}
+
+	bm := stubBrokerMetrics()
+	delete(bm, 1003)
+
+	if _, err := brokerReplicationCapacities(rtc, reassigningBrokers, bm); err == nil {
+		t.Error("Expected non-nil error")
+	}
+
+	// Brokers missing metrics receive the minimum rate.
+	rtc.tolerateMissingMetrics = true
+
+	brc, err := brokerReplicationCapacities(rtc, reassigningBrokers, bm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := brc[1003]
+	if got[0] != nil || got[1] == nil || *got[1] != 20.00 {
+		t.Errorf("Expected follower rate 20.00 for ID 1003, got %v\n", got)
+	}
+}
+
 func float64ptr(f float64) *float64 {
 	return &f
 }
@@ -108,6 +147,74 @@ func TestStoreFollowerCapacity(t *testing.T) {
 	}
 }
 
+func TestSetRoleRatesWithDefault(t *testing.T) {
+	rb := reassigningBrokers{
+		src: map[int]struct{}{1001: {}, 1002: {}},
+		dst: map[int]struct{}{1002: {}, 1003: {}},
+	}
+
+	capacities := ReplicationCapacityByBroker{}
+	capacities.setRoleRatesWithDefault(rb, 100)
+
+	// [ID]: [has leader rate, has follower rate]
+	expected := map[int][2]bool{
+		1001: {true, false},
+		1002: {true, true},
+		1003: {false, true},
+	}
+
+	for id, roles := range expected {
+		for i, has := range roles {
+			rate := capacities[id][i]
+			if (rate != nil) != has {
+				t.Errorf("Unexpected %s rate %v for ID %d", roleFromIndex(i), rate, id)
+			}
+			if rate != nil && *rate != 100 {
+				t.Errorf("Expected rate 100.00, got %.2f for ID %d", *rate, id)
+			}
+		}
+	}
+}
+
+func TestLimitToPeerAllowances(t *testing.T) {
+	rb := reassigningBrokers{pairs: map[int]map[int]struct{}{}}
+	rb.addPair(1000, 1003)
+	rb.addPair(1002, 1003)
+	rb.addPair(1002, 1005)
+
+	r := ReplicationCapacityByBroker{}
+	r.storeLeaderCapacity(1000, 100)
+	r.storeLeaderCapacity(1002, 100)
+	// A newly added broker with a low allowance.
+	r.storeFollowerCapacity(1003, 30)
+	r.storeFollowerCapacity(1005, 200)
+
+	r.limitToPeerAllowances(rb)
+
+	expected := map[int]ThrottleByRole{
+		// Limited to the 1003 allowance.
+		1000: {float64ptr(30), nil},
+		// Limited to the 1003 and 1005 allowances.
+		1002: {float64ptr(100), nil},
+		// Not limited; the sources may send up to 200.
+		1003: {nil, float64ptr(30)},
+		// Limited to the 1002 allowance.
+		1005: {nil, float64ptr(100)},
+	}
+
+	for id, e := range expected {
+		for i := range e {
+			got := r[id][i]
+			switch {
+			case e[i] == nil && got != nil:
+				t.Errorf("[ID %d] Expected nil %s rate, got %f", id, roleFromIndex(i), *got)
+			case e[i] != nil && (got == nil || *got != *e[i]):
+				t.Errorf("[ID %d] Expected %s rate %f, got %v", id, roleFromIndex(i), *e[i], got)
+			}
+		}
+	}
+}
+
 func TestReset(t *testing.T) {
 	capacities := ReplicationCapacityByBroker{}
 	capacities.setAllRatesWithDefault([]int{1001, 1002, 1003}, 100)
@@ -0,0 +1,93 @@
+package replication
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// ThrottleCleanup describes the throttle configs removed once reassignments
+// complete.
+type ThrottleCleanup struct {
+	// Topics that had throttled replicas configs removed.
+	Topics []string
+	// Brokers that had throttle rates removed.
+	Brokers []int
+}
+
+// Empty returns whether no throttle configs were removed.
+func (c ThrottleCleanup) Empty() bool {
+	return len(c.Topics) == 0 && len(c.Brokers) == 0
+}
+
+// String returns a summary of the removed throttle configs.
+func (c ThrottleCleanup) String() string {
+	var s []string
+
+	if len(c.Topics) > 0 {
+		s = append(s, fmt.Sprintf("throttled replicas configs removed from %d topic(s): %v", len(c.Topics), c.Topics))
+	}
+
+	if len(c.Brokers) > 0 {
+		s = append(s, fmt.Sprintf("throttle rates removed from %d broker(s): %v", len(c.Brokers), c.Brokers))
+	}
+
+	return strings.Join(s, "; ")
+}
+
+// CleanupCompletedThrottles removes throttle configs left in place by
+// reassignments that have completed while others are still ongoing: the
+// throttled replicas configs of the done topics and the throttle rates of
+// brokers previously throttled for a reassignment that no longer participate
+// in any. Brokers with an active override are left as is. The reassigning
+// brokers must be set to those of the ongoing reassignments.
+func (tm *ThrottleManager) CleanupCompletedThrottles(done []string) (ThrottleCleanup, error) {
+	var c ThrottleCleanup
+
+	if len(done) > 0 {
+		topics := append([]string{}, done...)
+		sort.Strings(topics)
+
+		if err := tm.removeTopicThrottlesByName(topics); err != nil {
+			return c, err
+		}
+		c.Topics = topics
+	}
+
+	var ids = make(map[int]struct{})
+	for id := range tm.throttledBrokers {
+		if _, exists := tm.reassigningBrokers.all[id]; exists {
+			continue
+		}
+
+		if override, exists := tm.brokerOverrides[id]; exists && override.Config.Rate != 0 {
+			continue
+		}
+
+		ids[id] = struct{}{}
+	}
+
+	if len(ids) > 0 {
+		if err := tm.removeBrokerThrottlesByID(ids); err != nil {
+			return c, err
+		}
+
+		for id := range ids {
+			delete(tm.throttledBrokers, id)
+			c.Brokers = append(c.Brokers, id)
+		}
+		sort.Ints(c.Brokers)
+
+		tm.status.recordAdjustment(time.Now(), ids)
+	}
+
+	if !c.Empty() {
+		logging.Info("Cleaned up completed reassignment throttles", logging.Fields{"topics": c.Topics, "brokers": c.Brokers})
+		tm.events.Write("Completed reassignment throttles removed", c.String())
+	}
+
+	return c, nil
+}
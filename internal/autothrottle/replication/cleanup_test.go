@@ -0,0 +1,61 @@
+package replication
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+type eventsStub map[string]string
+
+func (e eventsStub) Write(title, m string) {
+	e[title] = m
+}
+
+func TestCleanupCompletedThrottles(t *testing.T) {
+	events := eventsStub{}
+
+	tm := &ThrottleManager{
+		zk:     kafkazk.NewZooKeeperStub(),
+		events: events,
+		reassigningBrokers: reassigningBrokers{
+			all: map[int]struct{}{1001: {}},
+		},
+		brokerOverrides: throttlestore.BrokerOverrides{
+			1003: throttlestore.BrokerThrottleOverride{ID: 1003, Config: throttlestore.ThrottleOverrideConfig{Rate: 10}},
+			1004: throttlestore.BrokerThrottleOverride{ID: 1004},
+		},
+		throttledBrokers:       map[int]struct{}{1001: {}, 1002: {}, 1003: {}, 1004: {}},
+		previouslySetThrottles: ReplicationCapacityByBroker{},
+	}
+
+	c, err := tm.CleanupCompletedThrottles([]string{"test_topic2", "test_topic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Brokers still reassigning or with an active override are retained.
+	expected := ThrottleCleanup{
+		Topics:  []string{"test_topic", "test_topic2"},
+		Brokers: []int{1002, 1004},
+	}
+
+	if c.String() != expected.String() {
+		t.Errorf("Expected cleanup '%s', got '%s'", expected, c)
+	}
+
+	if len(tm.throttledBrokers) != 2 {
+		t.Errorf("Expected 2 throttled brokers, got %d", len(tm.throttledBrokers))
+	}
+
+	if events["Completed reassignment throttles removed"] != expected.String() {
+		t.Errorf("Expected cleanup event, got %v", events)
+	}
+
+	// Nothing left to clean up.
+	c, _ = tm.CleanupCompletedThrottles(nil)
+	if !c.Empty() {
+		t.Errorf("Expected empty cleanup, got '%s'", c)
+	}
+}
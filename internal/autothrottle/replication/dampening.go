@@ -0,0 +1,114 @@
+package replication
+
+import (
+	"math"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// utilizationAverages holds exponentially weighted moving averages of broker
+// network utilization by broker ID.
+type utilizationAverages map[int]*utilizationAverage
+
+// utilizationAverage holds the moving averages of a broker's NetTX and NetRX.
+type utilizationAverage struct {
+	netTX float64
+	netRX float64
+}
+
+// smoothUtilization replaces the NetTX and NetRX of each broker in the
+// BrokerMetrics with an exponentially weighted moving average of its
+// utilization, where the smoothing factor is the weight of the most recent
+// value. A smoothing factor of 0 or 1 leaves the metrics unchanged.
+func (tm *ThrottleManager) smoothUtilization(bm kafkametrics.BrokerMetrics) {
+	a := tm.smoothingFactor
+	if a <= 0 || a >= 1 {
+		return
+	}
+
+	if tm.utilization == nil {
+		tm.utilization = make(utilizationAverages)
+	}
+
+	for id, b := range bm {
+		avg, exists := tm.utilization[id]
+		// The first value seeds the average.
+		if !exists {
+			tm.utilization[id] = &utilizationAverage{netTX: b.NetTX, netRX: b.NetRX}
+			continue
+		}
+
+		avg.netTX = a*b.NetTX + (1-a)*avg.netTX
+		avg.netRX = a*b.NetRX + (1-a)*avg.netRX
+
+		b.NetTX, b.NetRX = avg.netTX, avg.netRX
+	}
+}
+
+// limitStep limits the change of a throttle rate from the previously set
+// rate to the max step percentage. Rates without a previously set rate, or
+// where no max step is configured, are returned unchanged.
+func (tm *ThrottleManager) limitStep(rate, prev float64) float64 {
+	if tm.maxStep <= 0 || prev <= 0 {
+		return rate
+	}
+
+	step := prev * tm.maxStep / 100
+
+	return math.Max(math.Min(rate, prev+step), prev-step)
+}
+
+// applyRampUp scales the throttles of brokers newly throttled for a
+// reassignment, starting at the ramp start percentage of the calculated rate
+// and increasing linearly to the full rate over the ramp intervals. The
+// rates are recalculated from broker utilization each interval, so ramping
+// brokers are still throttled down if their utilization rises. Scaled rates
+// aren't lowered below the minimum rate. Brokers are considered new to a
+// reassignment until throttled.
+func (tm *ThrottleManager) applyRampUp(capacities ReplicationCapacityByBroker) {
+	if tm.rampIntervals <= 0 {
+		return
+	}
+
+	if tm.rampSteps == nil {
+		tm.rampSteps = make(map[int]int)
+	}
+
+	// Reset the ramp of brokers whose throttles have since been removed.
+	for id := range tm.rampSteps {
+		if _, throttled := tm.throttledBrokers[id]; !throttled {
+			delete(tm.rampSteps, id)
+		}
+	}
+
+	min := tm.limits["minimum"]
+
+	for id, rates := range capacities {
+		step := tm.rampSteps[id]
+		if step >= tm.rampIntervals {
+			continue
+		}
+
+		start := tm.rampStart / 100
+		factor := start + (1-start)*float64(step)/float64(tm.rampIntervals)
+
+		for i, rate := range rates {
+			if rate == nil {
+				continue
+			}
+			r := math.Max(*rate*factor, math.Min(*rate, min))
+			rates[i] = &r
+		}
+		capacities[id] = rates
+
+		logging.Info("Ramping up throttles", logging.Fields{
+			"broker_id":      id,
+			"ramp_percent":   factor * 100,
+			"interval":       step + 1,
+			"ramp_intervals": tm.rampIntervals,
+		})
+
+		tm.rampSteps[id] = step + 1
+	}
+}
@@ -0,0 +1,138 @@
+package replication
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestSmoothUtilization(t *testing.T) {
+	tm := &ThrottleManager{smoothingFactor: 0.25}
+
+	// [NetTX, expected smoothed NetTX]
+	expected := [][2]float64{
+		// The first value seeds the average.
+		{100, 100},
+		{20, 80},
+		{80, 80},
+		{0, 60},
+	}
+
+	for n, e := range expected {
+		bm := kafkametrics.BrokerMetrics{
+			1001: &kafkametrics.Broker{ID: 1001, NetTX: e[0], NetRX: e[0] / 2},
+		}
+
+		tm.smoothUtilization(bm)
+
+		if bm[1001].NetTX != e[1] || bm[1001].NetRX != e[1]/2 {
+			t.Errorf("[test index %d] Expected smoothed utilization %f/%f, got %f/%f\n",
+				n, e[1], e[1]/2, bm[1001].NetTX, bm[1001].NetRX)
+		}
+	}
+
+	// Smoothing is disabled with a factor of 0.
+	tm = &ThrottleManager{}
+	bm := kafkametrics.BrokerMetrics{1001: &kafkametrics.Broker{ID: 1001, NetTX: 100}}
+	tm.smoothUtilization(bm)
+
+	if bm[1001].NetTX != 100 || tm.utilization != nil {
+		t.Errorf("Expected unchanged utilization, got %f\n", bm[1001].NetTX)
+	}
+}
+
+func TestLimitStep(t *testing.T) {
+	tm := &ThrottleManager{maxStep: 20}
+
+	// [rate, previous rate, expected rate]
+	expected := [][3]float64{
+		{150, 100, 120},
+		{50, 100, 80},
+		{110, 100, 110},
+		// No previous rate.
+		{150, 0, 150},
+	}
+
+	for n, e := range expected {
+		if r := tm.limitStep(e[0], e[1]); r != e[2] {
+			t.Errorf("[test index %d] Expected rate %f, got %f\n", n, e[2], r)
+		}
+	}
+
+	// Disabled.
+	tm.maxStep = 0
+	if r := tm.limitStep(150, 100); r != 150 {
+		t.Errorf("Expected rate 150, got %f\n", r)
+	}
+}
+
+func TestNewThrottleManagerDampening(t *testing.T) {
+	for _, cfg := range []ThrottleManagerConfig{
+		{MinChange: -1},
+		{MaxStep: -1},
+		{SmoothingFactor: 1.5},
+		{RampUpIntervals: -1},
+		{RampUpIntervals: 4},
+		{RampUpIntervals: 4, RampUpStart: 150},
+	} {
+		if _, err := NewThrottleManager(cfg); err == nil {
+			t.Errorf("Expected non-nil error for config %+v\n", cfg)
+		}
+	}
+}
+
+func TestApplyRampUp(t *testing.T) {
+	tm := &ThrottleManager{
+		limits:        Limits{"minimum": 10},
+		rampIntervals: 4,
+		rampStart:     20,
+	}
+
+	// [expected leader rate per interval, from a calculated rate of 100]
+	expected := []float64{20, 40, 60, 80, 100, 100}
+
+	for n, e := range expected {
+		capacities := ReplicationCapacityByBroker{
+			1001: {float64ptr(100), nil},
+			1002: {float64ptr(30), float64ptr(5)},
+		}
+
+		tm.applyRampUp(capacities)
+		tm.throttledBrokers = map[int]struct{}{1001: {}, 1002: {}}
+
+		if got := *capacities[1001][0]; math.Abs(got-e) > 0.0001 {
+			t.Errorf("[test index %d] Expected rate %f, got %f\n", n, e, got)
+		}
+
+		if capacities[1001][1] != nil {
+			t.Errorf("[test index %d] Expected nil follower rate\n", n)
+		}
+
+		// Ramped rates are floored at the minimum, and rates already below
+		// the minimum are unchanged.
+		if n == 0 {
+			if *capacities[1002][0] != 10 || *capacities[1002][1] != 5 {
+				t.Errorf("Expected rates 10/5, got %f/%f\n", *capacities[1002][0], *capacities[1002][1])
+			}
+		}
+	}
+
+	// The ramp restarts once a broker's throttles are removed.
+	tm.throttledBrokers = map[int]struct{}{1002: {}}
+	capacities := ReplicationCapacityByBroker{1001: {float64ptr(100), nil}}
+	tm.applyRampUp(capacities)
+
+	if got := *capacities[1001][0]; got != 20 {
+		t.Errorf("Expected rate 20, got %f\n", got)
+	}
+
+	// Disabled.
+	tm = &ThrottleManager{}
+	capacities = ReplicationCapacityByBroker{1001: {float64ptr(100), nil}}
+	tm.applyRampUp(capacities)
+
+	if got := *capacities[1001][0]; got != 100 {
+		t.Errorf("Expected rate 100, got %f\n", got)
+	}
+}
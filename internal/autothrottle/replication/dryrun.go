@@ -0,0 +1,65 @@
+package replication
+
+import (
+	"sort"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+)
+
+// dryRunEvents is an EventWriter that notes in event titles that throttles
+// weren't applied.
+type dryRunEvents struct {
+	EventWriter
+}
+
+// Write writes the event with a dry-run title prefix.
+func (e dryRunEvents) Write(title, m string) {
+	e.EventWriter.Write("[dry-run] "+title, m)
+}
+
+// WriteAlert writes the event with a dry-run title prefix and the alert type
+// if the wrapped EventWriter supports alert types.
+func (e dryRunEvents) WriteAlert(title, m, alertType string) {
+	if aw, ok := e.EventWriter.(AlertEventWriter); ok {
+		aw.WriteAlert("[dry-run] "+title, m, alertType)
+		return
+	}
+
+	e.Write(title, m)
+}
+
+// dryRunApplyBrokerThrottles logs the broker throttle configs that would be
+// applied and returns their change events without writing any configs. The
+// rates aren't stored as previously set throttles since they aren't in effect.
+func (tm *ThrottleManager) dryRunApplyBrokerThrottles(configs kafkaadmin.SetThrottleConfig, capacities ReplicationCapacityByBroker) (chan brokerChangeEvent, []error) {
+	events := make(chan brokerChangeEvent, len(configs.Brokers)*2)
+
+	var ids []int
+	for id := range configs.Brokers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		for i, limit := range []int{configs.Brokers[id].OutboundLimitBytes, configs.Brokers[id].InboundLimitBytes} {
+			if limit == 0 {
+				continue
+			}
+
+			role := roleFromIndex(i)
+			rate := *capacities[id][i]
+
+			logging.Info("[dry-run] Would update throttle", logging.Fields{"broker_id": id, "role": role, "rate": rate})
+			events <- brokerChangeEvent{
+				id:   id,
+				role: role,
+				rate: rate,
+			}
+		}
+	}
+
+	close(events)
+
+	return events, nil
+}
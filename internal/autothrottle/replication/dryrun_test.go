@@ -0,0 +1,56 @@
+package replication
+
+import (
+	"testing"
+)
+
+func TestDryRunApplyBrokerThrottles(t *testing.T) {
+	events := eventsStub{}
+
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Events: events,
+		DryRun: true,
+	})
+
+	capacities := ReplicationCapacityByBroker{}
+	capacities.storeLeaderCapacity(1001, 100)
+	capacities.storeFollowerCapacity(1002, 50)
+
+	// No ZooKeeper or Kafka clients are set; any config writes would panic.
+	changes, errs := tm.applyBrokerThrottles(map[int]struct{}{1001: {}, 1002: {}}, capacities)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	expected := map[int]brokerChangeEvent{
+		1001: {id: 1001, role: "leader", rate: 100},
+		1002: {id: 1002, role: "follower", rate: 50},
+	}
+
+	var n int
+	for e := range changes {
+		if e != expected[e.id] {
+			t.Errorf("Unexpected change event %+v\n", e)
+		}
+		n++
+	}
+
+	if n != len(expected) {
+		t.Errorf("Expected %d change events, got %d\n", len(expected), n)
+	}
+
+	// Dry-run rates aren't stored as previously set throttles.
+	if len(tm.previouslySetThrottles) != 0 {
+		t.Errorf("Expected no previously set throttles, got %v\n", tm.previouslySetThrottles)
+	}
+
+	if errs := tm.applyTopicThrottles(TopicThrottledReplicas{"test": Throttled{}}); errs != nil {
+		t.Errorf("Unexpected errors: %v\n", errs)
+	}
+
+	// Event titles are prefixed.
+	tm.events.Write("Broker replication throttle set", "")
+	if _, exists := events["[dry-run] Broker replication throttle set"]; !exists {
+		t.Errorf("Expected dry-run event title, got %v\n", events)
+	}
+}
@@ -0,0 +1,116 @@
+package replication
+
+import (
+	"fmt"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// FailurePolicy determines how throttles are handled once the metrics fetch
+// failure count exceeds the failure threshold.
+type FailurePolicy int
+
+const (
+	// FailureFixedRate applies the failure rate to all reassigning brokers.
+	FailureFixedRate FailurePolicy = iota
+	// FailureHoldRate retains the previously set throttles.
+	FailureHoldRate
+	// FailureRemove writes a critical event and removes all throttles.
+	FailureRemove
+)
+
+// ParseFailurePolicy returns the FailurePolicy for the name "fixed-rate",
+// "hold" or "remove".
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch s {
+	case "fixed-rate":
+		return FailureFixedRate, nil
+	case "hold":
+		return FailureHoldRate, nil
+	case "remove":
+		return FailureRemove, nil
+	default:
+		return 0, fmt.Errorf("unknown failure policy: %s", s)
+	}
+}
+
+// String returns the FailurePolicy name.
+func (p FailurePolicy) String() string {
+	switch p {
+	case FailureHoldRate:
+		return "hold"
+	case FailureRemove:
+		return "remove"
+	default:
+		return "fixed-rate"
+	}
+}
+
+// AlertEventWriter is an EventWriter that can also write events with an
+// alert type, e.g. "error".
+type AlertEventWriter interface {
+	EventWriter
+	WriteAlert(title, m, alertType string)
+}
+
+// writeCriticalEvent writes an event with the "error" alert type if the
+// EventWriter supports alert types, otherwise as a regular event.
+func (tm *ThrottleManager) writeCriticalEvent(title, m string) {
+	if aw, ok := tm.events.(AlertEventWriter); ok {
+		aw.WriteAlert(title, m, "error")
+		return
+	}
+
+	tm.events.Write(title, m)
+}
+
+// failureRate returns the throttle rate applied with the FailureFixedRate
+// policy. The minimum rate is used if no failure rate is configured.
+func (tm *ThrottleManager) failureRate() float64 {
+	if tm.failureFixedRate > 0 {
+		return tm.failureFixedRate
+	}
+
+	return tm.limits["minimum"]
+}
+
+// checkCriticalFailures writes a critical event once the failure count
+// reaches the critical failure threshold.
+func (tm *ThrottleManager) checkCriticalFailures(errs []error) {
+	if tm.criticalFailureThreshold == 0 || tm.failures != tm.criticalFailureThreshold {
+		return
+	}
+
+	tm.writeCriticalEvent(
+		"Metrics fetch failures exceed critical threshold",
+		fmt.Sprintf("%d consecutive metrics fetch failures, applying the %s failure policy: %s",
+			tm.failures, tm.failurePolicy, errs),
+	)
+}
+
+// failureRemoveThrottles removes all throttles with the FailureRemove
+// policy. Throttles are removed once per failure streak.
+func (tm *ThrottleManager) failureRemoveThrottles() error {
+	if tm.failureRemoved {
+		logging.Warn("Throttles were removed due to metrics fetch failures, skipping throttle updates", logging.Fields{"failures": tm.failures})
+		return nil
+	}
+
+	logging.Warn("Metrics fetch failure count exceeds threshold, removing all throttles",
+		logging.Fields{"failures": tm.failures, "failure_threshold": tm.failureThreshold})
+
+	tm.writeCriticalEvent(
+		"Removing throttles due to metrics fetch failures",
+		fmt.Sprintf("%d consecutive metrics fetch failures exceed the threshold of %d, removing all replication throttles",
+			tm.failures, tm.failureThreshold),
+	)
+
+	if _, err := tm.RemoveAllThrottles(); err != nil {
+		return err
+	}
+
+	tm.ResetPreviousThrottles()
+	tm.failureRemoved = true
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package replication
+
+import (
+	"testing"
+)
+
+// alertEventsStub is an AlertEventWriter recording alert types by title.
+type alertEventsStub map[string]string
+
+func (e alertEventsStub) Write(title, m string) {
+	e[title] = "info"
+}
+
+func (e alertEventsStub) WriteAlert(title, m, alertType string) {
+	e[title] = alertType
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	expected := map[string]FailurePolicy{
+		"fixed-rate": FailureFixedRate,
+		"hold":       FailureHoldRate,
+		"remove":     FailureRemove,
+	}
+
+	for s, p := range expected {
+		got, err := ParseFailurePolicy(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("Expected policy %s, got %s\n", p, got)
+		}
+		if got.String() != s {
+			t.Errorf("Expected policy name %s, got %s\n", s, got)
+		}
+	}
+
+	if _, err := ParseFailurePolicy("invalid"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestFailureRate(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits: Limits{"minimum": 10},
+	})
+
+	if r := tm.failureRate(); r != 10 {
+		t.Errorf("Expected failure rate 10, got %f\n", r)
+	}
+
+	tm, _ = NewThrottleManager(ThrottleManagerConfig{
+		Limits:      Limits{"minimum": 10},
+		FailureRate: 25,
+	})
+
+	if r := tm.failureRate(); r != 25 {
+		t.Errorf("Expected failure rate 25, got %f\n", r)
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{FailureRate: -1}); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestCheckCriticalFailures(t *testing.T) {
+	events := alertEventsStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Events:                   events,
+		CriticalFailureThreshold: 3,
+	})
+
+	title := "Metrics fetch failures exceed critical threshold"
+
+	// The event is written once, at the threshold.
+	for i := 1; i <= 5; i++ {
+		tm.Failure()
+		tm.checkCriticalFailures(nil)
+
+		alertType, written := events[title]
+		if written != (i == 3) {
+			t.Errorf("[failure %d] Unexpected critical event state %v\n", i, written)
+		}
+
+		if written && alertType != "error" {
+			t.Errorf("Expected alert type error, got %s\n", alertType)
+		}
+
+		delete(events, title)
+	}
+
+	// Regular EventWriters receive the event as a regular event.
+	plain := eventsStub{}
+	tm, _ = NewThrottleManager(ThrottleManagerConfig{
+		Events:                   plain,
+		CriticalFailureThreshold: 1,
+		DryRun:                   true,
+	})
+
+	tm.Failure()
+	tm.checkCriticalFailures(nil)
+
+	if _, exists := plain["[dry-run] "+title]; !exists {
+		t.Errorf("Expected dry-run critical event, got %v\n", plain)
+	}
+}
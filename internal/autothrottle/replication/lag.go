@@ -0,0 +1,91 @@
+package replication
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// applyLagBackoff fetches consumer group lag if a ConsumerLag handler is
+// configured and, where the lag of any group exceeds the lag threshold,
+// reduces the leader and follower throttles of each broker by the lag
+// backoff percentage. The reduced rates aren't lowered below the minimum
+// rate. Events are written when the backoff starts and once lag recovers.
+// It returns whether the backoff was applied.
+func (tm *ThrottleManager) applyLagBackoff(capacities ReplicationCapacityByBroker) bool {
+	if tm.consumerLag == nil {
+		return false
+	}
+
+	lag, err := tm.consumerLag.GetConsumerLag()
+	if err != nil {
+		// Retain the current backoff state until lag can be fetched again.
+		logging.Error("Error fetching consumer lag", logging.Fields{"error": err})
+		if !tm.lagging {
+			return false
+		}
+	} else {
+		lagging := laggingGroups(lag, tm.lagThreshold)
+		tm.setLagging(lagging)
+	}
+
+	tm.writeLagMetrics()
+
+	if !tm.lagging {
+		return false
+	}
+
+	factor := (100 - tm.lagBackoff) / 100
+	min := tm.limits["minimum"]
+
+	for id, rates := range capacities {
+		for i, rate := range rates {
+			if rate == nil {
+				continue
+			}
+			r := math.Max(*rate*factor, math.Min(*rate, min))
+			rates[i] = &r
+		}
+		capacities[id] = rates
+	}
+
+	logging.Warn("Consumer lag exceeds threshold, reducing throttles",
+		logging.Fields{"lag_threshold": tm.lagThreshold, "lag_backoff": tm.lagBackoff})
+
+	return true
+}
+
+// setLagging takes the consumer groups exceeding the lag threshold and
+// updates the lagging state, writing an event on transitions.
+func (tm *ThrottleManager) setLagging(groups []string) {
+	switch {
+	case len(groups) > 0 && !tm.lagging:
+		tm.lagging = true
+		tm.events.Write("Consumer lag backoff started",
+			fmt.Sprintf("Consumer groups exceeding lag threshold %.2f: %s\nReducing replication throttles by %.2f%%",
+				tm.lagThreshold, strings.Join(groups, ", "), tm.lagBackoff))
+	case len(groups) == 0 && tm.lagging:
+		tm.lagging = false
+		tm.events.Write("Consumer lag recovered",
+			fmt.Sprintf("Consumer lag is below threshold %.2f, resuming calculated replication throttles", tm.lagThreshold))
+	}
+}
+
+// laggingGroups returns the sorted names of the consumer groups whose lag
+// exceeds the threshold.
+func laggingGroups(lag kafkametrics.ConsumerLag, threshold float64) []string {
+	var groups []string
+	for group, v := range lag {
+		if v > threshold {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Strings(groups)
+
+	return groups
+}
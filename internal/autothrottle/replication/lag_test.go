@@ -0,0 +1,117 @@
+package replication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// lagStub is a kafkametrics.LagHandler returning a fixed ConsumerLag.
+type lagStub struct {
+	lag kafkametrics.ConsumerLag
+	err error
+}
+
+func (l *lagStub) GetConsumerLag() (kafkametrics.ConsumerLag, error) {
+	return l.lag, l.err
+}
+
+func TestApplyLagBackoff(t *testing.T) {
+	lag := &lagStub{lag: kafkametrics.ConsumerLag{"payments": 5000, "search": 10}}
+	events := eventsStub{}
+
+	tm, err := NewThrottleManager(ThrottleManagerConfig{
+		Limits:       Limits{"minimum": 10},
+		Events:       events,
+		ConsumerLag:  lag,
+		LagThreshold: 1000,
+		LagBackoff:   50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacities := ReplicationCapacityByBroker{
+		1000: {float64ptr(100), nil},
+		1001: {nil, float64ptr(15)},
+		1002: {float64ptr(5), float64ptr(0)},
+	}
+
+	if !tm.applyLagBackoff(capacities) {
+		t.Fatal("Expected lag backoff")
+	}
+
+	// [broker ID, role index, expected rate]
+	expected := []struct {
+		id   int
+		role int
+		rate float64
+	}{
+		{1000, 0, 50},
+		// Reduced rates are floored at the minimum.
+		{1001, 1, 10},
+		// Rates already below the minimum are unchanged.
+		{1002, 0, 5},
+		{1002, 1, 0},
+	}
+
+	for _, e := range expected {
+		if got := *capacities[e.id][e.role]; got != e.rate {
+			t.Errorf("Expected broker %d role %d rate %.2f, got %.2f\n", e.id, e.role, e.rate, got)
+		}
+	}
+
+	if capacities[1000][1] != nil || capacities[1001][0] != nil {
+		t.Error("Expected nil rates to remain nil")
+	}
+
+	if _, exists := events["Consumer lag backoff started"]; !exists {
+		t.Error("Expected backoff started event")
+	}
+
+	// Fetch errors retain the backoff.
+	lag.err = errors.New("error")
+	if !tm.applyLagBackoff(ReplicationCapacityByBroker{}) {
+		t.Error("Expected lag backoff to be retained")
+	}
+
+	// Recovery.
+	lag.err = nil
+	lag.lag = kafkametrics.ConsumerLag{"payments": 500}
+	if tm.applyLagBackoff(capacities) {
+		t.Error("Unexpected lag backoff")
+	}
+
+	if _, exists := events["Consumer lag recovered"]; !exists {
+		t.Error("Expected lag recovered event")
+	}
+
+	if got := *capacities[1000][0]; got != 50 {
+		t.Errorf("Expected unchanged rate 50.00, got %.2f\n", got)
+	}
+}
+
+func TestApplyLagBackoffDisabled(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits: Limits{"minimum": 10},
+	})
+
+	capacities := ReplicationCapacityByBroker{1000: {float64ptr(100), nil}}
+	if tm.applyLagBackoff(capacities) {
+		t.Error("Unexpected lag backoff")
+	}
+
+	if got := *capacities[1000][0]; got != 100 {
+		t.Errorf("Expected rate 100.00, got %.2f\n", got)
+	}
+}
+
+func TestLagBackoffConfig(t *testing.T) {
+	for _, backoff := range []float64{-1, 100} {
+		_, err := NewThrottleManager(ThrottleManagerConfig{LagBackoff: backoff})
+		if err == nil {
+			t.Errorf("Expected error for lag backoff %.2f\n", backoff)
+		}
+	}
+}
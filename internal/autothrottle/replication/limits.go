@@ -2,12 +2,15 @@ package replication
 
 import (
 	"errors"
+	"fmt"
 	"math"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 )
 
-// Limits is a map of instance-type to network bandwidth limits.
+// Limits is a map of instance-type to network bandwidth limits. Per-broker
+// network bandwidth limits are keyed by brokerCapacityKey, and capacity
+// profile headrooms by headroomKey.
 type Limits map[string]float64
 
 // NewLimitsConfig is used to initialize
@@ -15,12 +18,28 @@ type Limits map[string]float64
 type NewLimitsConfig struct {
 	// Min throttle rate in MB/s.
 	Minimum float64
+	// Max throttle rate in MB/s. A value of 0 disables the maximum.
+	Maximum float64
 	// Max source broker throttle rate as a portion of capacity.
 	SourceMaximum float64
 	// Max destination broker throttle rate as a portion of capacity.
 	DestinationMaximum float64
+	// CPU utilization percent above which broker throttle rates are
+	// reduced. A value of 0 disables CPU based reductions.
+	CPUThreshold float64
 	// Map of instance-type to total network capacity in MB/s.
 	CapacityMap map[string]float64
+	// Map of broker ID to total network capacity in MB/s. Broker capacities
+	// take precedence over the CapacityMap, allowing individual brokers of a
+	// mixed fleet to be throttled according to their own capacity.
+	BrokerCapacityMap map[int]float64
+	// Map of instance-type to max replication throttle rate as a percentage
+	// of available capacity, used in place of the source and destination
+	// maximums. Takes precedence over profile instance type headrooms.
+	HeadroomMap map[string]float64
+	// Capacity profiles by instance type and broker ID. The CapacityMap and
+	// BrokerCapacityMap take precedence over profile network capacities.
+	Profiles CapacityProfiles
 }
 
 // NewLimits takes a minimum float64 and a map of instance-type to
@@ -29,27 +48,84 @@ func NewLimits(c NewLimitsConfig) (Limits, error) {
 	switch {
 	case c.Minimum <= 0:
 		return nil, errors.New("minimum must be > 0")
+	case c.Maximum < 0:
+		return nil, errors.New("maximum must be >= 0")
+	case c.Maximum > 0 && c.Maximum < c.Minimum:
+		return nil, errors.New("maximum must be >= minimum")
 	case c.SourceMaximum <= 0 || c.SourceMaximum >= 100:
 		return nil, errors.New("source maximum must be > 0 and < 100")
 	case c.DestinationMaximum <= 0 || c.DestinationMaximum >= 100:
 		return nil, errors.New("destination maximum must be > 0 and < 100")
+	case c.CPUThreshold < 0 || c.CPUThreshold >= 100:
+		return nil, errors.New("CPU threshold must be >= 0 and < 100")
 	}
 
 	// Populate the min/max vals into the Limits map.
 	lim := Limits{
 		"minimum": c.Minimum,
+		"maximum": c.Maximum,
 		"srcMax":  c.SourceMaximum,
 		"dstMax":  c.DestinationMaximum,
+		"cpuMax":  c.CPUThreshold,
 	}
 
+	c.Profiles.apply(lim)
+
 	// Update with provided capacity map.
 	for k, v := range c.CapacityMap {
 		lim[k] = v
 	}
 
+	for id, v := range c.BrokerCapacityMap {
+		lim[brokerCapacityKey(id)] = v
+	}
+
+	for k, v := range c.HeadroomMap {
+		if v <= 0 || v >= 100 {
+			return nil, fmt.Errorf("%s headroom must be > 0 and < 100", k)
+		}
+		lim[headroomKey(k)] = v
+	}
+
 	return lim, nil
 }
 
+// brokerCapacityKey returns the Limits key for a broker's capacity.
+func brokerCapacityKey(id int) string {
+	return fmt.Sprintf("broker:%d", id)
+}
+
+// capacity returns the network capacity of a *kafkametrics.Broker in MB/s
+// and whether it's known. A broker capacity is used if configured, followed
+// by the capacity reported with the broker metrics, followed by the
+// capacity of the broker's instance type.
+func (l Limits) capacity(b *kafkametrics.Broker) (float64, bool) {
+	if c, exists := l[brokerCapacityKey(b.ID)]; exists {
+		return c, true
+	}
+
+	if b.NetworkCapacity > 0 {
+		return b.NetworkCapacity, true
+	}
+
+	c, exists := l[b.InstanceType]
+	return c, exists
+}
+
+// maxRatio returns the max replication throttle rate of a
+// *kafkametrics.Broker as a percentage of available capacity. A capacity
+// profile headroom is used if configured for the broker or its instance
+// type, otherwise the default.
+func (l Limits) maxRatio(b *kafkametrics.Broker, def float64) float64 {
+	for _, k := range []string{brokerCapacityKey(b.ID), b.InstanceType} {
+		if h, exists := l[headroomKey(k)]; exists {
+			return h
+		}
+	}
+
+	return def
+}
+
 // replicationHeadroom takes a *kafkametrics.Broker, what type of replica role
 // it's fulfilling, and the last set throttle rate. A replication headroom value
 // is returned based on utilization vs capacity. Headroom is determined by
@@ -60,30 +136,110 @@ func NewLimits(c NewLimitsConfig) (Limits, error) {
 // is available for replication. We then use the greater of:
 // - this value * the configured portion of free bandwidth eligible for replication
 // - the configured minimum replication rate in MB/s
+// If the broker's replication bytes out is known, a leader's non-replication
+// throughput is instead approximated by subtracting the lesser of the current
+// throttle rate and the replication bytes out from the network utilization.
+// If a CPU threshold is configured and the broker CPU utilization exceeds it,
+// the headroom is scaled down linearly, reaching the minimum replication rate
+// at 100% utilization.
 func (l Limits) replicationHeadroom(b *kafkametrics.Broker, rt ReplicaType, prevThrottle float64) (float64, error) {
 	var currNetUtilization float64
 	var maxRatio float64
+	var replicationUtil = prevThrottle
 
 	switch rt {
 	case "leader":
 		currNetUtilization = b.NetTX
-		maxRatio = l["srcMax"]
+		maxRatio = l.maxRatio(b, l["srcMax"])
+		if b.ReplicationBytesOut > 0 {
+			replicationUtil = math.Min(prevThrottle, b.ReplicationBytesOut)
+		}
 	case "follower":
 		currNetUtilization = b.NetRX
-		maxRatio = l["dstMax"]
+		maxRatio = l.maxRatio(b, l["dstMax"])
 	default:
 		return 0.00, errors.New("invalid replica type")
 	}
 
-	if capacity, exists := l[b.InstanceType]; exists {
-		nonThrottleUtil := math.Max(currNetUtilization-prevThrottle, 0.00)
+	if capacity, exists := l.capacity(b); exists {
+		nonThrottleUtil := math.Max(currNetUtilization-replicationUtil, 0.00)
 		// Determine if/how far over the target capacity
 		// we are. This is also subtracted from the available
 		// headroom.
 		overCap := math.Max(currNetUtilization-capacity, 0.00)
 
-		return math.Max((capacity-nonThrottleUtil-overCap)*(maxRatio/100), l["minimum"]), nil
+		headroom := (capacity - nonThrottleUtil - overCap) * (maxRatio / 100)
+
+		if cpuMax := l["cpuMax"]; cpuMax > 0 && b.CPU > cpuMax {
+			headroom *= math.Max((100-b.CPU)/(100-cpuMax), 0.00)
+		}
+
+		return math.Max(headroom, l["minimum"]), nil
 	}
 
 	return l["minimum"], errors.New("unknown instance type")
 }
+
+// bound limits a throttle rate in MB/s to the minimum rate and, if
+// configured, the maximum rate. Rates that aren't a number, such as those
+// calculated from NaN metrics, are set to the minimum rate.
+func (l Limits) bound(rate float64) float64 {
+	switch {
+	case math.IsNaN(rate) || rate < l["minimum"]:
+		return l["minimum"]
+	case l["maximum"] > 0 && rate > l["maximum"]:
+		return l["maximum"]
+	}
+
+	return rate
+}
+
+// logDirHeadroom takes a *kafkametrics.Broker, the max log dir throttle rate
+// as a percentage of available disk IO capacity and the last set log dir
+// throttle rate, returning a log dir throttle rate. As with
+// replicationHeadroom, the non-move disk IO is approximated by subtracting
+// the last set throttle rate from the disk IO utilization, and the rate is
+// the max percentage of the remaining disk IO capacity, bounded by the
+// configured minimum and maximum rates.
+func (l Limits) logDirHeadroom(b *kafkametrics.Broker, maxRatio, prevThrottle float64) (float64, error) {
+	capacity := b.DiskIOCapacity
+	if capacity <= 0 {
+		return l["minimum"], errors.New("unknown disk IO capacity")
+	}
+
+	nonThrottleUtil := math.Max(b.DiskIO-prevThrottle, 0.00)
+	overCap := math.Max(b.DiskIO-capacity, 0.00)
+
+	headroom := (capacity - nonThrottleUtil - overCap) * (maxRatio / 100)
+
+	return l.bound(headroom), nil
+}
+
+// clientQuotaHeadroom takes a *kafkametrics.Broker, the quota direction
+// ("produce" or "fetch"), the max client quota rate as a percentage of
+// available network capacity and the total of the last set client quotas,
+// returning the total client quota allowance of the broker in MB/s. As with
+// replicationHeadroom, the non-quota throughput is approximated by
+// subtracting the last set quotas from the network utilization.
+func (l Limits) clientQuotaHeadroom(b *kafkametrics.Broker, direction string, maxRatio, prevQuotas float64) (float64, error) {
+	var currNetUtilization float64
+
+	switch direction {
+	case "produce":
+		currNetUtilization = b.NetRX
+	case "fetch":
+		currNetUtilization = b.NetTX
+	default:
+		return 0.00, errors.New("invalid client quota direction")
+	}
+
+	capacity, exists := l.capacity(b)
+	if !exists {
+		return 0.00, errors.New("unknown instance type")
+	}
+
+	nonQuotaUtil := math.Max(currNetUtilization-prevQuotas, 0.00)
+	overCap := math.Max(currNetUtilization-capacity, 0.00)
+
+	return math.Max((capacity-nonQuotaUtil-overCap)*(maxRatio/100), 0.00), nil
+}
@@ -1,6 +1,7 @@
 package replication
 
 import (
+	"math"
 	"testing"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
@@ -37,6 +38,48 @@ func TestNewLimits(t *testing.T) {
 	if err == nil {
 		t.Error("Expected non-nil error")
 	}
+
+	c.DestinationMaximum = 80
+	c.CPUThreshold = 100 // Invalid.
+
+	_, err = NewLimits(c)
+	if err == nil {
+		t.Error("Expected non-nil error")
+	}
+
+	c.CPUThreshold = 0
+	c.Maximum = 5 // Invalid; below the minimum.
+
+	_, err = NewLimits(c)
+	if err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestBound(t *testing.T) {
+	l := Limits{"minimum": 10, "maximum": 200}
+
+	// [rate, expected bounded rate]
+	expected := [][2]float64{
+		{50, 50},
+		{5, 10},
+		{-20, 10},
+		{500, 200},
+		{math.Inf(1), 200},
+		{math.NaN(), 10},
+	}
+
+	for n, params := range expected {
+		if r := l.bound(params[0]); r != params[1] {
+			t.Errorf("[test index %d] Expected bounded rate %f, got %f\n", n, params[1], r)
+		}
+	}
+
+	// Without a maximum, rates are only floored.
+	l = Limits{"minimum": 10}
+	if r := l.bound(500); r != 500 {
+		t.Errorf("Expected bounded rate 500, got %f\n", r)
+	}
 }
 
 func TestReplicationHeadroom(t *testing.T) {
@@ -90,3 +133,152 @@ func TestReplicationHeadroom(t *testing.T) {
 		}
 	}
 }
+
+func TestReplicationHeadroomBrokerCapacity(t *testing.T) {
+	c := NewLimitsConfig{
+		Minimum:            10,
+		SourceMaximum:      80,
+		DestinationMaximum: 60,
+		CapacityMap: map[string]float64{
+			"stub": 100,
+		},
+		BrokerCapacityMap: map[int]float64{
+			1002: 200,
+		},
+	}
+
+	l, _ := NewLimits(c)
+
+	// [broker ID, reported capacity, expected headroom]
+	expected := [][3]float64{
+		// The instance type capacity.
+		{1001, 0, 24},
+		// The reported broker capacity.
+		{1001, 150, 64},
+		// The configured broker capacity takes precedence.
+		{1002, 150, 104},
+	}
+
+	for n, params := range expected {
+		b := &kafkametrics.Broker{
+			ID:              int(params[0]),
+			InstanceType:    "stub",
+			NetTX:           70,
+			NetworkCapacity: params[1],
+		}
+
+		h, err := l.replicationHeadroom(b, "leader", 0)
+		if err != nil {
+			t.Errorf("[test index %d] Unexpected error: %s\n", n, err)
+		}
+		if h != params[2] {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, params[2], h)
+		}
+	}
+
+	// Brokers without a known capacity receive the minimum.
+	b := &kafkametrics.Broker{ID: 1003, InstanceType: "unknown", NetTX: 70}
+	if h, err := l.replicationHeadroom(b, "leader", 0); err == nil || h != 10 {
+		t.Errorf("Expected minimum headroom and error, got %f, %v\n", h, err)
+	}
+}
+
+func TestReplicationHeadroomReplicationBytesOut(t *testing.T) {
+	c := NewLimitsConfig{
+		Minimum:            10,
+		SourceMaximum:      80,
+		DestinationMaximum: 60,
+		CapacityMap: map[string]float64{
+			"stub": 100,
+		},
+	}
+
+	l, _ := NewLimits(c)
+	b := &kafkametrics.Broker{
+		InstanceType: "stub",
+		NetTX:        80,
+	}
+
+	// [replication bytes out, current throttle, expected headroom]
+	expected := [][3]float64{
+		// Unknown; the throttle is subtracted.
+		{0, 70, 72},
+		// Replication is using less than the throttle.
+		{30, 70, 40},
+		// Replication bytes out exceeds the throttle.
+		{75, 70, 72},
+	}
+
+	for n, params := range expected {
+		b.ReplicationBytesOut = params[0]
+		h, _ := l.replicationHeadroom(b, "leader", params[1])
+		if h != params[2] {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, params[2], h)
+		}
+	}
+}
+
+func TestReplicationHeadroomCPU(t *testing.T) {
+	c := NewLimitsConfig{
+		Minimum:            10,
+		SourceMaximum:      80,
+		DestinationMaximum: 60,
+		CPUThreshold:       60,
+		CapacityMap: map[string]float64{
+			"stub": 100,
+		},
+	}
+
+	l, _ := NewLimits(c)
+	b := &kafkametrics.Broker{
+		InstanceType: "stub",
+		NetTX:        70,
+	}
+
+	// [current CPU utilization, expected headroom]
+	expected := [][2]float64{
+		{0, 24},
+		{60, 24},
+		{80, 12},
+		{95, 10},
+		{100, 10},
+	}
+
+	for n, params := range expected {
+		b.CPU = params[0]
+		h, _ := l.replicationHeadroom(b, "leader", 0)
+		if h != params[1] {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, params[1], h)
+		}
+	}
+}
+
+func TestLogDirHeadroom(t *testing.T) {
+	l := Limits{"minimum": 10}
+	b := &kafkametrics.Broker{DiskIOCapacity: 100}
+
+	// [current disk IO, current throttle, expected headroom]
+	expected := [][3]float64{
+		{70, 0, 15},
+		{80, 70, 45},
+		{110, 70, 25},
+		{200, 70, 10},
+	}
+
+	for n, params := range expected {
+		b.DiskIO = params[0]
+		h, err := l.logDirHeadroom(b, 50, params[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h != params[2] {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, params[2], h)
+		}
+	}
+
+	// Unknown disk IO capacities use the minimum.
+	h, err := l.logDirHeadroom(&kafkametrics.Broker{DiskIO: 50}, 50, 0)
+	if err == nil || h != 10 {
+		t.Errorf("Expected error and headroom 10, got %v, %f\n", err, h)
+	}
+}
@@ -0,0 +1,217 @@
+package replication
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+// logDirThrottleCfgName is the broker config limiting the rate of replica
+// moves between the broker's log dirs.
+const logDirThrottleCfgName = "replica.alter.log.dirs.io.max.bytes.per.second"
+
+// UpdateLogDirThrottles throttles replica moves between the log dirs of each
+// broker, such as JBOD disk-to-disk migrations. Brokers are considered to be
+// moving replicas while their AlterLogDirsLag is non-zero, and are throttled
+// according to their disk IO utilization and capacity. Throttles are removed
+// once the moves complete. Brokers missing from the broker metrics retain
+// any previously set throttle.
+func (tm *ThrottleManager) UpdateLogDirThrottles() error {
+	if tm.logDirMaxRate <= 0 {
+		return nil
+	}
+
+	bm, errs := tm.km.GetMetrics()
+	if len(bm) == 0 {
+		return fmt.Errorf("Error fetching metrics for log dir throttles: %s", errs)
+	}
+
+	rates, done := tm.logDirThrottleRates(bm)
+
+	var errStrings []string
+
+	if err := tm.applyLogDirThrottles(rates); err != nil {
+		errStrings = append(errStrings, err.Error())
+	}
+
+	if err := tm.removeLogDirThrottles(done); err != nil {
+		errStrings = append(errStrings, err.Error())
+	}
+
+	if errStrings != nil {
+		return fmt.Errorf("Error updating log dir throttles: %s", strings.Join(errStrings, ", "))
+	}
+
+	return nil
+}
+
+// logDirThrottleRates returns the log dir throttle rates of brokers moving
+// replicas between log dirs that differ from the previously set rates by at
+// least the change threshold, along with the IDs of previously throttled
+// brokers that are no longer moving replicas.
+func (tm *ThrottleManager) logDirThrottleRates(bm kafkametrics.BrokerMetrics) (map[int]float64, []int) {
+	rates := map[int]float64{}
+	var done []int
+
+	for id, b := range bm {
+		prev, throttled := tm.logDirThrottles[id]
+
+		if b.AlterLogDirsLag <= 0 {
+			if throttled {
+				done = append(done, id)
+			}
+			continue
+		}
+
+		rate, err := tm.limits.logDirHeadroom(b, tm.logDirMaxRate, prev)
+		if err != nil {
+			logging.Error("Error calculating log dir throttle, using the minimum rate",
+				logging.Fields{"broker_id": id, "error": err})
+		}
+
+		logging.Info("Log dir throttle rate",
+			logging.Fields{"broker_id": id, "max_utilization": tm.logDirMaxRate, "rate": rate})
+
+		if throttled && prev > 0 {
+			if d := math.Abs((prev - rate) / prev * 100); d < tm.changeThreshold {
+				logging.Info("Proposed log dir throttle is below the change threshold, skipping throttle update",
+					logging.Fields{"broker_id": id, "change": d, "change_threshold": tm.changeThreshold})
+				continue
+			}
+		}
+
+		rates[id] = rate
+	}
+
+	sort.Ints(done)
+
+	return rates, done
+}
+
+// applyLogDirThrottles sets the log dir throttle rates in MB/s by broker ID,
+// storing the applied rates and writing an event.
+func (tm *ThrottleManager) applyLogDirThrottles(rates map[int]float64) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	var ids []int
+	for id := range rates {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var updated []string
+	var errIDs []int
+
+	for _, id := range ids {
+		rate := rates[id]
+		rateBytes := int(math.Round(rate * 1000000.00))
+
+		var err error
+		switch {
+		case tm.dryRun:
+			logging.Info("[dry-run] Would update log dir throttle", logging.Fields{"broker_id": id, "rate": rate})
+		case !tm.kafkaNativeMode:
+			_, err = tm.zk.UpdateKafkaConfig(kafkazk.KafkaConfig{
+				Type:    "broker",
+				Name:    strconv.Itoa(id),
+				Configs: []kafkazk.KafkaConfigKV{{logDirThrottleCfgName, strconv.Itoa(rateBytes)}},
+			})
+		default:
+			ctx, cancel := tm.kafkaRequestContext()
+			err = tm.ka.SetThrottle(ctx, kafkaadmin.SetThrottleConfig{
+				Brokers: map[int]kafkaadmin.BrokerThrottleConfig{id: {LogDirLimitBytes: rateBytes}},
+			})
+			cancel()
+		}
+
+		if err != nil {
+			logging.Error("Error setting log dir throttle", logging.Fields{"broker_id": id, "error": err})
+			errIDs = append(errIDs, id)
+			continue
+		}
+
+		// Dry-run rates aren't in effect and aren't stored.
+		if !tm.dryRun {
+			logging.Info("Updated log dir throttle", logging.Fields{"broker_id": id, "rate": rate})
+			if tm.logDirThrottles == nil {
+				tm.logDirThrottles = make(map[int]float64)
+			}
+			tm.logDirThrottles[id] = rate
+		}
+
+		updated = append(updated, fmt.Sprintf("%d: %0.2fMB/s", id, rate))
+	}
+
+	if len(updated) > 0 {
+		m := fmt.Sprintf("Log dir throttle set on the following brokers: %s", strings.Join(updated, ", "))
+		tm.events.Write("Broker log dir throttle set", m)
+	}
+
+	if errIDs != nil {
+		return fmt.Errorf("failed to set log dir throttles on brokers %v", errIDs)
+	}
+
+	return nil
+}
+
+// removeLogDirThrottles removes the log dir throttles of the broker IDs,
+// writing an event.
+func (tm *ThrottleManager) removeLogDirThrottles(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var removed []int
+	var errIDs []int
+
+	for _, id := range ids {
+		var err error
+		switch {
+		case tm.dryRun:
+			logging.Info("[dry-run] Would remove log dir throttle", logging.Fields{"broker_id": id})
+		case !tm.kafkaNativeMode:
+			_, err = tm.zk.UpdateKafkaConfig(kafkazk.KafkaConfig{
+				Type:    "broker",
+				Name:    strconv.Itoa(id),
+				Configs: []kafkazk.KafkaConfigKV{{logDirThrottleCfgName, ""}},
+			})
+		default:
+			ctx, cancel := tm.kafkaRequestContext()
+			err = tm.ka.RemoveThrottle(ctx, kafkaadmin.RemoveThrottleConfig{
+				Brokers: []int{id},
+				LogDirs: true,
+			})
+			cancel()
+		}
+
+		if err != nil {
+			logging.Error("Error removing log dir throttle", logging.Fields{"broker_id": id, "error": err})
+			errIDs = append(errIDs, id)
+			continue
+		}
+
+		logging.Info("Log dir throttle removed", logging.Fields{"broker_id": id})
+		delete(tm.logDirThrottles, id)
+		removed = append(removed, id)
+	}
+
+	if len(removed) > 0 {
+		m := fmt.Sprintf("Log dir throttle removed on the following brokers: %v", removed)
+		tm.events.Write("Broker log dir throttle removed", m)
+	}
+
+	if errIDs != nil {
+		return fmt.Errorf("failed to remove log dir throttles on brokers %v", errIDs)
+	}
+
+	return nil
+}
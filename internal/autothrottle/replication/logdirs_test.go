@@ -0,0 +1,92 @@
+package replication
+
+import (
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+// metricsStub is a kafkametrics.Handler returning fixed BrokerMetrics.
+type metricsStub struct {
+	bm kafkametrics.BrokerMetrics
+}
+
+func (m *metricsStub) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return m.bm, nil
+}
+
+func (m *metricsStub) PostEvent(*kafkametrics.Event) error {
+	return nil
+}
+
+func TestUpdateLogDirThrottles(t *testing.T) {
+	events := eventsStub{}
+	km := &metricsStub{bm: kafkametrics.BrokerMetrics{
+		// Moving replicas.
+		1001: {ID: 1001, DiskIO: 70, DiskIOCapacity: 100, AlterLogDirsLag: 5000},
+		// Not moving replicas.
+		1002: {ID: 1002, DiskIO: 70, DiskIOCapacity: 100},
+	}}
+
+	tm, err := NewThrottleManager(ThrottleManagerConfig{
+		Limits:          Limits{"minimum": 10},
+		ChangeThreshold: 10,
+		LogDirMaxRate:   50,
+		KafkaZK:         kafkazk.NewZooKeeperStub(),
+		KafkaMetrics:    km,
+		Events:          events,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tm.UpdateLogDirThrottles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tm.logDirThrottles) != 1 || tm.logDirThrottles[1001] != 15 {
+		t.Errorf("Expected log dir throttle 15MB/s on broker 1001, got %v\n", tm.logDirThrottles)
+	}
+
+	if events["Broker log dir throttle set"] != "Log dir throttle set on the following brokers: 1001: 15.00MB/s" {
+		t.Errorf("Unexpected events %v\n", events)
+	}
+
+	// The previously set throttle is subtracted from the disk IO.
+	rates, _ := tm.logDirThrottleRates(km.bm)
+	if len(rates) != 1 || rates[1001] != 22.5 {
+		t.Errorf("Expected rate 22.5MB/s for broker 1001, got %v\n", rates)
+	}
+
+	// Rates within the change threshold aren't updated.
+	km.bm[1001].DiskIOCapacity = 85
+	if rates, _ := tm.logDirThrottleRates(km.bm); len(rates) != 0 {
+		t.Errorf("Expected no rate updates, got %v\n", rates)
+	}
+
+	// Throttles are removed once moves complete.
+	km.bm[1001].AlterLogDirsLag = 0
+	if err := tm.UpdateLogDirThrottles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tm.logDirThrottles) != 0 {
+		t.Errorf("Expected no log dir throttles, got %v\n", tm.logDirThrottles)
+	}
+
+	if events["Broker log dir throttle removed"] != "Log dir throttle removed on the following brokers: [1001]" {
+		t.Errorf("Unexpected events %v\n", events)
+	}
+}
+
+func TestUpdateLogDirThrottlesDisabled(t *testing.T) {
+	tm := &ThrottleManager{}
+	if err := tm.UpdateLogDirThrottles(); err != nil {
+		t.Errorf("Expected nil error, got %s\n", err)
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{LogDirMaxRate: 100}); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
@@ -0,0 +1,92 @@
+package replication
+
+import (
+	"fmt"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// Throttle metric names.
+const (
+	// The throttle rate of a broker in MB/s, tagged by broker ID, role and
+	// the source of the rate.
+	throttleRateMetric = "autothrottle.broker.throttle_rate"
+	// 1 if throttles are reverted to the min-rate due to metrics fetch
+	// failures, otherwise 0.
+	failureModeMetric = "autothrottle.failure_mode"
+	// The number of consecutive metrics fetch failures.
+	metricsFailuresMetric = "autothrottle.metrics_failures"
+	// 1 if throttles are reduced due to consumer lag, otherwise 0.
+	lagBackoffMetric = "autothrottle.consumer_lag_backoff"
+)
+
+// writeThrottleMetrics writes the throttle rates of each broker in the
+// throttle inputs as gauges if a MetricsWriter is configured.
+func (tm *ThrottleManager) writeThrottleMetrics(inputs map[int]api.ThrottleInputs) {
+	if tm.metrics == nil {
+		return
+	}
+
+	for id, in := range inputs {
+		for i, rate := range []*float64{in.LeaderRate, in.FollowerRate} {
+			if rate == nil {
+				continue
+			}
+
+			tags := tm.metricTags(fmt.Sprintf("broker_id:%d", id), "role:"+roleFromIndex(i), "source:"+in.Source)
+			if err := tm.metrics.Gauge(throttleRateMetric, *rate, tags); err != nil {
+				logging.Error("Error writing throttle rate metric", logging.Fields{"broker_id": id, "role": roleFromIndex(i), "rate": *rate, "error": err})
+			}
+		}
+	}
+}
+
+// writeFailureMetrics writes whether throttles are in the failure mode and
+// the metrics fetch failure count as gauges if a MetricsWriter is
+// configured.
+func (tm *ThrottleManager) writeFailureMetrics(inFailureMode bool) {
+	if tm.metrics == nil {
+		return
+	}
+
+	var failureMode float64
+	if inFailureMode {
+		failureMode = 1
+	}
+
+	for name, v := range map[string]float64{
+		failureModeMetric:     failureMode,
+		metricsFailuresMetric: float64(tm.failures),
+	} {
+		if err := tm.metrics.Gauge(name, v, tm.metricTags()); err != nil {
+			logging.Error("Error writing failure metric", logging.Fields{"metric": name, "error": err})
+		}
+	}
+}
+
+// writeLagMetrics writes whether throttles are reduced due to consumer lag
+// as a gauge if a MetricsWriter is configured.
+func (tm *ThrottleManager) writeLagMetrics() {
+	if tm.metrics == nil {
+		return
+	}
+
+	var lagging float64
+	if tm.lagging {
+		lagging = 1
+	}
+
+	if err := tm.metrics.Gauge(lagBackoffMetric, lagging, tm.metricTags()); err != nil {
+		logging.Error("Error writing lag metric", logging.Fields{"metric": lagBackoffMetric, "error": err})
+	}
+}
+
+// metricTags returns the tags with a dry-run tag appended in dry-run mode.
+func (tm *ThrottleManager) metricTags(tags ...string) []string {
+	if tm.dryRun {
+		tags = append(tags, "dry_run:true")
+	}
+
+	return tags
+}
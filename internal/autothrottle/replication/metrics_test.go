@@ -0,0 +1,70 @@
+package replication
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
+)
+
+// gaugeStub records gauges by name and sorted, joined tags.
+type gaugeStub map[string]float64
+
+func (g gaugeStub) Gauge(name string, value float64, tags []string) error {
+	t := append([]string{}, tags...)
+	sort.Strings(t)
+	g[name+"|"+strings.Join(t, ",")] = value
+	return nil
+}
+
+func TestWriteThrottleMetrics(t *testing.T) {
+	metrics := gaugeStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{Metrics: metrics})
+
+	inputs := map[int]api.ThrottleInputs{
+		1001: {Source: throttleSourceMetrics, LeaderRate: float64ptr(100)},
+		1002: {Source: throttleSourceBrokerOverride, LeaderRate: float64ptr(20), FollowerRate: float64ptr(30)},
+	}
+
+	tm.writeThrottleMetrics(inputs)
+
+	expected := gaugeStub{
+		throttleRateMetric + "|broker_id:1001,role:leader,source:metrics":           100,
+		throttleRateMetric + "|broker_id:1002,role:leader,source:broker_override":   20,
+		throttleRateMetric + "|broker_id:1002,role:follower,source:broker_override": 30,
+	}
+
+	if len(metrics) != len(expected) {
+		t.Errorf("Expected %d metrics, got %d: %v\n", len(expected), len(metrics), metrics)
+	}
+
+	for k, v := range expected {
+		if metrics[k] != v {
+			t.Errorf("Expected %s to be %f, got %f\n", k, v, metrics[k])
+		}
+	}
+}
+
+func TestWriteFailureMetrics(t *testing.T) {
+	metrics := gaugeStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{Metrics: metrics, DryRun: true})
+	tm.failures = 3
+
+	tm.writeFailureMetrics(true)
+
+	expected := gaugeStub{
+		failureModeMetric + "|dry_run:true":     1,
+		metricsFailuresMetric + "|dry_run:true": 3,
+	}
+
+	for k, v := range expected {
+		if metrics[k] != v {
+			t.Errorf("Expected %s to be %f, got %f\n", k, v, metrics[k])
+		}
+	}
+
+	// No MetricsWriter is a no-op.
+	tm, _ = NewThrottleManager(ThrottleManagerConfig{})
+	tm.writeFailureMetrics(true)
+}
@@ -0,0 +1,104 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CapacityProfile describes the network capacity of an instance type or
+// broker and the portion of it eligible for replication.
+type CapacityProfile struct {
+	// Network capacity in MB/s.
+	Network float64 `json:"network" yaml:"network"`
+	// Max replication throttle rate as a percentage of available capacity.
+	// If set, it's used in place of the source and destination maximums.
+	Headroom float64 `json:"headroom,omitempty" yaml:"headroom,omitempty"`
+}
+
+// CapacityProfiles holds CapacityProfile by instance type and broker ID. A
+// broker profile takes precedence over the profile of its instance type.
+type CapacityProfiles struct {
+	InstanceTypes map[string]CapacityProfile `json:"instance_types" yaml:"instance_types"`
+	Brokers       map[int]CapacityProfile    `json:"brokers" yaml:"brokers"`
+}
+
+// LoadCapacityProfiles reads CapacityProfiles from the file at path. Files
+// with a .json extension are parsed as JSON, all others as YAML.
+func LoadCapacityProfiles(path string) (CapacityProfiles, error) {
+	var p CapacityProfiles
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(b, &p)
+	default:
+		err = yaml.Unmarshal(b, &p)
+	}
+
+	if err != nil {
+		return p, fmt.Errorf("error parsing capacity profiles %s: %s", path, err)
+	}
+
+	return p, p.validate()
+}
+
+// validate checks that all profiles have a network capacity > 0 and a
+// headroom >= 0 and < 100.
+func (p CapacityProfiles) validate() error {
+	check := func(name string, c CapacityProfile) error {
+		switch {
+		case c.Network <= 0:
+			return fmt.Errorf("capacity profile %s: network must be > 0", name)
+		case c.Headroom < 0 || c.Headroom >= 100:
+			return fmt.Errorf("capacity profile %s: headroom must be >= 0 and < 100", name)
+		}
+		return nil
+	}
+
+	for t, c := range p.InstanceTypes {
+		if err := check(t, c); err != nil {
+			return err
+		}
+	}
+
+	for id, c := range p.Brokers {
+		if err := check(fmt.Sprintf("broker %d", id), c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headroomKey returns the Limits key for the headroom of a capacity key.
+func headroomKey(k string) string {
+	return "headroom:" + k
+}
+
+// apply populates the Limits with the capacities and headrooms of the
+// CapacityProfiles.
+func (p CapacityProfiles) apply(l Limits) {
+	for t, c := range p.InstanceTypes {
+		l.storeProfile(t, c)
+	}
+
+	for id, c := range p.Brokers {
+		l.storeProfile(brokerCapacityKey(id), c)
+	}
+}
+
+// storeProfile stores a CapacityProfile under the capacity key k.
+func (l Limits) storeProfile(k string, c CapacityProfile) {
+	l[k] = c.Network
+	if c.Headroom > 0 {
+		l[headroomKey(k)] = c.Headroom
+	}
+}
@@ -0,0 +1,149 @@
+package replication
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestLoadCapacityProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `{"instance_types":{"stub":{"network":100,"headroom":50}},"brokers":{"1002":{"network":200}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadCapacityProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.InstanceTypes["stub"] != (CapacityProfile{Network: 100, Headroom: 50}) {
+		t.Errorf("Unexpected profile %+v\n", p.InstanceTypes["stub"])
+	}
+
+	if p.Brokers[1002] != (CapacityProfile{Network: 200}) {
+		t.Errorf("Unexpected profile %+v\n", p.Brokers[1002])
+	}
+
+	// Invalid headroom.
+	data = `{"instance_types":{"stub":{"network":100,"headroom":120}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadCapacityProfiles(path); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestReplicationHeadroomProfiles(t *testing.T) {
+	c := NewLimitsConfig{
+		Minimum:            10,
+		SourceMaximum:      80,
+		DestinationMaximum: 60,
+		CapacityMap: map[string]float64{
+			"other": 100,
+		},
+		Profiles: CapacityProfiles{
+			InstanceTypes: map[string]CapacityProfile{
+				"stub": {Network: 100, Headroom: 50},
+			},
+			Brokers: map[int]CapacityProfile{
+				1002: {Network: 200, Headroom: 25},
+				1003: {Network: 200},
+			},
+		},
+	}
+
+	l, _ := NewLimits(c)
+
+	expected := []struct {
+		id           int
+		instanceType string
+		headroom     float64
+	}{
+		// The instance type profile.
+		{1001, "stub", 15},
+		// The broker profile takes precedence.
+		{1002, "stub", 32.5},
+		// The instance type headroom applies to broker profiles without one.
+		{1003, "stub", 65},
+		// Instance types without a profile use the source maximum.
+		{1004, "other", 24},
+	}
+
+	for n, e := range expected {
+		b := &kafkametrics.Broker{ID: e.id, InstanceType: e.instanceType, NetTX: 70}
+
+		h, err := l.replicationHeadroom(b, "leader", 0)
+		if err != nil {
+			t.Errorf("[test index %d] Unexpected error: %s\n", n, err)
+		}
+		if h != e.headroom {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, e.headroom, h)
+		}
+	}
+}
+
+func TestReplicationHeadroomMap(t *testing.T) {
+	c := NewLimitsConfig{
+		Minimum:            1,
+		SourceMaximum:      80,
+		DestinationMaximum: 60,
+		CapacityMap: map[string]float64{
+			"burstable": 100,
+			"other":     100,
+		},
+		HeadroomMap: map[string]float64{
+			"burstable": 40,
+			"stub":      20,
+		},
+		Profiles: CapacityProfiles{
+			InstanceTypes: map[string]CapacityProfile{
+				"stub": {Network: 100, Headroom: 50},
+			},
+			Brokers: map[int]CapacityProfile{
+				1002: {Network: 100, Headroom: 25},
+			},
+		},
+	}
+
+	l, err := NewLimits(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		id           int
+		instanceType string
+		headroom     float64
+	}{
+		{1001, "burstable", 12},
+		// The headroom map takes precedence over instance type profiles.
+		{1003, "stub", 6},
+		// Broker profiles take precedence over the headroom map.
+		{1002, "stub", 7.5},
+		// Instance types without a headroom use the source maximum.
+		{1004, "other", 24},
+	}
+
+	for n, e := range expected {
+		b := &kafkametrics.Broker{ID: e.id, InstanceType: e.instanceType, NetTX: 70}
+
+		h, err := l.replicationHeadroom(b, "leader", 0)
+		if err != nil {
+			t.Errorf("[test index %d] Unexpected error: %s\n", n, err)
+		}
+		if h != e.headroom {
+			t.Errorf("[test index %d] Expected headroom value of %f, got %f\n", n, e.headroom, h)
+		}
+	}
+
+	c.HeadroomMap["stub"] = 100 // Invalid.
+	if _, err := NewLimits(c); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
@@ -0,0 +1,171 @@
+package replication
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// clientQuotaRates are the produce and fetch quota rates in MB/s applied to
+// each managed client.
+type clientQuotaRates struct {
+	produce float64
+	fetch   float64
+}
+
+// UpdateClientQuotas sets the produce and fetch quotas of the managed
+// clients according to the network utilization and capacity of all brokers.
+// Since quotas are enforced by each broker, the managed clients share the
+// clientQuotaMaxRate percentage of the available capacity of the most
+// utilized broker, each receiving no less than the client quota minimum rate.
+func (tm *ThrottleManager) UpdateClientQuotas() error {
+	if tm.clientQuotaMaxRate <= 0 {
+		return nil
+	}
+
+	bm, errs := tm.km.GetMetrics()
+	if len(bm) == 0 {
+		return fmt.Errorf("Error fetching metrics for client quotas: %s", errs)
+	}
+
+	rates := tm.clientQuotaRates(bm)
+
+	if prev := tm.clientQuotas; prev != nil {
+		produceChange := math.Abs((prev.produce - rates.produce) / prev.produce * 100)
+		fetchChange := math.Abs((prev.fetch - rates.fetch) / prev.fetch * 100)
+
+		if produceChange < tm.changeThreshold && fetchChange < tm.changeThreshold {
+			logging.Info("Proposed client quotas are below the change threshold, skipping client quota update",
+				logging.Fields{"produce_change": produceChange, "fetch_change": fetchChange, "change_threshold": tm.changeThreshold})
+			return nil
+		}
+	}
+
+	return tm.applyClientQuotas(rates)
+}
+
+// clientQuotaRates returns the produce and fetch quota rates of each managed
+// client. Brokers with an unknown capacity are skipped; if no broker
+// capacity is known, the client quota minimum rate is used.
+func (tm *ThrottleManager) clientQuotaRates(bm kafkametrics.BrokerMetrics) clientQuotaRates {
+	n := float64(len(tm.clientQuotaClients))
+
+	var prev clientQuotaRates
+	if tm.clientQuotas != nil {
+		prev = *tm.clientQuotas
+	}
+
+	var ids []int
+	for id := range bm {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// The lowest total produce and fetch allowance of any broker.
+	produce, fetch := math.Inf(1), math.Inf(1)
+
+	for _, id := range ids {
+		// The previously set quotas are assumed to be consumed in full.
+		p, err := tm.limits.clientQuotaHeadroom(bm[id], "produce", tm.clientQuotaMaxRate, prev.produce*n)
+		if err != nil {
+			logging.Warn("Error calculating client quota headroom, skipping broker",
+				logging.Fields{"broker_id": id, "error": err})
+			continue
+		}
+		f, _ := tm.limits.clientQuotaHeadroom(bm[id], "fetch", tm.clientQuotaMaxRate, prev.fetch*n)
+
+		produce, fetch = math.Min(produce, p), math.Min(fetch, f)
+	}
+
+	rates := clientQuotaRates{
+		produce: tm.boundClientQuota(produce / n),
+		fetch:   tm.boundClientQuota(fetch / n),
+	}
+
+	logging.Info("Client quota rates",
+		logging.Fields{"max_utilization": tm.clientQuotaMaxRate, "produce_rate": rates.produce, "fetch_rate": rates.fetch})
+
+	return rates
+}
+
+// boundClientQuota limits a client quota rate in MB/s to the client quota
+// minimum rate. Rates that aren't a number or are unbounded, such as where
+// no broker capacity is known, are set to the minimum rate. The replication
+// throttle minimum and maximum rates don't apply to client quotas.
+func (tm *ThrottleManager) boundClientQuota(rate float64) float64 {
+	if math.IsNaN(rate) || math.IsInf(rate, 1) || rate < tm.clientQuotaMinRate {
+		return tm.clientQuotaMinRate
+	}
+
+	return rate
+}
+
+// applyClientQuotas sets the produce and fetch quotas of the managed clients,
+// storing the applied rates and writing an event.
+func (tm *ThrottleManager) applyClientQuotas(rates clientQuotaRates) error {
+	quotas := kafkaadmin.ClientQuotas{}
+	for _, client := range tm.clientQuotaClients {
+		quotas[kafkaadmin.QuotaEntity{Type: kafkaadmin.QuotaEntityClientID, Name: client}] = map[string]float64{
+			kafkaadmin.ProducerByteRate: math.Round(rates.produce * 1000000.00),
+			kafkaadmin.ConsumerByteRate: math.Round(rates.fetch * 1000000.00),
+		}
+	}
+
+	fields := logging.Fields{"clients": tm.clientQuotaClients, "produce_rate": rates.produce, "fetch_rate": rates.fetch}
+
+	if tm.dryRun {
+		logging.Info("[dry-run] Would update client quotas", fields)
+	} else {
+		ctx, cancel := tm.kafkaRequestContext()
+		err := tm.ka.SetClientQuotas(ctx, quotas)
+		cancel()
+
+		if err != nil {
+			return fmt.Errorf("Error setting client quotas: %s", err)
+		}
+
+		// Dry-run rates aren't in effect and aren't stored.
+		logging.Info("Updated client quotas", fields)
+		tm.clientQuotas = &rates
+	}
+
+	m := fmt.Sprintf("Client quotas set for clients %s: produce %0.2fMB/s, fetch %0.2fMB/s",
+		strings.Join(tm.clientQuotaClients, ", "), rates.produce, rates.fetch)
+	tm.events.Write("Client quotas set", m)
+
+	return nil
+}
+
+// removeClientQuotas removes the produce and fetch quotas of the managed
+// clients if previously set, writing an event.
+func (tm *ThrottleManager) removeClientQuotas() error {
+	if tm.clientQuotas == nil {
+		return nil
+	}
+
+	var entities []kafkaadmin.QuotaEntity
+	for _, client := range tm.clientQuotaClients {
+		entities = append(entities, kafkaadmin.QuotaEntity{Type: kafkaadmin.QuotaEntityClientID, Name: client})
+	}
+
+	ctx, cancel := tm.kafkaRequestContext()
+	defer cancel()
+
+	keys := []string{kafkaadmin.ProducerByteRate, kafkaadmin.ConsumerByteRate}
+	if err := tm.ka.RemoveClientQuotas(ctx, entities, keys); err != nil {
+		return fmt.Errorf("Error removing client quotas: %s", err)
+	}
+
+	logging.Info("Client quotas removed", logging.Fields{"clients": tm.clientQuotaClients})
+	tm.clientQuotas = nil
+
+	m := fmt.Sprintf("Client quotas removed for clients %s", strings.Join(tm.clientQuotaClients, ", "))
+	tm.events.Write("Client quotas removed", m)
+
+	return nil
+}
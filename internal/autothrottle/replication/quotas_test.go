@@ -0,0 +1,141 @@
+package replication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin/stub"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// quotasStub is a kafkaadmin.KafkaAdmin storing the client quotas set.
+type quotasStub struct {
+	stub.Client
+	quotas kafkaadmin.ClientQuotas
+}
+
+func (s *quotasStub) SetClientQuotas(_ context.Context, q kafkaadmin.ClientQuotas) error {
+	for e, values := range q {
+		s.quotas[e] = values
+	}
+	return nil
+}
+
+func (s *quotasStub) RemoveClientQuotas(_ context.Context, entities []kafkaadmin.QuotaEntity, _ []string) error {
+	for _, e := range entities {
+		delete(s.quotas, e)
+	}
+	return nil
+}
+
+func TestUpdateClientQuotas(t *testing.T) {
+	events := eventsStub{}
+	km := &metricsStub{bm: kafkametrics.BrokerMetrics{
+		1001: {ID: 1001, InstanceType: "a", NetRX: 40, NetTX: 100},
+		1002: {ID: 1002, InstanceType: "a", NetRX: 120, NetTX: 60},
+		// Unknown capacity.
+		1003: {ID: 1003, InstanceType: "b", NetRX: 190, NetTX: 190},
+	}}
+
+	// The replication throttle rate ceiling doesn't apply to client quotas.
+	tm, err := NewThrottleManager(ThrottleManagerConfig{
+		Limits:             Limits{"minimum": 10, "maximum": 15, "a": 200},
+		ChangeThreshold:    10,
+		KafkaNativeMode:    true,
+		KafkaMetrics:       km,
+		Events:             events,
+		ClientQuotaClients: []string{"client_a", "client_b"},
+		ClientQuotaMaxRate: 50,
+		ClientQuotaMinRate: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ka := &quotasStub{Client: stub.NewClient(), quotas: kafkaadmin.ClientQuotas{}}
+	tm.ka = ka
+
+	if err := tm.UpdateClientQuotas(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The allowances of the most utilized broker, broker 1002 for produce
+	// and broker 1001 for fetch, are shared by the clients.
+	expected := map[string]float64{kafkaadmin.ProducerByteRate: 20000000, kafkaadmin.ConsumerByteRate: 25000000}
+	for _, client := range []string{"client_a", "client_b"} {
+		q := ka.quotas[kafkaadmin.QuotaEntity{Type: kafkaadmin.QuotaEntityClientID, Name: client}]
+		for k, v := range expected {
+			if q[k] != v {
+				t.Errorf("Expected %s %s %.0f, got %.0f\n", client, k, v, q[k])
+			}
+		}
+	}
+
+	if m := events["Client quotas set"]; m != "Client quotas set for clients client_a, client_b: produce 20.00MB/s, fetch 25.00MB/s" {
+		t.Errorf("Unexpected event %q\n", m)
+	}
+
+	// The previously set quotas are subtracted from the utilization.
+	rates := tm.clientQuotaRates(km.bm)
+	if rates.produce != 30 || rates.fetch != 37.5 {
+		t.Errorf("Expected produce rate 30MB/s and fetch rate 37.5MB/s, got %+v\n", rates)
+	}
+
+	// Rates within the change threshold aren't updated.
+	km.bm[1001].NetRX, km.bm[1002].NetRX = 160, 160
+	km.bm[1001].NetTX, km.bm[1002].NetTX = 150, 150
+	delete(events, "Client quotas set")
+
+	if err := tm.UpdateClientQuotas(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := events["Client quotas set"]; exists {
+		t.Errorf("Expected no client quota updates, got %v\n", events)
+	}
+
+	if err := tm.removeClientQuotas(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ka.quotas) != 0 || tm.clientQuotas != nil {
+		t.Errorf("Expected no client quotas, got %v\n", ka.quotas)
+	}
+}
+
+func TestClientQuotaRatesMinimum(t *testing.T) {
+	// The client quota minimum is used in place of the replication
+	// throttle minimum.
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits:             Limits{"minimum": 10, "a": 200},
+		KafkaNativeMode:    true,
+		ClientQuotaClients: []string{"client_a"},
+		ClientQuotaMaxRate: 50,
+		ClientQuotaMinRate: 5,
+	})
+
+	// No broker capacities are known.
+	rates := tm.clientQuotaRates(kafkametrics.BrokerMetrics{1001: {ID: 1001, InstanceType: "b"}})
+	if rates.produce != 5 || rates.fetch != 5 {
+		t.Errorf("Expected the minimum rate, got %+v\n", rates)
+	}
+
+	// Rates don't fall below the minimum.
+	rates = tm.clientQuotaRates(kafkametrics.BrokerMetrics{1001: {ID: 1001, InstanceType: "a", NetRX: 195, NetTX: 250}})
+	if rates.produce != 5 || rates.fetch != 5 {
+		t.Errorf("Expected the minimum rate, got %+v\n", rates)
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{ClientQuotaMaxRate: 50, KafkaNativeMode: true}); err == nil {
+		t.Error("Expected error")
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{ClientQuotaMaxRate: 50, ClientQuotaClients: []string{"client_a"}}); err == nil {
+		t.Error("Expected error")
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{ClientQuotaMaxRate: 50, ClientQuotaClients: []string{"client_a"}, KafkaNativeMode: true}); err == nil {
+		t.Error("Expected error")
+	}
+}
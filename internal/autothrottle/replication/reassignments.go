@@ -0,0 +1,41 @@
+package replication
+
+import (
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+// ListReassignments returns all ongoing reassignments using the Kafka
+// ListPartitionReassignments API rather than ZooKeeper. Kafka native mode
+// is required.
+func (tm *ThrottleManager) ListReassignments() (kafkazk.Reassignments, error) {
+	ctx, cancel := tm.kafkaRequestContext()
+	defer cancel()
+
+	pr, err := tm.ka.ListPartitionReassignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reassignmentsFromPartitionReassignments(pr), nil
+}
+
+// reassignmentsFromPartitionReassignments takes a
+// kafkaadmin.PartitionReassignments and returns a kafkazk.Reassignments of
+// the target replica sets.
+func reassignmentsFromPartitionReassignments(pr kafkaadmin.PartitionReassignments) kafkazk.Reassignments {
+	reassignments := kafkazk.Reassignments{}
+
+	for topic, partitions := range pr {
+		reassignments[topic] = map[int][]int{}
+		for id, p := range partitions {
+			var replicas []int
+			for _, r := range p.TargetReplicas() {
+				replicas = append(replicas, int(r))
+			}
+			reassignments[topic][int(id)] = replicas
+		}
+	}
+
+	return reassignments
+}
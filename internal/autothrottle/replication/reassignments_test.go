@@ -0,0 +1,62 @@
+package replication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin/stub"
+)
+
+// reassignmentsStub is a kafkaadmin.KafkaAdmin returning fixed partition
+// reassignments.
+type reassignmentsStub struct {
+	stub.Client
+	reassignments kafkaadmin.PartitionReassignments
+}
+
+func (s reassignmentsStub) ListPartitionReassignments(context.Context) (kafkaadmin.PartitionReassignments, error) {
+	return s.reassignments, nil
+}
+
+func TestListReassignments(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{KafkaNativeMode: true})
+	tm.ka = reassignmentsStub{
+		Client: stub.NewClient(),
+		reassignments: kafkaadmin.PartitionReassignments{
+			"test_topic": {
+				0: {
+					Replicas:         []int32{1001, 1003, 1002},
+					AddingReplicas:   []int32{1001},
+					RemovingReplicas: []int32{1002},
+				},
+				1: {
+					Replicas:       []int32{1002, 1003},
+					AddingReplicas: []int32{1003},
+				},
+			},
+		},
+	}
+
+	r, err := tm.ListReassignments()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[int][]int{
+		0: {1001, 1003},
+		1: {1002, 1003},
+	}
+
+	for p, replicas := range expected {
+		got := r["test_topic"][p]
+		if len(got) != len(replicas) {
+			t.Fatalf("[partition %d] Expected replicas %v, got %v\n", p, replicas, got)
+		}
+		for i := range replicas {
+			if got[i] != replicas[i] {
+				t.Errorf("[partition %d] Expected replicas %v, got %v\n", p, replicas, got)
+			}
+		}
+	}
+}
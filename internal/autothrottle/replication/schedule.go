@@ -0,0 +1,222 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ThrottleSchedule defines throttle rate ceilings for recurring time windows,
+// e.g. higher ceilings overnight and conservative ones during business hours.
+type ThrottleSchedule struct {
+	// Timezone is the IANA time zone the windows are in. Defaults to UTC.
+	Timezone string           `json:"timezone" yaml:"timezone"`
+	Windows  []ScheduleWindow `json:"windows" yaml:"windows"`
+
+	location *time.Location
+}
+
+// ScheduleWindow is a daily time window with a throttle rate ceiling.
+type ScheduleWindow struct {
+	Name string `json:"name" yaml:"name"`
+	// Days are the days of the week the window starts on (sun, mon, ...).
+	// The window recurs daily if unset.
+	Days []string `json:"days" yaml:"days"`
+	// Start and End are times of day in the 15:04 format. Windows with an
+	// End at or before the Start span midnight.
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+	// MaxRate is the maximum throttle rate in MB/s during the window.
+	MaxRate float64 `json:"max_rate" yaml:"max_rate"`
+
+	days       map[time.Weekday]bool
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// LoadThrottleSchedule reads a ThrottleSchedule from the file at path. Files
+// with a .json extension are parsed as JSON, all others as YAML.
+func LoadThrottleSchedule(path string) (*ThrottleSchedule, error) {
+	s := &ThrottleSchedule{}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(b, s)
+	default:
+		err = yaml.Unmarshal(b, s)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing throttle schedule %s: %s", path, err)
+	}
+
+	if err := s.parse(); err != nil {
+		return nil, fmt.Errorf("throttle schedule %s: %s", path, err)
+	}
+
+	return s, nil
+}
+
+// parse validates the schedule and parses the timezone, days and times of
+// each window.
+func (s *ThrottleSchedule) parse() error {
+	s.location = time.UTC
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %s", err)
+		}
+		s.location = loc
+	}
+
+	for i := range s.Windows {
+		w := &s.Windows[i]
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("window %d", i)
+		}
+
+		if w.MaxRate <= 0 {
+			return fmt.Errorf("%s: max_rate must be > 0", w.Name)
+		}
+
+		var err error
+		if w.start, err = minuteOfDay(w.Start); err != nil {
+			return fmt.Errorf("%s: invalid start: %s", w.Name, err)
+		}
+		if w.end, err = minuteOfDay(w.End); err != nil {
+			return fmt.Errorf("%s: invalid end: %s", w.Name, err)
+		}
+
+		if len(w.Days) == 0 {
+			continue
+		}
+
+		w.days = map[time.Weekday]bool{}
+		for _, d := range w.Days {
+			wd, exists := weekdays[strings.ToLower(d)]
+			if !exists {
+				return fmt.Errorf("%s: invalid day %s", w.Name, d)
+			}
+			w.days[wd] = true
+		}
+	}
+
+	return nil
+}
+
+// minuteOfDay takes a 15:04 format time of day and returns the minutes
+// since midnight.
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// activeOn returns whether the window recurs on the weekday.
+func (w ScheduleWindow) activeOn(d time.Weekday) bool {
+	return w.days == nil || w.days[d]
+}
+
+// active returns whether the window is active at the time t.
+func (w ScheduleWindow) active(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	today, yesterday := t.Weekday(), t.AddDate(0, 0, -1).Weekday()
+
+	if w.start < w.end {
+		return w.activeOn(today) && m >= w.start && m < w.end
+	}
+
+	// The window spans midnight.
+	return (w.activeOn(today) && m >= w.start) || (w.activeOn(yesterday) && m < w.end)
+}
+
+// Ceiling returns the throttle rate ceiling in MB/s at the time t and the
+// name of the window it's from. Where several windows are active, the
+// lowest ceiling applies. If no windows are active, false is returned.
+func (s *ThrottleSchedule) Ceiling(t time.Time) (float64, string, bool) {
+	if s == nil {
+		return 0, "", false
+	}
+
+	loc := s.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	var ceiling float64
+	var name string
+	var found bool
+
+	for _, w := range s.Windows {
+		if !w.active(t) {
+			continue
+		}
+		if !found || w.MaxRate < ceiling {
+			ceiling, name, found = w.MaxRate, w.Name, true
+		}
+	}
+
+	return ceiling, name, found
+}
+
+// applyScheduleCeiling limits the leader and follower throttles of each
+// broker to the throttle schedule ceiling at the time t, if any. An event is
+// written whenever the active schedule window changes.
+func (tm *ThrottleManager) applyScheduleCeiling(capacities ReplicationCapacityByBroker, t time.Time) {
+	ceiling, window, active := tm.schedule.Ceiling(t)
+
+	if window != tm.scheduleWindow {
+		var m string
+		switch {
+		case active:
+			m = fmt.Sprintf("Throttle schedule window %s is active, limiting throttles to %.2fMB/s", window, ceiling)
+			logging.Info("Throttle schedule window active", logging.Fields{"window": window, "rate": ceiling})
+		default:
+			m = fmt.Sprintf("Throttle schedule window %s ended, throttles are no longer limited", tm.scheduleWindow)
+			logging.Info("Throttle schedule window ended", logging.Fields{"window": tm.scheduleWindow})
+		}
+		tm.events.Write("Throttle schedule window changed", m)
+		tm.scheduleWindow = window
+	}
+
+	if !active {
+		return
+	}
+
+	for id, rates := range capacities {
+		for i, rate := range rates {
+			if rate == nil || *rate <= ceiling {
+				continue
+			}
+			r := math.Min(*rate, ceiling)
+			rates[i] = &r
+		}
+		capacities[id] = rates
+	}
+}
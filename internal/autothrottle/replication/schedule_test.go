@@ -0,0 +1,166 @@
+package replication
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testSchedule(t *testing.T) *ThrottleSchedule {
+	s := &ThrottleSchedule{
+		Timezone: "UTC",
+		Windows: []ScheduleWindow{
+			{Name: "business", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00", MaxRate: 50},
+			{Name: "lunch", Days: []string{"Fri"}, Start: "12:00", End: "13:00", MaxRate: 20},
+			{Name: "overnight", Start: "22:00", End: "06:00", MaxRate: 300},
+		},
+	}
+
+	if err := s.parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestScheduleCeiling(t *testing.T) {
+	s := testSchedule(t)
+
+	// 2026-10-12 is a Monday.
+	tests := []struct {
+		at      string
+		ceiling float64
+		window  string
+	}{
+		{"2026-10-12T08:59:00Z", 0, ""},
+		{"2026-10-12T09:00:00Z", 50, "business"},
+		{"2026-10-12T17:59:00Z", 50, "business"},
+		{"2026-10-12T18:00:00Z", 0, ""},
+		// The lowest ceiling of overlapping windows applies.
+		{"2026-10-16T12:30:00Z", 20, "lunch"},
+		// Weekends.
+		{"2026-10-17T12:30:00Z", 0, ""},
+		// Windows spanning midnight.
+		{"2026-10-12T23:00:00Z", 300, "overnight"},
+		{"2026-10-13T05:59:00Z", 300, "overnight"},
+		{"2026-10-13T06:00:00Z", 0, ""},
+	}
+
+	for _, test := range tests {
+		at, _ := time.Parse(time.RFC3339, test.at)
+		ceiling, window, active := s.Ceiling(at)
+
+		if active != (test.window != "") || ceiling != test.ceiling || window != test.window {
+			t.Errorf("[%s] Expected ceiling %.2f from %q, got %.2f from %q\n", test.at, test.ceiling, test.window, ceiling, window)
+		}
+	}
+
+	// A nil schedule has no ceiling.
+	var ns *ThrottleSchedule
+	if _, _, active := ns.Ceiling(time.Now()); active {
+		t.Error("Unexpected active window")
+	}
+}
+
+func TestScheduleSpanningMidnightDays(t *testing.T) {
+	s := &ThrottleSchedule{
+		Windows: []ScheduleWindow{
+			{Days: []string{"fri"}, Start: "20:00", End: "08:00", MaxRate: 400},
+		},
+	}
+
+	if err := s.parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The window started Friday continues into Saturday morning only.
+	for at, expected := range map[string]bool{
+		"2026-10-16T21:00:00Z": true,
+		"2026-10-17T07:00:00Z": true,
+		"2026-10-17T21:00:00Z": false,
+		"2026-10-16T07:00:00Z": false,
+	} {
+		tm, _ := time.Parse(time.RFC3339, at)
+		if _, _, active := s.Ceiling(tm); active != expected {
+			t.Errorf("[%s] Expected active %t, got %t\n", at, expected, active)
+		}
+	}
+}
+
+func TestLoadThrottleSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	data := `{"timezone":"America/New_York","windows":[{"name":"peak","start":"09:00","end":"17:00","max_rate":40}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadThrottleSchedule(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 14:00 UTC is 10:00 in New York.
+	at, _ := time.Parse(time.RFC3339, "2026-10-12T14:00:00Z")
+	if c, _, _ := s.Ceiling(at); c != 40 {
+		t.Errorf("Expected ceiling 40.00, got %.2f\n", c)
+	}
+
+	// Invalid schedules.
+	for _, data := range []string{
+		`{"windows":[{"start":"09:00","end":"17:00"}]}`,
+		`{"windows":[{"start":"9am","end":"17:00","max_rate":40}]}`,
+		`{"windows":[{"days":["someday"],"start":"09:00","end":"17:00","max_rate":40}]}`,
+		`{"timezone":"Nowhere/Invalid","windows":[]}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadThrottleSchedule(path); err == nil {
+			t.Errorf("Expected error for schedule %s\n", data)
+		}
+	}
+}
+
+func TestApplyScheduleCeiling(t *testing.T) {
+	events := eventsStub{}
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits:   Limits{"minimum": 10},
+		Events:   events,
+		Schedule: testSchedule(t),
+	})
+
+	capacities := ReplicationCapacityByBroker{
+		1000: {float64ptr(100), nil},
+		1001: {nil, float64ptr(30)},
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2026-10-12T10:00:00Z")
+	tm.applyScheduleCeiling(capacities, at)
+
+	if r := *capacities[1000][0]; r != 50 {
+		t.Errorf("Expected rate 50.00, got %.2f\n", r)
+	}
+
+	if r := *capacities[1001][1]; r != 30 {
+		t.Errorf("Expected rate 30.00, got %.2f\n", r)
+	}
+
+	if capacities[1000][1] != nil {
+		t.Error("Expected nil rate")
+	}
+
+	if _, exists := events["Throttle schedule window changed"]; !exists {
+		t.Error("Expected schedule window event")
+	}
+
+	// Rates are unchanged outside of windows.
+	capacities[1000] = ThrottleByRole{float64ptr(100), nil}
+	at, _ = time.Parse(time.RFC3339, "2026-10-12T20:00:00Z")
+	tm.applyScheduleCeiling(capacities, at)
+
+	if r := *capacities[1000][0]; r != 100 {
+		t.Errorf("Expected rate 100.00, got %.2f\n", r)
+	}
+}
@@ -0,0 +1,133 @@
+package replication
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+)
+
+// ShutdownPolicy determines how throttles are handled when autothrottle
+// shuts down.
+type ShutdownPolicy int
+
+const (
+	// ShutdownFreeze leaves all throttles at their current values.
+	ShutdownFreeze ShutdownPolicy = iota
+	// ShutdownRemove removes all replication and log dir throttles and any
+	// client quotas set.
+	ShutdownRemove
+	// ShutdownFixedRate applies the shutdown rate to all reassigning brokers.
+	ShutdownFixedRate
+)
+
+// ParseShutdownPolicy returns the ShutdownPolicy for the name "freeze",
+// "remove" or "fixed-rate".
+func ParseShutdownPolicy(s string) (ShutdownPolicy, error) {
+	switch s {
+	case "freeze":
+		return ShutdownFreeze, nil
+	case "remove":
+		return ShutdownRemove, nil
+	case "fixed-rate":
+		return ShutdownFixedRate, nil
+	default:
+		return 0, fmt.Errorf("unknown shutdown policy: %s", s)
+	}
+}
+
+// String returns the ShutdownPolicy name.
+func (p ShutdownPolicy) String() string {
+	switch p {
+	case ShutdownRemove:
+		return "remove"
+	case ShutdownFixedRate:
+		return "fixed-rate"
+	default:
+		return "freeze"
+	}
+}
+
+// shutdownRate returns the throttle rate applied with the ShutdownFixedRate
+// policy. The minimum rate is used if no shutdown rate is configured.
+func (tm *ThrottleManager) shutdownRate() float64 {
+	if tm.shutdownFixedRate > 0 {
+		return tm.shutdownFixedRate
+	}
+
+	return tm.limits["minimum"]
+}
+
+// Shutdown applies the shutdown policy to the throttles. It's called once
+// when autothrottle stops managing the cluster.
+func (tm *ThrottleManager) Shutdown() error {
+	switch tm.shutdownPolicy {
+	case ShutdownRemove:
+		logging.Info("Removing all throttles on shutdown", logging.Fields{"shutdown_policy": tm.shutdownPolicy.String()})
+
+		if _, err := tm.RemoveAllThrottles(); err != nil {
+			return err
+		}
+
+		var ids []int
+		for id := range tm.logDirThrottles {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		if err := tm.removeLogDirThrottles(ids); err != nil {
+			return err
+		}
+
+		return tm.removeClientQuotas()
+	case ShutdownFixedRate:
+		return tm.applyShutdownRate()
+	}
+
+	logging.Info("Retaining throttles on shutdown", logging.Fields{"shutdown_policy": tm.shutdownPolicy.String()})
+
+	return nil
+}
+
+// applyShutdownRate applies the shutdown rate to the throttles of all
+// reassigning brokers, writing an event.
+func (tm *ThrottleManager) applyShutdownRate() error {
+	if len(tm.reassigningBrokers.all) == 0 {
+		logging.Info("No reassigning brokers, skipping the shutdown rate", nil)
+		return nil
+	}
+
+	rate := tm.shutdownRate()
+	logging.Info("Applying the shutdown rate to reassigning brokers",
+		logging.Fields{"shutdown_policy": tm.shutdownPolicy.String(), "rate": rate})
+
+	capacities := make(ReplicationCapacityByBroker)
+	capacities.setRoleRatesWithDefault(tm.reassigningBrokers, rate)
+
+	prev := tm.previouslySetThrottles.copy()
+	events, errs := tm.applyBrokerThrottles(tm.reassigningBrokers.all, capacities)
+	events = tm.auditChanges(events, prev, auditReasonShutdown, nil)
+
+	var changes []string
+	for e := range events {
+		changes = append(changes, fmt.Sprintf("[%d, %s, %.2f]", e.id, e.role, e.rate))
+	}
+	sort.Strings(changes)
+
+	if len(changes) > 0 {
+		m := fmt.Sprintf("Replication throttles set to the shutdown rate for brokers [ID, role, rate]: %s",
+			strings.Join(changes, ", "))
+		tm.events.Write("Shutdown throttle set", m)
+	}
+
+	if errs != nil {
+		var errStrings []string
+		for _, e := range errs {
+			errStrings = append(errStrings, e.Error())
+		}
+		return fmt.Errorf("Error applying the shutdown rate: %s", strings.Join(errStrings, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package replication
+
+import (
+	"testing"
+)
+
+func TestParseShutdownPolicy(t *testing.T) {
+	expected := map[string]ShutdownPolicy{
+		"freeze":     ShutdownFreeze,
+		"remove":     ShutdownRemove,
+		"fixed-rate": ShutdownFixedRate,
+	}
+
+	for s, p := range expected {
+		got, err := ParseShutdownPolicy(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("Expected policy %s, got %s\n", p, got)
+		}
+		if got.String() != s {
+			t.Errorf("Expected policy name %s, got %s\n", s, got)
+		}
+	}
+
+	if _, err := ParseShutdownPolicy("invalid"); err == nil {
+		t.Error("Expected error")
+	}
+
+	if _, err := NewThrottleManager(ThrottleManagerConfig{ShutdownRate: -1}); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestShutdownFixedRate(t *testing.T) {
+	events := eventsStub{}
+
+	// Dry-run mode, since no ZooKeeper or Kafka clients are set.
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits:         Limits{"minimum": 10},
+		Events:         events,
+		DryRun:         true,
+		ShutdownPolicy: ShutdownFixedRate,
+	})
+
+	// Without reassigning brokers, nothing is applied.
+	if err := tm.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %v\n", events)
+	}
+
+	tm.SetReassigningBrokers(reassigningBrokers{
+		src: map[int]struct{}{1001: {}},
+		dst: map[int]struct{}{1002: {}},
+		all: map[int]struct{}{1001: {}, 1002: {}},
+	})
+
+	if err := tm.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The minimum rate is used without a shutdown rate.
+	expected := "Replication throttles set to the shutdown rate for brokers [ID, role, rate]: [1001, leader, 10.00], [1002, follower, 10.00]"
+	if m := events["[dry-run] Shutdown throttle set"]; m != expected {
+		t.Errorf("Expected event %q, got %q\n", expected, m)
+	}
+
+	tm.shutdownFixedRate = 25
+	if r := tm.shutdownRate(); r != 25 {
+		t.Errorf("Expected shutdown rate 25, got %f\n", r)
+	}
+}
+
+func TestShutdownFreeze(t *testing.T) {
+	events := eventsStub{}
+
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits: Limits{"minimum": 10},
+		Events: events,
+	})
+
+	tm.SetReassigningBrokers(reassigningBrokers{
+		src: map[int]struct{}{1001: {}},
+		all: map[int]struct{}{1001: {}},
+	})
+
+	// No ZooKeeper or Kafka clients are set; any config writes would panic.
+	if err := tm.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %v\n", events)
+	}
+}
@@ -0,0 +1,245 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// MetricsSnapshot is a BrokerMetrics recorded at a point in time, along with
+// the source and destination brokers of the reassignments ongoing at the
+// time. Snapshots are stored as JSON lines.
+type MetricsSnapshot struct {
+	Time time.Time
+	// Sources and Destinations are the IDs of the brokers participating in
+	// reassignments. If both are empty, all brokers in the BrokerMetrics are
+	// treated as sources and destinations.
+	Sources      []int `json:",omitempty"`
+	Destinations []int `json:",omitempty"`
+	Brokers      kafkametrics.BrokerMetrics
+}
+
+// SimulatedThrottle is a throttle decision made for a MetricsSnapshot.
+type SimulatedThrottle struct {
+	Time     time.Time
+	BrokerID int
+	Role     string
+	// Rate is the calculated throttle rate in MB/s.
+	Rate float64
+	// Previous is the previously applied throttle rate of the broker and
+	// role, or 0 if none.
+	Previous float64
+	// Source is what the rate is based on, e.g. "metrics" or "min_rate".
+	Source string
+	// Applied is whether the rate would have been applied, as opposed to
+	// skipped for not exceeding the change threshold or minimum change.
+	Applied bool
+}
+
+// LoadMetricsSnapshots reads the MetricsSnapshots of a JSON lines file,
+// returning them in time order.
+func LoadMetricsSnapshots(path string) ([]MetricsSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readMetricsSnapshots(f)
+}
+
+// readMetricsSnapshots reads JSON lines MetricsSnapshots, returning them in
+// time order. Blank lines are skipped.
+func readMetricsSnapshots(r io.Reader) ([]MetricsSnapshot, error) {
+	var snapshots []MetricsSnapshot
+
+	s := bufio.NewScanner(r)
+	// Snapshots of large clusters exceed the default max line length.
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for n := 1; s.Scan(); n++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+
+		var snap MetricsSnapshot
+		if err := json.Unmarshal(s.Bytes(), &snap); err != nil {
+			return nil, fmt.Errorf("error parsing snapshot on line %d: %s", n, err)
+		}
+
+		snapshots = append(snapshots, snap)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+
+	return snapshots, nil
+}
+
+// recordSnapshot writes the BrokerMetrics as a MetricsSnapshot JSON line to
+// the metrics recorder, if configured.
+func (tm *ThrottleManager) recordSnapshot(bm kafkametrics.BrokerMetrics, src, dst []int, t time.Time) {
+	if tm.recorder == nil || len(bm) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(MetricsSnapshot{
+		Time:         t.UTC(),
+		Sources:      src,
+		Destinations: dst,
+		Brokers:      bm,
+	})
+	if err != nil {
+		logging.Error("Error recording metrics snapshot", logging.Fields{"error": err})
+		return
+	}
+
+	if _, err := tm.recorder.Write(append(b, '\n')); err != nil {
+		logging.Error("Error recording metrics snapshot", logging.Fields{"error": err})
+	}
+}
+
+// snapshotBrokers returns the reassigningBrokers of a MetricsSnapshot.
+func snapshotBrokers(snap MetricsSnapshot) reassigningBrokers {
+	rb := reassigningBrokers{
+		src:   map[int]struct{}{},
+		dst:   map[int]struct{}{},
+		all:   map[int]struct{}{},
+		pairs: map[int]map[int]struct{}{},
+	}
+
+	src, dst := snap.Sources, snap.Destinations
+	if len(src) == 0 && len(dst) == 0 {
+		for id := range snap.Brokers {
+			src = append(src, id)
+			dst = append(dst, id)
+		}
+	}
+
+	for _, id := range src {
+		rb.src[id] = struct{}{}
+		rb.all[id] = struct{}{}
+	}
+
+	for _, id := range dst {
+		rb.dst[id] = struct{}{}
+		rb.all[id] = struct{}{}
+	}
+
+	// Without partition assignments, each source is assumed to replicate to
+	// each destination.
+	for s := range rb.src {
+		rb.pairs[s] = map[int]struct{}{}
+		for d := range rb.dst {
+			if d != s {
+				rb.pairs[s][d] = struct{}{}
+			}
+		}
+	}
+
+	return rb
+}
+
+// Simulate replays the MetricsSnapshots through the throttle calculation,
+// returning the throttle decisions that would have been made for each. No
+// broker or topic configs are written; rates that exceed the change
+// thresholds are stored as previously set throttles as if applied.
+// Snapshots missing metrics for reassigning brokers count as metrics fetch
+// failures, applying the failure rate once over the failure threshold with
+// the FailureFixedRate policy and otherwise retaining the previous throttles.
+// Consumer lag isn't replayed.
+func (tm *ThrottleManager) Simulate(snapshots []MetricsSnapshot) []SimulatedThrottle {
+	var decisions []SimulatedThrottle
+
+	for _, snap := range snapshots {
+		rb := snapshotBrokers(snap)
+		tm.reassigningBrokers = rb
+		source := throttleSourceMetrics
+
+		tm.smoothUtilization(snap.Brokers)
+
+		capacities, err := brokerReplicationCapacities(tm, rb, snap.Brokers)
+		if err != nil {
+			logging.Warn("Error calculating throttles for snapshot",
+				logging.Fields{"time": snap.Time, "error": err})
+
+			if !tm.Failure() || tm.failurePolicy != FailureFixedRate {
+				continue
+			}
+
+			capacities = make(ReplicationCapacityByBroker)
+			capacities.setRoleRatesWithDefault(rb, tm.failureRate())
+			source = throttleSourceMinRate
+		} else {
+			tm.ResetFailures()
+
+			if tm.destinationAware {
+				capacities.limitToPeerAllowances(rb)
+			}
+
+			tm.applyRampUp(capacities)
+		}
+
+		tm.applyScheduleCeiling(capacities, snap.Time)
+		tm.applyRateBounds(capacities)
+
+		var ids []int
+		for id := range capacities {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			for i, rate := range capacities[id] {
+				if rate == nil {
+					continue
+				}
+
+				d := SimulatedThrottle{
+					Time:     snap.Time,
+					BrokerID: id,
+					Role:     roleFromIndex(i),
+					Rate:     *rate,
+					Source:   source,
+				}
+
+				if prev := tm.previouslySetThrottles[id][i]; prev != nil {
+					d.Previous = *prev
+				}
+
+				if d.Applied = tm.exceedsChange(id, d.Role, d.Previous, d.Rate); d.Applied {
+					switch i {
+					case 0:
+						tm.previouslySetThrottles.storeLeaderCapacity(id, d.Rate)
+					case 1:
+						tm.previouslySetThrottles.storeFollowerCapacity(id, d.Rate)
+					}
+				}
+
+				decisions = append(decisions, d)
+			}
+		}
+
+		// Track the throttled brokers for ramp-ups.
+		if tm.throttledBrokers == nil {
+			tm.throttledBrokers = make(map[int]struct{})
+		}
+		for id := range rb.all {
+			tm.throttledBrokers[id] = struct{}{}
+		}
+	}
+
+	return decisions
+}
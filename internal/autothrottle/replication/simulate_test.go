@@ -0,0 +1,106 @@
+package replication
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestReadMetricsSnapshots(t *testing.T) {
+	in := `{"Time":"2026-01-01T00:01:00Z","Brokers":{"1001":{"ID":1001,"InstanceType":"a","NetTX":40}}}
+
+{"Time":"2026-01-01T00:00:00Z","Sources":[1001],"Destinations":[1002],"Brokers":{"1001":{"ID":1001,"InstanceType":"a","NetTX":20}}}
+`
+
+	snapshots, err := readMetricsSnapshots(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d\n", len(snapshots))
+	}
+
+	// Snapshots are sorted by time.
+	if snapshots[0].Brokers[1001].NetTX != 20 || snapshots[0].Destinations[0] != 1002 {
+		t.Errorf("Unexpected first snapshot %+v\n", snapshots[0])
+	}
+
+	if _, err := readMetricsSnapshots(strings.NewReader("{")); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestRecordSnapshot(t *testing.T) {
+	var b bytes.Buffer
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{MetricsRecorder: &b})
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	bm := kafkametrics.BrokerMetrics{1001: {ID: 1001, NetTX: 20}}
+	tm.recordSnapshot(bm, []int{1001}, []int{1002}, now)
+	// Empty metrics aren't recorded.
+	tm.recordSnapshot(nil, nil, nil, now)
+
+	snapshots, err := readMetricsSnapshots(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 || !snapshots[0].Time.Equal(now) || snapshots[0].Brokers[1001].NetTX != 20 {
+		t.Errorf("Unexpected snapshots %+v\n", snapshots)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{
+		Limits:          Limits{"minimum": 10, "srcMax": 90, "dstMax": 90, "a": 200},
+		ChangeThreshold: 10,
+		Events:          eventsStub{},
+	})
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := func(m int, tx float64) MetricsSnapshot {
+		return MetricsSnapshot{
+			Time:         start.Add(time.Duration(m) * time.Minute),
+			Sources:      []int{1001},
+			Destinations: []int{1002},
+			Brokers: kafkametrics.BrokerMetrics{
+				1001: {ID: 1001, InstanceType: "a", NetTX: tx},
+				1002: {ID: 1002, InstanceType: "a", NetRX: 20},
+			},
+		}
+	}
+
+	decisions := tm.Simulate([]MetricsSnapshot{
+		snapshot(0, 20),
+		// The previous throttle is assumed to be consumed in full; the
+		// leader throttle is unchanged.
+		snapshot(1, 182),
+		// Missing broker metrics.
+		{Time: start.Add(2 * time.Minute), Sources: []int{1001}, Destinations: []int{1002}, Brokers: kafkametrics.BrokerMetrics{}},
+	})
+
+	// Leader and follower decisions for the first two snapshots, followed
+	// by failure rate decisions once over the failure threshold of 0.
+	if len(decisions) != 6 {
+		t.Fatalf("Expected 6 decisions, got %d: %+v\n", len(decisions), decisions)
+	}
+
+	first := decisions[0]
+	if first.BrokerID != 1001 || first.Role != "leader" || first.Rate != 162 || !first.Applied || first.Source != throttleSourceMetrics {
+		t.Errorf("Unexpected decision %+v\n", first)
+	}
+
+	second := decisions[2]
+	if second.Rate != 162 || second.Previous != 162 || second.Applied {
+		t.Errorf("Unexpected decision %+v\n", second)
+	}
+
+	failure := decisions[4]
+	if failure.Rate != 10 || failure.Source != throttleSourceMinRate || !failure.Applied {
+		t.Errorf("Unexpected decision %+v\n", failure)
+	}
+}
@@ -0,0 +1,218 @@
+package replication
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Throttle input sources.
+const (
+	throttleSourceMetrics        = "metrics"
+	throttleSourceGlobalOverride = "global_override"
+	throttleSourceBrokerOverride = "broker_override"
+	throttleSourceMinRate        = "min_rate"
+	throttleSourceConsumerLag    = "consumer_lag"
+)
+
+// throttleStatus records the inputs of the most recently computed throttles
+// and the time of the last throttle adjustment. It's shared with the admin
+// API and must be locked.
+type throttleStatus struct {
+	sync.Mutex
+	lastAdjustment time.Time
+	inputs         map[int]api.ThrottleInputs
+}
+
+// recordInputs merges in the throttle inputs by broker ID.
+func (s *throttleStatus) recordInputs(inputs map[int]api.ThrottleInputs) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.inputs == nil {
+		s.inputs = make(map[int]api.ThrottleInputs)
+	}
+
+	for id, in := range inputs {
+		s.inputs[id] = in
+	}
+}
+
+// recordAdjustment records a throttle adjustment at the time t. The inputs
+// of any brokers whose throttles were removed are cleared.
+func (s *throttleStatus) recordAdjustment(t time.Time, removed map[int]struct{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastAdjustment = t
+	for id := range removed {
+		delete(s.inputs, id)
+	}
+}
+
+// reset records the removal of all throttles at the time t, clearing all
+// inputs.
+func (s *throttleStatus) reset(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastAdjustment = t
+	s.inputs = nil
+}
+
+// throttleInputs returns the api.ThrottleInputs for each broker in the
+// ReplicationCapacityByBroker. Brokers with a broker override use the
+// throttleSourceBrokerOverride source, and brokers missing from the broker
+// metrics when throttles are computed from metrics use the
+// throttleSourceMinRate source.
+func (tm *ThrottleManager) throttleInputs(source string, capacities ReplicationCapacityByBroker, bm kafkametrics.BrokerMetrics, overrides map[int]struct{}, now time.Time) map[int]api.ThrottleInputs {
+	inputs := make(map[int]api.ThrottleInputs)
+
+	for id, rates := range capacities {
+		prev := tm.previouslySetThrottles[id]
+
+		in := api.ThrottleInputs{
+			Source:               source,
+			PreviousLeaderRate:   prev[0],
+			PreviousFollowerRate: prev[1],
+			LeaderRate:           rates[0],
+			FollowerRate:         rates[1],
+			Time:                 now,
+		}
+
+		if b, exists := bm[id]; exists {
+			in.InstanceType = b.InstanceType
+			in.NetTX = b.NetTX
+			in.NetRX = b.NetRX
+			in.CPU = b.CPU
+			in.Capacity, _ = tm.limits.capacity(b)
+		} else if source == throttleSourceMetrics {
+			in.Source = throttleSourceMinRate
+		}
+
+		if _, exists := overrides[id]; exists {
+			in.Source = throttleSourceBrokerOverride
+		}
+
+		inputs[id] = in
+	}
+
+	return inputs
+}
+
+// ThrottleStatus returns the live throttle rates of all brokers, read back
+// from the broker configs, along with the inputs of the most recently
+// computed throttles and the time of the last throttle adjustment.
+func (tm *ThrottleManager) ThrottleStatus() (api.ThrottleStatus, error) {
+	live, err := tm.liveThrottles()
+	if err != nil {
+		return api.ThrottleStatus{}, err
+	}
+
+	s := api.ThrottleStatus{Brokers: make(map[int]api.BrokerThrottleStatus)}
+
+	for id, rates := range live {
+		s.Brokers[id] = api.BrokerThrottleStatus{
+			LeaderRate:   rates[0],
+			FollowerRate: rates[1],
+		}
+	}
+
+	tm.status.Lock()
+	defer tm.status.Unlock()
+
+	if !tm.status.lastAdjustment.IsZero() {
+		t := tm.status.lastAdjustment
+		s.LastAdjustment = &t
+	}
+
+	for id, in := range tm.status.inputs {
+		in := in
+		b := s.Brokers[id]
+		b.Inputs = &in
+		s.Brokers[id] = b
+	}
+
+	return s, nil
+}
+
+// liveThrottles returns the throttle rates of all brokers as read from the
+// broker configs.
+func (tm *ThrottleManager) liveThrottles() (ReplicationCapacityByBroker, error) {
+	brokers, errs := tm.zk.GetAllBrokerMeta(false)
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	live := make(ReplicationCapacityByBroker)
+
+	// ZooKeeper method.
+	if !tm.kafkaNativeMode {
+		for id := range brokers {
+			c, err := tm.zk.GetBrokerConfig(id)
+			if err != nil {
+				return nil, err
+			}
+			live.storeConfigRates(id, c.Config)
+		}
+
+		return live, nil
+	}
+
+	var names []string
+	for id := range brokers {
+		names = append(names, strconv.Itoa(id))
+	}
+
+	if len(names) == 0 {
+		return live, nil
+	}
+
+	ctx, cancel := tm.kafkaRequestContext()
+	defer cancel()
+
+	configs, err := tm.ka.GetDynamicConfigs(ctx, "broker", names)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, c := range configs {
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		live.storeConfigRates(id, c)
+	}
+
+	return live, nil
+}
+
+// storeConfigRates stores the leader and follower throttle rates of a
+// broker from its configs, converted from bytes to MB/s. Unset rates are
+// stored as nil.
+func (r ReplicationCapacityByBroker) storeConfigRates(id int, configs map[string]string) {
+	r[id] = ThrottleByRole{}
+
+	for i, role := range []string{"leader", "follower"} {
+		v, exists := configs[role+".replication.throttled.rate"]
+		if !exists {
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			continue
+		}
+		rate /= 1000000.00
+
+		switch i {
+		case 0:
+			r.storeLeaderCapacity(id, rate)
+		case 1:
+			r.storeFollowerCapacity(id, rate)
+		}
+	}
+}
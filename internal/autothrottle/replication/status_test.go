@@ -0,0 +1,98 @@
+package replication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkazk"
+)
+
+func TestThrottleStatus(t *testing.T) {
+	lim, _ := NewLimits(NewLimitsConfig{
+		Minimum:            20,
+		SourceMaximum:      90,
+		DestinationMaximum: 80,
+		CapacityMap:        map[string]float64{"stub": 200.00},
+	})
+
+	tm := &ThrottleManager{
+		zk:                     kafkazk.NewZooKeeperStub(),
+		limits:                 lim,
+		previouslySetThrottles: ReplicationCapacityByBroker{1001: ThrottleByRole{float64ptr(20)}},
+	}
+
+	s, err := tm.ThrottleStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.LastAdjustment != nil {
+		t.Errorf("Expected nil last adjustment, got %v\n", s.LastAdjustment)
+	}
+
+	// Live rates are read from the stub broker configs.
+	b, exists := s.Brokers[1001]
+	if !exists {
+		t.Fatal("Expected broker 1001 status")
+	}
+
+	if *b.LeaderRate != 100 || *b.FollowerRate != 50 || b.Inputs != nil {
+		t.Errorf("Unexpected broker status %+v\n", b)
+	}
+
+	// Record inputs and an adjustment.
+	capacities := ReplicationCapacityByBroker{}
+	capacities.storeLeaderCapacity(1001, 180)
+	capacities.storeFollowerCapacity(1002, 20)
+
+	bm := kafkametrics.BrokerMetrics{
+		1001: &kafkametrics.Broker{ID: 1001, InstanceType: "stub", NetTX: 20},
+	}
+
+	now := time.Now()
+	tm.status.recordInputs(tm.throttleInputs(throttleSourceMetrics, capacities, bm, nil, now))
+	tm.status.recordAdjustment(now, nil)
+
+	s, _ = tm.ThrottleStatus()
+	if s.LastAdjustment == nil || !s.LastAdjustment.Equal(now) {
+		t.Errorf("Expected last adjustment %s, got %v\n", now, s.LastAdjustment)
+	}
+
+	in := s.Brokers[1001].Inputs
+	switch {
+	case in == nil:
+		t.Fatal("Expected broker 1001 inputs")
+	case in.Source != throttleSourceMetrics, in.Capacity != 200, in.NetTX != 20:
+		t.Errorf("Unexpected broker 1001 inputs %+v\n", in)
+	case *in.PreviousLeaderRate != 20, *in.LeaderRate != 180, in.FollowerRate != nil:
+		t.Errorf("Unexpected broker 1001 rates %+v\n", in)
+	}
+
+	// Brokers missing metrics receive the min rate.
+	if in := s.Brokers[1002].Inputs; in == nil || in.Source != throttleSourceMinRate {
+		t.Errorf("Expected broker 1002 min rate inputs, got %+v\n", in)
+	}
+
+	// Removals clear inputs.
+	tm.status.recordAdjustment(now, map[int]struct{}{1001: {}})
+	if s, _ = tm.ThrottleStatus(); s.Brokers[1001].Inputs != nil {
+		t.Error("Expected broker 1001 inputs to be cleared")
+	}
+}
+
+func TestStoreConfigRates(t *testing.T) {
+	r := ReplicationCapacityByBroker{}
+	r.storeConfigRates(1001, map[string]string{
+		"leader.replication.throttled.rate":   "25000000",
+		"follower.replication.throttled.rate": "invalid",
+	})
+
+	if r[1001][0] == nil || *r[1001][0] != 25 {
+		t.Errorf("Expected leader rate 25.00, got %v\n", r[1001][0])
+	}
+
+	if r[1001][1] != nil {
+		t.Errorf("Expected nil follower rate, got %v\n", *r[1001][1])
+	}
+}
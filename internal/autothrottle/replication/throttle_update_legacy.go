@@ -4,13 +4,13 @@ package replication
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 )
 
@@ -36,8 +36,6 @@ func (tm *ThrottleManager) legacyApplyBrokerThrottles(configs map[int]kafkazk.Ka
 				// Split on ".", get "leader" or "follower" string.
 				role := strings.Split(throttleConfigString, ".")[0]
 
-				log.Printf("Updated throttle on broker %d [%s]\n", ID, role)
-
 				var rate *float64
 
 				// Store the configured rate.
@@ -50,6 +48,8 @@ func (tm *ThrottleManager) legacyApplyBrokerThrottles(configs map[int]kafkazk.Ka
 					tm.previouslySetThrottles.storeFollowerCapacity(ID, *rate)
 				}
 
+				logging.Info("Updated throttle", logging.Fields{"broker_id": ID, "role": role, "rate": *rate})
+
 				events <- brokerChangeEvent{
 					id:   ID,
 					role: role,
@@ -106,12 +106,7 @@ func (tm *ThrottleManager) legacyApplyTopicThrottles(throttled TopicThrottledRep
 	return nil
 }
 
-func (tm *ThrottleManager) legacyRemoveTopicThrottles() error {
-	topics, err := tm.zk.GetTopics(topicsRegex)
-	if err != nil {
-		return err
-	}
-
+func (tm *ThrottleManager) legacyRemoveTopicThrottles(topics []string) error {
 	var errTopics []string
 
 	for _, topic := range topics {
@@ -167,12 +162,12 @@ func (tm *ThrottleManager) legacyRemoveBrokerThrottlesByID(ids map[int]struct{})
 			// don't exist, there's not even config to remove.
 		default:
 			errorEncountered = true
-			log.Printf("Error removing throttle on broker %d: %s\n", b, err)
+			logging.Error("Error removing throttle", logging.Fields{"broker_id": b, "error": err})
 		}
 
 		if changed[0] || changed[1] {
 			unthrottledBrokers = append(unthrottledBrokers, b)
-			log.Printf("Throttle removed on broker %d\n", b)
+			logging.Info("Throttle removed", logging.Fields{"broker_id": b})
 
 			// Unset the previously stored throttle rate.
 			tm.previouslySetThrottles[b] = [2]*float64{}
@@ -2,6 +2,8 @@ package replication
 
 import (
 	"context"
+	"errors"
+	"io"
 	"time"
 
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
@@ -20,6 +22,12 @@ type ThrottleManager struct {
 	kafkaNativeMode        bool
 	kafkaAPIRequestTimeout int
 	changeThreshold        float64
+	minChange              float64
+	maxStep                float64
+	smoothingFactor        float64
+	destinationAware       bool
+	dryRun                 bool
+	utilization            utilizationAverages
 	// The following three fields are for brokers with static overrides set
 	// and a TopicThrottledReplicas for topics where those brokers are assigned.
 	brokerOverrides          throttlestore.BrokerOverrides
@@ -27,11 +35,55 @@ type ThrottleManager struct {
 	skipOverrideTopicUpdates bool
 	reassigningBrokers       reassigningBrokers
 	events                   EventWriter
+	metrics                  MetricsWriter
 	previouslySetThrottles   ReplicationCapacityByBroker
 	limits                   Limits
 	failureThreshold         int
 	failures                 int
 	skipTopicUpdates         bool
+	tolerateMissingMetrics   bool
+	status                   throttleStatus
+	// Brokers throttled for reassignments since throttles were last removed.
+	throttledBrokers map[int]struct{}
+	// Failure policy params. failureRemoved is set once throttles are
+	// removed with the FailureRemove policy until the failures are reset.
+	failurePolicy            FailurePolicy
+	failureFixedRate         float64
+	criticalFailureThreshold int
+	failureRemoved           bool
+	// Shutdown policy params.
+	shutdownPolicy    ShutdownPolicy
+	shutdownFixedRate float64
+	// Consumer lag backoff params. lagging is set while the lag of any
+	// consumer group exceeds the lagThreshold.
+	consumerLag  kafkametrics.LagHandler
+	lagThreshold float64
+	lagBackoff   float64
+	lagging      bool
+	// Ramp-up params. rampSteps holds the number of intervals each broker
+	// has been ramped up for.
+	rampIntervals int
+	rampStart     float64
+	rampSteps     map[int]int
+	// Log dir throttle params. logDirThrottles holds the previously set log
+	// dir throttle rates by broker ID.
+	logDirMaxRate   float64
+	logDirThrottles map[int]float64
+	// The throttle schedule and the name of its active window, if any.
+	schedule       *ThrottleSchedule
+	scheduleWindow string
+	// Client quota params. clientQuotas holds the previously set quota rates
+	// of the clients, if any.
+	clientQuotaClients []string
+	clientQuotaMaxRate float64
+	clientQuotaMinRate float64
+	clientQuotas       *clientQuotaRates
+	// Optional writer of MetricsSnapshots of the fetched broker metrics.
+	recorder io.Writer
+	// Optional writer of throttle change AuditRecords.
+	audit AuditWriter
+	// The znode path of broker throttle overrides.
+	overrideRateZnodePath string
 }
 
 // ThrottleManagerConfig configures a ThrottleManager.
@@ -44,6 +96,77 @@ type ThrottleManagerConfig struct {
 	KafkaNativeMode        bool
 	KafkaAPIRequestTimeout int
 	Events                 EventWriter
+	Metrics                MetricsWriter
+	// TolerateMissingMetrics applies the minimum rate to brokers missing from
+	// the fetched metrics rather than reverting all brokers to it.
+	TolerateMissingMetrics bool
+	// MinChange is the minimum change in MB/s of a throttle rate required to
+	// trigger an update, in addition to the ChangeThreshold.
+	MinChange float64
+	// MaxStep is the maximum change of a metrics based throttle rate per
+	// interval as a percentage of the previously set rate. 0 disables.
+	MaxStep float64
+	// SmoothingFactor is the weight (0-1) of the most recent broker network
+	// utilization in an exponentially weighted moving average used in place
+	// of the raw utilization. 0 disables.
+	SmoothingFactor float64
+	// DestinationAware limits throttle rates to the allowances of the brokers
+	// on the other end of their replication.
+	DestinationAware bool
+	// DryRun computes and logs throttles without writing any broker or topic
+	// configs. Event titles are prefixed with "[dry-run]".
+	DryRun bool
+	// FailurePolicy determines how throttles are handled once the
+	// FailureThreshold is exceeded.
+	FailurePolicy FailurePolicy
+	// FailureRate is the throttle rate applied with the FailureFixedRate
+	// policy. 0 uses the minimum rate.
+	FailureRate float64
+	// CriticalFailureThreshold is the number of consecutive metrics fetch
+	// failures at which a critical event is written. 0 disables.
+	CriticalFailureThreshold int
+	// ShutdownPolicy determines how throttles are handled on Shutdown.
+	ShutdownPolicy ShutdownPolicy
+	// ShutdownRate is the throttle rate applied with the ShutdownFixedRate
+	// policy. 0 uses the minimum rate.
+	ShutdownRate float64
+	// ConsumerLag fetches consumer group lag. Optional; if set, throttles are
+	// reduced by the LagBackoff percentage while the lag of any group
+	// exceeds the LagThreshold.
+	ConsumerLag  kafkametrics.LagHandler
+	LagThreshold float64
+	LagBackoff   float64
+	// RampUpIntervals is the number of intervals over which the throttles of
+	// brokers newly throttled for a reassignment are ramped up to the
+	// calculated rate, starting at the RampUpStart percentage. 0 disables.
+	RampUpIntervals int
+	RampUpStart     float64
+	// LogDirMaxRate is the max throttle rate of replica moves between broker
+	// log dirs as a percentage of available disk IO capacity. 0 disables log
+	// dir throttles.
+	LogDirMaxRate float64
+	// Schedule optionally limits throttle rates to the ceiling of its active
+	// window. Throttle overrides aren't limited.
+	Schedule *ThrottleSchedule
+	// ClientQuotaClients are the client IDs whose produce and fetch quotas
+	// are managed, sharing the ClientQuotaMaxRate percentage of available
+	// network capacity. A ClientQuotaMaxRate of 0 disables client quotas.
+	// Requires KafkaNativeMode. The quotas of each client are no lower than
+	// the ClientQuotaMinRate in MB/s; the replication throttle Limits
+	// don't apply.
+	ClientQuotaClients []string
+	ClientQuotaMaxRate float64
+	ClientQuotaMinRate float64
+	// MetricsRecorder optionally records the broker metrics fetched for
+	// reassignment throttles as MetricsSnapshot JSON lines, which can be
+	// replayed with Simulate.
+	MetricsRecorder io.Writer
+	// Audit optionally writes an AuditRecord for each replication throttle
+	// change.
+	Audit AuditWriter
+	// OverrideRateZnodePath is the znode path under which broker throttle
+	// overrides are stored, as returned by api.OverrideRateZnodePath.
+	OverrideRateZnodePath string
 }
 
 // EventWriter for writing event key values.
@@ -51,19 +174,87 @@ type EventWriter interface {
 	Write(string, string)
 }
 
+// MetricsWriter for writing throttle metrics. Optional.
+type MetricsWriter interface {
+	Gauge(name string, value float64, tags []string) error
+}
+
 // NewThrottleManager takes a ThrottleManagerConfig and returns a
 // *ThrottleManager.
 func NewThrottleManager(cfg ThrottleManagerConfig) (*ThrottleManager, error) {
+	switch {
+	case cfg.MinChange < 0:
+		return nil, errors.New("min change must be >= 0")
+	case cfg.MaxStep < 0:
+		return nil, errors.New("max step must be >= 0")
+	case cfg.SmoothingFactor < 0 || cfg.SmoothingFactor > 1:
+		return nil, errors.New("smoothing factor must be >= 0 and <= 1")
+	case cfg.FailureRate < 0:
+		return nil, errors.New("failure rate must be >= 0")
+	case cfg.ShutdownRate < 0:
+		return nil, errors.New("shutdown rate must be >= 0")
+	case cfg.CriticalFailureThreshold < 0:
+		return nil, errors.New("critical failure threshold must be >= 0")
+	case cfg.RampUpIntervals < 0:
+		return nil, errors.New("ramp up intervals must be >= 0")
+	case cfg.RampUpIntervals > 0 && (cfg.RampUpStart <= 0 || cfg.RampUpStart > 100):
+		return nil, errors.New("ramp up start must be > 0 and <= 100")
+	case cfg.LogDirMaxRate < 0 || cfg.LogDirMaxRate >= 100:
+		return nil, errors.New("log dir max rate must be >= 0 and < 100")
+	case cfg.LagThreshold < 0:
+		return nil, errors.New("lag threshold must be >= 0")
+	case cfg.LagBackoff < 0 || cfg.LagBackoff >= 100:
+		return nil, errors.New("lag backoff must be >= 0 and < 100")
+	case cfg.ClientQuotaMaxRate < 0 || cfg.ClientQuotaMaxRate >= 100:
+		return nil, errors.New("client quota max rate must be >= 0 and < 100")
+	case cfg.ClientQuotaMaxRate > 0 && len(cfg.ClientQuotaClients) == 0:
+		return nil, errors.New("client quota max rate requires client quota clients")
+	case cfg.ClientQuotaMaxRate > 0 && !cfg.KafkaNativeMode:
+		return nil, errors.New("client quotas require kafka native mode")
+	case cfg.ClientQuotaMaxRate > 0 && cfg.ClientQuotaMinRate <= 0:
+		return nil, errors.New("client quota min rate must be > 0")
+	}
+
+	events := cfg.Events
+	if cfg.DryRun {
+		events = dryRunEvents{events}
+	}
+
 	return &ThrottleManager{
-		limits:                 cfg.Limits,
-		failureThreshold:       cfg.FailureThreshold,
-		changeThreshold:        cfg.ChangeThreshold,
-		zk:                     cfg.KafkaZK,
-		km:                     cfg.KafkaMetrics,
-		kafkaNativeMode:        cfg.KafkaNativeMode,
-		kafkaAPIRequestTimeout: cfg.KafkaAPIRequestTimeout,
-		events:                 cfg.Events,
-		previouslySetThrottles: make(ReplicationCapacityByBroker),
+		limits:                   cfg.Limits,
+		failureThreshold:         cfg.FailureThreshold,
+		changeThreshold:          cfg.ChangeThreshold,
+		minChange:                cfg.MinChange,
+		maxStep:                  cfg.MaxStep,
+		smoothingFactor:          cfg.SmoothingFactor,
+		destinationAware:         cfg.DestinationAware,
+		dryRun:                   cfg.DryRun,
+		zk:                       cfg.KafkaZK,
+		km:                       cfg.KafkaMetrics,
+		kafkaNativeMode:          cfg.KafkaNativeMode,
+		kafkaAPIRequestTimeout:   cfg.KafkaAPIRequestTimeout,
+		events:                   events,
+		metrics:                  cfg.Metrics,
+		tolerateMissingMetrics:   cfg.TolerateMissingMetrics,
+		previouslySetThrottles:   make(ReplicationCapacityByBroker),
+		failurePolicy:            cfg.FailurePolicy,
+		failureFixedRate:         cfg.FailureRate,
+		criticalFailureThreshold: cfg.CriticalFailureThreshold,
+		shutdownPolicy:           cfg.ShutdownPolicy,
+		shutdownFixedRate:        cfg.ShutdownRate,
+		consumerLag:              cfg.ConsumerLag,
+		lagThreshold:             cfg.LagThreshold,
+		lagBackoff:               cfg.LagBackoff,
+		schedule:                 cfg.Schedule,
+		rampIntervals:            cfg.RampUpIntervals,
+		rampStart:                cfg.RampUpStart,
+		logDirMaxRate:            cfg.LogDirMaxRate,
+		clientQuotaClients:       cfg.ClientQuotaClients,
+		clientQuotaMaxRate:       cfg.ClientQuotaMaxRate,
+		clientQuotaMinRate:       cfg.ClientQuotaMinRate,
+		recorder:                 cfg.MetricsRecorder,
+		audit:                    cfg.Audit,
+		overrideRateZnodePath:    cfg.OverrideRateZnodePath,
 	}, nil
 }
 
@@ -102,6 +293,16 @@ func (tm *ThrottleManager) SetReassigningBrokers(rb reassigningBrokers) {
 	tm.reassigningBrokers = rb
 }
 
+// SetLimits sets the ThrottleManager limits.
+func (tm *ThrottleManager) SetLimits(l Limits) {
+	tm.limits = l
+}
+
+// SetSchedule sets the ThrottleManager schedule.
+func (tm *ThrottleManager) SetSchedule(s *ThrottleSchedule) {
+	tm.schedule = s
+}
+
 // SetOverrideRate sets the ThrottleManager overrideRate.
 func (tm *ThrottleManager) SetOverrideRate(r int) {
 	tm.overrideRate = r
@@ -117,6 +318,12 @@ func (tm *ThrottleManager) GetBrokerOverrides() throttlestore.BrokerOverrides {
 	return tm.brokerOverrides
 }
 
+// ThrottledBrokerCount returns the number of brokers throttled for
+// reassignments since throttles were last removed.
+func (tm *ThrottleManager) ThrottledBrokerCount() int {
+	return len(tm.throttledBrokers)
+}
+
 // GetOverrideThrottleLists returns the ThrottleManager overrideThrottleLists.
 func (tm *ThrottleManager) GetOverrideThrottleLists() TopicThrottledReplicas {
 	return tm.overrideThrottleLists
@@ -135,6 +342,7 @@ func (tm *ThrottleManager) ResetPreviousThrottles() {
 // ResetFailures resets the failures count.
 func (tm *ThrottleManager) ResetFailures() {
 	tm.failures = 0
+	tm.failureRemoved = false
 }
 
 // DisableTopicUpdates prevents topic throttled replica lists from being
@@ -174,9 +382,10 @@ func HasActiveOverride(bto throttlestore.BrokerThrottleOverride) bool {
 	return bto.Config.Rate != 0
 }
 
-// NotReassignmentParticipant filter func.
+// NotReassignmentParticipant filter func. Ceiling overrides only limit the
+// throttles of reassignment participants and are excluded.
 func NotReassignmentParticipant(bto throttlestore.BrokerThrottleOverride) bool {
-	return !bto.ReassignmentParticipant && bto.Config.Rate != 0
+	return !bto.ReassignmentParticipant && bto.Config.Rate != 0 && !bto.Config.Ceiling
 }
 
 // ThrottledBrokers is a list of brokers with a throttle applied
@@ -3,13 +3,13 @@ package replication
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"math"
+	"sort"
 	"strconv"
-	"strings"
+	"time"
 
-	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/api"
 	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
+	"github.com/DataDog/kafka-kit/v4/internal/logging"
 	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
@@ -40,8 +40,7 @@ func (tm *ThrottleManager) UpdateReplicationThrottle() error {
 	// Creates lists from maps.
 	srcBrokers, dstBrokers, allBrokers := tm.reassigningBrokers.lists()
 
-	log.Printf("Source brokers participating in replication: %v\n", srcBrokers)
-	log.Printf("Destination brokers participating in replication: %v\n", dstBrokers)
+	logging.Info("Brokers participating in replication", logging.Fields{"sources": srcBrokers, "destinations": dstBrokers})
 
 	// Determine throttle rates.
 
@@ -51,50 +50,77 @@ func (tm *ThrottleManager) UpdateReplicationThrottle() error {
 	var brokerMetrics kafkametrics.BrokerMetrics
 	var rateOverride bool
 	var inFailureMode bool
+	var lagBackoff bool
 	var metricErrs []error
 
 	if tm.overrideRate != 0 {
-		log.Printf("A global throttle override is set: %dMB/s\n", tm.overrideRate)
+		logging.Info("A global throttle override is set", logging.Fields{"rate": tm.overrideRate})
 		rateOverride = true
 
-		capacities.setAllRatesWithDefault(allBrokers, float64(tm.overrideRate))
+		capacities.setRoleRatesWithDefault(tm.reassigningBrokers, float64(tm.overrideRate))
 	}
 
 	if !rateOverride {
 		// Get broker metrics.
 		brokerMetrics, metricErrs = tm.km.GetMetrics()
+		tm.recordSnapshot(brokerMetrics, srcBrokers, dstBrokers, time.Now())
 		// Even if errors are returned, we can still proceed as long as we have complete
 		// metrics data for all target brokers. If we have broker metrics for all target
 		// brokers, we can ignore any errors.
+		// If missing metrics are tolerated, we can also proceed with partial
+		// metrics; brokers without metrics are set to the minimum rate.
 		if metricErrs != nil {
-			if brokerMetrics == nil || incompleteBrokerMetrics(allBrokers, brokerMetrics) {
+			switch {
+			case brokerMetrics == nil || len(brokerMetrics) == 0:
 				inFailureMode = true
+			case incompleteBrokerMetrics(allBrokers, brokerMetrics):
+				if !tm.tolerateMissingMetrics {
+					inFailureMode = true
+					break
+				}
+				logging.Warn("Partial metrics fetched, using min-rate for brokers missing metrics", logging.Fields{"errors": fmt.Sprint(metricErrs)})
+				for _, s := range kafkametrics.SkippedBrokers(metricErrs) {
+					logging.Warn("Skipped broker", logging.Fields{"host": s.Host, "broker_id": s.ID, "reason": s.Reason})
+				}
 			}
 		}
 	}
 
 	// If we cannot proceed normally due to missing/partial metrics data, check what
-	// failure iteration we're in. If we're above the threshold, revert to the minimum
-	// rate, otherwise retain the previous rate.
+	// failure iteration we're in. If we're above the threshold, apply the failure
+	// policy, otherwise retain the previous rate.
 	if inFailureMode {
-		log.Printf("Errors fetching metrics: %s\n", metricErrs)
+		logging.Error("Errors fetching metrics", logging.Fields{"errors": fmt.Sprint(metricErrs)})
 
 		// Increment and check our failure count against the configured threshold.
 		over := tm.Failure()
+		tm.checkCriticalFailures(metricErrs)
 
 		// If we're not over the threshold, return and just retain previous throttles.
 		if !over {
-			log.Printf("Metrics fetch failure count %d doesn't exeed threshold %d, retaining previous throttle\n",
-				tm.failures, tm.failureThreshold)
+			tm.writeFailureMetrics(false)
+			logging.Warn("Metrics fetch failure count doesn't exceed threshold, retaining previous throttle",
+				logging.Fields{"failures": tm.failures, "failure_threshold": tm.failureThreshold})
 			return nil
 		}
 
-		// We're over the threshold; failback to the configured minimum.
-		log.Printf("Metrics fetch failure count %d exceeds threshold %d, reverting to min-rate %.2fMB/s\n",
-			tm.failures, tm.failureThreshold, tm.limits["minimum"])
+		switch tm.failurePolicy {
+		case FailureHoldRate:
+			tm.writeFailureMetrics(true)
+			logging.Warn("Metrics fetch failure count exceeds threshold, retaining previous throttle",
+				logging.Fields{"failures": tm.failures, "failure_threshold": tm.failureThreshold})
+			return nil
+		case FailureRemove:
+			tm.writeFailureMetrics(true)
+			return tm.failureRemoveThrottles()
+		}
+
+		// We're over the threshold; failback to the configured failure rate.
+		logging.Warn("Metrics fetch failure count exceeds threshold, reverting to failure rate",
+			logging.Fields{"failures": tm.failures, "failure_threshold": tm.failureThreshold, "rate": tm.failureRate()})
 
 		// Set the failback rate.
-		capacities.setAllRatesWithDefault(allBrokers, tm.limits["minimum"])
+		capacities.setRoleRatesWithDefault(tm.reassigningBrokers, tm.failureRate())
 	}
 
 	// Reset the failure counter. We may have incremented in past iterations, but if
@@ -103,43 +129,75 @@ func (tm *ThrottleManager) UpdateReplicationThrottle() error {
 		tm.ResetFailures()
 	}
 
+	tm.writeFailureMetrics(inFailureMode)
+
 	// If there's no override set and we're not in a failure mode, apply the
 	// calculated throttles.
 	if !rateOverride && !inFailureMode {
+		tm.smoothUtilization(brokerMetrics)
+
 		var err error
 		capacities, err = brokerReplicationCapacities(tm, tm.reassigningBrokers, brokerMetrics)
 		if err != nil {
 			return err
 		}
-	}
 
-	// Merge in broker-specific overrides if they're part of the reassignment.
-	for id := range tm.reassigningBrokers.all {
-		if override, exists := tm.brokerOverrides[id]; exists {
-			// Any brokers with throttle overrides that are being issued as part of a
-			// reassignemnt should be marked as such.
-			override.ReassignmentParticipant = true
-			tm.brokerOverrides[id] = override
+		if tm.destinationAware {
+			capacities.limitToPeerAllowances(tm.reassigningBrokers)
+		}
 
-			rate := override.Config.Rate
-			// A rate of 0 means we intend to remove this throttle override. Skip.
-			if rate == 0 {
-				continue
-			}
+		tm.applyRampUp(capacities)
+		lagBackoff = tm.applyLagBackoff(capacities)
+	}
 
-			log.Printf("A broker throttle override is set for %d: %dMB/s\n", id, rate)
-			// Store the rate for both inbound and outbound traffic.
-			capacities.storeLeaderAndFollerCapacity(id, float64(rate))
-		}
+	// Limit calculated and failure rates to any throttle schedule ceiling,
+	// then to the minimum and maximum rates.
+	if !rateOverride {
+		tm.applyScheduleCeiling(capacities, time.Now())
+		tm.applyRateBounds(capacities)
+	}
+
+	// Determine what the rates are based on for the throttle status.
+	source := throttleSourceMetrics
+	switch {
+	case rateOverride:
+		source = throttleSourceGlobalOverride
+	case inFailureMode:
+		source = throttleSourceMinRate
+	case lagBackoff:
+		source = throttleSourceConsumerLag
 	}
 
+	// Merge in broker-specific overrides if they're part of the reassignment.
+	overrides := tm.mergeBrokerOverrides(capacities)
+
+	// Record the throttle inputs and write the throttle rates as metrics.
+	now := time.Now()
+	inputs := tm.throttleInputs(source, capacities, brokerMetrics, overrides, now)
+	tm.status.recordInputs(inputs)
+	tm.writeThrottleMetrics(inputs)
+
 	// Set broker throttle configs.
+	prev := tm.previouslySetThrottles.copy()
 	events, errs := tm.applyBrokerThrottles(tm.reassigningBrokers.all, capacities)
+	events = tm.auditChanges(events, prev, source, inputs)
+
+	if len(events) > 0 {
+		tm.status.recordAdjustment(now, nil)
+	}
+
+	// Track the throttled brokers for cleanup once their reassignments complete.
+	if tm.throttledBrokers == nil {
+		tm.throttledBrokers = make(map[int]struct{})
+	}
+	for id := range tm.reassigningBrokers.all {
+		tm.throttledBrokers[id] = struct{}{}
+	}
 
 	for _, e := range errs {
 		// TODO(jamie): revisit whether we should actually be returning rather than
 		// just logging errors here.
-		log.Println(e)
+		logging.Error("Error setting broker throttle", logging.Fields{"error": e})
 	}
 
 	// Append broker throttle info to event.
@@ -158,11 +216,11 @@ func (tm *ThrottleManager) UpdateReplicationThrottle() error {
 	if !tm.skipTopicUpdates {
 		errs := tm.applyTopicThrottles(tm.reassigningBrokers.throttledReplicas)
 		for _, e := range errs {
-			log.Println(e)
+			logging.Error("Error setting topic throttle", logging.Fields{"error": e})
 		}
-		if errs == nil {
+		if errs == nil && !tm.dryRun {
 			topics := tm.reassigningBrokers.throttledReplicas.topics()
-			log.Printf("updated the throttle replicas configs for topics: %v\n", topics)
+			logging.Info("Updated the throttled replicas configs", logging.Fields{"topics": topics})
 		}
 	}
 
@@ -179,6 +237,89 @@ func (tm *ThrottleManager) UpdateReplicationThrottle() error {
 	return nil
 }
 
+// applyRateBounds limits the leader and follower throttles of each broker to
+// the minimum and maximum rates, logging any rates out of bounds.
+func (tm *ThrottleManager) applyRateBounds(capacities ReplicationCapacityByBroker) {
+	for id, rates := range capacities {
+		for i, rate := range rates {
+			if rate == nil {
+				continue
+			}
+
+			r := tm.limits.bound(*rate)
+			if r == *rate {
+				continue
+			}
+
+			logging.Warn("Throttle rate out of bounds, limiting to the rate bounds", logging.Fields{
+				"broker_id": id,
+				"role":      roleFromIndex(i),
+				"rate":      *rate,
+				"min_rate":  tm.limits["minimum"],
+				"max_rate":  tm.limits["maximum"],
+				"bound":     r,
+			})
+
+			rates[i] = &r
+		}
+		capacities[id] = rates
+	}
+}
+
+// mergeBrokerOverrides merges the broker overrides of reassigning brokers
+// into the ReplicationCapacityByBroker. Fixed rate overrides replace both the
+// leader and follower throttles, while ceiling overrides limit the
+// dynamically calculated throttles of the broker, which are otherwise left
+// to float. The IDs of brokers whose throttles were set or limited by an
+// override are returned.
+func (tm *ThrottleManager) mergeBrokerOverrides(capacities ReplicationCapacityByBroker) map[int]struct{} {
+	var overrides = make(map[int]struct{})
+
+	for id := range tm.reassigningBrokers.all {
+		override, exists := tm.brokerOverrides[id]
+		if !exists {
+			continue
+		}
+
+		// Any brokers with throttle overrides that are being issued as part of a
+		// reassignemnt should be marked as such.
+		override.ReassignmentParticipant = true
+		tm.brokerOverrides[id] = override
+
+		rate := override.Config.Rate
+		// A rate of 0 means we intend to remove this throttle override. Skip.
+		if rate == 0 {
+			continue
+		}
+
+		if !override.Config.Ceiling {
+			logging.Info("Broker throttle override set", logging.Fields{"broker_id": id, "rate": rate})
+			// Store the rate for both inbound and outbound traffic.
+			capacities.storeLeaderAndFollerCapacity(id, float64(rate))
+			overrides[id] = struct{}{}
+			continue
+		}
+
+		logging.Info("Broker throttle ceiling override set", logging.Fields{"broker_id": id, "rate": rate})
+
+		rates := capacities[id]
+		for i, r := range rates {
+			if r == nil || *r <= float64(rate) {
+				continue
+			}
+			limited := float64(rate)
+			rates[i] = &limited
+			overrides[id] = struct{}{}
+		}
+
+		if _, exists := capacities[id]; exists {
+			capacities[id] = rates
+		}
+	}
+
+	return overrides
+}
+
 // UpdateOverrideThrottles applies replication throttles for any brokers
 // with overrides set.
 func (tm *ThrottleManager) UpdateOverrideThrottles() error {
@@ -192,7 +333,9 @@ func (tm *ThrottleManager) UpdateOverrideThrottles() error {
 	for _, override := range tm.brokerOverrides {
 		// ReassignmentParticipant have already had their override rate used as part
 		// of an ongoing reassignment.
-		if !override.ReassignmentParticipant {
+		// Ceiling overrides have no calculated throttles to limit outside
+		// of reassignments.
+		if !override.ReassignmentParticipant && !override.Config.Ceiling {
 			rate := float64(override.Config.Rate)
 			// Rate == 0 means the rate was removed via the API.
 			if rate == 0 {
@@ -205,27 +348,39 @@ func (tm *ThrottleManager) UpdateOverrideThrottles() error {
 	}
 
 	if len(toAssign) > 0 || len(toRemove) > 0 {
-		log.Println("Setting broker level throttle overrides")
+		logging.Info("Setting broker level throttle overrides", logging.Fields{"assign": len(toAssign), "remove": len(toRemove)})
 	} else {
 		return nil
 	}
 
+	// Record the throttle inputs and write the throttle rates as metrics.
+	now := time.Now()
+	inputs := tm.throttleInputs(throttleSourceBrokerOverride, capacities, nil, toAssign, now)
+	tm.status.recordInputs(inputs)
+	tm.writeThrottleMetrics(inputs)
+
 	// Set broker throttle configs.
+	prev := tm.previouslySetThrottles.copy()
 	events, errs := tm.applyBrokerThrottles(toAssign, capacities)
+	events = tm.auditChanges(events, prev, throttleSourceBrokerOverride, inputs)
 
 	for _, e := range errs {
-		log.Println(e)
+		logging.Error("Error setting broker throttle override", logging.Fields{"error": e})
+	}
+
+	if len(events) > 0 {
+		tm.status.recordAdjustment(now, nil)
 	}
 
 	// Set topic throttle configs.
 	if !tm.skipOverrideTopicUpdates {
 		errs := tm.applyTopicThrottles(tm.overrideThrottleLists)
 		for _, e := range errs {
-			log.Println(e)
+			logging.Error("Error setting topic throttle", logging.Fields{"error": e})
 		}
-		if errs == nil {
+		if errs == nil && !tm.dryRun {
 			topics := tm.overrideThrottleLists.topics()
-			log.Printf("updated the throttle replicas configs for topics: %v\n", topics)
+			logging.Info("Updated the throttled replicas configs", logging.Fields{"topics": topics})
 		}
 	}
 
@@ -245,7 +400,15 @@ func (tm *ThrottleManager) UpdateOverrideThrottles() error {
 	tm.events.Write("Broker level throttle override(s) configured", b.String())
 
 	// Unset the broker throttles marked for removal.
-	return tm.removeBrokerThrottlesByID(toRemove)
+	if err := tm.removeBrokerThrottlesByID(toRemove); err != nil {
+		return err
+	}
+
+	if len(toRemove) > 0 {
+		tm.status.recordAdjustment(time.Now(), toRemove)
+	}
+
+	return nil
 }
 
 // PurgeOverrideThrottles takes a *ThrottleManager and removes
@@ -265,7 +428,7 @@ func (tm *ThrottleManager) PurgeOverrideThrottles() []error {
 	var errs []error
 
 	for id := range toRemove {
-		path := fmt.Sprintf("%s/%d", api.OverrideRateZnodePath, id)
+		path := fmt.Sprintf("%s/%d", tm.overrideRateZnodePath, id)
 		if err := throttlestore.RemoveThrottleOverride(tm.zk, path); err != nil {
 			errs = append(errs, err)
 		}
@@ -315,16 +478,14 @@ func (tm *ThrottleManager) applyBrokerThrottles(bs map[int]struct{}, capacities
 				max = tm.limits["dstMax"]
 			}
 
-			log.Printf("Replication throttle rate for broker %d [%s] (based on a %.0f%% max free capacity utilization): %0.2fMB/s\n",
-				ID, role, max, *rate)
+			logging.Info("Replication throttle rate", logging.Fields{
+				"broker_id":       ID,
+				"role":            role,
+				"max_utilization": max,
+				"rate":            *rate,
+			})
 
-			// Check if the delta between the newly calculated throttle and the previous
-			// throttle exceeds the ChangeThreshold param.
-			d := math.Abs((*prevRate - *rate) / *prevRate * 100)
-			if d < tm.changeThreshold {
-				log.Printf("Proposed throttle is within %.2f%% of the previous throttle "+
-					"(below %.2f%% threshold), skipping throttle update for broker %d\n",
-					d, tm.changeThreshold, ID)
+			if !tm.exceedsChange(ID, role, *prevRate, *rate) {
 				continue
 			}
 
@@ -351,6 +512,10 @@ func (tm *ThrottleManager) applyBrokerThrottles(bs map[int]struct{}, capacities
 
 	// Write the throttle configs.
 
+	if tm.dryRun {
+		return tm.dryRunApplyBrokerThrottles(configs, capacities)
+	}
+
 	if !tm.kafkaNativeMode {
 		// Use the direct ZooKeeper config update method.
 		return tm.legacyApplyBrokerThrottles(legacyConfigs, capacities)
@@ -359,6 +524,34 @@ func (tm *ThrottleManager) applyBrokerThrottles(bs map[int]struct{}, capacities
 	return tm.applyBrokerThrottlesSequential(configs, capacities)
 }
 
+// exceedsChange returns whether the delta between the newly calculated
+// throttle and the previous throttle exceeds both the ChangeThreshold and
+// MinChange params, logging any skipped throttle updates.
+func (tm *ThrottleManager) exceedsChange(id int, role string, prevRate, rate float64) bool {
+	d := math.Abs((prevRate - rate) / prevRate * 100)
+	if d < tm.changeThreshold {
+		logging.Info("Proposed throttle is below the change threshold, skipping throttle update", logging.Fields{
+			"broker_id":        id,
+			"role":             role,
+			"change":           d,
+			"change_threshold": tm.changeThreshold,
+		})
+		return false
+	}
+
+	if diff := math.Abs(prevRate - rate); prevRate > 0 && diff < tm.minChange {
+		logging.Info("Proposed throttle is below the minimum change, skipping throttle update", logging.Fields{
+			"broker_id":  id,
+			"role":       role,
+			"change":     diff,
+			"min_change": tm.minChange,
+		})
+		return false
+	}
+
+	return true
+}
+
 // KafkaAdmin applies these sequentially under the hood, but from an API perspective
 // it's a single batch job: if one fails, a single error is returned. We break
 // these into sequential KafkaAdmin SetThrottle calls so that we can individually
@@ -392,7 +585,7 @@ func (tm *ThrottleManager) applyBrokerThrottlesSequential(configs kafkaadmin.Set
 			rate := capacities[id][0]
 			tm.previouslySetThrottles.storeLeaderCapacity(id, *rate)
 
-			log.Printf("Updated throttle on broker %d [leader]\n", id)
+			logging.Info("Updated throttle", logging.Fields{"broker_id": id, "role": "leader", "rate": *rate})
 			events <- brokerChangeEvent{
 				id:   id,
 				role: "leader",
@@ -405,7 +598,7 @@ func (tm *ThrottleManager) applyBrokerThrottlesSequential(configs kafkaadmin.Set
 			rate := capacities[id][1]
 			tm.previouslySetThrottles.storeFollowerCapacity(id, *rate)
 
-			log.Printf("Updated throttle on broker %d [follower]\n", id)
+			logging.Info("Updated throttle", logging.Fields{"broker_id": id, "role": "follower", "rate": *rate})
 			events <- brokerChangeEvent{
 				id:   id,
 				role: "follower",
@@ -425,6 +618,11 @@ func (tm *ThrottleManager) applyBrokerThrottlesSequential(configs kafkaadmin.Set
 // finishes; each time the list changes here, we probably update the config then
 // propagate a watch to all the brokers in the cluster.
 func (tm *ThrottleManager) applyTopicThrottles(throttledTopics TopicThrottledReplicas) []error {
+	if tm.dryRun {
+		logging.Info("[dry-run] Would update the throttled replicas configs", logging.Fields{"topics": throttledTopics.topics()})
+		return nil
+	}
+
 	if !tm.kafkaNativeMode {
 		// Use the direct ZooKeeper config update method.
 		return tm.legacyApplyTopicThrottles(throttledTopics)
@@ -444,43 +642,79 @@ func (tm *ThrottleManager) applyTopicThrottles(throttledTopics TopicThrottledRep
 	return nil
 }
 
-// RemoveAllThrottles calls removeTopicThrottles and removeBrokerThrottles in sequence.
-func (tm *ThrottleManager) RemoveAllThrottles() error {
-	for _, fn := range []func() error{
-		tm.removeTopicThrottles,
-		tm.removeBrokerThrottles,
-	} {
-		if err := fn(); err != nil {
-			return err
-		}
+// RemoveAllThrottles calls removeTopicThrottles and removeBrokerThrottles in
+// sequence, returning a ThrottleCleanup of the removed configs. If any brokers
+// were throttled for reassignments since throttles were last removed, an event
+// summarizing the cleanup is written.
+func (tm *ThrottleManager) RemoveAllThrottles() (ThrottleCleanup, error) {
+	var c ThrottleCleanup
+	var err error
+
+	if c.Topics, err = tm.removeTopicThrottles(); err != nil {
+		return c, err
 	}
 
-	return nil
+	if c.Brokers, err = tm.removeBrokerThrottles(); err != nil {
+		return c, err
+	}
+
+	tm.status.reset(time.Now())
+	tm.utilization = nil
+
+	if len(tm.throttledBrokers) > 0 {
+		tm.events.Write("Reassignment throttles removed", c.String())
+	}
+	tm.throttledBrokers = nil
+
+	return c, nil
 }
 
-// removeTopicThrottles removes all topic throttle configs.
-func (tm *ThrottleManager) removeTopicThrottles() error {
+// removeTopicThrottles removes all topic throttle configs, returning the
+// names of all topics.
+func (tm *ThrottleManager) removeTopicThrottles() ([]string, error) {
+	var topics []string
+
 	// ZooKeeper method.
 	if !tm.kafkaNativeMode {
-		return tm.legacyRemoveTopicThrottles()
+		var err error
+		if topics, err = tm.zk.GetTopics(topicsRegex); err != nil {
+			return nil, err
+		}
+	} else {
+		// Get all topic states.
+		ctx, cancel := tm.kafkaRequestContext()
+		defer cancel()
+
+		tstates, err := tm.ka.DescribeTopics(ctx, []string{".*"})
+		if err != nil {
+			return nil, err
+		}
+
+		// States to []string of names.
+		for name := range tstates {
+			topics = append(topics, name)
+		}
 	}
 
-	// Get all topic states.
-	ctx, cancel := tm.kafkaRequestContext()
-	defer cancel()
+	sort.Strings(topics)
 
-	tstates, err := tm.ka.DescribeTopics(ctx, []string{".*"})
-	if err != nil {
-		return err
+	return topics, tm.removeTopicThrottlesByName(topics)
+}
+
+// removeTopicThrottlesByName removes topic throttle configs for the specified
+// topics.
+func (tm *ThrottleManager) removeTopicThrottlesByName(topics []string) error {
+	if tm.dryRun {
+		logging.Info("[dry-run] Would remove the throttled replicas configs", logging.Fields{"topics": topics})
+		return nil
 	}
 
-	// States to []string of names.
-	var topics []string
-	for name := range tstates {
-		topics = append(topics, name)
+	// ZooKeeper method.
+	if !tm.kafkaNativeMode {
+		return tm.legacyRemoveTopicThrottles(topics)
 	}
 
-	ctx, cancel = tm.kafkaRequestContext()
+	ctx, cancel := tm.kafkaRequestContext()
 	defer cancel()
 
 	cfg := kafkaadmin.RemoveThrottleConfig{
@@ -496,10 +730,25 @@ func (tm *ThrottleManager) removeTopicThrottles() error {
 }
 
 // removeBrokerThrottlesByID removes broker throttle configs for the specified IDs.
+// Removals of previously set throttles are audited.
 func (tm *ThrottleManager) removeBrokerThrottlesByID(ids map[int]struct{}) error {
+	prev := tm.previouslySetThrottles.copy()
+
+	if tm.dryRun {
+		if len(ids) > 0 {
+			logging.Info("[dry-run] Would remove throttles", logging.Fields{"brokers": len(ids)})
+		}
+		tm.auditRemovals(ids, prev)
+		return nil
+	}
+
 	// ZooKeeper method.
 	if !tm.kafkaNativeMode {
-		return tm.legacyRemoveBrokerThrottlesByID(ids)
+		if err := tm.legacyRemoveBrokerThrottlesByID(ids); err != nil {
+			return err
+		}
+		tm.auditRemovals(ids, prev)
+		return nil
 	}
 
 	// Set to list.
@@ -520,19 +769,20 @@ func (tm *ThrottleManager) removeBrokerThrottlesByID(ids map[int]struct{}) error
 		return fmt.Errorf("Error removing broker throttles: %s", err)
 	}
 
-	listStr := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(brokers)), ", "), "[]")
-	log.Printf("Throttles removed on brokers: %s\n", listStr)
+	logging.Info("Throttles removed", logging.Fields{"broker_ids": brokers})
+	tm.auditRemovals(ids, prev)
 
 	return nil
 }
 
-// removeBrokerThrottles removes all broker throttle configs.
-func (tm *ThrottleManager) removeBrokerThrottles() error {
+// removeBrokerThrottles removes all broker throttle configs, returning the
+// IDs of the brokers cleared.
+func (tm *ThrottleManager) removeBrokerThrottles() ([]int, error) {
 	// Fetch brokers.
 	// TODO(jamie): Switch this to a KafkaAdmin lookup.
 	brokers, errs := tm.zk.GetAllBrokerMeta(false)
 	if errs != nil {
-		return errs[0]
+		return nil, errs[0]
 	}
 
 	var ids = make(map[int]struct{})
@@ -547,5 +797,11 @@ func (tm *ThrottleManager) removeBrokerThrottles() error {
 		ids[id] = struct{}{}
 	}
 
-	return tm.removeBrokerThrottlesByID(ids)
+	var cleared []int
+	for id := range ids {
+		cleared = append(cleared, id)
+	}
+	sort.Ints(cleared)
+
+	return cleared, tm.removeBrokerThrottlesByID(ids)
 }
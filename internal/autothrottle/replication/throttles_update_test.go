@@ -0,0 +1,111 @@
+package replication
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/internal/autothrottle/throttlestore"
+)
+
+func TestApplyRateBounds(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{Limits: Limits{"minimum": 10, "maximum": 100}})
+
+	capacities := ReplicationCapacityByBroker{
+		1000: {float64ptr(50), float64ptr(500)},
+		1001: {float64ptr(math.NaN()), nil},
+	}
+
+	tm.applyRateBounds(capacities)
+
+	// [broker ID, role index, expected rate]
+	expected := []struct {
+		id   int
+		role int
+		rate float64
+	}{
+		{1000, 0, 50},
+		{1000, 1, 100},
+		{1001, 0, 10},
+	}
+
+	for _, e := range expected {
+		if r := capacities[e.id][e.role]; r == nil || *r != e.rate {
+			t.Errorf("[broker %d] Expected %s rate %f, got %v\n", e.id, roleFromIndex(e.role), e.rate, r)
+		}
+	}
+
+	if capacities[1001][1] != nil {
+		t.Errorf("Expected nil follower rate for broker 1001\n")
+	}
+}
+
+func TestMergeBrokerOverrides(t *testing.T) {
+	tm, _ := NewThrottleManager(ThrottleManagerConfig{Limits: Limits{"minimum": 10}})
+
+	tm.SetReassigningBrokers(reassigningBrokers{
+		all: map[int]struct{}{1000: {}, 1001: {}, 1002: {}, 1003: {}},
+	})
+
+	tm.SetBrokerOverrides(throttlestore.BrokerOverrides{
+		// Fixed rate.
+		1000: {ID: 1000, Config: throttlestore.ThrottleOverrideConfig{Rate: 30}},
+		// Ceiling below the calculated rate.
+		1001: {ID: 1001, Config: throttlestore.ThrottleOverrideConfig{Rate: 20, Ceiling: true}},
+		// Ceiling above the calculated rate.
+		1002: {ID: 1002, Config: throttlestore.ThrottleOverrideConfig{Rate: 200, Ceiling: true}},
+		// Not reassigning.
+		1004: {ID: 1004, Config: throttlestore.ThrottleOverrideConfig{Rate: 20, Ceiling: true}},
+	})
+
+	capacities := ReplicationCapacityByBroker{
+		1000: {float64ptr(100), nil},
+		1001: {float64ptr(100), nil},
+		1002: {nil, float64ptr(100)},
+		1003: {float64ptr(100), nil},
+	}
+
+	overrides := tm.mergeBrokerOverrides(capacities)
+
+	// [broker ID, role index, expected rate]
+	expected := []struct {
+		id   int
+		role int
+		rate float64
+	}{
+		{1000, 0, 30},
+		{1000, 1, 30},
+		{1001, 0, 20},
+		{1002, 1, 100},
+		{1003, 0, 100},
+	}
+
+	for _, e := range expected {
+		if got := *capacities[e.id][e.role]; got != e.rate {
+			t.Errorf("Expected broker %d role %d rate %.2f, got %.2f\n", e.id, e.role, e.rate, got)
+		}
+	}
+
+	// Ceilings leave unset roles unset.
+	if capacities[1001][1] != nil {
+		t.Error("Expected nil follower rate for broker 1001")
+	}
+
+	if _, exists := capacities[1004]; exists {
+		t.Error("Unexpected rates for broker 1004")
+	}
+
+	for id, expected := range map[int]bool{1000: true, 1001: true, 1002: false, 1003: false} {
+		if _, exists := overrides[id]; exists != expected {
+			t.Errorf("Expected broker %d override %t, got %t\n", id, expected, exists)
+		}
+	}
+
+	if !tm.GetBrokerOverrides()[1001].ReassignmentParticipant {
+		t.Error("Expected broker 1001 to be marked as a reassignment participant")
+	}
+
+	// Ceiling overrides aren't applied outside of reassignments.
+	if NotReassignmentParticipant(tm.GetBrokerOverrides()[1004]) {
+		t.Error("Expected ceiling override to be filtered")
+	}
+}
@@ -24,6 +24,8 @@ func (b BrokerThrottleOverride) Copy() BrokerThrottleOverride {
 		Config: ThrottleOverrideConfig{
 			Rate:       b.Config.Rate,
 			AutoRemove: b.Config.AutoRemove,
+			ExpiresAt:  b.Config.ExpiresAt,
+			Ceiling:    b.Config.Ceiling,
 		},
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/DataDog/kafka-kit/v4/kafkazk"
 )
@@ -20,6 +21,17 @@ type ThrottleOverrideConfig struct {
 	// Whether the override rate should be
 	// removed when the current reassignments finish.
 	AutoRemove bool `json:"autoremove"`
+	// Unix timestamp at which the override rate expires
+	// and is removed. 0 means it never expires.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// Whether the rate is a maximum applied to the dynamically calculated
+	// throttles rather than a fixed rate. Broker overrides only.
+	Ceiling bool `json:"ceiling,omitempty"`
+}
+
+// Expired returns whether the override has expired as of time t.
+func (c ThrottleOverrideConfig) Expired(t time.Time) bool {
+	return c.ExpiresAt > 0 && t.Unix() >= c.ExpiresAt
 }
 
 // fetchThrottleOverride gets a throttle override from path p.
@@ -73,6 +85,24 @@ func StoreThrottleOverride(zk kafkazk.Handler, p string, c ThrottleOverrideConfi
 	return nil
 }
 
+// ExpireThrottleOverride removes the override c stored at path p if it has
+// expired as of time t, resetting c. The removal marks the override rate as 0,
+// the same as a removal via the admin API. It returns whether the override
+// was expired.
+func ExpireThrottleOverride(zk kafkazk.Handler, p string, c *ThrottleOverrideConfig, t time.Time) (bool, error) {
+	if !c.Expired(t) {
+		return false, nil
+	}
+
+	if err := StoreThrottleOverride(zk, p, ThrottleOverrideConfig{}); err != nil {
+		return false, err
+	}
+
+	*c = ThrottleOverrideConfig{}
+
+	return true, nil
+}
+
 // removeThrottleOverride deletes an override at path p.
 func RemoveThrottleOverride(zk kafkazk.Handler, p string) error {
 	exists, err := zk.Exists(p)
@@ -0,0 +1,185 @@
+// Package health implements the /healthz (liveness) and /readyz (readiness)
+// endpoints of the kafka-kit daemons.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckFunc returns an error if the checked dependency is unhealthy.
+type CheckFunc func() error
+
+// Status is the JSON response of the health endpoints.
+type Status struct {
+	// Status is "ok" if all checks passed, otherwise "unavailable".
+	Status string `json:"status"`
+	// Checks are the results of each check by name: "ok" or the error text.
+	Checks map[string]string `json:"checks,omitempty"`
+	// LastLoop is the time of the last successful run loop iteration, if
+	// a Heartbeat is configured and has beat.
+	LastLoop *time.Time `json:"last_loop,omitempty"`
+}
+
+// Checker runs named liveness and readiness checks. The zero value is
+// ready to use.
+type Checker struct {
+	mu        sync.Mutex
+	liveness  map[string]CheckFunc
+	readiness map[string]CheckFunc
+	heartbeat *Heartbeat
+}
+
+// AddLivenessCheck adds a check run by /healthz. Liveness checks are also
+// run by /readyz.
+func (c *Checker) AddLivenessCheck(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.liveness == nil {
+		c.liveness = map[string]CheckFunc{}
+	}
+	c.liveness[name] = fn
+}
+
+// AddReadinessCheck adds a check run by /readyz.
+func (c *Checker) AddReadinessCheck(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readiness == nil {
+		c.readiness = map[string]CheckFunc{}
+	}
+	c.readiness[name] = fn
+}
+
+// SetHeartbeat sets the Heartbeat whose last beat is reported in the
+// Status.
+func (c *Checker) SetHeartbeat(h *Heartbeat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.heartbeat = h
+}
+
+// Liveness runs the liveness checks and returns the Status.
+func (c *Checker) Liveness() Status {
+	return c.run(false)
+}
+
+// Readiness runs the liveness and readiness checks and returns the Status.
+func (c *Checker) Readiness() Status {
+	return c.run(true)
+}
+
+func (c *Checker) run(readiness bool) Status {
+	c.mu.Lock()
+	checks := map[string]CheckFunc{}
+	for name, fn := range c.liveness {
+		checks[name] = fn
+	}
+	if readiness {
+		for name, fn := range c.readiness {
+			checks[name] = fn
+		}
+	}
+	hb := c.heartbeat
+	c.mu.Unlock()
+
+	s := Status{Status: "ok", Checks: map[string]string{}}
+
+	var names []string
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := checks[name](); err != nil {
+			s.Status = "unavailable"
+			s.Checks[name] = err.Error()
+			continue
+		}
+		s.Checks[name] = "ok"
+	}
+
+	if hb != nil {
+		if t := hb.Last(); !t.IsZero() {
+			s.LastLoop = &t
+		}
+	}
+
+	return s
+}
+
+// Register registers the /healthz and /readyz endpoints with the
+// *http.ServeMux.
+func (c *Checker) Register(m *http.ServeMux) {
+	m.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) { serveStatus(w, req, c.Liveness) })
+	m.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) { serveStatus(w, req, c.Readiness) })
+}
+
+// serveStatus writes the Status as JSON, with a 503 status code if any
+// checks failed.
+func serveStatus(w http.ResponseWriter, req *http.Request, fn func() Status) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := fn()
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(s)
+}
+
+// Heartbeat records the time of the last successful iteration of a run
+// loop.
+type Heartbeat struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Beat records a successful iteration at the time t.
+func (h *Heartbeat) Beat(t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = t
+}
+
+// Last returns the time of the last beat; the zero time if none.
+func (h *Heartbeat) Last() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.last
+}
+
+// Check returns a CheckFunc that fails if the last beat is older than the
+// maxAge. Before the first beat, the age is measured from the time the
+// CheckFunc was created, allowing for the first iteration to complete.
+func (h *Heartbeat) Check(maxAge time.Duration) CheckFunc {
+	start := time.Now()
+
+	return func() error {
+		last := h.Last()
+		if last.IsZero() {
+			last = start
+		}
+
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("last successful loop %s ago exceeds %s", age.Round(time.Second), maxAge)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	c := &Checker{}
+	var zkErr error
+
+	c.AddLivenessCheck("loop", func() error { return nil })
+	c.AddReadinessCheck("zookeeper", func() error { return zkErr })
+
+	if s := c.Readiness(); s.Status != "ok" || len(s.Checks) != 2 {
+		t.Errorf("Expected ok status with 2 checks, got %+v\n", s)
+	}
+
+	zkErr = errors.New("not connected")
+
+	s := c.Readiness()
+	if s.Status != "unavailable" {
+		t.Errorf("Expected unavailable status, got %s\n", s.Status)
+	}
+
+	if s.Checks["zookeeper"] != "not connected" || s.Checks["loop"] != "ok" {
+		t.Errorf("Unexpected checks %v\n", s.Checks)
+	}
+
+	// Readiness checks aren't run for liveness.
+	if s := c.Liveness(); s.Status != "ok" || len(s.Checks) != 1 {
+		t.Errorf("Expected ok status with 1 check, got %+v\n", s)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	c := &Checker{}
+	c.AddReadinessCheck("zookeeper", func() error { return errors.New("not connected") })
+
+	hb := &Heartbeat{}
+	hb.Beat(time.Unix(1000, 0))
+	c.SetHeartbeat(hb)
+
+	m := http.NewServeMux()
+	c.Register(m)
+
+	expected := map[string]int{
+		"/healthz": http.StatusOK,
+		"/readyz":  http.StatusServiceUnavailable,
+	}
+
+	for path, code := range expected {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != code {
+			t.Errorf("[%s] Expected status code %d, got %d\n", path, code, w.Code)
+		}
+
+		var s Status
+		if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.LastLoop == nil || !s.LastLoop.Equal(time.Unix(1000, 0)) {
+			t.Errorf("[%s] Expected last loop %s, got %v\n", path, time.Unix(1000, 0), s.LastLoop)
+		}
+	}
+}
+
+func TestHeartbeatCheck(t *testing.T) {
+	hb := &Heartbeat{}
+	check := hb.Check(time.Minute)
+
+	// The age is measured from creation before the first beat.
+	if err := check(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	hb.Beat(time.Now().Add(-2 * time.Minute))
+	if err := check(); err == nil {
+		t.Error("Expected error")
+	}
+
+	hb.Beat(time.Now())
+	if err := check(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+}
@@ -0,0 +1,339 @@
+// Package logging implements the leveled, structured logging of the
+// kafka-kit tools. Records are written as text or JSON lines with optional
+// fields, such as a broker ID or throttle rate, for ingestion by logging
+// pipelines.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is a log level.
+type Level int
+
+// Log levels.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Keys reserved for the time, level and message of JSON records. Fields
+// using a reserved key are prefixed with "fields.".
+var reservedKeys = map[string]struct{}{
+	"time":  {},
+	"level": {},
+	"msg":   {},
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel takes a level name [debug, info, warn, error] and returns the
+// Level.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+
+	return LevelInfo, fmt.Errorf("invalid log level %s", s)
+}
+
+// Fields are the key/value pairs of a log record.
+type Fields map[string]interface{}
+
+// Config holds Logger configs.
+type Config struct {
+	// Output is where records are written. Defaults to os.Stderr.
+	Output io.Writer
+	// Format is the record format [text, json]. Defaults to text.
+	Format string
+	// Level is the minimum level of written records.
+	Level Level
+}
+
+// Logger writes leveled log records with fields. A Logger is safe for
+// concurrent use.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	json   bool
+	level  Level
+	fields Fields
+	now    func() time.Time
+}
+
+// New takes a Config and returns a *Logger.
+func New(c Config) (*Logger, error) {
+	l := &Logger{
+		mu:    &sync.Mutex{},
+		out:   c.Output,
+		level: c.Level,
+		now:   time.Now,
+	}
+
+	if l.out == nil {
+		l.out = os.Stderr
+	}
+
+	switch c.Format {
+	case "", FormatText:
+	case FormatJSON:
+		l.json = true
+	default:
+		return nil, fmt.Errorf("invalid log format %s", c.Format)
+	}
+
+	return l, nil
+}
+
+// With returns a Logger that includes the fields in every record, in
+// addition to any fields of the called Logger.
+func (l *Logger) With(f Fields) *Logger {
+	w := *l
+	w.fields = make(Fields, len(l.fields)+len(f))
+
+	for k, v := range l.fields {
+		w.fields[k] = v
+	}
+	for k, v := range f {
+		w.fields[k] = v
+	}
+
+	return &w
+}
+
+// Enabled returns whether records of the level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug writes a debug record.
+func (l *Logger) Debug(msg string, f Fields) { l.Log(LevelDebug, msg, f) }
+
+// Info writes an info record.
+func (l *Logger) Info(msg string, f Fields) { l.Log(LevelInfo, msg, f) }
+
+// Warn writes a warn record.
+func (l *Logger) Warn(msg string, f Fields) { l.Log(LevelWarn, msg, f) }
+
+// Error writes an error record.
+func (l *Logger) Error(msg string, f Fields) { l.Log(LevelError, msg, f) }
+
+// Log writes a record of the level if enabled.
+func (l *Logger) Log(level Level, msg string, f Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := l.fields
+	if len(f) > 0 {
+		fields = l.With(f).fields
+	}
+
+	var b []byte
+	if l.json {
+		b = jsonRecord(l.now(), level, msg, fields)
+	} else {
+		b = textRecord(l.now(), level, msg, fields)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.out.Write(b)
+}
+
+// Writer returns an io.Writer that writes each Write as a record with the
+// message trimmed of trailing newlines. It's used as the output of the
+// standard library log package with log.SetFlags(0). Messages beginning with
+// "error" or "warn" (case-insensitive) are written at the error and warn
+// level respectively, otherwise at the level provided.
+func (l *Logger) Writer(level Level) io.Writer {
+	return writer{l: l, level: level}
+}
+
+type writer struct {
+	l     *Logger
+	level Level
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	w.l.Log(messageLevel(msg, w.level), msg, nil)
+
+	return len(p), nil
+}
+
+// messageLevel returns the level of a free-form message.
+func messageLevel(msg string, level Level) Level {
+	m := strings.ToLower(msg)
+
+	switch {
+	case strings.HasPrefix(m, "error"):
+		return LevelError
+	case strings.HasPrefix(m, "warn"):
+		return LevelWarn
+	}
+
+	return level
+}
+
+// textRecord returns a text record, e.g.:
+// 2006/01/02 15:04:05 [INFO] Updated throttle broker_id=1001 rate=25.00
+func textRecord(t time.Time, level Level, msg string, f Fields) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(t.Format("2006/01/02 15:04:05"))
+	b.WriteString(" [")
+	b.WriteString(strings.ToUpper(level.String()))
+	b.WriteString("] ")
+	b.WriteString(msg)
+
+	for _, k := range sortedKeys(f) {
+		b.WriteByte(' ')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(textValue(f[k]))
+	}
+
+	b.WriteByte('\n')
+
+	return b.Bytes()
+}
+
+// textValue formats a field value for a text record, quoting values
+// containing spaces.
+func textValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(t, 'f', 2, 64)
+	case error:
+		s = t.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// jsonRecord returns a JSON record with the time, level and msg keys
+// followed by the fields in key order.
+func jsonRecord(t time.Time, level Level, msg string, f Fields) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(`{"time":`)
+	b.Write(jsonValue(t.UTC().Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":`)
+	b.Write(jsonValue(level.String()))
+	b.WriteString(`,"msg":`)
+	b.Write(jsonValue(msg))
+
+	for _, k := range sortedKeys(f) {
+		key := k
+		if _, reserved := reservedKeys[k]; reserved {
+			key = "fields." + k
+		}
+
+		b.WriteByte(',')
+		b.Write(jsonValue(key))
+		b.WriteByte(':')
+
+		v := f[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		b.Write(jsonValue(v))
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+// jsonValue returns the JSON encoding of v, or of its string form if it
+// can't be encoded.
+func jsonValue(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	return b
+}
+
+func sortedKeys(f Fields) []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// std is the default Logger used by the package level functions.
+var std, _ = New(Config{Level: LevelInfo})
+
+// Default returns the default Logger.
+func Default() *Logger {
+	return std
+}
+
+// SetDefault sets the default Logger.
+func SetDefault(l *Logger) {
+	std = l
+}
+
+// Debug writes a debug record with the default Logger.
+func Debug(msg string, f Fields) { std.Debug(msg, f) }
+
+// Info writes an info record with the default Logger.
+func Info(msg string, f Fields) { std.Info(msg, f) }
+
+// Warn writes a warn record with the default Logger.
+func Warn(msg string, f Fields) { std.Warn(msg, f) }
+
+// Error writes an error record with the default Logger.
+func Error(msg string, f Fields) { std.Error(msg, f) }
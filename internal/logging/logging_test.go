@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLogger(t *testing.T, format string, level Level) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+
+	l, err := New(Config{Output: &buf, Format: format, Level: level})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	return l, &buf
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug": LevelDebug,
+		"INFO":  LevelInfo,
+		"warn":  LevelWarn,
+		"error": LevelError,
+	}
+
+	for s, expected := range tests {
+		if l, err := ParseLevel(s); err != nil || l != expected {
+			t.Errorf("[%s] Expected level %s, got %s (%v)\n", s, expected, l, err)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected invalid level error")
+	}
+
+	if _, err := New(Config{Format: "xml"}); err == nil {
+		t.Error("Expected invalid format error")
+	}
+}
+
+func TestTextRecord(t *testing.T) {
+	l, buf := testLogger(t, FormatText, LevelInfo)
+
+	l.Info("Updated throttle", Fields{"broker_id": 1001, "role": "leader", "rate": 25.0})
+	l.With(Fields{"cluster": "east"}).Error("Error setting throttle", Fields{"error": errors.New("timed out")})
+
+	expected := "2024/01/02 03:04:05 [INFO] Updated throttle broker_id=1001 rate=25.00 role=leader\n" +
+		"2024/01/02 03:04:05 [ERROR] Error setting throttle cluster=east error=\"timed out\"\n"
+
+	if buf.String() != expected {
+		t.Errorf("Expected records:\n%s\ngot:\n%s\n", expected, buf.String())
+	}
+}
+
+func TestJSONRecord(t *testing.T) {
+	l, buf := testLogger(t, FormatJSON, LevelInfo)
+
+	l.Warn("Proposed throttle within threshold", Fields{"broker_id": 1001, "msg": "dup"})
+
+	expected := `{"time":"2024-01-02T03:04:05Z","level":"warn","msg":"Proposed throttle within threshold","broker_id":1001,"fields.msg":"dup"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected record %s, got %s\n", expected, buf.String())
+	}
+
+	var r map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	l, buf := testLogger(t, FormatText, LevelWarn)
+
+	l.Debug("debug", nil)
+	l.Info("info", nil)
+	l.Warn("warn", nil)
+
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Errorf("Expected 1 record, got %d\n", n)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	l, buf := testLogger(t, FormatJSON, LevelInfo)
+
+	std := log.New(l.Writer(LevelInfo), "", 0)
+	std.Println("Autothrottle Running")
+	std.Printf("Error fetching metrics: %s\n", "timeout")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %d\n", len(lines))
+	}
+
+	expected := []struct{ level, msg string }{
+		{"info", "Autothrottle Running"},
+		{"error", "Error fetching metrics: timeout"},
+	}
+
+	for i, line := range lines {
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+
+		if r["level"] != expected[i].level || r["msg"] != expected[i].msg {
+			t.Errorf("Expected %s record %q, got %v\n", expected[i].level, expected[i].msg, r)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DataDog/kafka-kit/v4/internal/health"
+)
+
+// healthChecker returns a *health.Checker with readiness checks of the
+// ZooKeeper and Kafka connections.
+func (s *Server) healthChecker() *health.Checker {
+	c := &health.Checker{}
+
+	c.AddReadinessCheck("zookeeper", func() error {
+		if s.ZK == nil || !s.ZK.Ready() {
+			return errors.New("not connected")
+		}
+		return nil
+	})
+
+	c.AddReadinessCheck("kafka", func() error {
+		if s.kafkaadmin == nil {
+			return errors.New("not connected")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.defaultRequestTimeout)
+		defer cancel()
+
+		_, err := s.kafkaadmin.ListBrokers(ctx)
+		return err
+	})
+
+	return c
+}
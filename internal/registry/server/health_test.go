@@ -0,0 +1,19 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestHealthChecker(t *testing.T) {
+	s := testServer()
+
+	if st := s.healthChecker().Readiness(); st.Status != "ok" {
+		t.Errorf("Expected ok status, got %+v\n", st)
+	}
+
+	s.kafkaadmin = nil
+	st := s.healthChecker().Readiness()
+	if st.Status != "unavailable" || st.Checks["kafka"] != "not connected" {
+		t.Errorf("Expected unavailable kafka check, got %+v\n", st)
+	}
+}
@@ -155,9 +155,14 @@ func (s *Server) RunHTTP(ctx context.Context, wg *sync.WaitGroup) error {
 		return err
 	}
 
+	// Health endpoints are served alongside the gateway.
+	root := http.NewServeMux()
+	s.healthChecker().Register(root)
+	root.Handle("/", mux)
+
 	srvr := &http.Server{
 		Addr:    s.HTTPListen,
-		Handler: mux,
+		Handler: root,
 	}
 
 	// Shutdown procedure.
@@ -24,6 +24,7 @@ type FactoryFunc func(conf *kafka.ConfigMap) (*kafka.AdminClient, error)
 // Client implements a KafkaAdmin.
 type Client struct {
 	c                *kafka.AdminClient
+	cfg              Config
 	DefaultTimeoutMs int
 }
 
@@ -69,6 +70,20 @@ func NewConsumer(cfg Config) (*kafka.Consumer, error) {
 	return c, err
 }
 
+// NewProducer returns a *kafka.Producer.
+func NewProducer(cfg Config) (*kafka.Producer, error) {
+	kafkaCfg, err := cfgToConfigMap(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("[config] %s", err)
+	}
+	p, err := kafka.NewProducer(kafkaCfg)
+
+	if err != nil {
+		err = fmt.Errorf("[librdkafka] %s", err)
+	}
+	return p, err
+}
+
 func cfgToConfigMap(cfg Config) (*kafka.ConfigMap, error) {
 	kafkaCfg := &kafka.ConfigMap{
 		"bootstrap.servers": cfg.BootstrapServers,
@@ -99,6 +114,7 @@ func cfgToConfigMap(cfg Config) (*kafka.ConfigMap, error) {
 
 func newClient(cfg Config, factory FactoryFunc) (*Client, error) {
 	c := &Client{
+		cfg:              cfg,
 		DefaultTimeoutMs: cfg.DefaultTimeoutMs,
 	}
 
@@ -13,12 +13,18 @@ type KafkaAdmin interface {
 	DeleteTopic(context.Context, string) error
 	DescribeTopics(context.Context, []string) (TopicStates, error)
 	UnderReplicatedTopics(context.Context) (TopicStates, error)
+	ListPartitionReassignments(context.Context) (PartitionReassignments, error)
+	AlterPartitionReassignments(context.Context, ReplicaAssignments) error
 	// Brokers.
 	ListBrokers(context.Context) ([]int, error)
 	DescribeBrokers(context.Context, bool) (BrokerStates, error)
+	DescribeLogDirs(context.Context, []int) (LogDirs, error)
 	// Cluster.
 	SetThrottle(context.Context, SetThrottleConfig) error
 	RemoveThrottle(context.Context, RemoveThrottleConfig) error
 	GetConfigs(context.Context, string, []string) (ResourceConfigs, error)
 	GetDynamicConfigs(context.Context, string, []string) (ResourceConfigs, error)
+	// Client quotas.
+	SetClientQuotas(context.Context, ClientQuotas) error
+	RemoveClientQuotas(context.Context, []QuotaEntity, []string) error
 }
@@ -0,0 +1,170 @@
+package kafkaadmin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ReplicaSize describes a partition replica hosted in a log dir.
+type ReplicaSize struct {
+	Topic     string
+	Partition int32
+	// Size is the replica size in bytes.
+	Size int64
+	// Future is true for a replica being created by a move between log dirs
+	// of the same broker; the current replica is also listed.
+	Future bool
+}
+
+// LogDirs is a mapping of broker ID to log dir path to the replicas hosted in
+// the log dir.
+type LogDirs map[int]map[string][]ReplicaSize
+
+// DescribeLogDirs returns the log dirs of the brokers, or of all brokers if
+// none are specified, using the v2 DescribeLogDirs API. Log dirs are local to
+// each broker, so a request is sent to every broker. Brokers that can't be
+// described, as well as offline log dirs, are omitted; an error is only
+// returned if no broker could be described. As with
+// ListPartitionReassignments, SASL security protocols aren't supported.
+func (c Client) DescribeLogDirs(ctx context.Context, ids []int) (LogDirs, error) {
+	if strings.HasPrefix(c.cfg.SecurityProtocol, "SASL_") {
+		return nil, fmt.Errorf("DescribeLogDirs doesn't support the %s security protocol", c.cfg.SecurityProtocol)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Millisecond*time.Duration(c.DefaultTimeoutMs))
+		defer cancel()
+	}
+
+	brokers, err := c.fetchBrokers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := map[int]string{}
+	for _, b := range brokers {
+		addrs[int(b.ID)] = net.JoinHostPort(b.Host, fmt.Sprint(b.Port))
+	}
+
+	if len(ids) == 0 {
+		for id := range addrs {
+			ids = append(ids, id)
+		}
+	}
+
+	logDirs := LogDirs{}
+
+	var lastErr error = ErrNoData
+	for _, id := range ids {
+		addr, exists := addrs[id]
+		if !exists {
+			lastErr = fmt.Errorf("broker %d not found", id)
+			continue
+		}
+
+		dirs, err := c.describeLogDirsOn(ctx, addr)
+		if err != nil {
+			lastErr = fmt.Errorf("broker %d: %s", id, err)
+			continue
+		}
+
+		logDirs[id] = dirs
+	}
+
+	if len(logDirs) == 0 {
+		return nil, fmt.Errorf("failed to describe log dirs: %s", lastErr)
+	}
+
+	return logDirs, nil
+}
+
+// describeLogDirsOn requests the log dirs of the broker at the address.
+func (c Client) describeLogDirsOn(ctx context.Context, addr string) (map[string][]ReplicaSize, error) {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dl, _ := ctx.Deadline()
+	conn.SetDeadline(dl)
+
+	return describeLogDirs(conn)
+}
+
+// describeLogDirs performs a v2 DescribeLogDirs request for all topics over
+// the connection.
+func describeLogDirs(conn net.Conn) (map[string][]ReplicaSize, error) {
+	const correlationID = 1
+
+	var e protocolEncoder
+	e.requestHeader(apiKeyDescribeLogDirs, 2, correlationID)
+	// A null topics array describes all replicas.
+	e.uvarint(0)
+	e.emptyTaggedFields()
+
+	resp, err := roundTrip(conn, e.frame())
+	if err != nil {
+		return nil, err
+	}
+
+	d := &protocolDecoder{b: resp}
+	if err := d.responseHeader(correlationID); err != nil {
+		return nil, err
+	}
+
+	return decodeDescribeLogDirs(d)
+}
+
+// decodeDescribeLogDirs decodes a v2 DescribeLogDirs response body. Log dirs
+// with an error, such as offline log dirs, are omitted.
+func decodeDescribeLogDirs(d *protocolDecoder) (map[string][]ReplicaSize, error) {
+	// Throttle time.
+	d.int32()
+
+	dirs := map[string][]ReplicaSize{}
+
+	results := d.compactLength()
+	for i := 0; i < results && d.err == nil; i++ {
+		code := d.int16()
+		path := d.compactString()
+
+		var replicas []ReplicaSize
+
+		topics := d.compactLength()
+		for j := 0; j < topics && d.err == nil; j++ {
+			name := d.compactString()
+
+			partitions := d.compactLength()
+			for k := 0; k < partitions && d.err == nil; k++ {
+				r := ReplicaSize{Topic: name, Partition: d.int32(), Size: d.int64()}
+				// Offset lag.
+				d.int64()
+				r.Future = d.bool()
+				d.skipTaggedFields()
+
+				replicas = append(replicas, r)
+			}
+
+			d.skipTaggedFields()
+		}
+
+		d.skipTaggedFields()
+
+		if code == 0 {
+			dirs[path] = replicas
+		}
+	}
+
+	d.skipTaggedFields()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return dirs, nil
+}
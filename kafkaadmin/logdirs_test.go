@@ -0,0 +1,93 @@
+package kafkaadmin
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// describeLogDirsResponse returns a v2 DescribeLogDirs response with a log dir
+// hosting the replicas and an offline log dir.
+func describeLogDirsResponse(path string, replicas []ReplicaSize) []byte {
+	var e protocolEncoder
+	// Response header.
+	e.int32(1)
+	e.emptyTaggedFields()
+	// Throttle time.
+	e.int32(0)
+
+	e.uvarint(3)
+
+	// A log dir with a topic per replica.
+	e.int16(0)
+	e.compactString(path)
+	e.uvarint(uint64(len(replicas) + 1))
+	for _, r := range replicas {
+		e.compactString(r.Topic)
+		e.uvarint(2)
+		e.int32(r.Partition)
+		e.int64(r.Size)
+		// Offset lag.
+		e.int64(0)
+		e.bool(r.Future)
+		e.emptyTaggedFields()
+		e.emptyTaggedFields()
+	}
+	e.emptyTaggedFields()
+
+	// A KAFKA_STORAGE_ERROR log dir.
+	e.int16(56)
+	e.compactString("/data/offline")
+	e.uvarint(1)
+	e.emptyTaggedFields()
+
+	e.emptyTaggedFields()
+
+	return e.frame()
+}
+
+func TestDescribeLogDirs(t *testing.T) {
+	replicas := []ReplicaSize{
+		{Topic: "test_topic", Partition: 0, Size: 1024},
+		{Topic: "test_topic", Partition: 0, Size: 512, Future: true},
+		{Topic: "other_topic", Partition: 3, Size: 2048},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		var size int32
+		binary.Read(server, binary.BigEndian, &size)
+		req := make([]byte, size)
+		if _, err := io.ReadFull(server, req); err != nil {
+			return
+		}
+
+		assert.Equal(t, apiKeyDescribeLogDirs, int16(binary.BigEndian.Uint16(req[0:2])))
+		assert.Equal(t, int16(2), int16(binary.BigEndian.Uint16(req[2:4])))
+
+		server.Write(describeLogDirsResponse("/data/kafka", replicas))
+	}()
+
+	dirs, err := describeLogDirs(client)
+	assert.Nil(t, err)
+	// The offline log dir is omitted.
+	assert.Equal(t, map[string][]ReplicaSize{"/data/kafka": replicas}, dirs)
+}
+
+func TestDecodeDescribeLogDirsError(t *testing.T) {
+	resp := describeLogDirsResponse("/data/kafka", []ReplicaSize{{Topic: "test_topic", Size: 1024}})
+
+	// Truncated responses are an error.
+	d := &protocolDecoder{b: resp[4 : len(resp)-8]}
+	assert.Nil(t, d.responseHeader(1))
+
+	_, err := decodeDescribeLogDirs(d)
+	assert.Equal(t, errShortBuffer, err)
+}
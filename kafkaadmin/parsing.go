@@ -21,15 +21,13 @@ var (
 func stringsToRegex(names []string) ([]*regexp.Regexp, error) {
 	var out []*regexp.Regexp
 
-	// Update string literals to ^value$ regex.
-	for n, t := range names {
+	for _, t := range names {
+		// Update string literals to ^value$ regex.
 		if !containsRegex(t) {
-			names[n] = fmt.Sprintf(`^%s$`, t)
+			t = fmt.Sprintf(`^%s$`, t)
 		}
-	}
 
-	// Compile regex patterns.
-	for _, t := range names {
+		// Compile regex patterns.
 		r, err := regexp.Compile(t)
 		if err != nil {
 			return nil, fmt.Errorf("invalid regex pattern: %s\n", t)
@@ -0,0 +1,25 @@
+package kafkaadmin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringsToRegex(t *testing.T) {
+	names := []string{"test1", `^metrics\..*`}
+
+	re, err := stringsToRegex(names)
+	assert.Nil(t, err)
+
+	// The input names are left as-is.
+	assert.Equal(t, []string{"test1", `^metrics\..*`}, names)
+
+	assert.True(t, re[0].MatchString("test1"))
+	assert.False(t, re[0].MatchString("test10"))
+	assert.True(t, re[1].MatchString("metrics.requests"))
+	assert.False(t, re[1].MatchString("app.metrics"))
+
+	_, err = stringsToRegex([]string{"("})
+	assert.NotNil(t, err)
+}
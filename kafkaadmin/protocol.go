@@ -0,0 +1,272 @@
+package kafkaadmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A minimal Kafka protocol implementation for APIs that aren't supported by
+// the confluent-kafka-go AdminClient. Only flexible (KIP-482) request and
+// response versions are supported.
+
+const (
+	// Kafka protocol API keys.
+	apiKeyDescribeLogDirs             int16 = 35
+	apiKeyAlterPartitionReassignments int16 = 45
+	apiKeyListPartitionReassignments  int16 = 46
+	apiKeyAlterClientQuotas           int16 = 49
+
+	// Kafka protocol error codes.
+	errCodeNotController int16 = 41
+
+	protocolClientID = "kafka-kit"
+	// The maximum response size read.
+	maxResponseSize = 64 << 20
+)
+
+var errShortBuffer = errors.New("malformed response: short buffer")
+
+// ErrKafkaProtocol is a Kafka protocol error returned in a response.
+type ErrKafkaProtocol struct {
+	Code    int16
+	Message string
+}
+
+func (e ErrKafkaProtocol) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("kafka error code %d: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("kafka error code %d", e.Code)
+}
+
+// protocolEncoder encodes flexible version Kafka protocol requests.
+type protocolEncoder struct {
+	b bytes.Buffer
+}
+
+func (e *protocolEncoder) int16(v int16) {
+	binary.Write(&e.b, binary.BigEndian, v)
+}
+
+func (e *protocolEncoder) int32(v int32) {
+	binary.Write(&e.b, binary.BigEndian, v)
+}
+
+func (e *protocolEncoder) int64(v int64) {
+	binary.Write(&e.b, binary.BigEndian, v)
+}
+
+func (e *protocolEncoder) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.b.Write(buf[:n])
+}
+
+func (e *protocolEncoder) bool(v bool) {
+	if v {
+		e.b.WriteByte(1)
+		return
+	}
+	e.b.WriteByte(0)
+}
+
+func (e *protocolEncoder) float64(v float64) {
+	binary.Write(&e.b, binary.BigEndian, math.Float64bits(v))
+}
+
+// compactString writes a compact string.
+func (e *protocolEncoder) compactString(s string) {
+	e.uvarint(uint64(len(s) + 1))
+	e.b.WriteString(s)
+}
+
+// compactNullableString writes a compact nullable string, with an empty
+// string written as null.
+func (e *protocolEncoder) compactNullableString(s string) {
+	if s == "" {
+		e.uvarint(0)
+		return
+	}
+	e.compactString(s)
+}
+
+// compactInt32Array writes a compact int32 array.
+func (e *protocolEncoder) compactInt32Array(a []int32) {
+	e.uvarint(uint64(len(a) + 1))
+	for _, v := range a {
+		e.int32(v)
+	}
+}
+
+// string writes a non-compact nullable string, as used in request headers.
+func (e *protocolEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.b.WriteString(s)
+}
+
+// emptyTaggedFields writes an empty tagged fields section.
+func (e *protocolEncoder) emptyTaggedFields() {
+	e.uvarint(0)
+}
+
+// requestHeader writes a v2 request header.
+func (e *protocolEncoder) requestHeader(apiKey, apiVersion int16, correlationID int32) {
+	e.int16(apiKey)
+	e.int16(apiVersion)
+	e.int32(correlationID)
+	e.string(protocolClientID)
+	e.emptyTaggedFields()
+}
+
+// frame returns the size prefixed request.
+func (e *protocolEncoder) frame() []byte {
+	b := make([]byte, 4, 4+e.b.Len())
+	binary.BigEndian.PutUint32(b, uint32(e.b.Len()))
+	return append(b, e.b.Bytes()...)
+}
+
+// protocolDecoder decodes flexible version Kafka protocol responses.
+type protocolDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *protocolDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errShortBuffer
+		return nil
+	}
+
+	v := d.b[:n]
+	d.b = d.b[n:]
+
+	return v
+}
+
+func (d *protocolDecoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *protocolDecoder) bool() bool {
+	if b := d.take(1); b != nil {
+		return b[0] != 0
+	}
+	return false
+}
+
+func (d *protocolDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.b = d.b[n:]
+
+	return v
+}
+
+// compactLength returns the length of a compact string or array, or -1 if
+// null.
+func (d *protocolDecoder) compactLength() int {
+	return int(d.uvarint()) - 1
+}
+
+func (d *protocolDecoder) compactString() string {
+	n := d.compactLength()
+	if n <= 0 {
+		return ""
+	}
+	return string(d.take(n))
+}
+
+func (d *protocolDecoder) compactInt32Array() []int32 {
+	n := d.compactLength()
+	if n < 0 || d.err != nil {
+		return nil
+	}
+
+	a := make([]int32, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		a = append(a, d.int32())
+	}
+
+	return a
+}
+
+// skipTaggedFields skips a tagged fields section.
+func (d *protocolDecoder) skipTaggedFields() {
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		d.uvarint()
+		d.take(int(d.uvarint()))
+	}
+}
+
+// responseHeader reads a v1 response header, returning an error if the
+// correlation ID doesn't match.
+func (d *protocolDecoder) responseHeader(correlationID int32) error {
+	id := d.int32()
+	d.skipTaggedFields()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	if id != correlationID {
+		return fmt.Errorf("malformed response: unexpected correlation ID %d", id)
+	}
+
+	return nil
+}
+
+// roundTrip writes the request to the connection and returns the response
+// without its size prefix.
+func roundTrip(rw io.ReadWriter, req []byte) ([]byte, error) {
+	if _, err := rw.Write(req); err != nil {
+		return nil, err
+	}
+
+	var size int32
+	if err := binary.Read(rw, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+
+	if size < 0 || size > maxResponseSize {
+		return nil, fmt.Errorf("malformed response: invalid size %d", size)
+	}
+
+	resp := make([]byte, size)
+	if _, err := io.ReadFull(rw, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,215 @@
+package kafkaadmin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Client quota entity types.
+const (
+	QuotaEntityClientID = "client-id"
+	QuotaEntityUser     = "user"
+)
+
+// Client quota keys.
+const (
+	// ProducerByteRate is the produce quota in bytes/s.
+	ProducerByteRate = "producer_byte_rate"
+	// ConsumerByteRate is the fetch quota in bytes/s.
+	ConsumerByteRate = "consumer_byte_rate"
+)
+
+// QuotaEntity is a client ID or user that client quotas apply to. An empty
+// Name is the default entity of the Type.
+type QuotaEntity struct {
+	Type string
+	Name string
+}
+
+func (e QuotaEntity) String() string {
+	if e.Name == "" {
+		return e.Type + ":<default>"
+	}
+	return e.Type + ":" + e.Name
+}
+
+// ClientQuotas is a mapping of QuotaEntity to quota key to value.
+type ClientQuotas map[QuotaEntity]map[string]float64
+
+// clientQuotaAlteration describes the quota values set and the quota keys
+// removed for an entity.
+type clientQuotaAlteration struct {
+	entity QuotaEntity
+	set    map[string]float64
+	remove []string
+}
+
+// SetClientQuotas sets client quotas using the KIP-546 AlterClientQuotas API
+// (Kafka 2.8+). Quotas not included are left unchanged. As with
+// ListPartitionReassignments, SASL security protocols aren't supported.
+func (c Client) SetClientQuotas(ctx context.Context, q ClientQuotas) error {
+	var alterations []clientQuotaAlteration
+	for e, values := range q {
+		alterations = append(alterations, clientQuotaAlteration{entity: e, set: values})
+	}
+
+	return c.alterClientQuotas(ctx, alterations)
+}
+
+// RemoveClientQuotas removes the quota keys of each QuotaEntity using the
+// KIP-546 AlterClientQuotas API (Kafka 2.8+).
+func (c Client) RemoveClientQuotas(ctx context.Context, entities []QuotaEntity, keys []string) error {
+	var alterations []clientQuotaAlteration
+	for _, e := range entities {
+		alterations = append(alterations, clientQuotaAlteration{entity: e, remove: keys})
+	}
+
+	return c.alterClientQuotas(ctx, alterations)
+}
+
+// alterClientQuotas sends the alterations to the first broker reachable.
+// Any broker is able to serve the request.
+func (c Client) alterClientQuotas(ctx context.Context, alterations []clientQuotaAlteration) error {
+	if len(alterations) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(c.cfg.SecurityProtocol, "SASL_") {
+		return fmt.Errorf("AlterClientQuotas doesn't support the %s security protocol", c.cfg.SecurityProtocol)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Millisecond*time.Duration(c.DefaultTimeoutMs))
+		defer cancel()
+	}
+
+	brokers, err := c.fetchBrokers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error = ErrNoData
+	for _, b := range brokers {
+		addr := net.JoinHostPort(b.Host, fmt.Sprint(b.Port))
+
+		conn, err := c.dial(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		dl, _ := ctx.Deadline()
+		conn.SetDeadline(dl)
+
+		err = alterClientQuotas(conn, alterations)
+		conn.Close()
+
+		return err
+	}
+
+	return fmt.Errorf("failed to alter client quotas: %s", lastErr)
+}
+
+// alterClientQuotas performs a v1 AlterClientQuotas request over the
+// connection.
+func alterClientQuotas(conn net.Conn, alterations []clientQuotaAlteration) error {
+	const correlationID = 1
+
+	var e protocolEncoder
+	e.requestHeader(apiKeyAlterClientQuotas, 1, correlationID)
+
+	e.uvarint(uint64(len(alterations) + 1))
+	for _, a := range alterations {
+		// A single component entity.
+		e.uvarint(2)
+		e.compactString(a.entity.Type)
+		e.compactNullableString(a.entity.Name)
+		e.emptyTaggedFields()
+
+		// Ops are written in key order.
+		var keys []string
+		for k := range a.set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		e.uvarint(uint64(len(keys) + len(a.remove) + 1))
+		for _, k := range keys {
+			e.compactString(k)
+			e.float64(a.set[k])
+			e.bool(false)
+			e.emptyTaggedFields()
+		}
+		for _, k := range a.remove {
+			e.compactString(k)
+			e.float64(0)
+			e.bool(true)
+			e.emptyTaggedFields()
+		}
+
+		e.emptyTaggedFields()
+	}
+
+	// Validate only.
+	e.bool(false)
+	e.emptyTaggedFields()
+
+	resp, err := roundTrip(conn, e.frame())
+	if err != nil {
+		return err
+	}
+
+	d := &protocolDecoder{b: resp}
+	if err := d.responseHeader(correlationID); err != nil {
+		return err
+	}
+
+	return decodeAlterClientQuotas(d)
+}
+
+// decodeAlterClientQuotas decodes a v1 AlterClientQuotas response body,
+// returning an error listing the entities that couldn't be altered.
+func decodeAlterClientQuotas(d *protocolDecoder) error {
+	// Throttle time.
+	d.int32()
+
+	var errStrings []string
+
+	entries := d.compactLength()
+	for i := 0; i < entries && d.err == nil; i++ {
+		code := d.int16()
+		msg := d.compactString()
+
+		var entity []string
+		components := d.compactLength()
+		for j := 0; j < components && d.err == nil; j++ {
+			e := QuotaEntity{Type: d.compactString(), Name: d.compactString()}
+			entity = append(entity, e.String())
+			d.skipTaggedFields()
+		}
+
+		d.skipTaggedFields()
+
+		if code != 0 {
+			err := ErrKafkaProtocol{Code: code, Message: msg}
+			errStrings = append(errStrings, fmt.Sprintf("%s: %s", strings.Join(entity, ","), err))
+		}
+	}
+
+	d.skipTaggedFields()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	if errStrings != nil {
+		return fmt.Errorf("failed to alter client quotas: %s", strings.Join(errStrings, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package kafkaadmin
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// alterClientQuotasResponse returns a v1 AlterClientQuotas response with an
+// entry for the entity with the error code.
+func alterClientQuotasResponse(code int16, entity QuotaEntity) []byte {
+	var e protocolEncoder
+	// Response header.
+	e.int32(1)
+	e.emptyTaggedFields()
+	// Throttle time.
+	e.int32(0)
+
+	// One entry with a single component entity.
+	e.uvarint(2)
+	e.int16(code)
+	e.uvarint(0)
+	e.uvarint(2)
+	e.compactString(entity.Type)
+	e.compactNullableString(entity.Name)
+	e.emptyTaggedFields()
+	// Entry and response tagged fields.
+	e.emptyTaggedFields()
+	e.emptyTaggedFields()
+
+	return e.frame()
+}
+
+func TestAlterClientQuotas(t *testing.T) {
+	entity := QuotaEntity{Type: QuotaEntityClientID, Name: "noisy_client"}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		var size int32
+		binary.Read(server, binary.BigEndian, &size)
+		req := make([]byte, size)
+		if _, err := io.ReadFull(server, req); err != nil {
+			return
+		}
+
+		assert.Equal(t, apiKeyAlterClientQuotas, int16(binary.BigEndian.Uint16(req[0:2])))
+		assert.Equal(t, int16(1), int16(binary.BigEndian.Uint16(req[2:4])))
+
+		// The request body following the header.
+		var e protocolEncoder
+		e.requestHeader(apiKeyAlterClientQuotas, 1, 1)
+		body := req[e.b.Len():]
+
+		e.b.Reset()
+		e.uvarint(2)
+		e.uvarint(2)
+		e.compactString(QuotaEntityClientID)
+		e.compactString("noisy_client")
+		e.emptyTaggedFields()
+		e.uvarint(3)
+		e.compactString(ConsumerByteRate)
+		e.float64(2000000)
+		e.bool(false)
+		e.emptyTaggedFields()
+		e.compactString(ProducerByteRate)
+		e.float64(1000000)
+		e.bool(false)
+		e.emptyTaggedFields()
+		e.emptyTaggedFields()
+		e.bool(false)
+		e.emptyTaggedFields()
+
+		assert.Equal(t, e.b.Bytes(), body)
+
+		server.Write(alterClientQuotasResponse(0, entity))
+	}()
+
+	err := alterClientQuotas(client, []clientQuotaAlteration{
+		{entity: entity, set: map[string]float64{ProducerByteRate: 1000000, ConsumerByteRate: 2000000}},
+	})
+	assert.Nil(t, err)
+}
+
+func TestDecodeAlterClientQuotasError(t *testing.T) {
+	resp := alterClientQuotasResponse(42, QuotaEntity{Type: QuotaEntityClientID})
+
+	d := &protocolDecoder{b: resp[4:]}
+	assert.Nil(t, d.responseHeader(1))
+
+	err := decodeAlterClientQuotas(d)
+	assert.EqualError(t, err, "failed to alter client quotas: client-id:<default>: kafka error code 42")
+
+	// Truncated responses are an error.
+	resp = alterClientQuotasResponse(0, QuotaEntity{Type: QuotaEntityClientID, Name: "noisy_client"})
+	d = &protocolDecoder{b: resp[4 : len(resp)-4]}
+	assert.Nil(t, d.responseHeader(1))
+
+	assert.Equal(t, errShortBuffer, decodeAlterClientQuotas(d))
+}
@@ -0,0 +1,366 @@
+package kafkaadmin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PartitionReassignment describes an ongoing partition reassignment.
+type PartitionReassignment struct {
+	// Replicas is the current replica set, including any replicas being
+	// added or removed.
+	Replicas         []int32
+	AddingReplicas   []int32
+	RemovingReplicas []int32
+}
+
+// TargetReplicas returns the replica set once the reassignment completes.
+func (p PartitionReassignment) TargetReplicas() []int32 {
+	removing := map[int32]struct{}{}
+	for _, id := range p.RemovingReplicas {
+		removing[id] = struct{}{}
+	}
+
+	var target []int32
+	for _, id := range p.Replicas {
+		if _, exists := removing[id]; !exists {
+			target = append(target, id)
+		}
+	}
+
+	return target
+}
+
+// PartitionReassignments is a mapping of topic name to partition ID to
+// PartitionReassignment.
+type PartitionReassignments map[string]map[int32]PartitionReassignment
+
+// ReplicaAssignments is a mapping of topic name to partition ID to target
+// replica set.
+type ReplicaAssignments map[string]map[int32][]int32
+
+// ListPartitionReassignments returns all ongoing partition reassignments
+// using the KIP-455 ListPartitionReassignments API (Kafka 2.4+), which
+// doesn't require ZooKeeper access. The request is sent to each broker in
+// turn until one that's able to serve it, i.e. the controller or a KRaft
+// broker forwarding to the controller, is found. SASL security protocols
+// aren't supported.
+func (c Client) ListPartitionReassignments(ctx context.Context) (PartitionReassignments, error) {
+	if strings.HasPrefix(c.cfg.SecurityProtocol, "SASL_") {
+		return nil, fmt.Errorf("ListPartitionReassignments doesn't support the %s security protocol", c.cfg.SecurityProtocol)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Millisecond*time.Duration(c.DefaultTimeoutMs))
+		defer cancel()
+	}
+
+	brokers, err := c.fetchBrokers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error = ErrNoData
+	for _, b := range brokers {
+		addr := net.JoinHostPort(b.Host, fmt.Sprint(b.Port))
+
+		r, err := c.listPartitionReassignmentsFrom(ctx, addr)
+		if err == nil {
+			return r, nil
+		}
+
+		lastErr = err
+
+		// Only try other brokers if this one isn't able to serve the request.
+		var perr ErrKafkaProtocol
+		if errors.As(err, &perr) && perr.Code != errCodeNotController {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("failed to list partition reassignments: %s", lastErr)
+}
+
+// listPartitionReassignmentsFrom requests the ongoing partition
+// reassignments from the broker at the address.
+func (c Client) listPartitionReassignmentsFrom(ctx context.Context, addr string) (PartitionReassignments, error) {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dl, _ := ctx.Deadline()
+	conn.SetDeadline(dl)
+
+	return listPartitionReassignments(conn, time.Until(dl))
+}
+
+// AlterPartitionReassignments submits reassignments of each partition to its
+// target replica set using the KIP-455 AlterPartitionReassignments API (Kafka
+// 2.4+). As with ListPartitionReassignments, the request is sent to each
+// broker in turn until the controller is found and SASL security protocols
+// aren't supported. An error listing the partitions that couldn't be
+// reassigned is returned; all others are reassigned.
+func (c Client) AlterPartitionReassignments(ctx context.Context, ra ReplicaAssignments) error {
+	if len(ra) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(c.cfg.SecurityProtocol, "SASL_") {
+		return fmt.Errorf("AlterPartitionReassignments doesn't support the %s security protocol", c.cfg.SecurityProtocol)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Millisecond*time.Duration(c.DefaultTimeoutMs))
+		defer cancel()
+	}
+
+	brokers, err := c.fetchBrokers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error = ErrNoData
+	for _, b := range brokers {
+		addr := net.JoinHostPort(b.Host, fmt.Sprint(b.Port))
+
+		err := c.alterPartitionReassignmentsOn(ctx, addr, ra)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+
+		// Only try other brokers if this one isn't able to serve the request.
+		var perr ErrKafkaProtocol
+		if errors.As(err, &perr) && perr.Code != errCodeNotController {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to alter partition reassignments: %s", lastErr)
+}
+
+// alterPartitionReassignmentsOn submits the reassignments to the broker at
+// the address.
+func (c Client) alterPartitionReassignmentsOn(ctx context.Context, addr string, ra ReplicaAssignments) error {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	dl, _ := ctx.Deadline()
+	conn.SetDeadline(dl)
+
+	return alterPartitionReassignments(conn, time.Until(dl), ra)
+}
+
+// dial opens a connection to the broker at the address using the Client
+// security protocol.
+func (c Client) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if c.cfg.SecurityProtocol != "SSL" {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if c.cfg.SSLCALocation != "" {
+		pem, err := os.ReadFile(c.cfg.SSLCALocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA location: %s", err)
+		}
+		pool.AppendCertsFromPEM(pem)
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	d := tls.Dialer{Config: &tls.Config{RootCAs: pool, ServerName: host}}
+
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// listPartitionReassignments performs a v0 ListPartitionReassignments
+// request for all topics over the connection.
+func listPartitionReassignments(conn net.Conn, timeout time.Duration) (PartitionReassignments, error) {
+	const correlationID = 1
+
+	var e protocolEncoder
+	e.requestHeader(apiKeyListPartitionReassignments, 0, correlationID)
+	// Timeout in milliseconds.
+	e.int32(int32(timeout.Milliseconds()))
+	// A null topics array requests all reassignments.
+	e.uvarint(0)
+	e.emptyTaggedFields()
+
+	resp, err := roundTrip(conn, e.frame())
+	if err != nil {
+		return nil, err
+	}
+
+	d := &protocolDecoder{b: resp}
+	if err := d.responseHeader(correlationID); err != nil {
+		return nil, err
+	}
+
+	return decodeListPartitionReassignments(d)
+}
+
+// decodeListPartitionReassignments decodes a v0 ListPartitionReassignments
+// response body.
+func decodeListPartitionReassignments(d *protocolDecoder) (PartitionReassignments, error) {
+	// Throttle time.
+	d.int32()
+	code := d.int16()
+	msg := d.compactString()
+
+	if d.err == nil && code != 0 {
+		return nil, ErrKafkaProtocol{Code: code, Message: msg}
+	}
+
+	reassignments := PartitionReassignments{}
+
+	topics := d.compactLength()
+	for i := 0; i < topics && d.err == nil; i++ {
+		name := d.compactString()
+		partitions := d.compactLength()
+
+		for j := 0; j < partitions && d.err == nil; j++ {
+			id := d.int32()
+			p := PartitionReassignment{
+				Replicas:         d.compactInt32Array(),
+				AddingReplicas:   d.compactInt32Array(),
+				RemovingReplicas: d.compactInt32Array(),
+			}
+			d.skipTaggedFields()
+
+			if reassignments[name] == nil {
+				reassignments[name] = map[int32]PartitionReassignment{}
+			}
+			reassignments[name][id] = p
+		}
+
+		d.skipTaggedFields()
+	}
+
+	d.skipTaggedFields()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return reassignments, nil
+}
+
+// alterPartitionReassignments performs a v0 AlterPartitionReassignments
+// request over the connection. Topics and partitions are written in order.
+func alterPartitionReassignments(conn net.Conn, timeout time.Duration, ra ReplicaAssignments) error {
+	const correlationID = 1
+
+	var e protocolEncoder
+	e.requestHeader(apiKeyAlterPartitionReassignments, 0, correlationID)
+	// Timeout in milliseconds.
+	e.int32(int32(timeout.Milliseconds()))
+
+	var topics []string
+	for t := range ra {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	e.uvarint(uint64(len(topics) + 1))
+	for _, t := range topics {
+		e.compactString(t)
+
+		var ids []int32
+		for id := range ra[t] {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		e.uvarint(uint64(len(ids) + 1))
+		for _, id := range ids {
+			e.int32(id)
+			e.compactInt32Array(ra[t][id])
+			e.emptyTaggedFields()
+		}
+
+		e.emptyTaggedFields()
+	}
+
+	e.emptyTaggedFields()
+
+	resp, err := roundTrip(conn, e.frame())
+	if err != nil {
+		return err
+	}
+
+	d := &protocolDecoder{b: resp}
+	if err := d.responseHeader(correlationID); err != nil {
+		return err
+	}
+
+	return decodeAlterPartitionReassignments(d)
+}
+
+// decodeAlterPartitionReassignments decodes a v0 AlterPartitionReassignments
+// response body, returning the top level error, if any, or an error listing
+// the partitions that couldn't be reassigned.
+func decodeAlterPartitionReassignments(d *protocolDecoder) error {
+	// Throttle time.
+	d.int32()
+	code := d.int16()
+	msg := d.compactString()
+
+	if d.err == nil && code != 0 {
+		return ErrKafkaProtocol{Code: code, Message: msg}
+	}
+
+	var errStrings []string
+
+	topics := d.compactLength()
+	for i := 0; i < topics && d.err == nil; i++ {
+		name := d.compactString()
+		partitions := d.compactLength()
+
+		for j := 0; j < partitions && d.err == nil; j++ {
+			id := d.int32()
+			code := d.int16()
+			msg := d.compactString()
+			d.skipTaggedFields()
+
+			if code != 0 {
+				err := ErrKafkaProtocol{Code: code, Message: msg}
+				errStrings = append(errStrings, fmt.Sprintf("%s p%d: %s", name, id, err))
+			}
+		}
+
+		d.skipTaggedFields()
+	}
+
+	d.skipTaggedFields()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	if errStrings != nil {
+		return fmt.Errorf("failed to alter partition reassignments: %s", strings.Join(errStrings, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package kafkaadmin
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetReplicas(t *testing.T) {
+	p := PartitionReassignment{
+		Replicas:         []int32{1001, 1003, 1002},
+		AddingReplicas:   []int32{1001},
+		RemovingReplicas: []int32{1002},
+	}
+
+	assert.Equal(t, []int32{1001, 1003}, p.TargetReplicas())
+}
+
+// listPartitionReassignmentsResponse returns a v0 ListPartitionReassignments
+// response for the error code and a single partition reassignment.
+func listPartitionReassignmentsResponse(code int16, topic string, p PartitionReassignment) []byte {
+	var e protocolEncoder
+	// Response header.
+	e.int32(1)
+	e.emptyTaggedFields()
+	// Throttle time, error code and null error message.
+	e.int32(0)
+	e.int16(code)
+	e.uvarint(0)
+
+	compactArray := func(a []int32) {
+		e.uvarint(uint64(len(a) + 1))
+		for _, v := range a {
+			e.int32(v)
+		}
+	}
+
+	// One topic with one partition.
+	e.uvarint(2)
+	e.uvarint(uint64(len(topic) + 1))
+	e.b.WriteString(topic)
+	e.uvarint(2)
+	e.int32(3)
+	compactArray(p.Replicas)
+	compactArray(p.AddingReplicas)
+	compactArray(p.RemovingReplicas)
+	// A partition tagged field.
+	e.uvarint(1)
+	e.uvarint(0)
+	e.uvarint(2)
+	e.int16(0)
+	// Topic and response tagged fields.
+	e.emptyTaggedFields()
+	e.emptyTaggedFields()
+
+	return e.frame()
+}
+
+func TestListPartitionReassignments(t *testing.T) {
+	p := PartitionReassignment{
+		Replicas:         []int32{1001, 1003, 1002},
+		AddingReplicas:   []int32{1001},
+		RemovingReplicas: []int32{1002},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		// Read the request and check the API key and version.
+		var size int32
+		binary.Read(server, binary.BigEndian, &size)
+		req := make([]byte, size)
+		if _, err := io.ReadFull(server, req); err != nil {
+			return
+		}
+
+		assert.Equal(t, apiKeyListPartitionReassignments, int16(binary.BigEndian.Uint16(req[0:2])))
+		assert.Equal(t, int16(0), int16(binary.BigEndian.Uint16(req[2:4])))
+
+		server.Write(listPartitionReassignmentsResponse(0, "test_topic", p))
+	}()
+
+	r, err := listPartitionReassignments(client, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, PartitionReassignments{"test_topic": {3: p}}, r)
+}
+
+func TestDecodeListPartitionReassignmentsError(t *testing.T) {
+	resp := listPartitionReassignmentsResponse(errCodeNotController, "test_topic", PartitionReassignment{})
+
+	d := &protocolDecoder{b: resp[4:]}
+	assert.Nil(t, d.responseHeader(1))
+
+	_, err := decodeListPartitionReassignments(d)
+	assert.Equal(t, ErrKafkaProtocol{Code: errCodeNotController}, err)
+
+	// Truncated responses are an error.
+	resp = listPartitionReassignmentsResponse(0, "test_topic", PartitionReassignment{})
+	d = &protocolDecoder{b: resp[4 : len(resp)-8]}
+	assert.Nil(t, d.responseHeader(1))
+
+	_, err = decodeListPartitionReassignments(d)
+	assert.Equal(t, errShortBuffer, err)
+}
+
+// alterPartitionReassignmentsResponse returns a v0 AlterPartitionReassignments
+// response for the top level error code and a single partition error code.
+func alterPartitionReassignmentsResponse(code int16, topic string, partitionCode int16) []byte {
+	var e protocolEncoder
+	// Response header.
+	e.int32(1)
+	e.emptyTaggedFields()
+	// Throttle time, error code and null error message.
+	e.int32(0)
+	e.int16(code)
+	e.uvarint(0)
+
+	// One topic with one partition.
+	e.uvarint(2)
+	e.compactString(topic)
+	e.uvarint(2)
+	e.int32(3)
+	e.int16(partitionCode)
+	e.compactString("reassignment failed")
+	e.emptyTaggedFields()
+	// Topic and response tagged fields.
+	e.emptyTaggedFields()
+	e.emptyTaggedFields()
+
+	return e.frame()
+}
+
+func TestAlterPartitionReassignments(t *testing.T) {
+	ra := ReplicaAssignments{
+		"test_topic": {
+			3: {1001, 1003},
+			0: {1002, 1001},
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		// Read the request and check the API key, version and body.
+		var size int32
+		binary.Read(server, binary.BigEndian, &size)
+		req := make([]byte, size)
+		if _, err := io.ReadFull(server, req); err != nil {
+			return
+		}
+
+		assert.Equal(t, apiKeyAlterPartitionReassignments, int16(binary.BigEndian.Uint16(req[0:2])))
+		assert.Equal(t, int16(0), int16(binary.BigEndian.Uint16(req[2:4])))
+
+		// Skip the header: API key, version, correlation ID, client ID and
+		// tagged fields.
+		body := req[8+2+len(protocolClientID)+1:]
+
+		var e protocolEncoder
+		e.int32(1000)
+		e.uvarint(2)
+		e.compactString("test_topic")
+		e.uvarint(3)
+		e.int32(0)
+		e.compactInt32Array([]int32{1002, 1001})
+		e.emptyTaggedFields()
+		e.int32(3)
+		e.compactInt32Array([]int32{1001, 1003})
+		e.emptyTaggedFields()
+		e.emptyTaggedFields()
+		e.emptyTaggedFields()
+
+		assert.Equal(t, e.b.Bytes(), body)
+
+		server.Write(alterPartitionReassignmentsResponse(0, "test_topic", 0))
+	}()
+
+	err := alterPartitionReassignments(client, time.Second, ra)
+	assert.Nil(t, err)
+}
+
+func TestDecodeAlterPartitionReassignmentsError(t *testing.T) {
+	resp := alterPartitionReassignmentsResponse(errCodeNotController, "test_topic", 0)
+
+	d := &protocolDecoder{b: resp[4:]}
+	assert.Nil(t, d.responseHeader(1))
+	assert.Equal(t, ErrKafkaProtocol{Code: errCodeNotController}, decodeAlterPartitionReassignments(d))
+
+	// Partition errors are listed.
+	resp = alterPartitionReassignmentsResponse(0, "test_topic", 39)
+	d = &protocolDecoder{b: resp[4:]}
+	assert.Nil(t, d.responseHeader(1))
+
+	err := decodeAlterPartitionReassignments(d)
+	assert.EqualError(t, err, "failed to alter partition reassignments: test_topic p3: kafka error code 39: reassignment failed")
+
+	// Truncated responses are an error.
+	resp = alterPartitionReassignmentsResponse(0, "test_topic", 0)
+	d = &protocolDecoder{b: resp[4 : len(resp)-8]}
+	assert.Nil(t, d.responseHeader(1))
+	assert.Equal(t, errShortBuffer, decodeAlterPartitionReassignments(d))
+}
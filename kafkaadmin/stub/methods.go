@@ -46,6 +46,14 @@ func (s Client) UnderReplicatedTopics(ctx context.Context) (kafkaadmin.TopicStat
 	return nil, nil
 }
 
+func (s Client) ListPartitionReassignments(context.Context) (kafkaadmin.PartitionReassignments, error) {
+	return kafkaadmin.PartitionReassignments{}, nil
+}
+
+func (s Client) AlterPartitionReassignments(context.Context, kafkaadmin.ReplicaAssignments) error {
+	return nil
+}
+
 func (s Client) SetThrottle(context.Context, kafkaadmin.SetThrottleConfig) error {
 	return nil
 }
@@ -54,6 +62,14 @@ func (s Client) RemoveThrottle(context.Context, kafkaadmin.RemoveThrottleConfig)
 	return nil
 }
 
+func (s Client) SetClientQuotas(context.Context, kafkaadmin.ClientQuotas) error {
+	return nil
+}
+
+func (s Client) RemoveClientQuotas(context.Context, []kafkaadmin.QuotaEntity, []string) error {
+	return nil
+}
+
 func (s Client) ListBrokers(context.Context) ([]int, error) {
 	return nil, nil
 }
@@ -62,6 +78,10 @@ func (s Client) DescribeBrokers(context.Context, bool) (kafkaadmin.BrokerStates,
 	return s.brokerStates, nil
 }
 
+func (s Client) DescribeLogDirs(context.Context, []int) (kafkaadmin.LogDirs, error) {
+	return kafkaadmin.LogDirs{}, nil
+}
+
 func (s Client) GetConfigs(context.Context, string, []string) (kafkaadmin.ResourceConfigs, error) {
 	return nil, nil
 }
@@ -11,6 +11,7 @@ import (
 const (
 	brokerTXThrottleCfgName        = "leader.replication.throttled.rate"
 	brokerRXThrottleCfgName        = "follower.replication.throttled.rate"
+	brokerLogDirThrottleCfgName    = "replica.alter.log.dirs.io.max.bytes.per.second"
 	topicThrottledLeadersCfgName   = "leader.replication.throttled.replicas"
 	topicThrottledFollowersCfgName = "follower.replication.throttled.replicas"
 )
@@ -28,13 +29,18 @@ type SetThrottleConfig struct {
 type RemoveThrottleConfig struct {
 	Topics  []string
 	Brokers []int
+	// LogDirs removes the log dir throttles of the Brokers rather than their
+	// replication throttles.
+	LogDirs bool
 }
 
 // BrokerThrottleConfig defines an inbound and outbound throttle rate in bytes
-// to be applied to a broker.
+// to be applied to a broker, along with the throttle rate in bytes of replica
+// moves between the broker's log dirs.
 type BrokerThrottleConfig struct {
 	InboundLimitBytes  int
 	OutboundLimitBytes int
+	LogDirLimitBytes   int
 }
 
 // SetThrottle takes a SetThrottleConfig and sets the underlying throttle configs
@@ -146,7 +152,12 @@ func (c Client) RemoveThrottle(ctx context.Context, cfg RemoveThrottleConfig) er
 	}
 
 	// Update the broker configs to the desired new configs.
-	if err := clearBrokerThrottleConfigs(brokerDynamicConfigs); err != nil {
+	clearConfigs := clearBrokerThrottleConfigs
+	if cfg.LogDirs {
+		clearConfigs = clearBrokerLogDirThrottleConfigs
+	}
+
+	if err := clearConfigs(brokerDynamicConfigs); err != nil {
 		return ErrRemoveThrottle{Message: err.Error()}
 	}
 
@@ -264,6 +275,7 @@ func populateBrokerThrottleConfigs(brokers map[int]BrokerThrottleConfig, configs
 		id := strconv.Itoa(brokerID)
 		txRate := fmt.Sprintf("%d", throttleRates.OutboundLimitBytes)
 		rxRate := fmt.Sprintf("%d", throttleRates.InboundLimitBytes)
+		logDirRate := fmt.Sprintf("%d", throttleRates.LogDirLimitBytes)
 
 		// Write configs. We skip any zero configs which are interpreted as unset.
 		if throttleRates.OutboundLimitBytes != 0 {
@@ -278,6 +290,12 @@ func populateBrokerThrottleConfigs(brokers map[int]BrokerThrottleConfig, configs
 				return err
 			}
 		}
+		if throttleRates.LogDirLimitBytes != 0 {
+			err = configs.AddConfig(id, brokerLogDirThrottleCfgName, logDirRate)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -306,3 +324,19 @@ func clearBrokerThrottleConfigs(configs ResourceConfigs) error {
 
 	return nil
 }
+
+// clearBrokerLogDirThrottleConfigs takes a ResourceConfigs and searches for
+// brokers with a log dir throttle configuration. If the configuration exists,
+// it's cleared. Otherwise the broker is removed from the ResourceConfigs as a
+// configuration update does not need to be sent.
+func clearBrokerLogDirThrottleConfigs(configs ResourceConfigs) error {
+	for broker, config := range configs {
+		if _, hasLogDirCfg := config[brokerLogDirThrottleCfgName]; hasLogDirCfg {
+			delete(config, brokerLogDirThrottleCfgName)
+		} else {
+			delete(configs, broker)
+		}
+	}
+
+	return nil
+}
@@ -218,6 +218,26 @@ func TestPopulateBrokerThrottleConfigs(t *testing.T) {
 	}
 }
 
+func TestPopulateBrokerLogDirThrottleConfigs(t *testing.T) {
+	// Log dir throttles are set alongside any existing replication throttles.
+	input := ResourceConfigs{
+		"1001": map[string]string{
+			"leader.replication.throttled.rate": "2000",
+		},
+	}
+
+	expected := ResourceConfigs{
+		"1001": map[string]string{
+			"leader.replication.throttled.rate":              "2000",
+			"replica.alter.log.dirs.io.max.bytes.per.second": "5000",
+		},
+	}
+
+	err := populateBrokerThrottleConfigs(map[int]BrokerThrottleConfig{1001: {LogDirLimitBytes: 5000}}, input)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, input)
+}
+
 func TestClearBrokerThrottleConfigs(t *testing.T) {
 	tests := []struct {
 		input       ResourceConfigs
@@ -270,3 +290,27 @@ func TestClearBrokerThrottleConfigs(t *testing.T) {
 		assert.Equalf(t, testCase.expected, testCase.input, fmt.Sprintf("case %d", i))
 	}
 }
+
+func TestClearBrokerLogDirThrottleConfigs(t *testing.T) {
+	// Replication throttles are retained. Brokers without log dir throttles
+	// are excluded.
+	input := ResourceConfigs{
+		"1001": map[string]string{
+			"leader.replication.throttled.rate":              "2000",
+			"replica.alter.log.dirs.io.max.bytes.per.second": "5000",
+		},
+		"1002": map[string]string{
+			"follower.replication.throttled.rate": "4000",
+		},
+	}
+
+	expected := ResourceConfigs{
+		"1001": map[string]string{
+			"leader.replication.throttled.rate": "2000",
+		},
+	}
+
+	err := clearBrokerLogDirThrottleConfigs(input)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, input)
+}
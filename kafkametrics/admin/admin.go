@@ -0,0 +1,248 @@
+// Package admin implements a kafkametrics Handler that derives broker
+// metrics from the Kafka cluster itself.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Admin is the Kafka admin functionality required by the Handler. It's
+// satisfied by a kafkaadmin.KafkaAdmin.
+type Admin interface {
+	DescribeBrokers(context.Context, bool) (kafkaadmin.BrokerStates, error)
+	DescribeTopics(context.Context, []string) (kafkaadmin.TopicStates, error)
+	DescribeLogDirs(context.Context, []int) (kafkaadmin.LogDirs, error)
+}
+
+// topicPartition identifies a partition.
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// replicaSizes maps broker IDs to the sizes, in bytes, of the partition
+// replicas hosted by each broker.
+type replicaSizes map[int]map[topicPartition]int64
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Admin is used to fetch broker and partition metadata and replica sizes.
+	Admin Admin `json:"-"`
+	// InstanceType is the instance type applied to all brokers.
+	InstanceType string
+	// Timeout is the timeout in seconds for admin requests. Defaults to 30.
+	Timeout int
+}
+
+// sample is a point in time set of replica sizes.
+type sample struct {
+	ts    time.Time
+	sizes replicaSizes
+}
+
+type adminHandler struct {
+	admin        Admin
+	instanceType string
+	timeout      time.Duration
+
+	sync.Mutex
+	last *sample
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or connectivity validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	if c.Admin == nil {
+		return nil, errors.New("admin client must be specified")
+	}
+
+	h := &adminHandler{
+		admin:        c.Admin,
+		instanceType: c.InstanceType,
+		timeout:      30 * time.Second,
+	}
+
+	if c.Timeout > 0 {
+		h.timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	// Validate; the initial request also records the first sample.
+	if _, errs := h.GetMetrics(); errs != nil {
+		for _, err := range errs {
+			if _, ok := err.(*kafkametrics.APIError); ok {
+				return nil, err
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent isn't supported; Kafka doesn't have an event API, so events
+// are discarded.
+func (h *adminHandler) PostEvent(*kafkametrics.Event) error {
+	return nil
+}
+
+// PostEventContext isn't supported; see PostEvent.
+func (h *adminHandler) PostEventContext(context.Context, *kafkametrics.Event) error {
+	return nil
+}
+
+// GetMetrics returns a BrokerMetrics with broker throughput derived from the
+// growth of replica sizes since the previous call and DiskUsed from the
+// current replica sizes. A NoResults error is returned on the first call.
+//
+// Throughput is an approximation, as Kafka doesn't report network traffic:
+// NetRX is the growth rate of all replicas hosted by a broker, while NetTX is
+// the growth rate of the partitions a broker leads multiplied by the number
+// of followers, i.e. outbound replication. Consumer fetches, protocol
+// overhead and data removed by retention or compaction between samples
+// aren't accounted for; partitions that shrank between samples are excluded
+// entirely. The values are best suited to sizing replication throttles, not
+// to reflect NIC utilization.
+func (h *adminHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *adminHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	h.Lock()
+	defer h.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	brokers, err := h.admin.DescribeBrokers(ctx, false)
+	if err != nil {
+		return nil, []error{&kafkametrics.APIError{
+			Request: "describe brokers",
+			Message: err.Error(),
+		}}
+	}
+
+	topics, err := h.admin.DescribeTopics(ctx, []string{".*"})
+	if err != nil {
+		return nil, []error{&kafkametrics.APIError{
+			Request: "describe topics",
+			Message: err.Error(),
+		}}
+	}
+
+	var ids []int
+	for id := range brokers {
+		ids = append(ids, id)
+	}
+
+	logDirs, err := h.admin.DescribeLogDirs(ctx, ids)
+	if err != nil {
+		return nil, []error{&kafkametrics.APIError{
+			Request: "describe log dirs",
+			Message: err.Error(),
+		}}
+	}
+
+	sizes, used := sumLogDirs(logDirs)
+
+	current := &sample{ts: time.Now(), sizes: sizes}
+	last := h.last
+	h.last = current
+
+	if last == nil {
+		return nil, []error{&kafkametrics.NoResults{
+			Message: "Insufficient samples; replica sizes are compared across intervals",
+		}}
+	}
+
+	elapsed := current.ts.Sub(last.ts).Seconds()
+
+	bm := kafkametrics.BrokerMetrics{}
+	for id, state := range brokers {
+		bm[id] = &kafkametrics.Broker{
+			ID:           id,
+			Host:         state.Host,
+			InstanceType: h.instanceType,
+			DiskUsed:     used[id],
+		}
+	}
+
+	var errors []error
+
+	for id, replicas := range sizes {
+		b, exists := bm[id]
+		if !exists {
+			continue
+		}
+
+		for tp, size := range replicas {
+			growth, ok := replicaGrowth(last.sizes, id, tp, size)
+			if !ok {
+				continue
+			}
+
+			rate := growth / elapsed / 1024 / 1024
+			b.NetRX += rate
+
+			// Leaders transmit to each follower.
+			p, ok := topics[tp.topic].PartitionStates[tp.partition]
+			if ok && int(p.Leader) == id && len(p.Replicas) > 1 {
+				b.NetTX += rate * float64(len(p.Replicas)-1)
+			}
+		}
+	}
+
+	for id := range bm {
+		if _, exists := sizes[id]; !exists {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No log dirs for broker %d", id),
+			})
+			delete(bm, id)
+		}
+	}
+
+	return bm, errors
+}
+
+// sumLogDirs returns the size of each replica hosted by each broker and the
+// total bytes used across the log dirs of each broker. Future replicas, i.e.
+// replicas being moved between log dirs of a broker, count towards the bytes
+// used but not the replica size.
+func sumLogDirs(logDirs kafkaadmin.LogDirs) (replicaSizes, map[int]float64) {
+	sizes := replicaSizes{}
+	used := map[int]float64{}
+
+	for id, dirs := range logDirs {
+		sizes[id] = map[topicPartition]int64{}
+
+		for _, replicas := range dirs {
+			for _, r := range replicas {
+				used[id] += float64(r.Size)
+				if !r.Future {
+					sizes[id][topicPartition{r.Topic, int(r.Partition)}] += r.Size
+				}
+			}
+		}
+	}
+
+	return sizes, used
+}
+
+// replicaGrowth returns the size increase of the broker's replica since
+// the previous sample. False is returned if the replica wasn't previously
+// sampled or shrank.
+func replicaGrowth(prev replicaSizes, id int, tp topicPartition, size int64) (float64, bool) {
+	prevSize, exists := prev[id][tp]
+	if !exists || size < prevSize {
+		return 0, false
+	}
+
+	return float64(size - prevSize), true
+}
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkaadmin"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// stubAdmin returns replica sizes that grow by 1MB per DescribeLogDirs call,
+// except for broker 1003 which has no log dirs.
+type stubAdmin struct {
+	calls int64
+}
+
+func (*stubAdmin) DescribeBrokers(context.Context, bool) (kafkaadmin.BrokerStates, error) {
+	return kafkaadmin.BrokerStates{
+		1001: {Host: "kafka-0"},
+		1002: {Host: "kafka-1"},
+		1003: {Host: "kafka-2"},
+	}, nil
+}
+
+func (*stubAdmin) DescribeTopics(context.Context, []string) (kafkaadmin.TopicStates, error) {
+	ts := kafkaadmin.NewTopicStates()
+
+	t := kafkaadmin.NewTopicState("test")
+	t.PartitionStates[0] = kafkaadmin.PartitionState{ID: 0, Leader: 1001, Replicas: []int32{1001, 1002}}
+	t.PartitionStates[1] = kafkaadmin.PartitionState{ID: 1, Leader: 1002, Replicas: []int32{1002, 1001}}
+	ts["test"] = t
+
+	return ts, nil
+}
+
+func (s *stubAdmin) DescribeLogDirs(context.Context, []int) (kafkaadmin.LogDirs, error) {
+	s.calls++
+	size := s.calls * 1048576
+
+	replicas := []kafkaadmin.ReplicaSize{
+		{Topic: "test", Partition: 0, Size: size},
+		{Topic: "test", Partition: 1, Size: size},
+	}
+
+	return kafkaadmin.LogDirs{
+		1001: {"/data": replicas},
+		1002: {"/data": replicas},
+	}, nil
+}
+
+func TestGetMetrics(t *testing.T) {
+	h, err := NewHandler(&Config{
+		Admin:        &stubAdmin{},
+		InstanceType: "stub",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ah := h.(*adminHandler)
+	ah.last.ts = ah.last.ts.Add(-time.Second)
+
+	bm, errs := h.GetMetrics()
+
+	// Broker 1003 has no log dirs.
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %s\n", len(errs), errs)
+	}
+
+	if _, ok := errs[0].(*kafkametrics.PartialResults); !ok {
+		t.Errorf("Expected *kafkametrics.PartialResults, got %T\n", errs[0])
+	}
+
+	if len(bm) != 2 {
+		t.Fatalf("Expected BrokerMetrics len 2, got %d\n", len(bm))
+	}
+
+	for _, id := range []int{1001, 1002} {
+		b := bm[id]
+
+		if b.InstanceType != "stub" {
+			t.Errorf("Unexpected instance type %s\n", b.InstanceType)
+		}
+
+		// Each broker hosts two replicas growing ~1MB/s and leads one
+		// partition with one follower.
+		if b.NetRX < 1.9 || b.NetRX > 2.0 || b.NetTX < 0.95 || b.NetTX > 1.0 {
+			t.Errorf("Unexpected NetTX/NetRX %.2f/%.2f\n", b.NetTX, b.NetRX)
+		}
+
+		if b.DiskUsed != 4194304 {
+			t.Errorf("Expected DiskUsed 4194304, got %.0f\n", b.DiskUsed)
+		}
+	}
+}
+
+func TestSumLogDirs(t *testing.T) {
+	logDirs := kafkaadmin.LogDirs{
+		1001: {
+			"/data1": {{Topic: "test", Partition: 0, Size: 100}},
+			"/data2": {
+				{Topic: "test", Partition: 1, Size: 200},
+				// Being moved from /data1.
+				{Topic: "test", Partition: 0, Size: 50, Future: true},
+			},
+		},
+	}
+
+	sizes, used := sumLogDirs(logDirs)
+
+	expected := replicaSizes{1001: {{"test", 0}: 100, {"test", 1}: 200}}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Errorf("Expected sizes %v, got %v\n", expected, sizes)
+	}
+
+	if used[1001] != 350 {
+		t.Errorf("Expected 350 bytes used, got %.0f\n", used[1001])
+	}
+}
+
+func TestReplicaGrowth(t *testing.T) {
+	prev := replicaSizes{1001: {{"test", 0}: 100}}
+
+	if g, ok := replicaGrowth(prev, 1001, topicPartition{"test", 0}, 150); !ok || g != 50 {
+		t.Errorf("Expected growth 50, got %f\n", g)
+	}
+
+	// Shrunk.
+	if _, ok := replicaGrowth(prev, 1001, topicPartition{"test", 0}, 50); ok {
+		t.Error("Expected shrunk replica to be excluded")
+	}
+
+	// Not previously sampled.
+	if _, ok := replicaGrowth(prev, 1002, topicPartition{"test", 0}, 50); ok {
+		t.Error("Expected unsampled replica to be excluded")
+	}
+}
@@ -0,0 +1,94 @@
+package kafkametrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Aggregation describes how the points of a metrics window are reduced
+// to a single value: "avg", "max" or a percentile such as "p95" or "p99".
+type Aggregation string
+
+const (
+	// AggregationAvg is the average of all points.
+	AggregationAvg Aggregation = "avg"
+	// AggregationMax is the maximum of all points.
+	AggregationMax Aggregation = "max"
+	// AggregationP95 is the 95th percentile of all points.
+	AggregationP95 Aggregation = "p95"
+	// AggregationP99 is the 99th percentile of all points.
+	AggregationP99 Aggregation = "p99"
+)
+
+// ParseAggregation returns the Aggregation for the name "avg", "max" or
+// "p<percentile>", where the percentile is > 0 and <= 100, e.g. "p99.9".
+func ParseAggregation(s string) (Aggregation, error) {
+	a := Aggregation(s)
+
+	switch a {
+	case AggregationAvg, AggregationMax:
+		return a, nil
+	}
+
+	if _, ok := a.percentile(); ok {
+		return a, nil
+	}
+
+	return "", fmt.Errorf("unknown aggregation: %s", s)
+}
+
+// percentile returns the percentile of a "p<percentile>" Aggregation.
+func (a Aggregation) percentile() (float64, bool) {
+	if !strings.HasPrefix(string(a), "p") {
+		return 0, false
+	}
+
+	p, err := strconv.ParseFloat(string(a)[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, false
+	}
+
+	return p, true
+}
+
+// Apply reduces the values to a single value with the Aggregation,
+// ignoring NaN and infinite values. Percentiles are interpolated between the
+// closest ranks. Whether any valid values were found is returned.
+func (a Aggregation) Apply(values []float64) (float64, bool) {
+	var vs []float64
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			vs = append(vs, v)
+		}
+	}
+
+	if len(vs) == 0 {
+		return 0, false
+	}
+
+	if a == AggregationMax {
+		a = "p100"
+	}
+
+	p, ok := a.percentile()
+	if !ok {
+		// Default to the average.
+		var sum float64
+		for _, v := range vs {
+			sum += v
+		}
+
+		return sum / float64(len(vs)), true
+	}
+
+	sort.Float64s(vs)
+
+	rank := p / 100 * float64(len(vs)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return vs[lower] + (vs[upper]-vs[lower])*(rank-float64(lower)), true
+}
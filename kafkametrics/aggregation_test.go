@@ -0,0 +1,52 @@
+package kafkametrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAggregation(t *testing.T) {
+	for _, s := range []string{"avg", "max", "p95", "p99", "p99.9", "p100"} {
+		if a, err := ParseAggregation(s); err != nil || string(a) != s {
+			t.Errorf("Expected aggregation %s, got %s, %v\n", s, a, err)
+		}
+	}
+
+	for _, s := range []string{"", "sum", "p0", "p101", "px"} {
+		if _, err := ParseAggregation(s); err == nil {
+			t.Errorf("Expected error for aggregation %q\n", s)
+		}
+	}
+}
+
+func TestAggregationApply(t *testing.T) {
+	// 100 through 1, with invalid values.
+	var values []float64
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+	values = append(values, math.NaN(), math.Inf(1))
+
+	expected := map[Aggregation]float64{
+		AggregationAvg: 50.5,
+		AggregationMax: 100,
+		AggregationP95: 95.05,
+		AggregationP99: 99.01,
+		"p50":          50.5,
+	}
+
+	for a, e := range expected {
+		v, ok := a.Apply(values)
+		if !ok || math.Abs(v-e) > 0.0001 {
+			t.Errorf("[%s] Expected %f, got %f\n", a, e, v)
+		}
+	}
+
+	if v, ok := AggregationP99.Apply([]float64{42}); !ok || v != 42 {
+		t.Errorf("Expected 42, got %f\n", v)
+	}
+
+	if _, ok := AggregationAvg.Apply([]float64{math.NaN()}); ok {
+		t.Error("Expected no valid values")
+	}
+}
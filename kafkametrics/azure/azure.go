@@ -0,0 +1,284 @@
+// Package azure implements
+// a kafkametrics Handler.
+package azure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Azure AD service principal credentials. If unset, the instance
+	// managed identity is used.
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// ResourceIDs is the list of virtual machine resource IDs of the
+	// reference Kafka brokers.
+	// Example: "/subscriptions/<id>/resourceGroups/kafka/providers/Microsoft.Compute/virtualMachines/kafka-0"
+	ResourceIDs []string
+	// NetworkTXMetric is the name of the outbound network metric. Defaults
+	// to "Network Out Total".
+	NetworkTXMetric string
+	// NetworkRXMetric is the name of the inbound network metric. Defaults
+	// to "Network In Total".
+	NetworkRXMetric string
+	// Aggregation is the metric aggregation type. Defaults to "Total".
+	// Values for the "Total" aggregation are divided by the Interval to
+	// yield a per-second rate.
+	Aggregation string
+	// Interval is the metric time grain in seconds. Defaults to 60.
+	Interval int
+	// BrokerIDTag is the VM resource tag name for Kafka broker IDs.
+	BrokerIDTag string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// WorkspaceID and SharedKey are optional Log Analytics workspace
+	// credentials that events are written to.
+	WorkspaceID string
+	SharedKey   string
+	// LogType is the Log Analytics custom log type for events. Defaults to
+	// "KafkaKitEvents".
+	LogType string
+}
+
+type azureHandler struct {
+	c             *azureClient
+	resourceIDs   []string
+	metrics       []string
+	aggregation   string
+	interval      int
+	brokerIDTag   string
+	metricsWindow int
+	workspaceID   string
+	sharedKey     string
+	logType       string
+	vmCache       map[string]*virtualMachine
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case len(c.ResourceIDs) == 0:
+		return nil, errors.New("resource IDs must be specified")
+	case c.BrokerIDTag == "":
+		return nil, errors.New("broker ID tag must be specified")
+	case c.ClientID != "" && (c.TenantID == "" || c.ClientSecret == ""):
+		return nil, errors.New("tenant ID and client secret must be specified with a client ID")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	h := &azureHandler{
+		c: &azureClient{
+			c: client,
+			tokens: &tokenSource{
+				c:            client,
+				tenantID:     c.TenantID,
+				clientID:     c.ClientID,
+				clientSecret: c.ClientSecret,
+				loginURL:     loginURL,
+				imdsURL:      imdsTokenURL,
+			},
+			armURL:  armURL,
+			logsURL: fmt.Sprintf(logsURLFormat, c.WorkspaceID),
+		},
+		resourceIDs:   c.ResourceIDs,
+		metrics:       []string{"Network Out Total", "Network In Total"},
+		aggregation:   "Total",
+		interval:      60,
+		brokerIDTag:   c.BrokerIDTag,
+		metricsWindow: c.MetricsWindow,
+		workspaceID:   c.WorkspaceID,
+		sharedKey:     c.SharedKey,
+		logType:       "KafkaKitEvents",
+		vmCache:       make(map[string]*virtualMachine),
+	}
+
+	if c.NetworkTXMetric != "" {
+		h.metrics[0] = c.NetworkTXMetric
+	}
+
+	if c.NetworkRXMetric != "" {
+		h.metrics[1] = c.NetworkRXMetric
+	}
+
+	if c.Aggregation != "" {
+		h.aggregation = c.Aggregation
+	}
+
+	if c.Interval > 0 {
+		h.interval = c.Interval
+	}
+
+	if c.LogType != "" {
+		h.logType = c.LogType
+	}
+
+	// Validate.
+	if _, err := h.c.tokens.get(context.Background()); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent writes an event to the configured Log Analytics workspace. If
+// no workspace is configured, the event is discarded.
+func (h *azureHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *azureHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.workspaceID == "" {
+		return nil
+	}
+
+	record := map[string]interface{}{
+		"Title": e.Title,
+		"Text":  e.Text,
+		"Tags":  e.Tags,
+	}
+
+	if err := h.c.postLogRecord(ctx, h.workspaceID, h.sharedKey, h.logType, record); err != nil {
+		return &kafkametrics.APIError{
+			Request: "post log record",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the Azure Monitor
+// API and returns a BrokerMetrics. If any errors are encountered (i.e.
+// complete metadata for a given broker can't be retrieved), the broker will
+// not be included in the BrokerMetrics.
+func (h *azureHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *azureHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	var missingTags bytes.Buffer
+	bm := kafkametrics.BrokerMetrics{}
+
+	end := time.Now()
+	start := end.Add(-time.Duration(h.metricsWindow) * time.Second)
+
+	for _, id := range h.resourceIDs {
+		vm, err := h.getVirtualMachine(ctx, id)
+		if err != nil {
+			errors = append(errors, &kafkametrics.APIError{
+				Request: "virtual machine",
+				Message: fmt.Sprintf("Error requesting virtual machine %s: %s", id, err),
+			})
+			continue
+		}
+
+		brokerID, err := strconv.Atoi(vm.Tags[h.brokerIDTag])
+		if err != nil {
+			missingTags.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDTag, vm.Name))
+			continue
+		}
+
+		b := &kafkametrics.Broker{
+			ID:           brokerID,
+			Host:         vm.Name,
+			InstanceType: vm.Properties.HardwareProfile.VMSize,
+		}
+
+		// Get network metrics for tx and rx.
+		var complete = true
+		for i, metric := range h.metrics {
+			values, err := h.c.getMetricValues(ctx, id, metric, h.aggregation, h.interval, start, end)
+			if err != nil {
+				return nil, []error{&kafkametrics.APIError{
+					Request: "metrics query",
+					Message: err.Error(),
+				}}
+			}
+
+			if len(values) == 0 {
+				errors = append(errors, &kafkametrics.PartialResults{
+					Message: fmt.Sprintf("No points for host %s", vm.Name),
+				})
+				complete = false
+				break
+			}
+
+			var sum float64
+			for _, v := range values {
+				sum += v
+			}
+			avg := sum / float64(len(values))
+
+			if h.aggregation == "Total" {
+				avg = avg / float64(h.interval)
+			}
+
+			switch i {
+			case 0:
+				b.NetTX = avg / 1024 / 1024
+			case 1:
+				b.NetRX = avg / 1024 / 1024
+			}
+		}
+
+		if complete {
+			bm[brokerID] = b
+		}
+	}
+
+	if missingTags.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing resource tags:%s", missingTags.String()),
+		})
+	}
+
+	if len(bm) == 0 && errors == nil {
+		return nil, []error{&kafkametrics.NoResults{
+			Message: "No data returned for the configured resources",
+		}}
+	}
+
+	return bm, errors
+}
+
+// getVirtualMachine returns the VM metadata for a resource ID. Metadata for
+// VMs with a broker ID tag is cached.
+func (h *azureHandler) getVirtualMachine(ctx context.Context, id string) (*virtualMachine, error) {
+	if vm, cached := h.vmCache[id]; cached {
+		return vm, nil
+	}
+
+	vm, err := h.c.getVirtualMachine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := vm.Tags[h.brokerIDTag]; ok {
+		h.vmCache[id] = vm
+	}
+
+	return vm, nil
+}
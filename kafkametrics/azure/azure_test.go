@@ -0,0 +1,250 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestGetMetricValues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			if req.Header.Get("Metadata") != "true" {
+				t.Error("Expected IMDS metadata header")
+			}
+			// IMDS encodes expires_in as a string.
+			fmt.Fprint(w, `{"access_token":"token","expires_in":"3599","token_type":"Bearer"}`)
+			return
+		}
+
+		q := req.URL.Query()
+		if req.URL.Path != "/vms/kafka-0/providers/microsoft.insights/metrics" || q.Get("metricnames") != "Network Out Total" ||
+			q.Get("aggregation") != "Total" || q.Get("interval") != "PT1M" || q.Get("api-version") != metricsAPI {
+			t.Errorf("Unexpected request %s\n", req.URL)
+		}
+
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected authorization %s\n", req.Header.Get("Authorization"))
+		}
+
+		// Intervals without data have no aggregation value.
+		fmt.Fprint(w, `{"value":[{"name":{"value":"Network Out Total"},"timeseries":[{"data":[`+
+			`{"timeStamp":"2020-01-01T00:00:00Z","total":125829120},`+
+			`{"timeStamp":"2020-01-01T00:01:00Z"},`+
+			`{"timeStamp":"2020-01-01T00:02:00Z","total":377487360}]}]}]}`)
+	}))
+	defer srv.Close()
+
+	a := &azureClient{c: srv.Client(), tokens: &tokenSource{c: srv.Client(), imdsURL: srv.URL + "/token"}, armURL: srv.URL}
+
+	end := time.Now()
+	values, err := a.getMetricValues(context.Background(), "/vms/kafka-0", "Network Out Total", "Total", 60, end.Add(-3*time.Minute), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 2 || values[0] != 125829120 || values[1] != 377487360 {
+		t.Errorf("Unexpected values %v\n", values)
+	}
+
+	if time.Until(a.tokens.expiry) < 59*time.Minute {
+		t.Errorf("Unexpected token expiry %s\n", a.tokens.expiry)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// ARM error codes are returned.
+		{
+			http.StatusUnauthorized,
+			`{"error":{"code":"InvalidAuthenticationToken","message":"The access token is invalid."}}`,
+			"InvalidAuthenticationToken: The access token is invalid.",
+		},
+		{
+			http.StatusNotFound,
+			`{"error":{"code":"ResourceNotFound","message":"The Resource 'kafka-9' was not found."}}`,
+			"ResourceNotFound: The Resource 'kafka-9' was not found.",
+		},
+		{http.StatusBadGateway, "Bad Gateway", "unexpected status 502"},
+		{http.StatusOK, "<html></html>", "invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		// A cached token.
+		a := &azureClient{c: srv.Client(), tokens: &tokenSource{token: "token", expiry: time.Now().Add(time.Hour)}, armURL: srv.URL}
+
+		if _, err := a.getVirtualMachine(context.Background(), "/vms/kafka-9"); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		if req.URL.Path != "/tenant/oauth2/v2.0/token" || req.Form.Get("grant_type") != "client_credentials" || req.Form.Get("client_id") != "client" {
+			t.Errorf("Unexpected request %s %v\n", req.URL, req.Form)
+		}
+
+		if req.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+
+		// The v2.0 endpoint encodes expires_in as a number.
+		fmt.Fprint(w, `{"access_token":"token","expires_in":3599,"token_type":"Bearer"}`)
+	}))
+	defer srv.Close()
+
+	ts := &tokenSource{c: srv.Client(), tenantID: "tenant", clientID: "client", clientSecret: "wrong", loginURL: srv.URL}
+
+	if _, err := ts.get(context.Background()); err == nil || err.Error() != "error fetching token: status 401" {
+		t.Errorf("Unexpected error %v\n", err)
+	}
+
+	ts.clientSecret = "secret"
+
+	token, err := ts.get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "token" || time.Until(ts.expiry) < 59*time.Minute {
+		t.Errorf("Unexpected token %s expiring at %s\n", token, ts.expiry)
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/vms/kafka-0":
+			fmt.Fprint(w, `{"name":"kafka-0","tags":{"broker_id":"1000"},"properties":{"hardwareProfile":{"vmSize":"Standard_D4s_v3"}}}`)
+		case "/vms/kafka-1":
+			fmt.Fprint(w, `{"name":"kafka-1","tags":{"broker_id":"1001"},"properties":{"hardwareProfile":{"vmSize":"Standard_D4s_v3"}}}`)
+		case "/vms/kafka-9":
+			fmt.Fprint(w, `{"name":"kafka-9","tags":{},"properties":{"hardwareProfile":{"vmSize":"Standard_D4s_v3"}}}`)
+		case "/vms/kafka-0/providers/microsoft.insights/metrics":
+			fmt.Fprint(w, `{"value":[{"timeseries":[{"data":[{"total":125829120},{"total":377487360}]}]}]}`)
+		default:
+			fmt.Fprint(w, `{"value":[{"timeseries":[]}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := &azureHandler{
+		c:             &azureClient{c: srv.Client(), tokens: &tokenSource{token: "token", expiry: time.Now().Add(time.Hour)}, armURL: srv.URL},
+		resourceIDs:   []string{"/vms/kafka-0", "/vms/kafka-1", "/vms/kafka-9"},
+		metrics:       []string{"Network Out Total", "Network In Total"},
+		aggregation:   "Total",
+		interval:      60,
+		brokerIDTag:   "broker_id",
+		metricsWindow: 120,
+		vmCache:       make(map[string]*virtualMachine),
+	}
+
+	// Totals are per interval.
+	bm, errs := h.GetMetrics()
+
+	if b := bm[1000]; len(bm) != 1 || b == nil || b.NetTX != 4.00 || b.NetRX != 4.00 || b.Host != "kafka-0" || b.InstanceType != "Standard_D4s_v3" {
+		t.Errorf("Unexpected results %+v\n", bm[1000])
+	}
+
+	expected := []string{"No points for host kafka-1", "Missing resource tags: broker_id:kafka-9"}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+
+	// VMs without a broker ID tag aren't cached.
+	if _, cached := h.vmCache["/vms/kafka-9"]; cached || len(h.vmCache) != 2 {
+		t.Errorf("Unexpected VM cache %v\n", h.vmCache)
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("key"))
+	records := make(chan map[string]interface{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Log-Type") != "KafkaKitEvents" || !strings.HasPrefix(req.Header.Get("Authorization"), "SharedKey workspace:") {
+			t.Errorf("Unexpected headers %v\n", req.Header)
+		}
+
+		var r []map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&r)
+		records <- r[0]
+	}))
+	defer srv.Close()
+
+	h := &azureHandler{
+		c:           &azureClient{c: srv.Client(), logsURL: srv.URL},
+		workspaceID: "workspace",
+		sharedKey:   key,
+		logType:     "KafkaKitEvents",
+	}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-records
+	if r["Title"] != "title" || r["Text"] != "text" {
+		t.Errorf("Unexpected record %v\n", r)
+	}
+}
+
+func TestIsoDuration(t *testing.T) {
+	for s, expected := range map[int]string{30: "PT30S", 60: "PT1M", 300: "PT5M", 3600: "PT1H"} {
+		if got := isoDuration(s); got != expected {
+			t.Errorf("Expected %s, got %s\n", expected, got)
+		}
+	}
+}
+
+func TestLogAnalyticsSignature(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("key"))
+
+	sig, err := logAnalyticsSignature(key, "Mon, 02 Jan 2006 15:04:05 GMT", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(sig); err != nil {
+		t.Errorf("Expected base64 signature: %s\n", err)
+	}
+
+	if _, err := logAnalyticsSignature("not base64!", "", 0); err == nil {
+		t.Error("Expected error")
+	}
+}
@@ -0,0 +1,291 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	armURL        = "https://management.azure.com"
+	loginURL      = "https://login.microsoftonline.com"
+	imdsTokenURL  = "http://169.254.169.254/metadata/identity/oauth2/token"
+	metricsAPI    = "2018-01-01"
+	computeAPI    = "2023-03-01"
+	logsAPI       = "2016-04-01"
+	armResource   = "https://management.azure.com/"
+	logsURLFormat = "https://%s.ods.opinsights.azure.com"
+)
+
+// tokenSource provides ARM access tokens using either the client
+// credentials flow or the instance managed identity.
+type tokenSource struct {
+	sync.Mutex
+	c            *http.Client
+	tenantID     string
+	clientID     string
+	clientSecret string
+	loginURL     string
+	imdsURL      string
+	token        string
+	expiry       time.Time
+}
+
+// get returns a valid access token.
+func (t *tokenSource) get(ctx context.Context) (string, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	// Refresh a minute ahead of the expiry.
+	if t.token != "" && time.Now().Add(time.Minute).Before(t.expiry) {
+		return t.token, nil
+	}
+
+	var req *http.Request
+	var err error
+
+	if t.clientID != "" {
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", t.clientID)
+		form.Set("client_secret", t.clientSecret)
+		form.Set("scope", armResource+".default")
+
+		u := fmt.Sprintf("%s/%s/oauth2/v2.0/token", t.loginURL, t.tenantID)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		params := url.Values{}
+		params.Set("api-version", "2018-02-01")
+		params.Set("resource", armResource)
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, t.imdsURL+"?"+params.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	resp, err := t.c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching token: status %d", resp.StatusCode)
+	}
+
+	// IMDS encodes expires_in as a string, the v2.0 endpoint as a number.
+	var r struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", err
+	}
+
+	expiresIn, _ := r.ExpiresIn.Int64()
+
+	t.token = r.AccessToken
+	t.expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	return t.token, nil
+}
+
+// azureClient is a minimal client for the Azure Resource Manager and Log
+// Analytics APIs.
+type azureClient struct {
+	c       *http.Client
+	tokens  *tokenSource
+	armURL  string
+	logsURL string
+}
+
+// get issues an authenticated ARM GET request and unmarshals the JSON
+// response into v.
+func (a *azureClient) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s?%s", a.armURL, path, params.Encode()), nil)
+	if err != nil {
+		return err
+	}
+
+	token, err := a.tokens.get(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := a.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(d, &e) == nil && e.Error.Code != "" {
+			return fmt.Errorf("%s: %s", e.Error.Code, e.Error.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(d, v)
+}
+
+// virtualMachine holds the VM metadata of interest.
+type virtualMachine struct {
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		HardwareProfile struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+	} `json:"properties"`
+}
+
+// getVirtualMachine fetches the VM metadata for a resource ID.
+func (a *azureClient) getVirtualMachine(ctx context.Context, resourceID string) (*virtualMachine, error) {
+	params := url.Values{}
+	params.Set("api-version", computeAPI)
+
+	vm := &virtualMachine{}
+	if err := a.get(ctx, resourceID, params, vm); err != nil {
+		return nil, err
+	}
+
+	return vm, nil
+}
+
+type metricsResponse struct {
+	Value []struct {
+		Timeseries []struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"value"`
+}
+
+// getMetricValues returns all values for the metric and aggregation type
+// over the start and end time at the specified interval.
+func (a *azureClient) getMetricValues(ctx context.Context, resourceID, metric, aggregation string, interval int, start, end time.Time) ([]float64, error) {
+	params := url.Values{}
+	params.Set("api-version", metricsAPI)
+	params.Set("metricnames", metric)
+	params.Set("aggregation", aggregation)
+	params.Set("interval", isoDuration(interval))
+	params.Set("timespan", fmt.Sprintf("%s/%s", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)))
+
+	var r metricsResponse
+	if err := a.get(ctx, resourceID+"/providers/microsoft.insights/metrics", params, &r); err != nil {
+		return nil, err
+	}
+
+	// Data points are keyed by the lower camel case aggregation name.
+	key := strings.ToLower(aggregation[:1]) + aggregation[1:]
+
+	var values []float64
+	for _, v := range r.Value {
+		for _, ts := range v.Timeseries {
+			for _, d := range ts.Data {
+				if f, ok := d[key].(float64); ok {
+					values = append(values, f)
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// isoDuration formats seconds as an ISO 8601 duration using the largest
+// whole unit, e.g. 60 as "PT1M". Azure Monitor only accepts specific
+// metric time grains.
+func isoDuration(s int) string {
+	switch {
+	case s%3600 == 0:
+		return fmt.Sprintf("PT%dH", s/3600)
+	case s%60 == 0:
+		return fmt.Sprintf("PT%dM", s/60)
+	}
+
+	return fmt.Sprintf("PT%dS", s)
+}
+
+// postLogRecord writes a record to a Log Analytics workspace using the HTTP
+// Data Collector API.
+func (a *azureClient) postLogRecord(ctx context.Context, workspaceID, sharedKey, logType string, record interface{}) error {
+	body, err := json.Marshal([]interface{}{record})
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+
+	signature, err := logAnalyticsSignature(sharedKey, date, len(body))
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/api/logs?api-version=%s", a.logsURL, logsAPI)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Log-Type", logType)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", workspaceID, signature))
+
+	resp, err := a.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// logAnalyticsSignature returns the HTTP Data Collector API request
+// signature for a POST of the content length at the provided date.
+func logAnalyticsSignature(sharedKey, date string, contentLength int) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(sharedKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid shared key: %s", err)
+	}
+
+	s := fmt.Sprintf("POST\n%d\napplication/json\nx-ms-date:%s\n/api/logs", contentLength, date)
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
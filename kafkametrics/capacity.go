@@ -0,0 +1,136 @@
+package kafkametrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Capacity holds the capacity values for an instance type.
+type Capacity struct {
+	// Network capacity in MB/s.
+	Network float64 `json:"network" yaml:"network"`
+	// Disk capacity in bytes.
+	Disk float64 `json:"disk" yaml:"disk"`
+	// Disk IO capacity in MB/s.
+	DiskIO float64 `json:"disk_io" yaml:"disk_io"`
+	// Max replication throttle rate as a percentage of available network
+	// capacity, used by autothrottle in place of its default maximums. 0
+	// uses the defaults.
+	Headroom float64 `json:"headroom,omitempty" yaml:"headroom,omitempty"`
+}
+
+// CapacityMap is a map of instance types to Capacity.
+type CapacityMap map[string]Capacity
+
+// LoadCapacityMap reads a CapacityMap from the file at path. Files with a
+// .yaml or .yml extension are parsed as YAML, all others as JSON.
+func LoadCapacityMap(path string) (CapacityMap, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := CapacityMap{}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &c)
+	default:
+		err = json.Unmarshal(b, &c)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing capacity map %s: %s", path, err)
+	}
+
+	for t, capacity := range c {
+		if capacity.Headroom < 0 || capacity.Headroom >= 100 {
+			return nil, fmt.Errorf("capacity map %s: %s headroom must be >= 0 and < 100", path, t)
+		}
+	}
+
+	return c, nil
+}
+
+// NetworkCapacities returns a map of instance types to network capacity in
+// MB/s, as used by autothrottle's -cap-map.
+func (c CapacityMap) NetworkCapacities() map[string]float64 {
+	m := map[string]float64{}
+	for t, capacity := range c {
+		m[t] = capacity.Network
+	}
+
+	return m
+}
+
+// Headrooms returns a map of instance types to replication headroom, as a
+// percentage of available network capacity. Instance types without a
+// headroom are omitted.
+func (c CapacityMap) Headrooms() map[string]float64 {
+	m := map[string]float64{}
+	for t, capacity := range c {
+		if capacity.Headroom > 0 {
+			m[t] = capacity.Headroom
+		}
+	}
+
+	return m
+}
+
+// Apply populates the NetworkCapacity, DiskCapacity and DiskIOCapacity of
+// each broker in the BrokerMetrics from its instance type. Brokers with an instance type not in
+// the CapacityMap are left unchanged.
+func (c CapacityMap) Apply(bm BrokerMetrics) {
+	for _, b := range bm {
+		if capacity, exists := c[b.InstanceType]; exists {
+			b.NetworkCapacity = capacity.Network
+			b.DiskCapacity = capacity.Disk
+			b.DiskIOCapacity = capacity.DiskIO
+		}
+	}
+}
+
+// CapacityHandler is a Handler that populates broker capacities from a
+// CapacityMap on the metrics returned by the wrapped Handler.
+type CapacityHandler struct {
+	h          Handler
+	capacities CapacityMap
+}
+
+// NewCapacityHandler takes a Handler and CapacityMap and returns a
+// *CapacityHandler.
+func NewCapacityHandler(h Handler, c CapacityMap) *CapacityHandler {
+	return &CapacityHandler{
+		h:          h,
+		capacities: c,
+	}
+}
+
+// GetMetrics requests metrics from the wrapped Handler and applies the
+// CapacityMap to the results.
+func (c *CapacityHandler) GetMetrics() (BrokerMetrics, []error) {
+	return c.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics.
+func (c *CapacityHandler) GetMetricsContext(ctx context.Context) (BrokerMetrics, []error) {
+	bm, errs := GetMetricsContext(ctx, c.h)
+	c.capacities.Apply(bm)
+
+	return bm, errs
+}
+
+// PostEvent posts the event to the wrapped Handler.
+func (c *CapacityHandler) PostEvent(e *Event) error {
+	return c.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext posts the event to the wrapped Handler with the context.
+func (c *CapacityHandler) PostEventContext(ctx context.Context, e *Event) error {
+	return PostEventContext(ctx, c.h, e)
+}
@@ -0,0 +1,70 @@
+package kafkametrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCapacityMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capacity.json")
+	data := `{"stub":{"network":120,"disk":1000000,"disk_io":500},"other":{"network":240,"headroom":60}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadCapacityMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c["stub"] != (Capacity{Network: 120, Disk: 1000000, DiskIO: 500}) {
+		t.Errorf("Unexpected capacity %+v\n", c["stub"])
+	}
+
+	n := c.NetworkCapacities()
+	if len(n) != 2 || n["stub"] != 120 || n["other"] != 240 {
+		t.Errorf("Unexpected network capacities %v\n", n)
+	}
+
+	h := c.Headrooms()
+	if len(h) != 1 || h["other"] != 60 {
+		t.Errorf("Unexpected headrooms %v\n", h)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"stub":{"network":120,"headroom":100}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadCapacityMap(path); err == nil {
+		t.Error("Expected non-nil error")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadCapacityMap(path); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
+
+func TestCapacityHandler(t *testing.T) {
+	h := NewCapacityHandler(&Stub{}, CapacityMap{
+		"stub": {Network: 120, Disk: 1000000, DiskIO: 500},
+	})
+
+	bm, _ := h.GetMetrics()
+	for id, b := range bm {
+		if b.NetworkCapacity != 120 || b.DiskCapacity != 1000000 || b.DiskIOCapacity != 500 {
+			t.Errorf("[broker %d] Unexpected capacities %f/%f/%f\n", id, b.NetworkCapacity, b.DiskCapacity, b.DiskIOCapacity)
+		}
+	}
+
+	// Unknown instance types are left unchanged.
+	bm = BrokerMetrics{1001: &Broker{InstanceType: "unknown"}}
+	h.capacities.Apply(bm)
+	if bm[1001].NetworkCapacity != 0 {
+		t.Errorf("Expected NetworkCapacity 0, got %f\n", bm[1001].NetworkCapacity)
+	}
+}
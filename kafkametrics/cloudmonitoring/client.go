@@ -0,0 +1,371 @@
+package cloudmonitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	monitoringURL = "https://monitoring.googleapis.com"
+	loggingURL    = "https://logging.googleapis.com"
+	computeURL    = "https://compute.googleapis.com"
+	metadataURL   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// tokenSource provides OAuth2 access tokens, either from a static token or
+// from the GCE metadata server.
+type tokenSource struct {
+	sync.Mutex
+	c           *http.Client
+	static      string
+	metadataURL string
+	token       string
+	expiry      time.Time
+}
+
+// get returns a valid access token.
+func (t *tokenSource) get(ctx context.Context) (string, error) {
+	if t.static != "" {
+		return t.static, nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	// Refresh a minute ahead of the expiry.
+	if t.token != "" && time.Now().Add(time.Minute).Before(t.expiry) {
+		return t.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.metadataURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := t.c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching token from metadata server: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching token from metadata server: status %d", resp.StatusCode)
+	}
+
+	var r struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", err
+	}
+
+	t.token = r.AccessToken
+	t.expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+
+	return t.token, nil
+}
+
+// gcpClient is a minimal client for the Cloud Monitoring, Logging and
+// Compute Engine APIs.
+type gcpClient struct {
+	c             *http.Client
+	tokens        *tokenSource
+	monitoringURL string
+	loggingURL    string
+	computeURL    string
+}
+
+// do issues an authenticated request and unmarshals the JSON response
+// into v.
+func (g *gcpClient) do(ctx context.Context, method, u string, body interface{}, v interface{}) error {
+	var b io.Reader
+	if body != nil {
+		d, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		b = bytes.NewReader(d)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, b)
+	if err != nil {
+		return err
+	}
+
+	token, err := g.tokens.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(d, &e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("%s: %s", e.Error.Status, e.Error.Message)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.Unmarshal(d, v)
+}
+
+// labeledSeries is a timeseries with its labels flattened to the MQL
+// naming scheme, e.g. "resource.instance_id" or "metadata.user.broker_id".
+type labeledSeries struct {
+	labels map[string]string
+	values []float64
+}
+
+// typedValue is a Cloud Monitoring TypedValue. Int64 values are encoded as
+// JSON strings.
+type typedValue struct {
+	DoubleValue *float64 `json:"doubleValue"`
+	Int64Value  *string  `json:"int64Value"`
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+}
+
+// float returns the numeric value of the typedValue.
+func (v typedValue) float() (float64, bool) {
+	switch {
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, true
+	case v.Int64Value != nil:
+		f, err := strconv.ParseFloat(*v.Int64Value, 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// string returns the string representation of the typedValue.
+func (v typedValue) string() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.Int64Value != nil:
+		return *v.Int64Value
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64)
+	}
+
+	return ""
+}
+
+type listTimeSeriesResponse struct {
+	TimeSeries []struct {
+		Metric struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metric"`
+		Resource struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"resource"`
+		Metadata struct {
+			SystemLabels map[string]interface{} `json:"systemLabels"`
+			UserLabels   map[string]string      `json:"userLabels"`
+		} `json:"metadata"`
+		Points []struct {
+			Value typedValue `json:"value"`
+		} `json:"points"`
+	} `json:"timeSeries"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// listTimeSeries runs a filter based timeSeries.list request.
+func (g *gcpClient) listTimeSeries(ctx context.Context, project, filter, aligner string, period int, start, end time.Time) ([]labeledSeries, error) {
+	var result []labeledSeries
+	var next string
+
+	for {
+		params := url.Values{}
+		params.Set("filter", filter)
+		params.Set("interval.startTime", start.UTC().Format(time.RFC3339))
+		params.Set("interval.endTime", end.UTC().Format(time.RFC3339))
+		params.Set("aggregation.alignmentPeriod", fmt.Sprintf("%ds", period))
+		params.Set("aggregation.perSeriesAligner", aligner)
+		if next != "" {
+			params.Set("pageToken", next)
+		}
+
+		u := fmt.Sprintf("%s/v3/projects/%s/timeSeries?%s", g.monitoringURL, project, params.Encode())
+
+		var r listTimeSeriesResponse
+		if err := g.do(ctx, http.MethodGet, u, nil, &r); err != nil {
+			return nil, err
+		}
+
+		for _, ts := range r.TimeSeries {
+			s := labeledSeries{labels: map[string]string{}}
+			for k, v := range ts.Metric.Labels {
+				s.labels["metric."+k] = v
+			}
+			for k, v := range ts.Resource.Labels {
+				s.labels["resource."+k] = v
+			}
+			for k, v := range ts.Metadata.UserLabels {
+				s.labels["metadata.user."+k] = v
+			}
+			for k, v := range ts.Metadata.SystemLabels {
+				// System labels may be lists; only scalar values are useful here.
+				if str, ok := v.(string); ok {
+					s.labels["metadata.system."+k] = str
+				}
+			}
+			for _, p := range ts.Points {
+				if f, ok := p.Value.float(); ok {
+					s.values = append(s.values, f)
+				}
+			}
+			result = append(result, s)
+		}
+
+		if r.NextPageToken == "" {
+			return result, nil
+		}
+		next = r.NextPageToken
+	}
+}
+
+type queryTimeSeriesResponse struct {
+	TimeSeriesDescriptor struct {
+		LabelDescriptors []struct {
+			Key string `json:"key"`
+		} `json:"labelDescriptors"`
+	} `json:"timeSeriesDescriptor"`
+	TimeSeriesData []struct {
+		LabelValues []typedValue `json:"labelValues"`
+		PointData   []struct {
+			Values []typedValue `json:"values"`
+		} `json:"pointData"`
+	} `json:"timeSeriesData"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// queryTimeSeries runs an MQL timeSeries.query request. The first value
+// column of each point is used.
+func (g *gcpClient) queryTimeSeries(ctx context.Context, project, query string) ([]labeledSeries, error) {
+	var result []labeledSeries
+	var next string
+
+	u := fmt.Sprintf("%s/v3/projects/%s/timeSeries:query", g.monitoringURL, project)
+
+	for {
+		body := map[string]string{"query": query}
+		if next != "" {
+			body["pageToken"] = next
+		}
+
+		var r queryTimeSeriesResponse
+		if err := g.do(ctx, http.MethodPost, u, body, &r); err != nil {
+			return nil, err
+		}
+
+		keys := r.TimeSeriesDescriptor.LabelDescriptors
+
+		for _, ts := range r.TimeSeriesData {
+			s := labeledSeries{labels: map[string]string{}}
+			for i, v := range ts.LabelValues {
+				if i < len(keys) {
+					s.labels[keys[i].Key] = v.string()
+				}
+			}
+			for _, p := range ts.PointData {
+				if len(p.Values) == 0 {
+					continue
+				}
+				if f, ok := p.Values[0].float(); ok {
+					s.values = append(s.values, f)
+				}
+			}
+			result = append(result, s)
+		}
+
+		if r.NextPageToken == "" {
+			return result, nil
+		}
+		next = r.NextPageToken
+	}
+}
+
+// writeLogEntry writes a structured log entry to Cloud Logging.
+func (g *gcpClient) writeLogEntry(ctx context.Context, project, logName string, payload interface{}) error {
+	body := map[string]interface{}{
+		"logName":  fmt.Sprintf("projects/%s/logs/%s", project, url.PathEscape(logName)),
+		"resource": map[string]string{"type": "global"},
+		"entries":  []interface{}{map[string]interface{}{"jsonPayload": payload}},
+	}
+
+	return g.do(ctx, http.MethodPost, g.loggingURL+"/v2/entries:write", body, nil)
+}
+
+type aggregatedInstancesResponse struct {
+	Items map[string]struct {
+		Instances []struct {
+			MachineType string `json:"machineType"`
+		} `json:"instances"`
+	} `json:"items"`
+}
+
+// instanceMachineType returns the machine type of the Compute Engine instance
+// with the name in any zone of the project.
+func (g *gcpClient) instanceMachineType(ctx context.Context, project, name string) (string, error) {
+	params := url.Values{}
+	params.Set("filter", fmt.Sprintf("name = %q", name))
+
+	u := fmt.Sprintf("%s/compute/v1/projects/%s/aggregated/instances?%s", g.computeURL, project, params.Encode())
+
+	var r aggregatedInstancesResponse
+	if err := g.do(ctx, http.MethodGet, u, nil, &r); err != nil {
+		return "", err
+	}
+
+	for _, zone := range r.Items {
+		for _, i := range zone.Instances {
+			// Machine types are returned as a URL, e.g.
+			// ".../zones/us-central1-a/machineTypes/n2-standard-8".
+			if i.MachineType != "" {
+				return path.Base(i.MachineType), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no instance found with name %s", name)
+}
@@ -0,0 +1,305 @@
+// Package cloudmonitoring implements
+// a kafkametrics Handler.
+package cloudmonitoring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Project is the GCP project ID hosting the metrics.
+	Project string
+	// AccessToken is an optional static OAuth2 access token. If unset,
+	// tokens are fetched from the GCE metadata server.
+	AccessToken string
+	// NetworkTXQuery is an MQL query that should return the outbound network
+	// throughput in bytes/s for the reference Kafka brokers. A
+	// "| within <MetricsWindow>" operation is appended to the query.
+	// Example: "fetch gce_instance | metric 'compute.googleapis.com/instance/network/sent_bytes_count' | filter metadata.user.service == 'kafka' | align rate(1m) | every 1m"
+	NetworkTXQuery string
+	// NetworkRXQuery is the inbound network equivalent of NetworkTXQuery.
+	NetworkRXQuery string
+	// NetworkTXFilter is a monitoring filter used in place of NetworkTXQuery
+	// if no query is specified. Series are aligned with the Aligner.
+	// Example: "metric.type=\"compute.googleapis.com/instance/network/sent_bytes_count\" AND metadata.user_labels.service=\"kafka\""
+	NetworkTXFilter string
+	// NetworkRXFilter is the inbound network equivalent of NetworkTXFilter.
+	NetworkRXFilter string
+	// Aligner is the per-series aligner used with filters. Defaults to
+	// "ALIGN_RATE".
+	Aligner string
+	// Period is the alignment period in seconds used with filters. Defaults
+	// to 60.
+	Period int
+	// BrokerIDLabel is the series label for Kafka broker IDs, using the MQL
+	// label naming scheme.
+	// Example: "metadata.user.broker_id"
+	BrokerIDLabel string
+	// InstanceTypeLabel is the series label for the Kafka broker's instance
+	// type. Defaults to "metadata.system.machine_type".
+	InstanceTypeLabel string
+	// HostLabel is the series label for the Kafka broker's hostname.
+	// Defaults to "metadata.system.name".
+	HostLabel string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// LogName is an optional Cloud Logging log name that events are
+	// written to.
+	LogName string
+}
+
+type gcmHandler struct {
+	c                 *gcpClient
+	project           string
+	queries           []string
+	filters           []string
+	aligner           string
+	period            int
+	brokerIDLabel     string
+	instanceTypeLabel string
+	hostLabel         string
+	metricsWindow     int
+	logName           string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.Project == "":
+		return nil, errors.New("project must be specified")
+	case (c.NetworkTXQuery == "" || c.NetworkRXQuery == "") && (c.NetworkTXFilter == "" || c.NetworkRXFilter == ""):
+		return nil, errors.New("network tx and rx queries or filters must be specified")
+	case c.BrokerIDLabel == "":
+		return nil, errors.New("broker ID label must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	h := &gcmHandler{
+		c: &gcpClient{
+			c: client,
+			tokens: &tokenSource{
+				c:           client,
+				static:      c.AccessToken,
+				metadataURL: metadataURL,
+			},
+			monitoringURL: monitoringURL,
+			loggingURL:    loggingURL,
+			computeURL:    computeURL,
+		},
+		project:           c.Project,
+		aligner:           "ALIGN_RATE",
+		period:            60,
+		brokerIDLabel:     c.BrokerIDLabel,
+		instanceTypeLabel: "metadata.system.machine_type",
+		hostLabel:         "metadata.system.name",
+		metricsWindow:     c.MetricsWindow,
+		logName:           c.LogName,
+	}
+
+	if c.NetworkTXQuery != "" && c.NetworkRXQuery != "" {
+		for _, q := range []string{c.NetworkTXQuery, c.NetworkRXQuery} {
+			h.queries = append(h.queries, fmt.Sprintf("%s | within %ds", q, c.MetricsWindow))
+		}
+	} else {
+		h.filters = []string{c.NetworkTXFilter, c.NetworkRXFilter}
+	}
+
+	if c.Aligner != "" {
+		h.aligner = c.Aligner
+	}
+
+	if c.Period > 0 {
+		h.period = c.Period
+	}
+
+	if c.InstanceTypeLabel != "" {
+		h.instanceTypeLabel = c.InstanceTypeLabel
+	}
+
+	if c.HostLabel != "" {
+		h.hostLabel = c.HostLabel
+	}
+
+	// Validate.
+	if _, err := h.c.tokens.get(context.Background()); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent writes an event to Cloud Logging if a LogName is configured.
+// Otherwise, the event is discarded.
+func (h *gcmHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *gcmHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.logName == "" {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"title": e.Title,
+		"text":  e.Text,
+		"tags":  e.Tags,
+	}
+
+	if err := h.c.writeLogEntry(ctx, h.project, h.logName, payload); err != nil {
+		return &kafkametrics.APIError{
+			Request: "write log entry",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the Cloud Monitoring
+// API and returns a BrokerMetrics. If any errors are encountered (i.e.
+// complete metadata for a given broker can't be retrieved), the broker will
+// not be included in the BrokerMetrics.
+func (h *gcmHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *gcmHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	end := time.Now()
+	start := end.Add(-time.Duration(h.metricsWindow) * time.Second)
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i := 0; i < 2; i++ {
+		var series []labeledSeries
+		var query string
+		var err error
+
+		if h.queries != nil {
+			query = h.queries[i]
+			series, err = h.c.queryTimeSeries(ctx, h.project, query)
+		} else {
+			query = h.filters[i]
+			series, err = h.c.listTimeSeries(ctx, h.project, query, h.aligner, h.period, start, end)
+		}
+
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(series) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with query %s", query),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, series, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and []labeledSeries and
+// populates the BrokerMetrics with the window average for the metric type.
+// The number of brokers populated is returned along with any errors.
+func (h *gcmHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, series []labeledSeries, metricType int) (int, []error) {
+	var errors []error
+	var missingLabels bytes.Buffer
+	var n int
+
+	for _, s := range series {
+		host := s.labels[h.hostLabel]
+
+		if len(s.values) == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", host),
+			})
+			continue
+		}
+
+		// Get ID.
+		id, err := strconv.Atoi(s.labels[h.brokerIDLabel])
+		if err != nil {
+			missingLabels.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDLabel, host))
+			continue
+		}
+
+		// Get instance type.
+		it := s.labels[h.instanceTypeLabel]
+		if it == "" {
+			missingLabels.WriteString(fmt.Sprintf(" %s:%s", h.instanceTypeLabel, host))
+			continue
+		}
+
+		var sum float64
+		for _, v := range s.values {
+			sum += v
+		}
+		avg := sum / float64(len(s.values))
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         host,
+				InstanceType: it,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingLabels.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing series labels:%s", missingLabels.String()),
+		})
+	}
+
+	return n, errors
+}
@@ -0,0 +1,303 @@
+package cloudmonitoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestTokenSource(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		requests++
+		fmt.Fprint(w, `{"access_token":"token","expires_in":3600,"token_type":"Bearer"}`)
+	}))
+	defer srv.Close()
+
+	ts := &tokenSource{c: srv.Client(), metadataURL: srv.URL}
+
+	for i := 0; i < 2; i++ {
+		token, err := ts.get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token" {
+			t.Errorf("Expected token 'token', got '%s'\n", token)
+		}
+	}
+
+	// The token should be cached.
+	if requests != 1 {
+		t.Errorf("Expected 1 metadata request, got %d\n", requests)
+	}
+}
+
+func TestQueryTimeSeries(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]string
+		json.NewDecoder(req.Body).Decode(&body)
+
+		if req.URL.Path != "/v3/projects/test/timeSeries:query" || body["query"] != "tx | within 120s" {
+			t.Errorf("Unexpected request %s %v\n", req.URL, body)
+		}
+
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected authorization %s\n", req.Header.Get("Authorization"))
+		}
+
+		// Results are paged.
+		requests++
+		if body["pageToken"] == "" {
+			fmt.Fprint(w, `{"timeSeriesDescriptor":{"labelDescriptors":[{"key":"metadata.system.name"},{"key":"metadata.user.broker_id"}],`+
+				`"pointDescriptors":[{"key":"value.rate","valueType":"DOUBLE"}]},`+
+				`"timeSeriesData":[{"labelValues":[{"stringValue":"kafka-0"},{"int64Value":"1000"}],`+
+				`"pointData":[{"values":[{"doubleValue":1048576}]},{"values":[{"int64Value":"3145728"}]},{"values":[{"stringValue":"n/a"}]}]}],`+
+				`"nextPageToken":"1"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"timeSeriesDescriptor":{"labelDescriptors":[{"key":"metadata.system.name"},{"key":"metadata.user.broker_id"}]},`+
+			`"timeSeriesData":[{"labelValues":[{"stringValue":"kafka-1"},{"boolValue":true}],"pointData":[]}]}`)
+	}))
+	defer srv.Close()
+
+	g := &gcpClient{c: srv.Client(), tokens: &tokenSource{static: "token"}, monitoringURL: srv.URL}
+
+	series, err := g.queryTimeSeries(context.Background(), "test", "tx | within 120s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 || len(series) != 2 {
+		t.Fatalf("Expected 2 series over 2 pages, got %d over %d\n", len(series), requests)
+	}
+
+	// Non-numeric values are skipped and label values are formatted.
+	if s := series[0]; s.labels["metadata.user.broker_id"] != "1000" || len(s.values) != 2 || s.values[1] != 3145728 {
+		t.Errorf("Unexpected series %+v\n", s)
+	}
+
+	if s := series[1]; s.labels["metadata.system.name"] != "kafka-1" || s.labels["metadata.user.broker_id"] != "true" || len(s.values) != 0 {
+		t.Errorf("Unexpected series %+v\n", s)
+	}
+}
+
+func TestListTimeSeries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if req.URL.Path != "/v3/projects/test/timeSeries" || q.Get("filter") != "tx" ||
+			q.Get("aggregation.perSeriesAligner") != "ALIGN_RATE" || q.Get("aggregation.alignmentPeriod") != "60s" {
+			t.Errorf("Unexpected request %s\n", req.URL)
+		}
+
+		fmt.Fprint(w, `{"timeSeries":[{"metric":{"labels":{"interface":"nic0"}},"resource":{"labels":{"instance_id":"1"}},`+
+			`"metadata":{"systemLabels":{"name":"kafka-0","machine_type":"n2-standard-8","network_tags":["kafka"]},"userLabels":{"broker_id":"1000"}},`+
+			`"points":[{"value":{"doubleValue":1048576}},{"value":{"int64Value":"3145728"}}]}]}`)
+	}))
+	defer srv.Close()
+
+	g := &gcpClient{c: srv.Client(), tokens: &tokenSource{static: "token"}, monitoringURL: srv.URL}
+
+	end := time.Now()
+	series, err := g.listTimeSeries(context.Background(), "test", "tx", "ALIGN_RATE", 60, end.Add(-2*time.Minute), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(series) != 1 {
+		t.Fatalf("Expected 1 series, got %d\n", len(series))
+	}
+
+	// Labels are flattened to the MQL naming scheme. List system labels are
+	// skipped.
+	expected := map[string]string{
+		"metric.interface":             "nic0",
+		"resource.instance_id":         "1",
+		"metadata.system.name":         "kafka-0",
+		"metadata.system.machine_type": "n2-standard-8",
+		"metadata.user.broker_id":      "1000",
+	}
+
+	s := series[0]
+	if len(s.labels) != len(expected) || len(s.values) != 2 || s.values[0] != 1048576 {
+		t.Errorf("Unexpected series %+v\n", s)
+	}
+
+	for k, v := range expected {
+		if s.labels[k] != v {
+			t.Errorf("Expected label %s=%s, got %s\n", k, v, s.labels[k])
+		}
+	}
+}
+
+func TestDoErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// Google API error statuses and messages are returned.
+		{
+			http.StatusBadRequest,
+			`{"error":{"code":400,"message":"Line 1, column 1: Table or metric 'unknown' not found.","status":"INVALID_ARGUMENT"}}`,
+			"INVALID_ARGUMENT: Line 1, column 1: Table or metric 'unknown' not found.",
+		},
+		{
+			http.StatusUnauthorized,
+			`{"error":{"code":401,"message":"Request had invalid authentication credentials.","status":"UNAUTHENTICATED"}}`,
+			"UNAUTHENTICATED: Request had invalid authentication credentials.",
+		},
+		{http.StatusServiceUnavailable, "Service Unavailable", "unexpected status 503"},
+		{http.StatusOK, "<html></html>", "invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		g := &gcpClient{c: srv.Client(), tokens: &tokenSource{static: "token"}, monitoringURL: srv.URL}
+
+		if _, err := g.queryTimeSeries(context.Background(), "test", "unknown"); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+
+	// Metadata server token errors.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	g := &gcpClient{c: srv.Client(), tokens: &tokenSource{c: srv.Client(), metadataURL: srv.URL}, monitoringURL: srv.URL}
+
+	if _, err := g.queryTimeSeries(context.Background(), "test", "tx"); err == nil || err.Error() != "error fetching token from metadata server: status 403" {
+		t.Errorf("Unexpected error %v\n", err)
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &gcmHandler{
+		brokerIDLabel:     "metadata.user.broker_id",
+		instanceTypeLabel: "metadata.system.machine_type",
+		hostLabel:         "metadata.system.name",
+	}
+
+	series := []labeledSeries{
+		{
+			labels: map[string]string{"metadata.system.name": "kafka-0", "metadata.user.broker_id": "1000", "metadata.system.machine_type": "n2"},
+			values: []float64{1048576, 3145728},
+		},
+		{labels: map[string]string{"metadata.system.name": "kafka-1", "metadata.user.broker_id": "1001", "metadata.system.machine_type": "n2"}},
+		{labels: map[string]string{"metadata.system.name": "kafka-2", "metadata.user.broker_id": "1002"}, values: []float64{1048576}},
+		{labels: map[string]string{"metadata.system.name": "kafka-9", "metadata.system.machine_type": "n2"}, values: []float64{1048576}},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, series, 1)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetRX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "n2" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-1",
+		"Missing series labels: metadata.system.machine_type:kafka-2 metadata.user.broker_id:kafka-9",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	entries := make(chan string, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v2/entries:write" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		entries <- string(body)
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	h := &gcmHandler{c: &gcpClient{c: srv.Client(), tokens: &tokenSource{static: "token"}, loggingURL: srv.URL}, project: "test"}
+
+	// Events are discarded without a log name.
+	if err := h.PostEvent(&kafkametrics.Event{Title: "test"}); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected event to be discarded: %v\n", err)
+	}
+
+	h.logName = "kafka-autothrottle"
+
+	if err := h.PostEvent(&kafkametrics.Event{Title: "test", Text: "text", Tags: []string{"a:b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"entries":[{"jsonPayload":{"tags":["a:b"],"text":"text","title":"test"}}],` +
+		`"logName":"projects/test/logs/kafka-autothrottle","resource":{"type":"global"}}`
+	if e := <-entries; e != expected {
+		t.Errorf("Expected log entry:\n%s\ngot:\n%s\n", expected, e)
+	}
+}
+
+func TestInstanceTypeResolver(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.URL.Path != "/compute/v1/projects/test/aggregated/instances" || req.URL.Query().Get("filter") != `name = "kafka-0"` {
+			fmt.Fprint(w, `{"items":{"zones/us-central1-a":{"warning":{"code":"NO_RESULTS_ON_PAGE"}}}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"items":{"zones/us-central1-a":{"instances":[{"name":"kafka-0",`+
+			`"machineType":"https://www.googleapis.com/compute/v1/projects/test/zones/us-central1-a/machineTypes/n2-standard-8"}]}}}`)
+	}))
+	defer srv.Close()
+
+	r, err := NewInstanceTypeResolver(&ResolverConfig{Project: "test", AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.(*gceResolver).c.computeURL = srv.URL
+
+	// The second lookup is cached.
+	for i := 0; i < 2; i++ {
+		if it, err := r.InstanceType("kafka-0.c.test.internal"); err != nil || it != "n2-standard-8" {
+			t.Errorf("Expected instance type n2-standard-8, got %s, %v\n", it, err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d\n", requests)
+	}
+
+	if _, err := r.InstanceType("kafka-1"); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
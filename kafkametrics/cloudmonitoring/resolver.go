@@ -0,0 +1,80 @@
+package cloudmonitoring
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// ResolverConfig holds Compute Engine InstanceTypeResolver configuration
+// parameters.
+type ResolverConfig struct {
+	// Project is the GCP project ID hosting the Kafka brokers.
+	Project string
+	// AccessToken is an optional static OAuth2 access token. If unset,
+	// tokens are fetched from the GCE metadata server.
+	AccessToken string
+}
+
+type gceResolver struct {
+	sync.Mutex
+	c       *gcpClient
+	project string
+	cache   map[string]string
+}
+
+// NewInstanceTypeResolver takes a *ResolverConfig and returns a
+// kafkametrics.InstanceTypeResolver that looks up machine types with the
+// Compute Engine API. Hostnames are matched against instance names by their
+// first label, e.g. "kafka-1" for "kafka-1.c.project.internal". Resolved
+// machine types are cached.
+func NewInstanceTypeResolver(c *ResolverConfig) (kafkametrics.InstanceTypeResolver, error) {
+	if c.Project == "" {
+		return nil, errors.New("project must be specified")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	return &gceResolver{
+		c: &gcpClient{
+			c: client,
+			tokens: &tokenSource{
+				c:           client,
+				static:      c.AccessToken,
+				metadataURL: metadataURL,
+			},
+			computeURL: computeURL,
+		},
+		project: c.Project,
+		cache:   map[string]string{},
+	}, nil
+}
+
+// InstanceType returns the machine type of the Compute Engine instance for
+// the host.
+func (r *gceResolver) InstanceType(host string) (string, error) {
+	r.Lock()
+	it, cached := r.cache[host]
+	r.Unlock()
+
+	if cached {
+		return it, nil
+	}
+
+	name := strings.SplitN(host, ".", 2)[0]
+	it, err := r.c.instanceMachineType(context.Background(), r.project, name)
+	if err != nil {
+		return "", err
+	}
+
+	r.Lock()
+	r.cache[host] = it
+	r.Unlock()
+
+	return it, nil
+}
@@ -0,0 +1,339 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cloudWatchAPIVersion = "2010-08-01"
+	ec2APIVersion        = "2016-11-15"
+	snsAPIVersion        = "2010-03-31"
+	// The GetMetricData limit of queries per request.
+	maxMetricDataQueries = 500
+)
+
+// awsClient is a minimal client for the AWS APIs used by the handler.
+type awsClient struct {
+	c         *http.Client
+	creds     credentials
+	region    string
+	endpoints map[string]string
+}
+
+// endpoint returns the base URL for the AWS service.
+func (a *awsClient) endpoint(service string) string {
+	if e, exists := a.endpoints[service]; exists {
+		return e
+	}
+
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, a.region)
+}
+
+// do signs and issues a POST request to the AWS service, returning the
+// response body. Non-200 responses are returned as errors.
+func (a *awsClient) do(ctx context.Context, service, contentType string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint(service)+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	signRequest(req, body, a.creds, a.region, service, time.Now())
+
+	resp, err := a.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp.StatusCode, b)
+	}
+
+	return b, nil
+}
+
+// query issues an AWS query protocol request and unmarshals the XML
+// response into v.
+func (a *awsClient) query(ctx context.Context, service string, params url.Values, v interface{}) error {
+	body, err := a.do(ctx, service, "application/x-www-form-urlencoded; charset=utf-8", nil, []byte(params.Encode()))
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return xml.Unmarshal(body, v)
+}
+
+// responseError builds an error from either an XML (query protocol) or JSON
+// error response.
+func responseError(status int, b []byte) error {
+	var xmlErr struct {
+		Error struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		} `xml:"Error"`
+	}
+
+	if err := xml.Unmarshal(b, &xmlErr); err == nil && xmlErr.Error.Code != "" {
+		return fmt.Errorf("%s: %s", xmlErr.Error.Code, xmlErr.Error.Message)
+	}
+
+	var jsonErr struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(b, &jsonErr); err == nil && jsonErr.Type != "" {
+		return fmt.Errorf("%s: %s", jsonErr.Type, jsonErr.Message)
+	}
+
+	return fmt.Errorf("unexpected status %d", status)
+}
+
+// Dimension is a CloudWatch metric dimension.
+type Dimension struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// metric is a CloudWatch metric and its dimensions.
+type metric struct {
+	Namespace  string      `xml:"Namespace"`
+	MetricName string      `xml:"MetricName"`
+	Dimensions []Dimension `xml:"Dimensions>member"`
+}
+
+// dimension returns the value for the named dimension.
+func (m metric) dimension(name string) (string, bool) {
+	for _, d := range m.Dimensions {
+		if d.Name == name {
+			return d.Value, true
+		}
+	}
+
+	return "", false
+}
+
+type listMetricsResponse struct {
+	Metrics   []metric `xml:"ListMetricsResult>Metrics>member"`
+	NextToken string   `xml:"ListMetricsResult>NextToken"`
+}
+
+// listMetrics returns all metrics matching the namespace, metric name and
+// dimension filters.
+func (a *awsClient) listMetrics(ctx context.Context, namespace, name string, dims []Dimension) ([]metric, error) {
+	var metrics []metric
+	var next string
+
+	for {
+		params := url.Values{}
+		params.Set("Action", "ListMetrics")
+		params.Set("Version", cloudWatchAPIVersion)
+		params.Set("Namespace", namespace)
+		params.Set("MetricName", name)
+		for i, d := range dims {
+			params.Set(fmt.Sprintf("Dimensions.member.%d.Name", i+1), d.Name)
+			params.Set(fmt.Sprintf("Dimensions.member.%d.Value", i+1), d.Value)
+		}
+		if next != "" {
+			params.Set("NextToken", next)
+		}
+
+		var r listMetricsResponse
+		if err := a.query(ctx, "monitoring", params, &r); err != nil {
+			return nil, err
+		}
+
+		metrics = append(metrics, r.Metrics...)
+
+		if r.NextToken == "" {
+			return metrics, nil
+		}
+		next = r.NextToken
+	}
+}
+
+// metricDataResult holds the values returned for a metric data query ID.
+type metricDataResult struct {
+	ID     string    `xml:"Id"`
+	Values []float64 `xml:"Values>member"`
+}
+
+type getMetricDataResponse struct {
+	Results   []metricDataResult `xml:"GetMetricDataResult>MetricDataResults>member"`
+	NextToken string             `xml:"GetMetricDataResult>NextToken"`
+}
+
+// getMetricData fetches the values for each metric over the start and end
+// time, returning a map of the metric's index in the input slice to values.
+func (a *awsClient) getMetricData(ctx context.Context, metrics []metric, stat string, period int, start, end time.Time) (map[int][]float64, error) {
+	values := map[int][]float64{}
+
+	for offset := 0; offset < len(metrics); offset += maxMetricDataQueries {
+		batch := metrics[offset:]
+		if len(batch) > maxMetricDataQueries {
+			batch = batch[:maxMetricDataQueries]
+		}
+
+		var next string
+		for {
+			params := url.Values{}
+			params.Set("Action", "GetMetricData")
+			params.Set("Version", cloudWatchAPIVersion)
+			params.Set("StartTime", start.UTC().Format(time.RFC3339))
+			params.Set("EndTime", end.UTC().Format(time.RFC3339))
+
+			for i, m := range batch {
+				p := fmt.Sprintf("MetricDataQueries.member.%d.", i+1)
+				params.Set(p+"Id", fmt.Sprintf("m%d", offset+i))
+				params.Set(p+"MetricStat.Metric.Namespace", m.Namespace)
+				params.Set(p+"MetricStat.Metric.MetricName", m.MetricName)
+				for j, d := range m.Dimensions {
+					dp := fmt.Sprintf("%sMetricStat.Metric.Dimensions.member.%d.", p, j+1)
+					params.Set(dp+"Name", d.Name)
+					params.Set(dp+"Value", d.Value)
+				}
+				params.Set(p+"MetricStat.Period", strconv.Itoa(period))
+				params.Set(p+"MetricStat.Stat", stat)
+			}
+
+			if next != "" {
+				params.Set("NextToken", next)
+			}
+
+			var r getMetricDataResponse
+			if err := a.query(ctx, "monitoring", params, &r); err != nil {
+				return nil, err
+			}
+
+			for _, res := range r.Results {
+				i, err := strconv.Atoi(strings.TrimPrefix(res.ID, "m"))
+				if err != nil || i < offset || i >= offset+len(batch) {
+					continue
+				}
+				values[i] = append(values[i], res.Values...)
+			}
+
+			if r.NextToken == "" {
+				break
+			}
+			next = r.NextToken
+		}
+	}
+
+	return values, nil
+}
+
+// putEvent writes an event to an EventBridge event bus.
+func (a *awsClient) putEvent(ctx context.Context, bus, source, detailType string, detail interface{}) error {
+	d, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+
+	entry := map[string]string{
+		"Source":       source,
+		"DetailType":   detailType,
+		"Detail":       string(d),
+		"EventBusName": bus,
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"Entries": []map[string]string{entry}})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"X-Amz-Target": "AWSEvents.PutEvents"}
+	resp, err := a.do(ctx, "events", "application/x-amz-json-1.1", headers, body)
+	if err != nil {
+		return err
+	}
+
+	var r struct {
+		FailedEntryCount int
+		Entries          []struct {
+			ErrorCode    string
+			ErrorMessage string
+		}
+	}
+
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return err
+	}
+
+	if r.FailedEntryCount > 0 && len(r.Entries) > 0 {
+		return fmt.Errorf("%s: %s", r.Entries[0].ErrorCode, r.Entries[0].ErrorMessage)
+	}
+
+	return nil
+}
+
+// publish publishes a message to an SNS topic.
+func (a *awsClient) publish(ctx context.Context, topicARN, subject, message string) error {
+	params := url.Values{}
+	params.Set("Action", "Publish")
+	params.Set("Version", snsAPIVersion)
+	params.Set("TopicArn", topicARN)
+	params.Set("Subject", subject)
+	params.Set("Message", message)
+
+	return a.query(ctx, "sns", params, nil)
+}
+
+type describeInstancesResponse struct {
+	Reservations []struct {
+		Instances []struct {
+			InstanceType string `xml:"instanceType"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+}
+
+// describeInstanceType returns the instance type of the running EC2 instance
+// where the filter name matches the value.
+func (a *awsClient) describeInstanceType(ctx context.Context, filter, value string) (string, error) {
+	params := url.Values{}
+	params.Set("Action", "DescribeInstances")
+	params.Set("Version", ec2APIVersion)
+	params.Set("Filter.1.Name", filter)
+	params.Set("Filter.1.Value.1", value)
+	params.Set("Filter.2.Name", "instance-state-name")
+	params.Set("Filter.2.Value.1", "running")
+
+	var resp describeInstancesResponse
+	if err := a.query(ctx, "ec2", params, &resp); err != nil {
+		return "", err
+	}
+
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			if i.InstanceType != "" {
+				return i.InstanceType, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no running instance found with %s %s", filter, value)
+}
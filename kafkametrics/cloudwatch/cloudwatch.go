@@ -0,0 +1,324 @@
+// Package cloudwatch implements
+// a kafkametrics Handler.
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// AWS region.
+	Region string
+	// AWS credentials. If unset, the standard AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN env vars are used.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Namespace is the CloudWatch metrics namespace.
+	// Example (MSK): "AWS/Kafka"
+	Namespace string
+	// NetworkTXMetric is the name of the metric reporting outbound network
+	// throughput for the reference Kafka brokers.
+	// Example (MSK): "BytesOutPerSec"
+	NetworkTXMetric string
+	// NetworkRXMetric is the name of the metric reporting inbound network
+	// throughput for the reference Kafka brokers.
+	// Example (MSK): "BytesInPerSec"
+	NetworkRXMetric string
+	// Dimensions filters the metrics to those of the reference cluster.
+	// Example (MSK): [{"Name": "Cluster Name", "Value": "my-cluster"}]
+	Dimensions []Dimension
+	// BrokerIDDimension is the dimension that identifies each broker.
+	// Example (MSK): "Broker ID", (EC2): "InstanceId"
+	BrokerIDDimension string
+	// BrokerIDMap optionally maps BrokerIDDimension values to Kafka broker
+	// IDs. If unset, dimension values must be the broker IDs.
+	BrokerIDMap map[string]int
+	// InstanceType is the instance type applied to all brokers.
+	InstanceType string
+	// Statistic is the CloudWatch statistic to request. Defaults to "Average".
+	Statistic string
+	// DivideByPeriod should be set for metrics that report a total per period
+	// rather than a rate, such as the AWS/EC2 NetworkOut metric with the Sum
+	// statistic.
+	DivideByPeriod bool
+	// Period is the metric period in seconds. Defaults to 60.
+	Period int
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// EventBusName is an optional EventBridge event bus that events are
+	// posted to.
+	EventBusName string
+	// SNSTopicARN is an optional SNS topic that events are published to.
+	SNSTopicARN string
+	// Endpoints optionally overrides the endpoint URL by service name
+	// ("monitoring", "events", "sns"), e.g. for VPC endpoints.
+	Endpoints map[string]string
+}
+
+type cwHandler struct {
+	c                 *awsClient
+	namespace         string
+	metrics           []string
+	dimensions        []Dimension
+	brokerIDDimension string
+	brokerIDMap       map[string]int
+	instanceType      string
+	statistic         string
+	divideByPeriod    bool
+	period            int
+	metricsWindow     int
+	eventBusName      string
+	snsTopicARN       string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	creds := newCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+
+	switch {
+	case c.Region == "":
+		return nil, errors.New("region must be specified")
+	case creds.accessKeyID == "" || creds.secretAccessKey == "":
+		return nil, errors.New("AWS credentials must be specified")
+	case c.Namespace == "":
+		return nil, errors.New("namespace must be specified")
+	case c.NetworkTXMetric == "", c.NetworkRXMetric == "":
+		return nil, errors.New("network tx and rx metrics must be specified")
+	case c.BrokerIDDimension == "":
+		return nil, errors.New("broker ID dimension must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	h := &cwHandler{
+		c: &awsClient{
+			c:         &http.Client{Timeout: 30 * time.Second},
+			creds:     creds,
+			region:    c.Region,
+			endpoints: c.Endpoints,
+		},
+		namespace:         c.Namespace,
+		metrics:           []string{c.NetworkTXMetric, c.NetworkRXMetric},
+		dimensions:        c.Dimensions,
+		brokerIDDimension: c.BrokerIDDimension,
+		brokerIDMap:       c.BrokerIDMap,
+		instanceType:      c.InstanceType,
+		statistic:         "Average",
+		divideByPeriod:    c.DivideByPeriod,
+		period:            60,
+		metricsWindow:     c.MetricsWindow,
+		eventBusName:      c.EventBusName,
+		snsTopicARN:       c.SNSTopicARN,
+	}
+
+	if c.Statistic != "" {
+		h.statistic = c.Statistic
+	}
+
+	if c.Period > 0 {
+		h.period = c.Period
+	}
+
+	// Validate.
+	if _, err := h.c.listMetrics(context.Background(), h.namespace, h.metrics[0], h.dimensions); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent posts an event to the configured EventBridge event bus and/or
+// SNS topic. If neither is configured, the event is discarded.
+func (h *cwHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *cwHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.eventBusName != "" {
+		if err := h.c.putEvent(ctx, h.eventBusName, "kafka-kit", e.Title, e); err != nil {
+			return &kafkametrics.APIError{
+				Request: "put event",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	if h.snsTopicARN != "" {
+		// SNS subjects are limited to 100 characters.
+		subject := e.Title
+		if len(subject) > 100 {
+			subject = subject[:100]
+		}
+
+		if err := h.c.publish(ctx, h.snsTopicARN, subject, e.Text); err != nil {
+			return &kafkametrics.APIError{
+				Request: "publish event",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics from the CloudWatch API and returns a
+// BrokerMetrics. If any errors are encountered (i.e. a broker ID can't be
+// resolved), the broker will not be included in the BrokerMetrics.
+func (h *cwHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *cwHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	end := time.Now()
+	start := end.Add(-time.Duration(h.metricsWindow) * time.Second)
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, name := range h.metrics {
+		metrics, err := h.c.listMetrics(ctx, h.namespace, name, h.dimensions)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "list metrics",
+				Message: err.Error(),
+			}}
+		}
+
+		// Exclude any metrics not reported at broker granularity, e.g. MSK
+		// cluster level aggregates.
+		var brokerMetrics []metric
+		for _, m := range metrics {
+			if _, ok := m.dimension(h.brokerIDDimension); ok {
+				brokerMetrics = append(brokerMetrics, m)
+			}
+		}
+
+		if len(brokerMetrics) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No metrics found for %s/%s", h.namespace, name),
+			}}
+		}
+
+		values, err := h.c.getMetricData(ctx, brokerMetrics, h.statistic, h.period, start, end)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metric data query",
+				Message: err.Error(),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, brokerMetrics, values, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics, the []metric queried and a
+// map of metric index to values, and populates the BrokerMetrics with the
+// window average for the metric type. The number of brokers populated is
+// returned along with any errors.
+func (h *cwHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, metrics []metric, values map[int][]float64, metricType int) (int, []error) {
+	var errors []error
+	var unmapped bytes.Buffer
+	var n int
+
+	for i, m := range metrics {
+		dimVal, _ := m.dimension(h.brokerIDDimension)
+
+		vals := values[i]
+		if len(vals) == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for %s %s", h.brokerIDDimension, dimVal),
+			})
+			continue
+		}
+
+		id, err := h.brokerID(dimVal)
+		if err != nil {
+			unmapped.WriteString(" " + dimVal)
+			continue
+		}
+
+		var sum float64
+		for _, v := range vals {
+			sum += v
+		}
+		avg := sum / float64(len(vals))
+
+		if h.divideByPeriod {
+			avg = avg / float64(h.period)
+		}
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         dimVal,
+				InstanceType: h.instanceType,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if unmapped.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Unable to map %s values to broker IDs:%s", h.brokerIDDimension, unmapped.String()),
+		})
+	}
+
+	return n, errors
+}
+
+// brokerID returns the Kafka broker ID for a broker ID dimension value.
+func (h *cwHandler) brokerID(v string) (int, error) {
+	if len(h.brokerIDMap) > 0 {
+		if id, exists := h.brokerIDMap[v]; exists {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no broker ID mapped for %s", v)
+	}
+
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,349 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestSignRequest(t *testing.T) {
+	// The example request from the AWS Signature Version 4 documentation.
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds := credentials{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	ts, _ := time.Parse("20060102T150405Z", "20150830T123600Z")
+	signRequest(req, nil, creds, "us-east-1", "iam", ts)
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Expected Authorization header:\n%s\ngot:\n%s\n", expected, got)
+	}
+}
+
+func TestListMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(b))
+
+		if params.Get("Action") != "ListMetrics" || params.Get("MetricName") != "BytesOutPerSec" ||
+			params.Get("Dimensions.member.1.Name") != "Cluster Name" || params.Get("Dimensions.member.1.Value") != "test" {
+			t.Errorf("Unexpected params %v\n", params)
+		}
+
+		if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+			t.Errorf("Unexpected authorization %s\n", req.Header.Get("Authorization"))
+		}
+
+		// Results are paged.
+		if params.Get("NextToken") == "" {
+			fmt.Fprint(w, `<ListMetricsResponse><ListMetricsResult><Metrics>`+
+				`<member><Namespace>AWS/Kafka</Namespace><MetricName>BytesOutPerSec</MetricName><Dimensions>`+
+				`<member><Name>Cluster Name</Name><Value>test</Value></member>`+
+				`<member><Name>Broker ID</Name><Value>1</Value></member></Dimensions></member>`+
+				`</Metrics><NextToken>1</NextToken></ListMetricsResult></ListMetricsResponse>`)
+			return
+		}
+
+		fmt.Fprint(w, `<ListMetricsResponse><ListMetricsResult><Metrics>`+
+			`<member><Namespace>AWS/Kafka</Namespace><MetricName>BytesOutPerSec</MetricName><Dimensions>`+
+			`<member><Name>Cluster Name</Name><Value>test</Value></member></Dimensions></member>`+
+			`</Metrics></ListMetricsResult></ListMetricsResponse>`)
+	}))
+	defer srv.Close()
+
+	a := stubClient(srv)
+
+	metrics, err := a.listMetrics(context.Background(), "AWS/Kafka", "BytesOutPerSec", []Dimension{{Name: "Cluster Name", Value: "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metrics) != 2 || metrics[0].Namespace != "AWS/Kafka" || len(metrics[0].Dimensions) != 2 {
+		t.Fatalf("Unexpected metrics %+v\n", metrics)
+	}
+
+	if id, ok := metrics[0].dimension("Broker ID"); !ok || id != "1" {
+		t.Errorf("Expected Broker ID 1, got %s\n", id)
+	}
+
+	if _, ok := metrics[1].dimension("Broker ID"); ok {
+		t.Error("Unexpected Broker ID dimension")
+	}
+}
+
+func TestGetMetricData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(b))
+
+		if params.Get("Action") != "GetMetricData" || params.Get("MetricDataQueries.member.2.Id") != "m1" ||
+			params.Get("MetricDataQueries.member.2.MetricStat.Stat") != "Sum" || params.Get("MetricDataQueries.member.2.MetricStat.Period") != "60" ||
+			params.Get("MetricDataQueries.member.2.MetricStat.Metric.Dimensions.member.1.Value") != "1" {
+			t.Errorf("Unexpected params %v\n", params)
+		}
+
+		// Values for a query are paged.
+		if params.Get("NextToken") == "" {
+			fmt.Fprint(w, `<GetMetricDataResponse><GetMetricDataResult><MetricDataResults>`+
+				`<member><Id>m0</Id><Values><member>1048576</member></Values></member>`+
+				`<member><Id>m1</Id><Values><member>524288</member></Values></member>`+
+				`</MetricDataResults><NextToken>1</NextToken></GetMetricDataResult></GetMetricDataResponse>`)
+			return
+		}
+
+		fmt.Fprint(w, `<GetMetricDataResponse><GetMetricDataResult><MetricDataResults>`+
+			`<member><Id>m0</Id><Values><member>3145728</member></Values></member>`+
+			`<member><Id>m1</Id><Values/></member>`+
+			`</MetricDataResults></GetMetricDataResult></GetMetricDataResponse>`)
+	}))
+	defer srv.Close()
+
+	a := stubClient(srv)
+
+	metrics := []metric{
+		{Namespace: "AWS/Kafka", MetricName: "BytesOutPerSec", Dimensions: []Dimension{{Name: "Broker ID", Value: "0"}}},
+		{Namespace: "AWS/Kafka", MetricName: "BytesOutPerSec", Dimensions: []Dimension{{Name: "Broker ID", Value: "1"}}},
+	}
+
+	values, err := a.getMetricData(context.Background(), metrics, "Sum", 60, time.Now(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values[0]) != 2 || values[0][1] != 3145728 || len(values[1]) != 1 || values[1][0] != 524288 {
+		t.Errorf("Unexpected values %v\n", values)
+	}
+}
+
+func TestResponseErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// Query protocol XML and JSON protocol errors are returned.
+		{
+			http.StatusBadRequest,
+			`<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`,
+			"Throttling: Rate exceeded",
+		},
+		{
+			http.StatusForbidden,
+			`<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`,
+			"InvalidClientTokenId: The security token included in the request is invalid.",
+		},
+		{
+			http.StatusBadRequest,
+			`{"__type":"ResourceNotFoundException","message":"Event bus kafka does not exist."}`,
+			"ResourceNotFoundException: Event bus kafka does not exist.",
+		},
+		{http.StatusServiceUnavailable, "", "unexpected status 503"},
+		{http.StatusOK, "<ListMetricsResponse>", "XML syntax error on line 1: unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		a := stubClient(srv)
+
+		if _, err := a.listMetrics(context.Background(), "AWS/Kafka", "BytesOutPerSec", nil); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	events := make(chan string, 2)
+	failedEntries := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		failed := failedEntries
+		events <- string(b)
+
+		if req.Header.Get("X-Amz-Target") != "AWSEvents.PutEvents" {
+			fmt.Fprint(w, `<PublishResponse><PublishResult><MessageId>1</MessageId></PublishResult></PublishResponse>`)
+			return
+		}
+
+		if failed {
+			fmt.Fprint(w, `{"FailedEntryCount":1,"Entries":[{"ErrorCode":"InternalFailure","ErrorMessage":"internal error"}]}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"FailedEntryCount":0,"Entries":[{"EventId":"1"}]}`)
+	}))
+	defer srv.Close()
+
+	h := &cwHandler{
+		c:            stubClient(srv),
+		eventBusName: "kafka",
+		snsTopicARN:  "arn:aws:sns:us-east-1:123456789012:kafka",
+	}
+	h.c.endpoints = map[string]string{"events": srv.URL, "sns": srv.URL}
+
+	e := &kafkametrics.Event{Title: "throttle set", Text: "rate 100", Tags: []string{"a:b"}}
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"Entries":[{"Detail":"{\"Title\":\"throttle set\",\"Text\":\"rate 100\",\"Tags\":[\"a:b\"]}",` +
+		`"DetailType":"throttle set","EventBusName":"kafka","Source":"kafka-kit"}]}`
+	if got := <-events; got != expected {
+		t.Errorf("Expected event:\n%s\ngot:\n%s\n", expected, got)
+	}
+
+	params, _ := url.ParseQuery(<-events)
+	if params.Get("TopicArn") != h.snsTopicARN || params.Get("Subject") != "throttle set" || params.Get("Message") != "rate 100" {
+		t.Errorf("Unexpected publish params %v\n", params)
+	}
+
+	// Failed entries.
+	failedEntries = true
+
+	err := h.PostEvent(e)
+	if ae, ok := err.(*kafkametrics.APIError); !ok || ae.Request != "put event" || ae.Message != "InternalFailure: internal error" {
+		t.Errorf("Unexpected error %v\n", err)
+	}
+}
+
+func TestGetMetricDataIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<GetMetricDataResponse><GetMetricDataResult><MetricDataResults>`+
+			`<member><Id></Id><Values><member>1</member></Values></member>`+
+			`<member><Id>m</Id><Values><member>2</member></Values></member>`+
+			`<member><Id>m5</Id><Values><member>3</member></Values></member>`+
+			`<member><Id>m1</Id><Values><member>4</member></Values></member>`+
+			`</MetricDataResults></GetMetricDataResult></GetMetricDataResponse>`)
+	}))
+	defer srv.Close()
+
+	a := stubClient(srv)
+
+	metrics := []metric{{MetricName: "BytesOutPerSec"}, {MetricName: "BytesInPerSec"}}
+
+	// Results with malformed or unknown IDs are ignored.
+	values, err := a.getMetricData(context.Background(), metrics, "Average", 60, time.Now(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 || len(values[1]) != 1 || values[1][0] != 4 {
+		t.Errorf("Unexpected values %v\n", values)
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &cwHandler{
+		brokerIDDimension: "InstanceId",
+		brokerIDMap:       map[string]int{"i-0": 1001},
+		divideByPeriod:    true,
+		period:            60,
+	}
+
+	metrics := []metric{
+		{Dimensions: []Dimension{{Name: "InstanceId", Value: "i-0"}}},
+		{Dimensions: []Dimension{{Name: "InstanceId", Value: "i-1"}}},
+		{Dimensions: []Dimension{{Name: "InstanceId", Value: "i-2"}}},
+	}
+
+	values := map[int][]float64{
+		0: {60 * 1048576},
+		1: {60 * 1048576},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, metrics, values, 0)
+
+	// Sums are divided by the period.
+	if b := bm[1001]; n != 1 || b == nil || b.NetTX != 1.00 || b.Host != "i-0" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1001])
+	}
+
+	expected := []string{"No points for InstanceId i-2", "Unable to map InstanceId values to broker IDs: i-1"}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+// stubClient returns an awsClient for the "monitoring" service at the test
+// server.
+func stubClient(srv *httptest.Server) *awsClient {
+	return &awsClient{
+		c:         srv.Client(),
+		creds:     credentials{accessKeyID: "id", secretAccessKey: "secret"},
+		region:    "us-east-1",
+		endpoints: map[string]string{"monitoring": srv.URL},
+	}
+}
+
+func TestInstanceTypeResolver(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		b, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(b))
+
+		if params.Get("Action") != "DescribeInstances" || params.Get("Filter.1.Value.1") != "host0" {
+			fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet/></DescribeInstancesResponse>`)
+			return
+		}
+
+		fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet><item>`+
+			`<instanceId>i-1</instanceId><instanceType>m5.large</instanceType></item></instancesSet></item></reservationSet></DescribeInstancesResponse>`)
+	}))
+	defer srv.Close()
+
+	r, err := NewInstanceTypeResolver(&ResolverConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second lookup is cached.
+	for i := 0; i < 2; i++ {
+		if it, err := r.InstanceType("host0"); err != nil || it != "m5.large" {
+			t.Errorf("Expected instance type m5.large, got %s, %v\n", it, err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d\n", requests)
+	}
+
+	if _, err := r.InstanceType("host1"); err == nil {
+		t.Error("Expected non-nil error")
+	}
+}
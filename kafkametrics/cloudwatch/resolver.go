@@ -0,0 +1,91 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// ResolverConfig holds EC2 InstanceTypeResolver configuration parameters.
+type ResolverConfig struct {
+	// AWS region.
+	Region string
+	// AWS credentials. If unset, the standard AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN env vars are used.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// HostFilter is the EC2 DescribeInstances filter that broker hostnames
+	// are matched against. Defaults to "private-dns-name".
+	// Example: "tag:Name"
+	HostFilter string
+	// Endpoint optionally overrides the EC2 endpoint URL.
+	Endpoint string
+}
+
+type ec2Resolver struct {
+	sync.Mutex
+	c          *awsClient
+	hostFilter string
+	cache      map[string]string
+}
+
+// NewInstanceTypeResolver takes a *ResolverConfig and returns a
+// kafkametrics.InstanceTypeResolver that looks up instance types with the
+// EC2 DescribeInstances API. Resolved instance types are cached.
+func NewInstanceTypeResolver(c *ResolverConfig) (kafkametrics.InstanceTypeResolver, error) {
+	creds := newCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+
+	switch {
+	case c.Region == "":
+		return nil, errors.New("region must be specified")
+	case creds.accessKeyID == "" || creds.secretAccessKey == "":
+		return nil, errors.New("AWS credentials must be specified")
+	}
+
+	r := &ec2Resolver{
+		c: &awsClient{
+			c:      &http.Client{Timeout: 30 * time.Second},
+			creds:  creds,
+			region: c.Region,
+		},
+		hostFilter: "private-dns-name",
+		cache:      map[string]string{},
+	}
+
+	if c.HostFilter != "" {
+		r.hostFilter = c.HostFilter
+	}
+
+	if c.Endpoint != "" {
+		r.c.endpoints = map[string]string{"ec2": c.Endpoint}
+	}
+
+	return r, nil
+}
+
+// InstanceType returns the instance type of the EC2 instance for the host.
+func (r *ec2Resolver) InstanceType(host string) (string, error) {
+	r.Lock()
+	it, cached := r.cache[host]
+	r.Unlock()
+
+	if cached {
+		return it, nil
+	}
+
+	it, err := r.c.describeInstanceType(context.Background(), r.hostFilter, host)
+	if err != nil {
+		return "", err
+	}
+
+	r.Lock()
+	r.cache[host] = it
+	r.Unlock()
+
+	return it, nil
+}
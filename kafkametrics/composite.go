@@ -0,0 +1,182 @@
+package kafkametrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FailurePolicy determines which GetMetrics errors cause a CompositeHandler
+// to fall back to the next metrics Handler.
+type FailurePolicy int
+
+const (
+	// FallbackOnAPIError falls back when a Handler returns an *APIError.
+	FallbackOnAPIError FailurePolicy = iota
+	// FallbackOnNoResults falls back when a Handler returns an *APIError
+	// or *NoResults.
+	FallbackOnNoResults
+	// FallbackOnAnyError falls back when a Handler returns any error,
+	// including *PartialResults.
+	FallbackOnAnyError
+)
+
+// ParseFailurePolicy returns the FailurePolicy for the name "api-error",
+// "no-results" or "any-error".
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch s {
+	case "api-error":
+		return FallbackOnAPIError, nil
+	case "no-results":
+		return FallbackOnNoResults, nil
+	case "any-error":
+		return FallbackOnAnyError, nil
+	default:
+		return 0, fmt.Errorf("unknown failure policy: %s", s)
+	}
+}
+
+// CompositeConfig holds CompositeHandler configuration parameters.
+type CompositeConfig struct {
+	// Metrics are the Handlers used for GetMetrics, in priority order.
+	Metrics []Handler
+	// Events are the Handlers that events are posted to.
+	Events []Handler
+	// FailurePolicy determines when the next metrics Handler is tried.
+	FailurePolicy FailurePolicy
+	// EventTags are default tags, such as the cluster name or environment,
+	// appended to every event posted to the event Handlers.
+	EventTags []string
+}
+
+// CompositeHandler is a Handler that fetches metrics from the first of
+// several Handlers to succeed and posts events to several Handlers.
+type CompositeHandler struct {
+	metrics       []Handler
+	events        []Handler
+	failurePolicy FailurePolicy
+	eventTags     []string
+}
+
+// NewCompositeHandler takes a *CompositeConfig and returns a
+// *CompositeHandler.
+func NewCompositeHandler(c *CompositeConfig) (*CompositeHandler, error) {
+	if len(c.Metrics) == 0 {
+		return nil, errors.New("at least one metrics handler must be specified")
+	}
+
+	return &CompositeHandler{
+		metrics:       c.Metrics,
+		events:        c.Events,
+		failurePolicy: c.FailurePolicy,
+		eventTags:     c.EventTags,
+	}, nil
+}
+
+// GetMetrics requests metrics from each metrics Handler in priority order,
+// returning the results of the first that doesn't fail according to the
+// FailurePolicy. If all Handlers fail, the errors of the last are returned.
+func (c *CompositeHandler) GetMetrics() (BrokerMetrics, []error) {
+	return c.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. The
+// context is passed to each metrics Handler.
+func (c *CompositeHandler) GetMetricsContext(ctx context.Context) (BrokerMetrics, []error) {
+	var bm BrokerMetrics
+	var errs []error
+
+	for _, h := range c.metrics {
+		bm, errs = GetMetricsContext(ctx, h)
+		if !c.shouldFallback(errs) {
+			return bm, errs
+		}
+	}
+
+	return bm, errs
+}
+
+// shouldFallback returns whether the errors are failures according to
+// the FailurePolicy.
+func (c *CompositeHandler) shouldFallback(errs []error) bool {
+	for _, err := range errs {
+		switch err.(type) {
+		case *APIError:
+			return true
+		case *NoResults:
+			if c.failurePolicy >= FallbackOnNoResults {
+				return true
+			}
+		default:
+			if c.failurePolicy == FallbackOnAnyError {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Retries returns the total retries of any metrics and event Handlers that
+// implement RetryReporter. Handlers used for both metrics and events are
+// counted once.
+func (c *CompositeHandler) Retries() uint64 {
+	var n uint64
+	var seen []Handler
+
+	for _, h := range append(append([]Handler{}, c.metrics...), c.events...) {
+		rr, ok := h.(RetryReporter)
+		if !ok || containsHandler(seen, h) {
+			continue
+		}
+		seen = append(seen, h)
+		n += rr.Retries()
+	}
+
+	return n
+}
+
+// containsHandler returns whether the Handler is in the []Handler.
+func containsHandler(l []Handler, h Handler) bool {
+	if !reflect.TypeOf(h).Comparable() {
+		return false
+	}
+
+	for _, e := range l {
+		if e == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PostEvent posts the event to all event Handlers, with any EventTags
+// appended. An error describing all failures is returned if any Handler
+// fails.
+func (c *CompositeHandler) PostEvent(e *Event) error {
+	return c.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The context
+// is passed to each event Handler.
+func (c *CompositeHandler) PostEventContext(ctx context.Context, e *Event) error {
+	var failures []string
+
+	e = WithTags(e, c.eventTags)
+
+	for _, h := range c.events {
+		if err := PostEventContext(ctx, h, e); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("error posting event to %d of %d handlers: %s",
+			len(failures), len(c.events), strings.Join(failures, "; "))
+	}
+
+	return nil
+}
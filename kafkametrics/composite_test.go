@@ -0,0 +1,116 @@
+package kafkametrics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type failingStub struct {
+	err    error
+	events int
+	last   *Event
+}
+
+func (f *failingStub) GetMetrics() (BrokerMetrics, []error) {
+	return nil, []error{f.err}
+}
+
+func (f *failingStub) PostEvent(e *Event) error {
+	f.events++
+	f.last = e
+	return f.err
+}
+
+func TestCompositeGetMetrics(t *testing.T) {
+	apiErr := &failingStub{err: &APIError{Request: "test", Message: "test"}}
+	noResults := &failingStub{err: &NoResults{Message: "test"}}
+	partial := &failingStub{err: &PartialResults{Message: "test"}}
+
+	tests := []struct {
+		primary  Handler
+		policy   FailurePolicy
+		fallback bool
+	}{
+		{apiErr, FallbackOnAPIError, true},
+		{noResults, FallbackOnAPIError, false},
+		{noResults, FallbackOnNoResults, true},
+		{partial, FallbackOnNoResults, false},
+		{partial, FallbackOnAnyError, true},
+	}
+
+	for i, test := range tests {
+		c, err := NewCompositeHandler(&CompositeConfig{
+			Metrics:       []Handler{test.primary, &Stub{}},
+			FailurePolicy: test.policy,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The fallback Stub returns metrics without errors.
+		bm, errs := c.GetMetrics()
+		if fellBack := len(bm) == 10 && errs == nil; fellBack != test.fallback {
+			t.Errorf("[test %d] Expected fallback %v, got %v\n", i, test.fallback, fellBack)
+		}
+	}
+
+	// All handlers fail.
+	c, _ := NewCompositeHandler(&CompositeConfig{Metrics: []Handler{apiErr, noResults}})
+	if _, errs := c.GetMetrics(); len(errs) != 1 || errs[0] != noResults.err {
+		t.Errorf("Expected errors from the last handler, got %v\n", errs)
+	}
+}
+
+func TestCompositePostEvent(t *testing.T) {
+	ok := &failingStub{}
+	failing := &failingStub{err: errors.New("failed")}
+
+	c, _ := NewCompositeHandler(&CompositeConfig{
+		Metrics: []Handler{&Stub{}},
+		Events:  []Handler{failing, ok},
+	})
+
+	if err := c.PostEvent(&Event{}); err == nil {
+		t.Error("Expected error")
+	}
+
+	// Events are posted to all handlers regardless of failures.
+	if ok.events != 1 || failing.events != 1 {
+		t.Errorf("Expected events posted to all handlers")
+	}
+}
+
+func TestCompositeEventTags(t *testing.T) {
+	sink := &failingStub{}
+	c, _ := NewCompositeHandler(&CompositeConfig{
+		Metrics:   []Handler{&Stub{}},
+		Events:    []Handler{sink},
+		EventTags: []string{"cluster:test", "env:dev"},
+	})
+
+	e := &Event{Title: "test", Tags: []string{"name:test", "env:dev"}}
+	if err := c.PostEvent(e); err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	expected := []string{"name:test", "env:dev", "cluster:test"}
+	if !reflect.DeepEqual(sink.last.Tags, expected) {
+		t.Errorf("Expected tags %v, got %v\n", expected, sink.last.Tags)
+	}
+
+	// The original event is unchanged.
+	if len(e.Tags) != 2 {
+		t.Errorf("Expected original event tags unchanged, got %v\n", e.Tags)
+	}
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	if p, err := ParseFailurePolicy("no-results"); err != nil || p != FallbackOnNoResults {
+		t.Errorf("Unexpected policy %v: %v\n", p, err)
+	}
+
+	if _, err := ParseFailurePolicy("invalid"); err == nil {
+		t.Error("Expected error")
+	}
+}
@@ -0,0 +1,117 @@
+package kafkametrics
+
+import (
+	"context"
+	"time"
+)
+
+// ContextHandler is a Handler that additionally provides context-aware
+// methods, allowing callers to enforce deadlines and cancel in-flight
+// requests.
+type ContextHandler interface {
+	Handler
+	GetMetricsContext(context.Context) (BrokerMetrics, []error)
+	PostEventContext(context.Context, *Event) error
+}
+
+// GetMetricsContext requests metrics from the Handler, returning an *APIError
+// if the context is done before the request completes. If the Handler is a
+// ContextHandler, the context is passed through. Otherwise, such as for the
+// file backend, the request runs in a goroutine that isn't cancelled: it
+// continues until the request completes and its results are discarded if
+// the context is done first.
+func GetMetricsContext(ctx context.Context, h Handler) (BrokerMetrics, []error) {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch.GetMetricsContext(ctx)
+	}
+
+	type result struct {
+		bm   BrokerMetrics
+		errs []error
+	}
+
+	// Buffered so that an abandoned request doesn't leak the goroutine.
+	done := make(chan result, 1)
+	go func() {
+		bm, errs := h.GetMetrics()
+		done <- result{bm, errs}
+	}()
+
+	select {
+	case r := <-done:
+		return r.bm, r.errs
+	case <-ctx.Done():
+		return nil, []error{&APIError{
+			Request: "metrics query",
+			Message: ctx.Err().Error(),
+		}}
+	}
+}
+
+// PostEventContext posts the event to the Handler, returning an *APIError if
+// the context is done before the request completes. Context handling is the
+// same as GetMetricsContext.
+func PostEventContext(ctx context.Context, h Handler, e *Event) error {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch.PostEventContext(ctx, e)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.PostEvent(e)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return &APIError{
+			Request: "post event",
+			Message: ctx.Err().Error(),
+		}
+	}
+}
+
+// TimeoutHandler is a ContextHandler that applies a timeout to each request
+// of the wrapped Handler.
+type TimeoutHandler struct {
+	h       Handler
+	timeout time.Duration
+}
+
+// NewTimeoutHandler takes a Handler and timeout and returns a
+// *TimeoutHandler.
+func NewTimeoutHandler(h Handler, timeout time.Duration) *TimeoutHandler {
+	return &TimeoutHandler{
+		h:       h,
+		timeout: timeout,
+	}
+}
+
+// GetMetrics requests metrics from the wrapped Handler with the timeout.
+func (t *TimeoutHandler) GetMetrics() (BrokerMetrics, []error) {
+	return t.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext requests metrics from the wrapped Handler with the
+// timeout applied to the context.
+func (t *TimeoutHandler) GetMetricsContext(ctx context.Context) (BrokerMetrics, []error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return GetMetricsContext(ctx, t.h)
+}
+
+// PostEvent posts the event to the wrapped Handler with the timeout.
+func (t *TimeoutHandler) PostEvent(e *Event) error {
+	return t.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext posts the event to the wrapped Handler with the timeout
+// applied to the context.
+func (t *TimeoutHandler) PostEventContext(ctx context.Context, e *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return PostEventContext(ctx, t.h, e)
+}
@@ -0,0 +1,77 @@
+package kafkametrics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type slowStub struct {
+	Stub
+	delay time.Duration
+}
+
+func (s *slowStub) GetMetrics() (BrokerMetrics, []error) {
+	time.Sleep(s.delay)
+	return s.Stub.GetMetrics()
+}
+
+func (s *slowStub) PostEvent(e *Event) error {
+	time.Sleep(s.delay)
+	return s.Stub.PostEvent(e)
+}
+
+func TestGetMetricsContext(t *testing.T) {
+	bm, errs := GetMetricsContext(context.Background(), &Stub{})
+	if errs != nil || len(bm) != 10 {
+		t.Errorf("Expected 10 brokers and no errors, got %d, %v\n", len(bm), errs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	bm, errs = GetMetricsContext(ctx, &slowStub{delay: time.Second})
+	if bm != nil || len(errs) != 1 {
+		t.Fatalf("Expected 1 error and no results, got %v, %v\n", bm, errs)
+	}
+
+	if _, ok := errs[0].(*APIError); !ok {
+		t.Errorf("Expected *APIError, got %T\n", errs[0])
+	}
+}
+
+func TestTimeoutHandler(t *testing.T) {
+	h := NewTimeoutHandler(&slowStub{delay: time.Second}, 10*time.Millisecond)
+
+	if _, errs := h.GetMetrics(); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v\n", errs)
+	}
+
+	if err := h.PostEvent(&Event{}); err == nil {
+		t.Error("Expected non-nil error")
+	}
+
+	h = NewTimeoutHandler(&slowStub{}, time.Second)
+
+	if bm, errs := h.GetMetrics(); errs != nil || len(bm) != 10 {
+		t.Errorf("Expected 10 brokers and no errors, got %d, %v\n", len(bm), errs)
+	}
+
+	if err := h.PostEvent(&Event{}); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+}
+
+func TestCompositeGetMetricsContext(t *testing.T) {
+	// The slow primary times out with an *APIError, falling back to the Stub.
+	c, _ := NewCompositeHandler(&CompositeConfig{
+		Metrics: []Handler{
+			NewTimeoutHandler(&slowStub{delay: time.Second}, 10*time.Millisecond),
+			&Stub{},
+		},
+	})
+
+	if bm, errs := c.GetMetricsContext(context.Background()); errs != nil || len(bm) != 10 {
+		t.Errorf("Expected fallback with 10 brokers, got %d, %v\n", len(bm), errs)
+	}
+}
@@ -3,8 +3,13 @@
 package datadog
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
@@ -18,6 +23,16 @@ type Config struct {
 	APIKey string
 	// Datadog app key.
 	AppKey string
+	// Site is the optional Datadog site, e.g. "datadoghq.eu",
+	// "us3.datadoghq.com" or "ddog-gov.com", or a full API URL. Defaults
+	// to "datadoghq.com".
+	Site string
+	// ProxyURL is an optional HTTP(S) proxy URL for Datadog API requests.
+	// If unset, the HTTPS_PROXY environment variable is used.
+	ProxyURL string
+	// CACertFile is an optional path to a PEM CA bundle trusted in addition
+	// to the system roots, e.g. for TLS-intercepting proxies.
+	CACertFile string
 	// NetworkTXQuery is a query string that should return the outbound
 	// network metrics by host for the reference Kafka brokers.
 	// Example (Datadog): "avg:system.net.bytes_sent{service:kafka} by {host}"
@@ -26,6 +41,59 @@ type Config struct {
 	// network metrics by host for the reference Kafka brokers.
 	// Example (Datadog): "avg:system.net.bytes_rcvd{service:kafka} by {host}"
 	NetworkRXQuery string
+	// DiskUsedQuery is an optional query string that should return the disk
+	// used in bytes by host for the reference Kafka brokers.
+	// Example (Datadog): "avg:system.disk.used{service:kafka,device:/data} by {host}"
+	DiskUsedQuery string
+	// DiskFreeQuery is an optional query string that should return the disk
+	// free in bytes by host for the reference Kafka brokers.
+	// Example (Datadog): "avg:system.disk.free{service:kafka,device:/data} by {host}"
+	DiskFreeQuery string
+	// CPUQuery is an optional query string that should return the CPU
+	// utilization percent by host for the reference Kafka brokers.
+	// Example (Datadog): "100 - avg:system.cpu.idle{service:kafka} by {host}"
+	CPUQuery string
+	// LeaderBytesOutQuery is an optional query string that should return the
+	// bytes out serving client fetch requests by host for the reference
+	// Kafka brokers.
+	// Example (Datadog): "avg:kafka.net.bytes_out.rate{service:kafka} by {host}"
+	LeaderBytesOutQuery string
+	// ReplicationBytesOutQuery is an optional query string that should return
+	// the bytes out serving follower replication by host for the reference
+	// Kafka brokers.
+	// Example (Datadog): "avg:kafka.replication.bytes_out.rate{service:kafka} by {host}"
+	ReplicationBytesOutQuery string
+	// DiskIOQuery is an optional query string that should return the disk
+	// IO (reads and writes) in bytes/s by host for the reference Kafka
+	// brokers.
+	// Example (Datadog): "avg:system.io.rkb_s{service:kafka} by {host} * 1024 + avg:system.io.wkb_s{service:kafka} by {host} * 1024"
+	DiskIOQuery string
+	// AlterLogDirsLagQuery is an optional query string that should return
+	// the max lag of replicas being moved between log dirs by host for the
+	// reference Kafka brokers.
+	// Example (Datadog): "max:kafka.replica_alter_log_dirs_manager.max_lag{service:kafka} by {host}"
+	AlterLogDirsLagQuery string
+	// ConsumerLagQuery is an optional query string that should return the
+	// lag of consumer groups, grouped by the ConsumerGroupTag. Required for
+	// GetConsumerLag.
+	// Example (Datadog): "max:kafka.consumer_lag{consumer_group:payments} by {consumer_group}"
+	ConsumerLagQuery string
+	// ConsumerGroupTag is the tag name for consumer group names in the
+	// ConsumerLagQuery series. Defaults to "consumer_group".
+	ConsumerGroupTag string
+	// Queries is an optional map of names to query strings for any
+	// additional metrics by host for the reference Kafka brokers. Values
+	// are populated in the Broker Metrics map by name, unconverted.
+	// Example (Datadog): {"disk_in_use": "avg:system.disk.in_use{service:kafka} by {host}"}
+	Queries map[string]string
+	// Cluster is an optional Kafka cluster name available to query templates
+	// as {{.Cluster}}. All queries may be Go templates, for which the
+	// MetricsWindow is also available as {{.Window}}.
+	// Example (Datadog): "avg:system.net.bytes_sent{service:kafka,cluster:{{.Cluster}}} by {host}"
+	Cluster string
+	// QueryVars are optional additional query template variables,
+	// referenced as {{.Vars.name}}.
+	QueryVars map[string]string
 	// BrokerIDTag is the host tag name for Kafka broker IDs.
 	BrokerIDTag string
 	// InstanceTypeTag is the tag name for the kafka broker's instance type.
@@ -33,18 +101,84 @@ type Config struct {
 	// MetricsWindow specifies the window size of timeseries data to evaluate
 	// in seconds. All values for the window are averaged.
 	MetricsWindow int
+	// LazyValidation defers API and app key validation from NewHandler to
+	// the first GetMetrics or PostEvent request, which return any
+	// validation errors until validation succeeds. This avoids startup
+	// failures where the validate endpoint is briefly unavailable.
+	LazyValidation bool
+	// Timeout is an optional HTTP request timeout in seconds for Datadog API
+	// requests.
+	Timeout int
+	// RateLimit is an optional maximum rate of Datadog API requests per
+	// second, shared by all metrics, host tags and event requests.
+	RateLimit float64
+	// RateLimitBurst is the number of requests that may be made at once
+	// before the RateLimit applies. Defaults to the RateLimit, rounded up.
+	RateLimitBurst int
+	// HostTagConcurrency is the maximum number of concurrent host tags
+	// requests. Defaults to 10.
+	HostTagConcurrency int
+	// HostTagCacheTTL is the time in seconds that complete broker host tags
+	// are cached for. Cached tags never expire if unset.
+	HostTagCacheTTL int
+	// TagsFromScope reads the BrokerIDTag and InstanceTypeTag values from
+	// the network metric series scopes rather than the host tags API. The
+	// network queries must be grouped by the host and both tags.
+	// Example (Datadog): "avg:system.net.bytes_sent{service:kafka} by {host,broker_id,instance-type}"
+	TagsFromScope bool
+	// Aggregation optionally reduces the points of each window to a single
+	// value client-side: "avg", "max" or a percentile such as "p95" or
+	// "p99". Queries are then rolled up by the Period rather than the whole
+	// MetricsWindow. If unset, the window avg rollup is used.
+	Aggregation string
+	// Period is the rollup period in seconds used with an Aggregation.
+	// Defaults to 60.
+	Period int
+	// RetainSeries populates the full windowed series of each metric in the
+	// Broker Series map. Series are most useful with an Aggregation, where
+	// queries are rolled up by the Period.
+	RetainSeries bool
+	// MaxPointAge is an optional maximum age in seconds of the most recent
+	// point of each series. Brokers with older points, e.g. due to a stopped
+	// agent, are skipped as stale.
+	MaxPointAge int
+	// InstanceTypeResolver optionally resolves the instance type of brokers
+	// missing the InstanceTypeTag. Brokers that can't be resolved are
+	// skipped.
+	InstanceTypeResolver kafkametrics.InstanceTypeResolver
 }
 
 type ddHandler struct {
 	c               *dd.Client
+	hc              *http.Client
+	apiKey          string
+	appKey          string
+	site            string
 	netTXQuery      string
 	netRXQuery      string
+	optionalQueries []string
+	queries         map[string]string
+	queryNames      []string
+	lagQuery        string
+	groupTag        string
 	brokerIDTag     string
 	instanceTypeTag string
 	metricsWindow   int
+	tagCacheMu      sync.Mutex
 	tagCache        map[string][]string
+	tagCacheTimes   map[string]time.Time
+	tagCacheTTL     time.Duration
+	tagsFromScope   bool
 	keysRegex       *regexp.Regexp
 	redactionSub    []byte
+	limiter         *rateLimiter
+	hostTagConc     int
+	resolver        kafkametrics.InstanceTypeResolver
+	maxPointAge     time.Duration
+	aggregation     kafkametrics.Aggregation
+	retainSeries    bool
+	validateMu      sync.Mutex
+	validated       bool
 }
 
 // NewHandler takes a *Config and returns a Handler, along with any credential
@@ -57,67 +191,304 @@ func NewHandler(c *Config) (kafkametrics.Handler, error) {
 	// wrapped errors from the client.
 	keysRegex := regexp.MustCompile(fmt.Sprintf("%s|%s", c.APIKey, c.AppKey))
 
+	// Queries are rolled up to a single window avg point unless points are
+	// aggregated client-side.
+	var aggregation kafkametrics.Aggregation
+	rollup := c.MetricsWindow
+
+	if c.Aggregation != "" {
+		a, err := kafkametrics.ParseAggregation(c.Aggregation)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregation = a
+		rollup = 60
+		if c.Period > 0 {
+			rollup = c.Period
+		}
+	}
+
+	// Render any query templates.
+	vars := kafkametrics.QueryVars{
+		Cluster: c.Cluster,
+		Window:  c.MetricsWindow,
+		Vars:    c.QueryVars,
+	}
+
+	queries := []string{
+		c.NetworkTXQuery,
+		c.NetworkRXQuery,
+		c.DiskUsedQuery,
+		c.DiskFreeQuery,
+		c.CPUQuery,
+		c.LeaderBytesOutQuery,
+		c.ReplicationBytesOutQuery,
+		c.DiskIOQuery,
+		c.AlterLogDirsLagQuery,
+		c.ConsumerLagQuery,
+	}
+
+	if err := kafkametrics.RenderQueries(queries, vars); err != nil {
+		return nil, err
+	}
+
 	h := &ddHandler{
-		netTXQuery:      fmt.Sprintf("%s.rollup(avg, %d)", c.NetworkTXQuery, c.MetricsWindow),
-		netRXQuery:      fmt.Sprintf("%s.rollup(avg, %d)", c.NetworkRXQuery, c.MetricsWindow),
+		apiKey:          c.APIKey,
+		appKey:          c.AppKey,
+		site:            c.Site,
+		netTXQuery:      fmt.Sprintf("%s.rollup(avg, %d)", queries[0], rollup),
+		netRXQuery:      fmt.Sprintf("%s.rollup(avg, %d)", queries[1], rollup),
+		optionalQueries: []string{"", "", "", "", "", "", ""},
+		queries:         map[string]string{},
+		groupTag:        "consumer_group",
 		metricsWindow:   c.MetricsWindow,
 		brokerIDTag:     c.BrokerIDTag,
 		instanceTypeTag: c.InstanceTypeTag,
 		tagCache:        make(map[string][]string),
+		tagCacheTimes:   make(map[string]time.Time),
+		tagCacheTTL:     time.Duration(c.HostTagCacheTTL) * time.Second,
+		tagsFromScope:   c.TagsFromScope,
 		keysRegex:       keysRegex,
 		redactionSub:    []byte("xxx"),
+		hostTagConc:     10,
+		resolver:        c.InstanceTypeResolver,
+		maxPointAge:     time.Duration(c.MaxPointAge) * time.Second,
+		aggregation:     aggregation,
+		retainSeries:    c.RetainSeries,
+	}
+
+	if c.HostTagConcurrency > 0 {
+		h.hostTagConc = c.HostTagConcurrency
+	}
+
+	for i, q := range queries[2:9] {
+		if q != "" {
+			h.optionalQueries[i] = fmt.Sprintf("%s.rollup(avg, %d)", q, rollup)
+		}
 	}
 
-	client := dd.NewClient(c.APIKey, c.AppKey)
+	if q := queries[9]; q != "" {
+		h.lagQuery = fmt.Sprintf("%s.rollup(avg, %d)", q, rollup)
+	}
+
+	if c.ConsumerGroupTag != "" {
+		h.groupTag = c.ConsumerGroupTag
+	}
+
+	if c.RateLimit > 0 {
+		h.limiter = newRateLimiter(c.RateLimit, c.RateLimitBurst)
+	}
+
+	for name, q := range c.Queries {
+		q, err := kafkametrics.RenderQuery(q, vars)
+		if err != nil {
+			return nil, err
+		}
+		h.queries[name] = fmt.Sprintf("%s.rollup(avg, %d)", q, rollup)
+		h.queryNames = append(h.queryNames, name)
+	}
+	sort.Strings(h.queryNames)
+
+	hc, err := httpClient(c)
+	if err != nil {
+		return nil, err
+	}
+
+	h.hc = hc
+	h.c = h.newClient(hc)
 
 	// Validate.
-	ok, err := client.Validate()
+	if !c.LazyValidation {
+		if err := h.validate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// newClient returns a *dd.Client for the handler keys and site that makes
+// requests with the *http.Client.
+func (h *ddHandler) newClient(hc *http.Client) *dd.Client {
+	client := dd.NewClient(h.apiKey, h.appKey)
+	client.HttpClient = hc
+	if h.site != "" {
+		client.SetBaseUrl(siteURL(h.site))
+	}
+
+	return client
+}
+
+// client returns a *dd.Client whose requests are bound to the context. The
+// client does not accept contexts, so a client is created for any context
+// that can be done. Retries of failed requests stop at the context deadline.
+func (h *ddHandler) client(ctx context.Context) *dd.Client {
+	if ctx.Done() == nil || h.hc == nil {
+		return h.c
+	}
+
+	client := h.newClient(&http.Client{
+		Transport: contextTransport{ctx: ctx, rt: h.hc.Transport},
+		Timeout:   h.hc.Timeout,
+	})
+
+	// Retries stop at the deadline; a zero RetryTimeout retries indefinitely.
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < client.RetryTimeout {
+		client.RetryTimeout = time.Until(d)
+		if client.RetryTimeout <= 0 {
+			client.RetryTimeout = time.Nanosecond
+		}
+	}
+
+	return client
+}
+
+// wait blocks until the rate limiter permits a request, returning an
+// *APIError for the request if the context is done first.
+func (h *ddHandler) wait(ctx context.Context, request string) error {
+	if err := h.limiter.waitContext(ctx); err != nil {
+		return &kafkametrics.APIError{
+			Request: request,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// validate validates the API and app keys, returning an *APIError if
+// validation fails. Successful validation is only performed once.
+func (h *ddHandler) validate(ctx context.Context) error {
+	h.validateMu.Lock()
+	defer h.validateMu.Unlock()
+
+	if h.validated {
+		return nil
+	}
+
+	if err := h.wait(ctx, "validate credentials"); err != nil {
+		return err
+	}
+
+	ok, err := h.client(ctx).Validate()
 	if err != nil {
-		return nil, &kafkametrics.APIError{
+		return &kafkametrics.APIError{
 			Request: "validate credentials",
 			Message: h.scrubbedErrorText(err),
 		}
 	}
 
 	if !ok {
-		return nil, &kafkametrics.APIError{
+		return &kafkametrics.APIError{
 			Request: "validate credentials",
 			Message: "invalid API or app key",
 		}
 	}
 
-	h.c = client
+	h.validated = true
 
-	return h, nil
+	return nil
 }
 
 // PostEvent posts an event to the Datadog API.
 func (h *ddHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext posts an event to the Datadog API, cancelling the request
+// if the context is done.
+func (h *ddHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
 	m := &dd.Event{
 		Title: &e.Title,
 		Text:  &e.Text,
 		Tags:  e.Tags,
 	}
 
-	_, err := h.c.PostEvent(m)
+	if e.AggregationKey != "" {
+		m.Aggregation = &e.AggregationKey
+	}
+
+	if e.AlertType != "" {
+		m.AlertType = &e.AlertType
+	}
+
+	if e.SourceTypeName != "" {
+		m.SourceType = &e.SourceTypeName
+	}
+
+	if err := h.validate(ctx); err != nil {
+		return err
+	}
+
+	if err := h.wait(ctx, "post event"); err != nil {
+		return err
+	}
+
+	_, err := h.client(ctx).PostEvent(m)
 	return err
 }
 
+// Gauge posts a gauge metric point to the Datadog API.
+func (h *ddHandler) Gauge(name string, value float64, tags []string) error {
+	now := float64(time.Now().Unix())
+	metricType := "gauge"
+
+	m := dd.Metric{
+		Metric: &name,
+		Points: []dd.DataPoint{{&now, &value}},
+		Type:   &metricType,
+		Tags:   tags,
+	}
+
+	if err := h.validate(context.Background()); err != nil {
+		return err
+	}
+
+	h.limiter.wait()
+	if err := h.c.PostMetrics([]dd.Metric{m}); err != nil {
+		return &kafkametrics.APIError{
+			Request: "post metrics",
+			Message: h.scrubbedErrorText(err),
+		}
+	}
+
+	return nil
+}
+
 // GetMetrics requests broker metrics and metadata from the Datadog API and
 // returns a BrokerMetrics. If any errors are encountered (i.e. complete
 // metadata for a given broker can't be retrieved), the broker will not
 // be included in the BrokerMetrics.
 // TODO(jamie): retries.
 func (h *ddHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext requests broker metrics as GetMetrics, cancelling any
+// requests in flight if the context is done.
+func (h *ddHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
 	var errors []error
 	var mergedBrokerList []*kafkametrics.Broker
+	// Series scope tags by host.
+	scopes := map[string][]string{}
+
+	if err := h.validate(ctx); err != nil {
+		return nil, []error{err}
+	}
+
+	c := h.client(ctx)
 
 	start := time.Now().Add(-time.Duration(h.metricsWindow) * time.Second).Unix()
 
 	// Get network metrics for tx and rx.
 	var lastLen int
 	for i, query := range []string{h.netTXQuery, h.netRXQuery} {
-		series, err := h.c.QueryMetrics(start, time.Now().Unix(), query)
+		if err := h.wait(ctx, "metrics query"); err != nil {
+			return nil, []error{err}
+		}
+
+		series, err := c.QueryMetrics(start, time.Now().Unix(), query)
 		if err != nil {
 			return nil, []error{&kafkametrics.APIError{
 				Request: "metrics query",
@@ -131,9 +502,20 @@ func (h *ddHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
 			}}
 		}
 
+		series, errs := h.freshSeries(series, time.Now())
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		if h.tagsFromScope {
+			for _, ts := range series {
+				scopes[tagValFromScope(ts.GetScope(), "host")] = strings.Split(ts.GetScope(), ",")
+			}
+		}
+
 		// Get a []*kafkametrics.Broker from the series. Brokers with missing
 		// points are excluded from blist.
-		blist, errs := brokersFromSeries(series, i)
+		blist, errs := brokersFromSeries(series, i, h.aggregation)
 		if errs != nil {
 			errors = append(errors, errs...)
 		}
@@ -149,12 +531,90 @@ func (h *ddHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
 
 		// Merge the results into the mergedBrokerList.
 		mergedBrokerList = mergeBrokerLists(mergedBrokerList, blist)
+
+		if h.retainSeries {
+			setSeries(mergedBrokerList, seriesNames[i], series, seriesScales[i])
+		}
 	}
 
-	// The []*kafkametrics.Broker only contains hostnames and the network tx
-	// metric. Fetch the rest of the required metadata and construct a
+	// Get the optional disk, CPU, leader/replication bytes out and log dir
+	// metrics. These only update brokers that network metrics were received
+	// for.
+	for i, query := range h.optionalQueries {
+		if query == "" {
+			continue
+		}
+
+		if err := h.wait(ctx, "metrics query"); err != nil {
+			return nil, []error{err}
+		}
+
+		series, err := c.QueryMetrics(start, time.Now().Unix(), query)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: h.scrubbedErrorText(err),
+			}}
+		}
+
+		series, errs := h.freshSeries(series, time.Now())
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		blist, errs := brokersFromSeries(series, i+2, h.aggregation)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		updateBrokerList(mergedBrokerList, blist)
+
+		if h.retainSeries {
+			setSeries(mergedBrokerList, seriesNames[i+2], series, seriesScales[i+2])
+		}
+	}
+
+	// Get any named metrics.
+	for _, name := range h.queryNames {
+		if err := h.wait(ctx, "metrics query"); err != nil {
+			return nil, []error{err}
+		}
+
+		series, err := c.QueryMetrics(start, time.Now().Unix(), h.queries[name])
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: h.scrubbedErrorText(err),
+			}}
+		}
+
+		series, errs := h.freshSeries(series, time.Now())
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		if errs := setNamedMetric(mergedBrokerList, name, series, h.aggregation); errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		if h.retainSeries {
+			setSeries(mergedBrokerList, name, series, 1)
+		}
+	}
+
+	// The []*kafkametrics.Broker only contains hostnames and the network,
+	// disk, CPU and bytes out metrics. Populate the rest of the required
+	// metadata from the series scopes or host tags and construct a
 	// kafkametrics.BrokerMetrics.
-	bm, errs := h.brokerMetricsFromList(mergedBrokerList)
+	var bm kafkametrics.BrokerMetrics
+	var errs []error
+
+	if h.tagsFromScope {
+		bm, errs = h.brokerMetricsFromScopes(mergedBrokerList, scopes)
+	} else {
+		bm, errs = h.brokerMetricsFromList(ctx, c, mergedBrokerList)
+	}
+
 	if errs != nil {
 		errors = append(errors, errs...)
 	}
@@ -3,8 +3,16 @@
 package datadog
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 
 	dd "github.com/zorkian/go-datadog-api"
 )
@@ -16,7 +24,7 @@ import (
 func TestBrokersFromSeries(t *testing.T) {
 	// Test with expected input.
 	series := stubSeries()
-	bs, err := brokersFromSeries(series, 0)
+	bs, err := brokersFromSeries(series, 0, "")
 
 	if err != nil {
 		t.Fatal(err)
@@ -28,7 +36,7 @@ func TestBrokersFromSeries(t *testing.T) {
 
 	// Test with unexpected input.
 	series = stubSeriesWithoutPoints()
-	bs, err = brokersFromSeries(series, 0)
+	bs, err = brokersFromSeries(series, 0, "")
 	if err == nil {
 		t.Error("Expected error")
 	}
@@ -38,6 +46,202 @@ func TestBrokersFromSeries(t *testing.T) {
 	}
 }
 
+func TestSetNamedMetric(t *testing.T) {
+	l := []*kafkametrics.Broker{{ID: 1000, Host: "i-abc0"}, {ID: 1001, Host: "i-abc1"}}
+
+	var v = 42.00
+	scopes := []string{"host:i-abc0", "host:i-abc1", "host:i-abc2"}
+	series := []dd.Series{
+		{Scope: &scopes[0], Points: []dd.DataPoint{{&v, &v}}},
+		// Missing points.
+		{Scope: &scopes[1], Points: []dd.DataPoint{}},
+		// Not in the broker list.
+		{Scope: &scopes[2], Points: []dd.DataPoint{{&v, &v}}},
+	}
+
+	errs := setNamedMetric(l, "disk_in_use", series, "")
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d\n", len(errs))
+	}
+
+	if l[0].Metrics["disk_in_use"] != 42.00 {
+		t.Errorf("Expected disk_in_use 42.00, got %.2f\n", l[0].Metrics["disk_in_use"])
+	}
+
+	if l[1].Metrics != nil {
+		t.Errorf("Expected nil Metrics, got %v\n", l[1].Metrics)
+	}
+}
+
+func TestSetSeries(t *testing.T) {
+	l := []*kafkametrics.Broker{{ID: 1000, Host: "i-abc0"}, {ID: 1001, Host: "i-abc1"}}
+
+	t0, t1, v0, v1 := 60000.00, 120000.00, 1048576.00, 2097152.00
+	scopes := []string{"host:i-abc0", "host:i-abc2"}
+	series := []dd.Series{
+		{Scope: &scopes[0], Points: []dd.DataPoint{{&t0, &v0}, {&t1, &v1}}},
+		// Not in the broker list.
+		{Scope: &scopes[1], Points: []dd.DataPoint{{&t0, &v0}}},
+	}
+
+	setSeries(l, kafkametrics.SeriesNetTX, series, seriesScales[0])
+
+	expected := []kafkametrics.Point{{Time: time.Unix(60, 0), Value: 1}, {Time: time.Unix(120, 0), Value: 2}}
+	got := l[0].Series[kafkametrics.SeriesNetTX]
+	if len(got) != 2 || !got[0].Time.Equal(expected[0].Time) || got[1].Value != expected[1].Value {
+		t.Errorf("Expected series %v, got %v\n", expected, got)
+	}
+
+	if l[1].Series != nil {
+		t.Errorf("Expected nil Series, got %v\n", l[1].Series)
+	}
+}
+
+func TestSeriesValue(t *testing.T) {
+	var points []dd.DataPoint
+	for i := 1; i <= 10; i++ {
+		ts, v := float64(i*1000), float64(i)
+		points = append(points, dd.DataPoint{&ts, &v})
+	}
+	series := dd.Series{Points: points}
+
+	// Without an aggregation, the first point is used.
+	if v, ok := seriesValue(series, ""); !ok || v != 1 {
+		t.Errorf("Expected 1, got %f\n", v)
+	}
+
+	if v, ok := seriesValue(series, kafkametrics.AggregationMax); !ok || v != 10 {
+		t.Errorf("Expected 10, got %f\n", v)
+	}
+
+	if v, ok := seriesValue(series, kafkametrics.AggregationP95); !ok || math.Abs(v-9.55) > 0.0001 {
+		t.Errorf("Expected 9.55, got %f\n", v)
+	}
+
+	if _, ok := seriesValue(dd.Series{}, kafkametrics.AggregationAvg); ok {
+		t.Error("Expected no points")
+	}
+}
+
+func TestFreshSeries(t *testing.T) {
+	now := time.Unix(3600, 0)
+	fresh, stale, v := 3540000.00, 60000.00, 42.00
+
+	scopes := []string{"host:i-abc0", "host:i-abc1", "host:i-abc2"}
+	series := []dd.Series{
+		{Scope: &scopes[0], Points: []dd.DataPoint{{&stale, &v}, {&fresh, &v}}},
+		{Scope: &scopes[1], Points: []dd.DataPoint{{&stale, &v}}},
+		// Missing points are left for brokersFromSeries.
+		{Scope: &scopes[2], Points: []dd.DataPoint{}},
+	}
+
+	// Disabled.
+	h := &ddHandler{}
+	if s, errs := h.freshSeries(series, now); len(s) != 3 || errs != nil {
+		t.Errorf("Expected 3 series and no errors, got %d, %v\n", len(s), errs)
+	}
+
+	h.maxPointAge = 5 * time.Minute
+	s, errs := h.freshSeries(series, now)
+	if len(s) != 2 || s[0].GetScope() != "host:i-abc0" {
+		t.Errorf("Expected series i-abc0 and i-abc2, got %v\n", s)
+	}
+
+	skipped := kafkametrics.SkippedBrokers(errs)
+	if len(skipped) != 1 || skipped[0].Host != "i-abc1" || skipped[0].Missing != kafkametrics.StalePoints {
+		t.Errorf("Expected stale broker i-abc1, got %v\n", skipped)
+	}
+}
+
+func TestLazyValidation(t *testing.T) {
+	var valid atomic.Bool
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if !valid.Load() {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["Forbidden"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"valid":true}`)
+	}))
+	defer srv.Close()
+
+	c := &Config{Site: srv.URL, NetworkTXQuery: "tx", NetworkRXQuery: "rx"}
+	if _, err := NewHandler(c); err == nil {
+		t.Error("Expected validation error")
+	}
+
+	// Validation is deferred to the first request.
+	c.LazyValidation = true
+	h, err := NewHandler(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	if _, errs := h.GetMetrics(); len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v\n", errs)
+	} else if _, ok := errs[0].(*kafkametrics.APIError); !ok {
+		t.Errorf("Expected *APIError, got %T\n", errs[0])
+	}
+
+	// Successful validation is only performed once.
+	valid.Store(true)
+	dh := h.(*ddHandler)
+	for i := 0; i < 2; i++ {
+		if err := dh.validate(context.Background()); err != nil {
+			t.Errorf("Unexpected error: %s\n", err)
+		}
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("Expected 3 validate requests, got %d\n", n)
+	}
+}
+
+func TestGetMetricsContext(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/v1/validate" {
+			fmt.Fprint(w, `{"valid":true}`)
+			return
+		}
+
+		// Block metrics queries until the request is cancelled.
+		<-req.Context().Done()
+		cancelled <- struct{}{}
+	}))
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{Site: srv.URL, NetworkTXQuery: "tx", NetworkRXQuery: "rx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, errs := h.(kafkametrics.ContextHandler).GetMetricsContext(ctx)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v\n", errs)
+	} else if _, ok := errs[0].(*kafkametrics.APIError); !ok {
+		t.Errorf("Expected *APIError, got %T\n", errs[0])
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the in-flight request to be cancelled")
+	}
+
+	// Requests aren't made once the context is done.
+	if err := h.(kafkametrics.ContextHandler).PostEventContext(ctx, &kafkametrics.Event{}); err == nil {
+		t.Error("Expected error")
+	}
+}
+
 func stubSeries() []dd.Series {
 	ss := []dd.Series{}
 	var f1 = 0.00
@@ -0,0 +1,62 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+
+	dd "github.com/zorkian/go-datadog-api"
+)
+
+// GetConsumerLag requests the ConsumerLagQuery from the Datadog API and
+// returns a kafkametrics.ConsumerLag.
+func (h *ddHandler) GetConsumerLag() (kafkametrics.ConsumerLag, error) {
+	if h.lagQuery == "" {
+		return nil, errors.New("no consumer lag query configured")
+	}
+
+	if err := h.validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	start := time.Now().Add(-time.Duration(h.metricsWindow) * time.Second).Unix()
+
+	h.limiter.wait()
+	series, err := h.c.QueryMetrics(start, time.Now().Unix(), h.lagQuery)
+	if err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "metrics query",
+			Message: h.scrubbedErrorText(err),
+		}
+	}
+
+	return lagFromSeries(series, h.groupTag, h.aggregation), nil
+}
+
+// lagFromSeries takes a []dd.Series and the consumer group tag name and
+// returns a kafkametrics.ConsumerLag. Series without a group tag are keyed
+// by their scope. Where several series share a group, the greatest lag is
+// used. Series without points are skipped.
+func lagFromSeries(s []dd.Series, tag string, a kafkametrics.Aggregation) kafkametrics.ConsumerLag {
+	lag := kafkametrics.ConsumerLag{}
+
+	for _, ts := range s {
+		v, ok := seriesValue(ts, a)
+		if !ok || ts.Scope == nil {
+			continue
+		}
+
+		group := tagValFromScope(*ts.Scope, tag)
+		if group == "" {
+			group = *ts.Scope
+		}
+
+		if cur, exists := lag[group]; !exists || v > cur {
+			lag[group] = v
+		}
+	}
+
+	return lag
+}
@@ -0,0 +1,46 @@
+package datadog
+
+import (
+	"testing"
+
+	dd "github.com/zorkian/go-datadog-api"
+)
+
+func TestLagFromSeries(t *testing.T) {
+	var v1, v2, v3 = 10.00, 250.00, 40.00
+	scopes := []string{
+		"consumer_group:payments,partition:0",
+		"consumer_group:payments,partition:1",
+		"consumer_group:search",
+		"partition:2",
+		"consumer_group:empty",
+	}
+
+	series := []dd.Series{
+		{Scope: &scopes[0], Points: []dd.DataPoint{{&v1, &v1}}},
+		{Scope: &scopes[1], Points: []dd.DataPoint{{&v2, &v2}}},
+		{Scope: &scopes[2], Points: []dd.DataPoint{{&v3, &v3}}},
+		// Missing group tag.
+		{Scope: &scopes[3], Points: []dd.DataPoint{{&v1, &v1}}},
+		// Missing points.
+		{Scope: &scopes[4], Points: []dd.DataPoint{}},
+	}
+
+	lag := lagFromSeries(series, "consumer_group", "")
+
+	expected := map[string]float64{
+		"payments":    250.00,
+		"search":      40.00,
+		"partition:2": 10.00,
+	}
+
+	if len(lag) != len(expected) {
+		t.Fatalf("Expected %d groups, got %v\n", len(expected), lag)
+	}
+
+	for group, v := range expected {
+		if lag[group] != v {
+			t.Errorf("Expected %s lag %.2f, got %.2f\n", group, v, lag[group])
+		}
+	}
+}
@@ -1,30 +1,52 @@
 package datadog
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 
 	dd "github.com/zorkian/go-datadog-api"
 )
 
-// brokersFromSeries takes a []dd.Series an int desciptor for the metric
-// type and returns a []*kafkametrics.Broker. If for some reason points were
+// Series names and the scale applied to the series values for each metric
+// type descriptor used by brokersFromSeries.
+var (
+	seriesNames = []string{
+		kafkametrics.SeriesNetTX,
+		kafkametrics.SeriesNetRX,
+		kafkametrics.SeriesDiskUsed,
+		kafkametrics.SeriesDiskFree,
+		kafkametrics.SeriesCPU,
+		kafkametrics.SeriesLeaderBytesOut,
+		kafkametrics.SeriesReplicationBytesOut,
+		kafkametrics.SeriesDiskIO,
+		kafkametrics.SeriesAlterLogDirsLag,
+	}
+	seriesScales = []float64{1.0 / 1024 / 1024, 1.0 / 1024 / 1024, 1, 1, 1, 1.0 / 1024 / 1024, 1.0 / 1024 / 1024, 1.0 / 1024 / 1024, 1}
+)
+
+// brokersFromSeries takes a []dd.Series, an int desciptor for the metric
+// type and a kafkametrics.Aggregation (as used by seriesValue) and returns a
+// []*kafkametrics.Broker. If for some reason points were
 // not returned for a broker, it's excluded from the []*kafkametrics.Broker
 // and an error is populated in the return []error.
-func brokersFromSeries(s []dd.Series, metric int) ([]*kafkametrics.Broker, []error) {
+func brokersFromSeries(s []dd.Series, metric int, a kafkametrics.Aggregation) ([]*kafkametrics.Broker, []error) {
 	bs := []*kafkametrics.Broker{}
 	var errors []error
 
 	for _, ts := range s {
 		host := tagValFromScope(ts.GetScope(), "host")
 
-		if len(ts.Points) == 0 {
+		v, ok := seriesValue(ts, a)
+		if !ok {
 			errors = append(errors, &kafkametrics.PartialResults{
 				Message: fmt.Sprintf("No points for host %s", host),
+				Skipped: []kafkametrics.SkippedBroker{{Host: host, Reason: "no points", Missing: kafkametrics.MissingPoints}},
 			})
 			continue
 		}
@@ -35,9 +57,23 @@ func brokersFromSeries(s []dd.Series, metric int) ([]*kafkametrics.Broker, []err
 
 		switch metric {
 		case 0:
-			b.NetTX = *ts.Points[0][1] / 1024 / 1024
+			b.NetTX = v / 1024 / 1024
 		case 1:
-			b.NetRX = *ts.Points[0][1] / 1024 / 1024
+			b.NetRX = v / 1024 / 1024
+		case 2:
+			b.DiskUsed = v
+		case 3:
+			b.DiskFree = v
+		case 4:
+			b.CPU = v
+		case 5:
+			b.LeaderBytesOut = v / 1024 / 1024
+		case 6:
+			b.ReplicationBytesOut = v / 1024 / 1024
+		case 7:
+			b.DiskIO = v / 1024 / 1024
+		case 8:
+			b.AlterLogDirsLag = v
 		}
 
 		bs = append(bs, b)
@@ -46,6 +82,135 @@ func brokersFromSeries(s []dd.Series, metric int) ([]*kafkametrics.Broker, []err
 	return bs, errors
 }
 
+// setSeries takes a []*kafkametrics.Broker, a series name, a []dd.Series and
+// a scale and sets the scaled series points in the Series map of each broker
+// with a matching host.
+func setSeries(l []*kafkametrics.Broker, name string, s []dd.Series, scale float64) {
+	m := map[string]*kafkametrics.Broker{}
+	for _, b := range l {
+		m[b.Host] = b
+	}
+
+	for _, ts := range s {
+		b, exists := m[tagValFromScope(ts.GetScope(), "host")]
+		if !exists || len(ts.Points) == 0 {
+			continue
+		}
+
+		var points []kafkametrics.Point
+		for _, p := range ts.Points {
+			if p[0] == nil || p[1] == nil {
+				continue
+			}
+
+			// Point timestamps are in milliseconds.
+			points = append(points, kafkametrics.Point{
+				Time:  time.Unix(0, int64(*p[0])*int64(time.Millisecond)),
+				Value: *p[1] * scale,
+			})
+		}
+
+		b.SetSeries(name, points)
+	}
+}
+
+// seriesValue returns the value of the series and whether any points were
+// found. If no kafkametrics.Aggregation is specified, the first point is
+// returned as-is, i.e. a single window avg rollup point. Otherwise, all
+// points are reduced with the Aggregation.
+func seriesValue(ts dd.Series, a kafkametrics.Aggregation) (float64, bool) {
+	if a == "" {
+		if len(ts.Points) == 0 || ts.Points[0][1] == nil {
+			return 0, false
+		}
+		return *ts.Points[0][1], true
+	}
+
+	var values []float64
+	for _, p := range ts.Points {
+		if p[1] != nil {
+			values = append(values, *p[1])
+		}
+	}
+
+	return a.Apply(values)
+}
+
+// freshSeries takes a []dd.Series and the current time and returns the
+// series whose most recent point is within the configured MaxPointAge. An
+// error is populated in the return []error for each excluded stale series.
+// Series without points are left for the caller to handle as missing.
+func (h *ddHandler) freshSeries(s []dd.Series, now time.Time) ([]dd.Series, []error) {
+	if h.maxPointAge <= 0 {
+		return s, nil
+	}
+
+	var fresh []dd.Series
+	var errors []error
+
+	for _, ts := range s {
+		var newest float64
+		for _, p := range ts.Points {
+			if p[0] != nil && *p[0] > newest {
+				newest = *p[0]
+			}
+		}
+
+		// Point timestamps are in milliseconds.
+		age := now.Sub(time.Unix(0, int64(newest)*int64(time.Millisecond)))
+		if len(ts.Points) > 0 && age > h.maxPointAge {
+			host := tagValFromScope(ts.GetScope(), "host")
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("Stale points for host %s (%s old)", host, age.Truncate(time.Second)),
+				Skipped: []kafkametrics.SkippedBroker{{Host: host, Reason: "stale points", Missing: kafkametrics.StalePoints}},
+			})
+			continue
+		}
+
+		fresh = append(fresh, ts)
+	}
+
+	return fresh, errors
+}
+
+// setNamedMetric takes a []*kafkametrics.Broker, a metric name, a
+// []dd.Series and kafkametrics.Aggregation and sets the series value in the Metrics map of each broker
+// with a matching host. Brokers with missing points are skipped and an error
+// is populated in the return []error.
+func setNamedMetric(l []*kafkametrics.Broker, name string, s []dd.Series, a kafkametrics.Aggregation) []error {
+	var errors []error
+
+	m := map[string]*kafkametrics.Broker{}
+	for _, b := range l {
+		m[b.Host] = b
+	}
+
+	for _, ts := range s {
+		host := tagValFromScope(ts.GetScope(), "host")
+
+		b, exists := m[host]
+		if !exists {
+			continue
+		}
+
+		v, ok := seriesValue(ts, a)
+		if !ok {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No %s points for host %s", name, host),
+			})
+			continue
+		}
+
+		if b.Metrics == nil {
+			b.Metrics = map[string]float64{}
+		}
+
+		b.Metrics[name] = v
+	}
+
+	return errors
+}
+
 // mergeBrokerLists takes a destination and source []*kafkametrics.Broker
 // and adds/updates source brokers into the destination list, returning
 // a merged copy.
@@ -72,6 +237,22 @@ func mergeBrokerLists(dst, src []*kafkametrics.Broker) []*kafkametrics.Broker {
 	return dst
 }
 
+// updateBrokerList takes a destination and source []*kafkametrics.Broker
+// and updates destination brokers with the source broker metrics. Source
+// brokers not in the destination list are ignored.
+func updateBrokerList(dst, src []*kafkametrics.Broker) {
+	m := map[string]*kafkametrics.Broker{}
+	for _, b := range dst {
+		m[b.Host] = b
+	}
+
+	for _, b := range src {
+		if d, exists := m[b.Host]; exists {
+			updateBroker(d, b)
+		}
+	}
+}
+
 // updateBroker takes a destination and source broker and merges the
 // source broker metrics values to the destination if the destiation
 // are default values.
@@ -83,64 +264,179 @@ func updateBroker(dst, src *kafkametrics.Broker) {
 	if dst.NetRX == 0.00 {
 		dst.NetRX = src.NetRX
 	}
+
+	if dst.DiskUsed == 0.00 {
+		dst.DiskUsed = src.DiskUsed
+	}
+
+	if dst.DiskFree == 0.00 {
+		dst.DiskFree = src.DiskFree
+	}
+
+	if dst.CPU == 0.00 {
+		dst.CPU = src.CPU
+	}
+
+	if dst.LeaderBytesOut == 0.00 {
+		dst.LeaderBytesOut = src.LeaderBytesOut
+	}
+
+	if dst.ReplicationBytesOut == 0.00 {
+		dst.ReplicationBytesOut = src.ReplicationBytesOut
+	}
+
+	if dst.DiskIO == 0.00 {
+		dst.DiskIO = src.DiskIO
+	}
+
+	if dst.AlterLogDirsLag == 0.00 {
+		dst.AlterLogDirsLag = src.AlterLogDirsLag
+	}
 }
 
 // brokerMetricsFromList takes a *[]kafkametrics.Broker and fetches relevant
-// host tags for all brokers in the list, returning a BrokerMetrics.
-func (h *ddHandler) brokerMetricsFromList(l []*kafkametrics.Broker) (kafkametrics.BrokerMetrics, []error) {
+// host tags for all brokers in the list with the *dd.Client, returning a
+// BrokerMetrics.
+func (h *ddHandler) brokerMetricsFromList(ctx context.Context, c *dd.Client, l []*kafkametrics.Broker) (kafkametrics.BrokerMetrics, []error) {
 	var errors []error
 	// Get host tags for brokers
 	// in the list.
-	tags, errs := h.getHostTagMap(l)
+	tags, errs := h.getHostTagMap(ctx, c, l)
 	if errs != nil {
 		errors = append(errors, errs...)
 	}
 
+	// Complete tags are collected and cached once all brokers are populated,
+	// as GetMetrics may be called concurrently.
+	complete := map[string][]string{}
+
 	brokers := kafkametrics.BrokerMetrics{}
-	errs = populateFromTagMap(brokers, h.tagCache, tags, h.brokerIDTag, h.instanceTypeTag)
+	errs = populateFromTagMap(brokers, complete, tags, h.brokerIDTag, h.instanceTypeTag, h.resolver)
 	if errs != nil {
 		errors = append(errors, errs...)
 	}
 
+	h.cacheHostTags(complete, time.Now())
+
 	return brokers, errors
 }
 
+// brokerMetricsFromScopes takes a *[]kafkametrics.Broker and a map of hosts
+// to series scope tags and populates the relevant tags for all brokers in the
+// list from the scope tags, returning a BrokerMetrics.
+func (h *ddHandler) brokerMetricsFromScopes(l []*kafkametrics.Broker, scopes map[string][]string) (kafkametrics.BrokerMetrics, []error) {
+	tags := map[*kafkametrics.Broker][]string{}
+	for _, b := range l {
+		tags[b] = scopes[b.Host]
+	}
+
+	brokers := kafkametrics.BrokerMetrics{}
+	// Scope tags are always available; they're not cached.
+	errs := populateFromTagMap(brokers, map[string][]string{}, tags, h.brokerIDTag, h.instanceTypeTag, h.resolver)
+
+	return brokers, errs
+}
+
 // getHostTagMap takes a []*kafkametrics.Broker and fetches  host tags for
-// each. If no errors are encountered, a map[*kafkametrics.Broker][]string
-// holding the received tags is returned.
-func (h *ddHandler) getHostTagMap(l []*kafkametrics.Broker) (map[*kafkametrics.Broker][]string, []error) {
+// each with the *dd.Client, with up to the configured HostTagConcurrency
+// requests in flight. If no errors are encountered, a
+// map[*kafkametrics.Broker][]string holding the received tags is returned.
+func (h *ddHandler) getHostTagMap(ctx context.Context, c *dd.Client, l []*kafkametrics.Broker) (map[*kafkametrics.Broker][]string, []error) {
 	var errors []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, h.hostTagConc)
 
 	brokers := map[*kafkametrics.Broker][]string{}
 	// Get broker IDs for each host, populate into a BrokerMetrics.
 	for _, b := range l {
 		// Check if we already have this broker's metadata.
-		ht, cached := h.tagCache[b.Host]
+		ht, cached := h.cachedHostTags(b.Host, time.Now())
 
 		if cached {
+			mu.Lock()
 			brokers[b] = ht
-		} else {
-			// Else fetch it.
-			ht, err := h.c.GetHostTags(b.Host, "")
+			mu.Unlock()
+			continue
+		}
+
+		// Else fetch it.
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(b *kafkametrics.Broker) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			var ht []string
+			err := h.limiter.waitContext(ctx)
+			if err == nil {
+				ht, err = c.GetHostTags(b.Host, "")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
 			if err != nil {
 				errors = append(errors, &kafkametrics.APIError{
 					Request: "host tags",
 					Message: fmt.Sprintf("Error requesting host tags for %s", b.Host),
 				})
-				continue
+				return
 			}
 
 			brokers[b] = ht
-		}
+		}(b)
 	}
 
+	wg.Wait()
+
 	return brokers, errors
 }
 
+// cachedHostTags returns the cached host tags for the host at the time now,
+// and whether they were cached. Expired tags are evicted from the cache.
+func (h *ddHandler) cachedHostTags(host string, now time.Time) ([]string, bool) {
+	h.tagCacheMu.Lock()
+	defer h.tagCacheMu.Unlock()
+
+	ht, cached := h.tagCache[host]
+	if !cached {
+		return nil, false
+	}
+
+	if h.tagCacheTTL > 0 && now.Sub(h.tagCacheTimes[host]) >= h.tagCacheTTL {
+		delete(h.tagCache, host)
+		delete(h.tagCacheTimes, host)
+		return nil, false
+	}
+
+	return ht, true
+}
+
+// cacheHostTags adds the host tags by host to the cache, recording the time
+// now as the cache time of hosts newly added to the cache.
+func (h *ddHandler) cacheHostTags(tags map[string][]string, now time.Time) {
+	h.tagCacheMu.Lock()
+	defer h.tagCacheMu.Unlock()
+
+	for host, ht := range tags {
+		h.tagCache[host] = ht
+		if _, exists := h.tagCacheTimes[host]; !exists {
+			h.tagCacheTimes[host] = now
+		}
+	}
+}
+
 // populateFromTagMap takes a kafkametrics.BrokerMetrics, map of broker
 // IDs to []string host tags that functions as a cache, a map of brokers
 // to []string unparsed host tag key:value pairs, and a broker ID tag key
-// populates the kafkametrics.BrokerMetrics with tags of interest.
+// populates the kafkametrics.BrokerMetrics with tags of interest. If the
+// instance type tag is missing and a non-nil kafkametrics.InstanceTypeResolver
+// is provided, the instance type is resolved by hostname.
 // An error describing any missing tags is returned.
 func populateFromTagMap(
 	bm kafkametrics.BrokerMetrics,
@@ -148,22 +444,27 @@ func populateFromTagMap(
 	t map[*kafkametrics.Broker][]string,
 	btag string,
 	instanceTypeTag string,
+	resolver kafkametrics.InstanceTypeResolver,
 ) []error {
-	var missingTags bytes.Buffer
+	var skipped []kafkametrics.SkippedBroker
 
 	for b, ht := range t {
 		// We need to get both the ID and instance type tag values. Both must
 		// exist for the broker to be populated in the BrokerMetrics.
 		var id int
 		var it string
+		var err error
 
 		// Get ID.
 		ids := valFromTags(ht, btag)
 		if ids != "" {
 			id, _ = strconv.Atoi(ids)
 		} else {
-			s := fmt.Sprintf(" %s:%s", btag, b.Host)
-			missingTags.WriteString(s)
+			skipped = append(skipped, kafkametrics.SkippedBroker{
+				Host:    b.Host,
+				Reason:  fmt.Sprintf("missing %s tag", btag),
+				Missing: kafkametrics.MissingBrokerID,
+			})
 			continue
 		}
 
@@ -175,9 +476,17 @@ func populateFromTagMap(
 			// had all of their tags populated. Leaving it uncached gives it another
 			// chance for complete metadata in the preceding API lookups.
 			c[b.Host] = t[b]
-		} else {
-			s := fmt.Sprintf(" instance_type:%s", b.Host)
-			missingTags.WriteString(s)
+		} else if it, err = resolveInstanceType(resolver, b.Host); it == "" {
+			reason := fmt.Sprintf("missing %s tag", instanceTypeTag)
+			if err != nil {
+				reason = fmt.Sprintf("%s; %s", reason, err)
+			}
+			skipped = append(skipped, kafkametrics.SkippedBroker{
+				Host:    b.Host,
+				ID:      id,
+				Reason:  reason,
+				Missing: kafkametrics.MissingInstanceType,
+			})
 			continue
 		}
 
@@ -188,10 +497,8 @@ func populateFromTagMap(
 		bm[id] = b
 	}
 
-	if missingTags.String() != "" {
-		return []error{&kafkametrics.PartialResults{
-			Message: fmt.Sprintf("Missing host tags:%s", missingTags.String()),
-		}}
+	if len(skipped) > 0 {
+		return []error{kafkametrics.NewPartialResults("Missing host tags", skipped)}
 	}
 
 	return nil
@@ -205,6 +512,22 @@ func tagValFromScope(scope, tag string) string {
 	return valFromTags(ts, tag)
 }
 
+// resolveInstanceType resolves the instance type for the host with the
+// kafkametrics.InstanceTypeResolver. An empty string is returned if the
+// resolver is nil or fails.
+func resolveInstanceType(r kafkametrics.InstanceTypeResolver, host string) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	it, err := r.InstanceType(host)
+	if err != nil {
+		return "", fmt.Errorf("error resolving instance type: %s", err)
+	}
+
+	return it, nil
+}
+
 // valFromTags takes a []string of tags and a key, returning the
 // value for the key.
 func valFromTags(tags []string, key string) string {
@@ -3,6 +3,7 @@ package datadog
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DataDog/kafka-kit/v4/kafkametrics"
 )
@@ -83,13 +84,50 @@ func TestMergeBrokerLists(t *testing.T) {
 	}
 }
 
+func TestUpdateBrokerList(t *testing.T) {
+	var dst = []*kafkametrics.Broker{
+		{ID: 1000, Host: "i-abc0", NetTX: 40.50, NetRX: 30.00},
+		{ID: 1001, Host: "i-abc1", NetTX: 60.00, NetRX: 40.00},
+	}
+
+	var src = []*kafkametrics.Broker{
+		{Host: "i-abc0", DiskFree: 2048.00, CPU: 75.00, ReplicationBytesOut: 20.00, DiskIO: 150.00, AlterLogDirsLag: 5000},
+		// This broker doesn't exist in dst and should be ignored.
+		{Host: "i-abc2", DiskFree: 1024.00},
+	}
+
+	updateBrokerList(dst, src)
+
+	var expected = []*kafkametrics.Broker{
+		{ID: 1000, Host: "i-abc0", NetTX: 40.50, NetRX: 30.00, DiskFree: 2048.00, CPU: 75.00, ReplicationBytesOut: 20.00, DiskIO: 150.00, AlterLogDirsLag: 5000},
+		{ID: 1001, Host: "i-abc1", NetTX: 60.00, NetRX: 40.00},
+	}
+
+	if len(dst) != len(expected) {
+		t.Fatalf("Unexpected updated results len")
+	}
+
+	for i := range expected {
+		if !brokerEqual(dst[i], expected[i]) {
+			t.Errorf("Updated broker at index %d has unexpected values", i)
+		}
+	}
+}
+
 func brokerEqual(b0, b1 *kafkametrics.Broker) bool {
 	switch {
 	case b0.ID != b1.ID,
 		b0.Host != b1.Host,
 		b0.InstanceType != b1.InstanceType,
 		b0.NetTX != b1.NetTX,
-		b0.NetRX != b1.NetRX:
+		b0.NetRX != b1.NetRX,
+		b0.DiskUsed != b1.DiskUsed,
+		b0.DiskFree != b1.DiskFree,
+		b0.CPU != b1.CPU,
+		b0.LeaderBytesOut != b1.LeaderBytesOut,
+		b0.ReplicationBytesOut != b1.ReplicationBytesOut,
+		b0.DiskIO != b1.DiskIO,
+		b0.AlterLogDirsLag != b1.AlterLogDirsLag:
 		return false
 	default:
 		return true
@@ -102,12 +140,68 @@ func brokerEqual(b0, b1 *kafkametrics.Broker) bool {
 
 // func TestGetHostTagMap(t *testing.T) {}
 
+func TestCachedHostTags(t *testing.T) {
+	h := &ddHandler{
+		tagCache:      map[string][]string{},
+		tagCacheTimes: map[string]time.Time{},
+		tagCacheTTL:   time.Minute,
+	}
+
+	now := time.Now()
+	h.cacheHostTags(map[string][]string{"host0": {"broker_id:1000"}}, now)
+
+	if _, cached := h.cachedHostTags("host0", now.Add(30*time.Second)); !cached {
+		t.Error("Expected cached host tags")
+	}
+
+	if _, cached := h.cachedHostTags("host1", now); cached {
+		t.Error("Unexpected cached host tags")
+	}
+
+	// Expired.
+	if _, cached := h.cachedHostTags("host0", now.Add(time.Minute)); cached {
+		t.Error("Expected expired host tags")
+	}
+
+	if _, exists := h.tagCache["host0"]; exists {
+		t.Error("Expected expired host tags to be evicted")
+	}
+
+	// Without a TTL, tags never expire.
+	h.tagCacheTTL = 0
+	h.cacheHostTags(map[string][]string{"host0": {"broker_id:1000"}}, now)
+
+	if _, cached := h.cachedHostTags("host0", now.Add(24*time.Hour)); !cached {
+		t.Error("Expected cached host tags")
+	}
+}
+
+func TestBrokerMetricsFromScopes(t *testing.T) {
+	h := &ddHandler{brokerIDTag: "broker_id", instanceTypeTag: "instance-type"}
+
+	l := []*kafkametrics.Broker{{Host: "host0"}, {Host: "host1"}}
+	scopes := map[string][]string{
+		"host0": {"host:host0", "broker_id:1000", "instance-type:stub"},
+		// Missing the instance type.
+		"host1": {"host:host1", "broker_id:1001"},
+	}
+
+	bm, errs := h.brokerMetricsFromScopes(l, scopes)
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d\n", len(errs))
+	}
+
+	if len(bm) != 1 || bm[1000] == nil || bm[1000].InstanceType != "stub" {
+		t.Errorf("Unexpected BrokerMetrics %v\n", bm)
+	}
+}
+
 func TestPopulateFromTagMap(t *testing.T) {
 	b := kafkametrics.BrokerMetrics{}
 
 	// Test with complete input.
 	tagMap := stubTagMap()
-	err := populateFromTagMap(b, map[string][]string{}, tagMap, "broker_id", "instance-type")
+	err := populateFromTagMap(b, map[string][]string{}, tagMap, "broker_id", "instance-type", nil)
 	if err != nil {
 		t.Errorf("Unexpected error: %s\n", err)
 	}
@@ -128,9 +222,57 @@ func TestPopulateFromTagMap(t *testing.T) {
 
 	// Test with incomplete input.
 	tagMap[rndBroker] = tagMap[rndBroker][1:]
-	err = populateFromTagMap(b, map[string][]string{}, tagMap, "broker_id", "instance-type")
+	err = populateFromTagMap(b, map[string][]string{}, tagMap, "broker_id", "instance-type", nil)
 	if err == nil {
-		t.Errorf("Expected error, got nil")
+		t.Fatalf("Expected error, got nil")
+	}
+
+	skipped := kafkametrics.SkippedBrokers(err)
+	if len(skipped) != 1 || skipped[0].Host != rndBroker.Host {
+		t.Errorf("Expected skipped broker %s, got %v\n", rndBroker.Host, skipped)
+	}
+}
+
+type stubResolver map[string]string
+
+func (s stubResolver) InstanceType(host string) (string, error) {
+	if it, exists := s[host]; exists {
+		return it, nil
+	}
+
+	return "", fmt.Errorf("host %s not found", host)
+}
+
+func TestPopulateFromTagMapResolver(t *testing.T) {
+	b := kafkametrics.BrokerMetrics{}
+
+	// Remove the instance type tag from all brokers.
+	tagMap := stubTagMap()
+	for broker, tags := range tagMap {
+		tagMap[broker] = tags[:1]
+	}
+
+	r := stubResolver{"host0": "resolved", "host1": "resolved"}
+	cache := map[string][]string{}
+	err := populateFromTagMap(b, cache, tagMap, "broker_id", "instance-type", r)
+
+	if len(b) != 2 {
+		t.Fatalf("Expected 2 resolved brokers, got %d\n", len(b))
+	}
+
+	for _, broker := range b {
+		if broker.InstanceType != "resolved" {
+			t.Errorf("Expected broker InstanceType resolved, got %s\n", broker.InstanceType)
+		}
+	}
+
+	// Brokers with resolved instance types aren't cached.
+	if len(cache) != 0 {
+		t.Errorf("Expected empty tag cache, got %v\n", cache)
+	}
+
+	if skipped := kafkametrics.SkippedBrokers(err); len(skipped) != 3 {
+		t.Errorf("Expected 3 skipped brokers, got %v\n", skipped)
 	}
 }
 
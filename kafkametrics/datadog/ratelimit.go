@@ -0,0 +1,91 @@
+package datadog
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket rate limiter shared by all Datadog API
+// requests made by a handler.
+type rateLimiter struct {
+	sync.Mutex
+	// Tokens added per second.
+	rate float64
+	// Maximum tokens.
+	burst float64
+	// Currently available tokens; may be negative where requests
+	// have reserved future tokens.
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter takes a requests per second rate and burst size and returns
+// a *rateLimiter. A burst <= 0 defaults to the rate, rounded up.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(math.Ceil(rate), 1)
+	}
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+	}
+}
+
+// wait blocks until a request is permitted. A nil *rateLimiter never blocks.
+func (r *rateLimiter) wait() {
+	r.waitContext(context.Background())
+}
+
+// waitContext blocks until a request is permitted, returning the context
+// error if the context is done first. A nil *rateLimiter only checks the
+// context.
+func (r *rateLimiter) waitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if r == nil {
+		return nil
+	}
+
+	d := r.reserve(time.Now())
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token at the time now and returns how long the caller must
+// wait before the token is available.
+func (r *rateLimiter) reserve(now time.Time) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	// Refill tokens for the time elapsed since the last reservation.
+	if !r.last.IsZero() {
+		elapsed := now.Sub(r.last).Seconds()
+		r.tokens = math.Min(r.tokens+elapsed*r.rate, r.burst)
+	}
+	r.last = now
+
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
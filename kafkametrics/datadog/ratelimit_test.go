@@ -0,0 +1,73 @@
+package datadog
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	r := newRateLimiter(2, 2)
+	now := time.Unix(0, 0)
+
+	// [seconds since start, expected wait]
+	expected := []struct {
+		at   float64
+		wait time.Duration
+	}{
+		// The burst is available immediately.
+		{0, 0},
+		{0, 0},
+		// Subsequent requests are spaced at the rate.
+		{0, 500 * time.Millisecond},
+		{0, time.Second},
+		// Tokens are refilled over time.
+		{2, 0},
+		// Refills don't exceed the burst.
+		{10, 0},
+		{10, 0},
+		{10, 500 * time.Millisecond},
+	}
+
+	for i, e := range expected {
+		d := r.reserve(now.Add(time.Duration(e.at * float64(time.Second))))
+		if d != e.wait {
+			t.Errorf("[test index %d] Expected wait %s, got %s\n", i, e.wait, d)
+		}
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if r := newRateLimiter(0.5, 0); r.burst != 1 {
+		t.Errorf("Expected burst 1, got %f\n", r.burst)
+	}
+
+	if r := newRateLimiter(2.5, 0); r.burst != 3 {
+		t.Errorf("Expected burst 3, got %f\n", r.burst)
+	}
+
+	// A nil limiter doesn't block.
+	var r *rateLimiter
+	r.wait()
+}
+
+func TestRateLimiterWaitContext(t *testing.T) {
+	r := newRateLimiter(0.1, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := r.waitContext(ctx); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	// Waits for the next token end with the context.
+	if err := r.waitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v\n", err)
+	}
+
+	var nilLimiter *rateLimiter
+	if err := nilLimiter.waitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v\n", err)
+	}
+}
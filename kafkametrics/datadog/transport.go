@@ -0,0 +1,72 @@
+package datadog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// siteURL takes a Datadog site (e.g. "datadoghq.eu") or API URL and returns
+// the API base URL.
+func siteURL(site string) string {
+	if strings.Contains(site, "://") {
+		return strings.TrimSuffix(site, "/")
+	}
+
+	return "https://api." + strings.TrimPrefix(site, "api.")
+}
+
+// contextTransport is an http.RoundTripper that binds requests to a context,
+// for clients that don't accept one.
+type contextTransport struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+// RoundTrip performs the request with the transport context.
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.rt.RoundTrip(req.WithContext(t.ctx))
+}
+
+// httpClient returns the *http.Client used for Datadog API requests from the
+// Config timeout, proxy and CA bundle options.
+func httpClient(c *Config) (*http.Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %s", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if c.CACertFile != "" {
+		pem, err := os.ReadFile(c.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %s", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CACertFile)
+		}
+
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{
+		Transport: t,
+		Timeout:   time.Duration(c.Timeout) * time.Second,
+	}, nil
+}
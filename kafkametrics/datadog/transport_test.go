@@ -0,0 +1,51 @@
+package datadog
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSiteURL(t *testing.T) {
+	tests := map[string]string{
+		"datadoghq.eu":            "https://api.datadoghq.eu",
+		"us3.datadoghq.com":       "https://api.us3.datadoghq.com",
+		"api.ddog-gov.com":        "https://api.ddog-gov.com",
+		"https://dd.example.com/": "https://dd.example.com",
+	}
+
+	for site, expected := range tests {
+		if u := siteURL(site); u != expected {
+			t.Errorf("Expected %s, got %s\n", expected, u)
+		}
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	c, err := httpClient(&Config{ProxyURL: "http://proxy:3128", Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Timeout.Seconds() != 5 {
+		t.Errorf("Expected timeout 5s, got %s\n", c.Timeout)
+	}
+
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "api.datadoghq.com"}}
+	p, _ := c.Transport.(*http.Transport).Proxy(req)
+	if p == nil || p.Host != "proxy:3128" {
+		t.Errorf("Unexpected proxy %v\n", p)
+	}
+
+	// Invalid CA bundles are errors.
+	f := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(f, []byte("not a certificate"), 0644)
+
+	for _, cfg := range []*Config{{CACertFile: f}, {CACertFile: f + ".missing"}, {ProxyURL: "://"}} {
+		if _, err := httpClient(cfg); err == nil {
+			t.Errorf("Expected error for config %+v\n", cfg)
+		}
+	}
+}
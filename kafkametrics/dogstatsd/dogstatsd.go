@@ -0,0 +1,179 @@
+// Package dogstatsd implements
+// a kafkametrics Handler.
+//
+// The Handler sends events and metrics to a local Datadog agent through
+// the DogStatsD protocol; no metrics are provided by GetMetrics. This allows
+// events and throttle metrics to be written where egress to the Datadog API
+// is unavailable.
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Address is the DogStatsD address. Unix domain sockets are specified
+	// with the unix:// prefix. Defaults to "127.0.0.1:8125".
+	// Example: "unix:///var/run/datadog/dsd.socket"
+	Address string
+	// Namespace is an optional prefix for metric names.
+	// Example: "kafka."
+	Namespace string
+	// Tags are applied to all metrics.
+	Tags []string
+}
+
+// Handler writes events and metrics to DogStatsD.
+type Handler struct {
+	sync.Mutex
+	conn      net.Conn
+	namespace string
+	tags      []string
+}
+
+// NewHandler takes a *Config and returns a *Handler, along with any
+// connection errors.
+func NewHandler(c *Config) (*Handler, error) {
+	addr := "127.0.0.1:8125"
+	if c.Address != "" {
+		addr = c.Address
+	}
+
+	network := "udp"
+	if strings.HasPrefix(addr, "unix://") {
+		network = "unixgram"
+		addr = strings.TrimPrefix(addr, "unix://")
+	}
+
+	conn, err := net.DialTimeout(network, addr, 5*time.Second)
+	if err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "connect to dogstatsd",
+			Message: err.Error(),
+		}
+	}
+
+	return &Handler{
+		conn:      conn,
+		namespace: c.Namespace,
+		tags:      c.Tags,
+	}, nil
+}
+
+// GetMetrics isn't supported by the DogStatsD Handler and always
+// returns a NoResults error.
+func (h *Handler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return nil, []error{&kafkametrics.NoResults{
+		Message: "The dogstatsd handler doesn't provide metrics",
+	}}
+}
+
+// PostEvent writes an event.
+func (h *Handler) PostEvent(e *kafkametrics.Event) error {
+	if e.Title == "" {
+		return errors.New("event title must be specified")
+	}
+
+	if err := h.write(formatEvent(e)); err != nil {
+		return &kafkametrics.APIError{
+			Request: "write event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// Gauge writes a gauge metric with the Handler tags and any additional tags.
+func (h *Handler) Gauge(name string, value float64, tags []string) error {
+	m := formatMetric(h.namespace+name, value, "g", append(append([]string{}, h.tags...), tags...))
+
+	if err := h.write(m); err != nil {
+		return &kafkametrics.APIError{
+			Request: "write metric",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// Close closes the DogStatsD connection.
+func (h *Handler) Close() error {
+	return h.conn.Close()
+}
+
+func (h *Handler) write(datagram string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	_, err := h.conn.Write([]byte(datagram))
+
+	return err
+}
+
+// formatEvent returns the DogStatsD datagram for an event. Newlines are
+// escaped as the protocol requires.
+func formatEvent(e *kafkametrics.Event) string {
+	title := escape(e.Title)
+	text := escape(e.Text)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "_e{%d,%d}:%s|%s", len(title), len(text), title, text)
+
+	if e.AggregationKey != "" {
+		fmt.Fprintf(&b, "|k:%s", e.AggregationKey)
+	}
+
+	if e.SourceTypeName != "" {
+		fmt.Fprintf(&b, "|s:%s", e.SourceTypeName)
+	}
+
+	if e.AlertType != "" {
+		fmt.Fprintf(&b, "|t:%s", e.AlertType)
+	}
+
+	writeTags(&b, e.Tags)
+
+	return b.String()
+}
+
+// formatMetric returns the DogStatsD datagram for a metric.
+func formatMetric(name string, value float64, metricType string, tags []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%s|%s", name, strconv.FormatFloat(value, 'f', -1, 64), metricType)
+	writeTags(&b, tags)
+
+	return b.String()
+}
+
+func writeTags(b *strings.Builder, tags []string) {
+	var n int
+	for _, t := range tags {
+		if t == "" {
+			continue
+		}
+
+		if n == 0 {
+			b.WriteString("|#")
+		} else {
+			b.WriteString(",")
+		}
+
+		b.WriteString(strings.NewReplacer(",", "_", "|", "_").Replace(t))
+		n++
+	}
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
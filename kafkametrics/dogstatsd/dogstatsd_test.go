@@ -0,0 +1,87 @@
+package dogstatsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestPostEvent(t *testing.T) {
+	conn, h := stubHandler(t)
+
+	e := &kafkametrics.Event{
+		Title: "throttle set",
+		Text:  "line1\nline2",
+		Tags:  []string{"name:kafka-autothrottle", ""},
+	}
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `_e{12,12}:throttle set|line1\nline2|#name:kafka-autothrottle`
+	if got := read(t, conn); got != expected {
+		t.Errorf("Expected datagram:\n%s\ngot:\n%s\n", expected, got)
+	}
+
+	e.AggregationKey = "autothrottle"
+	e.AlertType = "info"
+	e.SourceTypeName = "kafka"
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `_e{12,12}:throttle set|line1\nline2|k:autothrottle|s:kafka|t:info|#name:kafka-autothrottle`
+	if got := read(t, conn); got != expected {
+		t.Errorf("Expected datagram:\n%s\ngot:\n%s\n", expected, got)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	conn, h := stubHandler(t)
+
+	if err := h.Gauge("throttle_rate", 92.5, []string{"broker_id:1001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "kafka.throttle_rate:92.5|g|#env:test,broker_id:1001"
+	if got := read(t, conn); got != expected {
+		t.Errorf("Expected datagram:\n%s\ngot:\n%s\n", expected, got)
+	}
+}
+
+// stubHandler returns a UDP listener and a Handler writing to it.
+func stubHandler(t *testing.T) (net.PacketConn, *Handler) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	h, err := NewHandler(&Config{
+		Address:   conn.LocalAddr().String(),
+		Namespace: "kafka.",
+		Tags:      []string{"env:test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	return conn, h
+}
+
+func read(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf[:n])
+}
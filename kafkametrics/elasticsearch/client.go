@@ -0,0 +1,133 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// esClient is a minimal Elasticsearch REST API client.
+type esClient struct {
+	c        *http.Client
+	url      string
+	username string
+	password string
+	apiKey   string
+}
+
+// do issues an authenticated request to the API path, unmarshaling the
+// response into out if non-nil.
+func (e *esClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.url+path, r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	switch {
+	case e.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	case e.username != "":
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(d, &e) == nil && e.Error.Reason != "" {
+			return fmt.Errorf("%s: %s", e.Error.Type, e.Error.Reason)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		return json.Unmarshal(d, out)
+	}
+
+	return nil
+}
+
+// searchResponse is the subset of a search response holding the broker
+// aggregation buckets.
+type searchResponse struct {
+	Aggregations struct {
+		Brokers struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"brokers"`
+	} `json:"aggregations"`
+}
+
+type bucket struct {
+	Key          json.RawMessage `json:"key"`
+	Value        valueAgg        `json:"value"`
+	Min          valueAgg        `json:"min"`
+	Max          valueAgg        `json:"max"`
+	FirstSeen    valueAgg        `json:"first_seen"`
+	LastSeen     valueAgg        `json:"last_seen"`
+	Host         termsAgg        `json:"host"`
+	InstanceType termsAgg        `json:"instance_type"`
+}
+
+type valueAgg struct {
+	Value *float64 `json:"value"`
+}
+
+type termsAgg struct {
+	Buckets []struct {
+		Key json.RawMessage `json:"key"`
+	} `json:"buckets"`
+}
+
+// first returns the first terms bucket key as a string.
+func (t termsAgg) first() string {
+	if len(t.Buckets) == 0 {
+		return ""
+	}
+	return keyString(t.Buckets[0].Key)
+}
+
+// keyString returns a bucket key as a string; keys may be numbers
+// or strings.
+func keyString(k json.RawMessage) string {
+	return strings.Trim(string(k), `"`)
+}
+
+// search runs a search request against the index.
+func (e *esClient) search(ctx context.Context, index string, body interface{}) (*searchResponse, error) {
+	r := &searchResponse{}
+	if err := e.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", body, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
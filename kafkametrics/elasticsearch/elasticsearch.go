@@ -0,0 +1,373 @@
+// Package elasticsearch implements
+// a kafkametrics Handler.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the Elasticsearch address.
+	// Example: "http://elasticsearch:9200"
+	URL string
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// APIKey is an optional base64 encoded API key.
+	APIKey string
+	// Index is the index name or pattern that is searched.
+	// Example: "metricbeat-*"
+	Index string
+	// Filter is an optional query string query that restricts the
+	// searched documents to the reference Kafka brokers.
+	// Example: "service.type:kafka AND metricset.name:network"
+	Filter string
+	// NetworkTXField is the numeric document field holding outbound network
+	// throughput for the Aggregation.
+	// Example: "host.network.egress.bytes"
+	NetworkTXField string
+	// NetworkRXField is the inbound network equivalent of NetworkTXField.
+	NetworkRXField string
+	// Aggregation is how field values are aggregated per broker over the
+	// metrics window. With "avg", the default, field values are averaged and
+	// divided by the Period; with "rate", the field is a monotonic counter
+	// and the rate of increase over the window is used.
+	Aggregation string
+	// Period is the interval in seconds that averaged field values are
+	// reported for, such as the Metricbeat module period. Values are divided
+	// by the period to yield bytes/s. Defaults to 1 (values are bytes/s).
+	Period int
+	// BrokerIDField is the document field holding Kafka broker IDs.
+	BrokerIDField string
+	// HostField is the document field holding the Kafka broker's hostname.
+	// Defaults to "host.name".
+	HostField string
+	// InstanceTypeField is the optional document field holding the Kafka
+	// broker's instance type.
+	// Example: "cloud.machine.type"
+	InstanceTypeField string
+	// TimestampField is the document timestamp field. Defaults to
+	// "@timestamp".
+	TimestampField string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds.
+	MetricsWindow int
+	// EventsIndex is an optional index that events are written to as
+	// documents.
+	EventsIndex string
+}
+
+type esHandler struct {
+	c                 *esClient
+	index             string
+	filter            string
+	fields            []string
+	aggregation       string
+	period            int
+	brokerIDField     string
+	hostField         string
+	instanceTypeField string
+	timestampField    string
+	metricsWindow     int
+	eventsIndex       string
+	maxBrokers        int
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "" || c.Index == "":
+		return nil, errors.New("elasticsearch URL and index must be specified")
+	case c.NetworkTXField == "", c.NetworkRXField == "":
+		return nil, errors.New("network tx and rx fields must be specified")
+	case c.BrokerIDField == "":
+		return nil, errors.New("broker ID field must be specified")
+	case c.APIKey != "" && c.Username != "":
+		return nil, errors.New("only one of API key or basic auth may be specified")
+	case c.Aggregation != "" && c.Aggregation != "avg" && c.Aggregation != "rate":
+		return nil, errors.New("aggregation must be one of avg or rate")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	h := &esHandler{
+		c: &esClient{
+			c:        &http.Client{Timeout: 30 * time.Second},
+			url:      strings.TrimSuffix(c.URL, "/"),
+			username: c.Username,
+			password: c.Password,
+			apiKey:   c.APIKey,
+		},
+		index:             c.Index,
+		filter:            c.Filter,
+		fields:            []string{c.NetworkTXField, c.NetworkRXField},
+		aggregation:       "avg",
+		period:            1,
+		brokerIDField:     c.BrokerIDField,
+		hostField:         "host.name",
+		instanceTypeField: c.InstanceTypeField,
+		timestampField:    "@timestamp",
+		metricsWindow:     c.MetricsWindow,
+		eventsIndex:       c.EventsIndex,
+		maxBrokers:        1000,
+	}
+
+	if c.Aggregation != "" {
+		h.aggregation = c.Aggregation
+	}
+
+	if c.Period > 0 {
+		h.period = c.Period
+	}
+
+	if c.HostField != "" {
+		h.hostField = c.HostField
+	}
+
+	if c.TimestampField != "" {
+		h.timestampField = c.TimestampField
+	}
+
+	// Validate.
+	if err := h.c.do(context.Background(), http.MethodGet, "/", nil, nil); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent writes an event as a document to the configured events index.
+// If no events index is configured, the event is discarded.
+func (h *esHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *esHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.eventsIndex == "" {
+		return nil
+	}
+
+	doc := map[string]interface{}{
+		h.timestampField: time.Now().UTC().Format(time.RFC3339),
+		"title":          e.Title,
+		"text":           e.Text,
+		"tags":           e.Tags,
+	}
+
+	if err := h.c.do(ctx, http.MethodPost, "/"+h.eventsIndex+"/_doc", doc, nil); err != nil {
+		return &kafkametrics.APIError{
+			Request: "index event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from Elasticsearch and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *esHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *esHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, field := range h.fields {
+		r, err := h.c.search(ctx, h.index, h.searchBody(field))
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "search",
+				Message: err.Error(),
+			}}
+		}
+
+		buckets := r.Aggregations.Brokers.Buckets
+		if len(buckets) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned for field %s", field),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, buckets, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of brokers.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// searchBody returns a search request body that aggregates the field for
+// each broker over the metrics window.
+func (h *esHandler) searchBody(field string) map[string]interface{} {
+	filters := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				h.timestampField: map[string]interface{}{
+					"gte": fmt.Sprintf("now-%ds", h.metricsWindow),
+				},
+			},
+		},
+	}
+
+	if h.filter != "" {
+		filters = append(filters, map[string]interface{}{
+			"query_string": map[string]interface{}{"query": h.filter},
+		})
+	}
+
+	aggs := map[string]interface{}{
+		"host": map[string]interface{}{
+			"terms": map[string]interface{}{"field": h.hostField, "size": 1},
+		},
+	}
+
+	if h.instanceTypeField != "" {
+		aggs["instance_type"] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": h.instanceTypeField, "size": 1},
+		}
+	}
+
+	switch h.aggregation {
+	case "rate":
+		aggs["min"] = map[string]interface{}{"min": map[string]interface{}{"field": field}}
+		aggs["max"] = map[string]interface{}{"max": map[string]interface{}{"field": field}}
+		aggs["first_seen"] = map[string]interface{}{"min": map[string]interface{}{"field": h.timestampField}}
+		aggs["last_seen"] = map[string]interface{}{"max": map[string]interface{}{"field": h.timestampField}}
+	default:
+		aggs["value"] = map[string]interface{}{"avg": map[string]interface{}{"field": field}}
+	}
+
+	return map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+		"aggs": map[string]interface{}{
+			"brokers": map[string]interface{}{
+				"terms": map[string]interface{}{"field": h.brokerIDField, "size": h.maxBrokers},
+				"aggs":  aggs,
+			},
+		},
+	}
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and broker aggregation
+// buckets and populates the BrokerMetrics with the aggregated value of each
+// bucket for the metric type. The number of brokers populated is returned
+// along with any errors.
+func (h *esHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, buckets []bucket, metricType int) (int, []error) {
+	var errors []error
+	var missingFields bytes.Buffer
+	var n int
+
+	for _, b := range buckets {
+		host := b.Host.first()
+
+		id, err := strconv.Atoi(keyString(b.Key))
+		if err != nil {
+			missingFields.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDField, host))
+			continue
+		}
+
+		var it string
+		if h.instanceTypeField != "" {
+			it = b.InstanceType.first()
+			if it == "" {
+				missingFields.WriteString(fmt.Sprintf(" %s:%s", h.instanceTypeField, host))
+				continue
+			}
+		}
+
+		v, ok := h.value(b)
+		if !ok {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", host),
+			})
+			continue
+		}
+
+		broker, exists := bm[id]
+		if !exists {
+			broker = &kafkametrics.Broker{
+				ID:           id,
+				Host:         host,
+				InstanceType: it,
+			}
+			bm[id] = broker
+		}
+
+		switch metricType {
+		case 0:
+			broker.NetTX = v / 1024 / 1024
+		case 1:
+			broker.NetRX = v / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingFields.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing fields:%s", missingFields.String()),
+		})
+	}
+
+	return n, errors
+}
+
+// value returns the bytes/s value for a bucket according to the
+// aggregation type, and whether a value could be computed.
+func (h *esHandler) value(b bucket) (float64, bool) {
+	switch h.aggregation {
+	case "rate":
+		if b.Min.Value == nil || b.Max.Value == nil || b.FirstSeen.Value == nil || b.LastSeen.Value == nil {
+			return 0, false
+		}
+
+		// Timestamps are in epoch milliseconds.
+		elapsed := (*b.LastSeen.Value - *b.FirstSeen.Value) / 1000
+		if elapsed <= 0 || *b.Max.Value < *b.Min.Value {
+			return 0, false
+		}
+
+		return (*b.Max.Value - *b.Min.Value) / elapsed, true
+	default:
+		if b.Value.Value == nil {
+			return 0, false
+		}
+
+		return *b.Value.Value / float64(h.period), true
+	}
+}
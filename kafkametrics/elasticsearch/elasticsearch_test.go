@@ -0,0 +1,184 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.EscapedPath() != "/metricbeat-%2A/_search" {
+			t.Errorf("Unexpected request %s %s\n", req.Method, req.URL)
+		}
+
+		if req.Header.Get("Authorization") != "ApiKey key" {
+			t.Errorf("Unexpected authorization %s\n", req.Header.Get("Authorization"))
+		}
+
+		// Broker ID keys may be numbers or strings.
+		fmt.Fprint(w, `{"took":5,"aggregations":{"brokers":{"buckets":[`+
+			`{"key":1000,"doc_count":12,"value":{"value":20971520},"host":{"buckets":[{"key":"kafka-0"}]},"instance_type":{"buckets":[{"key":"stub"}]}},`+
+			`{"key":"1001","doc_count":12,"value":{"value":null},"host":{"buckets":[{"key":"kafka-1"}]},"instance_type":{"buckets":[]}}]}}}`)
+	}))
+	defer srv.Close()
+
+	c := &esClient{c: srv.Client(), url: srv.URL, apiKey: "key"}
+
+	r, err := c.search(context.Background(), "metricbeat-*", map[string]interface{}{"size": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := r.Aggregations.Brokers.Buckets
+	if len(b) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d\n", len(b))
+	}
+
+	if keyString(b[0].Key) != "1000" || *b[0].Value.Value != 20971520 || b[0].Host.first() != "kafka-0" || b[0].InstanceType.first() != "stub" {
+		t.Errorf("Unexpected bucket %+v\n", b[0])
+	}
+
+	if keyString(b[1].Key) != "1001" || b[1].Value.Value != nil || b[1].InstanceType.first() != "" {
+		t.Errorf("Unexpected bucket %+v\n", b[1])
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// Elasticsearch error types and reasons are returned.
+		{
+			http.StatusBadRequest,
+			`{"error":{"root_cause":[],"type":"query_shard_exception","reason":"unknown field [tx]"},"status":400}`,
+			"query_shard_exception: unknown field [tx]",
+		},
+		{
+			http.StatusUnauthorized,
+			`{"error":{"type":"security_exception","reason":"unable to authenticate"},"status":401}`,
+			"security_exception: unable to authenticate",
+		},
+		{http.StatusBadGateway, "Bad Gateway\n", "unexpected status 502"},
+		{http.StatusOK, "<html></html>", "invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		c := &esClient{c: srv.Client(), url: srv.URL}
+
+		if _, err := c.search(context.Background(), "metricbeat-*", nil); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &esHandler{brokerIDField: "kafka.broker.id", instanceTypeField: "cloud.machine.type", aggregation: "avg", period: 10}
+
+	// avg values are per 10s period.
+	var buckets []bucket
+	json.Unmarshal([]byte(`[
+		{"key":1000,"value":{"value":20971520},"host":{"buckets":[{"key":"kafka-0"}]},"instance_type":{"buckets":[{"key":"stub"}]}},
+		{"key":1001,"value":{"value":null},"host":{"buckets":[{"key":"kafka-1"}]},"instance_type":{"buckets":[{"key":"stub"}]}},
+		{"key":"kafka-9","value":{"value":10485760},"host":{"buckets":[{"key":"kafka-9"}]},"instance_type":{"buckets":[{"key":"stub"}]}},
+		{"key":1003,"value":{"value":10485760},"host":{"buckets":[{"key":"kafka-3"}]},"instance_type":{"buckets":[]}}
+	]`), &buckets)
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, buckets, 0)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetTX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-1",
+		"Missing fields: kafka.broker.id:kafka-9 cloud.machine.type:kafka-3",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestValueRate(t *testing.T) {
+	h := &esHandler{aggregation: "rate"}
+
+	// Counters increase over the first and last seen timestamps, in epoch
+	// milliseconds.
+	tests := map[string]float64{
+		`{"min":{"value":0},"max":{"value":125829120},"first_seen":{"value":1000},"last_seen":{"value":61000}}`: 2097152,
+		// Single samples and counter resets don't yield a rate.
+		`{"min":{"value":0},"max":{"value":1048576},"first_seen":{"value":1000},"last_seen":{"value":1000}}`: -1,
+		`{"min":{"value":10},"max":{"value":null},"first_seen":{"value":1000},"last_seen":{"value":61000}}`:  -1,
+	}
+
+	for body, expected := range tests {
+		var b bucket
+		json.Unmarshal([]byte(body), &b)
+
+		v, ok := h.value(b)
+		if (expected < 0 && ok) || (expected >= 0 && (!ok || v != expected)) {
+			t.Errorf("Expected %f for %s, got %f, %t\n", expected, body, v, ok)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	docs := make(chan map[string]interface{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/kafka-events/_doc" {
+			t.Errorf("Unexpected path %s\n", req.URL.Path)
+		}
+
+		d := map[string]interface{}{}
+		json.NewDecoder(req.Body).Decode(&d)
+		docs <- d
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	h := &esHandler{c: &esClient{c: srv.Client(), url: srv.URL}, eventsIndex: "kafka-events", timestampField: "@timestamp"}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := <-docs
+	if d["title"] != "title" || d["text"] != "text" || d["@timestamp"] == nil {
+		t.Errorf("Unexpected document %v\n", d)
+	}
+
+	// Events are discarded without an events index.
+	h.eventsIndex = ""
+
+	if err := h.PostEvent(&kafkametrics.Event{Title: "title"}); err != nil || len(docs) != 0 {
+		t.Errorf("Expected event to be discarded")
+	}
+}
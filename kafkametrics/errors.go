@@ -2,6 +2,7 @@ package kafkametrics
 
 import (
 	"fmt"
+	"strings"
 )
 
 // APIError wraps backend
@@ -32,9 +33,106 @@ func (e *NoResults) Error() string {
 
 // PartialResults types are returned
 // when incomplete broker metrics or
-// metadata is returned.
+// metadata is returned. Skipped brokers
+// are described programmatically by
+// Skipped; see also MissingReport.
 type PartialResults struct {
 	Message string
+	// Skipped optionally describes the brokers
+	// excluded from the results.
+	Skipped []SkippedBroker
+}
+
+// SkippedBroker describes a broker
+// excluded from a BrokerMetrics.
+type SkippedBroker struct {
+	// Kafka broker hostname.
+	Host string
+	// Kafka broker ID, if known.
+	ID int
+	// Why the broker was skipped.
+	Reason string
+	// What was missing for the broker.
+	Missing MissingItem
+}
+
+// MissingItem describes the metrics or
+// metadata a broker was skipped for.
+type MissingItem int
+
+const (
+	// MissingUnknown is an unspecified item.
+	MissingUnknown MissingItem = iota
+	// MissingPoints is a series without points.
+	MissingPoints
+	// StalePoints is a series with points older
+	// than the configured max age.
+	StalePoints
+	// MissingBrokerID is a missing broker ID
+	// tag or label.
+	MissingBrokerID
+	// MissingInstanceType is a missing instance
+	// type tag or label.
+	MissingInstanceType
+)
+
+// String returns the MissingItem name.
+func (m MissingItem) String() string {
+	switch m {
+	case MissingPoints:
+		return "points"
+	case StalePoints:
+		return "stale points"
+	case MissingBrokerID:
+		return "broker ID"
+	case MissingInstanceType:
+		return "instance type"
+	default:
+		return "unknown"
+	}
+}
+
+// NewPartialResults takes a message prefix
+// and the skipped brokers and returns a
+// *PartialResults with a message describing
+// each skipped broker.
+func NewPartialResults(prefix string, skipped []SkippedBroker) *PartialResults {
+	desc := make([]string, len(skipped))
+	for i, s := range skipped {
+		desc[i] = fmt.Sprintf("%s (%s)", s.Host, s.Reason)
+	}
+
+	return &PartialResults{
+		Message: fmt.Sprintf("%s: %s", prefix, strings.Join(desc, ", ")),
+		Skipped: skipped,
+	}
+}
+
+// SkippedBrokers returns all SkippedBroker
+// descriptions from any PartialResults errors.
+func SkippedBrokers(errs []error) []SkippedBroker {
+	var s []SkippedBroker
+
+	for _, err := range errs {
+		if pr, ok := err.(*PartialResults); ok {
+			s = append(s, pr.Skipped...)
+		}
+	}
+
+	return s
+}
+
+// MissingReport returns a map of broker
+// hostnames to the items missing for each
+// from any PartialResults errors.
+func MissingReport(errs []error) map[string][]MissingItem {
+	r := map[string][]MissingItem{}
+
+	for _, s := range SkippedBrokers(errs) {
+		r[s.Host] = append(r[s.Host], s.Missing)
+	}
+
+	return r
 }
 
 // Error implements the error
@@ -0,0 +1,47 @@
+package kafkametrics
+
+import (
+	"testing"
+)
+
+func TestSkippedBrokers(t *testing.T) {
+	errs := []error{
+		&APIError{Request: "test", Message: "test"},
+		&PartialResults{Message: "test", Skipped: []SkippedBroker{{Host: "host0", Reason: "test"}}},
+		&PartialResults{Message: "test"},
+		&PartialResults{Message: "test", Skipped: []SkippedBroker{{Host: "host1", ID: 1001, Reason: "test"}}},
+	}
+
+	s := SkippedBrokers(errs)
+	if len(s) != 2 || s[0].Host != "host0" || s[1].ID != 1001 {
+		t.Errorf("Unexpected skipped brokers %v\n", s)
+	}
+
+	if s := SkippedBrokers(nil); s != nil {
+		t.Errorf("Expected nil, got %v\n", s)
+	}
+}
+
+func TestMissingReport(t *testing.T) {
+	errs := []error{
+		&PartialResults{Skipped: []SkippedBroker{{Host: "host0", Missing: MissingPoints}}},
+		NewPartialResults("Missing host tags", []SkippedBroker{
+			{Host: "host0", Reason: "missing instance-type tag", Missing: MissingInstanceType},
+			{Host: "host1", Reason: "missing broker_id tag", Missing: MissingBrokerID},
+		}),
+	}
+
+	r := MissingReport(errs)
+	if len(r) != 2 || len(r["host0"]) != 2 || r["host0"][1] != MissingInstanceType || r["host1"][0] != MissingBrokerID {
+		t.Errorf("Unexpected report %v\n", r)
+	}
+
+	expected := "Missing host tags: host0 (missing instance-type tag), host1 (missing broker_id tag)"
+	if msg := errs[1].Error(); msg != expected {
+		t.Errorf("Expected message %q, got %q\n", expected, msg)
+	}
+
+	if StalePoints.String() != "stale points" {
+		t.Errorf("Unexpected MissingItem string %s\n", StalePoints)
+	}
+}
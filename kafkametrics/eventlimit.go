@@ -0,0 +1,138 @@
+package kafkametrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventLimit configures the suppression of events of a class.
+type EventLimit struct {
+	// DedupWindow is the time during which events identical to the
+	// previously posted event of the class are suppressed. 0 disables.
+	DedupWindow time.Duration
+	// MinInterval is the minimum time between posted events of the class.
+	// 0 disables.
+	MinInterval time.Duration
+}
+
+// EventLimitConfig configures an EventLimitHandler.
+type EventLimitConfig struct {
+	// Default is the EventLimit for event classes not in Classes.
+	Default EventLimit
+	// Classes are EventLimits by event class, replacing the Default.
+	Classes map[string]EventLimit
+}
+
+// EventLimitHandler is a ContextHandler that deduplicates and rate limits
+// the events posted to a wrapped Handler. Events are classed by title,
+// ignoring any leading bracketed prefixes such as "[kafka-autothrottle] ".
+// Suppressed events are dropped without error. Metrics requests are passed
+// through.
+type EventLimitHandler struct {
+	h   Handler
+	cfg EventLimitConfig
+	now func() time.Time
+
+	mu sync.Mutex
+	// The most recently posted event of each class.
+	last map[string]postedEvent
+}
+
+// postedEvent records the text and time of a posted event.
+type postedEvent struct {
+	text string
+	time time.Time
+}
+
+// NewEventLimitHandler takes a Handler and EventLimitConfig and returns an
+// *EventLimitHandler.
+func NewEventLimitHandler(h Handler, c EventLimitConfig) *EventLimitHandler {
+	return &EventLimitHandler{
+		h:    h,
+		cfg:  c,
+		now:  time.Now,
+		last: map[string]postedEvent{},
+	}
+}
+
+// GetMetrics requests metrics from the wrapped Handler.
+func (l *EventLimitHandler) GetMetrics() (BrokerMetrics, []error) {
+	return l.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext requests metrics from the wrapped Handler with the
+// context.
+func (l *EventLimitHandler) GetMetricsContext(ctx context.Context) (BrokerMetrics, []error) {
+	return GetMetricsContext(ctx, l.h)
+}
+
+// PostEvent posts the event to the wrapped Handler unless suppressed.
+func (l *EventLimitHandler) PostEvent(e *Event) error {
+	return l.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext posts the event to the wrapped Handler with the context
+// unless suppressed. Only successfully posted events are recorded, so that
+// failed events aren't suppressed when retried.
+func (l *EventLimitHandler) PostEventContext(ctx context.Context, e *Event) error {
+	class := eventClass(e.Title)
+	now := l.now()
+
+	if l.suppressed(class, e.Text, now) {
+		return nil
+	}
+
+	if err := PostEventContext(ctx, l.h, e); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.last[class] = postedEvent{text: e.Text, time: now}
+	l.mu.Unlock()
+
+	return nil
+}
+
+// suppressed returns whether an event of the class with the text should be
+// suppressed at the time now.
+func (l *EventLimitHandler) suppressed(class, text string, now time.Time) bool {
+	limit, exists := l.cfg.Classes[class]
+	if !exists {
+		limit = l.cfg.Default
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	last, exists := l.last[class]
+	if !exists {
+		return false
+	}
+
+	since := now.Sub(last.time)
+
+	switch {
+	case limit.DedupWindow > 0 && last.text == text && since < limit.DedupWindow:
+		return true
+	case limit.MinInterval > 0 && since < limit.MinInterval:
+		return true
+	}
+
+	return false
+}
+
+// eventClass returns the class of an event from its title, trimming any
+// leading bracketed prefixes.
+func eventClass(title string) string {
+	for strings.HasPrefix(title, "[") {
+		i := strings.Index(title, "] ")
+		if i < 0 {
+			break
+		}
+		title = title[i+2:]
+	}
+
+	return title
+}
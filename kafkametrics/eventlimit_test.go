@@ -0,0 +1,96 @@
+package kafkametrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// eventsStub records posted events, optionally returning an error.
+type eventsStub struct {
+	Stub
+	posted []*Event
+	err    error
+}
+
+func (s *eventsStub) PostEvent(e *Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.posted = append(s.posted, e)
+	return nil
+}
+
+func TestEventLimitHandler(t *testing.T) {
+	stub := &eventsStub{}
+	h := NewEventLimitHandler(stub, EventLimitConfig{
+		Default: EventLimit{DedupWindow: time.Hour},
+		Classes: map[string]EventLimit{
+			"Broker replication throttle set": {MinInterval: 10 * time.Minute},
+		},
+	})
+
+	now := time.Unix(0, 0)
+	h.now = func() time.Time { return now }
+
+	// [minutes since start, title, text, expected posted]
+	expected := []struct {
+		at     int
+		title  string
+		text   string
+		posted bool
+	}{
+		{0, "[kafka-autothrottle] Topics done reassigning", "a", true},
+		// Identical events are suppressed within the dedup window.
+		{1, "[kafka-autothrottle] Topics done reassigning", "a", false},
+		{2, "[kafka-autothrottle] Topics done reassigning", "b", true},
+		{3, "[kafka-autothrottle] Topics done reassigning", "a", true},
+		{64, "[kafka-autothrottle] Topics done reassigning", "a", true},
+		// Events of a class with a min interval are rate limited, and
+		// classed regardless of title prefixes.
+		{0, "[kafka-autothrottle] Broker replication throttle set", "a", true},
+		{5, "[kafka-autothrottle] [dry-run] Broker replication throttle set", "b", false},
+		{10, "[kafka-autothrottle] Broker replication throttle set", "a", true},
+		{11, "[kafka-autothrottle] Broker replication throttle set", "a", false},
+	}
+
+	for i, e := range expected {
+		now = time.Unix(int64(e.at*60), 0)
+		n := len(stub.posted)
+
+		if err := h.PostEvent(&Event{Title: e.title, Text: e.text}); err != nil {
+			t.Fatal(err)
+		}
+
+		if posted := len(stub.posted) > n; posted != e.posted {
+			t.Errorf("[test index %d] Expected posted %v, got %v\n", i, e.posted, posted)
+		}
+	}
+
+	// Failed events aren't recorded.
+	stub.err = errors.New("error")
+	if err := h.PostEvent(&Event{Title: "Failed", Text: "a"}); err == nil {
+		t.Fatal("Expected non-nil error")
+	}
+
+	stub.err = nil
+	h.PostEvent(&Event{Title: "Failed", Text: "a"})
+	if len(stub.posted) != 7 {
+		t.Errorf("Expected 7 posted events, got %d\n", len(stub.posted))
+	}
+}
+
+func TestEventClass(t *testing.T) {
+	expected := map[string]string{
+		"[kafka-autothrottle] Topics done reassigning":     "Topics done reassigning",
+		"[kafka-autothrottle prod] [dry-run] Throttle set": "Throttle set",
+		"Topics done reassigning":                          "Topics done reassigning",
+		"[unterminated prefix":                             "[unterminated prefix",
+	}
+
+	for title, class := range expected {
+		if c := eventClass(title); c != class {
+			t.Errorf("Expected class %q for title %q, got %q\n", class, title, c)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package file implements
+// a kafkametrics Handler.
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Path is the broker metrics file. The file is read on every GetMetrics
+	// call.
+	Path string
+	// Format is the broker metrics file format, either "json" or "csv".
+	// Defaults to the Path file extension.
+	//
+	// JSON files hold an array of kafkametrics.Broker objects:
+	//   [{"ID": 1001, "Host": "kafka-0", "InstanceType": "m5.xlarge", "NetTX": 80.5, "NetRX": 110.1}]
+	//
+	// CSV files hold a header row of id, host, instance_type, net_tx and
+	// net_rx columns, in any order:
+	//   id,host,instance_type,net_tx,net_rx
+	//   1001,kafka-0,m5.xlarge,80.5,110.1
+	Format string
+	// EventsPath is an optional file that events are appended to as
+	// JSON lines.
+	EventsPath string
+}
+
+// eventRecord is an events file entry.
+type eventRecord struct {
+	Time  time.Time `json:"time"`
+	Title string    `json:"title"`
+	Text  string    `json:"text"`
+	Tags  []string  `json:"tags,omitempty"`
+}
+
+type fileHandler struct {
+	path       string
+	format     string
+	eventsPath string
+
+	// Serializes event writes.
+	sync.Mutex
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	if c.Path == "" {
+		return nil, errors.New("path must be specified")
+	}
+
+	h := &fileHandler{
+		path:       c.Path,
+		format:     strings.ToLower(strings.TrimPrefix(filepath.Ext(c.Path), ".")),
+		eventsPath: c.EventsPath,
+	}
+
+	if c.Format != "" {
+		h.format = strings.ToLower(c.Format)
+	}
+
+	if h.format != "json" && h.format != "csv" {
+		return nil, fmt.Errorf("unsupported format: %s", h.format)
+	}
+
+	// Validate.
+	if _, err := h.readBrokers(); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "read metrics file",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent appends the event to the events file. If no events file is
+// configured, the event is discarded.
+func (h *fileHandler) PostEvent(e *kafkametrics.Event) error {
+	if h.eventsPath == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(eventRecord{
+		Time:  time.Now().UTC(),
+		Title: e.Title,
+		Text:  e.Text,
+		Tags:  e.Tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	f, err := os.OpenFile(h.eventsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return &kafkametrics.APIError{
+			Request: "write event",
+			Message: err.Error(),
+		}
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return &kafkametrics.APIError{
+			Request: "write event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics reads the broker metrics file and returns a BrokerMetrics.
+func (h *fileHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	brokers, err := h.readBrokers()
+	if err != nil {
+		return nil, []error{&kafkametrics.APIError{
+			Request: "read metrics file",
+			Message: err.Error(),
+		}}
+	}
+
+	if len(brokers) == 0 {
+		return nil, []error{&kafkametrics.NoResults{
+			Message: fmt.Sprintf("No brokers found in %s", h.path),
+		}}
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	for _, b := range brokers {
+		bm[b.ID] = b
+	}
+
+	return bm, nil
+}
+
+// readBrokers reads the broker metrics file.
+func (h *fileHandler) readBrokers() ([]*kafkametrics.Broker, error) {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch h.format {
+	case "csv":
+		return parseCSV(f)
+	default:
+		var brokers []*kafkametrics.Broker
+		if err := json.NewDecoder(f).Decode(&brokers); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %s", h.path, err)
+		}
+		return brokers, nil
+	}
+}
+
+// parseCSV parses CSV broker metrics.
+func parseCSV(r io.Reader) ([]*kafkametrics.Broker, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		cols[strings.TrimSpace(name)] = i
+	}
+
+	for _, name := range []string{"id", "net_tx", "net_rx"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var brokers []*kafkametrics.Broker
+	for n, rec := range records[1:] {
+		b := &kafkametrics.Broker{
+			Host:         field(rec, "host"),
+			InstanceType: field(rec, "instance_type"),
+		}
+
+		if b.ID, err = strconv.Atoi(field(rec, "id")); err != nil {
+			return nil, fmt.Errorf("invalid id on line %d: %s", n+2, err)
+		}
+
+		if b.NetTX, err = strconv.ParseFloat(field(rec, "net_tx"), 64); err != nil {
+			return nil, fmt.Errorf("invalid net_tx on line %d: %s", n+2, err)
+		}
+
+		if b.NetRX, err = strconv.ParseFloat(field(rec, "net_rx"), 64); err != nil {
+			return nil, fmt.Errorf("invalid net_rx on line %d: %s", n+2, err)
+		}
+
+		brokers = append(brokers, b)
+	}
+
+	return brokers, nil
+}
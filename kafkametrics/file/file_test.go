@@ -0,0 +1,132 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestGetMetricsJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.json")
+
+	data := `[{"ID": 1001, "Host": "kafka-0", "InstanceType": "stub", "NetTX": 80.5, "NetRX": 110.1}]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bm, errs := h.GetMetrics()
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %s\n", errs)
+	}
+
+	b := bm[1001]
+	if b == nil || b.Host != "kafka-0" || b.InstanceType != "stub" || b.NetTX != 80.5 || b.NetRX != 110.1 {
+		t.Errorf("Unexpected broker %+v\n", b)
+	}
+
+	// The file is reread on each call.
+	if err := os.WriteFile(path, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, errs := h.GetMetrics(); len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d\n", len(errs))
+	} else if _, ok := errs[0].(*kafkametrics.NoResults); !ok {
+		t.Errorf("Expected *kafkametrics.NoResults, got %T\n", errs[0])
+	}
+}
+
+func TestGetMetricsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics")
+
+	data := "net_rx,net_tx,id,host\n110.1,80.5,1001,kafka-0\n20,10,1002,kafka-1\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&Config{Path: path, Format: "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bm, errs := h.GetMetrics()
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %s\n", errs)
+	}
+
+	if len(bm) != 2 || bm[1002].NetTX != 10 || bm[1001].NetRX != 110.1 || bm[1002].Host != "kafka-1" {
+		t.Errorf("Unexpected BrokerMetrics %v\n", bm)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []string{
+		"id,net_tx\n1001,1\n",
+		"id,net_tx,net_rx\nx,1,1\n",
+		"id,net_tx,net_rx\n1001,1,x\n",
+	}
+
+	for _, test := range tests {
+		if _, err := parseCSV(strings.NewReader(test)); err == nil {
+			t.Errorf("Expected error for %q\n", test)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.json")
+	eventsPath := filepath.Join(dir, "events.log")
+
+	if err := os.WriteFile(path, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(&Config{Path: path, EventsPath: eventsPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, title := range []string{"first", "second"} {
+		if err := h.PostEvent(&kafkametrics.Event{Title: title, Tags: []string{"a:b"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(eventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []eventRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e eventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 2 || events[1].Title != "second" || events[0].Tags[0] != "a:b" {
+		t.Errorf("Unexpected events %v\n", events)
+	}
+}
+
+func TestNewHandlerFormat(t *testing.T) {
+	if _, err := NewHandler(&Config{Path: "metrics.txt"}); err == nil {
+		t.Error("Expected unsupported format error")
+	}
+}
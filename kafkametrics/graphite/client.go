@@ -0,0 +1,110 @@
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// series is a render API JSON series.
+type series struct {
+	Target     string      `json:"target"`
+	Datapoints []datapoint `json:"datapoints"`
+}
+
+// datapoint is a [value, timestamp] pair. Values are null for intervals
+// without data.
+type datapoint [2]*float64
+
+// event is a graphite-web events API request body.
+type event struct {
+	What string   `json:"what"`
+	Tags []string `json:"tags"`
+	Data string   `json:"data"`
+}
+
+// render requests the target for the window, in seconds relative to now.
+func (h *graphiteHandler) render(ctx context.Context, target string, window int) ([]series, error) {
+	params := url.Values{}
+	params.Set("target", target)
+	params.Set("from", fmt.Sprintf("-%ds", window))
+	params.Set("until", "now")
+	params.Set("format", "json")
+
+	body, err := h.do(ctx, http.MethodGet, "/render?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var s []series
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, fmt.Errorf("error parsing render response: %s", err)
+	}
+
+	return s, nil
+}
+
+// postEvent writes an event to the events endpoint.
+func (h *graphiteHandler) postEvent(ctx context.Context, e *event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.do(ctx, http.MethodPost, "/events/", b)
+
+	return err
+}
+
+// do issues a request to the API path, returning the response body.
+func (h *graphiteHandler) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(d)))
+	}
+
+	return d, nil
+}
+
+// metricPath returns the metric path from a series target name, stripping
+// any function calls applied to it, e.g. "scale(kafka.*.bytes,8)" returns
+// "kafka.*.bytes".
+func metricPath(target string) string {
+	if i := strings.LastIndex(target, "("); i >= 0 {
+		target = target[i+1:]
+	}
+
+	if i := strings.IndexAny(target, ",)"); i >= 0 {
+		target = target[:i]
+	}
+
+	return strings.TrimSpace(target)
+}
@@ -0,0 +1,255 @@
+// Package graphite implements
+// a kafkametrics Handler.
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the graphite-web server address.
+	// Example: "http://graphite:8080"
+	URL string
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// NetworkTXTarget is a render API target that should return the outbound
+	// network throughput in bytes/s for the reference Kafka brokers, one
+	// series per broker.
+	// Example: "perSecond(kafka.*.net.bytes_sent)"
+	NetworkTXTarget string
+	// NetworkRXTarget is the inbound network equivalent of NetworkTXTarget.
+	NetworkRXTarget string
+	// BrokerIDNode is the zero-indexed metric path segment that identifies
+	// the broker, e.g. 1 for "kafka.1001.net.bytes_sent".
+	BrokerIDNode int
+	// BrokerIDMap optionally maps BrokerIDNode values, such as hostnames, to
+	// Kafka broker IDs. If unset, segment values must be the broker IDs.
+	BrokerIDMap map[string]int
+	// InstanceType is the instance type applied to all brokers.
+	InstanceType string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// EventTags are tags applied to all events, in addition to the
+	// event tags.
+	EventTags []string
+}
+
+type graphiteHandler struct {
+	c             *http.Client
+	url           string
+	username      string
+	password      string
+	targets       []string
+	brokerIDNode  int
+	brokerIDMap   map[string]int
+	instanceType  string
+	metricsWindow int
+	eventTags     []string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "":
+		return nil, errors.New("graphite URL must be specified")
+	case c.NetworkTXTarget == "", c.NetworkRXTarget == "":
+		return nil, errors.New("network tx and rx targets must be specified")
+	case c.BrokerIDNode < 0:
+		return nil, errors.New("broker ID node must be >= 0")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	h := &graphiteHandler{
+		c:             &http.Client{Timeout: 30 * time.Second},
+		url:           strings.TrimSuffix(c.URL, "/"),
+		username:      c.Username,
+		password:      c.Password,
+		targets:       []string{c.NetworkTXTarget, c.NetworkRXTarget},
+		brokerIDNode:  c.BrokerIDNode,
+		brokerIDMap:   c.BrokerIDMap,
+		instanceType:  c.InstanceType,
+		metricsWindow: c.MetricsWindow,
+		eventTags:     c.EventTags,
+	}
+
+	// Validate.
+	if _, err := h.render(context.Background(), "constantLine(1)", 60); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate connectivity",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent writes an event to the graphite-web events endpoint.
+func (h *graphiteHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *graphiteHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	ev := &event{
+		What: e.Title,
+		Data: e.Text,
+		Tags: append(append([]string{}, h.eventTags...), e.Tags...),
+	}
+
+	if err := h.postEvent(ctx, ev); err != nil {
+		return &kafkametrics.APIError{
+			Request: "post event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics from the Graphite render API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *graphiteHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *graphiteHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, target := range h.targets {
+		s, err := h.render(ctx, target, h.metricsWindow)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "render",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(s) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with target %s", target),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, s, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and []series and populates the
+// BrokerMetrics with the window average for the metric type. The number of
+// brokers populated is returned along with any errors.
+func (h *graphiteHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, s []series, metricType int) (int, []error) {
+	var errors []error
+	var unmapped bytes.Buffer
+	var n int
+
+	for _, ser := range s {
+		path := metricPath(ser.Target)
+
+		nodes := strings.Split(path, ".")
+		if h.brokerIDNode >= len(nodes) {
+			unmapped.WriteString(" " + path)
+			continue
+		}
+		node := nodes[h.brokerIDNode]
+
+		id, err := h.brokerID(node)
+		if err != nil {
+			unmapped.WriteString(" " + path)
+			continue
+		}
+
+		var sum float64
+		var points int
+		for _, dp := range ser.Datapoints {
+			if dp[0] == nil {
+				continue
+			}
+			sum += *dp[0]
+			points++
+		}
+
+		if points == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", node),
+			})
+			continue
+		}
+
+		avg := sum / float64(points)
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         node,
+				InstanceType: h.instanceType,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if unmapped.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Unable to map metric paths to broker IDs:%s", unmapped.String()),
+		})
+	}
+
+	return n, errors
+}
+
+// brokerID returns the Kafka broker ID for a broker ID path segment.
+func (h *graphiteHandler) brokerID(v string) (int, error) {
+	if len(h.brokerIDMap) > 0 {
+		if id, exists := h.brokerIDMap[v]; exists {
+			return id, nil
+		}
+		return 0, fmt.Errorf("no broker ID mapped for %s", v)
+	}
+
+	return strconv.Atoi(v)
+}
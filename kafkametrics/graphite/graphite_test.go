@@ -0,0 +1,163 @@
+package graphite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestRender(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if req.URL.Path != "/render" || q.Get("target") != "tx" || q.Get("from") != "-120s" || q.Get("format") != "json" {
+			t.Errorf("Unexpected request %s\n", req.URL)
+		}
+
+		if u, p, _ := req.BasicAuth(); u != "user" || p != "pass" {
+			t.Errorf("Unexpected credentials %s:%s\n", u, p)
+		}
+
+		fmt.Fprint(w, `[{"target":"perSecond(kafka.kafka-0.net.bytes_sent)","datapoints":[[1048576,1],[null,2]]}]`)
+	}))
+	defer srv.Close()
+
+	h := &graphiteHandler{c: srv.Client(), url: srv.URL, username: "user", password: "pass"}
+
+	s, err := h.render(context.Background(), "tx", 120)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s) != 1 || s[0].Target != "perSecond(kafka.kafka-0.net.bytes_sent)" || len(s[0].Datapoints) != 2 {
+		t.Fatalf("Unexpected series %+v\n", s)
+	}
+
+	// Intervals without data have null values.
+	if dp := s[0].Datapoints; *dp[0][0] != 1048576 || dp[1][0] != nil || *dp[1][1] != 2 {
+		t.Errorf("Unexpected datapoints %v\n", dp)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		{http.StatusUnauthorized, "Unauthorized\n", "unexpected status 401: Unauthorized"},
+		{http.StatusBadRequest, "Bad Request: unknown function foo\n", "unexpected status 400: Bad Request: unknown function foo"},
+		{http.StatusOK, "<html></html>", "error parsing render response: invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &graphiteHandler{c: srv.Client(), url: srv.URL}
+
+		if _, err := h.render(context.Background(), "tx", 60); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &graphiteHandler{
+		brokerIDNode: 1,
+		brokerIDMap:  map[string]int{"kafka-0": 1000, "kafka-2": 1002},
+		instanceType: "stub",
+	}
+
+	var s []series
+	json.Unmarshal([]byte(`[
+		{"target":"scale(perSecond(kafka.kafka-0.net.bytes_sent),1)","datapoints":[[1048576,1],[null,2],[3145728,3]]},
+		{"target":"perSecond(kafka.kafka-2.net.bytes_sent)","datapoints":[[null,1]]},
+		{"target":"perSecond(kafka.kafka-9.net.bytes_sent)","datapoints":[[1048576,1]]},
+		{"target":"kafka","datapoints":[[1048576,1]]}
+	]`), &s)
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, s, 0)
+
+	// Null datapoints are excluded from the average.
+	if b := bm[1000]; n != 1 || b == nil || b.NetTX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-2",
+		"Unable to map metric paths to broker IDs: kafka.kafka-9.net.bytes_sent kafka",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	events := make(chan *event, 1)
+	status := http.StatusOK
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e := &event{}
+		json.NewDecoder(req.Body).Decode(e)
+		events <- e
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	h := &graphiteHandler{c: srv.Client(), url: srv.URL, eventTags: []string{"kafka"}}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := <-events
+	if e.What != "title" || e.Data != "text" {
+		t.Errorf("Unexpected event %+v\n", e)
+	}
+
+	if len(e.Tags) != 2 || e.Tags[0] != "kafka" || e.Tags[1] != "name:kafka-autothrottle" {
+		t.Errorf("Unexpected event tags %v\n", e.Tags)
+	}
+
+	status = http.StatusInternalServerError
+
+	if _, ok := h.PostEvent(&kafkametrics.Event{Title: "title"}).(*kafkametrics.APIError); !ok {
+		t.Error("Expected APIError")
+	}
+}
+
+func TestMetricPath(t *testing.T) {
+	tests := map[string]string{
+		"kafka.kafka-0.net.bytes_sent":                     "kafka.kafka-0.net.bytes_sent",
+		"perSecond(kafka.kafka-0.net.bytes_sent)":          "kafka.kafka-0.net.bytes_sent",
+		"scale(perSecond(kafka.kafka-0.net.bytes_sent),8)": "kafka.kafka-0.net.bytes_sent",
+	}
+
+	for target, expected := range tests {
+		if got := metricPath(target); got != expected {
+			t.Errorf("Expected %s, got %s\n", expected, got)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxClient is a minimal client for the InfluxDB v2 API.
+type influxClient struct {
+	c     *http.Client
+	url   string
+	token string
+	org   string
+}
+
+// do issues an authenticated POST to the API path, returning the response
+// body.
+func (i *influxClient) do(ctx context.Context, path string, params url.Values, contentType string, body []byte) ([]byte, error) {
+	params.Set("org", i.org)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s%s?%s", i.url, path, params.Encode()), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Token "+i.token)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := i.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(d, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("%s: %s", e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return d, nil
+}
+
+// query runs a Flux query and returns the result rows as maps of column
+// name to value.
+func (i *influxClient) query(ctx context.Context, flux string) ([]map[string]string, error) {
+	body, err := i.do(ctx, "/api/v2/query", url.Values{}, "application/vnd.flux", []byte(flux))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCSV(body)
+}
+
+// parseCSV parses a Flux CSV response. Responses may hold several tables,
+// each with its own header row; header rows are identified by the "result"
+// and "table" columns. Annotation rows are skipped.
+func parseCSV(b []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+
+	var rows []map[string]string
+	var header []string
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing query response: %s", err)
+		}
+
+		if len(rec) == 0 || strings.HasPrefix(rec[0], "#") {
+			continue
+		}
+
+		if len(rec) > 2 && rec[1] == "result" && rec[2] == "table" {
+			header = rec
+			continue
+		}
+
+		if header == nil || len(rec) != len(header) {
+			continue
+		}
+
+		row := map[string]string{}
+		for n, col := range header {
+			row[col] = rec[n]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// writePoint writes a single point in line protocol to the bucket.
+func (i *influxClient) writePoint(ctx context.Context, bucket, measurement string, tags, fields map[string]string, t time.Time) error {
+	params := url.Values{}
+	params.Set("bucket", bucket)
+	params.Set("precision", "s")
+
+	line := lineProtocol(measurement, tags, fields, t)
+	_, err := i.do(ctx, "/api/v2/write", params, "text/plain; charset=utf-8", []byte(line))
+
+	return err
+}
+
+// lineProtocol formats a point as line protocol. Fields are written
+// as strings.
+func lineProtocol(measurement string, tags, fields map[string]string, t time.Time) string {
+	tagEscaper := strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	fieldEscaper := strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+
+	var b strings.Builder
+	b.WriteString(strings.NewReplacer(",", `\,`, " ", `\ `).Replace(measurement))
+
+	var keys []string
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, ",%s=%s", tagEscaper.Replace(k), tagEscaper.Replace(tags[k]))
+	}
+
+	keys = keys[:0]
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for n, k := range keys {
+		sep := ","
+		if n == 0 {
+			sep = " "
+		}
+		fmt.Fprintf(&b, `%s%s="%s"`, sep, tagEscaper.Replace(k), fieldEscaper.Replace(fields[k]))
+	}
+
+	b.WriteString(" " + strconv.FormatInt(t.Unix(), 10))
+
+	return b.String()
+}
@@ -0,0 +1,284 @@
+// Package influxdb implements
+// a kafkametrics Handler.
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the InfluxDB server address.
+	// Example: "http://influxdb:8086"
+	URL string
+	// Token is the InfluxDB API token.
+	Token string
+	// Org is the InfluxDB organization.
+	Org string
+	// NetworkTXQuery is a Flux query that should return the outbound network
+	// throughput in bytes/s for the reference Kafka brokers. The metrics
+	// window is available to queries as v.timeRangeStart and v.timeRangeStop.
+	// Example: from(bucket: "telegraf") |> range(start: v.timeRangeStart, stop: v.timeRangeStop) |> filter(fn: (r) => r._measurement == "net" and r._field == "bytes_sent") |> derivative(unit: 1s, nonNegative: true)
+	NetworkTXQuery string
+	// NetworkRXQuery is the inbound network equivalent of NetworkTXQuery.
+	NetworkRXQuery string
+	// BrokerIDTag is the tag name for Kafka broker IDs.
+	BrokerIDTag string
+	// InstanceTypeTag is the tag name for the Kafka broker's instance type.
+	InstanceTypeTag string
+	// HostTag is the tag name for the Kafka broker's hostname. Defaults
+	// to "host".
+	HostTag string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// EventsBucket is an optional bucket that events are written to as
+	// annotation points.
+	EventsBucket string
+	// EventsMeasurement is the measurement name for event points. Defaults
+	// to "events".
+	EventsMeasurement string
+}
+
+type influxHandler struct {
+	c                 *influxClient
+	queries           []string
+	brokerIDTag       string
+	instanceTypeTag   string
+	hostTag           string
+	metricsWindow     int
+	eventsBucket      string
+	eventsMeasurement string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "" || c.Org == "":
+		return nil, errors.New("influxdb URL and org must be specified")
+	case c.NetworkTXQuery == "", c.NetworkRXQuery == "":
+		return nil, errors.New("network tx and rx queries must be specified")
+	case c.BrokerIDTag == "":
+		return nil, errors.New("broker ID tag must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	// Define the v.timeRangeStart and v.timeRangeStop variables in the same
+	// manner as the InfluxDB UI.
+	window := fmt.Sprintf("option v = {timeRangeStart: -%ds, timeRangeStop: now()}\n", c.MetricsWindow)
+
+	h := &influxHandler{
+		c: &influxClient{
+			c:     &http.Client{Timeout: 30 * time.Second},
+			url:   strings.TrimSuffix(c.URL, "/"),
+			token: c.Token,
+			org:   c.Org,
+		},
+		queries:           []string{window + c.NetworkTXQuery, window + c.NetworkRXQuery},
+		brokerIDTag:       c.BrokerIDTag,
+		instanceTypeTag:   c.InstanceTypeTag,
+		hostTag:           "host",
+		metricsWindow:     c.MetricsWindow,
+		eventsBucket:      c.EventsBucket,
+		eventsMeasurement: "events",
+	}
+
+	if c.HostTag != "" {
+		h.hostTag = c.HostTag
+	}
+
+	if c.EventsMeasurement != "" {
+		h.eventsMeasurement = c.EventsMeasurement
+	}
+
+	// Validate.
+	if _, err := h.c.query(context.Background(), "buckets() |> limit(n: 1)"); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent writes an event as an annotation point to the configured events
+// bucket. Event tags in key:value form are written as point tags. If no
+// events bucket is configured, the event is discarded.
+func (h *influxHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *influxHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.eventsBucket == "" {
+		return nil
+	}
+
+	tags := map[string]string{}
+	for _, t := range e.Tags {
+		if kv := strings.SplitN(t, ":", 2); len(kv) == 2 {
+			tags[kv[0]] = kv[1]
+		}
+	}
+
+	fields := map[string]string{
+		"title": e.Title,
+		"text":  e.Text,
+	}
+
+	if err := h.c.writePoint(ctx, h.eventsBucket, h.eventsMeasurement, tags, fields, time.Now()); err != nil {
+		return &kafkametrics.APIError{
+			Request: "write event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the InfluxDB API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *influxHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *influxHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, query := range h.queries {
+		rows, err := h.c.query(ctx, query)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(rows) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with query %s", query),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, rows, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and query result rows and
+// populates the BrokerMetrics with the window average of each broker's
+// _value column for the metric type. The number of brokers populated is
+// returned along with any errors.
+func (h *influxHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, rows []map[string]string, metricType int) (int, []error) {
+	var missingTags bytes.Buffer
+
+	type series struct {
+		host string
+		it   string
+		sum  float64
+		n    int
+	}
+
+	// Group values by broker ID.
+	brokers := map[int]*series{}
+	var missing = map[string]struct{}{}
+
+	for _, row := range rows {
+		host := row[h.hostTag]
+
+		id, err := strconv.Atoi(row[h.brokerIDTag])
+		if err != nil {
+			missing[fmt.Sprintf(" %s:%s", h.brokerIDTag, host)] = struct{}{}
+			continue
+		}
+
+		var it string
+		if h.instanceTypeTag != "" {
+			it = row[h.instanceTypeTag]
+			if it == "" {
+				missing[fmt.Sprintf(" %s:%s", h.instanceTypeTag, host)] = struct{}{}
+				continue
+			}
+		}
+
+		v, err := strconv.ParseFloat(row["_value"], 64)
+		if err != nil {
+			continue
+		}
+
+		s, exists := brokers[id]
+		if !exists {
+			s = &series{host: host, it: it}
+			brokers[id] = s
+		}
+
+		s.sum += v
+		s.n++
+	}
+
+	for id, s := range brokers {
+		avg := s.sum / float64(s.n)
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         s.host,
+				InstanceType: s.it,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+	}
+
+	for m := range missing {
+		missingTags.WriteString(m)
+	}
+
+	if missingTags.String() != "" {
+		return len(brokers), []error{&kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing tags:%s", missingTags.String()),
+		}}
+	}
+
+	return len(brokers), nil
+}
@@ -0,0 +1,188 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		if req.URL.Path != "/api/v2/query" || req.URL.Query().Get("org") != "kafka" || string(body) != "tx" {
+			t.Errorf("Unexpected request %s %s\n", req.URL, body)
+		}
+
+		if req.Header.Get("Authorization") != "Token token" || req.Header.Get("Content-Type") != "application/vnd.flux" {
+			t.Errorf("Unexpected headers %v\n", req.Header)
+		}
+
+		// An annotated response with a table per broker.
+		fmt.Fprint(w, "#datatype,string,long,double,string\r\n"+
+			"#group,false,false,false,true\r\n"+
+			"#default,_result,,,\r\n"+
+			",result,table,_value,broker_id\r\n"+
+			",,0,1048576,1000\r\n"+
+			",,0,3145728,1000\r\n"+
+			",,1,524288,1001\r\n\r\n")
+	}))
+	defer srv.Close()
+
+	c := &influxClient{c: srv.Client(), url: srv.URL, token: "token", org: "kafka"}
+
+	rows, err := c.query(context.Background(), "tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 3 || rows[1]["_value"] != "3145728" || rows[2]["broker_id"] != "1001" || rows[2]["table"] != "1" {
+		t.Errorf("Unexpected rows %v\n", rows)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// InfluxDB error codes and messages are returned.
+		{
+			http.StatusUnauthorized,
+			`{"code":"unauthorized","message":"unauthorized access"}`,
+			"unauthorized: unauthorized access",
+		},
+		{
+			http.StatusBadRequest,
+			`{"code":"invalid","message":"compilation failed: error at @1:1-1:3: undefined identifier tx"}`,
+			"invalid: compilation failed: error at @1:1-1:3: undefined identifier tx",
+		},
+		{http.StatusServiceUnavailable, "", "unexpected status 503"},
+		{http.StatusOK, ",result,table,_value\r\n,,0,\"1", "error parsing query response: parse error on line 2, column 7: extraneous or missing \" in quoted-field"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		c := &influxClient{c: srv.Client(), url: srv.URL}
+
+		if _, err := c.query(context.Background(), "tx"); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &influxHandler{brokerIDTag: "broker_id", instanceTypeTag: "instance_type", hostTag: "host"}
+
+	rows := []map[string]string{
+		// Rows are averaged per broker.
+		{"_value": "1048576", "broker_id": "1000", "host": "kafka-0", "instance_type": "stub"},
+		{"_value": "3145728", "broker_id": "1000", "host": "kafka-0", "instance_type": "stub"},
+		// Non-numeric values are skipped.
+		{"_value": "", "broker_id": "1000", "host": "kafka-0", "instance_type": "stub"},
+		{"_value": "1048576", "broker_id": "1001", "host": "kafka-1"},
+		{"_value": "1048576", "host": "kafka-9", "instance_type": "stub"},
+		{"_value": "1048576", "host": "kafka-9", "instance_type": "stub"},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, rows, 0)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetTX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %s\n", len(errs), errs)
+	}
+
+	// Each missing tag is reported once.
+	msg := errs[0].Error()
+	if !strings.HasPrefix(msg, "Missing tags: ") || len(msg) != len("Missing tags: instance_type:kafka-1 broker_id:kafka-9") ||
+		!strings.Contains(msg, " instance_type:kafka-1") || !strings.Contains(msg, " broker_id:kafka-9") {
+		t.Errorf("Unexpected error %s\n", msg)
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	writes := make(chan string, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if q := req.URL.Query(); req.URL.Path != "/api/v2/write" || q.Get("bucket") != "events" || q.Get("precision") != "s" {
+			t.Errorf("Unexpected request %s\n", req.URL)
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		writes <- string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	h := &influxHandler{c: &influxClient{c: srv.Client(), url: srv.URL}, eventsMeasurement: "events"}
+
+	e := &kafkametrics.Event{
+		Title: "throttle set",
+		Text:  `rate "100"`,
+		Tags:  []string{"name:kafka-autothrottle", "invalid"},
+	}
+
+	// Events are discarded without an events bucket.
+	if err := h.PostEvent(e); err != nil || len(writes) != 0 {
+		t.Fatalf("Expected discarded event, got %v\n", err)
+	}
+
+	h.eventsBucket = "events"
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	line := <-writes
+	expected := `events,name=kafka-autothrottle text="rate \"100\"",title="throttle set"`
+	if !strings.HasPrefix(line, expected) {
+		t.Errorf("Expected line prefix:\n%s\ngot:\n%s\n", expected, line)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	// Two tables with differing schemas.
+	b := []byte(",result,table,_value,host\r\n,_result,0,1,host0\r\n\r\n" +
+		",result,table,_value,host,broker_id\r\n,_result,1,2,host1,1001\r\n")
+
+	rows, err := parseCSV(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d\n", len(rows))
+	}
+
+	if rows[1]["broker_id"] != "1001" || rows[0]["host"] != "host0" {
+		t.Errorf("Unexpected rows %v\n", rows)
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	l := lineProtocol("my events", map[string]string{"a b": "c,d", "empty": ""},
+		map[string]string{"f": `x\y`}, time.Unix(10, 0))
+
+	expected := `my\ events,a\ b=c\,d f="x\\y" 10`
+	if l != expected {
+		t.Errorf("Expected %s, got %s\n", expected, l)
+	}
+}
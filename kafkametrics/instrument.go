@@ -0,0 +1,242 @@
+package kafkametrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryReporter is implemented by Handlers that retry failed requests,
+// reporting the total number of retries made.
+type RetryReporter interface {
+	Retries() uint64
+}
+
+// Request types and results recorded by an InstrumentedHandler.
+const (
+	requestMetrics = "metrics"
+	requestEvent   = "event"
+
+	resultSuccess        = "success"
+	resultAPIError       = "api_error"
+	resultNoResults      = "no_results"
+	resultPartialResults = "partial_results"
+	resultError          = "error"
+)
+
+var (
+	instrumentedRequests = []string{requestMetrics, requestEvent}
+	instrumentedResults  = []string{resultSuccess, resultAPIError, resultNoResults, resultPartialResults, resultError}
+	instrumentedMissing  = []MissingItem{MissingUnknown, MissingPoints, StalePoints, MissingBrokerID, MissingInstanceType}
+
+	// DurationBuckets are the request duration histogram bucket upper
+	// bounds in seconds.
+	DurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+// InstrumentedHandler is a ContextHandler that records request counts,
+// latencies, partial results and resolved brokers of a wrapped Handler.
+// It implements http.Handler, serving the recorded metrics in the
+// Prometheus text exposition format.
+type InstrumentedHandler struct {
+	h Handler
+
+	mu sync.Mutex
+	// Request counts by request type and result.
+	requests map[string]map[string]uint64
+	// Duration histogram bucket counts, sums and counts by request type.
+	buckets   map[string][]uint64
+	durations map[string]float64
+	counts    map[string]uint64
+	skipped   map[MissingItem]uint64
+	resolved  int
+	partial   uint64
+	// The first error of the most recent metrics request returning no
+	// broker metrics.
+	metricsErr error
+}
+
+// NewInstrumentedHandler takes a Handler and returns an
+// *InstrumentedHandler.
+func NewInstrumentedHandler(h Handler) *InstrumentedHandler {
+	i := &InstrumentedHandler{
+		h:         h,
+		requests:  map[string]map[string]uint64{},
+		buckets:   map[string][]uint64{},
+		durations: map[string]float64{},
+		counts:    map[string]uint64{},
+		skipped:   map[MissingItem]uint64{},
+	}
+
+	for _, r := range instrumentedRequests {
+		i.requests[r] = map[string]uint64{}
+		i.buckets[r] = make([]uint64, len(DurationBuckets))
+	}
+
+	return i
+}
+
+// GetMetrics requests metrics from the wrapped Handler.
+func (i *InstrumentedHandler) GetMetrics() (BrokerMetrics, []error) {
+	return i.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext requests metrics from the wrapped Handler with the
+// context.
+func (i *InstrumentedHandler) GetMetricsContext(ctx context.Context) (BrokerMetrics, []error) {
+	start := time.Now()
+	bm, errs := GetMetricsContext(ctx, i.h)
+	d := time.Since(start)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.observe(requestMetrics, resultFromErrors(errs), d)
+	i.resolved = len(bm)
+
+	i.metricsErr = nil
+	if len(bm) == 0 && len(errs) > 0 {
+		i.metricsErr = errs[0]
+	}
+
+	for _, err := range errs {
+		if _, ok := err.(*PartialResults); ok {
+			i.partial++
+		}
+	}
+
+	for _, s := range SkippedBrokers(errs) {
+		i.skipped[s.Missing]++
+	}
+
+	return bm, errs
+}
+
+// MetricsError returns the first error of the most recent metrics request
+// if it returned no broker metrics, otherwise nil.
+func (i *InstrumentedHandler) MetricsError() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.metricsErr
+}
+
+// PostEvent posts the event to the wrapped Handler.
+func (i *InstrumentedHandler) PostEvent(e *Event) error {
+	return i.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext posts the event to the wrapped Handler with the context.
+func (i *InstrumentedHandler) PostEventContext(ctx context.Context, e *Event) error {
+	start := time.Now()
+	err := PostEventContext(ctx, i.h, e)
+	d := time.Since(start)
+
+	var errs []error
+	if err != nil {
+		errs = []error{err}
+	}
+
+	i.mu.Lock()
+	i.observe(requestEvent, resultFromErrors(errs), d)
+	i.mu.Unlock()
+
+	return err
+}
+
+// observe records a request. The lock must be held.
+func (i *InstrumentedHandler) observe(request, result string, d time.Duration) {
+	i.requests[request][result]++
+
+	s := d.Seconds()
+	for n, le := range DurationBuckets {
+		if s <= le {
+			i.buckets[request][n]++
+		}
+	}
+
+	i.durations[request] += s
+	i.counts[request]++
+}
+
+// resultFromErrors returns the result name for a request from its
+// errors. Where a request returns several error types, the most
+// severe is used.
+func resultFromErrors(errs []error) string {
+	if len(errs) == 0 {
+		return resultSuccess
+	}
+
+	result := resultError
+	for _, err := range errs {
+		switch err.(type) {
+		case *APIError:
+			return resultAPIError
+		case *NoResults:
+			result = resultNoResults
+		case *PartialResults:
+			if result == resultError {
+				result = resultPartialResults
+			}
+		}
+	}
+
+	return result
+}
+
+// ServeHTTP serves the recorded metrics in the Prometheus text exposition
+// format.
+func (i *InstrumentedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_ = req
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	i.WriteMetrics(w)
+}
+
+// WriteMetrics writes the recorded metrics in the Prometheus text
+// exposition format to the io.Writer.
+func (i *InstrumentedHandler) WriteMetrics(w io.Writer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP kafkametrics_requests_total Metrics and event requests by request type and result.")
+	fmt.Fprintln(w, "# TYPE kafkametrics_requests_total counter")
+	for _, r := range instrumentedRequests {
+		for _, res := range instrumentedResults {
+			fmt.Fprintf(w, "kafkametrics_requests_total{request=%q,result=%q} %d\n", r, res, i.requests[r][res])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP kafkametrics_request_duration_seconds Metrics and event request latencies.")
+	fmt.Fprintln(w, "# TYPE kafkametrics_request_duration_seconds histogram")
+	for _, r := range instrumentedRequests {
+		for n, le := range DurationBuckets {
+			fmt.Fprintf(w, "kafkametrics_request_duration_seconds_bucket{request=%q,le=\"%g\"} %d\n", r, le, i.buckets[r][n])
+		}
+		fmt.Fprintf(w, "kafkametrics_request_duration_seconds_bucket{request=%q,le=\"+Inf\"} %d\n", r, i.counts[r])
+		fmt.Fprintf(w, "kafkametrics_request_duration_seconds_sum{request=%q} %g\n", r, i.durations[r])
+		fmt.Fprintf(w, "kafkametrics_request_duration_seconds_count{request=%q} %d\n", r, i.counts[r])
+	}
+
+	fmt.Fprintln(w, "# HELP kafkametrics_partial_results_total Partial results errors returned by metrics requests.")
+	fmt.Fprintln(w, "# TYPE kafkametrics_partial_results_total counter")
+	fmt.Fprintf(w, "kafkametrics_partial_results_total %d\n", i.partial)
+
+	fmt.Fprintln(w, "# HELP kafkametrics_skipped_brokers_total Brokers skipped by metrics requests by missing item.")
+	fmt.Fprintln(w, "# TYPE kafkametrics_skipped_brokers_total counter")
+	for _, m := range instrumentedMissing {
+		fmt.Fprintf(w, "kafkametrics_skipped_brokers_total{missing=%q} %d\n", m, i.skipped[m])
+	}
+
+	fmt.Fprintln(w, "# HELP kafkametrics_brokers_resolved Brokers resolved by the most recent metrics request.")
+	fmt.Fprintln(w, "# TYPE kafkametrics_brokers_resolved gauge")
+	fmt.Fprintf(w, "kafkametrics_brokers_resolved %d\n", i.resolved)
+
+	if rr, ok := i.h.(RetryReporter); ok {
+		fmt.Fprintln(w, "# HELP kafkametrics_retries_total Retried requests.")
+		fmt.Fprintln(w, "# TYPE kafkametrics_retries_total counter")
+		fmt.Fprintf(w, "kafkametrics_retries_total %d\n", rr.Retries())
+	}
+}
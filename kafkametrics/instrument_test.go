@@ -0,0 +1,120 @@
+package kafkametrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type retryStub struct {
+	Stub
+	retries uint64
+}
+
+func (s *retryStub) Retries() uint64 {
+	return s.retries
+}
+
+type partialStub struct {
+	Stub
+}
+
+func (s *partialStub) GetMetrics() (BrokerMetrics, []error) {
+	bm, _ := s.Stub.GetMetrics()
+	return bm, []error{&PartialResults{Skipped: []SkippedBroker{{Host: "host0", Missing: StalePoints}}}}
+}
+
+func TestResultFromErrors(t *testing.T) {
+	tests := []struct {
+		errs     []error
+		expected string
+	}{
+		{nil, resultSuccess},
+		{[]error{&PartialResults{}}, resultPartialResults},
+		{[]error{&PartialResults{}, &NoResults{}}, resultNoResults},
+		{[]error{&NoResults{}, &APIError{}}, resultAPIError},
+		{[]error{errors.New("error")}, resultError},
+	}
+
+	for i, test := range tests {
+		if r := resultFromErrors(test.errs); r != test.expected {
+			t.Errorf("[test index %d] Expected result %s, got %s\n", i, test.expected, r)
+		}
+	}
+}
+
+func TestInstrumentedHandler(t *testing.T) {
+	h := NewInstrumentedHandler(&partialStub{})
+
+	h.GetMetrics()
+	h.GetMetrics()
+	h.PostEvent(&Event{})
+
+	var b bytes.Buffer
+	h.WriteMetrics(&b)
+	out := b.String()
+
+	for _, expected := range []string{
+		`kafkametrics_requests_total{request="metrics",result="partial_results"} 2`,
+		`kafkametrics_requests_total{request="event",result="success"} 1`,
+		`kafkametrics_request_duration_seconds_bucket{request="metrics",le="+Inf"} 2`,
+		`kafkametrics_request_duration_seconds_count{request="event"} 1`,
+		`kafkametrics_partial_results_total 2`,
+		`kafkametrics_skipped_brokers_total{missing="stale points"} 2`,
+		`kafkametrics_brokers_resolved 10`,
+	} {
+		if !strings.Contains(out, expected+"\n") {
+			t.Errorf("Expected metric %s, got:\n%s\n", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "kafkametrics_retries_total") {
+		t.Error("Unexpected retries metric")
+	}
+
+	// Retries are reported from RetryReporter Handlers.
+	h = NewInstrumentedHandler(&retryStub{retries: 3})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if !strings.Contains(w.Body.String(), "kafkametrics_retries_total 3\n") {
+		t.Errorf("Expected retries metric, got:\n%s\n", w.Body.String())
+	}
+}
+
+func TestInstrumentedMetricsError(t *testing.T) {
+	f := &failingStub{err: &APIError{Request: "test", Message: "test"}}
+	h := NewInstrumentedHandler(f)
+
+	if err := h.MetricsError(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	h.GetMetrics()
+	if err := h.MetricsError(); err != f.err {
+		t.Errorf("Expected error %s, got %v\n", f.err, err)
+	}
+
+	// Partial results aren't reported.
+	h = NewInstrumentedHandler(&partialStub{})
+	h.GetMetrics()
+	if err := h.MetricsError(); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+}
+
+func TestCompositeRetries(t *testing.T) {
+	r := &retryStub{retries: 2}
+
+	c, _ := NewCompositeHandler(&CompositeConfig{
+		Metrics: []Handler{r},
+		Events:  []Handler{r, &retryStub{retries: 1}, &Stub{}},
+	})
+
+	if n := c.Retries(); n != 3 {
+		t.Errorf("Expected 3 retries, got %d\n", n)
+	}
+}
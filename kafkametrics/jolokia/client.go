@@ -0,0 +1,92 @@
+package jolokia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// readRequest is a Jolokia read request.
+type readRequest struct {
+	Type      string   `json:"type"`
+	MBean     string   `json:"mbean"`
+	Attribute []string `json:"attribute"`
+}
+
+// readResponse is a Jolokia read response.
+type readResponse struct {
+	Value  meterValue `json:"value"`
+	Status int        `json:"status"`
+	Error  string     `json:"error"`
+}
+
+// meterValue holds the Yammer meter attributes read from an MBean.
+type meterValue struct {
+	Count         float64 `json:"Count"`
+	OneMinuteRate float64 `json:"OneMinuteRate"`
+}
+
+// read issues a bulk read request for the MBeans to the Jolokia endpoint,
+// returning the meter values in the order of the MBeans.
+func (h *jolokiaHandler) read(ctx context.Context, endpoint string, mbeans []string) ([]meterValue, error) {
+	var reqs []readRequest
+	for _, m := range mbeans {
+		reqs = append(reqs, readRequest{
+			Type:      "read",
+			MBean:     m,
+			Attribute: []string{"Count", "OneMinuteRate"},
+		})
+	}
+
+	b, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var r []readResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error parsing read response: %s", err)
+	}
+
+	if len(r) != len(mbeans) {
+		return nil, fmt.Errorf("expected %d read responses, got %d", len(mbeans), len(r))
+	}
+
+	var values []meterValue
+	for i, v := range r {
+		if v.Status != http.StatusOK {
+			return nil, fmt.Errorf("error reading %s: %s", mbeans[i], v.Error)
+		}
+		values = append(values, v.Value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,243 @@
+// Package jolokia implements
+// a kafkametrics Handler.
+package jolokia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+const (
+	bytesOutMBean = "kafka.server:type=BrokerTopicMetrics,name=BytesOutPerSec"
+	bytesInMBean  = "kafka.server:type=BrokerTopicMetrics,name=BytesInPerSec"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Brokers is the list of reference Kafka brokers to scrape.
+	Brokers []Broker
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// NetworkTXMBean is the outbound throughput meter MBean. Defaults to
+	// the BrokerTopicMetrics BytesOutPerSec MBean.
+	NetworkTXMBean string
+	// NetworkRXMBean is the inbound throughput meter MBean. Defaults to
+	// the BrokerTopicMetrics BytesInPerSec MBean.
+	NetworkRXMBean string
+	// MetricsWindow specifies the window size in seconds over which scraped
+	// meter counts are evaluated.
+	MetricsWindow int
+	// Timeout is the HTTP request timeout in seconds. Defaults to 10.
+	Timeout int
+}
+
+// Broker is a Kafka broker Jolokia endpoint.
+type Broker struct {
+	// ID is the Kafka broker ID.
+	ID int
+	// URL is the broker's Jolokia agent endpoint.
+	// Example: "http://kafka-0:8778/jolokia"
+	URL string
+	// InstanceType is the broker's instance type.
+	InstanceType string
+}
+
+// sample is a scraped pair of tx and rx meter values.
+type sample struct {
+	ts     time.Time
+	values []meterValue
+}
+
+type jolokiaHandler struct {
+	c             *http.Client
+	brokers       []Broker
+	username      string
+	password      string
+	mbeans        []string
+	metricsWindow time.Duration
+
+	sync.Mutex
+	samples map[int][]sample
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or connectivity validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case len(c.Brokers) == 0:
+		return nil, errors.New("brokers must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	for _, b := range c.Brokers {
+		if _, err := url.Parse(b.URL); err != nil || b.URL == "" {
+			return nil, fmt.Errorf("invalid URL for broker %d: %s", b.ID, b.URL)
+		}
+	}
+
+	h := &jolokiaHandler{
+		c:             &http.Client{Timeout: 10 * time.Second},
+		brokers:       c.Brokers,
+		username:      c.Username,
+		password:      c.Password,
+		mbeans:        []string{bytesOutMBean, bytesInMBean},
+		metricsWindow: time.Duration(c.MetricsWindow) * time.Second,
+		samples:       make(map[int][]sample),
+	}
+
+	if c.NetworkTXMBean != "" {
+		h.mbeans[0] = c.NetworkTXMBean
+	}
+
+	if c.NetworkRXMBean != "" {
+		h.mbeans[1] = c.NetworkRXMBean
+	}
+
+	if c.Timeout > 0 {
+		h.c.Timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	// Validate; the initial scrape also seeds the samples.
+	if bm, errs := h.GetMetrics(); bm == nil {
+		return nil, errs[0]
+	}
+
+	return h, nil
+}
+
+// PostEvent isn't supported; Jolokia doesn't have an event API, so events
+// are discarded.
+func (h *jolokiaHandler) PostEvent(*kafkametrics.Event) error {
+	return nil
+}
+
+// PostEventContext isn't supported; see PostEvent.
+func (h *jolokiaHandler) PostEventContext(context.Context, *kafkametrics.Event) error {
+	return nil
+}
+
+// GetMetrics scrapes the meter MBeans of each broker and returns a
+// BrokerMetrics. Throughput is calculated from the change in meter counts
+// since the newest sample preceding the metrics window; if fewer than two
+// samples are available, the meter's one minute rate is used. If any errors are
+// encountered, the broker will not be included in the BrokerMetrics.
+func (h *jolokiaHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *jolokiaHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	h.Lock()
+	defer h.Unlock()
+
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+	now := time.Now()
+
+	for _, b := range h.brokers {
+		values, err := h.read(ctx, b.URL, h.mbeans)
+		if err != nil {
+			errors = append(errors, &kafkametrics.APIError{
+				Request: "jolokia read",
+				Message: fmt.Sprintf("Error scraping broker %d: %s", b.ID, err),
+			})
+			continue
+		}
+
+		samples := h.addSample(b.ID, sample{ts: now, values: values})
+
+		broker := &kafkametrics.Broker{
+			ID:           b.ID,
+			Host:         hostname(b.URL),
+			InstanceType: b.InstanceType,
+		}
+
+		for i := range h.mbeans {
+			rate := rateOverSamples(samples, i)
+
+			switch i {
+			case 0:
+				broker.NetTX = rate / 1024 / 1024
+			case 1:
+				broker.NetRX = rate / 1024 / 1024
+			}
+		}
+
+		bm[b.ID] = broker
+	}
+
+	if len(bm) == 0 {
+		return nil, errors
+	}
+
+	return bm, errors
+}
+
+// addSample appends a sample to the broker's samples, dropping samples that
+// aren't needed to span the metrics window. Samples are reset if a meter count
+// decreases, i.e. the broker was restarted. The current samples are
+// returned.
+func (h *jolokiaHandler) addSample(id int, s sample) []sample {
+	samples := h.samples[id]
+
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+		for i := range s.values {
+			if s.values[i].Count < last.values[i].Count {
+				samples = nil
+				break
+			}
+		}
+	}
+
+	samples = append(samples, s)
+
+	// Retain the newest sample preceding the window as the baseline for
+	// rate calculations.
+	cutoff := s.ts.Add(-h.metricsWindow)
+	for len(samples) > 2 && !samples[1].ts.After(cutoff) {
+		samples = samples[1:]
+	}
+
+	h.samples[id] = samples
+
+	return samples
+}
+
+// rateOverSamples returns the per-second rate for the meter index over the
+// samples.
+func rateOverSamples(samples []sample, meter int) float64 {
+	last := samples[len(samples)-1]
+
+	if len(samples) < 2 {
+		return last.values[meter].OneMinuteRate
+	}
+
+	first := samples[0]
+	elapsed := last.ts.Sub(first.ts).Seconds()
+	if elapsed <= 0 {
+		return last.values[meter].OneMinuteRate
+	}
+
+	return (last.values[meter].Count - first.values[meter].Count) / elapsed
+}
+
+// hostname returns the hostname of an endpoint URL.
+func hostname(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	return parsed.Hostname()
+}
@@ -0,0 +1,123 @@
+package jolokia
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetMetrics(t *testing.T) {
+	var count float64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var reqs []readRequest
+		body, _ := io.ReadAll(req.Body)
+		json.Unmarshal(body, &reqs)
+
+		var resps []readResponse
+		for i := range reqs {
+			resps = append(resps, readResponse{
+				Status: 200,
+				Value: meterValue{
+					Count:         count * float64(i+1),
+					OneMinuteRate: 1048576 * float64(i+1),
+				},
+			})
+		}
+
+		json.NewEncoder(w).Encode(resps)
+	}))
+	defer srv.Close()
+
+	c := &Config{
+		Brokers: []Broker{
+			{ID: 1001, URL: srv.URL + "/jolokia", InstanceType: "stub"},
+			{ID: 1002, URL: "http://127.0.0.1:1/jolokia"},
+		},
+		MetricsWindow: 120,
+		Timeout:       1,
+	}
+
+	i, err := NewHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := i.(*jolokiaHandler)
+
+	// Initial scrape in NewHandler; the one minute rate is used.
+	bm, errs := h.GetMetrics()
+
+	// Broker 1002 is unreachable.
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d\n", len(errs))
+	}
+
+	b := bm[1001]
+	if b == nil || len(bm) != 1 {
+		t.Fatalf("Expected only broker 1001 in BrokerMetrics, got %v\n", bm)
+	}
+
+	if b.Host != "127.0.0.1" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected broker metadata %+v\n", b)
+	}
+
+	// Backdate the samples and bump the meter count.
+	for n := range h.samples[1001] {
+		h.samples[1001][n].ts = h.samples[1001][n].ts.Add(-60 * time.Second)
+	}
+	count = 60 * 4 * 1048576
+
+	bm, _ = h.GetMetrics()
+	b = bm[1001]
+
+	// 60s elapsed since the baseline sample.
+	if fmt.Sprintf("%.2f", b.NetTX) != "4.00" || fmt.Sprintf("%.2f", b.NetRX) != "8.00" {
+		t.Errorf("Unexpected NetTX/NetRX %.2f/%.2f\n", b.NetTX, b.NetRX)
+	}
+}
+
+func TestAddSample(t *testing.T) {
+	h := &jolokiaHandler{
+		metricsWindow: 120 * time.Second,
+		samples:       make(map[int][]sample),
+	}
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		h.addSample(1, sample{
+			ts:     start.Add(time.Duration(i) * time.Minute),
+			values: []meterValue{{Count: float64(i)}},
+		})
+	}
+
+	// The sample at the window start is retained as a baseline.
+	s := h.samples[1]
+	if len(s) != 3 || s[0].values[0].Count != 3 {
+		t.Errorf("Unexpected samples %v\n", s)
+	}
+
+	// A counter reset clears prior samples.
+	s = h.addSample(1, sample{ts: start.Add(6 * time.Minute), values: []meterValue{{Count: 0}}})
+	if len(s) != 1 {
+		t.Errorf("Expected 1 sample, got %d\n", len(s))
+	}
+}
+
+func TestRateOverSamples(t *testing.T) {
+	now := time.Now()
+	samples := []sample{
+		{ts: now, values: []meterValue{{Count: 0, OneMinuteRate: 5}}},
+	}
+
+	if r := rateOverSamples(samples, 0); r != 5 {
+		t.Errorf("Expected one minute rate 5, got %f\n", r)
+	}
+
+	samples = append(samples, sample{ts: now.Add(10 * time.Second), values: []meterValue{{Count: 100}}})
+	if r := rateOverSamples(samples, 0); r != 10 {
+		t.Errorf("Expected rate 10, got %f\n", r)
+	}
+}
@@ -2,12 +2,24 @@
 // supported metrics backends.
 package kafkametrics
 
+import (
+	"time"
+)
+
 // Handler requests broker metrics and posts events.
 type Handler interface {
 	GetMetrics() (BrokerMetrics, []error)
 	PostEvent(*Event) error
 }
 
+// LagHandler is implemented by Handlers that can fetch consumer group lag.
+type LagHandler interface {
+	GetConsumerLag() (ConsumerLag, error)
+}
+
+// ConsumerLag is a map of consumer group names to their lag, window avg.
+type ConsumerLag map[string]float64
+
 // BrokerMetrics is a map of broker IDs to *Broker structs.
 type BrokerMetrics map[int]*Broker
 
@@ -23,6 +35,73 @@ type Broker struct {
 	NetTX float64
 	// Network rx, window avg.
 	NetRX float64
+	// Disk used in bytes, window avg. Only populated if supported and
+	// configured in the backend.
+	DiskUsed float64
+	// Disk free in bytes, window avg. Only populated if supported and
+	// configured in the backend.
+	DiskFree float64
+	// CPU utilization percent, window avg. Only populated if supported and
+	// configured in the backend.
+	CPU float64
+	// Bytes out serving client (non-replication) fetch requests, window avg.
+	// Only populated if supported and configured in the backend.
+	LeaderBytesOut float64
+	// Bytes out serving follower replication fetch requests, window avg.
+	// Only populated if supported and configured in the backend.
+	ReplicationBytesOut float64
+	// Disk IO (reads and writes) in MB/s, window avg. Only populated if
+	// supported and configured in the backend.
+	DiskIO float64
+	// Max lag of replicas being moved between the broker's log dirs, window
+	// avg. A non-zero lag indicates ongoing log dir moves. Only populated if
+	// supported and configured in the backend.
+	AlterLogDirsLag float64
+	// Network capacity in MB/s. Only populated if a CapacityMap is
+	// applied and includes the broker's instance type.
+	NetworkCapacity float64
+	// Disk capacity in bytes. Only populated if a CapacityMap is applied
+	// and includes the broker's instance type.
+	DiskCapacity float64
+	// Disk IO capacity in MB/s. Only populated if a CapacityMap is applied
+	// and includes the broker's instance type.
+	DiskIOCapacity float64
+	// Metrics holds window avg values for any additional named queries
+	// configured in the backend, keyed by query name.
+	Metrics map[string]float64
+	// Series holds the full windowed series for each metric, keyed by the
+	// Series metric names or the additional named query names, in the same
+	// units as the corresponding Broker fields. Only populated if supported
+	// and configured in the backend.
+	Series map[string][]Point
+}
+
+// Series metric names for the Broker fields.
+const (
+	SeriesNetTX               = "net_tx"
+	SeriesNetRX               = "net_rx"
+	SeriesDiskUsed            = "disk_used"
+	SeriesDiskFree            = "disk_free"
+	SeriesCPU                 = "cpu"
+	SeriesLeaderBytesOut      = "leader_bytes_out"
+	SeriesReplicationBytesOut = "replication_bytes_out"
+	SeriesDiskIO              = "disk_io"
+	SeriesAlterLogDirsLag     = "alter_log_dirs_lag"
+)
+
+// Point is a single timestamped value of a metric series.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// SetSeries sets the named series for the Broker.
+func (b *Broker) SetSeries(name string, points []Point) {
+	if b.Series == nil {
+		b.Series = map[string][]Point{}
+	}
+
+	b.Series[name] = points
 }
 
 // Event is used to post autothrottle events to the backend metrics system.
@@ -30,4 +109,39 @@ type Event struct {
 	Title string
 	Text  string
 	Tags  []string
+	// AggregationKey optionally groups related events, e.g. rolling up
+	// repeated events into a single thread. Only used by backends that
+	// support event aggregation.
+	AggregationKey string `json:",omitempty"`
+	// AlertType is an optional event severity: "info", "warning", "error"
+	// or "success". Only used by backends that support alert types.
+	AlertType string `json:",omitempty"`
+	// SourceTypeName optionally identifies the source of the event. Only
+	// used by backends that support source types.
+	SourceTypeName string `json:",omitempty"`
+}
+
+// WithTags returns a copy of the Event with the tags appended. Tags already
+// present on the Event are not duplicated. The Event is returned as is if no
+// tags are provided.
+func WithTags(e *Event, tags []string) *Event {
+	if len(tags) == 0 {
+		return e
+	}
+
+	existing := map[string]struct{}{}
+	for _, t := range e.Tags {
+		existing[t] = struct{}{}
+	}
+
+	c := *e
+	c.Tags = append([]string{}, e.Tags...)
+	for _, t := range tags {
+		if _, exists := existing[t]; !exists {
+			c.Tags = append(c.Tags, t)
+			existing[t] = struct{}{}
+		}
+	}
+
+	return &c
 }
@@ -0,0 +1,84 @@
+// Package m3 implements
+// a kafkametrics Handler.
+//
+// Queries are issued against the Prometheus compatible query API of an M3
+// coordinator, using the prometheus Handler, with M3 specific request
+// headers selecting the namespaces that are read.
+package m3
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/prometheus"
+)
+
+// M3 coordinator request headers.
+const (
+	metricsTypeHeader       = "M3-Metrics-Type"
+	storagePolicyHeader     = "M3-Storage-Policy"
+	limitMaxSeriesHeader    = "M3-Limit-Max-Series"
+	requireExhaustiveHeader = "M3-Limit-Require-Exhaustive"
+)
+
+// Config holds Handler configuration parameters. The prometheus Config
+// fields are used for the M3 coordinator address and queries.
+// Example URL: "http://m3coordinator:7201"
+type Config struct {
+	prometheus.Config
+	// MetricsType selects the namespace type queried, either "unaggregated"
+	// or "aggregated". If unset, the coordinator fans out queries to all
+	// namespaces.
+	MetricsType string
+	// StoragePolicy is the resolution:retention of the aggregated namespace
+	// queried. Required with the "aggregated" MetricsType.
+	// Example: "1m:40d"
+	StoragePolicy string
+	// LimitMaxSeries optionally overrides the coordinator max series
+	// limit for queries.
+	LimitMaxSeries int
+	// RequireExhaustive causes queries exceeding coordinator limits to fail
+	// rather than return partial results.
+	RequireExhaustive bool
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or connectivity validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.MetricsType != "" && c.MetricsType != "unaggregated" && c.MetricsType != "aggregated":
+		return nil, errors.New("metrics type must be one of unaggregated or aggregated")
+	case c.MetricsType == "aggregated" && c.StoragePolicy == "":
+		return nil, errors.New("storage policy must be specified with the aggregated metrics type")
+	case c.StoragePolicy != "" && c.MetricsType != "aggregated":
+		return nil, errors.New("storage policy may only be specified with the aggregated metrics type")
+	case c.StoragePolicy != "" && strings.Count(c.StoragePolicy, ":") != 1:
+		return nil, errors.New("storage policy must be in resolution:retention form")
+	}
+
+	pc := c.Config
+	pc.Headers = map[string]string{}
+	for k, v := range c.Headers {
+		pc.Headers[k] = v
+	}
+
+	if c.MetricsType != "" {
+		pc.Headers[metricsTypeHeader] = c.MetricsType
+	}
+
+	if c.StoragePolicy != "" {
+		pc.Headers[storagePolicyHeader] = c.StoragePolicy
+	}
+
+	if c.LimitMaxSeries > 0 {
+		pc.Headers[limitMaxSeriesHeader] = strconv.Itoa(c.LimitMaxSeries)
+	}
+
+	if c.RequireExhaustive {
+		pc.Headers[requireExhaustiveHeader] = "true"
+	}
+
+	return prometheus.NewHandler(&pc)
+}
@@ -0,0 +1,132 @@
+package m3
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/prometheus"
+)
+
+func TestNewHandler(t *testing.T) {
+	for _, f := range []func(*Config){
+		func(c *Config) { c.MetricsType = "raw" },
+		func(c *Config) { c.StoragePolicy = "" },
+		func(c *Config) { c.MetricsType = "unaggregated" },
+		func(c *Config) { c.StoragePolicy = "40d" },
+	} {
+		c := stubConfig("http://localhost:7201")
+		f(c)
+		if _, err := NewHandler(c); err == nil {
+			t.Errorf("Expected error for config %+v\n", c)
+		}
+	}
+}
+
+func TestNewHandlerHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		headers <- req.Header
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]}]}}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		config   func(*Config)
+		expected map[string]string
+	}{
+		{
+			func(*Config) {},
+			map[string]string{
+				"X-Source":              "kafka-kit",
+				metricsTypeHeader:       "aggregated",
+				storagePolicyHeader:     "1m:40d",
+				limitMaxSeriesHeader:    "1000",
+				requireExhaustiveHeader: "true",
+			},
+		},
+		// Unaggregated namespaces are queried without a storage policy.
+		{
+			func(c *Config) { c.MetricsType, c.StoragePolicy = "unaggregated", "" },
+			map[string]string{metricsTypeHeader: "unaggregated", storagePolicyHeader: ""},
+		},
+		// Queries fan out to all namespaces without a metrics type.
+		{
+			func(c *Config) {
+				c.MetricsType, c.StoragePolicy, c.LimitMaxSeries, c.RequireExhaustive = "", "", 0, false
+			},
+			map[string]string{metricsTypeHeader: "", limitMaxSeriesHeader: "", requireExhaustiveHeader: ""},
+		},
+	}
+
+	for _, test := range tests {
+		c := stubConfig(srv.URL)
+		test.config(c)
+
+		if _, err := NewHandler(c); err != nil {
+			t.Fatal(err)
+		}
+
+		h := <-headers
+		for k, v := range test.expected {
+			if h.Get(k) != v {
+				t.Errorf("Expected header %s '%s', got '%s'\n", k, v, h.Get(k))
+			}
+		}
+
+		// The configured headers aren't modified.
+		if len(c.Headers) != 1 {
+			t.Errorf("Unexpected config headers %v\n", c.Headers)
+		}
+	}
+}
+
+func TestGetMetricsLimitExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("query") == "vector(1)" {
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]}]}}`)
+			return
+		}
+
+		// Exhaustive queries exceeding the coordinator limits fail.
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"query exceeded limit: max_fetch_series_limit"}`)
+	}))
+	defer srv.Close()
+
+	h, err := NewHandler(stubConfig(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, errs := h.GetMetrics()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %s\n", len(errs), errs)
+	}
+
+	expected := "bad_data: query exceeded limit: max_fetch_series_limit"
+	if e, ok := errs[0].(*kafkametrics.APIError); !ok || e.Message != expected {
+		t.Errorf("Expected APIError '%s', got %s\n", expected, errs[0])
+	}
+}
+
+func stubConfig(url string) *Config {
+	return &Config{
+		Config: prometheus.Config{
+			URL:               url,
+			Headers:           map[string]string{"X-Source": "kafka-kit"},
+			NetworkTXQuery:    "tx",
+			NetworkRXQuery:    "rx",
+			BrokerIDLabel:     "broker_id",
+			InstanceTypeLabel: "instance_type",
+			MetricsWindow:     120,
+		},
+		MetricsType:       "aggregated",
+		StoragePolicy:     "1m:40d",
+		LimitMaxSeries:    1000,
+		RequireExhaustive: true,
+	}
+}
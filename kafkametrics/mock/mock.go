@@ -0,0 +1,152 @@
+// Package mock provides a scriptable kafkametrics Handler
+// for testing.
+package mock
+
+import (
+	"sync"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Result is a scripted GetMetrics result.
+type Result struct {
+	Metrics kafkametrics.BrokerMetrics
+	Errors  []error
+}
+
+// Handler is a kafkametrics Handler that returns scripted results. Each
+// GetMetrics call returns the next scripted Result; once all results have
+// been returned, the last is repeated. PostEvent records all events and
+// returns the next scripted event error, if any.
+type Handler struct {
+	sync.Mutex
+	results     []Result
+	eventErrors []error
+	calls       int
+	events      []*kafkametrics.Event
+}
+
+// NewHandler returns a *Handler with no scripted results.
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// AddResult scripts a GetMetrics result.
+func (h *Handler) AddResult(bm kafkametrics.BrokerMetrics, errs ...error) *Handler {
+	h.Lock()
+	defer h.Unlock()
+
+	h.results = append(h.results, Result{Metrics: bm, Errors: errs})
+
+	return h
+}
+
+// AddAPIError scripts a GetMetrics result that fails with
+// a *kafkametrics.APIError.
+func (h *Handler) AddAPIError(request, message string) *Handler {
+	return h.AddResult(nil, &kafkametrics.APIError{Request: request, Message: message})
+}
+
+// AddNoResults scripts a GetMetrics result that fails with
+// a *kafkametrics.NoResults.
+func (h *Handler) AddNoResults(message string) *Handler {
+	return h.AddResult(nil, &kafkametrics.NoResults{Message: message})
+}
+
+// AddPartialResults scripts a GetMetrics result that returns the
+// BrokerMetrics along with a *kafkametrics.PartialResults.
+func (h *Handler) AddPartialResults(bm kafkametrics.BrokerMetrics, message string) *Handler {
+	return h.AddResult(bm, &kafkametrics.PartialResults{Message: message})
+}
+
+// AddEventError scripts the error returned by the next PostEvent call
+// without a scripted error. A nil error scripts a successful call.
+func (h *Handler) AddEventError(err error) *Handler {
+	h.Lock()
+	defer h.Unlock()
+
+	h.eventErrors = append(h.eventErrors, err)
+
+	return h
+}
+
+// GetMetrics returns the next scripted Result. A *kafkametrics.NoResults is
+// returned if no results are scripted.
+func (h *Handler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.calls++
+
+	if len(h.results) == 0 {
+		return nil, []error{&kafkametrics.NoResults{Message: "no scripted results"}}
+	}
+
+	r := h.results[0]
+	if len(h.results) > 1 {
+		h.results = h.results[1:]
+	}
+
+	return copyBrokerMetrics(r.Metrics), r.Errors
+}
+
+// PostEvent records the event and returns the next scripted event error.
+func (h *Handler) PostEvent(e *kafkametrics.Event) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.events = append(h.events, e)
+
+	if len(h.eventErrors) == 0 {
+		return nil
+	}
+
+	err := h.eventErrors[0]
+	h.eventErrors = h.eventErrors[1:]
+
+	return err
+}
+
+// Calls returns the number of GetMetrics calls.
+func (h *Handler) Calls() int {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.calls
+}
+
+// Events returns all events posted.
+func (h *Handler) Events() []*kafkametrics.Event {
+	h.Lock()
+	defer h.Unlock()
+
+	return append([]*kafkametrics.Event{}, h.events...)
+}
+
+// copyBrokerMetrics returns a copy of the BrokerMetrics so that callers may
+// modify repeated results.
+func copyBrokerMetrics(bm kafkametrics.BrokerMetrics) kafkametrics.BrokerMetrics {
+	if bm == nil {
+		return nil
+	}
+
+	c := kafkametrics.BrokerMetrics{}
+	for id, b := range bm {
+		cb := *b
+		if b.Metrics != nil {
+			cb.Metrics = map[string]float64{}
+			for k, v := range b.Metrics {
+				cb.Metrics[k] = v
+			}
+		}
+		if b.Series != nil {
+			cb.Series = map[string][]kafkametrics.Point{}
+			for k, v := range b.Series {
+				cb.Series[k] = append([]kafkametrics.Point{}, v...)
+			}
+		}
+		c[id] = &cb
+	}
+
+	return c
+}
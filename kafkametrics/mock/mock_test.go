@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestGetMetrics(t *testing.T) {
+	h := NewHandler()
+
+	// No scripted results.
+	if _, errs := h.GetMetrics(); len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d\n", len(errs))
+	} else if _, ok := errs[0].(*kafkametrics.NoResults); !ok {
+		t.Errorf("Expected *kafkametrics.NoResults, got %T\n", errs[0])
+	}
+
+	bm := kafkametrics.BrokerMetrics{1001: {ID: 1001, NetTX: 10}}
+
+	h.AddAPIError("test", "failed").
+		AddPartialResults(bm, "missing tags").
+		AddResult(bm)
+
+	if _, errs := h.GetMetrics(); len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d\n", len(errs))
+	} else if _, ok := errs[0].(*kafkametrics.APIError); !ok {
+		t.Errorf("Expected *kafkametrics.APIError, got %T\n", errs[0])
+	}
+
+	got, errs := h.GetMetrics()
+	if _, ok := errs[0].(*kafkametrics.PartialResults); !ok || got[1001].NetTX != 10 {
+		t.Errorf("Expected partial results, got %v %v\n", got, errs)
+	}
+
+	// Modifying results doesn't affect repeated results.
+	got[1001].NetTX = 20
+
+	// The last result is repeated.
+	for i := 0; i < 2; i++ {
+		got, errs = h.GetMetrics()
+		if errs != nil || got[1001].NetTX != 10 {
+			t.Errorf("Expected repeated result, got %v %v\n", got, errs)
+		}
+	}
+
+	if h.Calls() != 5 {
+		t.Errorf("Expected 5 calls, got %d\n", h.Calls())
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	h := NewHandler().AddEventError(nil).AddEventError(errors.New("failed"))
+
+	for i, expectErr := range []bool{false, true, false} {
+		err := h.PostEvent(&kafkametrics.Event{Title: "event"})
+		if (err != nil) != expectErr {
+			t.Errorf("[call %d] Expected error %v, got %v\n", i, expectErr, err)
+		}
+	}
+
+	if len(h.Events()) != 3 {
+		t.Errorf("Expected 3 events, got %d\n", len(h.Events()))
+	}
+}
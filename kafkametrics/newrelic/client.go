@@ -0,0 +1,146 @@
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	queryURLFormat  = "https://insights-api%s.newrelic.com/v1/accounts/%s/query"
+	eventsURLFormat = "https://insights-collector%s.newrelic.com/v1/accounts/%s/events"
+)
+
+// queryResponse is an Insights query API response for a faceted query.
+type queryResponse struct {
+	Facets   []facet `json:"facets"`
+	Metadata struct {
+		// Facet is a string for single facet queries and a list of
+		// strings for multi-facet queries.
+		Facet json.RawMessage `json:"facet"`
+	} `json:"metadata"`
+	Error string `json:"error"`
+}
+
+// facet is a single facet result.
+type facet struct {
+	Name    json.RawMessage          `json:"name"`
+	Results []map[string]interface{} `json:"results"`
+}
+
+// stringList decodes a facet name or metadata facet field that may be
+// a string or list of strings.
+func stringList(r json.RawMessage) []string {
+	var l []string
+	if err := json.Unmarshal(r, &l); err == nil {
+		return l
+	}
+
+	var s string
+	if err := json.Unmarshal(r, &s); err == nil {
+		return []string{s}
+	}
+
+	return nil
+}
+
+// attributes returns the result facet attribute names to values.
+func (r *queryResponse) attributes(f facet) map[string]string {
+	attrs := map[string]string{}
+	names := stringList(r.Metadata.Facet)
+	values := stringList(f.Name)
+
+	for i := 0; i < len(names) && i < len(values); i++ {
+		attrs[names[i]] = values[i]
+	}
+
+	return attrs
+}
+
+// value returns the first numeric result value of a facet.
+func (f facet) value() (float64, bool) {
+	for _, res := range f.Results {
+		for _, v := range res {
+			if fv, ok := v.(float64); ok {
+				return fv, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// query runs an NRQL query.
+func (h *nrHandler) query(ctx context.Context, nrql string) (*queryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.queryURL+"?nrql="+url.QueryEscape(nrql), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Query-Key", h.queryKey)
+
+	body, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &queryResponse{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, fmt.Errorf("error parsing query response: %s", err)
+	}
+
+	return r, nil
+}
+
+// insertEvent writes a custom event to the Events API.
+func (h *nrHandler) insertEvent(ctx context.Context, e map[string]interface{}) error {
+	b, err := json.Marshal([]map[string]interface{}{e})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.eventsURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Insert-Key", h.insertKey)
+
+	_, err = h.do(req)
+
+	return err
+}
+
+// do issues a request, returning the response body. Non-2xx responses are
+// returned as errors.
+func (h *nrHandler) do(req *http.Request) ([]byte, error) {
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return nil, fmt.Errorf("%s", e.Error)
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
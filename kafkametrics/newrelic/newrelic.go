@@ -0,0 +1,264 @@
+// Package newrelic implements
+// a kafkametrics Handler.
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// AccountID is the New Relic account ID.
+	AccountID string
+	// QueryKey is an Insights query key, required for metrics queries.
+	QueryKey string
+	// InsertKey is an Insights insert key, required for posting events.
+	InsertKey string
+	// Region is the New Relic datacenter region, either "US" or "EU".
+	// Defaults to "US".
+	Region string
+	// NetworkTXQuery is an NRQL query that should return the outbound network
+	// throughput in bytes/s for the reference Kafka brokers, faceted by the
+	// BrokerIDAttribute (and optionally the InstanceTypeAttribute and
+	// HostAttribute). A SINCE clause for the MetricsWindow is appended.
+	// Example: "SELECT average(transmitBytesPerSecond) FROM NetworkSample WHERE role = 'kafka' FACET broker_id, instanceType, hostname"
+	NetworkTXQuery string
+	// NetworkRXQuery is the inbound network equivalent of NetworkTXQuery.
+	NetworkRXQuery string
+	// BrokerIDAttribute is the facet attribute name for Kafka broker IDs.
+	BrokerIDAttribute string
+	// InstanceTypeAttribute is the facet attribute name for the Kafka
+	// broker's instance type.
+	InstanceTypeAttribute string
+	// HostAttribute is the facet attribute name for the Kafka broker's
+	// hostname. Defaults to "hostname".
+	HostAttribute string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds.
+	MetricsWindow int
+	// EventType is the custom event type name used for events. Defaults to
+	// "KafkaKitEvent".
+	EventType string
+}
+
+type nrHandler struct {
+	c                     *http.Client
+	queryURL              string
+	eventsURL             string
+	queryKey              string
+	insertKey             string
+	queries               []string
+	brokerIDAttribute     string
+	instanceTypeAttribute string
+	hostAttribute         string
+	eventType             string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	var region string
+
+	switch strings.ToUpper(c.Region) {
+	case "", "US":
+	case "EU":
+		region = ".eu"
+	default:
+		return nil, fmt.Errorf("unknown region %s", c.Region)
+	}
+
+	switch {
+	case c.AccountID == "" || c.QueryKey == "":
+		return nil, errors.New("account ID and query key must be specified")
+	case c.NetworkTXQuery == "", c.NetworkRXQuery == "":
+		return nil, errors.New("network tx and rx queries must be specified")
+	case c.BrokerIDAttribute == "":
+		return nil, errors.New("broker ID attribute must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	since := fmt.Sprintf(" SINCE %d seconds ago", c.MetricsWindow)
+
+	h := &nrHandler{
+		c:                     &http.Client{Timeout: 30 * time.Second},
+		queryURL:              fmt.Sprintf(queryURLFormat, region, c.AccountID),
+		eventsURL:             fmt.Sprintf(eventsURLFormat, region, c.AccountID),
+		queryKey:              c.QueryKey,
+		insertKey:             c.InsertKey,
+		queries:               []string{c.NetworkTXQuery + since, c.NetworkRXQuery + since},
+		brokerIDAttribute:     c.BrokerIDAttribute,
+		instanceTypeAttribute: c.InstanceTypeAttribute,
+		hostAttribute:         "hostname",
+		eventType:             "KafkaKitEvent",
+	}
+
+	if c.HostAttribute != "" {
+		h.hostAttribute = c.HostAttribute
+	}
+
+	if c.EventType != "" {
+		h.eventType = c.EventType
+	}
+
+	// Validate.
+	if _, err := h.query(context.Background(), "SELECT count(*) FROM "+h.eventType+" SINCE 1 minute ago"); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent posts an event as a custom event to the Events API. If no insert
+// key is configured, the event is discarded.
+func (h *nrHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *nrHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	if h.insertKey == "" {
+		return nil
+	}
+
+	event := map[string]interface{}{
+		"eventType": h.eventType,
+		"title":     e.Title,
+		"text":      e.Text,
+		"tags":      strings.Join(e.Tags, ","),
+	}
+
+	if err := h.insertEvent(ctx, event); err != nil {
+		return &kafkametrics.APIError{
+			Request: "insert event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the New Relic API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *nrHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *nrHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, query := range h.queries {
+		r, err := h.query(ctx, query)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "nrql query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(r.Facets) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with query %s", query),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, r, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of facets.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and a *queryResponse and
+// populates the BrokerMetrics with the facet values for the metric type. The
+// number of brokers populated is returned along with any errors.
+func (h *nrHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, r *queryResponse, metricType int) (int, []error) {
+	var errors []error
+	var missingAttrs bytes.Buffer
+	var n int
+
+	for _, f := range r.Facets {
+		attrs := r.attributes(f)
+		host := attrs[h.hostAttribute]
+
+		id, err := strconv.Atoi(attrs[h.brokerIDAttribute])
+		if err != nil {
+			missingAttrs.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDAttribute, host))
+			continue
+		}
+
+		var it string
+		if h.instanceTypeAttribute != "" {
+			if it = attrs[h.instanceTypeAttribute]; it == "" {
+				missingAttrs.WriteString(fmt.Sprintf(" %s:%s", h.instanceTypeAttribute, host))
+				continue
+			}
+		}
+
+		v, ok := f.value()
+		if !ok {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", host),
+			})
+			continue
+		}
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         host,
+				InstanceType: it,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = v / 1024 / 1024
+		case 1:
+			b.NetRX = v / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingAttrs.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing facet attributes:%s", missingAttrs.String()),
+		})
+	}
+
+	return n, errors
+}
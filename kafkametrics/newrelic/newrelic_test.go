@@ -0,0 +1,163 @@
+package newrelic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestQuery(t *testing.T) {
+	nrql := "SELECT average(net.transmitBytesPerSecond) FROM NetworkSample FACET broker_id, hostname"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("nrql") != nrql || req.Header.Get("X-Query-Key") != "key" {
+			t.Errorf("Unexpected request %s %v\n", req.URL, req.Header)
+		}
+
+		fmt.Fprint(w, `{"facets":[{"name":["1000","kafka-0"],"results":[{"average":1048576}]}],`+
+			`"metadata":{"facet":["broker_id","hostname"],"contents":[{"function":"average"}]}}`)
+	}))
+	defer srv.Close()
+
+	h := &nrHandler{c: srv.Client(), queryURL: srv.URL, queryKey: "key"}
+
+	r, err := h.query(context.Background(), nrql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Facets) != 1 {
+		t.Fatalf("Expected 1 facet, got %d\n", len(r.Facets))
+	}
+
+	if attrs := r.attributes(r.Facets[0]); attrs["broker_id"] != "1000" || attrs["hostname"] != "kafka-0" {
+		t.Errorf("Unexpected attributes %v\n", attrs)
+	}
+
+	if v, ok := r.Facets[0].value(); !ok || v != 1048576 {
+		t.Errorf("Unexpected value %f\n", v)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// Insights errors are returned as is.
+		{http.StatusForbidden, `{"error":"Invalid query key"}`, "Invalid query key"},
+		{http.StatusBadRequest, `{"error":"NRQL Syntax Error: Error at line 1 position 1"}`, "NRQL Syntax Error: Error at line 1 position 1"},
+		{http.StatusServiceUnavailable, "Service Unavailable\n", "unexpected status 503: Service Unavailable"},
+		{http.StatusOK, "<html></html>", "error parsing query response: invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &nrHandler{c: srv.Client(), queryURL: srv.URL}
+
+		if _, err := h.query(context.Background(), "SELECT 1"); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &nrHandler{brokerIDAttribute: "broker_id", instanceTypeAttribute: "instance_type", hostAttribute: "hostname"}
+
+	r := &queryResponse{}
+	json.Unmarshal([]byte(`{
+		"facets":[
+			{"name":["1000","kafka-0","stub"],"results":[{"average":2097152}]},
+			{"name":["1001","kafka-1","stub"],"results":[{"average":null}]},
+			{"name":["1002","kafka-2"],"results":[{"average":1048576}]},
+			{"name":["Other","kafka-9","stub"],"results":[{"average":1048576}]}
+		],
+		"metadata":{"facet":["broker_id","hostname","instance_type"]}
+	}`), r)
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, r, 0)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetTX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-1",
+		"Missing facet attributes: instance_type:kafka-2 broker_id:kafka-9",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Insert-Key") != "key" {
+			t.Errorf("Unexpected insert key %s\n", req.Header.Get("X-Insert-Key"))
+		}
+
+		var e []map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&e)
+		events <- e[0]
+	}))
+	defer srv.Close()
+
+	h := &nrHandler{c: srv.Client(), eventsURL: srv.URL, eventType: "KafkaKitEvent"}
+
+	e := &kafkametrics.Event{Title: "title", Text: "text", Tags: []string{"a:b", "c:d"}}
+
+	// Events are discarded without an insert key.
+	if err := h.PostEvent(e); err != nil || len(events) != 0 {
+		t.Fatalf("Expected discarded event, got %v\n", err)
+	}
+
+	h.insertKey = "key"
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev := <-events; ev["eventType"] != "KafkaKitEvent" || ev["title"] != "title" || ev["tags"] != "a:b,c:d" {
+		t.Errorf("Unexpected event %v\n", ev)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	r := &queryResponse{}
+	r.Metadata.Facet = json.RawMessage(`"broker_id"`)
+
+	attrs := r.attributes(facet{Name: json.RawMessage(`"1001"`)})
+	if attrs["broker_id"] != "1001" {
+		t.Errorf("Unexpected attributes %v\n", attrs)
+	}
+
+	// Multi-facet queries name a value per attribute.
+	r.Metadata.Facet = json.RawMessage(`["broker_id","hostname"]`)
+
+	attrs = r.attributes(facet{Name: json.RawMessage(`["1001"]`)})
+	if len(attrs) != 1 || attrs["broker_id"] != "1001" {
+		t.Errorf("Unexpected attributes %v\n", attrs)
+	}
+}
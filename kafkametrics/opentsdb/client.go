@@ -0,0 +1,140 @@
+package opentsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// queryRequest is an /api/query request body.
+type queryRequest struct {
+	Start   string     `json:"start"`
+	Queries []subQuery `json:"queries"`
+}
+
+// subQuery is a single metric query.
+type subQuery struct {
+	Aggregator  string       `json:"aggregator"`
+	Metric      string       `json:"metric"`
+	Rate        bool         `json:"rate,omitempty"`
+	RateOptions *rateOptions `json:"rateOptions,omitempty"`
+	Downsample  string       `json:"downsample,omitempty"`
+	Filters     []filter     `json:"filters"`
+}
+
+// rateOptions are subQuery rate options.
+type rateOptions struct {
+	Counter    bool `json:"counter"`
+	DropResets bool `json:"dropResets"`
+}
+
+// filter is a subQuery tag filter.
+type filter struct {
+	Type    string `json:"type"`
+	Tagk    string `json:"tagk"`
+	Filter  string `json:"filter"`
+	GroupBy bool   `json:"groupBy"`
+}
+
+// series is an /api/query response series.
+type series struct {
+	Metric string             `json:"metric"`
+	Tags   map[string]string  `json:"tags"`
+	DPS    map[string]float64 `json:"dps"`
+}
+
+// annotation is an /api/annotation request body.
+type annotation struct {
+	StartTime   int64             `json:"startTime"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	Custom      map[string]string `json:"custom,omitempty"`
+}
+
+// query runs a query, returning the result series.
+func (h *tsdbHandler) query(ctx context.Context, q *queryRequest) ([]series, error) {
+	body, err := h.post(ctx, "/api/query", q)
+	if err != nil {
+		return nil, err
+	}
+
+	var s []series
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, fmt.Errorf("error parsing query response: %s", err)
+	}
+
+	return s, nil
+}
+
+// postAnnotation writes a global annotation.
+func (h *tsdbHandler) postAnnotation(ctx context.Context, a *annotation) error {
+	_, err := h.post(ctx, "/api/annotation", a)
+	return err
+}
+
+// version requests the server version.
+func (h *tsdbHandler) version() error {
+	req, err := http.NewRequest(http.MethodGet, h.url+"/api/version", nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.do(req)
+
+	return err
+}
+
+// post issues a JSON POST request to the API path, returning the
+// response body.
+func (h *tsdbHandler) post(ctx context.Context, path string, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return h.do(req)
+}
+
+// do issues a request, returning the response body. Non-2xx responses are
+// returned as errors.
+func (h *tsdbHandler) do(req *http.Request) ([]byte, error) {
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+			return nil, fmt.Errorf("%s", e.Error.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,297 @@
+// Package opentsdb implements
+// a kafkametrics Handler.
+package opentsdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the OpenTSDB server address.
+	// Example: "http://opentsdb:4242"
+	URL string
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// NetworkTXMetric is the outbound network throughput metric for the
+	// reference Kafka brokers.
+	// Example: "kafka.server.BrokerTopicMetrics.BytesOutPerSec"
+	NetworkTXMetric string
+	// NetworkRXMetric is the inbound network equivalent of NetworkTXMetric.
+	NetworkRXMetric string
+	// Filters are literal tag filters applied to both metrics.
+	// Example: {"cluster": "my-cluster"}
+	Filters map[string]string
+	// Aggregator is the aggregation function applied to series with the
+	// same broker ID. Defaults to "sum".
+	Aggregator string
+	// Rate should be set for metrics that are monotonically increasing
+	// counters rather than rates in bytes/s.
+	Rate bool
+	// Downsample is an optional downsampling specifier.
+	// Example: "1m-avg"
+	Downsample string
+	// BrokerIDTag is the tag name for Kafka broker IDs.
+	BrokerIDTag string
+	// InstanceTypeTag is the tag name for the Kafka broker's instance type.
+	InstanceTypeTag string
+	// HostTag is the tag name for the Kafka broker's hostname. Defaults
+	// to "host".
+	HostTag string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+}
+
+type tsdbHandler struct {
+	c               *http.Client
+	url             string
+	username        string
+	password        string
+	queries         []*queryRequest
+	brokerIDTag     string
+	instanceTypeTag string
+	hostTag         string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "":
+		return nil, errors.New("opentsdb URL must be specified")
+	case c.NetworkTXMetric == "", c.NetworkRXMetric == "":
+		return nil, errors.New("network tx and rx metrics must be specified")
+	case c.BrokerIDTag == "":
+		return nil, errors.New("broker ID tag must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	h := &tsdbHandler{
+		c:               &http.Client{Timeout: 30 * time.Second},
+		url:             strings.TrimSuffix(c.URL, "/"),
+		username:        c.Username,
+		password:        c.Password,
+		brokerIDTag:     c.BrokerIDTag,
+		instanceTypeTag: c.InstanceTypeTag,
+		hostTag:         "host",
+	}
+
+	if c.HostTag != "" {
+		h.hostTag = c.HostTag
+	}
+
+	aggregator := "sum"
+	if c.Aggregator != "" {
+		aggregator = c.Aggregator
+	}
+
+	for _, metric := range []string{c.NetworkTXMetric, c.NetworkRXMetric} {
+		h.queries = append(h.queries, &queryRequest{
+			Start:   fmt.Sprintf("%ds-ago", c.MetricsWindow),
+			Queries: []subQuery{h.subQuery(c, metric, aggregator)},
+		})
+	}
+
+	// Validate.
+	if err := h.version(); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate connectivity",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// subQuery returns a subQuery for the metric that is grouped by broker ID and
+// instance type.
+func (h *tsdbHandler) subQuery(c *Config, metric, aggregator string) subQuery {
+	q := subQuery{
+		Aggregator: aggregator,
+		Metric:     metric,
+		Downsample: c.Downsample,
+		Filters: []filter{
+			{Type: "wildcard", Tagk: h.brokerIDTag, Filter: "*", GroupBy: true},
+		},
+	}
+
+	if c.Rate {
+		q.Rate = true
+		q.RateOptions = &rateOptions{Counter: true, DropResets: true}
+	}
+
+	if h.instanceTypeTag != "" {
+		q.Filters = append(q.Filters, filter{
+			Type: "wildcard", Tagk: h.instanceTypeTag, Filter: "*", GroupBy: true,
+		})
+	}
+
+	// Sort for a consistent query.
+	var tags []string
+	for k := range c.Filters {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+
+	for _, k := range tags {
+		q.Filters = append(q.Filters, filter{
+			Type: "literal_or", Tagk: k, Filter: c.Filters[k],
+		})
+	}
+
+	return q
+}
+
+// PostEvent writes an event as a global annotation. Event tags in key:value
+// form are written as custom annotation fields.
+func (h *tsdbHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *tsdbHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	custom := map[string]string{}
+	for _, t := range e.Tags {
+		if kv := strings.SplitN(t, ":", 2); len(kv) == 2 {
+			custom[kv[0]] = kv[1]
+		}
+	}
+
+	a := &annotation{
+		StartTime:   time.Now().Unix(),
+		Description: e.Title,
+		Notes:       e.Text,
+		Custom:      custom,
+	}
+
+	if err := h.postAnnotation(ctx, a); err != nil {
+		return &kafkametrics.APIError{
+			Request: "post annotation",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the OpenTSDB API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *tsdbHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *tsdbHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, q := range h.queries {
+		s, err := h.query(ctx, q)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(s) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned for metric %s", q.Queries[0].Metric),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, s, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and []series and populates the
+// BrokerMetrics with the window average for the metric type. The number of
+// brokers populated is returned along with any errors.
+func (h *tsdbHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, s []series, metricType int) (int, []error) {
+	var errors []error
+	var missingTags bytes.Buffer
+	var n int
+
+	for _, ser := range s {
+		host := ser.Tags[h.hostTag]
+
+		id, err := strconv.Atoi(ser.Tags[h.brokerIDTag])
+		if err != nil {
+			missingTags.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDTag, host))
+			continue
+		}
+
+		if len(ser.DPS) == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for broker %d", id),
+			})
+			continue
+		}
+
+		var sum float64
+		for _, v := range ser.DPS {
+			sum += v
+		}
+		avg := sum / float64(len(ser.DPS))
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         host,
+				InstanceType: ser.Tags[h.instanceTypeTag],
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingTags.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing tags:%s", missingTags.String()),
+		})
+	}
+
+	return n, errors
+}
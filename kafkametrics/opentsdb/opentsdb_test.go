@@ -0,0 +1,163 @@
+package opentsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := &queryRequest{}
+		json.NewDecoder(req.Body).Decode(q)
+
+		if req.URL.Path != "/api/query" || q.Start != "120s-ago" || q.Queries[0].Metric != "kafka.net.bytes_out" {
+			t.Errorf("Unexpected query %s %+v\n", req.URL.Path, q)
+		}
+
+		if u, p, _ := req.BasicAuth(); u != "user" || p != "pass" {
+			t.Errorf("Unexpected credentials %s:%s\n", u, p)
+		}
+
+		fmt.Fprint(w, `[{"metric":"kafka.net.bytes_out","tags":{"broker_id":"1000","host":"kafka-0"},`+
+			`"aggregateTags":["partition"],"dps":{"1600000000":1048576,"1600000060":3145728}}]`)
+	}))
+	defer srv.Close()
+
+	h := &tsdbHandler{c: srv.Client(), url: srv.URL, username: "user", password: "pass"}
+
+	s, err := h.query(context.Background(), &queryRequest{
+		Start:   "120s-ago",
+		Queries: []subQuery{{Metric: "kafka.net.bytes_out"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s) != 1 || s[0].Tags["broker_id"] != "1000" || s[0].DPS["1600000060"] != 3145728 {
+		t.Errorf("Unexpected series %+v\n", s)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// OpenTSDB error messages are returned as is.
+		{
+			http.StatusBadRequest,
+			`{"error":{"code":400,"message":"No such name for 'metrics': 'unknown'","details":"..."}}`,
+			"No such name for 'metrics': 'unknown'",
+		},
+		{http.StatusUnauthorized, "Unauthorized\n", "unexpected status 401: Unauthorized"},
+		{http.StatusBadGateway, "<html>Bad Gateway</html>", "unexpected status 502: <html>Bad Gateway</html>"},
+		{http.StatusOK, `{"error":{}}`, "error parsing query response: json: cannot unmarshal object into Go value of type []opentsdb.series"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &tsdbHandler{c: srv.Client(), url: srv.URL}
+
+		if _, err := h.query(context.Background(), &queryRequest{}); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &tsdbHandler{brokerIDTag: "broker_id", instanceTypeTag: "instance_type", hostTag: "hostname"}
+
+	s := []series{
+		{
+			Tags: map[string]string{"broker_id": "1000", "hostname": "kafka-0", "instance_type": "stub"},
+			DPS:  map[string]float64{"1600000000": 1048576, "1600000060": 3145728},
+		},
+		{Tags: map[string]string{"broker_id": "1001", "hostname": "kafka-1"}},
+		{
+			Tags: map[string]string{"hostname": "kafka-9"},
+			DPS:  map[string]float64{"1600000000": 1048576},
+		},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, s, 1)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetRX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{"No points for broker 1001", "Missing tags: broker_id:kafka-9"}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestSubQuery(t *testing.T) {
+	h := &tsdbHandler{brokerIDTag: "broker_id", instanceTypeTag: "instance_type"}
+	c := &Config{Rate: true, Filters: map[string]string{"cluster": "a|b"}}
+
+	q := h.subQuery(c, "metric", "sum")
+
+	if !q.Rate || q.RateOptions == nil || !q.RateOptions.Counter {
+		t.Errorf("Expected counter rate options")
+	}
+
+	if len(q.Filters) != 3 {
+		t.Fatalf("Expected 3 filters, got %d\n", len(q.Filters))
+	}
+
+	for i, tagk := range []string{"broker_id", "instance_type", "cluster"} {
+		f := q.Filters[i]
+		if f.Tagk != tagk || f.GroupBy != (i < 2) {
+			t.Errorf("Unexpected filter %+v\n", f)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	annotations := make(chan *annotation, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		a := &annotation{}
+		json.NewDecoder(req.Body).Decode(a)
+		annotations <- a
+	}))
+	defer srv.Close()
+
+	h := &tsdbHandler{c: srv.Client(), url: srv.URL}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle", "untagged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags are written as custom fields.
+	a := <-annotations
+	if a.Description != "title" || a.Notes != "text" || len(a.Custom) != 1 || a.Custom["name"] != "kafka-autothrottle" {
+		t.Errorf("Unexpected annotation %+v\n", a)
+	}
+}
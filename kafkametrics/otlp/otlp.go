@@ -0,0 +1,189 @@
+// Package otlp implements
+// a kafkametrics Handler.
+//
+// The Handler wraps another kafkametrics Handler and exports fetched broker
+// metrics and posted events to an OpenTelemetry collector using OTLP/HTTP
+// with JSON encoding.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Endpoint is the OTLP/HTTP endpoint base URL; data is sent to the
+	// /v1/metrics and /v1/logs paths.
+	// Example: "http://otel-collector:4318"
+	Endpoint string
+	// Headers are additional request headers, such as authentication
+	// headers required by the collector.
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute. Defaults to
+	// "kafka-kit".
+	ServiceName string
+	// ResourceAttributes are additional resource attributes.
+	ResourceAttributes map[string]string
+	// Signals is the list of signals exported: "metrics" for fetched broker
+	// metrics and "logs" for posted events. Defaults to both.
+	Signals []string
+	// Timeout is the export request timeout in seconds. Defaults to 10.
+	Timeout int
+	// ErrorHandler is called with errors exporting metrics, which otherwise
+	// aren't surfaced by GetMetrics. Optional.
+	ErrorHandler func(error) `json:"-"`
+}
+
+type otlpHandler struct {
+	kafkametrics.Handler
+	c             *http.Client
+	endpoint      string
+	headers       map[string]string
+	resource      resource
+	exportMetrics bool
+	exportLogs    bool
+	errorHandler  func(error)
+}
+
+// NewHandler takes a *Config and the kafkametrics Handler to wrap and
+// returns a Handler, along with any configuration errors.
+func NewHandler(c *Config, h kafkametrics.Handler) (kafkametrics.Handler, error) {
+	switch {
+	case c.Endpoint == "":
+		return nil, errors.New("OTLP endpoint must be specified")
+	case h == nil:
+		return nil, errors.New("a kafkametrics Handler must be specified")
+	}
+
+	oh := &otlpHandler{
+		Handler:      h,
+		c:            &http.Client{Timeout: 10 * time.Second},
+		endpoint:     strings.TrimSuffix(c.Endpoint, "/"),
+		headers:      c.Headers,
+		errorHandler: c.ErrorHandler,
+	}
+
+	if c.Timeout > 0 {
+		oh.c.Timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	signals := c.Signals
+	if len(signals) == 0 {
+		signals = []string{"metrics", "logs"}
+	}
+
+	for _, s := range signals {
+		switch s {
+		case "metrics":
+			oh.exportMetrics = true
+		case "logs":
+			oh.exportLogs = true
+		default:
+			return nil, fmt.Errorf("unknown OTLP signal: %s", s)
+		}
+	}
+
+	serviceName := "kafka-kit"
+	if c.ServiceName != "" {
+		serviceName = c.ServiceName
+	}
+
+	oh.resource.Attributes = append(oh.resource.Attributes, stringAttribute("service.name", serviceName))
+	for _, k := range sortedKeys(c.ResourceAttributes) {
+		oh.resource.Attributes = append(oh.resource.Attributes, stringAttribute(k, c.ResourceAttributes[k]))
+	}
+
+	return oh, nil
+}
+
+// GetMetrics fetches metrics from the wrapped Handler and exports any
+// returned broker metrics. Export errors are passed to the configured
+// ErrorHandler.
+func (h *otlpHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. The
+// context is passed through to the wrapped Handler.
+func (h *otlpHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	bm, errs := kafkametrics.GetMetricsContext(ctx, h.Handler)
+
+	if h.exportMetrics && len(bm) > 0 {
+		if err := h.post(ctx, "/v1/metrics", h.metricsRequest(bm, time.Now())); err != nil && h.errorHandler != nil {
+			h.errorHandler(&kafkametrics.APIError{
+				Request: "export metrics",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return bm, errs
+}
+
+// PostEvent posts the event to the wrapped Handler and exports it as
+// a log record.
+func (h *otlpHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The context
+// is passed through to the wrapped Handler.
+func (h *otlpHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	err := kafkametrics.PostEventContext(ctx, h.Handler, e)
+
+	if h.exportLogs {
+		if lerr := h.post(ctx, "/v1/logs", h.logsRequest(e, time.Now())); lerr != nil {
+			lerr = &kafkametrics.APIError{
+				Request: "export event",
+				Message: lerr.Error(),
+			}
+			if err == nil {
+				return lerr
+			}
+			return fmt.Errorf("%s; %s", err, lerr)
+		}
+	}
+
+	return err
+}
+
+// post sends an OTLP request to the path.
+func (h *otlpHandler) post(ctx context.Context, path string, r interface{}) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		d, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(d))
+	}
+
+	return nil
+}
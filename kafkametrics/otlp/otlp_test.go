@@ -0,0 +1,214 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/mock"
+)
+
+func TestMetricsRequest(t *testing.T) {
+	h := &otlpHandler{resource: resource{Attributes: []attribute{stringAttribute("service.name", "autothrottle")}}}
+
+	bm := kafkametrics.BrokerMetrics{
+		1001: {ID: 1001, Host: "host1", InstanceType: "stub", NetTX: 3.00, NetRX: 4.00},
+		1000: {ID: 1000, Host: "host0", InstanceType: "stub", NetTX: 1.00, NetRX: 2.00},
+	}
+
+	b, _ := json.Marshal(h.metricsRequest(bm, time.Unix(1, 0)))
+
+	// Data points are ordered by broker ID and int64 values are encoded as
+	// JSON strings.
+	expected := `{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"autothrottle"}}]},` +
+		`"scopeMetrics":[{"scope":{"name":"` + scopeName + `"},"metrics":[` +
+		`{"name":"kafka.broker.network.tx","description":"Kafka broker outbound network throughput, window average.","unit":"MiBy/s","gauge":{"dataPoints":[` +
+		`{"attributes":[{"key":"kafka.broker.id","value":{"intValue":"1000"}},{"key":"host.name","value":{"stringValue":"host0"}},` +
+		`{"key":"kafka.broker.instance_type","value":{"stringValue":"stub"}}],"timeUnixNano":"1000000000","asDouble":1},` +
+		`{"attributes":[{"key":"kafka.broker.id","value":{"intValue":"1001"}},{"key":"host.name","value":{"stringValue":"host1"}},` +
+		`{"key":"kafka.broker.instance_type","value":{"stringValue":"stub"}}],"timeUnixNano":"1000000000","asDouble":3}]}},` +
+		`{"name":"kafka.broker.network.rx","description":"Kafka broker inbound network throughput, window average.","unit":"MiBy/s","gauge":{"dataPoints":[` +
+		`{"attributes":[{"key":"kafka.broker.id","value":{"intValue":"1000"}},{"key":"host.name","value":{"stringValue":"host0"}},` +
+		`{"key":"kafka.broker.instance_type","value":{"stringValue":"stub"}}],"timeUnixNano":"1000000000","asDouble":2},` +
+		`{"attributes":[{"key":"kafka.broker.id","value":{"intValue":"1001"}},{"key":"host.name","value":{"stringValue":"host1"}},` +
+		`{"key":"kafka.broker.instance_type","value":{"stringValue":"stub"}}],"timeUnixNano":"1000000000","asDouble":4}]}}]}]}]}`
+
+	if string(b) != expected {
+		t.Errorf("Expected request:\n%s\ngot:\n%s\n", expected, b)
+	}
+}
+
+func TestLogsRequest(t *testing.T) {
+	h := &otlpHandler{}
+
+	r := h.logsRequest(&kafkametrics.Event{Title: "throttle set", Text: "text", Tags: []string{"name:kafka-autothrottle"}}, time.Unix(1, 0))
+
+	rec := r.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if *rec.Body.StringValue != "text" || rec.SeverityText != "INFO" || rec.TimeUnixNano != "1000000000" {
+		t.Errorf("Unexpected log record %+v\n", rec)
+	}
+
+	if a := rec.Attributes[0]; a.Key != "title" || *a.Value.StringValue != "throttle set" {
+		t.Errorf("Unexpected title attribute %+v\n", a)
+	}
+
+	if tags := rec.Attributes[1].Value.ArrayValue.Values; len(tags) != 1 || *tags[0].StringValue != "name:kafka-autothrottle" {
+		t.Errorf("Unexpected tags %+v\n", tags)
+	}
+
+	// Events without tags have an empty tags array.
+	r = h.logsRequest(&kafkametrics.Event{Title: "throttle set"}, time.Unix(1, 0))
+
+	b, _ := json.Marshal(r.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Attributes[1])
+	if string(b) != `{"key":"tags","value":{"arrayValue":{"values":[]}}}` {
+		t.Errorf("Unexpected tags attribute %s\n", b)
+	}
+}
+
+func TestPostErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		{http.StatusUnauthorized, `{"code":16,"message":"invalid api key"}`, `unexpected status 401: {"code":16,"message":"invalid api key"}`},
+		{http.StatusServiceUnavailable, "unavailable\n", "unexpected status 503: unavailable"},
+		// Partial success responses are accepted.
+		{http.StatusOK, `{"partialSuccess":{"rejectedDataPoints":1}}`, ""},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Content-Type") != "application/json" || req.Header.Get("X-Api-Key") != "key" {
+				t.Errorf("Unexpected headers %v\n", req.Header)
+			}
+
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &otlpHandler{c: srv.Client(), endpoint: srv.URL, headers: map[string]string{"X-Api-Key": "key"}}
+
+		err := h.post(context.Background(), "/v1/metrics", &metricsRequest{})
+		if (test.expected == "" && err != nil) || (test.expected != "" && (err == nil || err.Error() != test.expected)) {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	paths := make(chan string, 10)
+	status := http.StatusOK
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s := status
+		paths <- req.URL.Path
+		w.WriteHeader(s)
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	var exportErr error
+	bm := kafkametrics.BrokerMetrics{1000: {ID: 1000, Host: "host0", InstanceType: "stub", NetTX: 1.00, NetRX: 2.00}}
+	m := mock.NewHandler().
+		AddPartialResults(bm, "No points for host host1").
+		AddNoResults("No data returned with query rx").
+		AddResult(bm)
+
+	h, err := NewHandler(&Config{Endpoint: srv.URL, ErrorHandler: func(err error) { exportErr = err }}, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wrapped Handler errors are returned and the brokers returned are
+	// exported.
+	if got, errs := h.GetMetrics(); len(got) != 1 || len(errs) != 1 || errs[0].Error() != "No points for host host1" {
+		t.Fatalf("Unexpected results %v, %s\n", got, errs)
+	}
+
+	if p := <-paths; p != "/v1/metrics" {
+		t.Errorf("Unexpected path %s\n", p)
+	}
+
+	// Nothing is exported without brokers.
+	h.GetMetrics()
+
+	if len(paths) != 0 {
+		t.Errorf("Expected 0 export requests, got %d\n", len(paths))
+	}
+
+	// Export errors are passed to the ErrorHandler rather than returned.
+	status = http.StatusServiceUnavailable
+
+	if _, errs := h.GetMetrics(); errs != nil {
+		t.Errorf("Unexpected errors %s\n", errs)
+	}
+
+	<-paths
+
+	if e, ok := exportErr.(*kafkametrics.APIError); !ok || e.Request != "export metrics" || e.Message != "unexpected status 503: {}" {
+		t.Errorf("Unexpected export error %v\n", exportErr)
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	bodies := make(chan []byte, 10)
+	status := http.StatusOK
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s := status
+		body, _ := io.ReadAll(req.Body)
+		bodies <- body
+		w.WriteHeader(s)
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	backendErr := errors.New("backend error")
+	m := mock.NewHandler().AddEventError(backendErr).AddEventError(backendErr)
+
+	h, err := NewHandler(&Config{Endpoint: srv.URL, Signals: []string{"logs"}}, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &kafkametrics.Event{Title: "throttle set", Text: "text"}
+
+	// The wrapped Handler error is returned; the event is still exported.
+	if err := h.PostEvent(e); err == nil || err.Error() != "backend error" {
+		t.Errorf("Expected backend error, got %v\n", err)
+	}
+
+	var lr logsRequest
+	if err := json.Unmarshal(<-bodies, &lr); err != nil || len(m.Events()) != 1 {
+		t.Fatalf("Expected event posted and exported: %v\n", err)
+	}
+
+	// Both errors are returned.
+	status = http.StatusInternalServerError
+
+	expected := "backend error; API error [export event]: unexpected status 500: {}"
+	if err := h.PostEvent(e); err == nil || err.Error() != expected {
+		t.Errorf("Expected error '%s', got %v\n", expected, err)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	for _, c := range []*Config{
+		{},
+		{Endpoint: "http://localhost:4318", Signals: []string{"traces"}},
+	} {
+		if _, err := NewHandler(c, mock.NewHandler()); err == nil {
+			t.Errorf("Expected error for config %+v\n", c)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package otlp
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// The OTLP/HTTP JSON protobuf encoding. 64 bit integers are encoded
+// as strings.
+
+const (
+	scopeName = "github.com/DataDog/kafka-kit/kafkametrics"
+	// severityNumberInfo is the INFO log severity number.
+	severityNumberInfo = 9
+)
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value value  `json:"value"`
+}
+
+type value struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	ArrayValue  *arrayValue `json:"arrayValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []value `json:"values"`
+}
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Gauge       gauge  `json:"gauge"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type dataPoint struct {
+	Attributes   []attribute `json:"attributes"`
+	TimeUnixNano string      `json:"timeUnixNano"`
+	AsDouble     float64     `json:"asDouble"`
+}
+
+type logsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string      `json:"timeUnixNano"`
+	ObservedTimeUnixNano string      `json:"observedTimeUnixNano"`
+	SeverityNumber       int         `json:"severityNumber"`
+	SeverityText         string      `json:"severityText"`
+	Body                 value       `json:"body"`
+	Attributes           []attribute `json:"attributes"`
+}
+
+// metricsRequest returns an export request with network tx and rx gauges
+// for each broker. Values are in MB/s.
+func (h *otlpHandler) metricsRequest(bm kafkametrics.BrokerMetrics, t time.Time) *metricsRequest {
+	ts := strconv.FormatInt(t.UnixNano(), 10)
+
+	tx := metric{
+		Name:        "kafka.broker.network.tx",
+		Description: "Kafka broker outbound network throughput, window average.",
+		Unit:        "MiBy/s",
+	}
+	rx := metric{
+		Name:        "kafka.broker.network.rx",
+		Description: "Kafka broker inbound network throughput, window average.",
+		Unit:        "MiBy/s",
+	}
+
+	var ids []int
+	for id := range bm {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		b := bm[id]
+		attrs := []attribute{
+			intAttribute("kafka.broker.id", int64(b.ID)),
+			stringAttribute("host.name", b.Host),
+			stringAttribute("kafka.broker.instance_type", b.InstanceType),
+		}
+
+		tx.Gauge.DataPoints = append(tx.Gauge.DataPoints, dataPoint{Attributes: attrs, TimeUnixNano: ts, AsDouble: b.NetTX})
+		rx.Gauge.DataPoints = append(rx.Gauge.DataPoints, dataPoint{Attributes: attrs, TimeUnixNano: ts, AsDouble: b.NetRX})
+	}
+
+	return &metricsRequest{
+		ResourceMetrics: []resourceMetrics{{
+			Resource: h.resource,
+			ScopeMetrics: []scopeMetrics{{
+				Scope:   scope{Name: scopeName},
+				Metrics: []metric{tx, rx},
+			}},
+		}},
+	}
+}
+
+// logsRequest returns an export request with the event as a log record.
+// The event text is the log body; the title and tags are attributes.
+func (h *otlpHandler) logsRequest(e *kafkametrics.Event, t time.Time) *logsRequest {
+	ts := strconv.FormatInt(t.UnixNano(), 10)
+
+	tags := &arrayValue{Values: []value{}}
+	for _, tag := range e.Tags {
+		tag := tag
+		tags.Values = append(tags.Values, value{StringValue: &tag})
+	}
+
+	text := e.Text
+
+	return &logsRequest{
+		ResourceLogs: []resourceLogs{{
+			Resource: h.resource,
+			ScopeLogs: []scopeLogs{{
+				Scope: scope{Name: scopeName},
+				LogRecords: []logRecord{{
+					TimeUnixNano:         ts,
+					ObservedTimeUnixNano: ts,
+					SeverityNumber:       severityNumberInfo,
+					SeverityText:         "INFO",
+					Body:                 value{StringValue: &text},
+					Attributes: []attribute{
+						stringAttribute("title", e.Title),
+						{Key: "tags", Value: value{ArrayValue: tags}},
+					},
+				}},
+			}},
+		}},
+	}
+}
+
+func stringAttribute(k, v string) attribute {
+	return attribute{Key: k, Value: value{StringValue: &v}}
+}
+
+func intAttribute(k string, v int64) attribute {
+	s := strconv.FormatInt(v, 10)
+	return attribute{Key: k, Value: value{IntValue: &s}}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
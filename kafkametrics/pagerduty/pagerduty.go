@@ -0,0 +1,246 @@
+// Package pagerduty implements
+// a kafkametrics Handler.
+//
+// The Handler is an event sink; events are sent to the PagerDuty Events API
+// v2 and no metrics are provided.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+const (
+	eventsURL = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// severities maps valid PagerDuty severities to their rank.
+var severities = map[string]int{
+	"info":     0,
+	"warning":  1,
+	"error":    2,
+	"critical": 3,
+}
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// RoutingKey is the PagerDuty service integration key.
+	RoutingKey string
+	// Source is the event source. Defaults to the hostname.
+	Source string
+	// Component, Group and Class are optional event payload fields.
+	Component string
+	Group     string
+	Class     string
+	// SeverityRules map event tags to severities. The severity of the first
+	// rule with a tag matching any event tag is used.
+	SeverityRules []SeverityRule
+	// DefaultSeverity is the severity of events that don't match any
+	// severity rule. Defaults to "info".
+	DefaultSeverity string
+	// MinSeverity is the minimum severity of events that are sent;
+	// lower severity events are discarded. Defaults to "info".
+	MinSeverity string
+}
+
+// SeverityRule assigns a severity to events with a matching tag.
+type SeverityRule struct {
+	// Tag is a tag pattern in path.Match syntax.
+	// Example: "state:failure*"
+	Tag string
+	// Severity is one of "critical", "error", "warning" or "info".
+	Severity string
+}
+
+type pagerdutyHandler struct {
+	c               *http.Client
+	eventsURL       string
+	routingKey      string
+	source          string
+	component       string
+	group           string
+	class           string
+	severityRules   []SeverityRule
+	defaultSeverity string
+	minSeverity     string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	if c.RoutingKey == "" {
+		return nil, errors.New("routing key must be specified")
+	}
+
+	h := &pagerdutyHandler{
+		c:               &http.Client{Timeout: 10 * time.Second},
+		eventsURL:       eventsURL,
+		routingKey:      c.RoutingKey,
+		source:          c.Source,
+		component:       c.Component,
+		group:           c.Group,
+		class:           c.Class,
+		severityRules:   c.SeverityRules,
+		defaultSeverity: "info",
+		minSeverity:     "info",
+	}
+
+	if h.source == "" {
+		h.source, _ = os.Hostname()
+	}
+
+	if c.DefaultSeverity != "" {
+		h.defaultSeverity = c.DefaultSeverity
+	}
+
+	if c.MinSeverity != "" {
+		h.minSeverity = c.MinSeverity
+	}
+
+	for _, s := range []string{h.defaultSeverity, h.minSeverity} {
+		if _, valid := severities[s]; !valid {
+			return nil, fmt.Errorf("invalid severity: %s", s)
+		}
+	}
+
+	for _, r := range h.severityRules {
+		if _, valid := severities[r.Severity]; !valid {
+			return nil, fmt.Errorf("invalid severity: %s", r.Severity)
+		}
+		if _, err := path.Match(r.Tag, ""); err != nil {
+			return nil, fmt.Errorf("invalid severity rule tag %s: %s", r.Tag, err)
+		}
+	}
+
+	return h, nil
+}
+
+// GetMetrics isn't supported by the PagerDuty Handler and always
+// returns a NoResults error.
+func (h *pagerdutyHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return nil, []error{&kafkametrics.NoResults{
+		Message: "The pagerduty handler doesn't provide metrics",
+	}}
+}
+
+// GetMetricsContext isn't supported; see GetMetrics.
+func (h *pagerdutyHandler) GetMetricsContext(context.Context) (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetrics()
+}
+
+// PostEvent triggers a PagerDuty alert for the event if the event severity
+// meets the minimum severity.
+func (h *pagerdutyHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *pagerdutyHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	severity := h.severity(e)
+	if severities[severity] < severities[h.minSeverity] {
+		return nil
+	}
+
+	if err := h.enqueue(ctx, h.triggerAlert(e, severity)); err != nil {
+		return &kafkametrics.APIError{
+			Request: "enqueue event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// severity returns the event severity according to the severity rules.
+func (h *pagerdutyHandler) severity(e *kafkametrics.Event) string {
+	for _, r := range h.severityRules {
+		for _, t := range e.Tags {
+			if matched, _ := path.Match(r.Tag, t); matched {
+				return r.Severity
+			}
+		}
+	}
+
+	return h.defaultSeverity
+}
+
+type alert struct {
+	RoutingKey  string  `json:"routing_key"`
+	EventAction string  `json:"event_action"`
+	Payload     payload `json:"payload"`
+}
+
+type payload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+// triggerAlert returns a trigger alert for the event. The event title is
+// used as the alert summary; the text and tags are included as custom
+// details.
+func (h *pagerdutyHandler) triggerAlert(e *kafkametrics.Event, severity string) *alert {
+	return &alert{
+		RoutingKey:  h.routingKey,
+		EventAction: "trigger",
+		Payload: payload{
+			Summary:   e.Title,
+			Source:    h.source,
+			Severity:  severity,
+			Component: h.component,
+			Group:     h.group,
+			Class:     h.class,
+			CustomDetails: map[string]interface{}{
+				"text": e.Text,
+				"tags": e.Tags,
+			},
+		},
+	}
+}
+
+// enqueue sends an alert to the Events API.
+func (h *pagerdutyHandler) enqueue(ctx context.Context, a *alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		var r struct {
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&r) == nil && r.Message != "" {
+			return fmt.Errorf("%s: %v", r.Message, r.Errors)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestPostEvent(t *testing.T) {
+	srv, alerts := stubServer()
+	defer srv.Close()
+
+	h := stubHandler(srv.URL)
+
+	e := &kafkametrics.Event{
+		Title: "Broker replication throttle set",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle", "state:failure-mode"},
+	}
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	a := <-alerts
+	if a.RoutingKey != "key" || a.EventAction != "trigger" {
+		t.Errorf("Unexpected alert %+v\n", a)
+	}
+
+	if a.Payload.Summary != e.Title || a.Payload.Source != "autothrottle" || a.Payload.Severity != "critical" {
+		t.Errorf("Unexpected payload %+v\n", a.Payload)
+	}
+
+	// Events below the minimum severity are discarded.
+	e.Tags = []string{"name:kafka-autothrottle"}
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(alerts) != 0 {
+		t.Errorf("Expected no alerts, got %d\n", len(alerts))
+	}
+
+	// API errors.
+	h.routingKey = "invalid"
+	e.Tags = []string{"state:failure-mode"}
+	if _, ok := h.PostEvent(e).(*kafkametrics.APIError); !ok {
+		t.Error("Expected APIError")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	h := stubHandler("")
+
+	tests := map[string]string{
+		"state:failure-mode": "critical",
+		"state:warning":      "warning",
+		"state:ok":           "info",
+	}
+
+	for tag, expected := range tests {
+		e := &kafkametrics.Event{Tags: []string{"name:kafka-autothrottle", tag}}
+		if s := h.severity(e); s != expected {
+			t.Errorf("[%s] Expected severity %s, got %s\n", tag, expected, s)
+		}
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	for _, c := range []*Config{
+		{},
+		{RoutingKey: "key", DefaultSeverity: "high"},
+		{RoutingKey: "key", SeverityRules: []SeverityRule{{Tag: "state:*", Severity: "page"}}},
+		{RoutingKey: "key", SeverityRules: []SeverityRule{{Tag: "[", Severity: "info"}}},
+	} {
+		if _, err := NewHandler(c); err == nil {
+			t.Errorf("Expected error for config %+v\n", c)
+		}
+	}
+}
+
+func stubHandler(url string) *pagerdutyHandler {
+	h, _ := NewHandler(&Config{
+		RoutingKey: "key",
+		Source:     "autothrottle",
+		SeverityRules: []SeverityRule{
+			{Tag: "state:failure*", Severity: "critical"},
+			{Tag: "state:warn*", Severity: "warning"},
+		},
+		MinSeverity: "warning",
+	})
+
+	ph := h.(*pagerdutyHandler)
+	ph.eventsURL = url
+
+	return ph
+}
+
+// stubServer returns a test server that accepts alerts for the "key"
+// routing key. Alerts are sent to the returned channel.
+func stubServer() (*httptest.Server, chan *alert) {
+	alerts := make(chan *alert, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		a := &alert{}
+		if err := json.NewDecoder(req.Body).Decode(a); err != nil || a.RoutingKey != "key" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"invalid event","message":"Event object is invalid","errors":["Invalid routing key"]}`)
+			return
+		}
+
+		alerts <- a
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"status":"success","message":"Event processed","dedup_key":"k"}`)
+	}))
+
+	return srv, alerts
+}
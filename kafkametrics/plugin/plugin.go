@@ -0,0 +1,175 @@
+// Package plugin implements
+// a kafkametrics Handler.
+//
+// Metrics are fetched from an out-of-process plugin implementing the
+// MetricsPlugin gRPC service defined in proto/kafkametricspb. Plugins written
+// in Go can wrap any kafkametrics Handler with Serve.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	pb "github.com/DataDog/kafka-kit/v4/proto/kafkametricspb"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Address is the plugin gRPC address.
+	// Example: "localhost:9000", "unix:///var/run/kafka-metrics.sock"
+	Address string
+	// Timeout is the plugin request timeout in seconds. Defaults to 30.
+	Timeout int
+}
+
+type pluginHandler struct {
+	c       pb.MetricsPluginClient
+	timeout time.Duration
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or connection errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	if c.Address == "" {
+		return nil, errors.New("plugin address must be specified")
+	}
+
+	h := &pluginHandler{timeout: 30 * time.Second}
+
+	if c.Timeout > 0 {
+		h.timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	// Validate.
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, c.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "connect to plugin",
+			Message: err.Error(),
+		}
+	}
+
+	h.c = pb.NewMetricsPluginClient(conn)
+
+	return h, nil
+}
+
+// GetMetrics requests broker metrics and metadata from the plugin and
+// returns a BrokerMetrics. Errors reported by the plugin are returned as
+// their kafkametrics error types.
+func (h *pluginHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. The
+// request is cancelled if the context is done.
+func (h *pluginHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	resp, err := h.c.GetMetrics(ctx, &pb.GetMetricsRequest{})
+	if err != nil {
+		return nil, []error{&kafkametrics.APIError{
+			Request: "plugin GetMetrics",
+			Message: err.Error(),
+		}}
+	}
+
+	var errs []error
+	for _, e := range resp.Errors {
+		errs = append(errs, fromProtoError(e))
+	}
+
+	if len(resp.Brokers) == 0 {
+		if errs == nil {
+			errs = []error{&kafkametrics.NoResults{
+				Message: "No brokers returned by plugin",
+			}}
+		}
+		return nil, errs
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	for _, b := range resp.Brokers {
+		bm[int(b.Id)] = &kafkametrics.Broker{
+			ID:           int(b.Id),
+			Host:         b.Host,
+			InstanceType: b.InstanceType,
+			NetTX:        b.NetTx,
+			NetRX:        b.NetRx,
+		}
+	}
+
+	return bm, errs
+}
+
+// PostEvent posts an event to the plugin.
+func (h *pluginHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *pluginHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	req := &pb.PostEventRequest{
+		Title: e.Title,
+		Text:  e.Text,
+		Tags:  e.Tags,
+	}
+
+	if _, err := h.c.PostEvent(ctx, req); err != nil {
+		return &kafkametrics.APIError{
+			Request: "plugin PostEvent",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// Error kinds used in GetMetricsResponse errors.
+const (
+	kindAPIError       = "api_error"
+	kindNoResults      = "no_results"
+	kindPartialResults = "partial_results"
+)
+
+// fromProtoError converts a plugin error to its kafkametrics error type.
+// Errors of an unknown kind are treated as API errors.
+func fromProtoError(e *pb.Error) error {
+	switch e.Kind {
+	case kindNoResults:
+		return &kafkametrics.NoResults{Message: e.Message}
+	case kindPartialResults:
+		return &kafkametrics.PartialResults{Message: e.Message}
+	default:
+		return &kafkametrics.APIError{Request: e.Request, Message: e.Message}
+	}
+}
+
+// toProtoError converts a kafkametrics error to a plugin error.
+func toProtoError(err error) *pb.Error {
+	switch e := err.(type) {
+	case *kafkametrics.NoResults:
+		return &pb.Error{Kind: kindNoResults, Message: e.Message}
+	case *kafkametrics.PartialResults:
+		return &pb.Error{Kind: kindPartialResults, Message: e.Message}
+	case *kafkametrics.APIError:
+		return &pb.Error{Kind: kindAPIError, Request: e.Request, Message: e.Message}
+	default:
+		return &pb.Error{Kind: kindAPIError, Message: err.Error()}
+	}
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	"github.com/DataDog/kafka-kit/v4/kafkametrics/mock"
+	pb "github.com/DataDog/kafka-kit/v4/proto/kafkametricspb"
+)
+
+func TestProtoErrors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{&kafkametrics.NoResults{Message: "No data returned with query rx"}, &kafkametrics.NoResults{Message: "No data returned with query rx"}},
+		{&kafkametrics.PartialResults{Message: "No points for host host1"}, &kafkametrics.PartialResults{Message: "No points for host host1"}},
+		// API errors keep their request.
+		{
+			&kafkametrics.APIError{Request: "metrics query", Message: "unexpected status 503"},
+			&kafkametrics.APIError{Request: "metrics query", Message: "unexpected status 503"},
+		},
+		// Other errors are returned as API errors.
+		{errors.New("backend error"), &kafkametrics.APIError{Message: "backend error"}},
+	}
+
+	for _, test := range tests {
+		if got := fromProtoError(toProtoError(test.err)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Expected %#v, got %#v\n", test.expected, got)
+		}
+	}
+
+	// Errors of an unknown kind are treated as API errors.
+	got := fromProtoError(&pb.Error{Kind: "unknown", Request: "query", Message: "error"})
+	if e, ok := got.(*kafkametrics.APIError); !ok || e.Request != "query" || e.Message != "error" {
+		t.Errorf("Unexpected error %#v\n", got)
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	bm := kafkametrics.BrokerMetrics{
+		1000: {ID: 1000, Host: "host0", InstanceType: "stub", NetTX: 2, NetRX: 4},
+		1001: {ID: 1001, Host: "host1", InstanceType: "stub", NetTX: 0.5, NetRX: 1.5},
+	}
+
+	m := mock.NewHandler().
+		AddPartialResults(bm, "Missing series labels: broker_id:host2").
+		AddNoResults("No data returned with query rx").
+		AddResult(kafkametrics.BrokerMetrics{})
+
+	h, stop := stubHandler(t, m)
+
+	// Plugin errors are returned with the brokers.
+	got, errs := h.GetMetrics()
+	if !reflect.DeepEqual(got, bm) {
+		t.Errorf("Expected BrokerMetrics %v, got %v\n", bm, got)
+	}
+
+	if len(errs) != 1 || errs[0].Error() != "Missing series labels: broker_id:host2" {
+		t.Errorf("Unexpected errors %s\n", errs)
+	}
+
+	got, errs = h.GetMetrics()
+	if e, ok := errs[0].(*kafkametrics.NoResults); got != nil || len(errs) != 1 || !ok || e.Message != "No data returned with query rx" {
+		t.Errorf("Unexpected results %v, %s\n", got, errs)
+	}
+
+	// No brokers and no errors.
+	got, errs = h.GetMetrics()
+	if e, ok := errs[0].(*kafkametrics.NoResults); got != nil || len(errs) != 1 || !ok || e.Message != "No brokers returned by plugin" {
+		t.Errorf("Unexpected results %v, %s\n", got, errs)
+	}
+
+	// The plugin is unavailable.
+	stop()
+
+	_, errs = h.GetMetrics()
+	if e, ok := errs[0].(*kafkametrics.APIError); !ok || e.Request != "plugin GetMetrics" || !strings.Contains(e.Message, "code = Unavailable") {
+		t.Errorf("Unexpected error %s\n", errs[0])
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	m := mock.NewHandler()
+	h, _ := stubHandler(t, m)
+
+	e := &kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle"},
+	}
+
+	if err := h.PostEvent(e); err != nil {
+		t.Fatal(err)
+	}
+
+	events := m.Events()
+	if len(events) != 1 || events[0].Title != "title" || events[0].Tags[0] != "name:kafka-autothrottle" {
+		t.Errorf("Unexpected events %v\n", events)
+	}
+
+	m.AddEventError(errors.New("backend error"))
+
+	err := h.PostEvent(e)
+	if ae, ok := err.(*kafkametrics.APIError); !ok || ae.Request != "plugin PostEvent" || ae.Message != "rpc error: code = Unknown desc = backend error" {
+		t.Errorf("Unexpected error %v\n", err)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	_, err = NewHandler(&Config{Address: l.Addr().String(), Timeout: 1})
+	if e, ok := err.(*kafkametrics.APIError); !ok || e.Request != "connect to plugin" {
+		t.Errorf("Unexpected error %v\n", err)
+	}
+}
+
+// stubHandler serves the Handler h as a plugin and returns a
+// plugin Handler connected to it, along with a func that stops the plugin.
+func stubHandler(t *testing.T, h kafkametrics.Handler) (kafkametrics.Handler, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := grpc.NewServer()
+	pb.RegisterMetricsPluginServer(s, NewServer(h))
+
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	ph, err := NewHandler(&Config{Address: l.Addr().String(), Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ph, s.Stop
+}
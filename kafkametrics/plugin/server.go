@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+	pb "github.com/DataDog/kafka-kit/v4/proto/kafkametricspb"
+)
+
+// Server implements the MetricsPlugin gRPC service
+// backed by a kafkametrics Handler.
+type Server struct {
+	pb.UnimplementedMetricsPluginServer
+	h kafkametrics.Handler
+}
+
+// NewServer returns a *Server that serves metrics from the Handler.
+func NewServer(h kafkametrics.Handler) *Server {
+	return &Server{h: h}
+}
+
+// Serve serves the Handler as a plugin on the listener. Serve blocks until
+// the listener fails.
+func Serve(l net.Listener, h kafkametrics.Handler) error {
+	s := grpc.NewServer()
+	pb.RegisterMetricsPluginServer(s, NewServer(h))
+
+	return s.Serve(l)
+}
+
+// GetMetrics implements the MetricsPlugin GetMetrics method.
+func (s *Server) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.GetMetricsResponse, error) {
+	bm, errs := s.h.GetMetrics()
+
+	resp := &pb.GetMetricsResponse{}
+
+	for _, b := range bm {
+		resp.Brokers = append(resp.Brokers, &pb.Broker{
+			Id:           int32(b.ID),
+			Host:         b.Host,
+			InstanceType: b.InstanceType,
+			NetTx:        b.NetTX,
+			NetRx:        b.NetRX,
+		})
+	}
+
+	for _, err := range errs {
+		resp.Errors = append(resp.Errors, toProtoError(err))
+	}
+
+	return resp, nil
+}
+
+// PostEvent implements the MetricsPlugin PostEvent method.
+func (s *Server) PostEvent(ctx context.Context, req *pb.PostEventRequest) (*pb.PostEventResponse, error) {
+	e := &kafkametrics.Event{
+		Title: req.Title,
+		Text:  req.Text,
+		Tags:  req.Tags,
+	}
+
+	if err := s.h.PostEvent(e); err != nil {
+		return nil, err
+	}
+
+	return &pb.PostEventResponse{}, nil
+}
@@ -0,0 +1,117 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// apiResponse is the Prometheus HTTP API response envelope.
+type apiResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+}
+
+// queryData is the data field of a query or query_range response.
+type queryData struct {
+	ResultType string   `json:"resultType"`
+	Result     []series `json:"result"`
+}
+
+// series is a single labeled timeseries. Range queries populate Values,
+// instant queries populate Value.
+type series struct {
+	Metric map[string]string `json:"metric"`
+	Values []point           `json:"values"`
+	Value  point             `json:"value"`
+}
+
+// point is a [timestamp, "value"] pair.
+type point [2]interface{}
+
+// float returns the point value as a float64.
+func (p point) float() (float64, error) {
+	s, ok := p[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected point value %v", p[1])
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// query runs an instant query.
+func (h *promHandler) query(ctx context.Context, q string) ([]series, error) {
+	params := url.Values{}
+	params.Set("query", q)
+
+	return h.get(ctx, "/api/v1/query", params)
+}
+
+// queryRange runs a range query over the start and end time.
+func (h *promHandler) queryRange(ctx context.Context, q string, start, end time.Time) ([]series, error) {
+	params := url.Values{}
+	params.Set("query", q)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.Itoa(h.step))
+
+	return h.get(ctx, "/api/v1/query_range", params)
+}
+
+// get issues a request to the API path with the provided params and decodes
+// the response series.
+func (h *promHandler) get(ctx context.Context, path string, params url.Values) ([]series, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s?%s", h.url, path, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case h.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+h.bearerToken)
+	case h.username != "":
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var r apiResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error decoding response (status %d): %s", resp.StatusCode, err)
+	}
+
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s: %s", r.ErrorType, r.Error)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var d queryData
+	if err := json.Unmarshal(r.Data, &d); err != nil {
+		return nil, fmt.Errorf("error decoding response data: %s", err)
+	}
+
+	return d.Result, nil
+}
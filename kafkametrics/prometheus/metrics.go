@@ -0,0 +1,275 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Series names and the scale applied to the series values for each metric
+// type descriptor used by brokersFromSeries.
+var (
+	seriesNames = []string{
+		kafkametrics.SeriesNetTX,
+		kafkametrics.SeriesNetRX,
+		kafkametrics.SeriesDiskUsed,
+		kafkametrics.SeriesDiskFree,
+		kafkametrics.SeriesCPU,
+		kafkametrics.SeriesLeaderBytesOut,
+		kafkametrics.SeriesReplicationBytesOut,
+	}
+	seriesScales = []float64{1.0 / 1024 / 1024, 1.0 / 1024 / 1024, 1, 1, 1, 1.0 / 1024 / 1024, 1.0 / 1024 / 1024}
+)
+
+// brokersFromSeries takes a []series and an int descriptor for the metric
+// type and returns a []*kafkametrics.Broker. If for some reason points or
+// required labels were not returned for a broker, it's excluded from the
+// []*kafkametrics.Broker and an error is populated in the return []error.
+func (h *promHandler) brokersFromSeries(s []series, metric int) ([]*kafkametrics.Broker, []error) {
+	bs := []*kafkametrics.Broker{}
+	var errors []error
+	var skipped []kafkametrics.SkippedBroker
+
+	for _, ts := range s {
+		host := ts.Metric[h.hostLabel]
+
+		v, ok := windowValue(ts.Values, h.aggregation)
+		if !ok {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", host),
+				Skipped: []kafkametrics.SkippedBroker{{Host: host, Reason: "no points", Missing: kafkametrics.MissingPoints}},
+			})
+			continue
+		}
+
+		// Get ID.
+		id, err := strconv.Atoi(ts.Metric[h.brokerIDLabel])
+		if err != nil {
+			skipped = append(skipped, kafkametrics.SkippedBroker{
+				Host:    host,
+				Reason:  fmt.Sprintf("missing %s label", h.brokerIDLabel),
+				Missing: kafkametrics.MissingBrokerID,
+			})
+			continue
+		}
+
+		// Get instance type.
+		var it string
+		if h.instanceTypeLabel != "" {
+			it = ts.Metric[h.instanceTypeLabel]
+			if it == "" {
+				skipped = append(skipped, kafkametrics.SkippedBroker{
+					Host:    host,
+					ID:      id,
+					Reason:  fmt.Sprintf("missing %s label", h.instanceTypeLabel),
+					Missing: kafkametrics.MissingInstanceType,
+				})
+				continue
+			}
+		}
+
+		b := &kafkametrics.Broker{
+			ID:           id,
+			Host:         host,
+			InstanceType: it,
+		}
+
+		switch metric {
+		case 0:
+			b.NetTX = v / 1024 / 1024
+		case 1:
+			b.NetRX = v / 1024 / 1024
+		case 2:
+			b.DiskUsed = v
+		case 3:
+			b.DiskFree = v
+		case 4:
+			b.CPU = v
+		case 5:
+			b.LeaderBytesOut = v / 1024 / 1024
+		case 6:
+			b.ReplicationBytesOut = v / 1024 / 1024
+		}
+
+		bs = append(bs, b)
+	}
+
+	if len(skipped) > 0 {
+		errors = append(errors, kafkametrics.NewPartialResults("Missing series labels", skipped))
+	}
+
+	return bs, errors
+}
+
+// setNamedMetric takes a []*kafkametrics.Broker, a metric name and a
+// []series and sets the aggregated series window value in the Metrics map of each broker
+// with a matching broker ID. Brokers with missing points are skipped and an
+// error is populated in the return []error.
+func (h *promHandler) setNamedMetric(l []*kafkametrics.Broker, name string, s []series) []error {
+	var errors []error
+
+	m := map[int]*kafkametrics.Broker{}
+	for _, b := range l {
+		m[b.ID] = b
+	}
+
+	for _, ts := range s {
+		id, err := strconv.Atoi(ts.Metric[h.brokerIDLabel])
+		if err != nil {
+			continue
+		}
+
+		b, exists := m[id]
+		if !exists {
+			continue
+		}
+
+		v, ok := windowValue(ts.Values, h.aggregation)
+		if !ok {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No %s points for host %s", name, b.Host),
+			})
+			continue
+		}
+
+		if b.Metrics == nil {
+			b.Metrics = map[string]float64{}
+		}
+
+		b.Metrics[name] = v
+	}
+
+	return errors
+}
+
+// setSeries takes a []*kafkametrics.Broker, a series name, a []series and a
+// scale and sets the scaled series points in the Series map of each broker
+// with a matching broker ID. NaN and invalid values are excluded.
+func (h *promHandler) setSeries(l []*kafkametrics.Broker, name string, s []series, scale float64) {
+	m := map[int]*kafkametrics.Broker{}
+	for _, b := range l {
+		m[b.ID] = b
+	}
+
+	for _, ts := range s {
+		id, err := strconv.Atoi(ts.Metric[h.brokerIDLabel])
+		if err != nil {
+			continue
+		}
+
+		b, exists := m[id]
+		if !exists {
+			continue
+		}
+
+		var points []kafkametrics.Point
+		for _, p := range ts.Values {
+			t, ok := p[0].(float64)
+			v, err := p.float()
+			if !ok || err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+
+			points = append(points, kafkametrics.Point{
+				Time:  time.Unix(0, int64(t*float64(time.Second))),
+				Value: v * scale,
+			})
+		}
+
+		if len(points) > 0 {
+			b.SetSeries(name, points)
+		}
+	}
+}
+
+// windowValue returns the Aggregation of all non-NaN values in the []point
+// and whether any valid values were found.
+func windowValue(ps []point, a kafkametrics.Aggregation) (float64, bool) {
+	var values []float64
+
+	for _, p := range ps {
+		if v, err := p.float(); err == nil {
+			values = append(values, v)
+		}
+	}
+
+	return a.Apply(values)
+}
+
+// mergeBrokerLists takes a destination and source []*kafkametrics.Broker
+// and adds/updates source brokers into the destination list, returning
+// a merged copy.
+func mergeBrokerLists(dst, src []*kafkametrics.Broker) []*kafkametrics.Broker {
+	// Build a map of Broker.ID to []*kafkametrics.Broker index for the
+	// dst list.
+	m := map[int]int{}
+	for i, b := range dst {
+		m[b.ID] = i
+	}
+
+	// For each broker in the src list, add/update in the dst list.
+	for _, b := range src {
+		if i, exists := m[b.ID]; exists {
+			// Update.
+			updateBroker(dst[i], b)
+		} else {
+			// Add.
+			dst = append(dst, b)
+			m[b.ID] = len(dst) - 1
+		}
+	}
+
+	return dst
+}
+
+// updateBrokerList takes a destination and source []*kafkametrics.Broker
+// and updates destination brokers with the source broker metrics. Source
+// brokers not in the destination list are ignored.
+func updateBrokerList(dst, src []*kafkametrics.Broker) {
+	m := map[int]*kafkametrics.Broker{}
+	for _, b := range dst {
+		m[b.ID] = b
+	}
+
+	for _, b := range src {
+		if d, exists := m[b.ID]; exists {
+			updateBroker(d, b)
+		}
+	}
+}
+
+// updateBroker takes a destination and source broker and merges the
+// source broker metrics values to the destination if the destination
+// values are defaults.
+func updateBroker(dst, src *kafkametrics.Broker) {
+	if dst.NetTX == 0.00 {
+		dst.NetTX = src.NetTX
+	}
+
+	if dst.NetRX == 0.00 {
+		dst.NetRX = src.NetRX
+	}
+
+	if dst.DiskUsed == 0.00 {
+		dst.DiskUsed = src.DiskUsed
+	}
+
+	if dst.DiskFree == 0.00 {
+		dst.DiskFree = src.DiskFree
+	}
+
+	if dst.CPU == 0.00 {
+		dst.CPU = src.CPU
+	}
+
+	if dst.LeaderBytesOut == 0.00 {
+		dst.LeaderBytesOut = src.LeaderBytesOut
+	}
+
+	if dst.ReplicationBytesOut == 0.00 {
+		dst.ReplicationBytesOut = src.ReplicationBytesOut
+	}
+}
@@ -0,0 +1,345 @@
+// Package prometheus implements
+// a kafkametrics Handler.
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the Prometheus server address. Any server implementing the
+	// Prometheus HTTP query API, such as VictoriaMetrics, Thanos, Mimir or M3,
+	// is supported.
+	// Example: "http://prometheus:9090"
+	URL string
+	// PathPrefix is an optional path prefix for the query API.
+	// Example (Mimir): "/prometheus", (VictoriaMetrics cluster): "/select/0/prometheus"
+	PathPrefix string
+	// Headers are optional HTTP headers sent with all requests.
+	// Example (Mimir, Thanos): {"X-Scope-OrgID": "tenant-1"}
+	Headers map[string]string
+	// BearerToken is an optional bearer token for authentication.
+	BearerToken string
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// NetworkTXQuery is a PromQL query that should return the outbound
+	// network throughput in bytes/s by host for the reference Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (rate(node_network_transmit_bytes_total{job=\"kafka\"}[1m]))"
+	NetworkTXQuery string
+	// NetworkRXQuery is a PromQL query that should return the inbound
+	// network throughput in bytes/s by host for the reference Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (rate(node_network_receive_bytes_total{job=\"kafka\"}[1m]))"
+	NetworkRXQuery string
+	// DiskUsedQuery is an optional PromQL query that should return the disk
+	// used in bytes by host for the reference Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (node_filesystem_size_bytes{mountpoint=\"/data\"} - node_filesystem_avail_bytes{mountpoint=\"/data\"})"
+	DiskUsedQuery string
+	// DiskFreeQuery is an optional PromQL query that should return the disk
+	// free in bytes by host for the reference Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (node_filesystem_avail_bytes{mountpoint=\"/data\"})"
+	DiskFreeQuery string
+	// CPUQuery is an optional PromQL query that should return the CPU
+	// utilization percent by host for the reference Kafka brokers.
+	// Example: "100 * (1 - avg by (instance, broker_id, instance_type) (rate(node_cpu_seconds_total{mode=\"idle\"}[1m])))"
+	CPUQuery string
+	// LeaderBytesOutQuery is an optional PromQL query that should return the
+	// bytes/s out serving client fetch requests by host for the reference
+	// Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (rate(kafka_server_brokertopicmetrics_bytesout_total{topic=\"\"}[1m]))"
+	LeaderBytesOutQuery string
+	// ReplicationBytesOutQuery is an optional PromQL query that should return
+	// the bytes/s out serving follower replication by host for the reference
+	// Kafka brokers.
+	// Example: "sum by (instance, broker_id, instance_type) (rate(kafka_server_brokertopicmetrics_replicationbytesout_total{topic=\"\"}[1m]))"
+	ReplicationBytesOutQuery string
+	// Queries is an optional map of names to PromQL queries for any
+	// additional metrics by host for the reference Kafka brokers. Values
+	// are populated in the Broker Metrics map by name, unconverted.
+	// Example: {"under_replicated": "sum by (instance, broker_id, instance_type) (kafka_server_replicamanager_underreplicatedpartitions)"}
+	Queries map[string]string
+	// Cluster is an optional Kafka cluster name available to query templates
+	// as {{.Cluster}}. All queries may be Go templates, for which the
+	// MetricsWindow is also available as {{.Window}}.
+	// Example: "sum by (instance, broker_id, instance_type) (rate(node_network_transmit_bytes_total{cluster=\"{{.Cluster}}\"}[{{.Window}}s]))"
+	Cluster string
+	// QueryVars are optional additional query template variables,
+	// referenced as {{.Vars.name}}.
+	QueryVars map[string]string
+	// BrokerIDLabel is the series label name for Kafka broker IDs.
+	BrokerIDLabel string
+	// InstanceTypeLabel is the series label name for the Kafka broker's
+	// instance type.
+	InstanceTypeLabel string
+	// HostLabel is the series label name for the Kafka broker's hostname.
+	// Defaults to "instance".
+	HostLabel string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are reduced with the Aggregation.
+	MetricsWindow int
+	// Step is the range query resolution in seconds. Defaults to 60.
+	Step int
+	// Aggregation is how the points of each window are reduced to a single
+	// value: "avg", "max" or a percentile such as "p95" or "p99". Defaults
+	// to "avg".
+	Aggregation string
+	// RetainSeries populates the full windowed series of each metric in the
+	// Broker Series map, at the Step resolution.
+	RetainSeries bool
+	// Timeout is the HTTP request timeout in seconds. Defaults to 30.
+	Timeout int
+}
+
+type promHandler struct {
+	c                 *http.Client
+	url               string
+	headers           map[string]string
+	bearerToken       string
+	username          string
+	password          string
+	netTXQuery        string
+	netRXQuery        string
+	optionalQueries   []string
+	queries           map[string]string
+	queryNames        []string
+	brokerIDLabel     string
+	instanceTypeLabel string
+	hostLabel         string
+	metricsWindow     int
+	step              int
+	aggregation       kafkametrics.Aggregation
+	retainSeries      bool
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or connectivity validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "":
+		return nil, errors.New("prometheus URL must be specified")
+	case c.BearerToken != "" && c.Username != "":
+		return nil, errors.New("only one of bearer token or basic auth may be specified")
+	case c.NetworkTXQuery == "", c.NetworkRXQuery == "":
+		return nil, errors.New("network tx and rx queries must be specified")
+	case c.BrokerIDLabel == "":
+		return nil, errors.New("broker ID label must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	// Render any query templates.
+	vars := kafkametrics.QueryVars{
+		Cluster: c.Cluster,
+		Window:  c.MetricsWindow,
+		Vars:    c.QueryVars,
+	}
+
+	queries := []string{
+		c.NetworkTXQuery,
+		c.NetworkRXQuery,
+		c.DiskUsedQuery,
+		c.DiskFreeQuery,
+		c.CPUQuery,
+		c.LeaderBytesOutQuery,
+		c.ReplicationBytesOutQuery,
+	}
+
+	if err := kafkametrics.RenderQueries(queries, vars); err != nil {
+		return nil, err
+	}
+
+	named := make(map[string]string, len(c.Queries))
+	for name, q := range c.Queries {
+		q, err := kafkametrics.RenderQuery(q, vars)
+		if err != nil {
+			return nil, err
+		}
+		named[name] = q
+	}
+
+	h := &promHandler{
+		c:                 &http.Client{Timeout: 30 * time.Second},
+		url:               strings.TrimSuffix(c.URL, "/") + strings.TrimSuffix(c.PathPrefix, "/"),
+		headers:           c.Headers,
+		bearerToken:       c.BearerToken,
+		username:          c.Username,
+		password:          c.Password,
+		netTXQuery:        queries[0],
+		netRXQuery:        queries[1],
+		optionalQueries:   queries[2:],
+		queries:           named,
+		brokerIDLabel:     c.BrokerIDLabel,
+		instanceTypeLabel: c.InstanceTypeLabel,
+		hostLabel:         "instance",
+		metricsWindow:     c.MetricsWindow,
+		step:              60,
+		aggregation:       kafkametrics.AggregationAvg,
+		retainSeries:      c.RetainSeries,
+	}
+
+	for name := range c.Queries {
+		h.queryNames = append(h.queryNames, name)
+	}
+	sort.Strings(h.queryNames)
+
+	if c.HostLabel != "" {
+		h.hostLabel = c.HostLabel
+	}
+
+	if c.Step > 0 {
+		h.step = c.Step
+	}
+
+	if c.Aggregation != "" {
+		a, err := kafkametrics.ParseAggregation(c.Aggregation)
+		if err != nil {
+			return nil, err
+		}
+		h.aggregation = a
+	}
+
+	if c.Timeout > 0 {
+		h.c.Timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	// Validate.
+	if _, err := h.query(context.Background(), "vector(1)"); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate connection",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent isn't supported; the Prometheus query API doesn't have an event API, so events
+// are discarded.
+func (h *promHandler) PostEvent(*kafkametrics.Event) error {
+	return nil
+}
+
+// PostEventContext isn't supported; see PostEvent.
+func (h *promHandler) PostEventContext(context.Context, *kafkametrics.Event) error {
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the Prometheus API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *promHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *promHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	var mergedBrokerList []*kafkametrics.Broker
+
+	end := time.Now()
+	start := end.Add(-time.Duration(h.metricsWindow) * time.Second)
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, query := range []string{h.netTXQuery, h.netRXQuery} {
+		series, err := h.queryRange(ctx, query, start, end)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(series) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with query %s", query),
+			}}
+		}
+
+		// Get a []*kafkametrics.Broker from the series. Brokers with missing
+		// points or labels are excluded from blist.
+		blist, errs := h.brokersFromSeries(series, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && len(blist) != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = len(blist)
+
+		mergedBrokerList = mergeBrokerLists(mergedBrokerList, blist)
+
+		if h.retainSeries {
+			h.setSeries(mergedBrokerList, seriesNames[i], series, seriesScales[i])
+		}
+	}
+
+	// Get the optional disk, CPU and leader/replication bytes out metrics.
+	// These only update brokers that network metrics were received for.
+	for i, query := range h.optionalQueries {
+		if query == "" {
+			continue
+		}
+
+		series, err := h.queryRange(ctx, query, start, end)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: err.Error(),
+			}}
+		}
+
+		blist, errs := h.brokersFromSeries(series, i+2)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		updateBrokerList(mergedBrokerList, blist)
+
+		if h.retainSeries {
+			h.setSeries(mergedBrokerList, seriesNames[i+2], series, seriesScales[i+2])
+		}
+	}
+
+	// Get any named metrics.
+	for _, name := range h.queryNames {
+		series, err := h.queryRange(ctx, h.queries[name], start, end)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "metrics query",
+				Message: err.Error(),
+			}}
+		}
+
+		if errs := h.setNamedMetric(mergedBrokerList, name, series); errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		if h.retainSeries {
+			h.setSeries(mergedBrokerList, name, series, 1)
+		}
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	for _, b := range mergedBrokerList {
+		bm[b.ID] = b
+	}
+
+	return bm, errors
+}
@@ -0,0 +1,348 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestNewHandler(t *testing.T) {
+	srv := stubServer()
+	defer srv.Close()
+
+	// Test with incomplete config.
+	if _, err := NewHandler(&Config{URL: srv.URL}); err == nil {
+		t.Error("Expected error")
+	}
+
+	c := stubConfig(srv.URL)
+	if _, err := NewHandler(c); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	c.Aggregation = "sum"
+	if _, err := NewHandler(c); err == nil {
+		t.Error("Expected error for unknown aggregation")
+	}
+}
+
+func TestNewHandlerQueryTemplates(t *testing.T) {
+	srv := stubServer()
+	defer srv.Close()
+
+	c := stubConfig(srv.URL)
+	c.Cluster = "t"
+	c.QueryVars = map[string]string{"direction": "x"}
+	c.NetworkTXQuery = "{{.Cluster}}{{.Vars.direction}}"
+
+	h, err := NewHandler(c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	if bm, errs := h.GetMetrics(); errs != nil || bm[1000].NetTX != 2.00 {
+		t.Errorf("Expected rendered tx query results, got %v\n", errs)
+	}
+
+	c.NetworkTXQuery = "{{.Vars.missing}}"
+	if _, err := NewHandler(c); err == nil {
+		t.Error("Expected error for undefined query variable")
+	}
+}
+
+func TestNewHandlerRequestOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path != "/prometheus/api/v1/query":
+			w.WriteHeader(http.StatusNotFound)
+		case req.Header.Get("X-Scope-OrgID") != "tenant-1":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status":"error","errorType":"unauthorized","error":"no org id"}`)
+		case req.Header.Get("Authorization") != "Bearer token":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status":"error","errorType":"unauthorized","error":"bad token"}`)
+		default:
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := stubConfig(srv.URL)
+	c.PathPrefix = "/prometheus/"
+	c.Headers = map[string]string{"X-Scope-OrgID": "tenant-1"}
+	c.BearerToken = "token"
+
+	if _, err := NewHandler(c); err != nil {
+		t.Errorf("Unexpected error: %s\n", err)
+	}
+
+	c.BearerToken = "invalid"
+	if _, err := NewHandler(c); err == nil {
+		t.Error("Expected error")
+	}
+
+	// Bearer token and basic auth are mutually exclusive.
+	c.Username = "user"
+	if _, err := NewHandler(c); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	srv := stubServer()
+	defer srv.Close()
+
+	h, err := NewHandler(stubConfig(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bm, errs := h.GetMetrics()
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %s\n", errs)
+	}
+
+	if len(bm) != 3 {
+		t.Fatalf("Expected BrokerMetrics len 3, got %d\n", len(bm))
+	}
+
+	for i := 0; i < 3; i++ {
+		id := 1000 + i
+		b, exists := bm[id]
+		if !exists {
+			t.Fatalf("Expected broker %d in BrokerMetrics\n", id)
+		}
+
+		if b.Host != fmt.Sprintf("host%d", i) {
+			t.Errorf("Unexpected host %s for broker %d\n", b.Host, id)
+		}
+
+		if b.InstanceType != "stub" {
+			t.Errorf("Expected instance type stub, got %s\n", b.InstanceType)
+		}
+
+		// The tx series values are 1MB and 3MB; rx are 2MB and 4MB.
+		if b.NetTX != 2.00 {
+			t.Errorf("Expected NetTX 2.00, got %.2f\n", b.NetTX)
+		}
+
+		if b.NetRX != 3.00 {
+			t.Errorf("Expected NetRX 3.00, got %.2f\n", b.NetRX)
+		}
+	}
+}
+
+func TestGetMetricsContext(t *testing.T) {
+	srv := stubServer()
+	defer srv.Close()
+
+	h, err := NewHandler(stubConfig(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := h.(kafkametrics.ContextHandler).GetMetricsContext(ctx)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v\n", errs)
+	}
+
+	if _, ok := errs[0].(*kafkametrics.APIError); !ok {
+		t.Errorf("Expected *APIError, got %T\n", errs[0])
+	}
+}
+
+func TestGetMetricsOptional(t *testing.T) {
+	srv := stubServer()
+	defer srv.Close()
+
+	c := stubConfig(srv.URL)
+	c.DiskFreeQuery = "disk_free"
+	c.CPUQuery = "cpu"
+	c.ReplicationBytesOutQuery = "tx"
+	c.Queries = map[string]string{"cpu_pct": "cpu"}
+
+	h, err := NewHandler(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bm, errs := h.GetMetrics()
+	if errs != nil {
+		t.Fatalf("Unexpected errors: %s\n", errs)
+	}
+
+	for i := 0; i < 3; i++ {
+		b := bm[1000+i]
+		if b == nil {
+			t.Fatalf("Expected broker %d in BrokerMetrics\n", 1000+i)
+		}
+
+		// The disk_free series values are 3MB and 5MB.
+		if b.DiskFree != 4194304 || b.DiskUsed != 0 {
+			t.Errorf("Unexpected DiskFree/DiskUsed %.0f/%.0f\n", b.DiskFree, b.DiskUsed)
+		}
+
+		if b.CPU != 50 || b.Metrics["cpu_pct"] != 50 {
+			t.Errorf("Expected CPU and cpu_pct 50, got %.2f/%.2f\n", b.CPU, b.Metrics["cpu_pct"])
+		}
+
+		if b.ReplicationBytesOut != 2.00 || b.LeaderBytesOut != 0 {
+			t.Errorf("Unexpected ReplicationBytesOut/LeaderBytesOut %.2f/%.2f\n", b.ReplicationBytesOut, b.LeaderBytesOut)
+		}
+
+		if b.NetTX != 2.00 || b.NetRX != 3.00 {
+			t.Errorf("Unexpected NetTX/NetRX %.2f/%.2f\n", b.NetTX, b.NetRX)
+		}
+	}
+}
+
+func TestBrokersFromSeries(t *testing.T) {
+	h := &promHandler{brokerIDLabel: "broker_id", instanceTypeLabel: "instance_type", hostLabel: "instance"}
+
+	s := []series{
+		{
+			Metric: map[string]string{"instance": "host0", "broker_id": "1000", "instance_type": "stub"},
+			Values: []point{{1.0, "1048576"}},
+		},
+		// Missing points.
+		{
+			Metric: map[string]string{"instance": "host1", "broker_id": "1001", "instance_type": "stub"},
+			Values: []point{},
+		},
+		// Missing broker ID label.
+		{
+			Metric: map[string]string{"instance": "host2", "instance_type": "stub"},
+			Values: []point{{1.0, "1048576"}},
+		},
+		// Missing instance type label.
+		{
+			Metric: map[string]string{"instance": "host3", "broker_id": "1003"},
+			Values: []point{{1.0, "1048576"}},
+		},
+	}
+
+	bs, errs := h.brokersFromSeries(s, 0)
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %d\n", len(errs))
+	}
+
+	if skipped := kafkametrics.SkippedBrokers(errs); len(skipped) != 3 {
+		t.Errorf("Expected 3 skipped brokers, got %v\n", skipped)
+	}
+
+	if len(bs) != 1 {
+		t.Fatalf("Expected broker slice len 1, got %d\n", len(bs))
+	}
+
+	if bs[0].ID != 1000 || bs[0].NetTX != 1.00 {
+		t.Errorf("Unexpected broker values: %+v\n", bs[0])
+	}
+}
+
+func TestSetSeries(t *testing.T) {
+	h := &promHandler{brokerIDLabel: "broker_id"}
+	l := []*kafkametrics.Broker{{ID: 1000}, {ID: 1001}}
+
+	s := []series{
+		{
+			Metric: map[string]string{"broker_id": "1000"},
+			Values: []point{{60.0, "1048576"}, {120.0, "NaN"}, {180.0, "2097152"}},
+		},
+		// Not in the broker list.
+		{
+			Metric: map[string]string{"broker_id": "1002"},
+			Values: []point{{60.0, "1048576"}},
+		},
+	}
+
+	h.setSeries(l, kafkametrics.SeriesNetTX, s, seriesScales[0])
+
+	got := l[0].Series[kafkametrics.SeriesNetTX]
+	if len(got) != 2 || !got[1].Time.Equal(time.Unix(180, 0)) || got[1].Value != 2 {
+		t.Errorf("Unexpected series %v\n", got)
+	}
+
+	if l[1].Series != nil {
+		t.Errorf("Expected nil Series, got %v\n", l[1].Series)
+	}
+}
+
+func TestWindowValue(t *testing.T) {
+	avg, ok := windowValue([]point{{1.0, "10"}, {2.0, "NaN"}, {3.0, "20"}}, kafkametrics.AggregationAvg)
+	if !ok {
+		t.Fatal("Expected values")
+	}
+
+	if avg != 15 {
+		t.Errorf("Expected avg 15, got %f\n", avg)
+	}
+
+	max, _ := windowValue([]point{{1.0, "10"}, {2.0, "NaN"}, {3.0, "20"}}, kafkametrics.AggregationMax)
+	if max != 20 {
+		t.Errorf("Expected max 20, got %f\n", max)
+	}
+
+	if _, ok := windowValue([]point{{1.0, "NaN"}}, kafkametrics.AggregationAvg); ok {
+		t.Error("Expected no valid values")
+	}
+}
+
+func stubConfig(url string) *Config {
+	return &Config{
+		URL:               url,
+		NetworkTXQuery:    "tx",
+		NetworkRXQuery:    "rx",
+		BrokerIDLabel:     "broker_id",
+		InstanceTypeLabel: "instance_type",
+		MetricsWindow:     120,
+	}
+}
+
+// stubServer returns a test server that responds to the "tx", "rx",
+// "disk_free" and "cpu" queries with series for three brokers.
+func stubServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query().Get("query")
+
+		var base int
+		switch q {
+		case "tx":
+			base = 1
+		case "rx":
+			base = 2
+		case "disk_free":
+			base = 3
+		case "cpu":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[`+
+				`{"metric":{"instance":"host0","broker_id":"1000","instance_type":"stub"},"values":[[1,"40"],[2,"60"]]},`+
+				`{"metric":{"instance":"host1","broker_id":"1001","instance_type":"stub"},"values":[[1,"40"],[2,"60"]]},`+
+				`{"metric":{"instance":"host2","broker_id":"1002","instance_type":"stub"},"values":[[1,"40"],[2,"60"]]}]}}`)
+			return
+		case "vector(1)":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]}]}}`)
+			return
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"unknown query"}`)
+			return
+		}
+
+		var results []string
+		for i := 0; i < 3; i++ {
+			results = append(results, fmt.Sprintf(
+				`{"metric":{"instance":"host%d","broker_id":"100%d","instance_type":"stub"},"values":[[1,"%d"],[2,"%d"]]}`,
+				i, i, base*1048576, (base+2)*1048576))
+		}
+
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(results, ","))
+	}))
+}
@@ -0,0 +1,8 @@
+package kafkametrics
+
+// InstanceTypeResolver resolves the instance type of a broker by hostname,
+// e.g. from a cloud provider API. It's used by backends as a fallback where
+// the instance type isn't available from the metrics backend.
+type InstanceTypeResolver interface {
+	InstanceType(host string) (string, error)
+}
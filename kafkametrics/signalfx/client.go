@@ -0,0 +1,246 @@
+package signalfx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiURLFormat    = "https://api.%s.signalfx.com"
+	streamURLFormat = "https://stream.%s.signalfx.com"
+	ingestURLFormat = "https://ingest.%s.signalfx.com"
+)
+
+// stream is a single SignalFlow output timeseries.
+type stream struct {
+	Properties map[string]interface{}
+	Values     []float64
+}
+
+// property returns the string value of a metadata property.
+func (s *stream) property(name string) string {
+	if v, ok := s.Properties[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+
+	return ""
+}
+
+// message is a SignalFlow server-sent event.
+type message struct {
+	event string
+	data  []byte
+}
+
+// metadataMessage is the data of a SignalFlow metadata message.
+type metadataMessage struct {
+	TSID       string                 `json:"tsId"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// dataMessage is the data of a SignalFlow data message.
+type dataMessage struct {
+	Data []struct {
+		TSID  string   `json:"tsId"`
+		Value *float64 `json:"value"`
+	} `json:"data"`
+}
+
+// controlMessage is the data of a SignalFlow control message.
+type controlMessage struct {
+	Event string `json:"event"`
+}
+
+// errorMessage is the data of a SignalFlow error message.
+type errorMessage struct {
+	Message string `json:"message"`
+}
+
+// event is an ingest API event.
+type event struct {
+	Category   string            `json:"category"`
+	EventType  string            `json:"eventType"`
+	Dimensions map[string]string `json:"dimensions"`
+	Properties map[string]string `json:"properties"`
+	Timestamp  int64             `json:"timestamp"`
+}
+
+// execute runs a SignalFlow program over the window, in seconds relative to
+// now, and returns the output streams.
+func (h *sfxHandler) execute(ctx context.Context, program string, window int) ([]*stream, error) {
+	now := time.Now()
+	start := now.Add(-time.Duration(window) * time.Second)
+
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("stop", strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("resolution", strconv.Itoa(h.resolution*1000))
+	params.Set("immediate", "true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.streamURL+"/v2/signalflow/execute?"+params.Encode(), strings.NewReader(program))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readStreams(resp.Body)
+}
+
+// readStreams reads SignalFlow messages until the end of the channel and
+// returns the output streams.
+func readStreams(r io.Reader) ([]*stream, error) {
+	var streams []*stream
+	byTSID := map[string]*stream{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for {
+		m, ok := nextMessage(scanner)
+		if !ok {
+			break
+		}
+
+		switch m.event {
+		case "metadata":
+			md := metadataMessage{}
+			if err := json.Unmarshal(m.data, &md); err != nil {
+				return nil, fmt.Errorf("error parsing metadata message: %s", err)
+			}
+			s := &stream{Properties: md.Properties}
+			byTSID[md.TSID] = s
+			streams = append(streams, s)
+		case "data":
+			dm := dataMessage{}
+			if err := json.Unmarshal(m.data, &dm); err != nil {
+				return nil, fmt.Errorf("error parsing data message: %s", err)
+			}
+			for _, d := range dm.Data {
+				if s, ok := byTSID[d.TSID]; ok && d.Value != nil {
+					s.Values = append(s.Values, *d.Value)
+				}
+			}
+		case "error":
+			em := errorMessage{}
+			json.Unmarshal(m.data, &em)
+			return nil, fmt.Errorf("signalflow error: %s", em.Message)
+		case "control-message":
+			cm := controlMessage{}
+			json.Unmarshal(m.data, &cm)
+			if cm.Event == "END_OF_CHANNEL" {
+				return streams, nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return streams, nil
+}
+
+// nextMessage reads the next server-sent event. False is returned if no
+// further messages are available.
+func nextMessage(scanner *bufio.Scanner) (message, bool) {
+	var m message
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if m.event != "" || len(m.data) > 0 {
+				return m, true
+			}
+		case strings.HasPrefix(line, "event:"):
+			m.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if len(m.data) > 0 {
+				m.data = append(m.data, '\n')
+			}
+			m.data = append(m.data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+
+	return m, m.event != "" || len(m.data) > 0
+}
+
+// sendEvent writes an event to the ingest API.
+func (h *sfxHandler) sendEvent(ctx context.Context, e *event) error {
+	b, err := json.Marshal([]*event{e})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.ingestURL+"/v2/event", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// validate verifies credentials with a metric metadata request.
+func (h *sfxHandler) validate() error {
+	req, err := http.NewRequest(http.MethodGet, h.apiURL+"/v2/metric?limit=1", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// do issues an authenticated request. Non-2xx responses are returned
+// as errors.
+func (h *sfxHandler) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-SF-Token", h.token)
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("%s", e.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,272 @@
+// Package signalfx implements
+// a kafkametrics Handler.
+package signalfx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// Realm is the Splunk Observability realm. Defaults to "us0".
+	Realm string
+	// Token is an access token with API and ingest scopes.
+	Token string
+	// NetworkTXProgram is a SignalFlow program that should publish the
+	// outbound network throughput in bytes/s for the reference Kafka brokers,
+	// one stream per broker.
+	// Example: "data('kafka.server.BrokerTopicMetrics.BytesOutPerSec', filter=filter('cluster', 'my-cluster')).mean(by=['broker_id', 'host']).publish()"
+	NetworkTXProgram string
+	// NetworkRXProgram is the inbound network equivalent of NetworkTXProgram.
+	NetworkRXProgram string
+	// BrokerIDDimension is the dimension name for Kafka broker IDs.
+	BrokerIDDimension string
+	// InstanceTypeDimension is the dimension name for the Kafka broker's
+	// instance type.
+	InstanceTypeDimension string
+	// HostDimension is the dimension name for the Kafka broker's hostname.
+	// Defaults to "host".
+	HostDimension string
+	// Resolution is the program resolution in seconds. Defaults to 60.
+	Resolution int
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// EventType is the event type name used for events. Defaults to
+	// "kafka-kit".
+	EventType string
+}
+
+type sfxHandler struct {
+	c                     *http.Client
+	apiURL                string
+	streamURL             string
+	ingestURL             string
+	token                 string
+	programs              []string
+	brokerIDDimension     string
+	instanceTypeDimension string
+	hostDimension         string
+	resolution            int
+	metricsWindow         int
+	eventType             string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.Token == "":
+		return nil, errors.New("token must be specified")
+	case c.NetworkTXProgram == "", c.NetworkRXProgram == "":
+		return nil, errors.New("network tx and rx programs must be specified")
+	case c.BrokerIDDimension == "":
+		return nil, errors.New("broker ID dimension must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	realm := "us0"
+	if c.Realm != "" {
+		realm = c.Realm
+	}
+
+	h := &sfxHandler{
+		c:                     &http.Client{Timeout: 60 * time.Second},
+		apiURL:                fmt.Sprintf(apiURLFormat, realm),
+		streamURL:             fmt.Sprintf(streamURLFormat, realm),
+		ingestURL:             fmt.Sprintf(ingestURLFormat, realm),
+		token:                 c.Token,
+		programs:              []string{c.NetworkTXProgram, c.NetworkRXProgram},
+		brokerIDDimension:     c.BrokerIDDimension,
+		instanceTypeDimension: c.InstanceTypeDimension,
+		hostDimension:         "host",
+		resolution:            60,
+		metricsWindow:         c.MetricsWindow,
+		eventType:             "kafka-kit",
+	}
+
+	if c.HostDimension != "" {
+		h.hostDimension = c.HostDimension
+	}
+
+	if c.Resolution > 0 {
+		h.resolution = c.Resolution
+	}
+
+	if c.EventType != "" {
+		h.eventType = c.EventType
+	}
+
+	// Validate.
+	if err := h.validate(); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent sends a custom event to the ingest API. "key:value" event tags
+// are sent as event dimensions.
+func (h *sfxHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *sfxHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	dims := map[string]string{}
+	for _, t := range e.Tags {
+		if kv := strings.SplitN(t, ":", 2); len(kv) == 2 {
+			dims[kv[0]] = kv[1]
+		}
+	}
+
+	ev := &event{
+		Category:   "USER_DEFINED",
+		EventType:  h.eventType,
+		Dimensions: dims,
+		Properties: map[string]string{
+			"title": e.Title,
+			"text":  e.Text,
+		},
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+
+	if err := h.sendEvent(ctx, ev); err != nil {
+		return &kafkametrics.APIError{
+			Request: "send event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics executes the SignalFlow programs for broker metrics and returns
+// a BrokerMetrics. If any errors are encountered (i.e. complete metadata for
+// a given broker can't be retrieved), the broker will not be included in
+// the BrokerMetrics.
+func (h *sfxHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *sfxHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, program := range h.programs {
+		streams, err := h.execute(ctx, program, h.metricsWindow)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "signalflow execute",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(streams) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with program %s", program),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, streams, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of streams.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and []*stream and populates
+// the BrokerMetrics with the window average for the metric type. The number
+// of brokers populated is returned along with any errors.
+func (h *sfxHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, streams []*stream, metricType int) (int, []error) {
+	var errors []error
+	var missingDims bytes.Buffer
+	var n int
+
+	for _, s := range streams {
+		host := s.property(h.hostDimension)
+
+		id, err := strconv.Atoi(s.property(h.brokerIDDimension))
+		if err != nil {
+			missingDims.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDDimension, host))
+			continue
+		}
+
+		var it string
+		if h.instanceTypeDimension != "" {
+			if it = s.property(h.instanceTypeDimension); it == "" {
+				missingDims.WriteString(fmt.Sprintf(" %s:%s", h.instanceTypeDimension, host))
+				continue
+			}
+		}
+
+		if len(s.Values) == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", host),
+			})
+			continue
+		}
+
+		var sum float64
+		for _, v := range s.Values {
+			sum += v
+		}
+		avg := sum / float64(len(s.Values))
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         host,
+				InstanceType: it,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingDims.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing dimensions:%s", missingDims.String()),
+		})
+	}
+
+	return n, errors
+}
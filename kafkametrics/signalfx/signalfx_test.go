@@ -0,0 +1,174 @@
+package signalfx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestExecute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		q := req.URL.Query()
+
+		if req.URL.Path != "/v2/signalflow/execute" || string(body) != "tx" || q.Get("resolution") != "60000" || q.Get("immediate") != "true" {
+			t.Errorf("Unexpected request %s %s\n", req.URL, body)
+		}
+
+		if req.Header.Get("X-SF-Token") != "token" {
+			t.Errorf("Unexpected token %s\n", req.Header.Get("X-SF-Token"))
+		}
+
+		fmt.Fprint(w, "event: control-message\ndata: {\"event\": \"STREAM_START\"}\n\n"+
+			"event: metadata\ndata: {\"tsId\": \"AAA\", \"properties\": {\"host\": \"kafka-0\", \"broker_id\": 1000}}\n\n"+
+			"event: metadata\ndata: {\"tsId\": \"AAB\", \"properties\": {\"host\": \"kafka-1\", \"broker_id\": \"1001\"}}\n\n"+
+			"event: data\ndata: {\"data\": [{\"tsId\": \"AAA\", \"value\": 1048576},\n"+
+			"data: {\"tsId\": \"AAB\", \"value\": null}, {\"tsId\": \"AAC\", \"value\": 1}]}\n\n"+
+			"event: data\ndata: {\"data\": [{\"tsId\": \"AAA\", \"value\": 3145728}]}\n\n"+
+			"event: control-message\ndata: {\"event\": \"END_OF_CHANNEL\"}\n\n")
+	}))
+	defer srv.Close()
+
+	h := &sfxHandler{c: srv.Client(), streamURL: srv.URL, token: "token", resolution: 60}
+
+	streams, err := h.execute(context.Background(), "tx", 120)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != 2 {
+		t.Fatalf("Expected 2 streams, got %d\n", len(streams))
+	}
+
+	// Numeric properties are formatted; null values and values for unknown
+	// timeseries are skipped.
+	if s := streams[0]; s.property("broker_id") != "1000" || len(s.Values) != 2 || s.Values[1] != 3145728 {
+		t.Errorf("Unexpected stream %+v\n", s)
+	}
+
+	if s := streams[1]; s.property("host") != "kafka-1" || len(s.Values) != 0 {
+		t.Errorf("Unexpected stream %+v\n", s)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		{http.StatusUnauthorized, `{"message":"unauthorized"}`, "unauthorized"},
+		{http.StatusServiceUnavailable, "Service Unavailable\n", "unexpected status 503: Service Unavailable"},
+		{
+			http.StatusOK,
+			"event: error\ndata: {\"message\": \"bad program\"}\n\n",
+			"signalflow error: bad program",
+		},
+		{
+			http.StatusOK,
+			"event: metadata\ndata: {\"tsId\": \n\n",
+			"error parsing metadata message: unexpected end of JSON input",
+		},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &sfxHandler{c: srv.Client(), streamURL: srv.URL}
+
+		if _, err := h.execute(context.Background(), "tx", 60); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &sfxHandler{brokerIDDimension: "broker_id", instanceTypeDimension: "instance_type", hostDimension: "host"}
+
+	streams := []*stream{
+		{
+			Properties: map[string]interface{}{"host": "kafka-0", "broker_id": "1000", "instance_type": "stub"},
+			Values:     []float64{1048576, 3145728},
+		},
+		{Properties: map[string]interface{}{"host": "kafka-1", "broker_id": "1001", "instance_type": "stub"}},
+		{Properties: map[string]interface{}{"host": "kafka-2", "broker_id": "1002"}, Values: []float64{1}},
+		{Properties: map[string]interface{}{"host": "kafka-9", "instance_type": "stub"}, Values: []float64{1}},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, streams, 0)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetTX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-1",
+		"Missing dimensions: instance_type:kafka-2 broker_id:kafka-9",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	events := make(chan *event, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var e []*event
+		json.NewDecoder(req.Body).Decode(&e)
+		events <- e[0]
+	}))
+	defer srv.Close()
+
+	h := &sfxHandler{c: srv.Client(), ingestURL: srv.URL, eventType: "kafka-kit"}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle", "untagged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags without values aren't sent as dimensions.
+	e := <-events
+	if e.EventType != "kafka-kit" || e.Properties["title"] != "title" || len(e.Dimensions) != 1 || e.Dimensions["name"] != "kafka-autothrottle" {
+		t.Errorf("Unexpected event %+v\n", e)
+	}
+}
+
+func TestReadStreams(t *testing.T) {
+	// Streams without an end of channel message end at EOF.
+	r := strings.NewReader("event: metadata\ndata: {\"tsId\": \"AAA\", \"properties\": {}}\n\n" +
+		"event: data\ndata: {\"data\": [{\"tsId\": \"AAA\", \"value\": 1}]}")
+
+	streams, err := readStreams(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != 1 || len(streams[0].Values) != 1 {
+		t.Errorf("Unexpected streams %+v\n", streams)
+	}
+}
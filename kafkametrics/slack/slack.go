@@ -0,0 +1,248 @@
+// Package slack implements
+// a kafkametrics Handler.
+//
+// The Handler is an event sink; events are posted to a Slack channel and no
+// metrics are provided.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+const (
+	apiURL = "https://slack.com/api"
+)
+
+// Config holds Handler configuration parameters. Either a WebhookURL or
+// a Token and Channel must be specified.
+type Config struct {
+	// WebhookURL is a Slack incoming webhook URL.
+	WebhookURL string
+	// Token is a Slack bot token used with the chat.postMessage API.
+	Token string
+	// Channel is the channel ID or name that messages are posted to with
+	// the chat.postMessage API.
+	Channel string
+	// Username and IconEmoji optionally override the message author name
+	// and icon.
+	Username  string
+	IconEmoji string
+}
+
+type slackHandler struct {
+	c          *http.Client
+	apiURL     string
+	webhookURL string
+	token      string
+	channel    string
+	username   string
+	iconEmoji  string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.WebhookURL == "" && c.Token == "":
+		return nil, errors.New("one of webhook URL or token must be specified")
+	case c.WebhookURL != "" && c.Token != "":
+		return nil, errors.New("only one of webhook URL or token may be specified")
+	case c.Token != "" && c.Channel == "":
+		return nil, errors.New("channel must be specified with a token")
+	}
+
+	h := &slackHandler{
+		c:          &http.Client{Timeout: 10 * time.Second},
+		apiURL:     apiURL,
+		webhookURL: c.WebhookURL,
+		token:      c.Token,
+		channel:    c.Channel,
+		username:   c.Username,
+		iconEmoji:  c.IconEmoji,
+	}
+
+	// Validate. Incoming webhooks can't be validated without posting
+	// a message.
+	if h.token != "" {
+		if err := h.call(context.Background(), "auth.test", struct{}{}); err != nil {
+			return nil, &kafkametrics.APIError{
+				Request: "validate credentials",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return h, nil
+}
+
+// GetMetrics isn't supported by the Slack Handler and always
+// returns a NoResults error.
+func (h *slackHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return nil, []error{&kafkametrics.NoResults{
+		Message: "The slack handler doesn't provide metrics",
+	}}
+}
+
+// GetMetricsContext isn't supported; see GetMetrics.
+func (h *slackHandler) GetMetricsContext(context.Context) (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetrics()
+}
+
+// PostEvent posts an event as a Slack message.
+func (h *slackHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *slackHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	m := formatMessage(e)
+	m.Username = h.username
+	m.IconEmoji = h.iconEmoji
+
+	var err error
+	if h.webhookURL != "" {
+		err = h.postWebhook(ctx, m)
+	} else {
+		m.Channel = h.channel
+		err = h.call(ctx, "chat.postMessage", m)
+	}
+
+	if err != nil {
+		return &kafkametrics.APIError{
+			Request: "post message",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+type message struct {
+	Channel   string  `json:"channel,omitempty"`
+	Text      string  `json:"text"`
+	Blocks    []block `json:"blocks"`
+	Username  string  `json:"username,omitempty"`
+	IconEmoji string  `json:"icon_emoji,omitempty"`
+}
+
+type block struct {
+	Type     string  `json:"type"`
+	Text     *text   `json:"text,omitempty"`
+	Elements []*text `json:"elements,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// formatMessage returns a message with the event title in bold, followed by
+// the event text and a context line of event tags. The title is used as the
+// notification text.
+func formatMessage(e *kafkametrics.Event) *message {
+	m := &message{
+		Text: e.Title,
+		Blocks: []block{{
+			Type: "section",
+			Text: &text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", escape(e.Title), escape(e.Text))},
+		}},
+	}
+
+	if len(e.Tags) > 0 {
+		tags := make([]string, len(e.Tags))
+		for i, t := range e.Tags {
+			tags[i] = "`" + escape(t) + "`"
+		}
+
+		m.Blocks = append(m.Blocks, block{
+			Type:     "context",
+			Elements: []*text{{Type: "mrkdwn", Text: strings.Join(tags, " ")}},
+		})
+	}
+
+	return m
+}
+
+// escape escapes the Slack mrkdwn control characters.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// postWebhook posts a message to the incoming webhook.
+func (h *slackHandler) postWebhook(ctx context.Context, m *message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(d))
+	}
+
+	return nil
+}
+
+// call calls a Web API method with the token, returning any error reported
+// in the response.
+func (h *slackHandler) call(ctx context.Context, method string, params interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", h.apiURL, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+
+	if !r.OK {
+		return errors.New(r.Error)
+	}
+
+	return nil
+}
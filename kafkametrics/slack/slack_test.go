@@ -0,0 +1,125 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+var event = &kafkametrics.Event{
+	Title: "throttle set",
+	Text:  "rate <100>",
+	Tags:  []string{"name:kafka-autothrottle"},
+}
+
+func TestPostEventWebhook(t *testing.T) {
+	srv, msgs := stubServer()
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{WebhookURL: srv.URL + "/webhook", Username: "autothrottle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.PostEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	m := <-msgs
+	if m.Text != "throttle set" || m.Username != "autothrottle" || m.Channel != "" {
+		t.Errorf("Unexpected message %+v\n", m)
+	}
+
+	if len(m.Blocks) != 2 {
+		t.Fatalf("Expected 2 blocks, got %d\n", len(m.Blocks))
+	}
+
+	if expected := "*throttle set*\nrate &lt;100&gt;"; m.Blocks[0].Text.Text != expected {
+		t.Errorf("Expected text %q, got %q\n", expected, m.Blocks[0].Text.Text)
+	}
+
+	if expected := "`name:kafka-autothrottle`"; m.Blocks[1].Elements[0].Text != expected {
+		t.Errorf("Expected tags %q, got %q\n", expected, m.Blocks[1].Elements[0].Text)
+	}
+}
+
+func TestPostEventAPI(t *testing.T) {
+	srv, msgs := stubServer()
+	defer srv.Close()
+
+	h := stubHandler(srv.URL)
+
+	if err := h.PostEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := <-msgs; m.Channel != "C123" {
+		t.Errorf("Expected channel C123, got %s\n", m.Channel)
+	}
+
+	h.token = "invalid"
+	if _, ok := h.PostEvent(event).(*kafkametrics.APIError); !ok {
+		t.Error("Expected APIError")
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	for _, c := range []*Config{
+		{},
+		{WebhookURL: "http://localhost", Token: "token"},
+		{Token: "token"},
+	} {
+		if _, err := NewHandler(c); err == nil {
+			t.Errorf("Expected error for config %+v\n", c)
+		}
+	}
+}
+
+func stubHandler(url string) *slackHandler {
+	return &slackHandler{
+		c:       &http.Client{Timeout: 5 * time.Second},
+		apiURL:  url + "/api",
+		token:   "token",
+		channel: "C123",
+	}
+}
+
+// stubServer returns a test server that accepts incoming webhook and
+// chat.postMessage requests. Posted messages are sent to the returned
+// channel.
+func stubServer() (*httptest.Server, chan *message) {
+	msgs := make(chan *message, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		switch req.URL.Path {
+		case "/webhook":
+		case "/api/chat.postMessage":
+			if req.Header.Get("Authorization") != "Bearer token" {
+				fmt.Fprint(w, `{"ok":false,"error":"invalid_auth"}`)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		m := &message{}
+		if err := json.Unmarshal(body, m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msgs <- m
+
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+
+	return srv, msgs
+}
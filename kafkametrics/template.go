@@ -0,0 +1,55 @@
+package kafkametrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// QueryVars are the variables available to Go template metrics queries,
+// allowing one query configuration to serve multiple clusters and
+// environments.
+// Example: "avg:system.net.bytes_sent{service:kafka,cluster:{{.Cluster}}} by {host}"
+type QueryVars struct {
+	// Cluster is the Kafka cluster name.
+	Cluster string
+	// Window is the metrics window in seconds.
+	Window int
+	// Vars are any additional variables, referenced as {{.Vars.name}}.
+	Vars map[string]string
+}
+
+// RenderQuery takes a query string and QueryVars and returns the rendered
+// query. Queries without template actions are returned unchanged. References
+// to unset Vars are an error.
+func RenderQuery(q string, v QueryVars) (string, error) {
+	if !strings.Contains(q, "{{") {
+		return q, nil
+	}
+
+	t, err := template.New("query").Option("missingkey=error").Parse(q)
+	if err != nil {
+		return "", fmt.Errorf("invalid query template %q: %s", q, err)
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, v); err != nil {
+		return "", fmt.Errorf("failed to render query %q: %s", q, err)
+	}
+
+	return b.String(), nil
+}
+
+// RenderQueries renders each query of the []string in place with RenderQuery.
+func RenderQueries(qs []string, v QueryVars) error {
+	for i, q := range qs {
+		r, err := RenderQuery(q, v)
+		if err != nil {
+			return err
+		}
+		qs[i] = r
+	}
+
+	return nil
+}
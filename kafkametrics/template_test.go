@@ -0,0 +1,46 @@
+package kafkametrics
+
+import (
+	"testing"
+)
+
+func TestRenderQuery(t *testing.T) {
+	v := QueryVars{
+		Cluster: "kafka-a",
+		Window:  120,
+		Vars:    map[string]string{"env": "prod"},
+	}
+
+	tests := map[string]string{
+		"avg:system.net.bytes_sent{service:kafka} by {host}":                            "avg:system.net.bytes_sent{service:kafka} by {host}",
+		"avg:system.net.bytes_sent{cluster:{{.Cluster}},env:{{.Vars.env}}} by {host}":   "avg:system.net.bytes_sent{cluster:kafka-a,env:prod} by {host}",
+		`rate(node_network_transmit_bytes_total{cluster="{{.Cluster}}"}[{{.Window}}s])`: `rate(node_network_transmit_bytes_total{cluster="kafka-a"}[120s])`,
+	}
+
+	for q, expected := range tests {
+		r, err := RenderQuery(q, v)
+		if err != nil {
+			t.Errorf("Unexpected error: %s\n", err)
+		}
+		if r != expected {
+			t.Errorf("Expected %s, got %s\n", expected, r)
+		}
+	}
+
+	for _, q := range []string{"{{.Vars.region}}", "{{.Cluster"} {
+		if _, err := RenderQuery(q, v); err == nil {
+			t.Errorf("Expected error for query %s\n", q)
+		}
+	}
+}
+
+func TestRenderQueries(t *testing.T) {
+	qs := []string{"{{.Cluster}}", ""}
+	if err := RenderQueries(qs, QueryVars{Cluster: "kafka-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if qs[0] != "kafka-a" || qs[1] != "" {
+		t.Errorf("Unexpected rendered queries %v\n", qs)
+	}
+}
@@ -0,0 +1,119 @@
+package wavefront
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chartResponse is a chart API query response.
+type chartResponse struct {
+	Timeseries []timeseries `json:"timeseries"`
+	Warnings   string       `json:"warnings"`
+}
+
+// timeseries is a single timeseries query result. Host is the
+// Wavefront source.
+type timeseries struct {
+	Label string            `json:"label"`
+	Host  string            `json:"host"`
+	Tags  map[string]string `json:"tags"`
+	Data  [][2]float64      `json:"data"`
+}
+
+// event is an event API request body.
+type event struct {
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations"`
+	Tags        []string          `json:"tags,omitempty"`
+	StartTime   int64             `json:"startTime"`
+	EndTime     int64             `json:"endTime"`
+}
+
+// query runs a ts() query over the window, in seconds relative to now.
+func (h *wfHandler) query(ctx context.Context, q string, window int) (*chartResponse, error) {
+	start := time.Now().Add(-time.Duration(window) * time.Second)
+
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("s", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+	params.Set("g", h.granularity)
+	params.Set("strict", "true")
+
+	body, err := h.do(ctx, http.MethodGet, "/api/v2/chart/api?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &chartResponse{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, fmt.Errorf("error parsing query response: %s", err)
+	}
+
+	return r, nil
+}
+
+// createEvent posts an event.
+func (h *wfHandler) createEvent(ctx context.Context, e *event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.do(ctx, http.MethodPost, "/api/v2/event", b)
+
+	return err
+}
+
+// do issues an authenticated request to the API path, returning the
+// response body.
+func (h *wfHandler) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	d, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Status struct {
+				Message string `json:"message"`
+			} `json:"status"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(d, &e) == nil {
+			if e.Status.Message != "" {
+				return nil, fmt.Errorf("%s", e.Status.Message)
+			}
+			if e.Message != "" {
+				return nil, fmt.Errorf("%s", e.Message)
+			}
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(d)))
+	}
+
+	return d, nil
+}
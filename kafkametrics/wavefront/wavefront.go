@@ -0,0 +1,244 @@
+// Package wavefront implements
+// a kafkametrics Handler.
+package wavefront
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the Wavefront instance address.
+	// Example: "https://example.wavefront.com"
+	URL string
+	// Token is a Wavefront API token.
+	Token string
+	// NetworkTXQuery is a WQL query that should return the outbound network
+	// throughput in bytes/s for the reference Kafka brokers, one series
+	// per broker.
+	// Example: "rate(ts(kafka.server.BrokerTopicMetrics.BytesOutPerSec.count, cluster=my-cluster))"
+	NetworkTXQuery string
+	// NetworkRXQuery is the inbound network equivalent of NetworkTXQuery.
+	NetworkRXQuery string
+	// BrokerIDTag is the point tag name for Kafka broker IDs.
+	BrokerIDTag string
+	// InstanceTypeTag is the point tag name for the Kafka broker's
+	// instance type.
+	InstanceTypeTag string
+	// Granularity is the query granularity, one of "s", "m", "h" or "d".
+	// Defaults to "m".
+	Granularity string
+	// MetricsWindow specifies the window size of timeseries data to evaluate
+	// in seconds. All values for the window are averaged.
+	MetricsWindow int
+	// EventTags are tags applied to all events.
+	EventTags []string
+}
+
+type wfHandler struct {
+	c               *http.Client
+	url             string
+	token           string
+	queries         []string
+	brokerIDTag     string
+	instanceTypeTag string
+	granularity     string
+	metricsWindow   int
+	eventTags       []string
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration or credential validation errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "" || c.Token == "":
+		return nil, errors.New("wavefront URL and token must be specified")
+	case c.NetworkTXQuery == "", c.NetworkRXQuery == "":
+		return nil, errors.New("network tx and rx queries must be specified")
+	case c.BrokerIDTag == "":
+		return nil, errors.New("broker ID tag must be specified")
+	case c.MetricsWindow <= 0:
+		return nil, errors.New("metrics window must be > 0")
+	}
+
+	h := &wfHandler{
+		c:               &http.Client{Timeout: 30 * time.Second},
+		url:             strings.TrimSuffix(c.URL, "/"),
+		token:           c.Token,
+		queries:         []string{c.NetworkTXQuery, c.NetworkRXQuery},
+		brokerIDTag:     c.BrokerIDTag,
+		instanceTypeTag: c.InstanceTypeTag,
+		granularity:     "m",
+		metricsWindow:   c.MetricsWindow,
+		eventTags:       c.EventTags,
+	}
+
+	if c.Granularity != "" {
+		h.granularity = c.Granularity
+	}
+
+	// Validate.
+	if _, err := h.query(context.Background(), "1", 60); err != nil {
+		return nil, &kafkametrics.APIError{
+			Request: "validate credentials",
+			Message: err.Error(),
+		}
+	}
+
+	return h, nil
+}
+
+// PostEvent posts an instantaneous event to the Wavefront API.
+func (h *wfHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. The request
+// is cancelled if the context is done.
+func (h *wfHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	ev := &event{
+		Name: e.Title,
+		Annotations: map[string]string{
+			"severity": "info",
+			"type":     "kafka-kit",
+			"details":  e.Text,
+		},
+		Tags:      append(append([]string{}, h.eventTags...), e.Tags...),
+		StartTime: now,
+		EndTime:   now + 1,
+	}
+
+	if err := h.createEvent(ctx, ev); err != nil {
+		return &kafkametrics.APIError{
+			Request: "post event",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics requests broker metrics and metadata from the Wavefront API and
+// returns a BrokerMetrics. If any errors are encountered (i.e. complete
+// metadata for a given broker can't be retrieved), the broker will not
+// be included in the BrokerMetrics.
+func (h *wfHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetricsContext(context.Background())
+}
+
+// GetMetricsContext is the context-aware equivalent of GetMetrics. Requests
+// are cancelled if the context is done.
+func (h *wfHandler) GetMetricsContext(ctx context.Context) (kafkametrics.BrokerMetrics, []error) {
+	var errors []error
+	bm := kafkametrics.BrokerMetrics{}
+
+	// Get network metrics for tx and rx.
+	var lastLen int
+	for i, query := range h.queries {
+		r, err := h.query(ctx, query, h.metricsWindow)
+		if err != nil {
+			return nil, []error{&kafkametrics.APIError{
+				Request: "chart query",
+				Message: err.Error(),
+			}}
+		}
+
+		if len(r.Timeseries) == 0 {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: fmt.Sprintf("No data returned with query %s", query),
+			}}
+		}
+
+		n, errs := h.populateBrokerMetrics(bm, r.Timeseries, i)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+
+		// We received a different number of series.
+		if i > 0 && n != lastLen {
+			return nil, []error{&kafkametrics.NoResults{
+				Message: "Failed to fetch complete metrics for brokers",
+			}}
+		}
+
+		lastLen = n
+	}
+
+	return bm, errors
+}
+
+// populateBrokerMetrics takes a BrokerMetrics and []timeseries and populates
+// the BrokerMetrics with the window average for the metric type. The number
+// of brokers populated is returned along with any errors.
+func (h *wfHandler) populateBrokerMetrics(bm kafkametrics.BrokerMetrics, ts []timeseries, metricType int) (int, []error) {
+	var errors []error
+	var missingTags bytes.Buffer
+	var n int
+
+	for _, s := range ts {
+		id, err := strconv.Atoi(s.Tags[h.brokerIDTag])
+		if err != nil {
+			missingTags.WriteString(fmt.Sprintf(" %s:%s", h.brokerIDTag, s.Host))
+			continue
+		}
+
+		var it string
+		if h.instanceTypeTag != "" {
+			if it = s.Tags[h.instanceTypeTag]; it == "" {
+				missingTags.WriteString(fmt.Sprintf(" %s:%s", h.instanceTypeTag, s.Host))
+				continue
+			}
+		}
+
+		if len(s.Data) == 0 {
+			errors = append(errors, &kafkametrics.PartialResults{
+				Message: fmt.Sprintf("No points for host %s", s.Host),
+			})
+			continue
+		}
+
+		var sum float64
+		for _, p := range s.Data {
+			sum += p[1]
+		}
+		avg := sum / float64(len(s.Data))
+
+		b, exists := bm[id]
+		if !exists {
+			b = &kafkametrics.Broker{
+				ID:           id,
+				Host:         s.Host,
+				InstanceType: it,
+			}
+			bm[id] = b
+		}
+
+		switch metricType {
+		case 0:
+			b.NetTX = avg / 1024 / 1024
+		case 1:
+			b.NetRX = avg / 1024 / 1024
+		}
+
+		n++
+	}
+
+	if missingTags.String() != "" {
+		errors = append(errors, &kafkametrics.PartialResults{
+			Message: fmt.Sprintf("Missing point tags:%s", missingTags.String()),
+		})
+	}
+
+	return n, errors
+}
@@ -0,0 +1,143 @@
+package wavefront
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if req.URL.Path != "/api/v2/chart/api" || q.Get("q") != "ts(kafka.net.bytes_out)" || q.Get("g") != "m" || q.Get("strict") != "true" {
+			t.Errorf("Unexpected request %s\n", req.URL)
+		}
+
+		if req.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected authorization %s\n", req.Header.Get("Authorization"))
+		}
+
+		fmt.Fprint(w, `{"name":"ts(kafka.net.bytes_out)","granularity":60,"timeseries":[{"label":"kafka.net.bytes_out",`+
+			`"host":"kafka-0","tags":{"broker_id":"1000"},"data":[[1600000000,1048576],[1600000060,3145728]]}],`+
+			`"warnings":"Query may be slow"}`)
+	}))
+	defer srv.Close()
+
+	h := &wfHandler{c: srv.Client(), url: srv.URL, token: "token", granularity: "m"}
+
+	r, err := h.query(context.Background(), "ts(kafka.net.bytes_out)", 120)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Timeseries) != 1 || r.Warnings != "Query may be slow" {
+		t.Fatalf("Unexpected response %+v\n", r)
+	}
+
+	if ts := r.Timeseries[0]; ts.Host != "kafka-0" || ts.Tags["broker_id"] != "1000" || len(ts.Data) != 2 || ts.Data[1][1] != 3145728 {
+		t.Errorf("Unexpected timeseries %+v\n", ts)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected string
+	}{
+		// Chart API status messages and API error messages are returned.
+		{
+			http.StatusBadRequest,
+			`{"status":{"result":"ERROR","message":"Query syntax error: Missing expression","code":400}}`,
+			"Query syntax error: Missing expression",
+		},
+		{http.StatusUnauthorized, `{"message":"unauthorized","code":401}`, "unauthorized"},
+		{http.StatusBadGateway, "<html>Bad Gateway</html>", "unexpected status 502: <html>Bad Gateway</html>"},
+		{http.StatusOK, "<html></html>", "error parsing query response: invalid character '<' looking for beginning of value"},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		h := &wfHandler{c: srv.Client(), url: srv.URL}
+
+		if _, err := h.query(context.Background(), "ts(tx)", 60); err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s', got %v\n", test.expected, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestPopulateBrokerMetrics(t *testing.T) {
+	h := &wfHandler{brokerIDTag: "broker_id", instanceTypeTag: "instance_type"}
+
+	ts := []timeseries{
+		{Host: "kafka-0", Tags: map[string]string{"broker_id": "1000", "instance_type": "stub"}, Data: [][2]float64{{1, 1048576}, {2, 3145728}}},
+		{Host: "kafka-1", Tags: map[string]string{"broker_id": "1001", "instance_type": "stub"}},
+		{Host: "kafka-2", Tags: map[string]string{"broker_id": "1002"}, Data: [][2]float64{{1, 1048576}}},
+		{Host: "kafka-9", Tags: map[string]string{"instance_type": "stub"}, Data: [][2]float64{{1, 1048576}}},
+	}
+
+	bm := kafkametrics.BrokerMetrics{}
+	n, errs := h.populateBrokerMetrics(bm, ts, 1)
+
+	if b := bm[1000]; n != 1 || b == nil || b.NetRX != 2.00 || b.Host != "kafka-0" || b.InstanceType != "stub" {
+		t.Errorf("Unexpected results: %d brokers, %+v\n", n, bm[1000])
+	}
+
+	expected := []string{
+		"No points for host kafka-1",
+		"Missing point tags: instance_type:kafka-2 broker_id:kafka-9",
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %s\n", len(expected), len(errs), errs)
+	}
+
+	for i, err := range errs {
+		if e, ok := err.(*kafkametrics.PartialResults); !ok || e.Message != expected[i] {
+			t.Errorf("Expected PartialResults '%s', got %s\n", expected[i], err)
+		}
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	events := make(chan *event, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e := &event{}
+		json.NewDecoder(req.Body).Decode(e)
+		events <- e
+	}))
+	defer srv.Close()
+
+	h := &wfHandler{c: srv.Client(), url: srv.URL, eventTags: []string{"kafka"}}
+
+	err := h.PostEvent(&kafkametrics.Event{
+		Title: "title",
+		Text:  "text",
+		Tags:  []string{"name:kafka-autothrottle"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := <-events
+	if e.Name != "title" || e.Annotations["details"] != "text" || len(e.Tags) != 2 || e.Tags[0] != "kafka" {
+		t.Errorf("Unexpected event %+v\n", e)
+	}
+
+	// Events without an end time are ongoing.
+	if e.EndTime <= e.StartTime {
+		t.Errorf("Expected endTime after startTime")
+	}
+}
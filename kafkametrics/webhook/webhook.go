@@ -0,0 +1,239 @@
+// Package webhook implements
+// a kafkametrics Handler.
+//
+// The Handler is an event sink; events are sent to a webhook URL and no
+// metrics are provided.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+// Config holds Handler configuration parameters.
+type Config struct {
+	// URL is the webhook URL.
+	URL string
+	// Method is the HTTP request method. Defaults to "POST".
+	Method string
+	// Template is an optional text/template for the request body, executed
+	// with the *kafkametrics.Event. A json function is available to encode
+	// values as JSON. Defaults to the JSON encoded Event.
+	// Example: {"summary": {{json .Title}}, "details": {{json .Text}}}
+	Template string
+	// ContentType is the request Content-Type. Defaults to "application/json".
+	ContentType string
+	// Headers are additional request headers.
+	Headers map[string]string
+	// BearerToken is an optional bearer token for authentication.
+	BearerToken string
+	// Username and Password are optional basic auth credentials.
+	Username string
+	Password string
+	// Retries is the number of times a failed request is retried. Requests
+	// are retried on connection errors, 429 and 5xx responses. Defaults to 3;
+	// a negative value disables retries.
+	Retries int
+	// RetryBackoff is the initial delay between retries in milliseconds,
+	// doubled with each retry. Defaults to 500.
+	RetryBackoff int
+	// Timeout is the request timeout in seconds. Defaults to 10.
+	Timeout int
+}
+
+type webhookHandler struct {
+	c            *http.Client
+	url          string
+	method       string
+	template     *template.Template
+	contentType  string
+	headers      map[string]string
+	bearerToken  string
+	username     string
+	password     string
+	retries      int
+	retryBackoff time.Duration
+	retried      atomic.Uint64
+}
+
+// NewHandler takes a *Config and returns a Handler, along with any
+// configuration errors.
+func NewHandler(c *Config) (kafkametrics.Handler, error) {
+	switch {
+	case c.URL == "":
+		return nil, errors.New("webhook URL must be specified")
+	case c.BearerToken != "" && c.Username != "":
+		return nil, errors.New("only one of bearer token or basic auth may be specified")
+	}
+
+	h := &webhookHandler{
+		c:            &http.Client{Timeout: 10 * time.Second},
+		url:          c.URL,
+		method:       http.MethodPost,
+		contentType:  "application/json",
+		headers:      c.Headers,
+		bearerToken:  c.BearerToken,
+		username:     c.Username,
+		password:     c.Password,
+		retries:      3,
+		retryBackoff: 500 * time.Millisecond,
+	}
+
+	if c.Method != "" {
+		h.method = c.Method
+	}
+
+	if c.ContentType != "" {
+		h.contentType = c.ContentType
+	}
+
+	switch {
+	case c.Retries > 0:
+		h.retries = c.Retries
+	case c.Retries < 0:
+		h.retries = 0
+	}
+
+	if c.RetryBackoff > 0 {
+		h.retryBackoff = time.Duration(c.RetryBackoff) * time.Millisecond
+	}
+
+	if c.Timeout > 0 {
+		h.c.Timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	if c.Template != "" {
+		t, err := template.New("payload").Funcs(template.FuncMap{"json": toJSON}).Parse(c.Template)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing webhook template: %s", err)
+		}
+		h.template = t
+	}
+
+	return h, nil
+}
+
+// GetMetrics isn't supported by the webhook Handler and always
+// returns a NoResults error.
+func (h *webhookHandler) GetMetrics() (kafkametrics.BrokerMetrics, []error) {
+	return nil, []error{&kafkametrics.NoResults{
+		Message: "The webhook handler doesn't provide metrics",
+	}}
+}
+
+// GetMetricsContext isn't supported; see GetMetrics.
+func (h *webhookHandler) GetMetricsContext(context.Context) (kafkametrics.BrokerMetrics, []error) {
+	return h.GetMetrics()
+}
+
+// PostEvent sends an event to the webhook URL, retrying failed requests.
+func (h *webhookHandler) PostEvent(e *kafkametrics.Event) error {
+	return h.PostEventContext(context.Background(), e)
+}
+
+// PostEventContext is the context-aware equivalent of PostEvent. Requests are
+// cancelled, and no further retries are made, if the context is done.
+func (h *webhookHandler) PostEventContext(ctx context.Context, e *kafkametrics.Event) error {
+	body, err := h.payload(e)
+	if err != nil {
+		return err
+	}
+
+	backoff := h.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := h.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if !retry || attempt >= h.retries {
+			return &kafkametrics.APIError{
+				Request: "post webhook",
+				Message: err.Error(),
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return &kafkametrics.APIError{
+				Request: "post webhook",
+				Message: ctx.Err().Error(),
+			}
+		}
+
+		h.retried.Add(1)
+		backoff *= 2
+	}
+}
+
+// Retries returns the total number of retried requests.
+func (h *webhookHandler) Retries() uint64 {
+	return h.retried.Load()
+}
+
+// payload returns the request body for an event.
+func (h *webhookHandler) payload(e *kafkametrics.Event) ([]byte, error) {
+	if h.template == nil {
+		return json.Marshal(e)
+	}
+
+	var b bytes.Buffer
+	if err := h.template.Execute(&b, e); err != nil {
+		return nil, fmt.Errorf("error executing webhook template: %s", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// send issues a single webhook request. Whether a failed request may be
+// retried is returned along with any error.
+func (h *webhookHandler) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, h.method, h.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", h.contentType)
+
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case h.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+h.bearerToken)
+	case h.username != "":
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.c.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		d, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+		return retry, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(d))
+	}
+
+	return false, nil
+}
+
+// toJSON is a template function that returns the JSON encoding of v.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/kafka-kit/v4/kafkametrics"
+)
+
+var event = &kafkametrics.Event{
+	Title: "throttle set",
+	Text:  `rate "100"`,
+	Tags:  []string{"name:kafka-autothrottle"},
+}
+
+func TestPostEvent(t *testing.T) {
+	srv, reqs := stubServer(0)
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{
+		URL:         srv.URL,
+		BearerToken: "token",
+		Headers:     map[string]string{"X-Source": "autothrottle"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.PostEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-reqs
+	expected := `{"Title":"throttle set","Text":"rate \"100\"","Tags":["name:kafka-autothrottle"]}`
+	if r.body != expected {
+		t.Errorf("Expected body:\n%s\ngot:\n%s\n", expected, r.body)
+	}
+
+	if r.header.Get("Authorization") != "Bearer token" || r.header.Get("X-Source") != "autothrottle" {
+		t.Errorf("Unexpected headers %v\n", r.header)
+	}
+
+	if r.header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %s\n", r.header.Get("Content-Type"))
+	}
+}
+
+func TestPostEventTemplate(t *testing.T) {
+	srv, reqs := stubServer(0)
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{
+		URL:      srv.URL,
+		Template: `{"summary": {{json .Title}}, "details": {{json .Text}}, "tag": "{{index .Tags 0}}"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.PostEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-reqs
+	expected := `{"summary": "throttle set", "details": "rate \"100\"", "tag": "name:kafka-autothrottle"}`
+	if r.body != expected {
+		t.Errorf("Expected body:\n%s\ngot:\n%s\n", expected, r.body)
+	}
+}
+
+func TestPostEventRetries(t *testing.T) {
+	// Fail the first two requests.
+	srv, reqs := stubServer(2)
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{URL: srv.URL, RetryBackoff: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.PostEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reqs) != 3 {
+		t.Errorf("Expected 3 requests, got %d\n", len(reqs))
+	}
+
+	if n := h.(kafkametrics.RetryReporter).Retries(); n != 2 {
+		t.Errorf("Expected 2 retries, got %d\n", n)
+	}
+
+	// Retries disabled.
+	srv2, _ := stubServer(1)
+	defer srv2.Close()
+
+	h, err = NewHandler(&Config{URL: srv2.URL, Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := h.PostEvent(event).(*kafkametrics.APIError); !ok {
+		t.Error("Expected APIError")
+	}
+}
+
+func TestPostEventContext(t *testing.T) {
+	srv, reqs := stubServer(2)
+	defer srv.Close()
+
+	h, err := NewHandler(&Config{URL: srv.URL, RetryBackoff: 60000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The retry backoff is abandoned once the context is done.
+	if _, ok := h.(kafkametrics.ContextHandler).PostEventContext(ctx, event).(*kafkametrics.APIError); !ok {
+		t.Error("Expected APIError")
+	}
+
+	if len(reqs) != 1 {
+		t.Errorf("Expected 1 request, got %d\n", len(reqs))
+	}
+
+	if n := h.(kafkametrics.RetryReporter).Retries(); n != 0 {
+		t.Errorf("Expected 0 retries, got %d\n", n)
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	for _, c := range []*Config{
+		{},
+		{URL: "http://localhost", BearerToken: "token", Username: "user"},
+		{URL: "http://localhost", Template: "{{"},
+	} {
+		if _, err := NewHandler(c); err == nil {
+			t.Errorf("Expected error for config %+v\n", c)
+		}
+	}
+}
+
+type request struct {
+	header http.Header
+	body   string
+}
+
+// stubServer returns a test server that records requests to the returned
+// channel, responding with a 503 to the first failures requests.
+func stubServer(failures int) (*httptest.Server, chan request) {
+	reqs := make(chan request, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		reqs <- request{header: req.Header, body: string(body)}
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+
+	return srv, reqs
+}
@@ -33,6 +33,7 @@ type Handler interface {
 	GetPendingDeletion() ([]string, error)
 	GetTopics([]*regexp.Regexp) ([]string, error)
 	GetTopicConfig(string) (*TopicConfig, error)
+	GetBrokerConfig(int) (*KafkaConfigData, error)
 	GetTopicMetadata(string) (TopicMetadata, error)
 	GetAllBrokerMeta(bool) (mapper.BrokerMetaMap, []error)
 	GetAllPartitionMeta() (mapper.PartitionMetaMap, error)
@@ -47,6 +48,7 @@ type SimpleZooKeeperClient interface {
 	Exists(string) (bool, error)
 	Create(string, string) error
 	CreateSequential(string, string) error
+	CreateEphemeral(string, string) error
 	Set(string, string) error
 	Get(string) ([]byte, error)
 	Delete(string) error
@@ -67,7 +69,8 @@ type ZKHandler struct {
 // Config holds initialization paramaters for a Handler. Connect is a ZooKeeper
 // connect string. Prefix should reflect any prefix used for Kafka on the
 // reference ZooKeeper cluster (excluding slashes). MetricsPrefix is the prefix
-// used for broker metrics metadata persisted in ZooKeeper.
+// used for the broker metrics and partition size metadata persisted in
+// ZooKeeper, as written by metricsfetcher.
 type Config struct {
 	Connect       string
 	Prefix        string
@@ -162,6 +165,24 @@ func (z *ZKHandler) CreateSequential(p string, d string) error {
 	return err
 }
 
+// CreateEphemeral takes a path p and data d and creates an ephemeral znode at
+// p with data d. The znode is deleted by ZooKeeper when the session of the
+// *ZKHandler ends. An error is returned if encountered, including if the
+// znode exists.
+func (z *ZKHandler) CreateEphemeral(p string, d string) error {
+	_, e := z.client.Create(p, []byte(d), zkclient.FlagEphemeral, zkclient.WorldACL(31))
+	if e != nil {
+		switch e {
+		case zkclient.ErrNoNode:
+			return ErrNoNode{s: fmt.Sprintf("[%s] %s", p, e.Error())}
+		default:
+			return fmt.Errorf("[%s] %s", p, e.Error())
+		}
+	}
+
+	return nil
+}
+
 // Create creates the provided path p with the data from the provided string d
 // and returns an error if encountered.
 func (z *ZKHandler) Create(p string, d string) error {
@@ -367,6 +388,29 @@ func (z *ZKHandler) GetTopicConfig(t string) (*TopicConfig, error) {
 	return config, nil
 }
 
+// GetBrokerConfig takes a broker ID and returns a *KafkaConfigData of the
+// dynamic configs found in the /config/brokers/<id> znode. Brokers that have
+// never had a dynamic config applied return an empty config.
+func (z *ZKHandler) GetBrokerConfig(id int) (*KafkaConfigData, error) {
+	config := NewKafkaConfigData()
+	path := z.getPath(fmt.Sprintf("/config/brokers/%d", id))
+
+	// Get broker config.
+	data, err := z.Get(path)
+	if err != nil {
+		switch err.(type) {
+		case ErrNoNode:
+			return &config, nil
+		default:
+			return nil, err
+		}
+	}
+
+	json.Unmarshal(data, &config)
+
+	return &config, nil
+}
+
 // GetAllBrokerMeta looks up all registered Kafka brokers and returns their
 // metadata as a mapper.BrokerMetaMap. A withMetrics bool param determines whether
 // we additionally want to fetch stored broker metrics.
@@ -808,9 +852,12 @@ func (z *ZKHandler) getPath(p string) string {
 	return p
 }
 
+// getMetricsPath returns the path p under the MetricsPrefix. The prefix may
+// span several znodes (e.g. "metrics/kafka") and leading or trailing slashes
+// are ignored.
 func (z *ZKHandler) getMetricsPath(p string) string {
-	if z.MetricsPrefix != "" {
-		return fmt.Sprintf("/%s/%s", z.MetricsPrefix, strings.TrimLeft(p, "/"))
+	if prefix := strings.Trim(z.MetricsPrefix, "/"); prefix != "" {
+		return fmt.Sprintf("/%s/%s", prefix, strings.TrimLeft(p, "/"))
 	}
 
 	return p
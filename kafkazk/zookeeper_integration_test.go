@@ -338,6 +338,28 @@ func TestCreateSequential(t *testing.T) {
 	}
 }
 
+func TestCreateEphemeral(t *testing.T) {
+	p := zkprefix + "/ephemeral"
+
+	if err := zki.CreateEphemeral(p, "instance-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating an existing ephemeral znode should fail.
+	if err := zki.CreateEphemeral(p, "instance-b"); err == nil {
+		t.Error("Expected node exists error")
+	}
+
+	v, err := zki.Get(p)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(v) != "instance-a" {
+		t.Errorf("Expected value 'instance-a', got '%s'", v)
+	}
+}
+
 func TestExists(t *testing.T) {
 	e, err := zki.Exists(zkprefix)
 	if err != nil {
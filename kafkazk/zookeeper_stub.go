@@ -5,6 +5,7 @@ package kafkazk
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
@@ -103,6 +104,17 @@ func (zk *Stub) CreateSequential(a, b string) error {
 	return nil
 }
 
+// CreateEphemeral stubs CreateEphemeral. Unlike Create, an error is returned
+// if the znode exists. Ephemeral znodes aren't deleted by the stub; sessions
+// ending can be simulated with Delete.
+func (zk *Stub) CreateEphemeral(p, d string) error {
+	if _, err := zk.Get(p); err == nil {
+		return fmt.Errorf("[%s] node already exists", p)
+	}
+
+	return zk.Set(p, d)
+}
+
 // Exists stubs Exists.
 func (zk *Stub) Exists(p string) (bool, error) {
 	_, err := zk.Get(p)
@@ -290,8 +302,7 @@ func (zk *Stub) InitRawClient() error {
 
 // UpdateKafkaConfig stubs UpdateKafkaConfig.
 func (zk *Stub) UpdateKafkaConfig(c KafkaConfig) ([]bool, error) {
-	_ = c
-	return []bool{}, nil
+	return make([]bool, len(c.Configs)), nil
 }
 
 // GetTopics stubs GetTopics.
@@ -332,6 +343,18 @@ func (zk *Stub) GetTopicMetadata(t string) (TopicMetadata, error) {
 	}, nil
 }
 
+// GetBrokerConfig stubs GetBrokerConfig.
+func (zk *Stub) GetBrokerConfig(id int) (*KafkaConfigData, error) {
+	_ = id
+	return &KafkaConfigData{
+		Version: 1,
+		Config: map[string]string{
+			"leader.replication.throttled.rate":   "100000000",
+			"follower.replication.throttled.rate": "50000000",
+		},
+	}, nil
+}
+
 // GetTopicConfig stubs GetTopicConfig.
 func (zk *Stub) GetTopicConfig(t string) (*TopicConfig, error) {
 	return &TopicConfig{
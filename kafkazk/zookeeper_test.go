@@ -48,3 +48,20 @@ func testGetMapString(n string) string {
     {"topic":"%s","partition":2,"replicas":[1003,1004,1001]},
     {"topic":"%s","partition":3,"replicas":[1004,1003,1002]}]}`, n, n, n, n)
 }
+
+func TestGetMetricsPath(t *testing.T) {
+	tests := map[string]string{
+		"":                "/partitionmeta",
+		"/":               "/partitionmeta",
+		"topicmappr":      "/topicmappr/partitionmeta",
+		"/metrics/kafka/": "/metrics/kafka/partitionmeta",
+		"metrics/kafka":   "/metrics/kafka/partitionmeta",
+	}
+
+	for prefix, expected := range tests {
+		z := &ZKHandler{MetricsPrefix: prefix}
+		if p := z.getMetricsPath("/partitionmeta"); p != expected {
+			t.Errorf("Expected path %s for prefix '%s', got %s", expected, prefix, p)
+		}
+	}
+}
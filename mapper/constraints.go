@@ -13,16 +13,18 @@ var (
 
 // Constraints holds a map of IDs and locality key-values.
 type Constraints struct {
-	requestSize float64
-	locality    map[string]bool
-	id          map[int]bool
+	requestSize   float64
+	locality      map[string]bool
+	localityCount map[string]int
+	id            map[int]bool
 }
 
 // NewConstraints returns an empty *Constraints.
 func NewConstraints() *Constraints {
 	return &Constraints{
-		locality: make(map[string]bool),
-		id:       make(map[int]bool),
+		locality:      make(map[string]bool),
+		localityCount: make(map[string]int),
+		id:            make(map[int]bool),
 	}
 }
 
@@ -33,6 +35,14 @@ type ConstraintsParams struct {
 	MinUniqueRackIDs int
 	RequestSize      float64
 	SeedVal          int64
+	// MaxReplicas is the maximum Used value of a selected broker (0 is
+	// unlimited).
+	MaxReplicas int
+	// MaxTopicReplicas is the maximum number of replicas of the topic being
+	// placed that a selected broker may hold, as counted in TopicReplicas by
+	// broker ID (0 is unlimited).
+	MaxTopicReplicas int
+	TopicReplicas    map[int]int
 }
 
 // SelectBroker takes a BrokerList and a ConstraintsParams and selects the most
@@ -67,6 +77,59 @@ func (c *Constraints) SelectBroker(b BrokerList, p ConstraintsParams) (*Broker,
 	return nil, ErrNoBrokers
 }
 
+// SelectBrokerRelaxed is used where SelectBroker fails to find a broker that
+// passes all constraints. It selects a broker that isn't already in the
+// replica set and has sufficient storage, preferring brokers in the rack with
+// the fewest existing replicas. Ties are broken by the placement criteria
+// ordering as with SelectBroker.
+func (c *Constraints) SelectBrokerRelaxed(b BrokerList, p ConstraintsParams) (*Broker, error) {
+	switch p.SelectorMethod {
+	case "count":
+		b.SortPseudoShuffle(p.SeedVal)
+	case "storage":
+		b.SortByStorage()
+	default:
+		return nil, ErrInvalidSelectionMethod
+	}
+
+	var best *Broker
+
+	for _, candidate := range b.Filter(AllBrokersFn) {
+		switch {
+		case c.id[candidate.ID]:
+			continue
+		case p.atReplicaLimit(candidate):
+			continue
+		case candidate.StorageFree-p.RequestSize < 0:
+			continue
+		}
+
+		if best == nil || c.colocations(candidate) < c.colocations(best) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoBrokers
+	}
+
+	c.requestSize = p.RequestSize
+	c.Add(best)
+	best.Used++
+
+	return best, nil
+}
+
+// colocations returns the number of brokers in the *Constraints that share
+// the locality of the *Broker.
+func (c *Constraints) colocations(b *Broker) int {
+	if b.Locality == "" {
+		return 0
+	}
+
+	return c.localityCount[b.Locality]
+}
+
 // TODO deprecate.
 // BestCandidate takes a *Constraints, selection method and pass / iteration
 // number (for use as a seed value for pseudo-random number generation) and
@@ -105,8 +168,9 @@ func (b BrokerList) BestCandidate(c *Constraints, by string, p int64) (*Broker,
 func (c *Constraints) Add(b *Broker) {
 	b.StorageFree -= c.requestSize
 
-	if b.Locality != "" {
+	if b.Locality != "" && !c.id[b.ID] {
 		c.locality[b.Locality] = true
+		c.localityCount[b.Locality]++
 	}
 
 	c.id[b.ID] = true
@@ -117,8 +181,9 @@ func (c *Constraints) Add(b *Broker) {
 func (c *Constraints) MergeConstraints(bl BrokerList) {
 	// Don't merge in attributes from nodes that will be removed.
 	for _, b := range bl.Filter(NotReplacedBrokersFn) {
-		if b.Locality != "" {
+		if b.Locality != "" && !c.id[b.ID] {
 			c.locality[b.Locality] = true
+			c.localityCount[b.Locality]++
 		}
 
 		c.id[b.ID] = true
@@ -153,6 +218,9 @@ func (c *Constraints) passesWithParams(b *Broker, p ConstraintsParams) bool {
 	// Check the candidate against already used IDs.
 	case c.id[b.ID]:
 		return false
+	// Check the candidate against replica limits.
+	case p.atReplicaLimit(b):
+		return false
 	// Check the candidate against rack ID constraints where all rack IDs must be
 	// unique.
 	case c.locality[b.Locality] && p.MinUniqueRackIDs == 0:
@@ -171,6 +239,20 @@ func (c *Constraints) passesWithParams(b *Broker, p ConstraintsParams) bool {
 	return true
 }
 
+// atReplicaLimit returns whether the *Broker holds the maximum number of
+// replicas, in total or of the topic being placed, allowed by the
+// ConstraintsParams.
+func (p ConstraintsParams) atReplicaLimit(b *Broker) bool {
+	switch {
+	case p.MaxReplicas > 0 && b.Used >= p.MaxReplicas:
+		return true
+	case p.MaxTopicReplicas > 0 && p.TopicReplicas[b.ID] >= p.MaxTopicReplicas:
+		return true
+	}
+
+	return false
+}
+
 // TODO deprecate.
 // MergeConstraints takes a brokerlist and builds a *Constraints by merging the
 // attributes of all brokers from the supplied list.
@@ -183,8 +265,9 @@ func MergeConstraints(bl BrokerList) *Constraints {
 			continue
 		}
 
-		if b.Locality != "" {
+		if b.Locality != "" && !c.id[b.ID] {
 			c.locality[b.Locality] = true
+			c.localityCount[b.Locality]++
 		}
 
 		c.id[b.ID] = true
@@ -46,6 +46,75 @@ func TestSelectBrokerByCount(t *testing.T) {
 	}
 }
 
+func TestSelectBrokerRelaxed(t *testing.T) {
+	localities := []string{"a", "a", "b", "a", "b"}
+	bl := BrokerList{}
+
+	for i, l := range localities {
+		bl = append(bl, &Broker{ID: 1000 + i, Locality: l, Used: i})
+	}
+
+	c := NewConstraints()
+	c.MergeConstraints(bl[:3])
+
+	p := ConstraintsParams{
+		SelectorMethod: "count",
+	}
+
+	if _, err := c.SelectBroker(bl, p); err != ErrNoBrokers {
+		t.Fatalf("Expected error '%s', got '%v'", ErrNoBrokers, err)
+	}
+
+	b, err := c.SelectBrokerRelaxed(bl, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rack "b" has fewer colocations.
+	if b.ID != 1004 {
+		t.Errorf("Expected candidate with ID 1004, got %d", b.ID)
+	}
+
+	b, _ = c.SelectBrokerRelaxed(bl, p)
+	if b.ID != 1003 {
+		t.Errorf("Expected candidate with ID 1003, got %d", b.ID)
+	}
+
+	if _, err := c.SelectBrokerRelaxed(bl, p); err != ErrNoBrokers {
+		t.Errorf("Expected error '%s', got '%v'", ErrNoBrokers, err)
+	}
+}
+
+func TestSelectBrokerReplicaLimits(t *testing.T) {
+	bl := BrokerList{
+		&Broker{ID: 1000, Used: 3},
+		&Broker{ID: 1001, Used: 1},
+		&Broker{ID: 1002, Used: 0},
+	}
+
+	p := ConstraintsParams{
+		SelectorMethod:   "count",
+		MaxReplicas:      2,
+		MaxTopicReplicas: 1,
+		TopicReplicas:    map[int]int{1002: 1},
+	}
+
+	// 1000 is at the total limit, 1002 at the topic limit.
+	b, err := NewConstraints().SelectBroker(bl, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.ID != 1001 {
+		t.Errorf("Expected candidate with ID 1001, got %d", b.ID)
+	}
+
+	// 1001 is now at the total limit.
+	if _, err := NewConstraints().SelectBroker(bl, p); err != ErrNoBrokers {
+		t.Errorf("Expected error '%s', got '%v'", ErrNoBrokers, err)
+	}
+}
+
 func TestSelectBrokerByStorage(t *testing.T) {
 	localities := []string{"a", "b", "c"}
 	bl := BrokerList{}
@@ -190,6 +190,16 @@ type RebuildParams struct {
 	Affinities       SubstitutionAffinities
 	PartnSzFactor    float64
 	MinUniqueRackIDs int
+	// RelaxRackAwareness allows placing replicas in racks already used by the
+	// replica set where no broker satisfies the rack constraints, minimizing
+	// the number of same-rack colocations.
+	RelaxRackAwareness bool
+	// MaxReplicasPerBroker limits the replicas held by any broker selected
+	// for placement, as counted by the broker's Used value (0 is unlimited).
+	MaxReplicasPerBroker int
+	// MaxTopicReplicasPerBroker limits the replicas of any one topic held by
+	// any broker selected for placement (0 is unlimited).
+	MaxTopicReplicasPerBroker int
 }
 
 // NewRebuildParams initializes a RebuildParams.
@@ -205,6 +215,10 @@ func NewRebuildParams() RebuildParams {
 // has a high leader/follower ratio, it should go further down the replica list.
 // This ratio is recalculated at each replica set visited to avoid extreme skew.
 func (pm *PartitionMap) OptimizeLeaderFollower() {
+	if len(pm.Partitions) == 0 {
+		return
+	}
+
 	for i := 0; i < len(pm.Partitions[0].Replicas); i++ {
 		for _, partn := range pm.Partitions {
 			sort.Sort(replicasByLeaderFollowerRatio{
@@ -296,12 +310,21 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 	// removal.
 	bl := params.BM.Filter(NotReplacedBrokersFn).List()
 
+	// Replicas held per topic by each broker, for per-topic replica limits.
+	topicReplicas := params.pm.topicReplicaCounts(params.BM)
+
 	var errs []error
 	var pass int
 
+	// Positions of the replicas selected in the current pass, by partition
+	// index.
+	var selected map[int]int
+
 	// Check if we need more passes. If we've just counted as many skips as there
 	// are partitions to handle, we have nothing left to do.
 	for skipped := 0; skipped < len(params.pm.Partitions); {
+		selected = map[int]int{}
+
 		for n, partn := range params.pm.Partitions {
 			// If this is the first pass, create the new partition.
 			if pass == 0 {
@@ -343,6 +366,9 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 				constraintsParams := ConstraintsParams{
 					SelectorMethod:   params.Strategy,
 					MinUniqueRackIDs: params.MinUniqueRackIDs,
+					MaxReplicas:      params.MaxReplicasPerBroker,
+					MaxTopicReplicas: params.MaxTopicReplicasPerBroker,
+					TopicReplicas:    topicReplicas[partn.Topic],
 				}
 				constraints.MergeConstraints(replicaSet)
 
@@ -379,6 +405,13 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 					// Otherwise, use the standard constraints based selector.
 					constraintsParams.SeedVal = int64(pass*n + 1)
 					replacement, err = constraints.SelectBroker(bl, constraintsParams)
+					if err == nil {
+						selected[n] = len(newMap.Partitions[n].Replicas)
+					}
+				}
+
+				if err == ErrNoBrokers && params.RelaxRackAwareness {
+					replacement, err = constraints.SelectBrokerRelaxed(bl, constraintsParams)
 				}
 
 				if err != nil {
@@ -390,9 +423,17 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 
 				// Add the replacement to the map.
 				newMap.Partitions[n].Replicas = append(newMap.Partitions[n].Replicas, replacement.ID)
+				topicReplicas[partn.Topic][replacement.ID]++
 			}
 		}
 
+		// Selecting the least used broker for each partition in turn can leave
+		// the last partitions of a pass with only their own replicas as the least
+		// used brokers, skewing counts.
+		if params.Strategy == "count" {
+			rebalancePass(params, newMap, selected, bl, topicReplicas)
+		}
+
 		// Increment the pass.
 		pass++
 
@@ -410,6 +451,82 @@ func placeByPosition(params RebuildParams) (*PartitionMap, []error) {
 	return newMap, errs
 }
 
+// rebalancePass moves replicas selected in a placeByPosition pass from the
+// most used to the least used brokers where the replica counts differ by more
+// than one and the replica set constraints allow it. The selected map holds
+// the replica positions selected in the pass by partition index.
+func rebalancePass(params RebuildParams, newMap *PartitionMap, selected map[int]int, bl BrokerList, topicReplicas map[string]map[int]int) {
+	if len(bl) < 2 {
+		return
+	}
+
+	// Iterate partitions in order for deterministic placements.
+	var idx []int
+	for n := range selected {
+		idx = append(idx, n)
+	}
+	sort.Ints(idx)
+
+	for {
+		bl.SortByCount()
+		least, most := bl[0], bl[len(bl)-1]
+
+		if most.Used-least.Used < 2 {
+			return
+		}
+
+		var moved bool
+
+		for _, n := range idx {
+			partn := newMap.Partitions[n]
+			pos := selected[n]
+
+			if partn.Replicas[pos] != most.ID {
+				continue
+			}
+
+			// Build constraints from the replica set sans the replica being moved.
+			replicaSet := BrokerList{}
+			for _, bid := range params.pm.Partitions[n].Replicas {
+				replicaSet = append(replicaSet, params.BM[bid])
+			}
+			for i, bid := range partn.Replicas {
+				if i != pos {
+					replicaSet = append(replicaSet, params.BM[bid])
+				}
+			}
+
+			constraints := NewConstraints()
+			constraints.MergeConstraints(replicaSet)
+
+			constraintsParams := ConstraintsParams{
+				SelectorMethod:   params.Strategy,
+				MinUniqueRackIDs: params.MinUniqueRackIDs,
+				MaxReplicas:      params.MaxReplicasPerBroker,
+				MaxTopicReplicas: params.MaxTopicReplicasPerBroker,
+				TopicReplicas:    topicReplicas[partn.Topic],
+			}
+
+			if !constraints.passesWithParams(least, constraintsParams) {
+				continue
+			}
+
+			partn.Replicas[pos] = least.ID
+			most.Used--
+			least.Used++
+			topicReplicas[partn.Topic][most.ID]--
+			topicReplicas[partn.Topic][least.ID]++
+			moved = true
+
+			break
+		}
+
+		if !moved {
+			return
+		}
+	}
+}
+
 func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 	newMap := NewPartitionMap()
 
@@ -417,6 +534,9 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 	// removal.
 	bl := params.BM.Filter(NotReplacedBrokersFn).List()
 
+	// Replicas held per topic by each broker, for per-topic replica limits.
+	topicReplicas := params.pm.topicReplicaCounts(params.BM)
+
 	var errs []error
 
 	for _, partn := range params.pm.Partitions {
@@ -450,6 +570,9 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 					SelectorMethod:   params.Strategy,
 					MinUniqueRackIDs: params.MinUniqueRackIDs,
 					SeedVal:          1,
+					MaxReplicas:      params.MaxReplicasPerBroker,
+					MaxTopicReplicas: params.MaxTopicReplicasPerBroker,
+					TopicReplicas:    topicReplicas[partn.Topic],
 				}
 				constraints.MergeConstraints(replicaSet)
 
@@ -468,6 +591,10 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 				// Fetch the best candidate and append.
 				replacement, err := constraints.SelectBroker(bl, constraintsParams)
 
+				if err == ErrNoBrokers && params.RelaxRackAwareness {
+					replacement, err = constraints.SelectBrokerRelaxed(bl, constraintsParams)
+				}
+
 				if err != nil {
 					// Append any caught errors.
 					e := fmt.Errorf("%s p%d: %s", partn.Topic, partn.Partition, err.Error())
@@ -476,6 +603,7 @@ func placeByPartition(params RebuildParams) (*PartitionMap, []error) {
 				}
 
 				newPartn.Replicas = append(newPartn.Replicas, replacement.ID)
+				topicReplicas[partn.Topic][replacement.ID]++
 			}
 		}
 
@@ -580,6 +708,18 @@ func PartitionMapFromString(s string) (*PartitionMap, error) {
 	return pm, nil
 }
 
+// PartitionMapFromFile takes the path of a json encoded partition map file, such
+// as a reassignment file generated by topicmappr or the kafka-reassign-partitions
+// tool, and returns a *PartitionMap.
+func PartitionMapFromFile(path string) (*PartitionMap, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading partition map: %s", err.Error())
+	}
+
+	return PartitionMapFromString(string(b))
+}
+
 // PartitionMapFromTopicStates translates a kafkaadmin.TopicStates to a *PartitionMap.
 func PartitionMapFromTopicStates(ts kafkaadmin.TopicStates) (*PartitionMap, error) {
 	pm := NewPartitionMap()
@@ -632,6 +772,201 @@ func (pm *PartitionMap) SetReplication(r int) {
 	}
 }
 
+// ReduceReplication reduces replica sets exceeding the replication factor r to
+// r replicas. Leaders are retained. Followers are removed first from brokers
+// marked for replacement, then where their rack ID is shared with another
+// replica in the set, then from the most utilized brokers; by StorageFree for
+// the "storage" strategy, otherwise by the number of replicas held. The
+// StorageFree of brokers in the BrokerMap is updated with the sizes of removed
+// replicas for the "storage" strategy. Unless RelaxRackAwareness is set, an
+// error is returned for each replica set left spanning fewer unique rack IDs
+// than required by MinUniqueRackIDs (0 requires that all are unique).
+func (pm *PartitionMap) ReduceReplication(r int, params RebuildParams) []error {
+	// 0 is a no-op.
+	if r == 0 {
+		return nil
+	}
+
+	var errs []error
+	use := pm.UseStats()
+
+	locality := func(id int) string {
+		if b, exists := params.BM[id]; exists {
+			return b.Locality
+		}
+		return ""
+	}
+
+	// Brokers not in the BrokerMap, such as stub brokers, are treated as
+	// marked for replacement.
+	replaced := func(id int) bool {
+		b, exists := params.BM[id]
+		return !exists || b.Replace
+	}
+
+	// moreUtilized returns whether broker a is more utilized than broker b.
+	moreUtilized := func(a, b int) bool {
+		ba, bb := params.BM[a], params.BM[b]
+		if params.Strategy == "storage" && ba != nil && bb != nil {
+			return ba.StorageFree < bb.StorageFree
+		}
+
+		var ua, ub int
+		if s, exists := use[a]; exists {
+			ua = s.Leader + s.Follower
+		}
+		if s, exists := use[b]; exists {
+			ub = s.Leader + s.Follower
+		}
+
+		return ua > ub
+	}
+
+	for n, p := range pm.Partitions {
+		if len(p.Replicas) <= r {
+			continue
+		}
+
+		replicas := append([]int{}, p.Replicas...)
+
+		for len(replicas) > r && len(replicas) > 1 {
+			racks := map[string]int{}
+			for _, id := range replicas {
+				racks[locality(id)]++
+			}
+
+			shared := func(id int) bool {
+				rack := locality(id)
+				return rack != "" && racks[rack] > 1
+			}
+
+			// Select the follower to remove, preferring brokers marked for
+			// replacement, then those sharing a rack ID, then the most utilized,
+			// then later positions in a tie.
+			remove := -1
+			for i := len(replicas) - 1; i > 0; i-- {
+				a := replicas[i]
+				if remove == -1 {
+					remove = i
+					continue
+				}
+
+				b := replicas[remove]
+				switch {
+				case replaced(a) != replaced(b):
+					if replaced(a) {
+						remove = i
+					}
+				case shared(a) != shared(b):
+					if shared(a) {
+						remove = i
+					}
+				case moreUtilized(a, b):
+					remove = i
+				}
+			}
+
+			id := replicas[remove]
+			replicas = append(replicas[:remove], replicas[remove+1:]...)
+
+			// Update utilization.
+			if s, exists := use[id]; exists {
+				s.Follower--
+			}
+
+			if params.Strategy == "storage" {
+				if b, exists := params.BM[id]; exists {
+					size, err := params.PMM.Size(p)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%s p%d: %s", p.Topic, p.Partition, err.Error()))
+					} else {
+						b.StorageFree += size * params.PartnSzFactor
+					}
+				}
+			}
+		}
+
+		pm.Partitions[n].Replicas = replicas
+
+		// Check the rack IDs spanned by the remaining replicas, where known.
+		required := len(replicas)
+		if params.MinUniqueRackIDs > 0 && params.MinUniqueRackIDs < required {
+			required = params.MinUniqueRackIDs
+		}
+
+		racks := map[string]struct{}{}
+		for _, id := range replicas {
+			racks[locality(id)] = struct{}{}
+		}
+
+		if _, unknown := racks[""]; !unknown && !params.RelaxRackAwareness && len(racks) < required {
+			errs = append(errs, fmt.Errorf("%s p%d: replicas %v span %d unique rack IDs, %d required",
+				p.Topic, p.Partition, replicas, len(racks), required))
+		}
+	}
+
+	return errs
+}
+
+// topicReplicaCounts returns the number of replicas of each topic held by each
+// broker not marked for replacement in the BrokerMap, keyed by topic then
+// broker ID.
+func (pm *PartitionMap) topicReplicaCounts(bm BrokerMap) map[string]map[int]int {
+	counts := map[string]map[int]int{}
+
+	for _, p := range pm.Partitions {
+		if counts[p.Topic] == nil {
+			counts[p.Topic] = map[int]int{}
+		}
+
+		for _, id := range p.Replicas {
+			if b, exists := bm[id]; exists && !b.Replace {
+				counts[p.Topic][id]++
+			}
+		}
+	}
+
+	return counts
+}
+
+// SubstituteBrokers replaces each broker ID that's a key in subs with the
+// corresponding value in all replica sets, preserving the replica positions
+// (and therefore leadership). Substitutions are applied relative to the
+// original replica sets. Replica sets where a substitution would result in a
+// duplicate broker ID are left unchanged and an error is returned for each.
+func (pm *PartitionMap) SubstituteBrokers(subs map[int]int) []error {
+	var errs []error
+
+	for n, p := range pm.Partitions {
+		replicas := make([]int, len(p.Replicas))
+		seen := map[int]struct{}{}
+		var duplicate bool
+
+		for i, id := range p.Replicas {
+			if sub, exists := subs[id]; exists {
+				id = sub
+			}
+
+			if _, exists := seen[id]; exists {
+				duplicate = true
+			}
+
+			seen[id] = struct{}{}
+			replicas[i] = id
+		}
+
+		if duplicate {
+			errs = append(errs, fmt.Errorf("%s p%d: substitution of replicas %v results in duplicate broker IDs %v",
+				p.Topic, p.Partition, p.Replicas, replicas))
+			continue
+		}
+
+		pm.Partitions[n].Replicas = replicas
+	}
+
+	return errs
+}
+
 // Topics returns a []string of topic names held in the PartitionMap.
 func (pm *PartitionMap) Topics() []string {
 	// Set.
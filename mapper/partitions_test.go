@@ -267,6 +267,30 @@ func TestPartitionMapFromString(t *testing.T) {
 	}
 }
 
+func TestPartitionMapFromFile(t *testing.T) {
+	// Fields such as log_dirs from kafka-reassign-partitions are ignored.
+	path := t.TempDir() + "/map.json"
+	err := ioutil.WriteFile(path, []byte(`{"version":1,"partitions":[
+		{"topic":"test","partition":1,"replicas":[1002,1001],"log_dirs":["any","any"]},
+		{"topic":"test","partition":0,"replicas":[1001,1002],"log_dirs":["any","any"]}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pm, err := PartitionMapFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pm.Partitions) != 2 || pm.Partitions[0].Partition != 0 || pm.Partitions[1].Replicas[0] != 1002 {
+		t.Errorf("Unexpected partition map: %v", pm.Partitions)
+	}
+
+	if _, err := PartitionMapFromFile(path + ".missing"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
 func TestSetReplication(t *testing.T) {
 	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
 
@@ -315,6 +339,123 @@ func TestSetReplication(t *testing.T) {
 	}
 }
 
+func TestReduceReplication(t *testing.T) {
+	pm, _ := PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test","partition":1,"replicas":[1002,1003,1004]},
+		{"topic":"test","partition":2,"replicas":[1003,1004]}]}`)
+
+	bm := BrokerMap{
+		1001: &Broker{ID: 1001, Locality: "a", StorageFree: 100},
+		1002: &Broker{ID: 1002, Locality: "b", StorageFree: 50},
+		1003: &Broker{ID: 1003, Locality: "c", StorageFree: 200},
+		1004: &Broker{ID: 1004, Locality: "b", StorageFree: 300},
+	}
+
+	pmm := PartitionMetaMap{
+		"test": {
+			0: &PartitionMeta{Size: 10},
+			1: &PartitionMeta{Size: 10},
+			2: &PartitionMeta{Size: 10},
+		},
+	}
+
+	params := NewRebuildParams()
+	params.BM = bm
+	params.PMM = pmm
+	params.Strategy = "storage"
+
+	errs := pm.ReduceReplication(2, params)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	expected := [][]int{
+		// The follower with the least storage free is removed.
+		{1001, 1003},
+		// 1004 shares a rack ID with the leader.
+		{1002, 1003},
+		// Unchanged.
+		{1003, 1004},
+	}
+
+	for i, p := range pm.Partitions {
+		if len(p.Replicas) != len(expected[i]) {
+			t.Fatalf("[p%d] Expected replicas %v, got %v", i, expected[i], p.Replicas)
+		}
+		for j := range p.Replicas {
+			if p.Replicas[j] != expected[i][j] {
+				t.Errorf("[p%d] Expected replicas %v, got %v", i, expected[i], p.Replicas)
+			}
+		}
+	}
+
+	if bm[1002].StorageFree != 60 || bm[1004].StorageFree != 310 {
+		t.Errorf("Unexpected storage free values: %f, %f", bm[1002].StorageFree, bm[1004].StorageFree)
+	}
+
+	// Brokers marked for replacement are removed first.
+	pm, _ = PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002,1003]}]}`)
+	bm[1003].Replace = true
+
+	pm.ReduceReplication(2, params)
+	if r := pm.Partitions[0].Replicas; r[1] != 1002 {
+		t.Errorf("Expected replicas [1001 1002], got %v", r)
+	}
+
+	bm[1003].Replace = false
+
+	// Replica sets left without unique rack IDs.
+	pm, _ = PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1002,1004,1001]}]}`)
+	params.Strategy = "count"
+
+	if errs := pm.ReduceReplication(2, params); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	pm, _ = PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1002,1004,1005]}]}`)
+	bm[1005] = &Broker{ID: 1005, Locality: "b"}
+
+	if errs := pm.ReduceReplication(2, params); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(errs))
+	}
+}
+
+func TestSubstituteBrokers(t *testing.T) {
+	pm, _ := PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002,1003]},
+		{"topic":"test","partition":1,"replicas":[1002,1001,1004]},
+		{"topic":"test","partition":2,"replicas":[1003,1004]},
+		{"topic":"test","partition":3,"replicas":[1001,1005]}]}`)
+
+	errs := pm.SubstituteBrokers(map[int]int{1001: 1005, 1002: 1001})
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(errs))
+	}
+
+	expected := [][]int{
+		// Positions are preserved; substitutions are relative to the original
+		// replica sets.
+		{1005, 1001, 1003},
+		{1001, 1005, 1004},
+		// Unchanged.
+		{1003, 1004},
+		// Unchanged; 1005 is already in the replica set.
+		{1001, 1005},
+	}
+
+	for i, p := range pm.Partitions {
+		for j := range p.Replicas {
+			if p.Replicas[j] != expected[i][j] {
+				t.Errorf("[p%d] Expected replicas %v, got %v", i, expected[i], p.Replicas)
+			}
+		}
+	}
+}
+
 func TestStrip(t *testing.T) {
 	pm, _ := PartitionMapFromString(testGetMapString("test_topic"))
 
@@ -490,6 +631,34 @@ func TestRebuildByCountSA(t *testing.T) {
 }
 
 // Storage rebuild, distribution optimization.
+func TestRebuildByCountBalance(t *testing.T) {
+	pm, _ := PartitionMapFromString(`{"version":1,"partitions":[
+		{"topic":"test","partition":0,"replicas":[1001,1002]},
+		{"topic":"test","partition":1,"replicas":[1001,1002]},
+		{"topic":"test","partition":2,"replicas":[1001,1002]},
+		{"topic":"test","partition":3,"replicas":[1001,1002]}]}`)
+
+	brokers := BrokerMapFromPartitionMap(pm, nil, true)
+	brokers[1003] = &Broker{ID: 1003, New: true}
+	brokers[1004] = &Broker{ID: 1004, New: true}
+
+	rebuildParams := RebuildParams{BM: brokers, Strategy: "count", Optimization: "distribution"}
+
+	// Greedy placement of the final follower would otherwise leave 1004 with a
+	// single replica.
+	out, errs := pm.Strip().Rebuild(rebuildParams)
+	if errs != nil {
+		t.Errorf("Unexpected error(s): %s", errs)
+	}
+
+	stats := out.UseStats()
+	for _, id := range []int{1001, 1002, 1003, 1004} {
+		if s, exists := stats[id]; !exists || s.Leader != 1 || s.Follower != 1 {
+			t.Errorf("Expected 1 leader and 1 follower on broker %d, got %+v", id, s)
+		}
+	}
+}
+
 func TestRebuildByStorageDistribution(t *testing.T) {
 	forceRebuild := true
 	withMetrics := true
@@ -631,6 +800,9 @@ func TestOptimizeLeaderFollower(t *testing.T) {
 	if equal, _ := optimized.Equal(expected); !equal {
 		t.Errorf("Unexpected OptimizeLeaderFollower results")
 	}
+
+	// An empty map is a no-op.
+	NewPartitionMap().OptimizeLeaderFollower()
 }
 
 func TestShuffle(t *testing.T) {
@@ -0,0 +1,158 @@
+//
+//If this proto file is updated, the generated outputs can be updated with
+//the `make generate-code` command.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.19.1
+// source: kafkametrics.proto
+
+package kafkametricspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MetricsPlugin_GetMetrics_FullMethodName = "/kafkametrics.MetricsPlugin/GetMetrics"
+	MetricsPlugin_PostEvent_FullMethodName  = "/kafkametrics.MetricsPlugin/PostEvent"
+)
+
+// MetricsPluginClient is the client API for MetricsPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MetricsPluginClient interface {
+	// GetMetrics returns the network metrics and metadata for all reference
+	// brokers. Errors that would be returned from a kafkametrics Handler are
+	// returned in the errors field; gRPC errors are treated as API errors.
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
+	// PostEvent records an event with the metrics provider.
+	PostEvent(ctx context.Context, in *PostEventRequest, opts ...grpc.CallOption) (*PostEventResponse, error)
+}
+
+type metricsPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMetricsPluginClient(cc grpc.ClientConnInterface) MetricsPluginClient {
+	return &metricsPluginClient{cc}
+}
+
+func (c *metricsPluginClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
+	out := new(GetMetricsResponse)
+	err := c.cc.Invoke(ctx, MetricsPlugin_GetMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsPluginClient) PostEvent(ctx context.Context, in *PostEventRequest, opts ...grpc.CallOption) (*PostEventResponse, error) {
+	out := new(PostEventResponse)
+	err := c.cc.Invoke(ctx, MetricsPlugin_PostEvent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetricsPluginServer is the server API for MetricsPlugin service.
+// All implementations must embed UnimplementedMetricsPluginServer
+// for forward compatibility
+type MetricsPluginServer interface {
+	// GetMetrics returns the network metrics and metadata for all reference
+	// brokers. Errors that would be returned from a kafkametrics Handler are
+	// returned in the errors field; gRPC errors are treated as API errors.
+	GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error)
+	// PostEvent records an event with the metrics provider.
+	PostEvent(context.Context, *PostEventRequest) (*PostEventResponse, error)
+	mustEmbedUnimplementedMetricsPluginServer()
+}
+
+// UnimplementedMetricsPluginServer must be embedded to have forward compatible implementations.
+type UnimplementedMetricsPluginServer struct {
+}
+
+func (UnimplementedMetricsPluginServer) GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedMetricsPluginServer) PostEvent(context.Context, *PostEventRequest) (*PostEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostEvent not implemented")
+}
+func (UnimplementedMetricsPluginServer) mustEmbedUnimplementedMetricsPluginServer() {}
+
+// UnsafeMetricsPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MetricsPluginServer will
+// result in compilation errors.
+type UnsafeMetricsPluginServer interface {
+	mustEmbedUnimplementedMetricsPluginServer()
+}
+
+func RegisterMetricsPluginServer(s grpc.ServiceRegistrar, srv MetricsPluginServer) {
+	s.RegisterService(&MetricsPlugin_ServiceDesc, srv)
+}
+
+func _MetricsPlugin_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsPluginServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsPlugin_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsPluginServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsPlugin_PostEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsPluginServer).PostEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsPlugin_PostEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsPluginServer).PostEvent(ctx, req.(*PostEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MetricsPlugin_ServiceDesc is the grpc.ServiceDesc for MetricsPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MetricsPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kafkametrics.MetricsPlugin",
+	HandlerType: (*MetricsPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMetrics",
+			Handler:    _MetricsPlugin_GetMetrics_Handler,
+		},
+		{
+			MethodName: "PostEvent",
+			Handler:    _MetricsPlugin_PostEvent_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "kafkametrics.proto",
+}